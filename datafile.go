@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/filewatch"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// dataFileDebounce is how long the data file must stay unchanged before it is
+// reloaded
+const dataFileDebounce = 250 * time.Millisecond
+
+// watchDataFile reloads store from path whenever the file changes, until ctx
+// is canceled
+func watchDataFile(ctx context.Context, logger *slog.Logger, store itemstore.Store, path string, interval time.Duration) {
+	filewatch.New(path, interval, dataFileDebounce).Run(ctx, func() {
+		reloadDataFile(ctx, logger, store, path)
+	})
+}
+
+// reloadDataFile swaps the store's contents for the file's. An invalid file
+// is logged and the current items are kept.
+func reloadDataFile(ctx context.Context, logger *slog.Logger, store itemstore.Store, path string) {
+	items, err := itemstore.LoadFile(path)
+	if err != nil {
+		logger.ErrorContext(ctx, "data file rejected, keeping current items", "path", path, "error", err)
+		return
+	}
+	diff, err := store.SetItems(items)
+	if err != nil {
+		logger.ErrorContext(ctx, "data file rejected, keeping current items", "path", path, "error", err)
+		return
+	}
+	if diff.Empty() {
+		logger.DebugContext(ctx, "data file reloaded without changes", "path", path)
+		return
+	}
+	logger.InfoContext(ctx, "data file reloaded",
+		"path", path,
+		"added", itemIDs(diff.Added),
+		"removed", itemIDs(diff.Removed),
+		"changed", changedIDs(diff.Changed))
+}
+
+func itemIDs(items []itemstore.Item) []itemstore.ID {
+	ids := make([]itemstore.ID, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+func changedIDs(changes []itemstore.ItemChange) []itemstore.ID {
+	ids := make([]itemstore.ID, len(changes))
+	for i, c := range changes {
+		ids[i] = c.After.ID
+	}
+	return ids
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+func TestWatchDataFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`[{"id":1,"color":"red","shape":"circle","category":"A"}]`)
+
+	store := newTestStore(t)
+	buf := &lockedBuffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchDataFile(ctx, logger, store, path, 10*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+
+	waitUntil := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s; logs: %s", what, buf)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	t.Run("valid edit", func(t *testing.T) {
+		write(`[{"id":1,"color":"blue","shape":"circle","category":"A"},{"id":7,"color":"green","shape":"square","category":"B"}]`)
+		waitUntil("the reload", func() bool { return store.Len() == 2 })
+
+		if item, _ := store.Get(itemstore.IntID(1)); item.Color != "blue" {
+			t.Errorf("item 1 color = %q, want blue", item.Color)
+		}
+		waitUntil("the diff log", func() bool { return strings.Contains(buf.String(), `"msg":"data file reloaded"`) })
+		for _, rec := range logRecords(t, bytes.NewBufferString(buf.String())) {
+			if rec["msg"] == "data file reloaded" {
+				if got := rec["added"]; len(got.([]any)) != 1 {
+					t.Errorf("added = %v, want [7]", got)
+				}
+				if got := rec["removed"]; len(got.([]any)) != 2 {
+					t.Errorf("removed = %v, want [2 3]", got)
+				}
+			}
+		}
+	})
+
+	t.Run("invalid edit", func(t *testing.T) {
+		buf.Reset()
+		write(`[{"id":1,"color":"","shape":"circle","category":"A"}]`)
+		waitUntil("the rejection", func() bool { return strings.Contains(buf.String(), "data file rejected") })
+
+		if store.Len() != 2 {
+			t.Errorf("Len() = %d after an invalid edit, want the previous 2 items", store.Len())
+		}
+		if item, _ := store.Get(itemstore.IntID(1)); item.Color != "blue" {
+			t.Errorf("item 1 color = %q, want the previous value blue", item.Color)
+		}
+	})
+}
+
+func TestRun_DataFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	os.WriteFile("items.json", []byte(`[{"id":7,"color":"teal","shape":"circle","category":"Z"}]`), 0o600)
+	// run freezes the shape registry, which other tests still register in
+	defer func(shapes *format.ShapeRegistry) { format.Shapes = shapes }(format.Shapes)
+	format.Shapes = format.NewShapeRegistry()
+
+	cfg := config.Default()
+	cfg.Addr = "unix:" + filepath.Join(dir, "dashboard.sock")
+	cfg.DataFile = "items.json"
+	logs := &lockedBuffer{}
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, cfg, slog.New(slog.NewJSONHandler(logs, nil))) }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", filepath.Join(dir, "dashboard.sock"))
+		},
+	}}
+	var body []byte
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		resp, err := client.Get("http://dashboard/api/items")
+		if err == nil {
+			body, _ = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server never answered: %v\n%s", err, logs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	var list struct{ Items []itemstore.Item }
+	if err := json.Unmarshal(body, &list); err != nil || len(list.Items) != 1 || list.Items[0].Color != "teal" {
+		t.Errorf("GET /api/items = %s, want the item from the data file", body)
+	}
+	if want := `"path":"` + filepath.Join(dir, "items.json") + `"`; !strings.Contains(logs.String(), want) {
+		t.Errorf("logs do not show the absolute data file path %s:\n%s", want, logs)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("run() = %v after cancel, want nil", err)
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for a logger writing in one goroutine
+// while the test reads in another
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *lockedBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/grpcapi"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	pb "github.com/ElodinLaarz/dashboard/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// serveGRPC serves the ItemService for store on ln until ctx is canceled,
+// applying the same API key policy as the JSON API. With a non-nil tlsConfig
+// the connections are served over TLS.
+func serveGRPC(ctx context.Context, ln net.Listener, store itemstore.Store, cfg config.Config, tlsConfig *tls.Config, logger *slog.Logger) error {
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	g := grpc.NewServer(opts...)
+	svc := grpcapi.New(store,
+		grpcapi.WithLogger(logger),
+		grpcapi.WithAPIKeys(cfg.APIKeys, cfg.AllowUnauthenticatedWrites))
+	pb.RegisterItemServiceServer(g, svc)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- g.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Event streams never finish on their own, so end them before waiting
+	// for in-flight calls
+	svc.Close()
+	stopped := make(chan struct{})
+	go func() {
+		g.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		g.Stop()
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	pb "github.com/ElodinLaarz/dashboard/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestServeGRPC(t *testing.T) {
+	cfg := config.Default()
+	cfg.APIKeys = []string{"secret"}
+	store := newTestStore(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- serveGRPC(ctx, ln, store, cfg, nil, slog.New(slog.DiscardHandler)) }()
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := pb.NewItemServiceClient(conn)
+
+	callCtx, callCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer callCancel()
+
+	item := &pb.Item{Color: "red", Shape: "square", Category: "C"}
+	if _, err := client.CreateItem(callCtx, &pb.CreateItemRequest{Item: item}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("CreateItem without key: code = %v, want Unauthenticated", status.Code(err))
+	}
+	authed := metadata.AppendToOutgoingContext(callCtx, "authorization", "Bearer secret")
+	if _, err := client.CreateItem(authed, &pb.CreateItemRequest{Item: item}); err != nil {
+		t.Fatalf("CreateItem with key: %v", err)
+	}
+	if store.Len() != 4 {
+		t.Errorf("store has %d items, want 4", store.Len())
+	}
+
+	// An open event stream must not hold up shutdown
+	stream, err := client.StreamEvents(callCtx, &pb.StreamEventsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Header(); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serveGRPC() = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveGRPC did not return after cancellation")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ElodinLaarz/dashboard/pkg/logfile"
+)
+
+// newLogger builds the application logger writing to w. level is one of
+// debug, info, warn, or error, and format is text or json.
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: must be debug, info, warn, or error", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q: must be text or json", format)
+	}
+}
+
+// reopenOnHangup reopens f on every SIGHUP until ctx is done, so a tool such
+// as logrotate can move the file away and signal the server to start a new
+// one. The signal is caught from the moment it returns.
+func reopenOnHangup(ctx context.Context, f *logfile.File, logger *slog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				if err := f.Reopen(); err != nil {
+					// The logger may be what failed
+					fmt.Fprintf(os.Stderr, "reopen log file: %v\n", err)
+					continue
+				}
+				logger.Info("log file reopened")
+			}
+		}
+	}()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/logfile"
+)
+
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, "warn", "json")
+	if err != nil {
+		t.Fatalf("newLogger() error = %v", err)
+	}
+
+	logger.Info("dropped")
+	logger.Warn("kept", "count", 3)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d log lines, want 1: %q", len(lines), buf.String())
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if rec["msg"] != "kept" || rec["count"] != float64(3) || rec["level"] != "WARN" {
+		t.Errorf("record = %v, want msg=kept count=3 level=WARN", rec)
+	}
+}
+
+func TestNewLogger_Invalid(t *testing.T) {
+	if _, err := newLogger(&bytes.Buffer{}, "loud", "text"); err == nil {
+		t.Error("newLogger() accepted an invalid level")
+	}
+	if _, err := newLogger(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("newLogger() accepted an invalid format")
+	}
+}
+
+func TestReopenOnHangup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dashboard.log")
+	f, err := logfile.Open(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	logger, _ := newLogger(f, "info", "text")
+	reopenOnHangup(t.Context(), f, logger)
+
+	logger.Info("before")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if data, _ := os.ReadFile(path); strings.Contains(string(data), "log file reopened") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the log file was not reopened after SIGHUP")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if data, _ := os.ReadFile(path + ".1"); !strings.Contains(string(data), "before") {
+		t.Errorf("moved log file = %q, want the earlier record", data)
+	}
+}
@@ -1,201 +1,307 @@
 package main
 
 import (
-	"embed"
-	"html/template"
-	"io/fs"
-	"log"
-	"net/http"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
-	"unicode"
+	"syscall"
+	"time"
 
+	"github.com/ElodinLaarz/dashboard/pkg/audit"
+	"github.com/ElodinLaarz/dashboard/pkg/buildinfo"
+	"github.com/ElodinLaarz/dashboard/pkg/cli"
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/format"
 	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/logfile"
+	"github.com/ElodinLaarz/dashboard/pkg/server"
+	"github.com/ElodinLaarz/dashboard/pkg/source"
 )
 
-// formatTitle converts a string to title case (e.g., "hello world" -> "Hello World")
-func formatTitle(s string) string {
-	if s == "" {
-		return s
-	}
-	r := []rune(s)
-	r[0] = unicode.ToUpper(r[0])
-	for i := 1; i < len(r); i++ {
-		r[i] = unicode.ToLower(r[i])
-	}
-	return string(r)
-}
-
-// logRequest logs HTTP requests
-func logRequest(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("%s %s %s", r.RemoteAddr, r.Method, r.URL)
-		handler.ServeHTTP(w, r)
-	})
-}
+// shutdownTimeout bounds how long the gRPC server and the trace exporter may
+// take to finish once the server is asked to stop
+const shutdownTimeout = 10 * time.Second
 
-//go:embed templates/* static/*
-var embedFS embed.FS
+// devTemplatesDir and devStaticDir are where --dev re-reads the templates
+// and static files from, relative to the root of a checkout
+const (
+	devTemplatesDir = "pkg/server/templates"
+	devStaticDir    = "pkg/server/static"
+)
 
-var store *itemstore.ItemStore
+// usage lists the subcommands
+const usage = `usage: dashboard [serve] [flags]
+       dashboard validate [--json] <file>
+       dashboard export [--format=csv|json] [--filter property=value]... <file>
+       dashboard import [--on-conflict=fail|skip|overwrite|renumber] --into <store-file> <new-items.json>
+       dashboard selfcheck [flags]
+`
 
-func init() {
-	// Initialize the item store with sample data
-	sampleItems := []itemstore.Item{
-		{ID: 1, Color: "red", Shape: "circle", Category: "A"},
-		{ID: 2, Color: "blue", Shape: "square", Category: "A"},
-		{ID: 3, Color: "green", Shape: "triangle", Category: "B"},
-		{ID: 4, Color: "red", Shape: "square", Category: "B"},
-		{ID: 5, Color: "blue", Shape: "circle", Category: "C"},
-		{ID: 6, Color: "green", Shape: "square", Category: "C"},
+func main() {
+	// Without a subcommand the server starts, as it did before there were any
+	args := os.Args[1:]
+	command := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
 	}
 
-	var err error
-	store, err = itemstore.New(sampleItems)
-	if err != nil {
-		log.Fatalf("Failed to initialize item store: %v", err)
+	switch command {
+	case "serve":
+		serve(args)
+	case "validate":
+		os.Exit(cli.RunValidate(args, os.Stdout, os.Stderr))
+	case "export":
+		os.Exit(cli.RunExport(args, os.Stdout, os.Stderr))
+	case "import":
+		os.Exit(cli.RunImport(args, os.Stdout, os.Stderr))
+	case "selfcheck":
+		os.Exit(runSelfCheck(args, os.Stdout, os.Stderr))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n%s", command, usage)
+		os.Exit(cli.ExitUsage)
 	}
 }
 
-// groupItems groups items by the specified property
-func groupItems(items []itemstore.Item, groupBy string) map[string][]itemstore.Item {
-	grouped := make(map[string][]itemstore.Item)
+// serve runs the server configured by args until SIGINT or SIGTERM
+func serve(args []string) {
+	cfg, err := config.Load(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(cli.ExitUsage)
+	}
 
-	switch groupBy {
-	case "color":
-		for _, item := range items {
-			grouped[item.Color] = append(grouped[item.Color], item)
-		}
-	case "shape":
-		for _, item := range items {
-			grouped[item.Shape] = append(grouped[item.Shape], item)
+	var logOut io.Writer = os.Stderr
+	var logFile *logfile.File
+	if cfg.LogFile != "" {
+		logFile, err = logfile.Open(cfg.LogFile, int64(cfg.LogMaxSize)<<20, cfg.LogMaxBackups)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+			os.Exit(cli.ExitUsage)
 		}
-	case "category":
-		for _, item := range items {
-			grouped[item.Category] = append(grouped[item.Category], item)
-		}
-	default:
-		// If the groupBy property is invalid, return all items in a single group
-		grouped["All"] = items
+		defer logFile.Close()
+		logOut = logFile
 	}
-
-	return grouped
-}
-
-func main() {
-	// Serve static files
-	staticFS, err := fs.Sub(embedFS, "static")
+	logger, err := newLogger(logOut, cfg.LogLevel, cfg.LogFormat)
 	if err != nil {
-		log.Fatalf("Failed to get static directory from embedded filesystem: %v", err)
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(cli.ExitUsage)
 	}
+	logger.Debug("configuration loaded", "config", cfg.String())
+	build := buildinfo.Get()
+	logger.Info("build", "version", build.Version, "commit", build.Commit, "date", build.BuildDate, "go", build.GoVersion)
 
-	// Set up HTTP handlers
-	http.Handle(
-		"/static/",
-		http.StripPrefix(
-			"/static/",
-			http.FileServer(http.FS(staticFS)),
-		),
-	)
-
-	http.HandleFunc("/", indexHandler)
-	http.HandleFunc("/items", itemsHandler)
-
-	// Start the server
-	port := ":8080"
-	log.Printf("Server starting on http://localhost%s", port)
-	log.Fatal(http.ListenAndServe(port, logRequest(http.DefaultServeMux)))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if logFile != nil {
+		reopenOnHangup(ctx, logFile, logger)
+	}
+	if err := run(ctx, cfg, logger); err != nil {
+		logger.Error("server stopped", "error", err)
+		os.Exit(cli.ExitFailure)
+	}
 }
 
-func indexHandler(w http.ResponseWriter, r *http.Request) {
-	http.Redirect(w, r, "/items", http.StatusFound)
-}
+// run builds the store and server and serves until the listener fails or
+// ctx is canceled, which main does on SIGINT or SIGTERM. It first runs the
+// self-check, and fails with every problem it finds.
+func run(ctx context.Context, cfg config.Config, logger *slog.Logger) error {
+	if err := registerFormats(cfg); err != nil {
+		return err
+	}
+	// Handlers read the registry concurrently from here on
+	format.Shapes.Freeze()
 
-func itemsHandler(w http.ResponseWriter, r *http.Request) {
-	// Get filter parameters
-	groupBy := r.URL.Query().Get("groupBy")
-	if groupBy == "" {
-		groupBy = "shape" // Default grouping
+	storeOpts := newStoreOptions(cfg)
+	if err := selfCheck(cfg, storeOpts); err != nil {
+		return fmt.Errorf("self-check failed:\n%w", err)
+	}
+	items, err := loadItems(cfg, logger)
+	if err != nil {
+		return err
 	}
 
-	// Parse filters from URL
-	filters := make(map[string]string)
-	for _, filter := range r.URL.Query()["filter"] {
-		parts := strings.SplitN(filter, ":", 2)
-		if len(parts) == 2 {
-			filters[parts[0]] = parts[1]
+	store, err := itemstore.New(items, storeOpts...)
+	if err != nil {
+		return fmt.Errorf("initialize item store: %w", err)
+	}
+	// The handlers get served stores, which refuse writes in read-only mode
+	// even if a request slips past the server's read-only check. Data file
+	// reloads and source syncs still write to the stores themselves.
+	served := func(store itemstore.Store) itemstore.Store {
+		if cfg.ReadOnly {
+			return itemstore.ReadOnly(store)
 		}
+		return store
 	}
 
-	// For backward compatibility with old format
-	if filterBy := r.URL.Query().Get("filterBy"); filterBy != "" {
-		if filterValue := r.URL.Query().Get("filterValue"); filterValue != "" {
-			filters[filterBy] = filterValue
+	opts := []server.Option{server.WithConfig(cfg), server.WithLogger(logger)}
+	collectionStores := make(map[string]itemstore.Store, len(cfg.Collections))
+	for _, c := range cfg.Collections {
+		loaded, err := itemstore.LoadFile(c.DataFile)
+		if err != nil {
+			return fmt.Errorf("collection %s: %w", c.Name, err)
+		}
+		cstore, err := itemstore.New(loaded, storeOpts...)
+		if err != nil {
+			return fmt.Errorf("collection %s: %w", c.Name, err)
 		}
+		opts = append(opts,
+			server.WithCollection(c.Name, served(cstore)),
+			server.WithReadinessCheck("collection_"+c.Name, fileExists(c.DataFile)))
+		collectionStores[c.Name] = cstore
 	}
 
-	log.Printf("Processing filters: %v", filters)
+	if cfg.Dev {
+		logger.Warn("development mode: templates and static files are re-read from ./" + devTemplatesDir + " and ./" + devStaticDir + " on every request")
+		opts = append(opts, server.WithTemplates(os.DirFS(devTemplatesDir)), server.WithStatic(os.DirFS(devStaticDir)))
+	}
+	if cfg.ReadOnly {
+		logger.Info("read-only mode: changes to the items are refused")
+	}
 
-	// Apply filters
-	filteredItems := store.Filter(filters)
-	log.Printf("Filtered items count: %d", len(filteredItems))
+	if cfg.OTLPEndpoint != "" {
+		tp, err := newTracerProvider(context.Background(), cfg.OTLPEndpoint)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := tp.Shutdown(ctx); err != nil {
+				logger.Warn("pending spans dropped", "error", err)
+			}
+		}()
+		opts = append(opts, server.WithTracerProvider(tp))
+	}
 
-	// Group items by the specified property
-	groupedItems := groupItems(filteredItems, groupBy)
+	var poller *source.Poller
+	if cfg.SourceURL != "" {
+		poller = source.New(cfg.SourceURL, cfg.SourceInterval, store, source.WithLogger(logger))
+		opts = append(opts, server.WithReadinessCheck("source", poller.Check))
+	}
+	if cfg.DataFile != "" {
+		opts = append(opts, server.WithReadinessCheck("data_file", fileExists(cfg.DataFile)))
+	}
 
-	// Get unique values for sidebar
-	uniqueColors := make(map[string]int)
-	uniqueShapes := make(map[string]int)
-	uniqueCategories := make(map[string]int)
+	srv, err := server.New(served(store), opts...)
+	if err != nil {
+		return err
+	}
 
-	for _, color := range store.GetUniqueValues("color") {
-		uniqueColors[color] = len(store.Filter(map[string]string{"color": color}))
+	tlsConfig, err := server.NewTLSConfig(cfg)
+	if err != nil {
+		return err
+	}
+	ln, err := server.Listen(cfg.Addr, cfg.SocketMode)
+	if err != nil {
+		return err
 	}
 
-	for _, shape := range store.GetUniqueValues("shape") {
-		uniqueShapes[shape] = len(store.Filter(map[string]string{"shape": shape}))
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	if poller != nil {
+		go poller.Run(ctx)
+	}
+	if cfg.DataWatchInterval > 0 {
+		if cfg.DataFile != "" {
+			go watchDataFile(ctx, logger, store, cfg.DataFile, cfg.DataWatchInterval)
+		}
+		for _, c := range cfg.Collections {
+			go watchDataFile(ctx, logger, collectionStores[c.Name], c.DataFile, cfg.DataWatchInterval)
+		}
 	}
 
-	for _, category := range store.GetUniqueValues("category") {
-		uniqueCategories[category] = len(store.Filter(map[string]string{"category": category}))
+	grpcErr := make(chan error, 1)
+	if cfg.GRPCAddr != "" {
+		grpcLn, err := server.Listen(cfg.GRPCAddr, cfg.SocketMode)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		logger.Info("grpc server starting", "addr", grpcLn.Addr().String(), "network", grpcLn.Addr().Network())
+		grpcStore := served(store)
+		if log := srv.AuditLog(); log != nil {
+			grpcStore = audit.Store(grpcStore, log, audit.Source{Name: "grpc"})
+		}
+		go func() {
+			err := serveGRPC(ctx, grpcLn, grpcStore, cfg, tlsConfig, logger)
+			if err != nil {
+				// Take the HTTP server down with it
+				stop()
+			}
+			grpcErr <- err
+		}()
+	} else {
+		grpcErr <- nil
 	}
 
-	// Get all items for animation delays
-	allItems := store.Filter(nil)
+	err = srv.Serve(ctx, ln, tlsConfig)
+	stop()
+	if gerr := <-grpcErr; err == nil && gerr != nil {
+		err = fmt.Errorf("grpc: %w", gerr)
+	}
+	return err
+}
 
-	// Prepare template data
-	data := struct {
-		Title           string
-		GroupedItems    map[string][]itemstore.Item
-		GroupBy         string
-		UniqueColors    map[string]int
-		UniqueShapes    map[string]int
-		UniqueCategories map[string]int
-		ActiveFilters   map[string]string
-		AllItems       []itemstore.Item
-	}{
-		Title:           "Dashboard",
-		GroupedItems:    groupedItems,
-		GroupBy:         groupBy,
-		UniqueColors:    uniqueColors,
-		UniqueShapes:    uniqueShapes,
-		UniqueCategories: uniqueCategories,
-		ActiveFilters:   filters,
-		AllItems:       allItems,
+// loadItems returns the items cfg starts the server with: those in the data
+// file, generated ones, or the seed profile's
+func loadItems(cfg config.Config, logger *slog.Logger) ([]itemstore.Item, error) {
+	switch {
+	case cfg.DataFile != "":
+		if path, err := filepath.Abs(cfg.DataFile); err == nil {
+			logger.Info("loading data file", "path", path)
+		}
+		return itemstore.LoadFile(cfg.DataFile)
+	case cfg.Generate > 0:
+		items := itemstore.Generate(cfg.Generate, cfg.GenerateSeed)
+		logger.Info("generated items", "count", len(items), "seed", cfg.GenerateSeed)
+		return items, nil
+	default:
+		items, err := seedItems(cfg.Seed)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("seeded items", "profile", cfg.Seed, "count", len(items))
+		return items, nil
 	}
+}
 
-	// Create a new template with the formatTitle function
-	tmpl := template.New("items.html").Funcs(template.FuncMap{
-		"title": formatTitle,
-	})
+// registerFormats adds the configured acronyms and shapes to the format
+// registries
+func registerFormats(cfg config.Config) error {
+	for _, word := range cfg.Acronyms {
+		format.RegisterAcronym(word)
+	}
+	for _, shape := range cfg.Shapes {
+		if err := format.RegisterShape(shape.Name, shape.DisplayName, shape.Aliases...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Parse the template
-	tmpl, parseErr := tmpl.ParseFS(embedFS, "templates/items.html")
-	if parseErr != nil {
-		http.Error(w, parseErr.Error(), http.StatusInternalServerError)
-		return
+// newStoreOptions returns the options every store is built with
+func newStoreOptions(cfg config.Config) []itemstore.Option {
+	opts := []itemstore.Option{itemstore.WithIndexes(cfg.IndexedProperties...)}
+	if cfg.KnownShapesOnly {
+		opts = append(opts, itemstore.WithKnownShapes(format.Shapes))
+	}
+	if cfg.StringIDs {
+		opts = append(opts, itemstore.WithStringIDs())
 	}
+	return opts
+}
 
-	if err := tmpl.Execute(w, data); err != nil {
-		http.Error(w, "Error executing template: "+err.Error(), http.StatusInternalServerError)
+// fileExists returns a readiness check that fails while path is missing
+func fileExists(path string) func(context.Context) error {
+	return func(context.Context) error {
+		_, err := os.Stat(path)
+		return err
 	}
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// newTestStore returns a store of three items
+func newTestStore(t testing.TB) *itemstore.ItemStore {
+	t.Helper()
+	store, err := itemstore.New([]itemstore.Item{
+		{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"},
+		{ID: itemstore.IntID(2), Color: "blue", Shape: "square", Category: "A"},
+		{ID: itemstore.IntID(3), Color: "green", Shape: "triangle", Category: "B"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	return store
+}
+
+// logRecords decodes the JSON log records in buf
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for dec.More() {
+		var rec map[string]any
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatalf("Failed to decode log record: %v", err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
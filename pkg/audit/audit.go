@@ -0,0 +1,241 @@
+// Package audit records who changed which item, when, and how. Entries are
+// kept in a fixed-size ring buffer, newest evicting oldest, and optionally
+// appended to a file of JSON lines that is read back at startup.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// Entry is one recorded mutation. Before is nil for a creation and After is
+// nil for a deletion.
+type Entry struct {
+	// Seq numbers the entries in the order they were recorded, from 1
+	Seq    uint64               `json:"seq"`
+	Time   time.Time            `json:"time"`
+	Action itemstore.ChangeType `json:"action"`
+	ItemID itemstore.ID         `json:"itemId"`
+	// Collection names the collection the item is in; empty for the
+	// default store
+	Collection string `json:"collection,omitempty"`
+	// Source is who made the change, e.g. "form" or "api-key:1a2b3c4d"
+	Source    string          `json:"source"`
+	RequestID string          `json:"requestId,omitempty"`
+	Before    *itemstore.Item `json:"before,omitempty"`
+	After     *itemstore.Item `json:"after,omitempty"`
+	// Undoes is the Seq of the change this entry reverted, if it was made by
+	// Undo
+	Undoes uint64 `json:"undoes,omitempty"`
+}
+
+// Query selects entries. Zero fields select everything.
+type Query struct {
+	// Limit caps the number of entries returned
+	Limit  int
+	ItemID itemstore.ID
+	// Collection is matched exactly, so "" selects the default store only
+	// unless AnyCollection is set
+	Collection    string
+	AnyCollection bool
+	// Since excludes entries recorded before it
+	Since time.Time
+}
+
+// Log is a bounded audit log. It is safe for concurrent use.
+type Log struct {
+	size   int
+	path   string
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu sync.Mutex
+	// entries is a ring of up to size entries; the oldest is at start
+	entries []Entry
+	start   int
+	seq     uint64
+	file    *os.File
+
+	// writes serializes the writes of audited stores, so the item an entry
+	// shows as before a change is the one the change replaced
+	writes sync.Mutex
+}
+
+// Option configures a Log
+type Option func(*Log)
+
+// WithFile persists the log to path as JSON lines. The newest entries in an
+// existing file are loaded when the log is created.
+func WithFile(path string) Option {
+	return func(l *Log) { l.path = path }
+}
+
+// WithLogger sets where failures to persist an entry are reported
+func WithLogger(logger *slog.Logger) Option {
+	return func(l *Log) { l.logger = logger }
+}
+
+// WithClock replaces time.Now for entry timestamps
+func WithClock(now func() time.Time) Option {
+	return func(l *Log) { l.now = now }
+}
+
+// New creates a log that keeps the newest size entries
+func New(size int, opts ...Option) (*Log, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("audit: size must be positive, got %d", size)
+	}
+	l := &Log{size: size, logger: slog.New(slog.DiscardHandler), now: time.Now}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.path != "" {
+		if err := l.load(); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// load reads the entries persisted at l.path, keeping the newest l.size,
+// and opens the file for appending. A file holding more than that is
+// rewritten with only the kept entries, so it does not grow without bound
+// across restarts.
+func (l *Log) load() error {
+	var read int
+	f, err := os.Open(l.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("audit: %w", err)
+	default:
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1<<20)
+		for line := 1; scanner.Scan(); line++ {
+			var e Entry
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				f.Close()
+				return fmt.Errorf("audit: %s:%d: %w", l.path, line, err)
+			}
+			l.add(e)
+			l.seq = max(l.seq, e.Seq)
+			read++
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("audit: %s: %w", l.path, err)
+		}
+	}
+
+	if read > len(l.entries) {
+		if err := l.rewrite(); err != nil {
+			return err
+		}
+	}
+	l.file, err = os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	return nil
+}
+
+// rewrite replaces the file with the entries in memory
+func (l *Log) rewrite() error {
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*")
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, e := range l.ordered() {
+		enc.Encode(e)
+	}
+	if err := errors.Join(w.Flush(), tmp.Chmod(0o600), tmp.Close()); err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	return nil
+}
+
+// add puts e in the ring, evicting the oldest entry when it is full. The
+// caller holds l.mu or has not shared l yet.
+func (l *Log) add(e Entry) {
+	if len(l.entries) < l.size {
+		l.entries = append(l.entries, e)
+		return
+	}
+	l.entries[l.start] = e
+	l.start = (l.start + 1) % l.size
+}
+
+// ordered returns the entries oldest first. The caller holds l.mu or has
+// not shared l yet.
+func (l *Log) ordered() []Entry {
+	return append(l.entries[l.start:len(l.entries):len(l.entries)], l.entries[:l.start]...)
+}
+
+// Record numbers and timestamps e, keeps it, and appends it to the file if
+// there is one. The recorded entry is returned.
+func (l *Log) Record(e Entry) Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	e.Seq = l.seq
+	e.Time = l.now().UTC()
+	l.add(e)
+	if l.file != nil {
+		line, err := json.Marshal(e)
+		if err == nil {
+			_, err = l.file.Write(append(line, '\n'))
+		}
+		if err != nil {
+			l.logger.Warn("failed to persist audit entry", "seq", e.Seq, "error", err)
+		}
+	}
+	return e
+}
+
+// Entries returns the entries q selects, newest first
+func (l *Log) Entries(q Query) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var matches []Entry
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		e := l.entries[(l.start+i)%len(l.entries)]
+		if q.Limit > 0 && len(matches) == q.Limit {
+			break
+		}
+		if !q.Since.IsZero() && e.Time.Before(q.Since) {
+			continue
+		}
+		if (!q.ItemID.IsZero() && e.ItemID != q.ItemID) || (!q.AnyCollection && e.Collection != q.Collection) {
+			continue
+		}
+		matches = append(matches, e)
+	}
+	return matches
+}
+
+// Close closes the file; later entries are only kept in memory
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,283 @@
+package audit
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// testClock returns a clock that advances a minute on every call
+func testClock() func() time.Time {
+	now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+}
+
+func seqs(entries []Entry) []uint64 {
+	var s []uint64
+	for _, e := range entries {
+		s = append(s, e.Seq)
+	}
+	return s
+}
+
+func TestLog_Eviction(t *testing.T) {
+	l, err := New(3, WithClock(testClock()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := 1; id <= 5; id++ {
+		l.Record(Entry{Action: itemstore.ItemCreated, ItemID: itemstore.IntID(id)})
+	}
+	if got := seqs(l.Entries(Query{})); !slices.Equal(got, []uint64{5, 4, 3}) {
+		t.Errorf("Entries() = %v, want the newest three, newest first", got)
+	}
+	if got := seqs(l.Entries(Query{Limit: 2})); !slices.Equal(got, []uint64{5, 4}) {
+		t.Errorf("Entries(Limit: 2) = %v", got)
+	}
+
+	if _, err := New(0); err == nil {
+		t.Error("New(0) succeeded")
+	}
+}
+
+func TestLog_Query(t *testing.T) {
+	l, _ := New(10, WithClock(testClock()))
+	l.Record(Entry{ItemID: itemstore.IntID(1)})                    // 15:01
+	l.Record(Entry{ItemID: itemstore.IntID(2)})                    // 15:02
+	l.Record(Entry{ItemID: itemstore.IntID(1), Collection: "lab"}) // 15:03
+	l.Record(Entry{ItemID: itemstore.IntID(1)})                    // 15:04
+
+	tests := []struct {
+		name string
+		q    Query
+		want []uint64
+	}{
+		{"default store", Query{}, []uint64{4, 2, 1}},
+		{"item", Query{ItemID: itemstore.IntID(1)}, []uint64{4, 1}},
+		{"collection", Query{Collection: "lab"}, []uint64{3}},
+		{"any collection", Query{ItemID: itemstore.IntID(1), AnyCollection: true}, []uint64{4, 3, 1}},
+		{"since", Query{Since: time.Date(2026, 1, 2, 15, 2, 0, 0, time.UTC)}, []uint64{4, 2}},
+	}
+	for _, tt := range tests {
+		if got := seqs(l.Entries(tt.q)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Entries() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLog_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := New(2, WithFile(path), WithClock(testClock()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := 1; id <= 3; id++ {
+		l.Record(Entry{Action: itemstore.ItemDeleted, ItemID: itemstore.IntID(id), Before: &itemstore.Item{ID: itemstore.IntID(id), Color: "red"}})
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := countLines(t, path); n != 3 {
+		t.Fatalf("file has %d lines, want every entry appended", n)
+	}
+
+	// Reloading keeps the newest entries, continues the numbering, and
+	// drops the evicted ones from the file
+	l, err = New(2, WithFile(path), WithClock(testClock()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	entries := l.Entries(Query{})
+	if !slices.Equal(seqs(entries), []uint64{3, 2}) || entries[0].Before == nil || entries[0].Before.Color != "red" {
+		t.Errorf("reloaded entries = %+v, want entries 3 and 2 intact", entries)
+	}
+	if e := l.Record(Entry{ItemID: itemstore.IntID(4)}); e.Seq != 4 {
+		t.Errorf("Record() after reload = seq %d, want 4", e.Seq)
+	}
+	if n := countLines(t, path); n != 3 {
+		t.Errorf("file has %d lines after reload and one entry, want 3", n)
+	}
+
+	os.WriteFile(path, []byte("{not json\n"), 0o600)
+	if _, err := New(2, WithFile(path)); err == nil {
+		t.Error("New() loaded a corrupt file")
+	}
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	n := 0
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		n++
+	}
+	return n
+}
+
+func TestStore(t *testing.T) {
+	base, err := itemstore.New([]itemstore.Item{{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, _ := New(10)
+	store := Store(base, l, Source{Name: "form", RequestID: "req-1", Collection: "lab"})
+
+	added, err := store.Add(itemstore.Item{Color: "blue", Shape: "square", Category: "B"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Update(itemstore.Item{ID: itemstore.IntID(1), Color: "green", Shape: "circle", Category: "A"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(added.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Failed writes are not recorded
+	if err := store.Delete(itemstore.IntID(99)); !errors.Is(err, itemstore.ErrNotFound) {
+		t.Fatalf("Delete(99) = %v", err)
+	}
+	if _, err := store.Update(itemstore.Item{ID: itemstore.IntID(1)}); err == nil {
+		t.Fatal("Update() of an invalid item succeeded")
+	}
+
+	entries := l.Entries(Query{Collection: "lab"})
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3: %+v", len(entries), entries)
+	}
+	del, upd, add := entries[0], entries[1], entries[2]
+	if add.Action != itemstore.ItemCreated || add.Before != nil || add.After == nil || add.After.ID != added.ID {
+		t.Errorf("create entry = %+v", add)
+	}
+	if upd.Action != itemstore.ItemUpdated || upd.Before.Color != "red" || upd.After.Color != "green" {
+		t.Errorf("update entry = %+v, want red before and green after", upd)
+	}
+	if del.Action != itemstore.ItemDeleted || del.After != nil || del.Before.Color != "blue" {
+		t.Errorf("delete entry = %+v", del)
+	}
+	for _, e := range entries {
+		if e.Source != "form" || e.RequestID != "req-1" || e.Collection != "lab" {
+			t.Errorf("entry %d = %+v, want it attributed to the source", e.Seq, e)
+		}
+	}
+}
+
+func TestUndo(t *testing.T) {
+	created := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	base, err := itemstore.New([]itemstore.Item{{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A", CreatedAt: created}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, _ := New(10)
+	source := Source{Name: "api", RequestID: "req-1"}
+	store := Store(base, l, source)
+
+	// Script create → update → delete, keeping the items before each step
+	var states [][]itemstore.Item
+	states = append(states, base.Filter(nil))
+	added, err := store.Add(itemstore.Item{Color: "blue", Shape: "square", Category: "B", CreatedAt: created})
+	if err != nil {
+		t.Fatal(err)
+	}
+	states = append(states, base.Filter(nil))
+	if _, err := store.Update(itemstore.Item{ID: added.ID, Color: "teal", Shape: "square", Category: "B"}); err != nil {
+		t.Fatal(err)
+	}
+	states = append(states, base.Filter(nil))
+	if err := store.Delete(added.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	wantUndone := []itemstore.ChangeType{itemstore.ItemDeleted, itemstore.ItemUpdated, itemstore.ItemCreated}
+	for i, want := range wantUndone {
+		undone, entry, err := l.Undo(base, source, 10)
+		if err != nil {
+			t.Fatalf("undo %d: %v", i+1, err)
+		}
+		if undone.Action != want || entry.Undoes != undone.Seq || entry.Source != "api" {
+			t.Errorf("undo %d reverted %+v as %+v, want the %s", i+1, undone, entry, want)
+		}
+		if d := itemstore.ComputeDiff(states[len(states)-1-i], base.Filter(nil)); !d.Empty() {
+			t.Errorf("after undo %d the items differ from before the %s: %+v", i+1, want, d)
+		}
+	}
+	if _, _, err := l.Undo(base, source, 10); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("fourth undo error = %v, want ErrNothingToUndo", err)
+	}
+	if n := len(l.Entries(Query{})); n != 6 {
+		t.Errorf("log has %d entries, want the 3 changes and 3 undos", n)
+	}
+}
+
+func TestUndo_Limits(t *testing.T) {
+	base, _ := itemstore.New(nil)
+	l, _ := New(10)
+	store := Store(base, l, Source{})
+	for range 3 {
+		store.Add(itemstore.Item{Color: "red", Shape: "circle", Category: "A"})
+	}
+
+	// Only the newest two changes are within reach
+	for range 2 {
+		if _, _, err := l.Undo(base, Source{}, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := l.Undo(base, Source{}, 2); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("undo beyond the depth error = %v, want ErrNothingToUndo", err)
+	}
+	if base.Len() != 1 {
+		t.Errorf("store has %d items, want 1", base.Len())
+	}
+
+	// Undoing the creation of item 1 conflicts once it changed elsewhere
+	base.Update(itemstore.Item{ID: itemstore.IntID(1), Color: "blue", Shape: "circle", Category: "A"})
+	if _, _, err := l.Undo(base, Source{}, 3); !errors.Is(err, ErrUndoConflict) {
+		t.Errorf("undo of a changed item error = %v, want ErrUndoConflict", err)
+	}
+
+	// Undoing a deletion conflicts once the ID is in use again
+	store.Delete(itemstore.IntID(1))
+	base.Add(itemstore.Item{ID: itemstore.IntID(1), Color: "green", Shape: "circle", Category: "A"})
+	if _, _, err := l.Undo(base, Source{}, 3); !errors.Is(err, ErrUndoConflict) {
+		t.Errorf("undo of a deletion whose ID was reused error = %v, want ErrUndoConflict", err)
+	}
+	if item, _ := base.Get(itemstore.IntID(1)); item.Color != "green" {
+		t.Errorf("conflicting undo changed item 1 to %+v", item)
+	}
+	// Other collections have nothing to undo
+	if _, _, err := l.Undo(base, Source{Collection: "lab"}, 3); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("undo in another collection error = %v, want ErrNothingToUndo", err)
+	}
+}
+
+func TestStore_Merge(t *testing.T) {
+	base, _ := itemstore.New([]itemstore.Item{{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"}})
+	l, _ := New(10)
+	store := Store(base, l, Source{Name: "api"})
+	_, err := store.Merge([]itemstore.Item{
+		{ID: itemstore.IntID(1), Color: "blue", Shape: "circle", Category: "A"},
+		{ID: itemstore.IntID(2), Color: "green", Shape: "square", Category: "B"},
+	}, itemstore.MergeOverwrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := l.Entries(Query{})
+	if len(entries) != 2 || entries[1].Action != itemstore.ItemUpdated || entries[1].Before.Color != "red" ||
+		entries[0].Action != itemstore.ItemCreated || entries[0].ItemID != itemstore.IntID(2) {
+		t.Errorf("entries = %+v, want the overwrite of item 1 and the creation of item 2", entries)
+	}
+}
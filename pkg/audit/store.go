@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// Source identifies who is writing through an audited store
+type Source struct {
+	// Name is recorded as Entry.Source
+	Name       string
+	RequestID  string
+	Collection string
+}
+
+// auditedStore records the writes made through the Store it embeds
+type auditedStore struct {
+	itemstore.Store
+	log    *Log
+	source Source
+}
+
+// Store returns a Store that serves the reads of store and records every
+// successful Add, AddAll, Update, UpdateIf, Delete, SetItems, and Merge made
+// through it in log, attributed to source. Audited writes to stores sharing
+// a log are serialized, so each entry's before and after values are exact as
+// long as every writer goes through an audited store.
+func Store(store itemstore.Store, log *Log, source Source) itemstore.Store {
+	return auditedStore{Store: store, log: log, source: source}
+}
+
+// record adds an entry for one change
+func (s auditedStore) record(action itemstore.ChangeType, id itemstore.ID, before, after *itemstore.Item) {
+	s.log.Record(Entry{
+		Action:     action,
+		ItemID:     id,
+		Collection: s.source.Collection,
+		Source:     s.source.Name,
+		RequestID:  s.source.RequestID,
+		Before:     before,
+		After:      after,
+	})
+}
+
+func (s auditedStore) Add(item itemstore.Item) (itemstore.Item, error) {
+	s.log.writes.Lock()
+	defer s.log.writes.Unlock()
+	added, err := s.Store.Add(item)
+	if err == nil {
+		s.record(itemstore.ItemCreated, added.ID, nil, &added)
+	}
+	return added, err
+}
+
+func (s auditedStore) AddAll(items []itemstore.Item) ([]itemstore.Item, error) {
+	s.log.writes.Lock()
+	defer s.log.writes.Unlock()
+	added, err := s.Store.AddAll(items)
+	if err == nil {
+		for _, item := range added {
+			s.record(itemstore.ItemCreated, item.ID, nil, &item)
+		}
+	}
+	return added, err
+}
+
+func (s auditedStore) Update(item itemstore.Item) (itemstore.Item, error) {
+	return s.UpdateIf(item, nil)
+}
+
+func (s auditedStore) UpdateIf(item itemstore.Item, match func(stored itemstore.Item) bool) (itemstore.Item, error) {
+	s.log.writes.Lock()
+	defer s.log.writes.Unlock()
+	before, err := s.Store.Get(item.ID)
+	if err != nil {
+		return itemstore.Item{}, err
+	}
+	updated, err := s.Store.UpdateIf(item, match)
+	if err == nil {
+		s.record(itemstore.ItemUpdated, updated.ID, &before, &updated)
+	}
+	return updated, err
+}
+
+func (s auditedStore) Delete(id itemstore.ID) error {
+	s.log.writes.Lock()
+	defer s.log.writes.Unlock()
+	before, err := s.Store.Get(id)
+	if err != nil {
+		return err
+	}
+	err = s.Store.Delete(id)
+	if err == nil {
+		s.record(itemstore.ItemDeleted, before.ID, &before, nil)
+	}
+	return err
+}
+
+func (s auditedStore) SetItems(items []itemstore.Item) (itemstore.Diff, error) {
+	s.log.writes.Lock()
+	defer s.log.writes.Unlock()
+	diff, err := s.Store.SetItems(items)
+	if err != nil {
+		return diff, err
+	}
+	for _, item := range diff.Removed {
+		s.record(itemstore.ItemDeleted, item.ID, &item, nil)
+	}
+	for _, c := range diff.Changed {
+		s.record(itemstore.ItemUpdated, c.After.ID, &c.Before, &c.After)
+	}
+	for _, item := range diff.Added {
+		s.record(itemstore.ItemCreated, item.ID, nil, &item)
+	}
+	return diff, err
+}
+
+func (s auditedStore) Merge(items []itemstore.Item, strategy itemstore.MergeStrategy) (itemstore.MergeReport, error) {
+	s.log.writes.Lock()
+	defer s.log.writes.Unlock()
+	report, err := s.Store.Merge(items, strategy)
+	if err != nil {
+		return report, err
+	}
+	for _, result := range report.Results {
+		switch result.Outcome {
+		case itemstore.MergeAdded, itemstore.MergeRenumbered:
+			s.record(itemstore.ItemCreated, result.Item.ID, nil, &result.Item)
+		case itemstore.MergeOverwritten:
+			s.record(itemstore.ItemUpdated, result.Item.ID, result.Existing, &result.Item)
+		}
+	}
+	return report, err
+}
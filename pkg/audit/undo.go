@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+var (
+	// ErrNothingToUndo is returned by Undo when every change within reach has
+	// been undone already
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// ErrUndoConflict is returned by Undo when the item was changed by
+	// something else since the change being undone
+	ErrUndoConflict = errors.New("undo conflicts with the current items")
+)
+
+// Undo reverts the newest change recorded for source.Collection that has not
+// been undone yet, looking back over at most depth changes. Undos are not
+// changes that can be undone themselves, so repeated calls walk further back.
+// The reverse change is made to store and recorded attributed to source, with
+// Undoes set. Undo returns the change it reverted and the recorded entry.
+func (l *Log) Undo(store itemstore.Store, source Source, depth int) (undone, entry Entry, err error) {
+	l.writes.Lock()
+	defer l.writes.Unlock()
+
+	undone, ok := l.undoable(source.Collection, depth)
+	if !ok {
+		return Entry{}, Entry{}, ErrNothingToUndo
+	}
+	entry = Entry{
+		ItemID:     undone.ItemID,
+		Collection: source.Collection,
+		Source:     source.Name,
+		RequestID:  source.RequestID,
+		Undoes:     undone.Seq,
+	}
+
+	current, err := store.Get(undone.ItemID)
+	switch {
+	case err != nil && !errors.Is(err, itemstore.ErrNotFound):
+		return Entry{}, Entry{}, err
+	case undone.Action == itemstore.ItemDeleted:
+		if err == nil {
+			return Entry{}, Entry{}, fmt.Errorf("%w: item %s was created again", ErrUndoConflict, undone.ItemID)
+		}
+		restored, err := store.Add(*undone.Before)
+		if err != nil {
+			return Entry{}, Entry{}, err
+		}
+		entry.Action, entry.After = itemstore.ItemCreated, &restored
+	case err != nil:
+		return Entry{}, Entry{}, fmt.Errorf("%w: item %s was deleted", ErrUndoConflict, undone.ItemID)
+	case !sameItem(current, *undone.After):
+		return Entry{}, Entry{}, fmt.Errorf("%w: item %s was changed again", ErrUndoConflict, undone.ItemID)
+	case undone.Action == itemstore.ItemCreated:
+		if err := store.Delete(undone.ItemID); err != nil {
+			return Entry{}, Entry{}, err
+		}
+		entry.Action, entry.Before = itemstore.ItemDeleted, &current
+	default:
+		restored, err := store.Update(*undone.Before)
+		if err != nil {
+			return Entry{}, Entry{}, err
+		}
+		entry.Action, entry.Before, entry.After = itemstore.ItemUpdated, &current, &restored
+	}
+	return undone, l.Record(entry), nil
+}
+
+// undoable returns the newest of the latest depth changes in collection that
+// has not been undone
+func (l *Log) undoable(collection string, depth int) (Entry, bool) {
+	undone := make(map[uint64]bool)
+	for _, e := range l.Entries(Query{Collection: collection}) {
+		if e.Undoes != 0 {
+			undone[e.Undoes] = true
+			continue
+		}
+		if depth == 0 {
+			break
+		}
+		depth--
+		if !undone[e.Seq] {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// sameItem reports whether a and b hold the same values; their creation
+// times may differ in location after a round trip through the file
+func sameItem(a, b itemstore.Item) bool {
+	return a.ID == b.ID && a.Color == b.Color && a.Shape == b.Shape && a.Category == b.Category &&
+		a.CreatedAt.Equal(b.CreatedAt)
+}
@@ -0,0 +1,69 @@
+// Package buildinfo reports which build of the dashboard is running. The
+// version, commit, and date are injected at link time, e.g.
+//
+//	go build -ldflags "-X github.com/ElodinLaarz/dashboard/pkg/buildinfo.Version=v1.2.0 \
+//	  -X github.com/ElodinLaarz/dashboard/pkg/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/ElodinLaarz/dashboard/pkg/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Defaults of the variables below, which identify a development build
+const (
+	devVersion = "dev"
+	unknown    = "unknown"
+)
+
+// Set with -ldflags -X; the defaults identify a development build
+var (
+	Version = devVersion
+	Commit  = unknown
+	Date    = unknown
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build information. Variables left unset fall back to what
+// the Go toolchain embeds in the binary, if anything.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: Date,
+		GoVersion: runtime.Version(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info = withBuildInfo(info, bi)
+	}
+	return info
+}
+
+// withBuildInfo fills the fields of info still at their defaults from bi: the
+// version from the main module's version, as set by go install of a tagged
+// release, and the commit and date from the VCS revision and commit time
+func withBuildInfo(info Info, bi *debug.BuildInfo) Info {
+	if info.Version == devVersion && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	for _, setting := range bi.Settings {
+		if setting.Value == "" {
+			continue
+		}
+		switch {
+		case setting.Key == "vcs.revision" && info.Commit == unknown:
+			info.Commit = setting.Value
+		case setting.Key == "vcs.time" && info.BuildDate == unknown:
+			info.BuildDate = setting.Value
+		}
+	}
+	return info
+}
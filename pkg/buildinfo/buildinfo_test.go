@@ -0,0 +1,58 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	origVersion, origCommit, origDate := Version, Commit, Date
+	t.Cleanup(func() { Version, Commit, Date = origVersion, origCommit, origDate })
+
+	Version, Commit, Date = "v1.2.3", "abc123", "2024-01-02T03:04:05Z"
+	got := Get()
+
+	want := Info{Version: "v1.2.3", Commit: "abc123", BuildDate: "2024-01-02T03:04:05Z", GoVersion: runtime.Version()}
+	if got != want {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGet_Defaults(t *testing.T) {
+	got := Get()
+	if got.Version != "dev" {
+		t.Errorf("Version = %q, want dev", got.Version)
+	}
+	if got.Commit == "" || got.BuildDate == "" || got.GoVersion == "" {
+		t.Errorf("Get() = %+v, want every field populated", got)
+	}
+}
+
+func TestWithBuildInfo(t *testing.T) {
+	bi := &debug.BuildInfo{
+		Main: debug.Module{Version: "v1.4.0"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "def456"},
+			{Key: "vcs.time", Value: "2024-05-06T07:08:09Z"},
+		},
+	}
+
+	got := withBuildInfo(Info{Version: devVersion, Commit: unknown, BuildDate: unknown}, bi)
+	want := Info{Version: "v1.4.0", Commit: "def456", BuildDate: "2024-05-06T07:08:09Z"}
+	if got != want {
+		t.Errorf("withBuildInfo(defaults) = %+v, want %+v", got, want)
+	}
+
+	// Values set with -ldflags win
+	set := Info{Version: "v1.2.3", Commit: "abc123", BuildDate: "2024-01-02T03:04:05Z"}
+	if got := withBuildInfo(set, bi); got != set {
+		t.Errorf("withBuildInfo(set) = %+v, want it unchanged", got)
+	}
+
+	// A build from a checkout has no module version
+	bi.Main.Version = "(devel)"
+	if got := withBuildInfo(Info{Version: devVersion}, bi); got.Version != devVersion {
+		t.Errorf("Version = %q for a (devel) build, want %s", got.Version, devVersion)
+	}
+}
@@ -0,0 +1,45 @@
+// Package cli implements the dashboard's subcommands other than serve. They
+// work on data files directly, with the same loaders and validation rules as
+// the server, so checking or converting data needs no running server.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// Exit codes returned by the Run functions
+const (
+	ExitOK = 0
+	// ExitFailure means the command ran but found a problem, such as an
+	// invalid item or an import conflict
+	ExitFailure = 1
+	// ExitUsage means the command line was wrong
+	ExitUsage = 2
+)
+
+// newFlagSet returns a flag set for the named command that writes errors and
+// usage to stderr
+func newFlagSet(name, usage string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: dashboard %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses args into fs and returns the single file argument that
+// must follow the flags
+func parseArgs(fs *flag.FlagSet, args []string) (string, bool) {
+	if err := fs.Parse(args); err != nil {
+		return "", false
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", false
+	}
+	return fs.Arg(0), true
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// filterFlag collects repeated --filter property=value flags
+type filterFlag map[string]string
+
+func (f filterFlag) String() string {
+	var filters []string
+	for _, property := range slices.Sorted(maps.Keys(f)) {
+		filters = append(filters, property+"="+f[property])
+	}
+	return strings.Join(filters, ",")
+}
+
+func (f filterFlag) Set(s string) error {
+	property, value, ok := strings.Cut(s, "=")
+	if !ok || value == "" {
+		return fmt.Errorf("%q is not in the form property=value", s)
+	}
+	if !itemstore.IsProperty(property) {
+		return fmt.Errorf("unknown property %q", property)
+	}
+	if _, dup := f[property]; dup {
+		return fmt.Errorf("%s is filtered more than once", property)
+	}
+	f[property] = value
+	return nil
+}
+
+// RunExport implements "dashboard export [--format=csv|json] [--filter
+// property=value]... <file>". It writes the file's items to stdout in the
+// chosen format, keeping only those that match every filter. Filters match
+// the way /items filters do, so "--filter color=Grey" finds gray items.
+func RunExport(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("export", "[--format=csv|json] [--filter property=value]... <file>", stderr)
+	format := fs.String("format", "json", "output format: csv or json")
+	filters := make(filterFlag)
+	fs.Var(filters, "filter", "keep only items whose property has this value, e.g. color=red; may be repeated")
+	path, ok := parseArgs(fs, args)
+	if !ok {
+		return ExitUsage
+	}
+	var write func(io.Writer, []itemstore.Item) error
+	switch *format {
+	case "csv":
+		write = itemstore.WriteCSV
+	case "json":
+		write = itemstore.WriteJSON
+	default:
+		fmt.Fprintf(stderr, "--format must be csv or json, got %q\n", *format)
+		return ExitUsage
+	}
+
+	items, err := itemstore.LoadFile(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	store, err := itemstore.New(items)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	if err := write(stdout, store.Filter(filters)); err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	return ExitOK
+}
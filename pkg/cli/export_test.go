@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+const exportItems = `[
+	{"id":1,"color":"red","shape":"circle","category":"A"},
+	{"id":2,"color":"Grey","shape":"square","category":"A"},
+	{"id":3,"color":"red","shape":"square","category":"B"}
+]`
+
+func TestRunExport(t *testing.T) {
+	path := writeFile(t, "items.json", exportItems)
+
+	code, stdout, stderr := runCommand(RunExport, "--format=csv", "--filter", "color=red", "--filter", "shape=square", path)
+	if want := "id,color,shape,category,createdAt\n3,red,square,B,\n"; code != ExitOK || stdout != want {
+		t.Errorf("RunExport(csv) = %d, %q (%s); want %q", code, stdout, stderr, want)
+	}
+
+	// JSON is the default, and filters match the way /items does
+	code, stdout, _ = runCommand(RunExport, "--filter", "color=gray", path)
+	items, err := itemstore.LoadJSON(strings.NewReader(stdout))
+	if code != ExitOK || err != nil || len(items) != 1 || items[0].ID != itemstore.IntID(2) || items[0].Color != "gray" {
+		t.Errorf("RunExport(json) = %d, %+v, %v; want item 2", code, items, err)
+	}
+
+	for _, args := range [][]string{
+		{"--format=xml", path},
+		{"--filter", "colour=red", path},
+		{"--filter", "color", path},
+		{"--filter", "color=red", "--filter", "color=blue", path},
+		{},
+	} {
+		if code, _, _ := runCommand(RunExport, args...); code != ExitUsage {
+			t.Errorf("RunExport(%q) = %d, want %d", args, code, ExitUsage)
+		}
+	}
+	bad := writeFile(t, "bad.json", `[{"id":1}]`)
+	if code, _, stderr := runCommand(RunExport, bad); code != ExitFailure || !strings.Contains(stderr, bad+":1:2:") {
+		t.Errorf("RunExport(bad) = %d, %q; want the invalid item's position", code, stderr)
+	}
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// conflictReplace is the former name of --on-conflict=overwrite, still
+// accepted
+const conflictReplace = "replace"
+
+// RunImport implements "dashboard import
+// [--on-conflict=fail|skip|overwrite|renumber] --into <store-file>
+// <new-items.json>". It merges the new items into the store file, which is
+// created if it does not exist. An incoming item whose ID is taken by an
+// identical item is left alone; one whose ID is taken by a different item is
+// a conflict, and every conflict is reported. By default any conflict fails
+// the import without writing anything; --on-conflict=skip keeps the stored
+// items, overwrite takes the incoming ones, and renumber adds the incoming
+// ones under new IDs.
+func RunImport(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("import", "[--on-conflict=fail|skip|overwrite|renumber] --into <store-file> <new-items.json>", stderr)
+	into := flags.String("into", "", "data file to merge the new items into")
+	onConflict := flags.String("on-conflict", itemstore.MergeFail.String(), "what to do with an item whose ID is taken by a different item: fail, skip, overwrite, or renumber")
+	path, ok := parseArgs(flags, args)
+	if !ok {
+		return ExitUsage
+	}
+	if *into == "" {
+		fmt.Fprintln(stderr, "--into is required")
+		return ExitUsage
+	}
+	if *onConflict == conflictReplace {
+		*onConflict = itemstore.MergeOverwrite.String()
+	}
+	strategy, err := itemstore.ParseMergeStrategy(*onConflict)
+	if err != nil {
+		fmt.Fprintf(stderr, "--on-conflict must be fail, skip, overwrite, or renumber, got %q\n", *onConflict)
+		return ExitUsage
+	}
+
+	existing, err := itemstore.LoadFile(*into)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	incoming, err := itemstore.LoadFile(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	store, err := itemstore.New(existing)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", *into, err)
+		return ExitFailure
+	}
+
+	// Failing merges stop at the first conflict, but every conflict is
+	// reported, so they are found by skipping them; the store is discarded
+	merge := strategy
+	if merge == itemstore.MergeFail {
+		merge = itemstore.MergeSkip
+	}
+	report, err := store.Merge(incoming, merge)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	conflicts := 0
+	for _, result := range report.Results {
+		if result.Existing != nil {
+			conflicts++
+			diffs := differences(*result.Existing, incoming[result.Index].Normalize())
+			fmt.Fprintf(stderr, "conflict: item %s: %s\n", result.Existing.ID, strings.Join(diffs, ", "))
+		}
+	}
+	if strategy == itemstore.MergeFail && conflicts > 0 {
+		fmt.Fprintf(stderr, "%d conflicts; %s was not changed\n", conflicts, *into)
+		return ExitFailure
+	}
+	if err := writeFileAtomic(*into, store.SaveJSON); err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	fmt.Fprintf(stdout, "%s: added %d, unchanged %d, replaced %d, renumbered %d, skipped %d\n", *into,
+		report.Count(itemstore.MergeAdded), report.Count(itemstore.MergeUnchanged), report.Count(itemstore.MergeOverwritten),
+		report.Count(itemstore.MergeRenumbered), report.Count(itemstore.MergeSkipped))
+	return ExitOK
+}
+
+// differences describes each field in which incoming differs from stored as
+// "field stored -> incoming". An incoming item without a creation time takes
+// the stored one, so its absence is no difference.
+func differences(stored, incoming itemstore.Item) []string {
+	var diffs []string
+	for _, f := range []struct{ name, stored, incoming string }{
+		{"color", stored.Color, incoming.Color},
+		{"shape", stored.Shape, incoming.Shape},
+		{"category", stored.Category, incoming.Category},
+	} {
+		if f.stored != f.incoming {
+			diffs = append(diffs, fmt.Sprintf("%s %q -> %q", f.name, f.stored, f.incoming))
+		}
+	}
+	if !incoming.CreatedAt.IsZero() && !incoming.CreatedAt.Equal(stored.CreatedAt) {
+		diffs = append(diffs, fmt.Sprintf("createdAt %s -> %s", stored.CreatedAt.Format(time.RFC3339), incoming.CreatedAt.Format(time.RFC3339)))
+	}
+	return diffs
+}
+
+// writeFileAtomic replaces the file at path with what write produces, via a
+// temporary file in the same directory so a failed write leaves it intact. An
+// existing file keeps its permissions.
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("save %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("save %s: %w", path, err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("save %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("save %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("save %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+const storedItems = `[
+	{"id":1,"color":"red","shape":"circle","category":"A","createdAt":"2026-01-01T00:00:00Z"},
+	{"id":2,"color":"blue","shape":"square","category":"A","createdAt":"2026-01-01T00:00:00Z"}
+]`
+
+// newItems keeps item 1, changes item 2, and adds item 3
+const newItems = `[
+	{"id":1,"color":"Red","shape":"circle","category":"A"},
+	{"id":2,"color":"green","shape":"square","category":"B"},
+	{"id":3,"color":"red","shape":"triangle","category":"C","createdAt":"2026-02-01T00:00:00Z"}
+]`
+
+// loadColors returns the color of each item in the file at path by ID
+func loadColors(t *testing.T, path string) map[int]string {
+	t.Helper()
+	items, err := itemstore.LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	colors := make(map[int]string)
+	for _, item := range items {
+		id, _ := item.ID.Int()
+		colors[id] = item.Color
+	}
+	return colors
+}
+
+func TestRunImport(t *testing.T) {
+	incoming := writeFile(t, "new.json", newItems)
+
+	t.Run("conflicts fail by default", func(t *testing.T) {
+		store := writeFile(t, "store.json", storedItems)
+		code, _, stderr := runCommand(RunImport, "--into", store, incoming)
+		if code != ExitFailure || !strings.Contains(stderr, `conflict: item 2: color "blue" -> "green", category "A" -> "B"`) {
+			t.Errorf("RunImport() = %d, %q; want the conflict reported", code, stderr)
+		}
+		if data, _ := os.ReadFile(store); string(data) != storedItems {
+			t.Errorf("store file changed after a failed import:\n%s", data)
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		store := writeFile(t, "store.json", storedItems)
+		code, stdout, stderr := runCommand(RunImport, "--on-conflict=skip", "--into", store, incoming)
+		if want := store + ": added 1, unchanged 1, replaced 0, renumbered 0, skipped 1\n"; code != ExitOK || stdout != want {
+			t.Errorf("RunImport() = %d, %q (%s); want %q", code, stdout, stderr, want)
+		}
+		if !strings.Contains(stderr, "conflict: item 2") {
+			t.Errorf("stderr = %q, want skipped conflicts reported", stderr)
+		}
+		if got, want := loadColors(t, store), map[int]string{1: "red", 2: "blue", 3: "red"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("stored colors = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		store := writeFile(t, "store.json", storedItems)
+		if code, _, stderr := runCommand(RunImport, "--on-conflict=replace", "--into", store, incoming); code != ExitOK {
+			t.Fatalf("RunImport() = %d: %s", code, stderr)
+		}
+		if got, want := loadColors(t, store), map[int]string{1: "red", 2: "green", 3: "red"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("stored colors = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("renumber", func(t *testing.T) {
+		store := writeFile(t, "store.json", storedItems)
+		code, stdout, stderr := runCommand(RunImport, "--on-conflict=renumber", "--into", store, incoming)
+		if want := store + ": added 1, unchanged 1, replaced 0, renumbered 1, skipped 0\n"; code != ExitOK || stdout != want {
+			t.Errorf("RunImport() = %d, %q (%s); want %q", code, stdout, stderr, want)
+		}
+		if got, want := loadColors(t, store), map[int]string{1: "red", 2: "blue", 3: "red", 4: "green"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("stored colors = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("new store file", func(t *testing.T) {
+		store := filepath.Join(t.TempDir(), "store.json")
+		if code, _, stderr := runCommand(RunImport, "--into", store, incoming); code != ExitOK {
+			t.Fatalf("RunImport() = %d: %s", code, stderr)
+		}
+		if got := loadColors(t, store); len(got) != 3 {
+			t.Errorf("stored colors = %v, want all three items", got)
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		store := writeFile(t, "store.json", storedItems)
+		bad := writeFile(t, "bad.json", `[{"id":4,"color":"red"}]`)
+		if code, _, stderr := runCommand(RunImport, "--into", store, bad); code != ExitFailure || !strings.Contains(stderr, "shape is required") {
+			t.Errorf("RunImport(bad) = %d, %q; want the invalid item reported", code, stderr)
+		}
+		for _, args := range [][]string{
+			{incoming},
+			{"--into", store, "--on-conflict=merge", incoming},
+			{"--into", store},
+		} {
+			if code, _, _ := runCommand(RunImport, args...); code != ExitUsage {
+				t.Errorf("RunImport(%q) = %d, want %d", args, code, ExitUsage)
+			}
+		}
+	})
+}
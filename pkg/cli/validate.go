@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// Problem is one problem validate found in a data file. Field, Value, and
+// Message describe a field of an invalid item; problems with the file as a
+// whole, such as malformed JSON, have only a Message.
+type Problem struct {
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+// RunValidate implements "dashboard validate [--json] <file>". It checks the
+// file with the rules the server loads it with and prints every problem, one
+// per line as "file:line:column: problem", or as a JSON array of Problem with
+// --json. It returns ExitFailure when the file is invalid.
+func RunValidate(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("validate", "[--json] <file>", stderr)
+	asJSON := fs.Bool("json", false, "print problems as a JSON array")
+	path, ok := parseArgs(fs, args)
+	if !ok {
+		return ExitUsage
+	}
+
+	problems, err := validateFile(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	if *asJSON {
+		if problems == nil {
+			problems = []Problem{}
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(problems)
+	} else {
+		for _, p := range problems {
+			fmt.Fprintf(stdout, "%s:%d:%d: %s\n", path, p.Line, p.Column, p.text())
+		}
+	}
+	if len(problems) > 0 {
+		return ExitFailure
+	}
+	if !*asJSON {
+		fmt.Fprintf(stdout, "%s: ok\n", path)
+	}
+	return ExitOK
+}
+
+// text formats p without its position
+func (p Problem) text() string {
+	if p.Field == "" {
+		return p.Message
+	}
+	return p.Field + " " + p.Message
+}
+
+// validateFile returns the problems in the data file at path. The error is
+// for a file that cannot be read at all.
+func validateFile(path string) ([]Problem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	err = itemstore.ValidateJSON(f)
+	if err == nil {
+		return nil, nil
+	}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	var problems []Problem
+	for _, err := range errs {
+		var posErr *itemstore.PositionError
+		if !errors.As(err, &posErr) {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		var fieldErrs itemstore.ValidationErrors
+		if !errors.As(err, &fieldErrs) {
+			problems = append(problems, Problem{Line: posErr.Line, Column: posErr.Column, Message: posErr.Err.Error()})
+			continue
+		}
+		for _, fe := range fieldErrs {
+			problems = append(problems, Problem{Line: posErr.Line, Column: posErr.Column, Field: fe.Field, Value: fe.Value, Message: fe.Message})
+		}
+	}
+	return problems, nil
+}
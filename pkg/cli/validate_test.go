@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunValidate(t *testing.T) {
+	bad := writeFile(t, "bad.json", "[\n"+
+		`  {"id":1,"color":"red","shape":"circle","category":"A"},`+"\n"+
+		`  {"id":2,"color":"","shape":"","category":"B"},`+"\n"+
+		`  {"id":1,"color":"blue","shape":"square","category":"B"}`+"\n]")
+
+	code, stdout, _ := runCommand(RunValidate, bad)
+	want := bad + ":3:3: color is required\n" +
+		bad + ":3:3: shape is required\n" +
+		bad + ":4:3: invalid item at index 2: duplicate item ID: 1\n"
+	if code != ExitFailure || stdout != want {
+		t.Errorf("RunValidate(bad) = %d, %q; want %d, %q", code, stdout, ExitFailure, want)
+	}
+
+	code, stdout, _ = runCommand(RunValidate, "--json", bad)
+	var problems []Problem
+	if err := json.Unmarshal([]byte(stdout), &problems); err != nil {
+		t.Fatalf("--json output %q: %v", stdout, err)
+	}
+	wantProblems := []Problem{
+		{Line: 3, Column: 3, Field: "color", Message: "is required"},
+		{Line: 3, Column: 3, Field: "shape", Message: "is required"},
+		{Line: 4, Column: 3, Message: "invalid item at index 2: duplicate item ID: 1"},
+	}
+	if code != ExitFailure || !reflect.DeepEqual(problems, wantProblems) {
+		t.Errorf("RunValidate(--json bad) = %d, %+v; want %d, %+v", code, problems, ExitFailure, wantProblems)
+	}
+
+	good := writeFile(t, "good.json", `[{"id":1,"color":"red","shape":"circle","category":"A"}]`)
+	if code, stdout, _ := runCommand(RunValidate, good); code != ExitOK || stdout != good+": ok\n" {
+		t.Errorf("RunValidate(good) = %d, %q; want %d", code, stdout, ExitOK)
+	}
+	if code, stdout, _ := runCommand(RunValidate, "--json", good); code != ExitOK || strings.TrimSpace(stdout) != "[]" {
+		t.Errorf("RunValidate(--json good) = %d, %q; want an empty array", code, stdout)
+	}
+
+	malformed := writeFile(t, "malformed.json", "[\n  {\"id\":1,}\n]")
+	if code, stdout, _ := runCommand(RunValidate, malformed); code != ExitFailure || !strings.HasPrefix(stdout, malformed+":2:") {
+		t.Errorf("RunValidate(malformed) = %d, %q; want a position on line 2", code, stdout)
+	}
+	if code, _, stderr := runCommand(RunValidate, malformed+".missing"); code != ExitFailure || stderr == "" {
+		t.Errorf("RunValidate(missing) = %d, %q; want a failure reported", code, stderr)
+	}
+	if code, _, _ := runCommand(RunValidate); code != ExitUsage {
+		t.Errorf("RunValidate() without a file = %d, want %d", code, ExitUsage)
+	}
+}
@@ -0,0 +1,855 @@
+// Package config holds the dashboard's settings and loads them from, in
+// order of precedence, command-line flags, DASHBOARD_* environment variables,
+// a JSON config file, and built-in defaults. It is the only place that reads
+// the server's flags or the environment.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+	"github.com/ElodinLaarz/dashboard/pkg/graphqlapi"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/palette"
+	"github.com/ElodinLaarz/dashboard/pkg/preset"
+	"github.com/ElodinLaarz/dashboard/pkg/webhook"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// EnvPrefix is prepended to the upper-cased flag name to form the environment
+// variable that supplies a flag's value, e.g. --cors-origins reads
+// DASHBOARD_CORS_ORIGINS
+const EnvPrefix = "DASHBOARD_"
+
+// GroupByNone is the group-by value that lists items without grouping them
+const GroupByNone = "none"
+
+// DefaultSeed is the seed profile of the built-in demo items
+const DefaultSeed = "demo"
+
+// GroupFields are the values /items can be grouped by
+var GroupFields = []string{"color", "shape", "category", GroupByNone}
+
+// SortFields are the values /items can be sorted by
+var SortFields = []string{"id", "color", "shape", "category"}
+
+// collectionNamePattern restricts collection names to what can sit in a URL
+// path segment unescaped
+var collectionNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidCollectionName reports whether name can name a collection
+func ValidCollectionName(name string) bool {
+	return collectionNamePattern.MatchString(name)
+}
+
+// Config holds the server settings. Every field is set by a flag, and every
+// flag can also be supplied through the environment or the config file.
+type Config struct {
+	// Addr is a TCP address, or "unix:" followed by a socket path
+	Addr string
+	// BasePath is the URL prefix the dashboard is mounted under, e.g.
+	// "/dashboard", or empty when served from the root
+	BasePath string
+	// GRPCAddr is where the gRPC ItemService listens, in the same form as
+	// Addr; empty disables it
+	GRPCAddr string
+	// SocketMode is the permission set on Unix socket files
+	SocketMode       os.FileMode
+	MaxBodyBytes     int64
+	MaxBulkBodyBytes int64
+	CORS             CORS
+	RateLimit        RateLimit
+	Timeouts         Timeouts
+	// TrustProxyHeaders makes the client IP come from X-Forwarded-For, which
+	// is only safe when a reverse proxy always sets that header
+	TrustProxyHeaders bool
+	// TrustedProxies are the networks of the reverse proxies whose
+	// X-Forwarded-For and X-Forwarded-Proto headers are believed; headers
+	// from any other peer are ignored
+	TrustedProxies []netip.Prefix
+	// APIKeys are accepted for write requests under /api/
+	APIKeys []string
+	// AllowUnauthenticatedWrites permits API writes when no keys are
+	// configured; without it such writes are rejected
+	AllowUnauthenticatedWrites bool
+	// AuthUser and AuthPasswordHash enable HTTP Basic Auth for the whole
+	// dashboard. The hash is a bcrypt hash; the plaintext is never configured.
+	AuthUser         string
+	AuthPasswordHash string
+	// DataFile is a JSON array of items loaded at startup instead of the
+	// sample data, and reloaded every DataWatchInterval when it changes
+	DataFile          string
+	DataWatchInterval time.Duration
+	// Generate, when positive, fills the store with that many generated
+	// items instead of the sample data, drawn from GenerateSeed
+	Generate     int
+	GenerateSeed int64
+	// Seed names the profile of items the store starts with when neither
+	// DataFile nor Generate is set: demo, empty, large, or file:<path>
+	Seed string
+	// Collections are named stores served next to the default one, each
+	// loaded from its own data file
+	Collections []Collection
+	// Views are named pages of items with their query applied, served at
+	// /views/{Name}
+	Views []View
+	// SourceURL, when set, is polled every SourceInterval for a JSON array
+	// of items that replaces the store's contents
+	SourceURL      string
+	SourceInterval time.Duration
+	// Webhooks receive a signed notification for every item mutation
+	Webhooks []webhook.Target
+	// WebhookConcurrency bounds the number of deliveries in flight
+	WebhookConcurrency int
+	// GraphQLMaxDepth and GraphQLMaxComplexity bound the queries accepted
+	// by /graphql
+	GraphQLMaxDepth      int
+	GraphQLMaxComplexity int
+	// Defaults are the grouping, sort, and filters of /items when a
+	// request gives none of its own
+	Defaults ViewDefaults
+	// Acronyms are extra words written in capitals wherever titles are
+	// formatted, e.g. "USB"
+	Acronyms []string
+	// Shapes are registered next to the built-in square, circle, and
+	// triangle, or add aliases to them
+	Shapes []Shape
+	// KnownShapesOnly rejects items whose shape is not registered
+	KnownShapesOnly bool
+	// IndexedProperties are the item properties each store keeps an index
+	// of, so filtering on them does not scan every item
+	IndexedProperties []string
+	// StringIDs keys items by strings, e.g. the UUIDs of an upstream system,
+	// instead of numbering them
+	StringIDs bool
+	// ViewCacheSize is the number of /items pages whose groups and sidebar
+	// are kept for reuse until the store changes; 0 disables the cache
+	ViewCacheSize int
+	// Palette maps color names to "#rrggbb" values, replacing or adding to
+	// the built-in CSS colors
+	Palette map[string]string
+	// ShareTTL is how long a short link from /api/share keeps working
+	ShareTTL time.Duration
+	// StateSecret signs the cookie that remembers each visitor's view of
+	// /items; a random secret is used when empty
+	StateSecret string
+	// AuditSize is the number of item changes kept in the audit log; 0
+	// disables it
+	AuditSize int
+	// AuditFile persists the audit log as JSON lines; empty keeps it in
+	// memory only
+	AuditFile string
+	// UndoDepth is how many of the latest changes POST /api/undo can revert;
+	// 0 disables it
+	UndoDepth int
+	// ReadOnly refuses every change to the items over HTTP, GraphQL, and
+	// gRPC; data files and sources still update the stores
+	ReadOnly bool
+	// Dev re-reads templates and static files from disk on every request
+	// instead of using the embedded copies
+	Dev bool
+	// OTLPEndpoint is the OTLP/HTTP collector that request and store spans
+	// are exported to; empty disables tracing
+	OTLPEndpoint string
+	// LogLevel is one of debug, info, warn, or error
+	LogLevel string
+	// LogFormat is text or json
+	LogFormat string
+	// LogFile is where logs are written instead of stderr. It is rotated
+	// once it reaches LogMaxSize megabytes, keeping LogMaxBackups backups;
+	// 0 disables rotation or keeps every backup respectively.
+	LogFile       string
+	LogMaxSize    int
+	LogMaxBackups int
+	// TLSCertFile and TLSKeyFile switch the server to HTTPS
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSSelfSigned serves HTTPS with a generated certificate for localhost
+	TLSSelfSigned bool
+}
+
+// Collection is one named collection: its pages are served under /c/{Name}/
+// and its API under /api/c/{Name}/
+type Collection struct {
+	Name     string
+	DataFile string
+}
+
+// View is a named view of the items page: its query, such as
+// "filter=color:red&groupBy=category", is applied under Title
+type View struct {
+	Name  string
+	Title string
+	Query string
+}
+
+// CORS controls cross-origin access to the /api/ routes. With no allowed
+// origins, no CORS headers are sent and browsers enforce same-origin.
+type CORS struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// RateLimit controls the per-client rate limit on the /api/ routes. A zero
+// RPS disables rate limiting.
+type RateLimit struct {
+	RPS   float64
+	Burst int
+}
+
+// Timeouts bound how long a connection or request may take. A zero value
+// disables the corresponding timeout.
+type Timeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+	// Request is the deadline for a whole handler, enforced by the server's
+	// timeout middleware; streaming endpoints are exempt
+	Request time.Duration
+}
+
+// ViewDefaults are the grouping, sort, and filters used by a request that
+// does not give its own
+type ViewDefaults struct {
+	GroupBy    string
+	SortBy     string
+	Descending bool
+	Filters    map[string]string
+}
+
+// Shape is a shape registered at startup
+type Shape struct {
+	Name string
+	// DisplayName is how the shape is shown; empty keeps the registered
+	// name or title-cases a new one
+	DisplayName string
+	Aliases     []string
+}
+
+// TLSEnabled reports whether the server should serve HTTPS
+func (c Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" || c.TLSSelfSigned
+}
+
+// Load reads the configuration from args and the process's environment
+func Load(args []string) (Config, error) {
+	return Parse(args, os.Getenv)
+}
+
+// Default returns the configuration of a run without flags, environment
+// variables, or config file
+func Default() Config {
+	cfg, err := Parse(nil, func(string) string { return "" })
+	if err != nil {
+		panic(fmt.Sprintf("config: invalid defaults: %v", err))
+	}
+	return cfg
+}
+
+// Parse builds the configuration from command-line arguments, falling back
+// to environment variables for flags that were not given explicitly, then to
+// the config file named by --config, then to the defaults. The result is
+// validated, and every problem found is reported together.
+func Parse(args []string, getenv func(string) string) (Config, error) {
+	var (
+		cfg                       Config
+		configFile                string
+		origins, methods, headers string
+		apiKeys                   string
+		socketMode                string
+		webhookURLs, webhookKeys  string
+		defaultSort, defaultFilt  string
+		paletteEntries            string
+		acronyms                  string
+		shapes, shapeAliases      string
+		indexed                   string
+		collections               string
+		views                     string
+		trustedProxies            string
+	)
+
+	fs := flag.NewFlagSet("dashboard", flag.ContinueOnError)
+	fs.StringVar(&configFile, "config", "", "JSON file of settings keyed by flag name; flags and environment variables take precedence over it")
+	fs.StringVar(&cfg.Addr, "addr", ":8080", `address to listen on; "unix:/path/to.sock" listens on a Unix domain socket`)
+	fs.StringVar(&cfg.BasePath, "base-path", "", `URL prefix the dashboard is served under, e.g. "/dashboard"`)
+	fs.StringVar(&cfg.GRPCAddr, "grpc-addr", "", "address for the gRPC ItemService; empty disables it")
+	fs.StringVar(&socketMode, "socket-mode", "0660", "octal permissions for the Unix socket file")
+	fs.Int64Var(&cfg.MaxBodyBytes, "max-body-bytes", 1<<20, "maximum size of a JSON request body in bytes")
+	fs.Int64Var(&cfg.MaxBulkBodyBytes, "max-bulk-body-bytes", 32<<20, "maximum size of a bulk import request body in bytes")
+	fs.StringVar(&origins, "cors-origins", "", `comma-separated origins allowed to call /api/ ("*" for any); empty disables CORS`)
+	fs.StringVar(&methods, "cors-methods", "GET,POST,PUT,PATCH,DELETE", "comma-separated methods allowed in CORS requests")
+	fs.StringVar(&headers, "cors-headers", "Content-Type,Authorization", "comma-separated request headers allowed in CORS requests")
+	fs.BoolVar(&cfg.CORS.AllowCredentials, "cors-credentials", false, "allow credentialed CORS requests")
+	fs.DurationVar(&cfg.CORS.MaxAge, "cors-max-age", 10*time.Minute, "how long browsers may cache a CORS preflight response")
+	fs.Float64Var(&cfg.RateLimit.RPS, "rate-limit-rps", 10, "requests per second allowed per client on /api/ (0 disables)")
+	fs.IntVar(&cfg.RateLimit.Burst, "rate-limit-burst", 20, "burst size allowed per client on /api/")
+	fs.DurationVar(&cfg.Timeouts.ReadHeader, "read-header-timeout", 5*time.Second, "maximum time to read request headers")
+	fs.DurationVar(&cfg.Timeouts.Read, "read-timeout", 30*time.Second, "maximum time to read a whole request, including the body")
+	fs.DurationVar(&cfg.Timeouts.Write, "write-timeout", 60*time.Second, "maximum time to write a response")
+	fs.DurationVar(&cfg.Timeouts.Idle, "idle-timeout", 120*time.Second, "how long keep-alive connections may sit idle")
+	fs.DurationVar(&cfg.Timeouts.Request, "request-timeout", 30*time.Second, "maximum time a handler may run before it is cut off with 503 (0 disables)")
+	fs.BoolVar(&cfg.TrustProxyHeaders, "trust-proxy-headers", false, "take the client IP from X-Forwarded-For")
+	fs.StringVar(&trustedProxies, "trusted-proxies", "", "comma-separated CIDRs of reverse proxies whose X-Forwarded-For and X-Forwarded-Proto are trusted")
+	fs.StringVar(&apiKeys, "api-keys", "", "comma-separated API keys accepted for writes under /api/")
+	fs.BoolVar(&cfg.AllowUnauthenticatedWrites, "allow-unauthenticated-writes", false, "allow API writes without a key when no keys are configured")
+	fs.StringVar(&cfg.AuthUser, "auth-user", "", "username for HTTP Basic Auth on every route (requires --auth-password-hash)")
+	fs.StringVar(&cfg.AuthPasswordHash, "auth-password-hash", "", "bcrypt hash of the HTTP Basic Auth password")
+	fs.StringVar(&cfg.DataFile, "data", "", "JSON file of items to serve instead of the sample data")
+	fs.IntVar(&cfg.Generate, "generate", 0, "serve this many generated items instead of the sample data, for load testing")
+	fs.Int64Var(&cfg.GenerateSeed, "generate-seed", 1, "seed for --generate; the same seed always generates the same items")
+	fs.StringVar(&cfg.Seed, "seed", DefaultSeed, "items to start with: demo, empty, large, or file:<path>")
+	fs.DurationVar(&cfg.DataWatchInterval, "data-watch-interval", time.Second, "how often to check --data for changes (0 disables reloading)")
+	fs.StringVar(&collections, "collections", "", `comma-separated named collections and their data files, e.g. "inventory=inventory.json,samples=samples.json"`)
+	fs.StringVar(&views, "views", "", `comma-separated named views of the items page and their queries, e.g. "reds=title=Red+items&filter=color:red&groupBy=category"`)
+	fs.IntVar(&cfg.ViewCacheSize, "view-cache-size", 256, "number of /items pages whose groups and sidebar are cached until the store changes; 0 disables the cache")
+	fs.BoolVar(&cfg.StringIDs, "string-ids", false, "key items by string IDs, e.g. UUIDs, instead of numbers; new items get a random UUID")
+	fs.StringVar(&indexed, "index", "", `comma-separated item properties to index for faster filtering of large datasets, e.g. "color,category"`)
+	fs.StringVar(&cfg.SourceURL, "source-url", "", "URL of a JSON array of items to sync the store from")
+	fs.DurationVar(&cfg.SourceInterval, "source-interval", time.Minute, "how often to poll --source-url")
+	fs.StringVar(&webhookURLs, "webhook-urls", "", "comma-separated URLs notified of every item change")
+	fs.StringVar(&webhookKeys, "webhook-secrets", "", "comma-separated HMAC secrets, one per --webhook-urls entry")
+	fs.IntVar(&cfg.WebhookConcurrency, "webhook-concurrency", 4, "maximum webhook deliveries in flight")
+	fs.IntVar(&cfg.GraphQLMaxDepth, "graphql-max-depth", graphqlapi.DefaultLimits.MaxDepth, "deepest field nesting accepted by /graphql")
+	fs.IntVar(&cfg.GraphQLMaxComplexity, "graphql-max-complexity", graphqlapi.DefaultLimits.MaxComplexity, "highest estimated query cost accepted by /graphql")
+	fs.StringVar(&cfg.Defaults.GroupBy, "default-group-by", "shape", "grouping of /items when a request has no groupBy: color, shape, category, or none")
+	fs.StringVar(&defaultSort, "default-sort", "", `sort of /items when a request has no sortBy, e.g. "color" or "color:desc"`)
+	fs.StringVar(&defaultFilt, "default-filters", "", `comma-separated filters applied when a request has none, e.g. "color:red,category:A"`)
+	fs.StringVar(&acronyms, "acronyms", "", `comma-separated words always written in capitals in titles, e.g. "USB,HDMI"; ID and URL always are`)
+	fs.StringVar(&shapes, "shapes", "", `comma-separated shapes to register, each optionally with a display name, e.g. "hexagon,rect=Rectangle"`)
+	fs.StringVar(&shapeAliases, "shape-aliases", "", `comma-separated alternative names of shapes, e.g. "box=square,hex=hexagon"`)
+	fs.BoolVar(&cfg.KnownShapesOnly, "known-shapes-only", false, "reject items whose shape is not built in or registered with --shapes")
+	fs.StringVar(&paletteEntries, "palette", "", `comma-separated color overrides, e.g. "red=#e53935,brand=#0af"`)
+	fs.DurationVar(&cfg.ShareTTL, "share-ttl", 30*24*time.Hour, "how long short links created by /api/share keep working")
+	fs.StringVar(&cfg.StateSecret, "state-secret", "", "secret that signs the saved /items view cookie; empty generates one at startup, so saved views reset on restart")
+	fs.IntVar(&cfg.AuditSize, "audit-size", 1000, "number of item changes kept in the audit log served by /api/audit (0 disables it)")
+	fs.StringVar(&cfg.AuditFile, "audit-file", "", "JSON lines file the audit log is appended to and reloaded from; empty keeps it in memory")
+	fs.IntVar(&cfg.UndoDepth, "undo-depth", 20, "number of the latest item changes POST /api/undo can revert, one per call (0 disables it)")
+	fs.BoolVar(&cfg.ReadOnly, "read-only", false, "refuse every change to the items through the web pages and APIs, e.g. for a public demo")
+	fs.BoolVar(&cfg.Dev, "dev", false, "development mode: re-read templates and static files from ./pkg/server on every request")
+	fs.StringVar(&cfg.OTLPEndpoint, "otlp-endpoint", "", `OTLP/HTTP collector URL for traces, e.g. "http://localhost:4318"; empty disables tracing`)
+	fs.StringVar(&cfg.LogLevel, "log-level", "info", "minimum log level: debug, info, warn, or error")
+	fs.StringVar(&cfg.LogFormat, "log-format", "text", "log output format: text or json")
+	fs.StringVar(&cfg.LogFile, "log-file", "", "file to write logs to instead of stderr; reopened on SIGHUP")
+	fs.IntVar(&cfg.LogMaxSize, "log-max-size", 100, "size in megabytes at which --log-file is rotated (0 disables rotation)")
+	fs.IntVar(&cfg.LogMaxBackups, "log-max-backups", 5, "number of rotated --log-file backups to keep (0 keeps all)")
+	fs.StringVar(&cfg.TLSCertFile, "tls-cert", "", "PEM certificate file; enables HTTPS (requires --tls-key)")
+	fs.StringVar(&cfg.TLSKeyFile, "tls-key", "", "PEM private key file for --tls-cert")
+	fs.BoolVar(&cfg.TLSSelfSigned, "tls-self-signed", false, "serve HTTPS with a generated certificate for localhost (development only)")
+
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	// set records the flags given a value so far; each source only fills
+	// the flags that the sources before it left alone
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var errs []error
+	var fromEnv []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] {
+			return
+		}
+		name := EnvPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if v := getenv(name); v != "" {
+			if err := fs.Set(f.Name, v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid value %q for %s: %w", v, name, err))
+			}
+			fromEnv = append(fromEnv, f.Name)
+		}
+	})
+	for _, name := range fromEnv {
+		set[name] = true
+	}
+	if configFile != "" {
+		errs = append(errs, applyFile(fs, configFile, set)...)
+	}
+	if len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+
+	mode, err := strconv.ParseUint(socketMode, 8, 32)
+	if err != nil || mode > 0o777 {
+		errs = append(errs, fmt.Errorf("--socket-mode must be octal permissions such as 0660, got %q", socketMode))
+	}
+	cfg.SocketMode = os.FileMode(mode)
+	if cfg.BasePath, err = normalizeBasePath(cfg.BasePath); err != nil {
+		errs = append(errs, err)
+	}
+
+	cfg.CORS.AllowedOrigins = splitList(origins)
+	cfg.CORS.AllowedMethods = splitList(methods)
+	cfg.CORS.AllowedHeaders = splitList(headers)
+	cfg.APIKeys = splitList(apiKeys)
+	cfg.Acronyms = splitList(acronyms)
+	cfg.IndexedProperties = splitList(indexed)
+	if cfg.Webhooks, err = parseWebhooks(splitList(webhookURLs), splitList(webhookKeys)); err != nil {
+		errs = append(errs, err)
+	}
+	if err := parseViewDefaults(&cfg.Defaults, defaultSort, splitList(defaultFilt)); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.Shapes, err = parseShapes(splitList(shapes), splitList(shapeAliases)); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.Collections, err = parseCollections(splitList(collections)); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.Views, err = parseViews(splitList(views)); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.Palette, err = parsePalette(splitList(paletteEntries)); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.TrustedProxies, err = parseTrustedProxies(splitList(trustedProxies)); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// applyFile sets the flags named in the JSON object in path that no other
+// source has set. Values may be strings, numbers, booleans, or arrays of
+// strings, which are joined into a comma-separated list.
+func applyFile(fs *flag.FlagSet, path string, set map[string]bool) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("read config file: %w", err)}
+	}
+	var settings map[string]json.RawMessage
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return []error{fmt.Errorf("config file %s: %w", path, err)}
+	}
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(settings)) {
+		if name == "config" || fs.Lookup(name) == nil {
+			errs = append(errs, fmt.Errorf("config file %s: unknown setting %q", path, name))
+			continue
+		}
+		v, err := settingValue(settings[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %s: %w", path, name, err))
+			continue
+		}
+		if set[name] {
+			continue
+		}
+		if err := fs.Set(name, v); err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: invalid value %q for %s: %w", path, v, name, err))
+		}
+	}
+	return errs
+}
+
+// settingValue returns the flag value written as raw in a config file
+func settingValue(raw json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return strings.Join(list, ","), nil
+	}
+	raw = bytes.TrimSpace(raw)
+	var scalar any
+	if err := json.Unmarshal(raw, &scalar); err == nil {
+		switch scalar.(type) {
+		case bool, float64:
+			return string(raw), nil
+		}
+	}
+	return "", errors.New("want a string, number, boolean, or array of strings")
+}
+
+// Validate checks c as a whole and reports every problem it finds, so a
+// misconfigured deployment can be fixed in one pass
+func (c Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.SocketMode&^os.ModePerm != 0 {
+		fail("--socket-mode must be octal permissions such as 0660, got %o", c.SocketMode)
+	}
+	if c.BasePath != "" && (!strings.HasPrefix(c.BasePath, "/") || strings.HasSuffix(c.BasePath, "/") || strings.ContainsAny(c.BasePath, "?#")) {
+		fail("--base-path must be a plain path such as /dashboard, got %q", c.BasePath)
+	}
+	if c.Timeouts.Request > 0 && c.Timeouts.Write > 0 && c.Timeouts.Request >= c.Timeouts.Write {
+		fail("--request-timeout must be shorter than --write-timeout so the timeout response can be written")
+	}
+	if c.ViewCacheSize < 0 {
+		fail("--view-cache-size must not be negative")
+	}
+	if c.AuditSize < 0 {
+		fail("--audit-size must not be negative")
+	}
+	if c.UndoDepth < 0 {
+		fail("--undo-depth must not be negative")
+	}
+	if c.AuditFile != "" && c.AuditSize == 0 {
+		fail("--audit-file needs a positive --audit-size")
+	}
+	if c.GraphQLMaxDepth <= 0 || c.GraphQLMaxComplexity <= 0 {
+		fail("--graphql-max-depth and --graphql-max-complexity must be positive")
+	}
+	if c.ShareTTL <= 0 {
+		fail("--share-ttl must be positive")
+	}
+	if c.TrustProxyHeaders && len(c.TrustedProxies) > 0 {
+		fail("--trust-proxy-headers trusts every peer and cannot be combined with --trusted-proxies")
+	}
+	if c.GRPCAddr != "" && c.GRPCAddr == c.Addr {
+		fail("--grpc-addr must differ from --addr")
+	}
+	if c.DataFile != "" && c.SourceURL != "" {
+		fail("--data and --source-url cannot be combined")
+	}
+	if c.Generate < 0 {
+		fail("--generate must not be negative")
+	}
+	if c.Generate > 0 && (c.DataFile != "" || c.SourceURL != "") {
+		fail("--generate cannot be combined with --data or --source-url")
+	}
+	if c.Seed != DefaultSeed && (c.DataFile != "" || c.Generate > 0) {
+		fail("--seed cannot be combined with --data or --generate")
+	}
+	if c.SourceURL != "" {
+		if !isHTTPURL(c.SourceURL) {
+			fail("--source-url: %q is not an http(s) URL", redactURL(c.SourceURL))
+		}
+		if c.SourceInterval <= 0 {
+			fail("--source-interval must be positive")
+		}
+	}
+	if c.OTLPEndpoint != "" && !isHTTPURL(c.OTLPEndpoint) {
+		fail("--otlp-endpoint: %q is not an http(s) URL", redactURL(c.OTLPEndpoint))
+	}
+	for _, target := range c.Webhooks {
+		if !isHTTPURL(target.URL) {
+			fail("--webhook-urls: %q is not an http(s) URL", redactURL(target.URL))
+		}
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		fail("--tls-cert and --tls-key must be set together")
+	}
+	if c.TLSSelfSigned && c.TLSCertFile != "" {
+		fail("--tls-self-signed cannot be combined with --tls-cert")
+	}
+	if (c.AuthUser == "") != (c.AuthPasswordHash == "") {
+		fail("--auth-user and --auth-password-hash must be set together")
+	}
+	if c.AuthPasswordHash != "" {
+		if _, err := bcrypt.Cost([]byte(c.AuthPasswordHash)); err != nil {
+			fail("--auth-password-hash is not a bcrypt hash: %w", err)
+		}
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		fail("--log-level must be debug, info, warn, or error, got %q", c.LogLevel)
+	}
+	if f := strings.ToLower(c.LogFormat); f != "text" && f != "json" {
+		fail("--log-format must be text or json, got %q", c.LogFormat)
+	}
+	if c.LogMaxSize < 0 || c.LogMaxBackups < 0 {
+		fail("--log-max-size and --log-max-backups must not be negative")
+	}
+
+	for _, property := range c.IndexedProperties {
+		if !itemstore.IsProperty(property) {
+			fail("--index: unknown property %q", property)
+		}
+	}
+	if !slices.Contains(GroupFields, c.Defaults.GroupBy) {
+		fail("--default-group-by: unknown property %q (supported: %s)", c.Defaults.GroupBy, strings.Join(GroupFields, ", "))
+	}
+	if c.Defaults.SortBy != "" && !slices.Contains(SortFields, c.Defaults.SortBy) {
+		fail("--default-sort: unknown field %q (supported: %s)", c.Defaults.SortBy, strings.Join(SortFields, ", "))
+	}
+	for _, property := range slices.Sorted(maps.Keys(c.Defaults.Filters)) {
+		if !itemstore.IsProperty(property) {
+			fail("--default-filters: unknown property %q", property)
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, collection := range c.Collections {
+		switch {
+		case !ValidCollectionName(collection.Name):
+			fail("--collections: name %q must be lower-case letters, digits, dashes, and underscores", collection.Name)
+		case names[collection.Name]:
+			fail("--collections: %s is defined more than once", collection.Name)
+		case collection.DataFile == "":
+			fail("--collections: %s has no data file", collection.Name)
+		}
+		names[collection.Name] = true
+	}
+
+	names = make(map[string]bool)
+	for _, view := range c.Views {
+		if err := preset.ValidateName(view.Name); err != nil {
+			fail("--views: %v", err)
+		} else if names[view.Name] {
+			fail("--views: %s is defined more than once", view.Name)
+		}
+		names[view.Name] = true
+	}
+
+	// Register into a scratch registry so conflicts fail here rather than
+	// at startup
+	registry := format.NewShapeRegistry()
+	for _, shape := range c.Shapes {
+		if err := registry.RegisterShape(shape.Name, shape.DisplayName, shape.Aliases...); err != nil {
+			fail("--shapes: %w", err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// redacted replaces a secret in String's output
+const redacted = "REDACTED"
+
+// String formats c for logging with its secrets masked: API keys, the
+// password hash, the state secret, webhook secrets, and credentials in URLs
+func (c Config) String() string {
+	// plain drops the String method so formatting does not recurse
+	type plain Config
+	p := plain(c)
+	if len(c.APIKeys) > 0 {
+		p.APIKeys = make([]string, len(c.APIKeys))
+		for i := range p.APIKeys {
+			p.APIKeys[i] = redacted
+		}
+	}
+	if c.AuthPasswordHash != "" {
+		p.AuthPasswordHash = redacted
+	}
+	if c.StateSecret != "" {
+		p.StateSecret = redacted
+	}
+	if len(c.Webhooks) > 0 {
+		p.Webhooks = make([]webhook.Target, len(c.Webhooks))
+		for i, target := range c.Webhooks {
+			p.Webhooks[i] = webhook.Target{URL: redactURL(target.URL), Secret: redacted}
+		}
+	}
+	p.SourceURL = redactURL(c.SourceURL)
+	p.OTLPEndpoint = redactURL(c.OTLPEndpoint)
+	return fmt.Sprintf("%+v", p)
+}
+
+// redactURL masks the password in raw if it parses as a URL with one
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.Redacted()
+}
+
+// isHTTPURL reports whether raw is an absolute http or https URL
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// normalizeBasePath returns p with a leading slash and without a trailing one,
+// so "dashboard/" becomes "/dashboard". The root path normalizes to "".
+func normalizeBasePath(p string) (string, error) {
+	if strings.ContainsAny(p, "?#") {
+		return "", fmt.Errorf("--base-path must be a plain path, got %q", p)
+	}
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return "", nil
+	}
+	return "/" + p, nil
+}
+
+// parseViewDefaults fills in the default sort and filters. Validate checks
+// the properties they name, so a misspelled one fails at startup instead of
+// quietly matching nothing.
+func parseViewDefaults(d *ViewDefaults, sort string, filters []string) error {
+	if sort != "" {
+		field, order, _ := strings.Cut(sort, ":")
+		if order != "" && order != "asc" && order != "desc" {
+			return fmt.Errorf("--default-sort: order must be asc or desc, got %q", order)
+		}
+		d.SortBy, d.Descending = field, order == "desc"
+	}
+
+	for _, f := range filters {
+		property, value, ok := strings.Cut(f, ":")
+		if !ok || value == "" {
+			return fmt.Errorf("--default-filters: %q is not in the form property:value", f)
+		}
+		if _, dup := d.Filters[property]; dup {
+			return fmt.Errorf("--default-filters: %s is filtered more than once", property)
+		}
+		if d.Filters == nil {
+			d.Filters = make(map[string]string)
+		}
+		d.Filters[property] = value
+	}
+	return nil
+}
+
+// parseCollections parses "name=path" collection definitions
+func parseCollections(entries []string) ([]Collection, error) {
+	var collections []Collection
+	for _, entry := range entries {
+		name, path, ok := strings.Cut(entry, "=")
+		name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+		if !ok || path == "" {
+			return nil, fmt.Errorf("--collections: %q is not in the form name=path", entry)
+		}
+		collections = append(collections, Collection{Name: name, DataFile: path})
+	}
+	return collections, nil
+}
+
+// parseViews parses "name=query" view definitions. The title parameter of
+// the query is the view's title, which defaults to its name.
+func parseViews(entries []string) ([]View, error) {
+	var views []View
+	for _, entry := range entries {
+		name, raw, _ := strings.Cut(entry, "=")
+		query, err := url.ParseQuery(raw)
+		if err != nil {
+			return nil, fmt.Errorf("--views: %s: %q is not a valid query string", name, raw)
+		}
+		view := View{Name: strings.TrimSpace(name), Title: query.Get("title")}
+		if view.Title == "" {
+			view.Title = view.Name
+		}
+		query.Del("title")
+		view.Query = query.Encode()
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// parseShapes parses "name[=Display Name]" shapes and "alias=name" aliases
+func parseShapes(shapes, aliases []string) ([]Shape, error) {
+	var configs []Shape
+	index := make(map[string]int)
+	for _, entry := range shapes {
+		name, display, _ := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return nil, fmt.Errorf("--shapes: %q is not in the form name or name=Display Name", entry)
+		}
+		if _, dup := index[name]; dup {
+			return nil, fmt.Errorf("--shapes: %s is given more than once", name)
+		}
+		index[name] = len(configs)
+		configs = append(configs, Shape{Name: name, DisplayName: strings.TrimSpace(display)})
+	}
+	for _, entry := range aliases {
+		alias, name, ok := strings.Cut(entry, "=")
+		alias, name = strings.ToLower(strings.TrimSpace(alias)), strings.ToLower(strings.TrimSpace(name))
+		if !ok || alias == "" || name == "" {
+			return nil, fmt.Errorf("--shape-aliases: %q is not in the form alias=shape", entry)
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(configs)
+			index[name] = i
+			configs = append(configs, Shape{Name: name})
+		}
+		configs[i].Aliases = append(configs[i].Aliases, alias)
+	}
+	return configs, nil
+}
+
+// parsePalette parses "name=#hex" color overrides
+func parsePalette(entries []string) (map[string]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	colors := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		name, value, ok := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" {
+			return nil, fmt.Errorf("--palette: %q is not in the form name=#hex", entry)
+		}
+		hex, err := palette.ParseHex(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("--palette: %s: %w", name, err)
+		}
+		if _, dup := colors[name]; dup {
+			return nil, fmt.Errorf("--palette: %s is given more than once", name)
+		}
+		colors[name] = hex
+	}
+	return colors, nil
+}
+
+// parseTrustedProxies parses CIDRs such as "10.0.0.0/8". A bare address is
+// a network of that address alone.
+func parseTrustedProxies(entries []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(entry)
+			if addrErr != nil {
+				return nil, fmt.Errorf("--trusted-proxies: %q is not a CIDR such as 10.0.0.0/8", entry)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// parseWebhooks pairs each webhook URL with its secret
+func parseWebhooks(urls, secrets []string) ([]webhook.Target, error) {
+	if len(urls) != len(secrets) {
+		return nil, fmt.Errorf("--webhook-urls has %d entries but --webhook-secrets has %d; each URL needs a secret", len(urls), len(secrets))
+	}
+	targets := make([]webhook.Target, 0, len(urls))
+	for i, raw := range urls {
+		targets = append(targets, webhook.Target{URL: raw, Secret: secrets[i]})
+	}
+	return targets, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+// Package events implements an in-process publish/subscribe hub. Publishing
+// never blocks: each subscriber has a bounded buffer, and when it is full the
+// oldest pending event is dropped to make room for the newest.
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Event is a single notification delivered to subscribers
+type Event struct {
+	Type string
+	Data any
+}
+
+// Hub fans published events out to every current subscriber
+type Hub struct {
+	mu     sync.Mutex
+	buffer int
+	subs   map[*Subscriber]struct{}
+}
+
+// Subscriber receives events from a Hub until it is unsubscribed
+type Subscriber struct {
+	ch      chan Event
+	dropped atomic.Int64
+}
+
+// NewHub creates a hub whose subscribers each buffer up to buffer events
+func NewHub(buffer int) *Hub {
+	return &Hub{
+		buffer: max(buffer, 1),
+		subs:   make(map[*Subscriber]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber. Callers must Unsubscribe it when they
+// stop reading.
+func (h *Hub) Subscribe() *Subscriber {
+	sub := &Subscriber{ch: make(chan Event, h.buffer)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subs[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe removes sub from the hub and closes its channel. It is safe to
+// call more than once.
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Publish delivers e to every subscriber without blocking
+func (h *Hub) Publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		sub.send(e)
+	}
+}
+
+// Len returns the number of current subscribers
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// send queues e, discarding the oldest queued event if the buffer is full.
+// The hub lock serializes senders, so after one event is discarded there is
+// always room.
+func (s *Subscriber) send(e Event) {
+	select {
+	case s.ch <- e:
+		return
+	default:
+	}
+
+	select {
+	case <-s.ch:
+		s.dropped.Add(1)
+	default:
+	}
+	select {
+	case s.ch <- e:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Events returns the channel events are delivered on. It is closed when the
+// subscriber is unsubscribed.
+func (s *Subscriber) Events() <-chan Event {
+	return s.ch
+}
+
+// Dropped returns how many events were discarded because the subscriber fell
+// behind
+func (s *Subscriber) Dropped() int64 {
+	return s.dropped.Load()
+}
@@ -0,0 +1,58 @@
+package events
+
+import "testing"
+
+func TestHub_PublishSubscribe(t *testing.T) {
+	hub := NewHub(4)
+	a, b := hub.Subscribe(), hub.Subscribe()
+
+	hub.Publish(Event{Type: "item.created", Data: 1})
+
+	for _, sub := range []*Subscriber{a, b} {
+		select {
+		case e := <-sub.Events():
+			if e.Type != "item.created" || e.Data != 1 {
+				t.Errorf("got %+v, want item.created with data 1", e)
+			}
+		default:
+			t.Error("subscriber did not receive the event")
+		}
+	}
+}
+
+func TestHub_DropOldest(t *testing.T) {
+	hub := NewHub(2)
+	sub := hub.Subscribe()
+
+	for i := range 5 {
+		hub.Publish(Event{Type: "tick", Data: i})
+	}
+
+	if got := sub.Dropped(); got != 3 {
+		t.Errorf("Dropped() = %d, want 3", got)
+	}
+	for _, want := range []int{3, 4} {
+		if e := <-sub.Events(); e.Data != want {
+			t.Errorf("got event %v, want %d", e.Data, want)
+		}
+	}
+}
+
+func TestHub_Unsubscribe(t *testing.T) {
+	hub := NewHub(1)
+	sub := hub.Subscribe()
+	if hub.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", hub.Len())
+	}
+
+	hub.Unsubscribe(sub)
+	hub.Unsubscribe(sub)
+	hub.Publish(Event{Type: "ignored"})
+
+	if hub.Len() != 0 {
+		t.Errorf("Len() = %d after Unsubscribe, want 0", hub.Len())
+	}
+	if _, ok := <-sub.Events(); ok {
+		t.Error("Events() still open after Unsubscribe")
+	}
+}
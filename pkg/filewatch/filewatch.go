@@ -0,0 +1,68 @@
+// Package filewatch detects changes to a file by polling its modification
+// time and size, which avoids platform-specific notification APIs.
+package filewatch
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Watcher polls one file for changes
+type Watcher struct {
+	path     string
+	interval time.Duration
+	debounce time.Duration
+}
+
+// New creates a Watcher that polls path every interval. A change is reported
+// only once the file has stayed the same for debounce, so an editor that
+// writes a file twice in quick succession triggers a single callback.
+func New(path string, interval, debounce time.Duration) *Watcher {
+	return &Watcher{path: path, interval: interval, debounce: debounce}
+}
+
+// fileState is what the watcher compares between polls
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func (w *Watcher) stat() fileState {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
+
+// Run calls onChange after every settled change to the file until ctx is
+// canceled. While the file is missing no callback is made; it fires once the
+// file reappears and settles.
+func (w *Watcher) Run(ctx context.Context, onChange func()) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	last := w.stat()
+	var (
+		pending   bool
+		changedAt time.Time
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			cur := w.stat()
+			if cur != last {
+				last, pending, changedAt = cur, true, now
+				continue
+			}
+			if pending && cur.exists && now.Sub(changedAt) >= w.debounce {
+				pending = false
+				onChange()
+			}
+		}
+	}
+}
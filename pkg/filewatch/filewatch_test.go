@@ -0,0 +1,67 @@
+package filewatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatcher_Debounce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.json")
+	if err := os.WriteFile(path, []byte("[]"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go New(path, 5*time.Millisecond, 50*time.Millisecond).Run(ctx, func() { calls.Add(1) })
+
+	// Give the watcher time to record the initial state
+	time.Sleep(20 * time.Millisecond)
+	if calls.Load() != 0 {
+		t.Fatalf("onChange called %d times before any change", calls.Load())
+	}
+
+	// Two writes in quick succession, as editors often do
+	os.WriteFile(path, []byte(`[{"id":1}]`), 0o600)
+	time.Sleep(15 * time.Millisecond)
+	os.WriteFile(path, []byte(`[{"id":1},{"id":2}]`), 0o600)
+
+	waitFor(t, func() bool { return calls.Load() == 1 })
+	time.Sleep(100 * time.Millisecond)
+	if got := calls.Load(); got != 1 {
+		t.Errorf("onChange called %d times for one burst of writes, want 1", got)
+	}
+}
+
+func TestWatcher_MissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.json")
+
+	var calls atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go New(path, 5*time.Millisecond, 10*time.Millisecond).Run(ctx, func() { calls.Add(1) })
+
+	time.Sleep(30 * time.Millisecond)
+	if calls.Load() != 0 {
+		t.Fatal("onChange called while the file does not exist")
+	}
+
+	os.WriteFile(path, []byte("[]"), 0o600)
+	waitFor(t, func() bool { return calls.Load() == 1 })
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before the deadline")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,85 @@
+package format
+
+import (
+	"strings"
+	"unicode"
+)
+
+// colorWordAliases are spellings of a word in a color name that mean the
+// same as another, e.g. "dark grey" is "dark-gray"
+var colorWordAliases = map[string]string{
+	"grey": "gray",
+}
+
+// colorAliases are whole color names that mean the same as another
+var colorAliases = map[string]string{
+	"aqua":    "cyan",
+	"fuchsia": "magenta",
+}
+
+// colorModifiers are the words that run together with a base color in names
+// such as "lightblue", which is "light-blue"
+var colorModifiers = []string{"light", "dark", "pale", "deep", "medium", "bright"}
+
+// baseColors are the colors a modifier can run together with
+var baseColors = map[string]bool{
+	"black": true, "blue": true, "brown": true, "cyan": true, "gray": true,
+	"green": true, "grey": true, "orange": true, "pink": true, "purple": true,
+	"red": true, "violet": true, "yellow": true,
+}
+
+// ColorKey returns the canonical form of a color name, which items are
+// stored, grouped, and filtered by: lower-case words joined by hyphens, with
+// aliases resolved, e.g. "NAVY BLUE" -> "navy-blue", "Grey" -> "gray",
+// "lightblue" -> "light-blue". Values that are not plain names, such as
+// "#ff0000", are only trimmed.
+func ColorKey(color string) string {
+	color = strings.TrimSpace(color)
+	if strings.ContainsFunc(color, func(r rune) bool {
+		return !unicode.IsLetter(r) && !isColorSeparator(r)
+	}) {
+		return color
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(color), isColorSeparator)
+	if len(words) == 1 {
+		words = splitModifier(words[0])
+	}
+	for i, word := range words {
+		if alias, ok := colorWordAliases[word]; ok {
+			words[i] = alias
+		}
+	}
+	key := strings.Join(words, "-")
+	if alias, ok := colorAliases[key]; ok {
+		return alias
+	}
+	return key
+}
+
+// isColorSeparator reports whether r separates the words of a color name
+func isColorSeparator(r rune) bool {
+	return unicode.IsSpace(r) || r == '-' || r == '_'
+}
+
+// splitModifier splits a name such as "lightblue" into its modifier and base
+// color, and returns any other word on its own
+func splitModifier(word string) []string {
+	for _, modifier := range colorModifiers {
+		if base, ok := strings.CutPrefix(word, modifier); ok && baseColors[base] {
+			return []string{modifier, base}
+		}
+	}
+	return []string{word}
+}
+
+// Color formats a color name for display: the canonical name with its words
+// capitalized and spaced, e.g. "navy-blue" -> "Navy Blue". Values that are
+// not plain names are returned as ColorKey leaves them.
+func Color(color string) string {
+	key := ColorKey(color)
+	if strings.ContainsFunc(key, func(r rune) bool { return !unicode.IsLetter(r) && r != '-' }) {
+		return key
+	}
+	return Title(strings.ReplaceAll(key, "-", " "))
+}
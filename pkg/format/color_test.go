@@ -0,0 +1,64 @@
+package format
+
+import "testing"
+
+func TestColorKey(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "red", "red"},
+		{"upper-case", "RED", "red"},
+		{"surrounding space", "  blue ", "blue"},
+		{"alias", "Grey", "gray"},
+		{"alias in a compound", "dark grey", "dark-gray"},
+		{"whole-name alias", "Aqua", "cyan"},
+		{"compound with a space", "NAVY BLUE", "navy-blue"},
+		{"compound with a hyphen", "light-blue", "light-blue"},
+		{"compound with an underscore", "light_blue", "light-blue"},
+		{"compound with repeated separators", "light -  blue", "light-blue"},
+		{"run-together compound", "lightblue", "light-blue"},
+		{"run-together compound with an alias", "DarkGrey", "dark-gray"},
+		{"modifier prefix of an unknown word", "lightning", "lightning"},
+		{"unknown name", "Chartreuse", "chartreuse"},
+		{"unknown compound", "sea foam", "sea-foam"},
+		{"hex value", " #FF0000 ", "#FF0000"},
+		{"function value", "rgb(1, 2, 3)", "rgb(1, 2, 3)"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ColorKey(tt.in); got != tt.want {
+				t.Errorf("ColorKey(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColor(t *testing.T) {
+	tests := map[string]string{
+		"red":          "Red",
+		"navy-blue":    "Navy Blue",
+		"NAVY BLUE":    "Navy Blue",
+		"grey":         "Gray",
+		"lightblue":    "Light Blue",
+		"#ff0000":      "#ff0000",
+		"rgb(1, 2, 3)": "rgb(1, 2, 3)",
+		"":             "",
+	}
+	for in, want := range tests {
+		if got := Color(in); got != want {
+			t.Errorf("Color(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestColorKey_SeedData checks that the sample colors are stored as they are
+func TestColorKey_SeedData(t *testing.T) {
+	for _, color := range []string{"red", "blue", "green"} {
+		if got := ColorKey(color); got != color {
+			t.Errorf("ColorKey(%q) = %q, want it unchanged", color, got)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package format formats item values and counts for display.
+package format
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	acronymsMu sync.RWMutex
+	// acronyms maps the lower-case form of each word Title writes in capitals
+	// to that form
+	acronyms = map[string]string{
+		"id":  "ID",
+		"url": "URL",
+	}
+)
+
+// RegisterAcronym makes Title write word in capitals wherever it appears as
+// a whole word, in any case
+func RegisterAcronym(word string) {
+	acronymsMu.Lock()
+	defer acronymsMu.Unlock()
+	acronyms[strings.ToLower(word)] = strings.ToUpper(word)
+}
+
+// isWordRune reports whether r continues a word: letters, digits, and
+// combining marks
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// isApostrophe reports whether r is an apostrophe, which stays inside a word
+// when it follows a letter, as in "don't"
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’'
+}
+
+// Title capitalizes the first letter of each word of s and lower-cases the
+// rest, e.g. "light-BLUE shade" -> "Light-Blue Shade". Words are runs of
+// letters and digits, plus apostrophes inside them, so "don't" stays one word;
+// everything between words is kept as is. Registered acronyms are written in
+// capitals, e.g. "item id" -> "Item ID". Casing follows the Unicode defaults
+// rather than any one language's rules, except that the first letter is put
+// in title case from its original form, so "İstanbul" keeps its dot.
+func Title(s string) string {
+	acronymsMu.RLock()
+	defer acronymsMu.RUnlock()
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for s != "" {
+		start := strings.IndexFunc(s, isWordRune)
+		if start < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:start])
+		s = s[start:]
+		end := wordEnd(s)
+		b.WriteString(titleWord(s[:end]))
+		s = s[end:]
+	}
+	return b.String()
+}
+
+// wordEnd returns the length of the word at the start of s
+func wordEnd(s string) int {
+	for i, r := range s {
+		if isWordRune(r) {
+			continue
+		}
+		if isApostrophe(r) {
+			// Only an apostrophe followed by more of the word belongs to it
+			next, _ := utf8.DecodeRuneInString(s[i+utf8.RuneLen(r):])
+			if unicode.IsLetter(next) {
+				continue
+			}
+		}
+		return i
+	}
+	return len(s)
+}
+
+// titleWord capitalizes a single word. acronymsMu must be held.
+func titleWord(word string) string {
+	if acronym, ok := acronyms[strings.ToLower(word)]; ok {
+		return acronym
+	}
+	first, size := utf8.DecodeRuneInString(word)
+	return string(unicode.ToTitle(first)) + strings.ToLower(word[size:])
+}
@@ -0,0 +1,94 @@
+package format
+
+import (
+	"strings"
+	"testing"
+)
+
+// registerAcronyms registers words as acronyms for the rest of the test
+func registerAcronyms(t *testing.T, words ...string) {
+	t.Helper()
+	for _, word := range words {
+		RegisterAcronym(word)
+	}
+	t.Cleanup(func() {
+		acronymsMu.Lock()
+		defer acronymsMu.Unlock()
+		for _, word := range words {
+			delete(acronyms, strings.ToLower(word))
+		}
+	})
+}
+
+func TestTitle(t *testing.T) {
+	registerAcronyms(t, "USB")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"single word", "circle", "Circle"},
+		{"lower-cases the rest", "cIRCLE", "Circle"},
+		{"single letter", "a", "A"},
+		{"several words", "hello world", "Hello World"},
+		{"repeated spaces", "hello  world ", "Hello  World "},
+		{"only separators", " - _ ", " - _ "},
+		{"digits", "2nd place", "2nd Place"},
+
+		{"hyphenated", "light-BLUE", "Light-Blue"},
+		{"underscores", "dark_red_tone", "Dark_Red_Tone"},
+		{"leading separator", "-x", "-X"},
+		{"slash", "black/white", "Black/White"},
+		{"parentheses", "shape (old)", "Shape (Old)"},
+
+		{"apostrophe inside a word", "don't stop", "Don't Stop"},
+		{"apostrophe after a name", "o'neil", "O'neil"},
+		{"typographic apostrophe", "it’s FINE", "It’s Fine"},
+		{"leading apostrophe", "'tis", "'Tis"},
+		{"trailing apostrophe", "dogs' toys", "Dogs' Toys"},
+		{"quoted word", "'red'", "'Red'"},
+
+		{"accented letters", "élan émile", "Élan Émile"},
+		{"accented upper-case", "ÇA VA", "Ça Va"},
+		{"greek", "ΑΛΦΑ βήτα", "Αλφα Βήτα"},
+		{"cyrillic", "МОСКВА", "Москва"},
+		{"title-case digraph", "ǆungla", "ǅungla"},
+		{"combining mark stays in the word", "e\u0301cole", "E\u0301cole"},
+		{"no letters to case", "東京 タワー", "東京 タワー"},
+
+		{"dotted capital I keeps its dot", "İstanbul", "İstanbul"},
+		{"dotted capital I lower-cases without a stray dot", "KİLİM", "Kilim"},
+		{"dotless i capitalizes to I", "ıspanak", "Ispanak"},
+		{"capital I lower-cases to i", "IĞDIR", "Iğdir"},
+
+		{"acronym", "id", "ID"},
+		{"acronym in any case", "Url", "URL"},
+		{"acronym among words", "url shorteners", "URL Shorteners"},
+		{"acronym after hyphen", "source-url", "Source-URL"},
+		{"acronym only as a whole word", "idea identity", "Idea Identity"},
+		{"acronym before a hyphen", "USB-c cable", "USB-C Cable"},
+		{"registered acronym in lower case", "usb hub", "USB Hub"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Title(tt.in); got != tt.want {
+				t.Errorf("Title(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterAcronym(t *testing.T) {
+	if got := Title("api key"); got != "Api Key" {
+		t.Fatalf("Title(%q) before RegisterAcronym = %q, want %q", "api key", got, "Api Key")
+	}
+	registerAcronyms(t, "Api")
+	if got := Title("api key"); got != "API Key" {
+		t.Errorf("Title(%q) = %q, want %q", "api key", got, "API Key")
+	}
+	if got := Title("rapid"); got != "Rapid" {
+		t.Errorf("Title(%q) = %q, want %q", "rapid", got, "Rapid")
+	}
+}
@@ -0,0 +1,28 @@
+package format_test
+
+import (
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// TestItemFormat checks that Item.Format formats each field exactly as the
+// format package does when called directly, as the web handlers do
+func TestItemFormat(t *testing.T) {
+	for _, item := range []itemstore.Item{
+		{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"},
+		{ID: itemstore.IntID(2), Color: "Light-BLUE", Shape: "SQUARE", Category: "item id"},
+		{ID: itemstore.IntID(3), Color: "ÉCRU", Shape: "hexagon", Category: "dark_red-tone"},
+	} {
+		want := itemstore.Item{
+			ID:       item.ID,
+			Color:    format.Color(item.Color),
+			Shape:    format.Shape(item.Shape),
+			Category: format.Title(item.Category),
+		}
+		if got := item.Format(); got != want {
+			t.Errorf("%+v.Format() = %+v, want %+v", item, got, want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package format
+
+import (
+	"strconv"
+	"strings"
+)
+
+// numberFormat describes how a locale groups the digits of whole numbers
+type numberFormat struct {
+	// separator goes between groups of digits
+	separator string
+	// minDigits is the fewest digits a number needs before it is grouped
+	// at all, e.g. 5 where "1000" is written without a separator
+	minDigits int
+	// indian groups by two digits above the first thousand, as in
+	// "12,34,567"
+	indian bool
+}
+
+// numberFormats are keyed by lower-case language tag, with regional
+// variants where they differ from their base language
+var numberFormats = map[string]numberFormat{
+	"en":    {separator: ",", minDigits: 4},
+	"en-in": {separator: ",", minDigits: 4, indian: true},
+	"hi":    {separator: ",", minDigits: 4, indian: true},
+	"ja":    {separator: ",", minDigits: 4},
+	"ko":    {separator: ",", minDigits: 4},
+	"zh":    {separator: ",", minDigits: 4},
+	"de":    {separator: ".", minDigits: 4},
+	"de-ch": {separator: "’", minDigits: 4},
+	"it":    {separator: ".", minDigits: 4},
+	"nl":    {separator: ".", minDigits: 4},
+	"pt":    {separator: ".", minDigits: 4},
+	"pt-pt": {separator: "\u00a0", minDigits: 5},
+	"es":    {separator: ".", minDigits: 5},
+	"fr":    {separator: "\u202f", minDigits: 4},
+	"pl":    {separator: "\u00a0", minDigits: 5},
+	"ru":    {separator: "\u00a0", minDigits: 4},
+	"sv":    {separator: "\u00a0", minDigits: 4},
+}
+
+// Number formats n with the digit grouping of locale, e.g. 1234567 is
+// "1,234,567" in "en", "1.234.567" in "de", and "12,34,567" in "hi". Unknown
+// locales are formatted as English.
+func Number(n int, locale string) string {
+	f := lookupLocale(numberFormats, locale, numberFormats["en"])
+	digits := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if len(digits) < f.minDigits {
+		return sign + digits
+	}
+
+	// Split off groups from the right: three digits, then two or three
+	var groups []string
+	size := 3
+	for len(digits) > size {
+		groups = append(groups, digits[len(digits)-size:])
+		digits = digits[:len(digits)-size]
+		if f.indian {
+			size = 2
+		}
+	}
+	groups = append(groups, digits)
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i := len(groups) - 1; i >= 0; i-- {
+		b.WriteString(groups[i])
+		if i > 0 {
+			b.WriteString(f.separator)
+		}
+	}
+	return b.String()
+}
+
+// SupportsLocale reports whether numbers or plurals are formatted specially
+// for locale or its base language, rather than as English
+func SupportsLocale(locale string) bool {
+	if _, ok := lookupLocaleOK(numberFormats, locale); ok {
+		return true
+	}
+	pluralizersMu.RLock()
+	defer pluralizersMu.RUnlock()
+	_, ok := lookupLocaleOK(pluralizers, locale)
+	return ok
+}
+
+// normalizeLocale lower-cases a language tag and writes it with hyphens,
+// e.g. "pt_BR" -> "pt-br"
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// lookupLocale returns the entry of m for locale or, failing that, its base
+// language, or fallback when neither has one
+func lookupLocale[T any](m map[string]T, locale string, fallback T) T {
+	if v, ok := lookupLocaleOK(m, locale); ok {
+		return v
+	}
+	return fallback
+}
+
+func lookupLocaleOK[T any](m map[string]T, locale string) (T, bool) {
+	locale = normalizeLocale(locale)
+	if v, ok := m[locale]; ok {
+		return v, true
+	}
+	base, _, _ := strings.Cut(locale, "-")
+	v, ok := m[base]
+	return v, ok
+}
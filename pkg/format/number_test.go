@@ -0,0 +1,49 @@
+package format
+
+import "testing"
+
+func TestNumber(t *testing.T) {
+	tests := []struct {
+		n      int
+		locale string
+		want   string
+	}{
+		{0, "en", "0"},
+		{999, "en", "999"},
+		{1000, "en", "1,000"},
+		{12000, "en-US", "12,000"},
+		{1234567, "en", "1,234,567"},
+		{-1234567, "en", "-1,234,567"},
+		{1234567, "de", "1.234.567"},
+		{1234567, "de-AT", "1.234.567"},
+		{1234567, "de-CH", "1’234’567"},
+		{1234567, "fr", "1\u202f234\u202f567"},
+		{1234567, "fr-CA", "1\u202f234\u202f567"},
+		{1234567, "ru", "1\u00a0234\u00a0567"},
+		{1234567, "pt_BR", "1.234.567"},
+		// Spanish and Polish leave four-digit numbers ungrouped
+		{1000, "es", "1000"},
+		{10000, "es", "10.000"},
+		{-1000, "pl", "-1000"},
+		{12345, "pl", "12\u00a0345"},
+		// Indian grouping: thousands, then lakhs and crores
+		{1234567, "hi", "12,34,567"},
+		{123456789, "en-IN", "12,34,56,789"},
+		{1000, "hi-IN", "1,000"},
+		{1234567, "tlh", "1,234,567"},
+		{1234567, "", "1,234,567"},
+	}
+	for _, tt := range tests {
+		if got := Number(tt.n, tt.locale); got != tt.want {
+			t.Errorf("Number(%d, %q) = %q, want %q", tt.n, tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestSupportsLocale(t *testing.T) {
+	for locale, want := range map[string]bool{"en": true, "de-DE": true, "pt_BR": true, "tlh": false, "": false} {
+		if got := SupportsLocale(locale); got != want {
+			t.Errorf("SupportsLocale(%q) = %v, want %v", locale, got, want)
+		}
+	}
+}
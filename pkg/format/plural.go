@@ -0,0 +1,101 @@
+package format
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pluralizer returns the form of noun, given in the singular, that goes with
+// a count of n in one language
+type Pluralizer func(n int, noun string) string
+
+var (
+	pluralizersMu sync.RWMutex
+	// pluralizers are keyed by lower-case language tag, e.g. "en" or "pt-br"
+	pluralizers = map[string]Pluralizer{"en": English}
+)
+
+// RegisterPluralizer sets the plural rules of the language with the given
+// tag. A rule for a base language such as "pt" also covers its regional
+// variants unless they have their own.
+func RegisterPluralizer(lang string, p Pluralizer) {
+	pluralizersMu.Lock()
+	defer pluralizersMu.Unlock()
+	pluralizers[normalizeLocale(lang)] = p
+}
+
+// pluralizerFor returns the rules for locale, falling back to its base
+// language and then to English
+func pluralizerFor(locale string) Pluralizer {
+	pluralizersMu.RLock()
+	defer pluralizersMu.RUnlock()
+	return lookupLocale(pluralizers, locale, English)
+}
+
+// irregularPlurals are the English nouns whose plurals no suffix rule gives
+var irregularPlurals = map[string]string{
+	"child":  "children",
+	"foot":   "feet",
+	"goose":  "geese",
+	"man":    "men",
+	"mouse":  "mice",
+	"person": "people",
+	"tooth":  "teeth",
+	"woman":  "women",
+	// Nouns that are the same in both
+	"fish":    "fish",
+	"series":  "series",
+	"sheep":   "sheep",
+	"species": "species",
+}
+
+// English is the Pluralizer for English: the singular for a count of one
+// (or minus one) and the plural for anything else, including zero
+func English(n int, noun string) string {
+	if n == 1 || n == -1 {
+		return noun
+	}
+	return englishPlural(noun)
+}
+
+// englishPlural returns the plural of an English noun, e.g. "category" ->
+// "categories", "box" -> "boxes", "person" -> "people"
+func englishPlural(noun string) string {
+	lower := strings.ToLower(noun)
+	if plural, ok := irregularPlurals[lower]; ok {
+		// Keep a capitalized noun capitalized
+		if first, _ := utf8.DecodeRuneInString(noun); unicode.IsUpper(first) {
+			return strings.ToUpper(plural[:1]) + plural[1:]
+		}
+		return plural
+	}
+	switch {
+	case noun == "":
+		return noun
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return noun[:len(noun)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return noun + "es"
+	}
+	return noun + "s"
+}
+
+// Noun returns the form of noun that goes with a count of n in locale
+func Noun(n int, noun, locale string) string {
+	return pluralizerFor(locale)(n, noun)
+}
+
+// Count formats a count with its noun in locale, e.g. 12000, "category",
+// "en" -> "12,000 categories"
+func Count(n int, noun, locale string) string {
+	return Number(n, locale) + " " + Noun(n, noun, locale)
+}
+
+// Plural formats a count with its noun in English (e.g., 1, "item" -> "1 item";
+// 4, "item" -> "4 items")
+func Plural(n int, noun string) string {
+	return Count(n, noun, "en")
+}
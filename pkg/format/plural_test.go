@@ -0,0 +1,68 @@
+package format
+
+import "testing"
+
+func TestPlural(t *testing.T) {
+	tests := []struct {
+		n    int
+		noun string
+		want string
+	}{
+		{0, "item", "0 items"},
+		{1, "item", "1 item"},
+		{2, "item", "2 items"},
+		{12000, "item", "12,000 items"},
+		{-1, "item", "-1 item"},
+		{-3, "item", "-3 items"},
+		{1, "category", "1 category"},
+		{0, "category", "0 categories"},
+		{2, "category", "2 categories"},
+		{2, "key", "2 keys"},
+		{2, "box", "2 boxes"},
+		{2, "class", "2 classes"},
+		{2, "batch", "2 batches"},
+		{2, "person", "2 people"},
+		{2, "Person", "2 People"},
+		{2, "sheep", "2 sheep"},
+		{2, "", "2 "},
+	}
+	for _, tt := range tests {
+		if got := Plural(tt.n, tt.noun); got != tt.want {
+			t.Errorf("Plural(%d, %q) = %q, want %q", tt.n, tt.noun, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterPluralizer(t *testing.T) {
+	// A toy rule: the noun is marked with its count class
+	RegisterPluralizer("xx", func(n int, noun string) string {
+		if n == 0 {
+			return noun + "[zero]"
+		}
+		return noun + "[other]"
+	})
+	t.Cleanup(func() {
+		pluralizersMu.Lock()
+		defer pluralizersMu.Unlock()
+		delete(pluralizers, "xx")
+	})
+
+	tests := []struct {
+		locale string
+		n      int
+		want   string
+	}{
+		{"xx", 0, "0 item[zero]"},
+		{"XX", 1, "1 item[other]"},
+		{"xx-YY", 5, "5 item[other]"}, // regional variants fall back to the language
+		{"de", 5, "5 items"},          // languages without rules use English
+	}
+	for _, tt := range tests {
+		if got := Count(tt.n, "item", tt.locale); got != tt.want {
+			t.Errorf("Count(%d, %q, %q) = %q, want %q", tt.n, "item", tt.locale, got, tt.want)
+		}
+	}
+	if !SupportsLocale("xx-yy") {
+		t.Error("SupportsLocale(xx-yy) = false after registering xx")
+	}
+}
@@ -0,0 +1,155 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrRegistryFrozen is returned by RegisterShape once the registry has been
+// frozen
+var ErrRegistryFrozen = errors.New("shape registry is frozen")
+
+// ShapeRegistry holds the known item shapes, each with a display name and
+// any aliases that mean the same shape. Shapes are registered at startup and
+// the registry then frozen, after which it is read-only; it is safe for
+// concurrent use either way.
+type ShapeRegistry struct {
+	mu sync.RWMutex
+	// names maps each shape's canonical name to its display name
+	names map[string]string
+	// aliases maps each alias to the canonical name it stands for
+	aliases map[string]string
+	frozen  bool
+}
+
+// NewShapeRegistry returns a registry of the default shapes: square, circle,
+// and triangle
+func NewShapeRegistry() *ShapeRegistry {
+	return &ShapeRegistry{
+		names: map[string]string{
+			"circle":   "Circle",
+			"square":   "Square",
+			"triangle": "Triangle",
+		},
+		aliases: make(map[string]string),
+	}
+}
+
+// Shapes is the registry used by Shape and ShapeName
+var Shapes = NewShapeRegistry()
+
+// RegisterShape registers a shape in Shapes. See ShapeRegistry.RegisterShape.
+func RegisterShape(name, displayName string, aliases ...string) error {
+	return Shapes.RegisterShape(name, displayName, aliases...)
+}
+
+// RegisterShape adds a shape, or adds aliases to a registered one and
+// replaces its display name if one is given. A new shape without a display
+// name is shown title-cased. Names are matched without regard to case or surrounding space, and a
+// name or alias may only stand for one shape.
+func (r *ShapeRegistry) RegisterShape(name, displayName string, aliases ...string) error {
+	name = shapeKey(name)
+	if name == "" {
+		return errors.New("shape name is empty")
+	}
+	displayName = strings.TrimSpace(displayName)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.frozen {
+		return fmt.Errorf("%w: cannot register %s", ErrRegistryFrozen, name)
+	}
+	if canonical, ok := r.aliases[name]; ok {
+		return fmt.Errorf("shape %s is already an alias of %s", name, canonical)
+	}
+	keys := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		alias = shapeKey(alias)
+		if _, ok := r.names[alias]; ok || alias == name {
+			return fmt.Errorf("alias %s of %s is already a shape", alias, name)
+		}
+		if canonical, ok := r.aliases[alias]; ok && canonical != name {
+			return fmt.Errorf("alias %s of %s is already an alias of %s", alias, name, canonical)
+		}
+		keys = append(keys, alias)
+	}
+
+	if displayName != "" {
+		r.names[name] = displayName
+	} else if _, ok := r.names[name]; !ok {
+		r.names[name] = Title(name)
+	}
+	for _, alias := range keys {
+		r.aliases[alias] = name
+	}
+	return nil
+}
+
+// Freeze makes the registry read-only: later calls to RegisterShape fail
+// with ErrRegistryFrozen
+func (r *ShapeRegistry) Freeze() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frozen = true
+}
+
+// Resolve returns the canonical name of shape, resolving aliases, and
+// whether it is a registered shape. Unknown shapes come back lower-cased.
+func (r *ShapeRegistry) Resolve(shape string) (string, bool) {
+	key := shapeKey(shape)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if canonical, ok := r.aliases[key]; ok {
+		return canonical, true
+	}
+	_, ok := r.names[key]
+	return key, ok
+}
+
+// Known reports whether shape is a registered shape or alias
+func (r *ShapeRegistry) Known(shape string) bool {
+	_, ok := r.Resolve(shape)
+	return ok
+}
+
+// DisplayName returns the display name of shape, or the title-cased name of
+// an unknown shape
+func (r *ShapeRegistry) DisplayName(shape string) string {
+	canonical, _ := r.Resolve(shape)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if name, ok := r.names[canonical]; ok {
+		return name
+	}
+	return Title(canonical)
+}
+
+// Names returns the canonical names of the registered shapes, sorted
+func (r *ShapeRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Sorted(maps.Keys(r.names))
+}
+
+// shapeKey is the form shape names and aliases are matched in
+func shapeKey(shape string) string {
+	return strings.ToLower(strings.TrimSpace(shape))
+}
+
+// Shape returns the canonical form of a shape name, which items are stored
+// by: aliases registered in Shapes are resolved and everything else is
+// lower-cased
+func Shape(shape string) string {
+	canonical, _ := Shapes.Resolve(shape)
+	return canonical
+}
+
+// ShapeName formats a shape name for display, with the display name it was
+// registered with in Shapes
+func ShapeName(shape string) string {
+	return Shapes.DisplayName(shape)
+}
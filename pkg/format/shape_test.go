@@ -0,0 +1,105 @@
+package format
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestShape(t *testing.T) {
+	for in, want := range map[string]string{"circle": "circle", "Circle": "circle", "SQUARE": "square", " hexagon ": "hexagon", "": ""} {
+		if got := Shape(in); got != want {
+			t.Errorf("Shape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestShapeRegistry_Resolve(t *testing.T) {
+	r := NewShapeRegistry()
+	if err := r.RegisterShape("hexagon", "Hex Prism", "hex", "SIX-SIDED"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RegisterShape("Square", "", "box"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		shape       string
+		wantName    string
+		wantKnown   bool
+		wantDisplay string
+	}{
+		{"circle", "circle", true, "Circle"},
+		{"hexagon", "hexagon", true, "Hex Prism"},
+		{"Hex", "hexagon", true, "Hex Prism"},
+		{" six-sided ", "hexagon", true, "Hex Prism"},
+		// Adding an alias keeps the built-in display name
+		{"BOX", "square", true, "Square"},
+		{"Octagon", "octagon", false, "Octagon"},
+		{"", "", false, ""},
+	}
+	for _, tt := range tests {
+		name, known := r.Resolve(tt.shape)
+		if name != tt.wantName || known != tt.wantKnown {
+			t.Errorf("Resolve(%q) = %q, %v, want %q, %v", tt.shape, name, known, tt.wantName, tt.wantKnown)
+		}
+		if got := r.DisplayName(tt.shape); got != tt.wantDisplay {
+			t.Errorf("DisplayName(%q) = %q, want %q", tt.shape, got, tt.wantDisplay)
+		}
+	}
+	if got, want := r.Names(), []string{"circle", "hexagon", "square", "triangle"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %q, want %q", got, want)
+	}
+}
+
+func TestShapeRegistry_Conflicts(t *testing.T) {
+	r := NewShapeRegistry()
+	if err := r.RegisterShape("hexagon", "", "hex"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name    string
+		aliases []string
+	}{
+		{"", nil},
+		{"hex", nil},
+		{"rect", []string{"square"}},
+		{"rect", []string{"rect"}},
+		{"rect", []string{"hex"}},
+	} {
+		if err := r.RegisterShape(tt.name, "", tt.aliases...); err == nil {
+			t.Errorf("RegisterShape(%q, %q) succeeded, want a conflict", tt.name, tt.aliases)
+		}
+	}
+	if r.Known("rect") {
+		t.Error("a rejected shape was registered")
+	}
+	// Repeating an alias of the same shape is fine
+	if err := r.RegisterShape("hexagon", "", "hex"); err != nil {
+		t.Errorf("re-registering hexagon: %v", err)
+	}
+}
+
+func TestShapeRegistry_Freeze(t *testing.T) {
+	r := NewShapeRegistry()
+	r.Freeze()
+	err := r.RegisterShape("hexagon", "")
+	if !errors.Is(err, ErrRegistryFrozen) {
+		t.Fatalf("RegisterShape() after Freeze error = %v, want ErrRegistryFrozen", err)
+	}
+	if r.Known("hexagon") {
+		t.Error("a shape was registered after Freeze")
+	}
+
+	// Reads stay safe from any number of goroutines
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			if !r.Known("circle") {
+				t.Error("circle is not known")
+			}
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,151 @@
+// Package graphqlapi exposes an item store through a GraphQL schema with
+// bounded query depth and cost.
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+}
+
+// Operation is a parsed request that passed validation and the limits, ready
+// to be executed
+type Operation struct {
+	req      Request
+	doc      *ast.Document
+	mutation bool
+}
+
+// Mutation reports whether the operation writes to the store
+func (op *Operation) Mutation() bool {
+	return op.mutation
+}
+
+// Executor runs GraphQL requests against an item store
+type Executor struct {
+	schema graphql.Schema
+	limits Limits
+}
+
+// Option configures an Executor
+type Option func(*Executor)
+
+// WithLimits replaces the default query limits
+func WithLimits(l Limits) Option {
+	return func(e *Executor) { e.limits = l }
+}
+
+// New builds the schema for store
+func New(store itemstore.Store, opts ...Option) (*Executor, error) {
+	schema, err := newSchema(&resolver{store: store})
+	if err != nil {
+		return nil, fmt.Errorf("graphql schema: %w", err)
+	}
+	e := &Executor{schema: schema, limits: DefaultLimits}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+// Prepare parses and validates req and checks it against the limits. The
+// returned errors are ready to be sent to the client.
+func (e *Executor) Prepare(req Request) (*Operation, []gqlerrors.FormattedError) {
+	if req.Query == "" {
+		return nil, gqlerrors.FormatErrors(errors.New("query must not be empty"))
+	}
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return nil, gqlerrors.FormatErrors(err)
+	}
+	if res := graphql.ValidateDocument(&e.schema, doc, nil); !res.IsValid {
+		return nil, res.Errors
+	}
+
+	op := selectOperation(doc, req.OperationName)
+	if op == nil {
+		if req.OperationName != "" {
+			return nil, gqlerrors.FormatErrors(fmt.Errorf("unknown operation %q", req.OperationName))
+		}
+		return nil, gqlerrors.FormatErrors(errors.New("operationName is required when the document has several operations"))
+	}
+	if err := e.limits.check(&e.schema, doc, op, req.Variables); err != nil {
+		f := gqlerrors.FormatError(err)
+		f.Extensions = err.Extensions()
+		return nil, []gqlerrors.FormattedError{f}
+	}
+	return &Operation{req: req, doc: doc, mutation: op.Operation == ast.OperationTypeMutation}, nil
+}
+
+// storeKey is the context key of the store set by WithStore
+type storeKey struct{}
+
+// WithStore returns a copy of ctx that makes the operations executed with it
+// write to store instead of the store the Executor was built for, e.g. one
+// that records who made each change
+func WithStore(ctx context.Context, store itemstore.Store) context.Context {
+	return context.WithValue(ctx, storeKey{}, store)
+}
+
+// Execute runs a prepared operation
+func (e *Executor) Execute(ctx context.Context, op *Operation) *graphql.Result {
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        e.schema,
+		AST:           op.doc,
+		OperationName: op.req.OperationName,
+		Args:          op.req.Variables,
+		Context:       ctx,
+	})
+}
+
+// selectOperation returns the operation named name, or the only operation
+// when name is empty
+func selectOperation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = op
+		} else if op.Name != nil && op.Name.Value == name {
+			return op
+		}
+	}
+	return found
+}
+
+// Error is a request or resolver error carrying a machine-readable code in
+// its extensions
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Extensions implements gqlerrors.ExtendedError
+func (e *Error) Extensions() map[string]any {
+	return map[string]any{"code": e.Code}
+}
@@ -0,0 +1,231 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Limits bound how much work a single query may ask for. They are checked
+// before execution, so an over-limit query never touches the store.
+type Limits struct {
+	// MaxDepth is the deepest field nesting allowed, counting the operation's
+	// top-level fields as depth 1
+	MaxDepth int
+	// MaxComplexity caps the estimated number of values a query resolves.
+	// Every field costs 1, and the cost of a list field's selection is
+	// multiplied by its limit argument, or by ListSize when it has none.
+	MaxComplexity int
+	// ListSize is the assumed length of lists without a limit argument
+	ListSize int
+}
+
+// DefaultLimits allow every query the schema can express at a useful size
+// while rejecting deep introspection chains and fragment explosions
+var DefaultLimits = Limits{MaxDepth: 10, MaxComplexity: 2000, ListSize: 10}
+
+// check measures op and returns an error if it is too deep or too costly
+func (l Limits) check(schema *graphql.Schema, doc *ast.Document, op *ast.OperationDefinition, vars map[string]any) *Error {
+	root := schema.QueryType()
+	if op.Operation == ast.OperationTypeMutation {
+		root = schema.MutationType()
+	}
+	m := measurer{
+		schema:    schema,
+		fragments: make(map[string]*ast.FragmentDefinition),
+		vars:      vars,
+		limits:    l,
+		memo:      make(map[fragmentKey]int),
+	}
+	for _, def := range doc.Definitions {
+		if frag, ok := def.(*ast.FragmentDefinition); ok {
+			m.fragments[frag.Name.Value] = frag
+		}
+	}
+
+	cost, err := m.selectionSet(root, op.SelectionSet, 1)
+	if err != nil {
+		return err
+	}
+	if l.MaxComplexity > 0 && cost > l.MaxComplexity {
+		return &Error{Code: "QUERY_TOO_COMPLEX",
+			Message: fmt.Sprintf("query cost %d exceeds the maximum of %d", cost, l.MaxComplexity)}
+	}
+	return nil
+}
+
+// measurer walks a validated document, expanding fragments, to compute its
+// depth and cost
+type measurer struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	vars      map[string]any
+	limits    Limits
+	memo      map[fragmentKey]int
+}
+
+// fragmentKey identifies a fragment spread at a given depth
+type fragmentKey struct {
+	name  string
+	depth int
+}
+
+// selectionSet returns the cost of the selections made on parent at depth
+func (m *measurer) selectionSet(parent graphql.Type, set *ast.SelectionSet, depth int) (int, *Error) {
+	if set == nil {
+		return 0, nil
+	}
+	if m.limits.MaxDepth > 0 && depth > m.limits.MaxDepth {
+		return 0, &Error{Code: "QUERY_TOO_DEEP",
+			Message: fmt.Sprintf("query is nested deeper than the maximum of %d", m.limits.MaxDepth)}
+	}
+
+	total := 0
+	for _, sel := range set.Selections {
+		var cost int
+		var err *Error
+		switch sel := sel.(type) {
+		case *ast.Field:
+			cost, err = m.field(parent, sel, depth)
+		case *ast.InlineFragment:
+			cost, err = m.selectionSet(m.typeCondition(parent, sel.TypeCondition), sel.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			cost, err = m.fragment(parent, sel.Name.Value, depth)
+		}
+		if err != nil {
+			return 0, err
+		}
+		total = saturatingAdd(total, cost)
+	}
+	return total, nil
+}
+
+// fragment returns the cost of spreading a named fragment. Results are
+// memoized so fragments that spread each other repeatedly are measured in
+// linear time.
+func (m *measurer) fragment(parent graphql.Type, name string, depth int) (int, *Error) {
+	key := fragmentKey{name: name, depth: depth}
+	if cost, ok := m.memo[key]; ok {
+		return cost, nil
+	}
+	frag := m.fragments[name]
+	if frag == nil {
+		return 0, nil
+	}
+	cost, err := m.selectionSet(m.typeCondition(parent, frag.TypeCondition), frag.SelectionSet, depth)
+	if err != nil {
+		return 0, err
+	}
+	m.memo[key] = cost
+	return cost, nil
+}
+
+// field returns the cost of one field and its own selections
+func (m *measurer) field(parent graphql.Type, f *ast.Field, depth int) (int, *Error) {
+	def := fieldDef(m.schema, parent, f.Name.Value)
+	if def == nil || f.SelectionSet == nil {
+		return 1, nil
+	}
+
+	fieldType, isList := unwrap(def.Type)
+	children, err := m.selectionSet(fieldType, f.SelectionSet, depth+1)
+	if err != nil {
+		return 0, err
+	}
+	if isList {
+		children = saturatingMul(children, m.listSize(f))
+	}
+	return saturatingAdd(1, children), nil
+}
+
+// listSize returns the limit argument of f, or the assumed list size
+func (m *measurer) listSize(f *ast.Field) int {
+	for _, arg := range f.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil && n >= 0 {
+				return n
+			}
+		case *ast.Variable:
+			switch n := m.vars[v.Name.Value].(type) {
+			case int:
+				return max(n, 0)
+			case float64:
+				return max(int(n), 0)
+			}
+		}
+	}
+	return m.limits.ListSize
+}
+
+// typeCondition resolves a fragment's type condition, falling back to parent
+func (m *measurer) typeCondition(parent graphql.Type, cond *ast.Named) graphql.Type {
+	if cond == nil {
+		return parent
+	}
+	if t := m.schema.Type(cond.Name.Value); t != nil {
+		return t
+	}
+	return parent
+}
+
+// fieldDef looks up a field, including the introspection meta fields, on an
+// object or interface type
+func fieldDef(schema *graphql.Schema, parent graphql.Type, name string) *graphql.FieldDefinition {
+	switch name {
+	case graphql.SchemaMetaFieldDef.Name:
+		if parent == schema.QueryType() {
+			return graphql.SchemaMetaFieldDef
+		}
+	case graphql.TypeMetaFieldDef.Name:
+		if parent == schema.QueryType() {
+			return graphql.TypeMetaFieldDef
+		}
+	case graphql.TypeNameMetaFieldDef.Name:
+		return graphql.TypeNameMetaFieldDef
+	}
+	switch t := parent.(type) {
+	case *graphql.Object:
+		return t.Fields()[name]
+	case *graphql.Interface:
+		return t.Fields()[name]
+	}
+	return nil
+}
+
+// unwrap strips non-null and list wrappers from t and reports whether any
+// list was among them
+func unwrap(t graphql.Type) (graphql.Type, bool) {
+	isList := false
+	for {
+		switch w := t.(type) {
+		case *graphql.NonNull:
+			t = w.OfType
+		case *graphql.List:
+			isList = true
+			t = w.OfType
+		default:
+			return t, isList
+		}
+	}
+}
+
+func saturatingAdd(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+func saturatingMul(a, b int) int {
+	if a != 0 && b > math.MaxInt/a {
+		return math.MaxInt
+	}
+	return a * b
+}
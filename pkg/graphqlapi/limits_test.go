@@ -0,0 +1,55 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	e, _ := newTestExecutor(t, WithLimits(Limits{MaxDepth: 4, MaxComplexity: 100, ListSize: 10}))
+
+	// Each fragment spreads the next twice, doubling the fields at every
+	// level
+	var explode strings.Builder
+	explode.WriteString("{ items(limit: 1) { ...F0 } }\n")
+	for i := range 30 {
+		fmt.Fprintf(&explode, "fragment F%d on Item { ...F%d ...F%d }\n", i, i+1, i+1)
+	}
+	explode.WriteString("fragment F30 on Item { id }\n")
+
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]any
+		code  string
+	}{
+		{"within limits", `{ items { id color } }`, nil, ""},
+		{"limit lowers the cost", `{ items(limit: 40) { id color } }`, nil, ""},
+		{"large limit", `{ items(limit: 60) { id color } }`, nil, "QUERY_TOO_COMPLEX"},
+		{"large limit variable", `query($n: Int) { items(limit: $n) { id color } }`, map[string]any{"n": float64(60)}, "QUERY_TOO_COMPLEX"},
+		{"nested lists multiply", `{ groups(by: COLOR) { items { id color shape } } }`, nil, "QUERY_TOO_COMPLEX"},
+		{"fragment explosion", explode.String(), nil, "QUERY_TOO_COMPLEX"},
+		{"introspection depth", `{ __schema { types { fields { type { ofType { name } } } } } }`, nil, "QUERY_TOO_DEEP"},
+		{"shallow introspection", `{ __type(name: "Item") { name } }`, nil, ""},
+		{"inline fragment depth", `{ __schema { types { ... on __Type { fields { type { name } } } } } }`, nil, "QUERY_TOO_DEEP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := e.Prepare(Request{Query: tt.query, Variables: tt.vars})
+			if tt.code == "" {
+				if errs != nil {
+					t.Fatalf("errors = %v, want none", errs)
+				}
+				return
+			}
+			if len(errs) != 1 {
+				t.Fatalf("errors = %v, want one", errs)
+			}
+			if code := errs[0].Extensions["code"]; code != tt.code {
+				t.Errorf("code = %v, want %s (message %q)", code, tt.code, errs[0].Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package graphqlapi
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/graphql-go/graphql"
+)
+
+// maxLimit bounds the limit argument of list fields
+const maxLimit = 1000
+
+// resolver implements the schema's fields on top of an item store
+type resolver struct {
+	store itemstore.Store
+}
+
+// writeStore returns the store mutations write to: the one set on ctx by
+// WithStore, or else r.store
+func (r *resolver) writeStore(ctx context.Context) itemstore.Store {
+	if store, ok := ctx.Value(storeKey{}).(itemstore.Store); ok {
+		return store
+	}
+	return r.store
+}
+
+// Group is one bucket returned by the groups query
+type Group struct {
+	Name  string           `json:"name"`
+	Count int              `json:"count"`
+	Items []itemstore.Item `json:"items"`
+}
+
+// ValueCount is the number of items sharing one property value
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Stats summarizes one property over the matching items
+type Stats struct {
+	Property string       `json:"property"`
+	Total    int          `json:"total"`
+	Values   []ValueCount `json:"values"`
+}
+
+// items resolves Query.items
+func (r *resolver) items(p graphql.ResolveParams) (any, error) {
+	items, err := r.store.FilterContext(p.Context, filterArg(p.Args))
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	if sort, ok := p.Args["sort"].(map[string]any); ok {
+		field, _ := sort["field"].(string)
+		order, _ := sort["order"].(string)
+		if err := itemstore.Sort(p.Context, items, field, order == "desc"); err != nil {
+			return nil, storeError(err)
+		}
+	}
+
+	offset, _ := p.Args["offset"].(int)
+	if offset < 0 {
+		return nil, badInput("offset must not be negative")
+	}
+	limit, hasLimit := p.Args["limit"].(int)
+	if hasLimit && (limit < 0 || limit > maxLimit) {
+		return nil, badInput("limit must be between 0 and %d", maxLimit)
+	}
+
+	items = items[min(offset, len(items)):]
+	if hasLimit {
+		items = items[:min(limit, len(items))]
+	}
+	return items, nil
+}
+
+// item resolves Query.item; a missing item is null rather than an error
+func (r *resolver) item(p graphql.ResolveParams) (any, error) {
+	item, err := r.store.Get(idArg(p.Args["id"]))
+	if errors.Is(err, itemstore.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return item, nil
+}
+
+// groups resolves Query.groups, ordered by group name
+func (r *resolver) groups(p graphql.ResolveParams) (any, error) {
+	by, _ := p.Args["by"].(string)
+
+	items, err := r.store.FilterContext(p.Context, filterArg(p.Args))
+	if err != nil {
+		return nil, storeError(err)
+	}
+	var groups []Group
+	index := make(map[string]int)
+	for _, item := range items {
+		name := property(item, by)
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, Group{Name: name})
+		}
+		groups[i].Items = append(groups[i].Items, item)
+		groups[i].Count++
+	}
+	slices.SortFunc(groups, func(a, b Group) int { return cmp.Compare(a.Name, b.Name) })
+	return groups, nil
+}
+
+// stats resolves Query.stats, with values ordered by value
+func (r *resolver) stats(p graphql.ResolveParams) (any, error) {
+	prop, _ := p.Args["property"].(string)
+	items, err := r.store.FilterContext(p.Context, filterArg(p.Args))
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	counts := make(map[string]int)
+	for _, item := range items {
+		counts[property(item, prop)]++
+	}
+	values := make([]ValueCount, 0, len(counts))
+	for _, v := range slices.Sorted(maps.Keys(counts)) {
+		values = append(values, ValueCount{Value: v, Count: counts[v]})
+	}
+	return Stats{Property: prop, Total: len(items), Values: values}, nil
+}
+
+// createItem resolves Mutation.createItem
+func (r *resolver) createItem(p graphql.ResolveParams) (any, error) {
+	input, _ := p.Args["input"].(map[string]any)
+	item := itemstore.Item{ID: idArg(input["id"])}
+	applyFields(&item, input)
+
+	created, err := r.writeStore(p.Context).Add(item)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return created, nil
+}
+
+// updateItem resolves Mutation.updateItem, changing only the fields present
+// in the input
+func (r *resolver) updateItem(p graphql.ResolveParams) (any, error) {
+	input, _ := p.Args["input"].(map[string]any)
+
+	store := r.writeStore(p.Context)
+	item, err := store.Get(idArg(p.Args["id"]))
+	if err != nil {
+		return nil, storeError(err)
+	}
+	applyFields(&item, input)
+
+	updated, err := store.Update(item)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return updated, nil
+}
+
+// deleteItem resolves Mutation.deleteItem
+func (r *resolver) deleteItem(p graphql.ResolveParams) (any, error) {
+	if err := r.writeStore(p.Context).Delete(idArg(p.Args["id"])); err != nil {
+		return nil, storeError(err)
+	}
+	return true, nil
+}
+
+// itemID resolves Item.id, a number or a string by the kind of ID
+func itemID(p graphql.ResolveParams) (any, error) {
+	item, _ := p.Source.(itemstore.Item)
+	if id, ok := item.ID.Int(); ok {
+		return id, nil
+	}
+	return item.ID.String(), nil
+}
+
+// idArg converts an id argument, an Int or an ID, to a store ID. A missing
+// one is the zero ID.
+func idArg(v any) itemstore.ID {
+	switch v := v.(type) {
+	case int:
+		return itemstore.IntID(v)
+	case string:
+		return itemstore.StringID(v)
+	}
+	return itemstore.ID{}
+}
+
+// createdAt resolves Item.createdAt, which is null for items stored without
+// a creation time
+func createdAt(p graphql.ResolveParams) (any, error) {
+	item, _ := p.Source.(itemstore.Item)
+	if item.CreatedAt.IsZero() {
+		return nil, nil
+	}
+	return item.CreatedAt, nil
+}
+
+// storeError maps store errors to coded resolver errors
+func storeError(err error) error {
+	switch {
+	case errors.Is(err, itemstore.ErrNotFound):
+		return &Error{Code: "NOT_FOUND", Message: err.Error()}
+	case errors.Is(err, itemstore.ErrDuplicateID):
+		return &Error{Code: "CONFLICT", Message: err.Error()}
+	case errors.Is(err, itemstore.ErrInvalidItem):
+		return &Error{Code: "BAD_USER_INPUT", Message: err.Error()}
+	case errors.Is(err, itemstore.ErrReadOnly):
+		return &Error{Code: "FORBIDDEN", Message: err.Error()}
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: "CANCELED", Message: err.Error()}
+	}
+	return &Error{Code: "INTERNAL", Message: "internal error"}
+}
+
+// badInput reports an invalid argument
+func badInput(format string, args ...any) error {
+	return &Error{Code: "BAD_USER_INPUT", Message: fmt.Sprintf(format, args...)}
+}
+
+// filterArg converts the optional filter argument to store filters
+func filterArg(args map[string]any) map[string]string {
+	in, _ := args["filter"].(map[string]any)
+	filters := make(map[string]string, len(in))
+	for k, v := range in {
+		if s, ok := v.(string); ok {
+			filters[k] = s
+		}
+	}
+	return filters
+}
+
+// applyFields copies the property fields present in input onto item
+func applyFields(item *itemstore.Item, input map[string]any) {
+	if v, ok := input["color"].(string); ok {
+		item.Color = v
+	}
+	if v, ok := input["shape"].(string); ok {
+		item.Shape = v
+	}
+	if v, ok := input["category"].(string); ok {
+		item.Category = v
+	}
+}
+
+// property returns item's value for one of the filterable properties
+func property(item itemstore.Item, name string) string {
+	switch name {
+	case "color":
+		return item.Color
+	case "shape":
+		return item.Shape
+	case "category":
+		return item.Category
+	}
+	return ""
+}
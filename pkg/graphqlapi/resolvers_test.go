@@ -0,0 +1,250 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+func newTestExecutor(t *testing.T, opts ...Option) (*Executor, *itemstore.ItemStore) {
+	t.Helper()
+	store, err := itemstore.New([]itemstore.Item{
+		{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"},
+		{ID: itemstore.IntID(2), Color: "blue", Shape: "square", Category: "A"},
+		{ID: itemstore.IntID(3), Color: "green", Shape: "triangle", Category: "B"},
+		{ID: itemstore.IntID(4), Color: "red", Shape: "square", Category: "B"},
+	})
+	if err != nil {
+		t.Fatalf("itemstore.New: %v", err)
+	}
+	e, err := New(store, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return e, store
+}
+
+// run prepares and executes query and returns the result as JSON. Any error
+// fails the test.
+func run(t *testing.T, e *Executor, query string, vars map[string]any) string {
+	t.Helper()
+	op, errs := e.Prepare(Request{Query: query, Variables: vars})
+	if errs != nil {
+		t.Fatalf("Prepare(%s): %v", query, errs)
+	}
+	res := e.Execute(context.Background(), op)
+	if res.HasErrors() {
+		t.Fatalf("Execute(%s): %v", query, res.Errors)
+	}
+	b, err := json.Marshal(res.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestQueryResolvers(t *testing.T) {
+	e, _ := newTestExecutor(t)
+
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]any
+		want  string
+	}{
+		{
+			name:  "all items",
+			query: `{ items { id } }`,
+			want:  `{"items":[{"id":1},{"id":2},{"id":3},{"id":4}]}`,
+		},
+		{
+			name:  "filter",
+			query: `{ items(filter: {color: "red"}) { id shape } }`,
+			want:  `{"items":[{"id":1,"shape":"circle"},{"id":4,"shape":"square"}]}`,
+		},
+		{
+			name:  "sort descending by shape",
+			query: `{ items(sort: {field: SHAPE, order: DESC}) { id } }`,
+			want:  `{"items":[{"id":3},{"id":4},{"id":2},{"id":1}]}`,
+		},
+		{
+			name:  "limit and offset",
+			query: `query($n: Int) { items(limit: $n, offset: 1) { id } }`,
+			vars:  map[string]any{"n": 2},
+			want:  `{"items":[{"id":2},{"id":3}]}`,
+		},
+		{
+			name:  "offset past the end",
+			query: `{ items(offset: 10) { id } }`,
+			want:  `{"items":[]}`,
+		},
+		{
+			name:  "item",
+			query: `{ item(id: 2) { color shape category } }`,
+			want:  `{"item":{"category":"A","color":"blue","shape":"square"}}`,
+		},
+		{
+			name:  "missing item is null",
+			query: `{ item(id: 99) { id } }`,
+			want:  `{"item":null}`,
+		},
+		{
+			name:  "groups",
+			query: `{ groups(by: CATEGORY) { name count items { id } } }`,
+			want:  `{"groups":[{"count":2,"items":[{"id":1},{"id":2}],"name":"A"},{"count":2,"items":[{"id":3},{"id":4}],"name":"B"}]}`,
+		},
+		{
+			name:  "groups with filter",
+			query: `{ groups(by: COLOR, filter: {category: "B"}) { name count } }`,
+			want:  `{"groups":[{"count":1,"name":"green"},{"count":1,"name":"red"}]}`,
+		},
+		{
+			name:  "stats",
+			query: `{ stats(property: COLOR) { property total values { value count } } }`,
+			want:  `{"stats":{"property":"COLOR","total":4,"values":[{"count":1,"value":"blue"},{"count":1,"value":"green"},{"count":2,"value":"red"}]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := run(t, e, tt.query, tt.vars); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMutationResolvers(t *testing.T) {
+	e, store := newTestExecutor(t)
+
+	got := run(t, e, `mutation { createItem(input: {color: "purple", shape: "circle", category: "C"}) { id color } }`, nil)
+	if want := `{"createItem":{"color":"purple","id":5}}`; got != want {
+		t.Errorf("createItem = %s, want %s", got, want)
+	}
+
+	got = run(t, e, `mutation { updateItem(id: 1, input: {shape: "hexagon"}) { id color shape } }`, nil)
+	if want := `{"updateItem":{"color":"red","id":1,"shape":"hexagon"}}`; got != want {
+		t.Errorf("updateItem = %s, want %s", got, want)
+	}
+
+	got = run(t, e, `mutation { deleteItem(id: 2) }`, nil)
+	if want := `{"deleteItem":true}`; got != want {
+		t.Errorf("deleteItem = %s, want %s", got, want)
+	}
+	if store.Len() != 4 {
+		t.Errorf("store has %d items, want 4", store.Len())
+	}
+
+	// Seed items have no creation time; created ones do
+	got = run(t, e, `{ items(filter: {color: "red"}) { id createdAt } }`, nil)
+	if want := `{"items":[{"createdAt":null,"id":1}`; !strings.HasPrefix(got, want) {
+		t.Errorf("items = %s, want item 1 with a null createdAt", got)
+	}
+	got = run(t, e, `{ item(id: 5) { createdAt } }`, nil)
+	if strings.Contains(got, "null") {
+		t.Errorf("item = %s, want a createdAt for a created item", got)
+	}
+}
+
+func TestStringIDs(t *testing.T) {
+	store, err := itemstore.New([]itemstore.Item{
+		{ID: itemstore.StringID("b-item"), Color: "red", Shape: "circle", Category: "A"},
+		{ID: itemstore.StringID("a-item"), Color: "blue", Shape: "square", Category: "A"},
+	}, itemstore.WithStringIDs())
+	if err != nil {
+		t.Fatalf("itemstore.New: %v", err)
+	}
+	e, err := New(store)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	got := run(t, e, `{ items(sort: {field: ID}) { id } }`, nil)
+	if want := `{"items":[{"id":"a-item"},{"id":"b-item"}]}`; got != want {
+		t.Errorf("items = %s, want %s", got, want)
+	}
+	got = run(t, e, `query($id: ID!) { item(id: $id) { color } }`, map[string]any{"id": "b-item"})
+	if want := `{"item":{"color":"red"}}`; got != want {
+		t.Errorf("item = %s, want %s", got, want)
+	}
+
+	var created struct {
+		CreateItem struct{ ID string } `json:"createItem"`
+	}
+	got = run(t, e, `mutation { createItem(input: {color: "green", shape: "circle", category: "B"}) { id } }`, nil)
+	if err := json.Unmarshal([]byte(got), &created); err != nil || len(created.CreateItem.ID) != 36 {
+		t.Errorf("createItem = %s, want a generated UUID", got)
+	}
+	got = run(t, e, `mutation { deleteItem(id: "a-item") }`, nil)
+	if want := `{"deleteItem":true}`; got != want {
+		t.Errorf("deleteItem = %s, want %s", got, want)
+	}
+}
+
+func TestResolverErrors(t *testing.T) {
+	e, _ := newTestExecutor(t)
+
+	tests := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{"duplicate ID", `mutation { createItem(input: {id: 1, color: "red", shape: "circle", category: "A"}) { id } }`, "CONFLICT"},
+		{"invalid item", `mutation { createItem(input: {color: " ", shape: "circle", category: "A"}) { id } }`, "BAD_USER_INPUT"},
+		{"update missing", `mutation { updateItem(id: 99, input: {color: "red"}) { id } }`, "NOT_FOUND"},
+		{"delete missing", `mutation { deleteItem(id: 99) }`, "NOT_FOUND"},
+		{"negative offset", `{ items(offset: -1) { id } }`, "BAD_USER_INPUT"},
+		{"limit too large", `{ items(limit: 5000) { id } }`, "QUERY_TOO_COMPLEX"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, errs := e.Prepare(Request{Query: tt.query})
+			if errs == nil {
+				res := e.Execute(context.Background(), op)
+				errs = res.Errors
+			}
+			if len(errs) != 1 {
+				t.Fatalf("errors = %v, want one", errs)
+			}
+			if code := errs[0].Extensions["code"]; code != tt.code {
+				t.Errorf("code = %v, want %s (message %q)", code, tt.code, errs[0].Message)
+			}
+		})
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	e, _ := newTestExecutor(t)
+
+	tests := []struct {
+		name     string
+		req      Request
+		wantErr  bool
+		mutation bool
+	}{
+		{"query", Request{Query: `{ items { id } }`}, false, false},
+		{"mutation", Request{Query: `mutation { deleteItem(id: 1) }`}, false, true},
+		{"named operation", Request{Query: `query A { items { id } } mutation B { deleteItem(id: 1) }`, OperationName: "B"}, false, true},
+		{"empty", Request{}, true, false},
+		{"syntax error", Request{Query: `{ items { id }`}, true, false},
+		{"unknown field", Request{Query: `{ widgets { id } }`}, true, false},
+		{"ambiguous operation", Request{Query: `query A { items { id } } query B { items { id } }`}, true, false},
+		{"unknown operation", Request{Query: `query A { items { id } }`, OperationName: "C"}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, errs := e.Prepare(tt.req)
+			if (errs != nil) != tt.wantErr {
+				t.Fatalf("errors = %v, wantErr %v", errs, tt.wantErr)
+			}
+			if op != nil && op.Mutation() != tt.mutation {
+				t.Errorf("Mutation() = %v, want %v", op.Mutation(), tt.mutation)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package graphqlapi
+
+import "github.com/graphql-go/graphql"
+
+// newSchema builds the executable schema. Enum values resolve to the
+// lower-case names the store uses. Item IDs are Int, or ID for a store of
+// string IDs.
+func newSchema(r *resolver) (graphql.Schema, error) {
+	nonNullString := graphql.NewNonNull(graphql.String)
+	nonNullInt := graphql.NewNonNull(graphql.Int)
+	idType := graphql.Int
+	if r.store.StringIDs() {
+		idType = graphql.ID
+	}
+	nonNullID := graphql.NewNonNull(idType)
+
+	propertyEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "Property",
+		Description: "An item property that items can be grouped and counted by",
+		Values: graphql.EnumValueConfigMap{
+			"COLOR":    {Value: "color"},
+			"SHAPE":    {Value: "shape"},
+			"CATEGORY": {Value: "category"},
+		},
+	})
+	sortField := graphql.NewEnum(graphql.EnumConfig{
+		Name: "SortField",
+		Values: graphql.EnumValueConfigMap{
+			"ID":       {Value: "id"},
+			"COLOR":    {Value: "color"},
+			"SHAPE":    {Value: "shape"},
+			"CATEGORY": {Value: "category"},
+		},
+	})
+	sortOrder := graphql.NewEnum(graphql.EnumConfig{
+		Name: "SortOrder",
+		Values: graphql.EnumValueConfigMap{
+			"ASC":  {Value: "asc"},
+			"DESC": {Value: "desc"},
+		},
+	})
+
+	item := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Item",
+		Fields: graphql.Fields{
+			"id":       {Type: nonNullID, Resolve: itemID},
+			"color":    {Type: nonNullString},
+			"shape":    {Type: nonNullString},
+			"category": {Type: nonNullString},
+			"createdAt": {
+				Type:        graphql.DateTime,
+				Description: "When the item was created, or null if unknown",
+				Resolve:     createdAt,
+			},
+		},
+	})
+	itemList := graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(item)))
+
+	group := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Group",
+		Fields: graphql.Fields{
+			"name":  {Type: nonNullString},
+			"count": {Type: nonNullInt},
+			"items": {Type: itemList},
+		},
+	})
+	valueCount := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ValueCount",
+		Fields: graphql.Fields{
+			"value": {Type: nonNullString},
+			"count": {Type: nonNullInt},
+		},
+	})
+	stats := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Stats",
+		Fields: graphql.Fields{
+			"property": {Type: graphql.NewNonNull(propertyEnum)},
+			"total":    {Type: nonNullInt},
+			"values":   {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(valueCount)))},
+		},
+	})
+
+	itemFilter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "ItemFilter",
+		Description: "Properties an item must equal to match",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"color":    {Type: graphql.String},
+			"shape":    {Type: graphql.String},
+			"category": {Type: graphql.String},
+		},
+	})
+	itemSort := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ItemSort",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"field": {Type: graphql.NewNonNull(sortField)},
+			"order": {Type: sortOrder, DefaultValue: "asc"},
+		},
+	})
+	newItem := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "NewItem",
+		Description: "An item to create; a missing or zero id is assigned automatically",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"id":       {Type: idType},
+			"color":    {Type: nonNullString},
+			"shape":    {Type: nonNullString},
+			"category": {Type: nonNullString},
+		},
+	})
+	itemPatch := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "ItemPatch",
+		Description: "Fields to change on an existing item; omitted fields are kept",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"color":    {Type: graphql.String},
+			"shape":    {Type: graphql.String},
+			"category": {Type: graphql.String},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"items": {
+				Type: itemList,
+				Args: graphql.FieldConfigArgument{
+					"filter": {Type: itemFilter},
+					"sort":   {Type: itemSort},
+					"limit":  {Type: graphql.Int},
+					"offset": {Type: graphql.Int},
+				},
+				Resolve: r.items,
+			},
+			"item": {
+				Type:    item,
+				Args:    graphql.FieldConfigArgument{"id": {Type: nonNullID}},
+				Resolve: r.item,
+			},
+			"groups": {
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(group))),
+				Args: graphql.FieldConfigArgument{
+					"by":     {Type: graphql.NewNonNull(propertyEnum)},
+					"filter": {Type: itemFilter},
+				},
+				Resolve: r.groups,
+			},
+			"stats": {
+				Type: graphql.NewNonNull(stats),
+				Args: graphql.FieldConfigArgument{
+					"property": {Type: graphql.NewNonNull(propertyEnum)},
+					"filter":   {Type: itemFilter},
+				},
+				Resolve: r.stats,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createItem": {
+				Type:    graphql.NewNonNull(item),
+				Args:    graphql.FieldConfigArgument{"input": {Type: graphql.NewNonNull(newItem)}},
+				Resolve: r.createItem,
+			},
+			"updateItem": {
+				Type: graphql.NewNonNull(item),
+				Args: graphql.FieldConfigArgument{
+					"id":    {Type: nonNullID},
+					"input": {Type: graphql.NewNonNull(itemPatch)},
+				},
+				Resolve: r.updateItem,
+			},
+			"deleteItem": {
+				Type:    graphql.NewNonNull(graphql.Boolean),
+				Args:    graphql.FieldConfigArgument{"id": {Type: nonNullID}},
+				Resolve: r.deleteItem,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
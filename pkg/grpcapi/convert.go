@@ -0,0 +1,104 @@
+package grpcapi
+
+import (
+	"math"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	pb "github.com/ElodinLaarz/dashboard/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toProto converts a store item to its wire form, with a string ID in
+// string_id. Int IDs outside the int32 range cannot be represented and are
+// reported as OutOfRange.
+func toProto(item itemstore.Item) (*pb.Item, error) {
+	p := &pb.Item{
+		Color:    item.Color,
+		Shape:    item.Shape,
+		Category: item.Category,
+	}
+	id, ok := item.ID.Int()
+	if !ok {
+		p.StringId = item.ID.String()
+		return p, nil
+	}
+	if id < math.MinInt32 || id > math.MaxInt32 {
+		return nil, status.Errorf(codes.OutOfRange, "item ID %d does not fit in int32", id)
+	}
+	p.Id = int32(id)
+	return p, nil
+}
+
+// toProtoList converts items in order, failing on the first that cannot be
+// represented
+func toProtoList(items []itemstore.Item) ([]*pb.Item, error) {
+	out := make([]*pb.Item, 0, len(items))
+	for _, item := range items {
+		p, err := toProto(item)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// fromProto converts a wire item to a store item. A nil message is rejected
+// as InvalidArgument.
+func fromProto(p *pb.Item) (itemstore.Item, error) {
+	if p == nil {
+		return itemstore.Item{}, status.Error(codes.InvalidArgument, "item is required")
+	}
+	id, err := fromProtoID(p.GetId(), p.GetStringId())
+	if err != nil {
+		return itemstore.Item{}, err
+	}
+	return itemstore.Item{
+		ID:       id,
+		Color:    p.GetColor(),
+		Shape:    p.GetShape(),
+		Category: p.GetCategory(),
+	}, nil
+}
+
+// fromProtoID converts the id and string_id of a message to the ID they
+// name. Setting both is rejected as InvalidArgument; the store decides
+// whether a string ID suits it.
+func fromProtoID(id int32, stringID string) (itemstore.ID, error) {
+	switch {
+	case stringID == "":
+		return itemstore.IntID(int(id)), nil
+	case id != 0:
+		return itemstore.ID{}, status.Error(codes.InvalidArgument, "only one of id and string_id may be set")
+	}
+	return itemstore.StringID(stringID), nil
+}
+
+// eventToProto converts a store change to a stream event
+func eventToProto(c itemstore.Change) (*pb.ItemEvent, error) {
+	ev := &pb.ItemEvent{Type: eventType(c.Type)}
+	var err error
+	if ev.Item, err = toProto(c.Item); err != nil {
+		return nil, err
+	}
+	if c.Previous != nil {
+		if ev.Previous, err = toProto(*c.Previous); err != nil {
+			return nil, err
+		}
+	}
+	return ev, nil
+}
+
+// eventType maps a store change type to its enum value
+func eventType(t itemstore.ChangeType) pb.ItemEvent_Type {
+	switch t {
+	case itemstore.ItemCreated:
+		return pb.ItemEvent_TYPE_CREATED
+	case itemstore.ItemUpdated:
+		return pb.ItemEvent_TYPE_UPDATED
+	case itemstore.ItemDeleted:
+		return pb.ItemEvent_TYPE_DELETED
+	}
+	return pb.ItemEvent_TYPE_UNSPECIFIED
+}
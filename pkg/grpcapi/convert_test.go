@@ -0,0 +1,100 @@
+package grpcapi
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	pb "github.com/ElodinLaarz/dashboard/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestItemRoundTrip(t *testing.T) {
+	item := itemstore.Item{ID: itemstore.IntID(7), Color: "red", Shape: "circle", Category: "A"}
+
+	p, err := toProto(item)
+	if err != nil {
+		t.Fatalf("toProto: %v", err)
+	}
+	want := &pb.Item{Id: 7, Color: "red", Shape: "circle", Category: "A"}
+	if !proto.Equal(p, want) {
+		t.Errorf("toProto = %v, want %v", p, want)
+	}
+
+	back, err := fromProto(p)
+	if err != nil {
+		t.Fatalf("fromProto: %v", err)
+	}
+	if back != item {
+		t.Errorf("fromProto = %+v, want %+v", back, item)
+	}
+}
+
+func TestToProtoRejectsWideIDs(t *testing.T) {
+	_, err := toProto(itemstore.Item{ID: itemstore.IntID(math.MaxInt32 + 1), Color: "red", Shape: "circle", Category: "A"})
+	if status.Code(err) != codes.OutOfRange {
+		t.Errorf("code = %v, want OutOfRange (err %v)", status.Code(err), err)
+	}
+
+	_, err = toProtoList([]itemstore.Item{{ID: itemstore.IntID(1)}, {ID: itemstore.IntID(math.MaxInt32 + 1)}})
+	if status.Code(err) != codes.OutOfRange {
+		t.Errorf("list code = %v, want OutOfRange (err %v)", status.Code(err), err)
+	}
+}
+
+func TestFromProtoRejectsNil(t *testing.T) {
+	if _, err := fromProto(nil); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("code = %v, want InvalidArgument (err %v)", status.Code(err), err)
+	}
+}
+
+func TestEventToProto(t *testing.T) {
+	before := itemstore.Item{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"}
+	after := itemstore.Item{ID: itemstore.IntID(1), Color: "blue", Shape: "circle", Category: "A"}
+
+	tests := []struct {
+		name   string
+		change itemstore.Change
+		want   *pb.ItemEvent
+	}{
+		{
+			name:   "created",
+			change: itemstore.Change{Type: itemstore.ItemCreated, Item: after},
+			want: &pb.ItemEvent{Type: pb.ItemEvent_TYPE_CREATED,
+				Item: &pb.Item{Id: 1, Color: "blue", Shape: "circle", Category: "A"}},
+		},
+		{
+			name:   "updated",
+			change: itemstore.Change{Type: itemstore.ItemUpdated, Item: after, Previous: &before},
+			want: &pb.ItemEvent{Type: pb.ItemEvent_TYPE_UPDATED,
+				Item:     &pb.Item{Id: 1, Color: "blue", Shape: "circle", Category: "A"},
+				Previous: &pb.Item{Id: 1, Color: "red", Shape: "circle", Category: "A"}},
+		},
+		{
+			name:   "deleted",
+			change: itemstore.Change{Type: itemstore.ItemDeleted, Item: before},
+			want: &pb.ItemEvent{Type: pb.ItemEvent_TYPE_DELETED,
+				Item: &pb.Item{Id: 1, Color: "red", Shape: "circle", Category: "A"}},
+		},
+		{
+			name:   "unknown type",
+			change: itemstore.Change{Type: "item.renamed", Item: before},
+			want: &pb.ItemEvent{Type: pb.ItemEvent_TYPE_UNSPECIFIED,
+				Item: &pb.Item{Id: 1, Color: "red", Shape: "circle", Category: "A"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eventToProto(tt.change)
+			if err != nil {
+				t.Fatalf("eventToProto: %v", err)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("eventToProto = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+// Package grpcapi serves an item store over gRPC as the ItemService defined
+// in proto/items.proto.
+package grpcapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ElodinLaarz/dashboard/pkg/events"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	pb "github.com/ElodinLaarz/dashboard/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// eventBuffer is the number of events queued per stream before the oldest
+// are dropped
+const eventBuffer = 64
+
+// filterProperties are the keys accepted in ListItemsRequest.filters
+var filterProperties = []string{"color", "shape", "category"}
+
+// Server implements pb.ItemServiceServer on top of an item store
+type Server struct {
+	pb.UnimplementedItemServiceServer
+
+	store  itemstore.Store
+	hub    *events.Hub
+	logger *slog.Logger
+
+	// keyDigests are SHA-256 digests of the accepted API keys
+	keyDigests           [][sha256.Size]byte
+	allowUnauthenticated bool
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithLogger sets where internal errors are reported
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Server) { s.logger = l }
+}
+
+// WithAPIKeys requires one of keys, sent as "authorization: Bearer <key>" or
+// "x-api-key: <key>" metadata, on CreateItem, UpdateItem, and DeleteItem.
+// With no keys, writes are refused unless allowUnauthenticated is set.
+func WithAPIKeys(keys []string, allowUnauthenticated bool) Option {
+	return func(s *Server) {
+		s.keyDigests = make([][sha256.Size]byte, len(keys))
+		for i, key := range keys {
+			s.keyDigests[i] = sha256.Sum256([]byte(key))
+		}
+		s.allowUnauthenticated = allowUnauthenticated
+	}
+}
+
+// New creates a Server backed by store. Without WithAPIKeys, writes are
+// allowed from any client.
+func New(store itemstore.Store, opts ...Option) *Server {
+	s := &Server{
+		store:                store,
+		hub:                  events.NewHub(eventBuffer),
+		logger:               slog.New(slog.DiscardHandler),
+		allowUnauthenticated: true,
+		done:                 make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	store.OnChange(func(c itemstore.Change) {
+		s.hub.Publish(events.Event{Type: string(c.Type), Data: c})
+	})
+	return s
+}
+
+// Close ends every open StreamEvents call so a graceful stop of the gRPC
+// server does not wait on them
+func (s *Server) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// ListItems returns the items matching every filter
+func (s *Server) ListItems(ctx context.Context, req *pb.ListItemsRequest) (*pb.ListItemsResponse, error) {
+	for key := range req.GetFilters() {
+		if !slices.Contains(filterProperties, key) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown filter property %q (supported: %s)",
+				key, strings.Join(filterProperties, ", "))
+		}
+	}
+	filtered, err := s.store.FilterContext(ctx, req.GetFilters())
+	if err != nil {
+		return nil, s.statusError(ctx, err)
+	}
+	items, err := toProtoList(filtered)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ListItemsResponse{Items: items}, nil
+}
+
+// GetItem returns the item with the requested ID
+func (s *Server) GetItem(ctx context.Context, req *pb.GetItemRequest) (*pb.Item, error) {
+	id, err := fromProtoID(req.GetId(), req.GetStringId())
+	if err != nil {
+		return nil, err
+	}
+	item, err := s.store.Get(id)
+	if err != nil {
+		return nil, s.statusError(ctx, err)
+	}
+	return toProto(item)
+}
+
+// CreateItem stores a new item and returns it with its assigned ID
+func (s *Server) CreateItem(ctx context.Context, req *pb.CreateItemRequest) (*pb.Item, error) {
+	if err := s.authorizeWrite(ctx); err != nil {
+		return nil, err
+	}
+	item, err := fromProto(req.GetItem())
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.store.Add(item)
+	if err != nil {
+		return nil, s.statusError(ctx, err)
+	}
+	return toProto(created)
+}
+
+// UpdateItem replaces the stored item with the same ID
+func (s *Server) UpdateItem(ctx context.Context, req *pb.UpdateItemRequest) (*pb.Item, error) {
+	if err := s.authorizeWrite(ctx); err != nil {
+		return nil, err
+	}
+	item, err := fromProto(req.GetItem())
+	if err != nil {
+		return nil, err
+	}
+	updated, err := s.store.Update(item)
+	if err != nil {
+		return nil, s.statusError(ctx, err)
+	}
+	return toProto(updated)
+}
+
+// DeleteItem removes the item with the requested ID
+func (s *Server) DeleteItem(ctx context.Context, req *pb.DeleteItemRequest) (*pb.DeleteItemResponse, error) {
+	if err := s.authorizeWrite(ctx); err != nil {
+		return nil, err
+	}
+	id, err := fromProtoID(req.GetId(), req.GetStringId())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Delete(id); err != nil {
+		return nil, s.statusError(ctx, err)
+	}
+	return &pb.DeleteItemResponse{}, nil
+}
+
+// StreamEvents sends one event per store mutation until the client cancels
+// or the server is closed. Streams that fall behind lose their oldest
+// pending events.
+func (s *Server) StreamEvents(req *pb.StreamEventsRequest, stream pb.ItemService_StreamEventsServer) error {
+	sub := s.hub.Subscribe()
+	defer s.hub.Unsubscribe(sub)
+
+	// Send the response headers right away so clients know the stream is
+	// subscribed before they trigger any mutations
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.done:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case ev := <-sub.Events():
+			change, ok := ev.Data.(itemstore.Change)
+			if !ok {
+				continue
+			}
+			msg, err := eventToProto(change)
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// authorizeWrite applies the WithAPIKeys policy to a write call
+func (s *Server) authorizeWrite(ctx context.Context) error {
+	if len(s.keyDigests) == 0 {
+		if s.allowUnauthenticated {
+			return nil
+		}
+		return status.Error(codes.PermissionDenied, "writes are disabled: no API keys are configured")
+	}
+
+	key := requestAPIKey(ctx)
+	given := sha256.Sum256([]byte(key))
+	match := 0
+	for _, d := range s.keyDigests {
+		match |= subtle.ConstantTimeCompare(given[:], d[:])
+	}
+	if key == "" || match != 1 {
+		return status.Error(codes.Unauthenticated, "a valid API key is required")
+	}
+	return nil
+}
+
+// requestAPIKey returns the key from the authorization bearer token or,
+// failing that, the x-api-key metadata
+func requestAPIKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(v, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	if v := md.Get("x-api-key"); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// statusError maps store errors to canonical gRPC codes. Unrecognized errors
+// are logged and reported as a generic Internal so details never reach the
+// client.
+func (s *Server) statusError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, itemstore.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, itemstore.ErrDuplicateID):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, itemstore.ErrInvalidItem):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, itemstore.ErrReadOnly):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	s.logger.ErrorContext(ctx, "internal error", "error", err)
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,357 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	pb "github.com/ElodinLaarz/dashboard/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestStore returns a store holding three items
+func newTestStore(t *testing.T) *itemstore.ItemStore {
+	t.Helper()
+	store, err := itemstore.New([]itemstore.Item{
+		{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"},
+		{ID: itemstore.IntID(2), Color: "blue", Shape: "square", Category: "A"},
+		{ID: itemstore.IntID(3), Color: "green", Shape: "triangle", Category: "B"},
+	})
+	if err != nil {
+		t.Fatalf("itemstore.New: %v", err)
+	}
+	return store
+}
+
+// serve runs srv on an in-memory listener and returns a client connected to
+// it
+func serve(t *testing.T, srv *Server) pb.ItemServiceClient {
+	t.Helper()
+
+	g := grpc.NewServer()
+	pb.RegisterItemServiceServer(g, srv)
+	ln := bufconn.Listen(1 << 20)
+	go g.Serve(ln)
+	t.Cleanup(func() {
+		srv.Close()
+		g.Stop()
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewItemServiceClient(conn)
+}
+
+// newTestClient serves a fresh test store and returns a client for it
+func newTestClient(t *testing.T, opts ...Option) (pb.ItemServiceClient, *itemstore.ItemStore) {
+	t.Helper()
+	store := newTestStore(t)
+	return serve(t, New(store, opts...)), store
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestListItems(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := testContext(t)
+
+	tests := []struct {
+		name    string
+		filters map[string]string
+		wantIDs []int32
+	}{
+		{"all", nil, []int32{1, 2, 3}},
+		{"one filter", map[string]string{"category": "A"}, []int32{1, 2}},
+		{"two filters", map[string]string{"category": "A", "color": "blue"}, []int32{2}},
+		{"no match", map[string]string{"shape": "hexagon"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.ListItems(ctx, &pb.ListItemsRequest{Filters: tt.filters})
+			if err != nil {
+				t.Fatalf("ListItems: %v", err)
+			}
+			var ids []int32
+			for _, item := range resp.GetItems() {
+				ids = append(ids, item.GetId())
+			}
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("IDs = %v, want %v", ids, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Fatalf("IDs = %v, want %v", ids, tt.wantIDs)
+				}
+			}
+		})
+	}
+
+	_, err := client.ListItems(ctx, &pb.ListItemsRequest{Filters: map[string]string{"size": "large"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("unknown filter: code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestGetItem(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := testContext(t)
+
+	item, err := client.GetItem(ctx, &pb.GetItemRequest{Id: 2})
+	if err != nil {
+		t.Fatalf("GetItem: %v", err)
+	}
+	if item.GetColor() != "blue" || item.GetShape() != "square" {
+		t.Errorf("GetItem(2) = %v", item)
+	}
+
+	_, err = client.GetItem(ctx, &pb.GetItemRequest{Id: 99})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("missing item: code = %v, want NotFound", status.Code(err))
+	}
+}
+
+func TestCreateItem(t *testing.T) {
+	client, store := newTestClient(t)
+	ctx := testContext(t)
+
+	created, err := client.CreateItem(ctx, &pb.CreateItemRequest{
+		Item: &pb.Item{Color: "red", Shape: "square", Category: "C"},
+	})
+	if err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+	if created.GetId() != 4 {
+		t.Errorf("assigned ID = %d, want 4", created.GetId())
+	}
+	if store.Len() != 4 {
+		t.Errorf("store has %d items, want 4", store.Len())
+	}
+
+	tests := []struct {
+		name string
+		req  *pb.CreateItemRequest
+		want codes.Code
+	}{
+		{"duplicate ID", &pb.CreateItemRequest{Item: &pb.Item{Id: 1, Color: "red", Shape: "circle", Category: "A"}}, codes.AlreadyExists},
+		{"empty field", &pb.CreateItemRequest{Item: &pb.Item{Color: "red", Shape: "circle"}}, codes.InvalidArgument},
+		{"missing item", &pb.CreateItemRequest{}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.CreateItem(ctx, tt.req)
+			if status.Code(err) != tt.want {
+				t.Errorf("code = %v, want %v (err %v)", status.Code(err), tt.want, err)
+			}
+		})
+	}
+}
+
+func TestUpdateItem(t *testing.T) {
+	client, store := newTestClient(t)
+	ctx := testContext(t)
+
+	updated, err := client.UpdateItem(ctx, &pb.UpdateItemRequest{
+		Item: &pb.Item{Id: 1, Color: "purple", Shape: "circle", Category: "A"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateItem: %v", err)
+	}
+	if updated.GetColor() != "purple" {
+		t.Errorf("updated color = %q, want purple", updated.GetColor())
+	}
+	if got, _ := store.Get(itemstore.IntID(1)); got.Color != "purple" {
+		t.Errorf("stored color = %q, want purple", got.Color)
+	}
+
+	_, err = client.UpdateItem(ctx, &pb.UpdateItemRequest{
+		Item: &pb.Item{Id: 99, Color: "red", Shape: "circle", Category: "A"},
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("missing item: code = %v, want NotFound", status.Code(err))
+	}
+
+	_, err = client.UpdateItem(ctx, &pb.UpdateItemRequest{Item: &pb.Item{Id: 1}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("invalid item: code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestDeleteItem(t *testing.T) {
+	client, store := newTestClient(t)
+	ctx := testContext(t)
+
+	if _, err := client.DeleteItem(ctx, &pb.DeleteItemRequest{Id: 3}); err != nil {
+		t.Fatalf("DeleteItem: %v", err)
+	}
+	if store.Len() != 2 {
+		t.Errorf("store has %d items, want 2", store.Len())
+	}
+
+	_, err := client.DeleteItem(ctx, &pb.DeleteItemRequest{Id: 3})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("second delete: code = %v, want NotFound", status.Code(err))
+	}
+}
+
+func TestStringIDs(t *testing.T) {
+	const uuid = "6f1c2a4e-0d3b-4f7a-9c55-2b8e1d7a3f90"
+	store, err := itemstore.New([]itemstore.Item{
+		{ID: itemstore.StringID(uuid), Color: "red", Shape: "circle", Category: "A"},
+	}, itemstore.WithStringIDs())
+	if err != nil {
+		t.Fatalf("itemstore.New: %v", err)
+	}
+	client := serve(t, New(store, WithAPIKeys(nil, true)))
+	ctx := testContext(t)
+
+	list, err := client.ListItems(ctx, &pb.ListItemsRequest{})
+	if err != nil || len(list.GetItems()) != 1 || list.GetItems()[0].GetStringId() != uuid || list.GetItems()[0].GetId() != 0 {
+		t.Fatalf("ListItems = %v, %v; want the item by string_id", list, err)
+	}
+	if item, err := client.GetItem(ctx, &pb.GetItemRequest{StringId: uuid}); err != nil || item.GetColor() != "red" {
+		t.Errorf("GetItem = %v, %v; want the red item", item, err)
+	}
+	created, err := client.CreateItem(ctx, &pb.CreateItemRequest{Item: &pb.Item{Color: "blue", Shape: "square", Category: "B"}})
+	if err != nil || len(created.GetStringId()) != 36 {
+		t.Fatalf("CreateItem = %v, %v; want a generated UUID", created, err)
+	}
+	updated, err := client.UpdateItem(ctx, &pb.UpdateItemRequest{Item: &pb.Item{StringId: uuid, Color: "green", Shape: "circle", Category: "A"}})
+	if err != nil || updated.GetColor() != "green" {
+		t.Errorf("UpdateItem = %v, %v; want the green item", updated, err)
+	}
+	if _, err := client.DeleteItem(ctx, &pb.DeleteItemRequest{StringId: created.GetStringId()}); err != nil {
+		t.Errorf("DeleteItem: %v", err)
+	}
+	if store.Len() != 1 {
+		t.Errorf("store has %d items, want 1", store.Len())
+	}
+
+	if _, err := client.GetItem(ctx, &pb.GetItemRequest{Id: 1, StringId: uuid}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetItem with id and string_id: code = %v, want InvalidArgument", status.Code(err))
+	}
+	// A store of int IDs takes a number in string_id but no other string
+	intClient, _ := newTestClient(t)
+	if item, err := intClient.GetItem(ctx, &pb.GetItemRequest{StringId: "1"}); err != nil || item.GetId() != 1 {
+		t.Errorf("GetItem(string_id 1) = %v, %v; want item 1", item, err)
+	}
+	_, err = intClient.CreateItem(ctx, &pb.CreateItemRequest{Item: &pb.Item{StringId: uuid, Color: "blue", Shape: "square", Category: "B"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateItem with a string ID in a store of int IDs: code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	client, store := newTestClient(t)
+	ctx := testContext(t)
+
+	stream, err := client.StreamEvents(ctx, &pb.StreamEventsRequest{})
+	if err != nil {
+		t.Fatalf("StreamEvents: %v", err)
+	}
+	// Headers arrive once the server has subscribed
+	if _, err := stream.Header(); err != nil {
+		t.Fatalf("Header: %v", err)
+	}
+
+	if _, err := store.Add(itemstore.Item{Color: "red", Shape: "square", Category: "C"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Update(itemstore.Item{ID: itemstore.IntID(1), Color: "blue", Shape: "circle", Category: "A"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(itemstore.IntID(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		typ    pb.ItemEvent_Type
+		id     int32
+		hasPre bool
+	}{
+		{pb.ItemEvent_TYPE_CREATED, 4, false},
+		{pb.ItemEvent_TYPE_UPDATED, 1, true},
+		{pb.ItemEvent_TYPE_DELETED, 2, false},
+	}
+	for _, w := range want {
+		ev, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		if ev.GetType() != w.typ || ev.GetItem().GetId() != w.id || (ev.GetPrevious() != nil) != w.hasPre {
+			t.Errorf("event = %v, want type %v for item %d (previous %v)", ev, w.typ, w.id, w.hasPre)
+		}
+	}
+}
+
+func TestStreamEventsEndsOnClose(t *testing.T) {
+	srv := New(newTestStore(t))
+	client := serve(t, srv)
+
+	stream, err := client.StreamEvents(testContext(t), &pb.StreamEventsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Header(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Close()
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("Recv after Close: code = %v, want Unavailable", status.Code(err))
+	}
+}
+
+func TestWriteAuthorization(t *testing.T) {
+	item := &pb.Item{Color: "red", Shape: "square", Category: "C"}
+
+	tests := []struct {
+		name string
+		opts []Option
+		md   metadata.MD
+		want codes.Code
+	}{
+		{"open by default", nil, nil, codes.OK},
+		{"no keys refuses writes", []Option{WithAPIKeys(nil, false)}, nil, codes.PermissionDenied},
+		{"no keys, unauthenticated allowed", []Option{WithAPIKeys(nil, true)}, nil, codes.OK},
+		{"missing key", []Option{WithAPIKeys([]string{"secret"}, false)}, nil, codes.Unauthenticated},
+		{"wrong key", []Option{WithAPIKeys([]string{"secret"}, false)}, metadata.Pairs("x-api-key", "nope"), codes.Unauthenticated},
+		{"bearer token", []Option{WithAPIKeys([]string{"secret"}, false)}, metadata.Pairs("authorization", "Bearer secret"), codes.OK},
+		{"api key header", []Option{WithAPIKeys([]string{"other", "secret"}, false)}, metadata.Pairs("x-api-key", "secret"), codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t, tt.opts...)
+			ctx := metadata.NewOutgoingContext(testContext(t), tt.md)
+
+			_, err := client.CreateItem(ctx, &pb.CreateItemRequest{Item: item})
+			if status.Code(err) != tt.want {
+				t.Errorf("CreateItem code = %v, want %v (err %v)", status.Code(err), tt.want, err)
+			}
+			// Reads never need a key
+			if _, err := client.GetItem(ctx, &pb.GetItemRequest{Id: 1}); err != nil {
+				t.Errorf("GetItem: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Package i18n looks up user interface messages in per-language catalogs.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Bundle holds one message catalog per language. Messages missing from a
+// catalog come from the fallback language's.
+type Bundle struct {
+	catalogs map[string]map[string]string
+	fallback string
+}
+
+// Load reads every "<lang>.json" file in fsys, each a JSON object mapping
+// message keys to text, e.g. locales/de.json for German. The fallback
+// language must be among them.
+func Load(fsys fs.FS, fallback string) (*Bundle, error) {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{catalogs: make(map[string]map[string]string, len(files)), fallback: normalize(fallback)}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("message catalog %s: %w", file, err)
+		}
+		b.catalogs[normalize(strings.TrimSuffix(path.Base(file), ".json"))] = catalog
+	}
+	if _, ok := b.catalogs[b.fallback]; !ok {
+		return nil, fmt.Errorf("no message catalog for the fallback language %q", fallback)
+	}
+	return b, nil
+}
+
+// Languages returns the languages with a catalog, sorted
+func (b *Bundle) Languages() []string {
+	return slices.Sorted(maps.Keys(b.catalogs))
+}
+
+// Fallback returns the language whose catalog fills in missing messages
+func (b *Bundle) Fallback() string {
+	return b.fallback
+}
+
+// Match returns the language of the first tag with a catalog, trying each
+// tag's base language after the tag itself, e.g. "de" for "de-AT"
+func (b *Bundle) Match(tags ...string) (string, bool) {
+	for _, tag := range tags {
+		tag = normalize(tag)
+		if _, ok := b.catalogs[tag]; ok {
+			return tag, true
+		}
+		if base, _, ok := strings.Cut(tag, "-"); ok {
+			if _, ok := b.catalogs[base]; ok {
+				return base, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Message returns the text of key in lang, falling back to the fallback
+// language and then to the key itself, so a missing message shows up on the
+// page rather than as an empty string
+func (b *Bundle) Message(lang, key string) string {
+	if text, ok := b.catalogs[normalize(lang)][key]; ok {
+		return text
+	}
+	if text, ok := b.catalogs[b.fallback][key]; ok {
+		return text
+	}
+	return key
+}
+
+// Keys returns the message keys of lang's catalog, sorted
+func (b *Bundle) Keys(lang string) []string {
+	return slices.Sorted(maps.Keys(b.catalogs[normalize(lang)]))
+}
+
+// normalize lower-cases a language tag and writes it with hyphens
+func normalize(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
@@ -0,0 +1,77 @@
+package i18n
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func testBundle(t *testing.T) *Bundle {
+	t.Helper()
+	b, err := Load(fstest.MapFS{
+		"en.json":    {Data: []byte(`{"greeting": "Hello", "farewell": "Goodbye"}`)},
+		"de.json":    {Data: []byte(`{"greeting": "Hallo"}`)},
+		"pt-BR.json": {Data: []byte(`{"greeting": "Olá"}`)},
+		"notes.txt":  {Data: []byte("not a catalog")},
+	}, "en")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return b
+}
+
+func TestLoad(t *testing.T) {
+	b := testBundle(t)
+	if got, want := b.Languages(), []string{"de", "en", "pt-br"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Languages() = %q, want %q", got, want)
+	}
+
+	if _, err := Load(fstest.MapFS{"de.json": {Data: []byte(`{}`)}}, "en"); err == nil {
+		t.Error("Load() without a fallback catalog succeeded")
+	}
+	if _, err := Load(fstest.MapFS{"en.json": {Data: []byte(`{"a": 1}`)}}, "en"); err == nil {
+		t.Error("Load() with a non-string message succeeded")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	b := testBundle(t)
+	tests := []struct {
+		tags   []string
+		want   string
+		wantOK bool
+	}{
+		{[]string{"de"}, "de", true},
+		{[]string{"DE-at"}, "de", true},
+		{[]string{"fr", "de", "en"}, "de", true},
+		{[]string{"pt_BR"}, "pt-br", true},
+		{[]string{"pt"}, "", false},
+		{[]string{"fr"}, "", false},
+		{nil, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := b.Match(tt.tags...)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Match(%q) = %q, %v, want %q, %v", tt.tags, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	b := testBundle(t)
+	tests := []struct {
+		lang, key, want string
+	}{
+		{"de", "greeting", "Hallo"},
+		{"DE", "greeting", "Hallo"},
+		{"de", "farewell", "Goodbye"}, // missing in German
+		{"fr", "greeting", "Hello"},   // no French catalog
+		{"en", "missing.key", "missing.key"},
+		{"de", "missing.key", "missing.key"},
+	}
+	for _, tt := range tests {
+		if got := b.Message(tt.lang, tt.key); got != tt.want {
+			t.Errorf("Message(%q, %q) = %q, want %q", tt.lang, tt.key, got, tt.want)
+		}
+	}
+}
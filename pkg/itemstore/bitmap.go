@@ -0,0 +1,137 @@
+package itemstore
+
+import (
+	"iter"
+	"math/bits"
+	"slices"
+)
+
+// bitset is a set of item positions, one bit per position. Words past the
+// end of the slice are zero, so a bitset only grows as high positions are
+// set.
+type bitset []uint64
+
+// newBitset returns an empty bitset with room for n positions
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+// set returns b with position i added, growing it if needed
+func (b bitset) set(i int) bitset {
+	if w := i / 64; w >= len(b) {
+		b = append(b, make(bitset, w+1-len(b))...)
+	}
+	b[i/64] |= 1 << (i % 64)
+	return b
+}
+
+// clear removes position i
+func (b bitset) clear(i int) {
+	if w := i / 64; w < len(b) {
+		b[w] &^= 1 << (i % 64)
+	}
+}
+
+// padded returns a copy of b with room for exactly n positions
+func (b bitset) padded(n int) bitset {
+	out := newBitset(n)
+	copy(out, b)
+	return out
+}
+
+// and keeps only the positions also in o, which must be as long as b
+func (b bitset) and(o bitset) {
+	for i := range b {
+		b[i] &= o[i]
+	}
+}
+
+// or adds the positions in o, which must be as long as b
+func (b bitset) or(o bitset) {
+	for i := range b {
+		b[i] |= o[i]
+	}
+}
+
+// not complements b in place over positions below n and returns it
+func (b bitset) not(n int) bitset {
+	for i := range b {
+		b[i] = ^b[i]
+	}
+	if rem := n % 64; rem != 0 && len(b) > 0 {
+		b[len(b)-1] &= 1<<rem - 1
+	}
+	return b
+}
+
+// count returns the number of positions in b
+func (b bitset) count() int {
+	n := 0
+	for _, word := range b {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// positions yields the positions in b in ascending order
+func (b bitset) positions() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for w, word := range b {
+			for word != 0 {
+				if !yield(w*64 + bits.TrailingZeros64(word)) {
+					return
+				}
+				word &= word - 1
+			}
+		}
+	}
+}
+
+// bitmapIndex holds a bitset of item positions for every value of every
+// property, keyed by property and then value
+type bitmapIndex map[string]map[string]bitset
+
+// WithBitmapIndex makes the store keep a bitset of item positions for every
+// value of every property, so that Select evaluates AND, OR, and NOT as
+// bitwise operations. It costs one bit per item for each distinct value;
+// IndexStats reports the total.
+func WithBitmapIndex() Option {
+	return func(s *ItemStore) {
+		s.bitmaps = make(bitmapIndex)
+	}
+}
+
+// rebuild indexes items from scratch
+func (b bitmapIndex) rebuild(items []Item) {
+	for _, property := range properties {
+		values := make(map[string]bitset)
+		for i, item := range items {
+			v := item.property(property)
+			values[v] = values[v].set(i)
+		}
+		b[property] = values
+	}
+}
+
+// add indexes item at position i
+func (b bitmapIndex) add(i int, item Item) {
+	for _, property := range properties {
+		v := item.property(property)
+		b[property][v] = b[property][v].set(i)
+	}
+}
+
+// replace moves position i from previous's values to item's
+func (b bitmapIndex) replace(i int, previous, item Item) {
+	for _, property := range properties {
+		old, v := previous.property(property), item.property(property)
+		if old == v {
+			continue
+		}
+		b[property][old].clear(i)
+		if !slices.ContainsFunc(b[property][old], func(w uint64) bool { return w != 0 }) {
+			delete(b[property], old)
+		}
+		b[property][v] = b[property][v].set(i)
+	}
+}
@@ -0,0 +1,159 @@
+package itemstore
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestBitset(t *testing.T) {
+	var b bitset
+	for _, i := range []int{0, 63, 64, 130} {
+		b = b.set(i)
+	}
+	b.clear(63)
+	if got := slices.Collect(b.positions()); !reflect.DeepEqual(got, []int{0, 64, 130}) {
+		t.Errorf("positions = %v, want [0 64 130]", got)
+	}
+	// Complementing stops at n, so no position past the last item appears
+	if got := slices.Collect(b.padded(131).not(131).positions()); len(got) != 128 || slices.Contains(got, 130) || got[len(got)-1] != 129 {
+		t.Errorf("not(131) = %v, want 128 positions ending at 129", got)
+	}
+}
+
+// randomExpr returns a random expression of at most depth levels over the
+// values randomItem uses, with the odd unknown property or value
+func randomExpr(r *rand.Rand, depth int) Expr {
+	if depth == 0 || r.IntN(3) == 0 {
+		switch r.IntN(10) {
+		case 0:
+			return Eq("size", "big")
+		case 1:
+			return Eq("color", "purple")
+		case 2:
+			return Eq("color", "Red")
+		}
+		item := randomItem(r, 1)
+		property := properties[r.IntN(len(properties))]
+		return Eq(property, item.property(property))
+	}
+	subs := make([]Expr, r.IntN(4))
+	for i := range subs {
+		subs[i] = randomExpr(r, depth-1)
+	}
+	switch r.IntN(3) {
+	case 0:
+		return And(subs...)
+	case 1:
+		return Or(subs...)
+	}
+	return Not(randomExpr(r, depth-1))
+}
+
+func TestItemStore_SelectBitmapMatchesScan(t *testing.T) {
+	r := rand.New(rand.NewPCG(7, 11))
+	for dataset := range 20 {
+		items := make([]Item, r.IntN(300))
+		for i := range items {
+			items[i] = randomItem(r, i+1)
+		}
+		scan, err := New(items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bitmap, err := New(items, WithBitmapIndex())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for step := range 50 {
+			op := r.IntN(5)
+			seed := r.Uint64()
+			mutate(scan, op, rand.New(rand.NewPCG(seed, 0)))
+			mutate(bitmap, op, rand.New(rand.NewPCG(seed, 0)))
+
+			for range 10 {
+				expr := randomExpr(r, 3)
+				want, got := idsOf(scan.Select(expr)), idsOf(bitmap.Select(expr))
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("dataset %d step %d: Select(%+v) = %v with the bitmap index, %v by scanning", dataset, step, expr, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	store, err := New(testItems, WithBitmapIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr Expr
+		want []int
+	}{
+		{And(Eq("color", "red"), Not(Eq("category", "A"))), []int{3}},
+		{Or(Eq("shape", "circle"), Eq("color", "BLUE")), []int{1, 2, 4}},
+		{Not(Or(Eq("color", "red"), Eq("color", "green"))), []int{2}},
+		{And(), []int{1, 2, 3, 4}},
+		{Or(), nil},
+		{Eq("size", ""), nil},
+	}
+	for _, tt := range tests {
+		if got := idsOf(store.Select(tt.expr)); !slices.Equal(got, tt.want) {
+			t.Errorf("Select(%+v) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestItemStore_IndexStats(t *testing.T) {
+	store, err := New(testItems, WithBitmapIndex(), WithIndexes("color"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := store.IndexStats()
+	// Three colors, two shapes, and two categories
+	if stats.Items != 4 || stats.Bitmaps != 7 || stats.PostingLists != 3 {
+		t.Errorf("IndexStats() = %+v, want 4 items, 7 bitmaps, and 3 posting lists", stats)
+	}
+	if stats.BitmapBytes < 7*8 || stats.PostingBytes < 4*8 {
+		t.Errorf("IndexStats() = %+v, want at least a word per bitmap and per position", stats)
+	}
+	if stats := newTestStore(t).IndexStats(); stats.Bitmaps != 0 || stats.PostingLists != 0 {
+		t.Errorf("IndexStats() without indexes = %+v", stats)
+	}
+}
+
+// BenchmarkSelect compares evaluating a combined filter over 50,000 items
+// by scanning and with the bitmap index
+func BenchmarkSelect(b *testing.B) {
+	items := make([]Item, 50000)
+	for i := range items {
+		items[i] = Item{
+			ID:       IntID(i + 1),
+			Color:    fmt.Sprintf("color-%d", i%40),
+			Shape:    []string{"circle", "square", "triangle"}[i%3],
+			Category: fmt.Sprintf("c%d", i%25),
+		}
+	}
+	expr := And(
+		Or(Eq("color", "color-1"), Eq("color", "color-2"), Eq("color", "color-3")),
+		Eq("shape", "circle"),
+		Not(Eq("category", "c4")),
+	)
+
+	for name, opts := range map[string][]Option{"scan": nil, "bitmap": {WithBitmapIndex()}} {
+		store, err := New(items, opts...)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				store.Select(expr)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package itemstore
+
+import (
+	"cmp"
+	"slices"
+)
+
+// valueCounts holds the number of items with each value of each property,
+// keyed by property and then value. Values no item has are removed, so the
+// keys of a property's map are exactly its values in use.
+type valueCounts map[string]map[string]int
+
+// newValueCounts counts the values of items
+func newValueCounts(items []Item) valueCounts {
+	c := make(valueCounts, len(properties))
+	for _, property := range properties {
+		c[property] = make(map[string]int)
+	}
+	for _, item := range items {
+		c.add(item)
+	}
+	return c
+}
+
+// add counts item's values
+func (c valueCounts) add(item Item) {
+	for _, property := range properties {
+		c[property][item.property(property)]++
+	}
+}
+
+// remove uncounts item's values, dropping any no item has any more
+func (c valueCounts) remove(item Item) {
+	for _, property := range properties {
+		values, v := c[property], item.property(property)
+		if values[v]--; values[v] <= 0 {
+			delete(values, v)
+		}
+	}
+}
+
+// Combination is one distinct tuple of property values and the number of
+// items that have it
+type Combination struct {
+	// Values holds the value of each requested property, in request order
+	Values []string
+	Count  int
+}
+
+// Combinations returns each distinct tuple of values of props among the
+// items with its count, most common first and ties in order of values. A
+// single property is read from the value counts like CountBy instead of
+// scanning the items. Unknown properties have the value "" on every item.
+func (s *ItemStore) Combinations(props ...string) []Combination {
+	var combinations []Combination
+	if len(props) == 1 && IsProperty(props[0]) {
+		for value, n := range s.CountBy(props[0]) {
+			combinations = append(combinations, Combination{Values: []string{value}, Count: n})
+		}
+	} else {
+		positions := make(map[string]int)
+		key := make([]byte, 0, 64)
+		for _, item := range s.current() {
+			key = key[:0]
+			for _, property := range props {
+				key = append(key, item.property(property)...)
+				key = append(key, 0)
+			}
+			if i, ok := positions[string(key)]; ok {
+				combinations[i].Count++
+				continue
+			}
+			values := make([]string, len(props))
+			for i, property := range props {
+				values[i] = item.property(property)
+			}
+			positions[string(key)] = len(combinations)
+			combinations = append(combinations, Combination{Values: values, Count: 1})
+		}
+	}
+	slices.SortFunc(combinations, func(a, b Combination) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), slices.Compare(a.Values, b.Values))
+	})
+	return combinations
+}
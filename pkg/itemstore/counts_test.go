@@ -0,0 +1,111 @@
+package itemstore
+
+import (
+	"maps"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// recount counts the values of items from scratch
+func recount(items []Item) map[string]map[string]int {
+	counts := make(map[string]map[string]int)
+	for _, property := range properties {
+		counts[property] = make(map[string]int)
+		for _, item := range items {
+			counts[property][item.property(property)]++
+		}
+	}
+	return counts
+}
+
+func TestItemStore_CountsFollowMutations(t *testing.T) {
+	r := rand.New(rand.NewPCG(13, 17))
+	for dataset := range 20 {
+		items := make([]Item, r.IntN(50))
+		for i := range items {
+			items[i] = randomItem(r, i+1)
+		}
+		store, err := New(items)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for step := range 200 {
+			op := r.IntN(5)
+			mutate(store, op, r)
+
+			want := recount(store.Filter(nil))
+			if got := store.CountsBy(properties...); !reflect.DeepEqual(got, want) {
+				t.Fatalf("dataset %d step %d (op %d): CountsBy() = %v, recounted %v", dataset, step, op, got, want)
+			}
+			for _, property := range properties {
+				wantValues := slices.Sorted(maps.Keys(want[property]))
+				if got := store.GetUniqueValues(property); !slices.Equal(got, wantValues) {
+					t.Fatalf("dataset %d step %d (op %d): GetUniqueValues(%s) = %v, want %v", dataset, step, op, property, got, wantValues)
+				}
+			}
+		}
+	}
+}
+
+func TestItemStore_CountsAreCopies(t *testing.T) {
+	store := newTestStore(t)
+	store.CountBy("color")["red"] = 100
+	if got := store.CountBy("color")["red"]; got != 2 {
+		t.Errorf("CountBy(color)[red] = %d after changing a returned map, want 2", got)
+	}
+}
+
+func TestItemStore_Combinations(t *testing.T) {
+	store := newTestStore(t)
+	// Item 5 repeats the color and shape of item 1
+	if _, err := store.Add(Item{Color: "red", Shape: "circle", Category: "C"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		props []string
+		want  []Combination
+	}{
+		{
+			[]string{"color", "shape"},
+			[]Combination{
+				{[]string{"red", "circle"}, 2},
+				{[]string{"blue", "square"}, 1},
+				{[]string{"green", "circle"}, 1},
+				{[]string{"red", "square"}, 1},
+			},
+		},
+		{
+			[]string{"color"},
+			[]Combination{{[]string{"red"}, 3}, {[]string{"blue"}, 1}, {[]string{"green"}, 1}},
+		},
+		{
+			[]string{"category", "shape", "color", "shape"},
+			[]Combination{
+				{[]string{"A", "circle", "red", "circle"}, 1},
+				{[]string{"A", "square", "blue", "square"}, 1},
+				{[]string{"B", "circle", "green", "circle"}, 1},
+				{[]string{"B", "square", "red", "square"}, 1},
+				{[]string{"C", "circle", "red", "circle"}, 1},
+			},
+		},
+		{[]string{"weight"}, []Combination{{[]string{""}, 5}}},
+	}
+	for _, tt := range tests {
+		if got := store.Combinations(tt.props...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Combinations(%q) = %v, want %v", tt.props, got, tt.want)
+		}
+	}
+
+	empty, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, props := range [][]string{{"color"}, {"color", "shape"}} {
+		if got := empty.Combinations(props...); len(got) != 0 {
+			t.Errorf("Combinations(%q) of an empty store = %v, want none", props, got)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package itemstore
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// csvHeader names the columns WriteCSV writes
+var csvHeader = []string{"id", "color", "shape", "category", "createdAt"}
+
+// SaveJSON writes the store's items to w as a JSON array that LoadJSON reads
+// back. It writes a snapshot without holding the lock, so a slow w never
+// holds up writers, and mutations made meanwhile are not included.
+func (s *ItemStore) SaveJSON(w io.Writer) error {
+	return WriteJSON(w, s.current())
+}
+
+// WriteJSON writes items to w as a JSON array that LoadJSON reads back
+func WriteJSON(w io.Writer, items []Item) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	bw.WriteByte('[')
+	for i, item := range items {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		if err := enc.Encode(&item); err != nil {
+			return fmt.Errorf("encode item %s: %w", item.ID, err)
+		}
+	}
+	bw.WriteString("]\n")
+	return bw.Flush()
+}
+
+// WriteCSV writes items to w as CSV with a header row. Creation times are
+// RFC 3339, and empty for items without one.
+func WriteCSV(w io.Writer, items []Item) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, item := range items {
+		var created string
+		if !item.CreatedAt.IsZero() {
+			created = item.CreatedAt.Format(time.RFC3339)
+		}
+		cw.Write([]string{item.ID.String(), item.Color, item.Shape, item.Category, created})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvRequired are the columns ReadCSV needs; the others may be left out
+var csvRequired = []string{"color", "shape", "category"}
+
+// ReadCSV reads items in the form WriteCSV writes them: a header row naming
+// the columns, in any order and any case, then one item per row. The color,
+// shape, and category columns are required, while id and createdAt may be
+// left out or empty, for the store to fill in. Values are trimmed of
+// surrounding spaces. The items are not validated. Malformed content is
+// reported as a *PositionError.
+func ReadCSV(r io.Reader) ([]Item, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("no header row")
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+
+	// columns maps each column name to its position in a row
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			// Spreadsheets often start the file with a byte order mark
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.TrimSpace(name)
+		line, column := cr.FieldPos(i)
+		known := slices.IndexFunc(csvHeader, func(c string) bool { return strings.EqualFold(c, name) })
+		if known < 0 {
+			return nil, &PositionError{Line: line, Column: column, Err: fmt.Errorf("unknown column %q (supported: %s)", name, strings.Join(csvHeader, ", "))}
+		}
+		if _, seen := columns[csvHeader[known]]; seen {
+			return nil, &PositionError{Line: line, Column: column, Err: fmt.Errorf("column %q appears twice", csvHeader[known])}
+		}
+		columns[csvHeader[known]] = i
+	}
+	for _, name := range csvRequired {
+		if _, ok := columns[name]; !ok {
+			return nil, &PositionError{Line: 1, Column: 1, Err: fmt.Errorf("missing column %q", name)}
+		}
+	}
+
+	var items []Item
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		field := func(name string) (string, bool) {
+			i, ok := columns[name]
+			if !ok {
+				return "", false
+			}
+			return strings.TrimSpace(record[i]), true
+		}
+		fail := func(name string, err error) error {
+			line, column := cr.FieldPos(columns[name])
+			return &PositionError{Line: line, Column: column, Err: err}
+		}
+
+		var item Item
+		item.Color, _ = field("color")
+		item.Shape, _ = field("shape")
+		item.Category, _ = field("category")
+		if v, ok := field("id"); ok && v != "" {
+			if item.ID, err = parseID(v); err != nil {
+				return nil, fail("id", err)
+			}
+		}
+		if v, ok := field("createdAt"); ok && v != "" {
+			if item.CreatedAt, err = time.Parse(time.RFC3339, v); err != nil {
+				return nil, fail("createdAt", fmt.Errorf("invalid createdAt %q, want an RFC 3339 time", v))
+			}
+		}
+		items = append(items, item)
+	}
+}
+
+// csvError returns a *csv.ParseError as a *PositionError
+func csvError(err error) error {
+	var pe *csv.ParseError
+	if errors.As(err, &pe) {
+		return &PositionError{Line: pe.Line, Column: pe.Column, Err: pe.Err}
+	}
+	return err
+}
@@ -0,0 +1,95 @@
+package itemstore
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteCSV(t *testing.T) {
+	items := []Item{
+		{ID: IntID(1), Color: "red", Shape: "circle", Category: "A, B"},
+		{ID: IntID(2), Color: "blue", Shape: "square", Category: `say "hi"`, CreatedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, items); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,color,shape,category,createdAt\n" +
+		"1,red,circle,\"A, B\",\n" +
+		"2,blue,square,\"say \"\"hi\"\"\",2026-03-01T12:00:00Z\n"
+	if buf.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, testItems); err != nil {
+		t.Fatal(err)
+	}
+	items, err := LoadJSON(&buf)
+	if err != nil {
+		t.Fatalf("LoadJSON(WriteJSON()) error = %v", err)
+	}
+	if !reflect.DeepEqual(items, testItems) {
+		t.Errorf("round trip = %+v, want %+v", items, testItems)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testItems); err != nil {
+		t.Fatal(err)
+	}
+	items, err := ReadCSV(&buf)
+	if err != nil {
+		t.Fatalf("ReadCSV(WriteCSV()) error = %v", err)
+	}
+	if !reflect.DeepEqual(items, testItems) {
+		t.Errorf("round trip = %+v, want %+v", items, testItems)
+	}
+
+	// Columns may come in any order and case, and id and createdAt may be
+	// left out
+	items, err = ReadCSV(strings.NewReader("\ufeffCategory, Color ,shape\nA,red,circle\n B ,blue, square\n"))
+	want := []Item{{Color: "red", Shape: "circle", Category: "A"}, {Color: "blue", Shape: "square", Category: "B"}}
+	if err != nil || !reflect.DeepEqual(items, want) {
+		t.Errorf("ReadCSV() = %+v, %v; want %+v", items, err, want)
+	}
+
+	// IDs that are not numbers are string IDs
+	items, err = ReadCSV(strings.NewReader("id,color,shape,category\nab-1,red,circle,A\n7,blue,square,B\n"))
+	want = []Item{{ID: StringID("ab-1"), Color: "red", Shape: "circle", Category: "A"}, {ID: IntID(7), Color: "blue", Shape: "square", Category: "B"}}
+	if err != nil || !reflect.DeepEqual(items, want) {
+		t.Errorf("ReadCSV() = %+v, %v; want %+v", items, err, want)
+	}
+}
+
+func TestReadCSV_Invalid(t *testing.T) {
+	tests := []struct {
+		name, input string
+		line        int
+		want        string
+	}{
+		{"unknown column", "color,shape,category,size\n", 1, `unknown column "size"`},
+		{"repeated column", "color,shape,category,Color\n", 1, `column "color" appears twice`},
+		{"missing column", "color,shape\n", 1, `missing column "category"`},
+		{"bad id", "id,color,shape,category\n1,red,circle,A\n0,red,circle,A\n", 3, `invalid id "0"`},
+		{"bad time", "color,shape,category,createdAt\nred,circle,A,yesterday\n", 2, "invalid createdAt"},
+		{"short row", "color,shape,category\nred,circle\n", 2, "wrong number of fields"},
+	}
+	for _, tt := range tests {
+		_, err := ReadCSV(strings.NewReader(tt.input))
+		var pe *PositionError
+		if !errors.As(err, &pe) || pe.Line != tt.line || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ReadCSV() error = %v, want %q on line %d", tt.name, err, tt.want, tt.line)
+		}
+	}
+	if _, err := ReadCSV(strings.NewReader("")); err == nil {
+		t.Error("ReadCSV() of an empty file succeeded")
+	}
+}
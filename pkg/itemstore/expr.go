@@ -0,0 +1,153 @@
+package itemstore
+
+// Expr is a filter over item properties: an equality test, or an AND, OR,
+// or NOT of other expressions. Build one with Eq, And, Or, and Not and run
+// it with Select.
+type Expr interface {
+	// matches reports whether item satisfies the expression
+	matches(item Item) bool
+	// normalize returns the expression with its values in the form s
+	// stores them in
+	normalize(s *ItemStore) Expr
+	// bits returns the positions of the matching items among the n items
+	// indexed by b. The result is the caller's to modify.
+	bits(b bitmapIndex, n int) bitset
+}
+
+// Eq matches items whose property has value. Values are compared as
+// Filter compares them, so "Grey" matches a gray item. A name that is not a
+// property matches no item.
+func Eq(property, value string) Expr {
+	return eqExpr{property: property, value: value}
+}
+
+// And matches items that match every expression; with none it matches
+// every item
+func And(exprs ...Expr) Expr {
+	return andExpr(exprs)
+}
+
+// Or matches items that match any of the expressions; with none it matches
+// no item
+func Or(exprs ...Expr) Expr {
+	return orExpr(exprs)
+}
+
+// Not matches items that do not match expr
+func Not(expr Expr) Expr {
+	return notExpr{expr: expr}
+}
+
+type eqExpr struct {
+	property, value string
+}
+
+func (e eqExpr) matches(item Item) bool {
+	return IsProperty(e.property) && item.property(e.property) == e.value
+}
+
+func (e eqExpr) normalize(s *ItemStore) Expr {
+	return eqExpr{property: e.property, value: s.filterValue(e.property, e.value)}
+}
+
+func (e eqExpr) bits(b bitmapIndex, n int) bitset {
+	return b[e.property][e.value].padded(n)
+}
+
+type andExpr []Expr
+
+func (e andExpr) matches(item Item) bool {
+	for _, sub := range e {
+		if !sub.matches(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e andExpr) normalize(s *ItemStore) Expr {
+	return andExpr(normalizeAll(s, e))
+}
+
+func (e andExpr) bits(b bitmapIndex, n int) bitset {
+	if len(e) == 0 {
+		return newBitset(n).not(n)
+	}
+	set := e[0].bits(b, n)
+	for _, sub := range e[1:] {
+		set.and(sub.bits(b, n))
+	}
+	return set
+}
+
+type orExpr []Expr
+
+func (e orExpr) matches(item Item) bool {
+	for _, sub := range e {
+		if sub.matches(item) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e orExpr) normalize(s *ItemStore) Expr {
+	return orExpr(normalizeAll(s, e))
+}
+
+func (e orExpr) bits(b bitmapIndex, n int) bitset {
+	set := newBitset(n)
+	for _, sub := range e {
+		set.or(sub.bits(b, n))
+	}
+	return set
+}
+
+type notExpr struct {
+	expr Expr
+}
+
+func (e notExpr) matches(item Item) bool {
+	return !e.expr.matches(item)
+}
+
+func (e notExpr) normalize(s *ItemStore) Expr {
+	return notExpr{expr: e.expr.normalize(s)}
+}
+
+func (e notExpr) bits(b bitmapIndex, n int) bitset {
+	return e.expr.bits(b, n).not(n)
+}
+
+// normalizeAll normalizes each of exprs into a new slice
+func normalizeAll(s *ItemStore, exprs []Expr) []Expr {
+	normalized := make([]Expr, len(exprs))
+	for i, e := range exprs {
+		normalized[i] = e.normalize(s)
+	}
+	return normalized
+}
+
+// Select returns the items matching expr, in store order. With
+// WithBitmapIndex the expression is evaluated as bitwise operations over
+// the index and only the final result is turned into items; otherwise every
+// item is tested.
+func (s *ItemStore) Select(expr Expr) []Item {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	expr = expr.normalize(s)
+	var result []Item
+	if s.bitmaps != nil {
+		for i := range expr.bits(s.bitmaps, len(s.items)).positions() {
+			result = append(result, s.items[i])
+		}
+		return result
+	}
+	for _, item := range s.items {
+		if expr.matches(item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
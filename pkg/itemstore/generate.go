@@ -0,0 +1,132 @@
+package itemstore
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Weighted is a value drawn with probability proportional to its weight
+type Weighted struct {
+	Value  string
+	Weight float64
+}
+
+// GenOption configures Generate
+type GenOption func(*genConfig)
+
+type genConfig struct {
+	colors     []Weighted
+	shapes     []Weighted
+	categories int
+	skew       float64
+	from, to   time.Time
+}
+
+// WithColors draws colors from the given weighted values instead of the
+// default mix, where a few colors are common and the rest rare. Values
+// without a name or a positive weight are ignored.
+func WithColors(colors ...Weighted) GenOption {
+	return func(c *genConfig) {
+		if usable := usableWeights(colors); len(usable) > 0 {
+			c.colors = usable
+		}
+	}
+}
+
+// WithShapes draws shapes from the given weighted values instead of an even
+// mix of circles, squares, and triangles. Values without a name or a
+// positive weight are ignored.
+func WithShapes(shapes ...Weighted) GenOption {
+	return func(c *genConfig) {
+		if usable := usableWeights(shapes); len(usable) > 0 {
+			c.shapes = usable
+		}
+	}
+}
+
+// WithCategories draws from n categories following a Zipf distribution with
+// exponent skew, so the first categories hold most items and the rest form
+// a long tail. skew must be greater than 1; the default is 50 categories
+// with a skew of 1.2.
+func WithCategories(n int, skew float64) GenOption {
+	return func(c *genConfig) {
+		if n > 0 && skew > 1 {
+			c.categories, c.skew = n, skew
+		}
+	}
+}
+
+// WithCreatedBetween spreads the items' creation times evenly at random
+// over [from, to). Without it items have no CreatedAt.
+func WithCreatedBetween(from, to time.Time) GenOption {
+	return func(c *genConfig) {
+		if to.After(from) {
+			c.from, c.to = from, to
+		}
+	}
+}
+
+// Generate returns n valid items with IDs 1 to n for load testing. The same
+// seed and options always produce the same items.
+func Generate(n int, seed int64, opts ...GenOption) []Item {
+	c := genConfig{
+		colors: []Weighted{
+			{"red", 30}, {"blue", 25}, {"green", 20}, {"yellow", 10},
+			{"purple", 8}, {"orange", 5}, {"gray", 2},
+		},
+		shapes:     []Weighted{{"circle", 1}, {"square", 1}, {"triangle", 1}},
+		categories: 50,
+		skew:       1.2,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	category := rand.NewZipf(r, c.skew, 1, uint64(c.categories-1))
+	categories := make([]string, c.categories)
+	for i := range categories {
+		categories[i] = fmt.Sprintf("Category %02d", i+1)
+	}
+	items := make([]Item, n)
+	for i := range items {
+		items[i] = Item{
+			ID:       IntID(i + 1),
+			Color:    pick(r, c.colors),
+			Shape:    pick(r, c.shapes),
+			Category: categories[category.Uint64()],
+		}
+		if !c.from.IsZero() {
+			items[i].CreatedAt = c.from.Add(time.Duration(r.Int63n(int64(c.to.Sub(c.from))))).UTC()
+		}
+	}
+	return items
+}
+
+// pick draws one of values with probability proportional to its weight
+func pick(r *rand.Rand, values []Weighted) string {
+	var total float64
+	for _, v := range values {
+		total += v.Weight
+	}
+	x := r.Float64() * total
+	for _, v := range values {
+		if x < v.Weight {
+			return v.Value
+		}
+		x -= v.Weight
+	}
+	return values[len(values)-1].Value
+}
+
+// usableWeights returns the values that have a name and a positive weight
+func usableWeights(values []Weighted) []Weighted {
+	var usable []Weighted
+	for _, v := range values {
+		if v.Value != "" && v.Weight > 0 {
+			usable = append(usable, v)
+		}
+	}
+	return usable
+}
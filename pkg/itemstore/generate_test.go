@@ -0,0 +1,69 @@
+package itemstore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGenerate(t *testing.T) {
+	items := Generate(5000, 42)
+	if !reflect.DeepEqual(Generate(5000, 42), items) {
+		t.Fatal("Generate() with the same seed produced different items")
+	}
+	if reflect.DeepEqual(Generate(5000, 43), items) {
+		t.Error("Generate() with another seed produced the same items")
+	}
+	if _, err := New(items); err != nil {
+		t.Fatalf("New(Generate()) error = %v", err)
+	}
+	for i, item := range items {
+		if err := item.Validate(); err != nil || item.ID != IntID(i+1) {
+			t.Fatalf("items[%d] = %+v: %v", i, item, err)
+		}
+	}
+
+	counts := recount(items)
+	colors, categories := counts["color"], counts["category"]
+	if colors["red"] <= colors["gray"] {
+		t.Errorf("color counts = %v, want red far more common than gray", colors)
+	}
+	if categories["Category 01"] <= categories["Category 10"] || len(categories) < 20 {
+		t.Errorf("category counts = %v, want a long tail", categories)
+	}
+}
+
+func TestGenerate_Options(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	items := Generate(1000, 1,
+		WithColors(Weighted{"teal", 1}, Weighted{"ignored", 0}),
+		WithShapes(Weighted{"circle", 3}, Weighted{"square", 1}),
+		WithCategories(1, 2),
+		WithCreatedBetween(from, to),
+	)
+	shapes := recount(items)["shape"]
+	if shapes["circle"] <= shapes["square"] || len(shapes) != 2 {
+		t.Errorf("shape counts = %v, want mostly circles", shapes)
+	}
+	for _, item := range items {
+		if item.Color != "teal" || item.Category != "Category 01" {
+			t.Fatalf("item = %+v, want only teal and Category 01", item)
+		}
+		if item.CreatedAt.Before(from) || !item.CreatedAt.Before(to) {
+			t.Fatalf("CreatedAt = %v, want within %v and %v", item.CreatedAt, from, to)
+		}
+	}
+
+	// Unusable options keep the defaults
+	if items := Generate(10, 1, WithColors(), WithCategories(0, 0.5)); items[0].Color == "" || items[0].Category == "" {
+		t.Errorf("Generate() = %+v, want the default colors and categories", items[0])
+	}
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		Generate(100000, 1)
+	}
+}
@@ -0,0 +1,183 @@
+package itemstore
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ID identifies an item. Stores number their items with positive ints unless
+// made WithStringIDs, which keys them by strings instead, e.g. the UUIDs of
+// an upstream system. The zero ID is no ID at all; Add assigns one.
+type ID struct {
+	n int
+	s string
+}
+
+// IntID returns the ID numbered n
+func IntID(n int) ID {
+	return ID{n: n}
+}
+
+// StringID returns the ID named s
+func StringID(s string) ID {
+	return ID{s: s}
+}
+
+// IsZero reports whether id is no ID
+func (id ID) IsZero() bool {
+	return id == ID{}
+}
+
+// Int returns the number of an int ID, or false for a string ID
+func (id ID) Int() (int, bool) {
+	return id.n, id.s == ""
+}
+
+// String returns the ID as it appears in paths and files: the decimal
+// number of an int ID, or a string ID as it is
+func (id ID) String() string {
+	if id.s != "" {
+		return id.s
+	}
+	return strconv.Itoa(id.n)
+}
+
+// Compare orders IDs: int IDs by number, string IDs lexically, and int IDs
+// before string ones
+func (id ID) Compare(other ID) int {
+	_, isInt := id.Int()
+	_, otherInt := other.Int()
+	switch {
+	case isInt && otherInt:
+		return cmp.Compare(id.n, other.n)
+	case isInt != otherInt:
+		if isInt {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(id.s, other.s)
+}
+
+// MarshalJSON writes an int ID as a number and a string ID as a string
+func (id ID) MarshalJSON() ([]byte, error) {
+	if n, ok := id.Int(); ok {
+		return strconv.AppendInt(nil, int64(n), 10), nil
+	}
+	return json.Marshal(id.s)
+}
+
+// UnmarshalJSON reads a number as an int ID and a string as a string ID.
+// Stores convert between the two, so either form of an ID is accepted.
+func (id *ID) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*id = ID{}
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*id = StringID(s)
+		return nil
+	}
+	var n int
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("id %s is neither an integer nor a string", data)
+	}
+	*id = IntID(n)
+	return nil
+}
+
+// WithStringIDs makes the store key items by string IDs: items added without
+// an ID get a random UUID, IDs given as numbers are kept as their decimal
+// strings, and IDs sort lexically
+func WithStringIDs() Option {
+	return func(s *ItemStore) {
+		s.stringIDs = true
+	}
+}
+
+// StringIDs reports whether the store was made WithStringIDs
+func (s *ItemStore) StringIDs() bool {
+	return s.stringIDs
+}
+
+// ParseID parses an ID from a path or a form: a positive number, or in a
+// store made WithStringIDs any string that is not blank
+func (s *ItemStore) ParseID(v string) (ID, error) {
+	if s.stringIDs {
+		if strings.TrimSpace(v) == "" {
+			return ID{}, fmt.Errorf("invalid item ID %q", v)
+		}
+		return StringID(v), nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return ID{}, fmt.Errorf("invalid item ID %q", v)
+	}
+	return IntID(n), nil
+}
+
+// parseID reads an ID as String writes it: a number is an int ID, which
+// must be positive, and anything else a string ID
+func parseID(v string) (ID, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return StringID(v), nil
+	}
+	if n <= 0 {
+		return ID{}, fmt.Errorf("invalid id %q", v)
+	}
+	return IntID(n), nil
+}
+
+// normalizeID converts id to the kind of ID the store keeps. IDs that do not
+// convert are left for validation to reject.
+func (s *ItemStore) normalizeID(id ID) ID {
+	if s.stringIDs {
+		if n, ok := id.Int(); ok && n > 0 {
+			return StringID(strconv.Itoa(n))
+		}
+		return id
+	}
+	if id.s != "" {
+		if n, err := strconv.Atoi(id.s); err == nil && n > 0 {
+			return IntID(n)
+		}
+	}
+	return id
+}
+
+// newID returns an ID for an item added without one: next in a store of int
+// IDs, or a random UUID
+func (s *ItemStore) newID(next int) ID {
+	if s.stringIDs {
+		return StringID(newUUID())
+	}
+	return IntID(next)
+}
+
+// followingID returns next, or the number after id when that is higher, so
+// that next stays above every int ID in use
+func followingID(next int, id ID) int {
+	if n, ok := id.Int(); ok {
+		return max(next, n+1)
+	}
+	return next
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,98 @@
+package itemstore
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestID_JSON(t *testing.T) {
+	tests := []struct {
+		id   ID
+		json string
+	}{
+		{IntID(7), `7`},
+		{StringID("7"), `"7"`},
+		{StringID("a1b2"), `"a1b2"`},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.id)
+		if err != nil || string(b) != tt.json {
+			t.Errorf("Marshal(%s) = %s, %v; want %s", tt.id, b, err, tt.json)
+		}
+		var got ID
+		if err := json.Unmarshal([]byte(tt.json), &got); err != nil || got != tt.id {
+			t.Errorf("Unmarshal(%s) = %#v, %v; want %#v", tt.json, got, err, tt.id)
+		}
+	}
+
+	var got ID
+	if err := json.Unmarshal([]byte(`null`), &got); err != nil || !got.IsZero() {
+		t.Errorf("Unmarshal(null) = %#v, %v; want the zero ID", got, err)
+	}
+	for _, bad := range []string{`true`, `[4]`, `1.5`} {
+		if err := json.Unmarshal([]byte(bad), &got); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestID_Compare(t *testing.T) {
+	ids := []ID{StringID("b"), IntID(10), StringID("a"), IntID(2)}
+	slices.SortFunc(ids, ID.Compare)
+	want := []ID{IntID(2), IntID(10), StringID("a"), StringID("b")}
+	if !slices.Equal(ids, want) {
+		t.Errorf("sorted IDs = %v, want %v", ids, want)
+	}
+}
+
+func TestParseID(t *testing.T) {
+	ints, _ := New(nil)
+	strs, _ := New(nil, WithStringIDs())
+	tests := []struct {
+		store *ItemStore
+		input string
+		want  ID
+		ok    bool
+	}{
+		{ints, "3", IntID(3), true},
+		{ints, "0", ID{}, false},
+		{ints, "abc", ID{}, false},
+		{strs, "abc", StringID("abc"), true},
+		{strs, "3", StringID("3"), true},
+		{strs, " ", ID{}, false},
+	}
+	for _, tt := range tests {
+		got, err := tt.store.ParseID(tt.input)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseID(%q) with string IDs %v = %#v, %v; want %#v", tt.input, tt.store.StringIDs(), got, err, tt.want)
+		}
+	}
+}
+
+func TestItemStore_StringIDs(t *testing.T) {
+	store, err := New([]Item{
+		{ID: IntID(2), Color: "red", Shape: "circle", Category: "A"},
+		{ID: StringID("b"), Color: "blue", Shape: "square", Category: "A"},
+	}, WithStringIDs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Int IDs are stored as their decimal strings
+	if item, err := store.Get(StringID("2")); err != nil || item.ID != StringID("2") {
+		t.Errorf(`Get("2") = %+v, %v; want item "2"`, item, err)
+	}
+	if _, err := store.Add(Item{ID: IntID(2), Color: "red", Shape: "circle", Category: "A"}); !errors.Is(err, ErrDuplicateID) {
+		t.Errorf("Add() of ID 2 error = %v, want ErrDuplicateID", err)
+	}
+
+	a, err := store.Add(Item{Color: "green", Shape: "circle", Category: "B"})
+	b, _ := store.Add(Item{Color: "green", Shape: "circle", Category: "B"})
+	if _, isInt := a.ID.Int(); err != nil || isInt || len(a.ID.String()) != 36 || a.ID == b.ID {
+		t.Errorf("Add() = %+v, %+v; want distinct UUIDs", a, b)
+	}
+	if _, err := store.Add(Item{ID: StringID(" "), Color: "red", Shape: "circle", Category: "A"}); !errors.Is(err, ErrInvalidItem) {
+		t.Errorf("Add() of a blank ID error = %v, want ErrInvalidItem", err)
+	}
+}
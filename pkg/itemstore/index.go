@@ -0,0 +1,151 @@
+package itemstore
+
+import (
+	"slices"
+)
+
+// propertyIndex maps each value of one property to the positions in the
+// items slice of the items that have it, in ascending order
+type propertyIndex map[string][]int
+
+// WithIndexes makes the store index the given properties, so that filters
+// on them look the matching items up instead of scanning every item. Names
+// that are not properties are ignored.
+func WithIndexes(properties ...string) Option {
+	return func(s *ItemStore) {
+		for _, p := range properties {
+			if !IsProperty(p) {
+				continue
+			}
+			if s.indexes == nil {
+				s.indexes = make(map[string]propertyIndex)
+			}
+			s.indexes[p] = nil
+		}
+	}
+}
+
+// rebuildIndexesLocked indexes every item from scratch. Mutations that shift
+// positions call it; the caller must hold the write lock.
+func (s *ItemStore) rebuildIndexesLocked() {
+	if s.bitmaps != nil {
+		s.bitmaps.rebuild(s.items)
+	}
+	for property := range s.indexes {
+		index := make(propertyIndex)
+		for i, item := range s.items {
+			value := item.property(property)
+			index[value] = append(index[value], i)
+		}
+		s.indexes[property] = index
+	}
+}
+
+// indexAppendedLocked indexes the item just appended at position i. The
+// caller must hold the write lock.
+func (s *ItemStore) indexAppendedLocked(i int) {
+	if s.bitmaps != nil {
+		s.bitmaps.add(i, s.items[i])
+	}
+	for property, index := range s.indexes {
+		value := s.items[i].property(property)
+		index[value] = append(index[value], i)
+	}
+}
+
+// indexReplacedLocked moves position i from previous's values to those of
+// the item now stored there. The caller must hold the write lock.
+func (s *ItemStore) indexReplacedLocked(i int, previous Item) {
+	if s.bitmaps != nil {
+		s.bitmaps.replace(i, previous, s.items[i])
+	}
+	for property, index := range s.indexes {
+		old, value := previous.property(property), s.items[i].property(property)
+		if old == value {
+			continue
+		}
+		if at, ok := slices.BinarySearch(index[old], i); ok {
+			index[old] = slices.Delete(index[old], at, at+1)
+			if len(index[old]) == 0 {
+				delete(index, old)
+			}
+		}
+		at, _ := slices.BinarySearch(index[value], i)
+		index[value] = slices.Insert(index[value], at, i)
+	}
+}
+
+// indexedPositionsLocked returns the positions of the items matching every
+// filter, in ascending order, and true; or false if some filter is on a
+// property that is not indexed. Filter values must already be normalized.
+// The caller must hold the lock.
+func (s *ItemStore) indexedPositionsLocked(filters map[string]string) ([]int, bool) {
+	lists := make([][]int, 0, len(filters))
+	for property, value := range filters {
+		index, ok := s.indexes[property]
+		if !ok {
+			return nil, false
+		}
+		lists = append(lists, index[value])
+	}
+	// Intersecting from the shortest list keeps every step at most that long
+	slices.SortFunc(lists, func(a, b []int) int { return len(a) - len(b) })
+	positions := lists[0]
+	for _, list := range lists[1:] {
+		positions = intersectSorted(positions, list)
+	}
+	return positions, true
+}
+
+// intersectSorted returns the values present in both ascending lists
+func intersectSorted(a, b []int) []int {
+	var out []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+// IndexStats describes the store's indexes and roughly how much memory they
+// take, for debugging and capacity planning
+type IndexStats struct {
+	Items int
+	// PostingLists counts the value lists kept for WithIndexes, and
+	// PostingBytes approximates their size
+	PostingLists int
+	PostingBytes int
+	// Bitmaps counts the bitsets kept for WithBitmapIndex, and BitmapBytes
+	// is their size
+	Bitmaps     int
+	BitmapBytes int
+}
+
+// IndexStats reports the size of the store's indexes
+func (s *ItemStore) IndexStats() IndexStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := IndexStats{Items: len(s.items)}
+	for _, index := range s.indexes {
+		for value, positions := range index {
+			stats.PostingLists++
+			stats.PostingBytes += len(value) + 8*cap(positions)
+		}
+	}
+	for _, values := range s.bitmaps {
+		for value, set := range values {
+			stats.Bitmaps++
+			stats.BitmapBytes += len(value) + 8*cap(set)
+		}
+	}
+	return stats
+}
@@ -0,0 +1,141 @@
+package itemstore
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+// storeVariants are the store configurations the index and snapshot tests
+// run against, so the indexed and scanning paths are held to the same
+// results. TestItemStore_Conformance runs them through storetest too.
+var storeVariants = map[string][]Option{
+	"scan":    nil,
+	"indexed": {WithIndexes("color", "shape", "category")},
+	"partial": {WithIndexes("color")},
+	"bitmap":  {WithBitmapIndex()},
+}
+
+// filterCases are filters checked after every mutation in
+// TestItemStore_IndexesFollowMutations
+var filterCases = []map[string]string{
+	{"color": "red"},
+	{"color": "Red", "shape": "square"},
+	{"shape": "circle", "category": "B"},
+	{"category": "A", "color": "blue", "shape": "square"},
+	{"color": "purple"},
+	{"color": "red", "weight": "heavy"},
+}
+
+func TestItemStore_IndexesFollowMutations(t *testing.T) {
+	stores := make(map[string]*ItemStore, len(storeVariants))
+	for name, opts := range storeVariants {
+		store, err := New(testItems, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stores[name] = store
+	}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for step := range 500 {
+		op := r.IntN(5)
+		for _, store := range stores {
+			// Every store sees the same operation
+			mutate(store, op, rand.New(rand.NewPCG(uint64(step), 3)))
+		}
+
+		for _, filters := range filterCases {
+			want := idsOf(stores["scan"].Filter(filters))
+			for name, store := range stores {
+				if got := idsOf(store.Filter(filters)); !reflect.DeepEqual(got, want) {
+					t.Fatalf("step %d: Filter(%v) = %v with %s, %v by scanning", step, filters, got, name, want)
+				}
+				if got := store.Count(filters); got != len(want) {
+					t.Fatalf("step %d: Count(%v) = %d with %s, want %d", step, filters, got, name, len(want))
+				}
+			}
+		}
+	}
+}
+
+func TestItemStore_Count(t *testing.T) {
+	for name, opts := range storeVariants {
+		store, err := New(testItems, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, filters := range append(filterCases, nil, map[string]string{}, map[string]string{"weight": "heavy"}) {
+			if got, want := store.Count(filters), len(store.Filter(filters)); got != want {
+				t.Errorf("%s: Count(%v) = %d, want %d", name, filters, got, want)
+			}
+		}
+	}
+}
+
+// randomItem returns an item with the given ID and random property values
+// from a small set, so filters match several items
+func randomItem(r *rand.Rand, id int) Item {
+	return Item{
+		ID:       IntID(id),
+		Color:    []string{"red", "blue", "green"}[r.IntN(3)],
+		Shape:    []string{"circle", "square", "triangle"}[r.IntN(3)],
+		Category: []string{"A", "B", "C"}[r.IntN(3)],
+	}
+}
+
+// mutate applies one of five kinds of change, chosen by op, to store
+func mutate(store *ItemStore, op int, r *rand.Rand) {
+	ids := idsOf(store.Filter(nil))
+	switch {
+	case op == 0 || len(ids) == 0:
+		store.Add(randomItem(r, 0))
+	case op == 1:
+		store.Update(randomItem(r, ids[r.IntN(len(ids))]))
+	case op == 2:
+		store.Delete(IntID(ids[r.IntN(len(ids))]))
+	case op == 3:
+		// Fails on the duplicate ID and rolls back
+		store.AddAll([]Item{randomItem(r, 0), {ID: IntID(ids[0]), Color: "red", Shape: "circle", Category: "A"}})
+	default:
+		items := store.Filter(nil)
+		items[r.IntN(len(items))] = randomItem(r, ids[r.IntN(len(ids))]+1000)
+		store.SetItems(items)
+	}
+}
+
+// idsOf returns the int IDs of items in order
+func idsOf(items []Item) []int {
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i], _ = item.ID.Int()
+	}
+	return ids
+}
+
+// BenchmarkFilter compares filtering 50,000 items by scanning and by index
+func BenchmarkFilter(b *testing.B) {
+	items := make([]Item, 50000)
+	for i := range items {
+		items[i] = Item{
+			ID:       IntID(i + 1),
+			Color:    fmt.Sprintf("color-%d", i%40),
+			Shape:    []string{"circle", "square", "triangle"}[i%3],
+			Category: fmt.Sprintf("c%d", i%25),
+		}
+	}
+	filters := map[string]string{"color": "color-7", "category": "c3"}
+
+	for _, name := range []string{"scan", "indexed"} {
+		store, err := New(items, storeVariants[name]...)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				store.Filter(filters)
+			}
+		})
+	}
+}
@@ -1,154 +1,949 @@
 package itemstore
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"os"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+)
+
+var (
+	// ErrNotFound is returned when no item has the requested ID
+	ErrNotFound = errors.New("item not found")
+	// ErrDuplicateID is returned when adding an item whose ID is already taken
+	ErrDuplicateID = errors.New("duplicate item ID")
+	// ErrInvalidItem is returned when an item fails validation
+	ErrInvalidItem = errors.New("invalid item")
+	// ErrReadOnly is returned by the writes of a store wrapped by ReadOnly
+	ErrReadOnly = errors.New("store is read-only")
+	// ErrConflict is returned by UpdateIf when the stored item fails the check
+	ErrConflict = errors.New("item has changed")
 )
 
 // Item represents an item with multiple properties
 type Item struct {
-	ID       int    `json:"id"`
+	ID       ID     `json:"id"`
 	Color    string `json:"color"`
 	Shape    string `json:"shape"`
 	Category string `json:"category"`
+	// CreatedAt is when the item was created. Add stamps items that have none
+	// with the current time, and Update never changes it.
+	CreatedAt time.Time `json:"createdAt,omitzero"`
+}
+
+// Normalize returns the item in the form it is stored in: the color and
+// shape in their canonical forms, so that "Grey" and "gray" are one color and
+// a shape's aliases are the shape itself
+func (i Item) Normalize() Item {
+	i.Color = format.ColorKey(i.Color)
+	i.Shape = format.Shape(i.Shape)
+	return i
+}
+
+// Format formats the item's fields for display
+func (i Item) Format() Item {
+	return Item{
+		ID:        i.ID,
+		Color:     format.Color(i.Color),
+		Shape:     format.Shape(i.Shape),
+		Category:  format.Title(i.Category),
+		CreatedAt: i.CreatedAt,
+	}
+}
+
+// properties are the item fields that can be filtered, grouped, and sorted by
+var properties = []string{"color", "shape", "category"}
+
+// IsProperty reports whether name is an item property that can be filtered,
+// grouped, and sorted by
+func IsProperty(name string) bool {
+	return slices.Contains(properties, name)
+}
+
+// property returns the item's value for one of properties, or "" for any
+// other name
+func (i Item) property(name string) string {
+	switch name {
+	case "color":
+		return i.Color
+	case "shape":
+		return i.Shape
+	case "category":
+		return i.Category
+	}
+	return ""
+}
+
+// ChangeType identifies the kind of mutation reported to change hooks
+type ChangeType string
+
+// The mutations reported to change hooks
+const (
+	ItemCreated ChangeType = "item.created"
+	ItemUpdated ChangeType = "item.updated"
+	ItemDeleted ChangeType = "item.deleted"
+)
+
+// Change describes one mutation of the store. For deletions Item is the item
+// that was removed; for updates Previous holds the item as it was before.
+type Change struct {
+	Type     ChangeType
+	Item     Item
+	Previous *Item
 }
 
-// Validate checks if the item has valid field values
-func (i Item) Validate() error {
-	if i.ID <= 0 {
-		return fmt.Errorf("invalid item ID: %d", i.ID)
+// ItemStore handles storage and retrieval of items. It is safe for
+// concurrent use.
+type ItemStore struct {
+	mu sync.RWMutex
+	// items is copy-on-write: mutations replace it or append past its end
+	// but never change an element in place. Each mutation ends by publishing
+	// it to snapshot, from which readers that need no index take it without
+	// locking, so they never wait for writers and writers never wait for a
+	// slow reader.
+	items    []Item
+	snapshot atomic.Pointer[[]Item]
+	// revision counts the snapshots published
+	revision atomic.Uint64
+	// nextID follows the highest int ID in use; stores of string IDs
+	// generate theirs instead
+	nextID    int
+	stringIDs bool
+	hooks     []func(Change)
+	// now stamps CreatedAt; tests replace it
+	now func() time.Time
+	// shapes, when set, are the only shapes items may have
+	shapes *format.ShapeRegistry
+	// indexes holds an index of each property chosen with WithIndexes
+	indexes map[string]propertyIndex
+	// counts tracks the values in use, updated by every mutation
+	counts valueCounts
+	// bitmaps is the WithBitmapIndex index; nil without it
+	bitmaps bitmapIndex
+}
+
+// Store is the set of operations the HTTP and gRPC layers need from an item
+// store. *ItemStore implements it.
+type Store interface {
+	Get(id ID) (Item, error)
+	Add(item Item) (Item, error)
+	AddAll(items []Item) ([]Item, error)
+	Check(item Item) (Item, error)
+	Update(item Item) (Item, error)
+	UpdateIf(item Item, match func(stored Item) bool) (Item, error)
+	Delete(id ID) error
+	SetItems(items []Item) (Diff, error)
+	Merge(items []Item, strategy MergeStrategy) (MergeReport, error)
+	Filter(filters map[string]string) []Item
+	Items(filters map[string]string) iter.Seq[Item]
+	ItemsContext(ctx context.Context, filters map[string]string) iter.Seq[Item]
+	FilterContext(ctx context.Context, filters map[string]string) ([]Item, error)
+	GetUniqueValues(property string) []string
+	CountBy(property string) map[string]int
+	CountsBy(properties ...string) map[string]map[string]int
+	Combinations(props ...string) []Combination
+	Len() int
+	Count(filters map[string]string) int
+	Revision() uint64
+	OnChange(fn func(Change))
+	ParseID(v string) (ID, error)
+	StringIDs() bool
+}
+
+var _ Store = (*ItemStore)(nil)
+
+// Option configures an ItemStore
+type Option func(*ItemStore)
+
+// WithKnownShapes makes the store reject items whose shape is not registered
+// in shapes
+func WithKnownShapes(shapes *format.ShapeRegistry) Option {
+	return func(s *ItemStore) {
+		s.shapes = shapes
 	}
+}
 
-	i.Color = strings.TrimSpace(i.Color)
-	i.Shape = strings.TrimSpace(i.Shape)
-	i.Category = strings.TrimSpace(i.Category)
+// New creates a new ItemStore with the given items
+func New(items []Item, opts ...Option) (*ItemStore, error) {
+	s := &ItemStore{now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
 
-	if i.Color == "" || i.Shape == "" || i.Category == "" {
-		return fmt.Errorf("item %d has empty fields", i.ID)
+	items = s.normalizeItems(items)
+	nextID, err := validateItems(items, s.validate)
+	if err != nil {
+		return nil, err
 	}
+	s.items = items
+	s.nextID = nextID
+	s.counts = newValueCounts(items)
+	s.rebuildIndexesLocked()
+	s.publishLocked()
+	return s, nil
+}
+
+// publishLocked makes the current items the snapshot readers see. The
+// caller must hold the write lock and must not publish items a failed
+// mutation will take back, since appends reuse the slots past a snapshot.
+func (s *ItemStore) publishLocked() {
+	items := s.items
+	s.snapshot.Store(&items)
+	s.revision.Add(1)
+}
+
+// Revision returns a number that changes whenever the items do, so that
+// results computed from the store can be cached under it
+func (s *ItemStore) Revision() uint64 {
+	return s.revision.Load()
+}
 
+// current returns the published snapshot of the items. It must not be
+// modified.
+func (s *ItemStore) current() []Item {
+	if items := s.snapshot.Load(); items != nil {
+		return *items
+	}
 	return nil
 }
 
-// Format formats the item's fields for display
-func (i Item) Format() Item {
-	return Item{
-		ID:       i.ID,
-		Color:    formatColor(i.Color),
-		Shape:    formatShape(i.Shape),
-		Category: formatTitle(i.Category),
+// validate checks item with Validate and, if the store only accepts known
+// shapes, checks its shape
+func (s *ItemStore) validate(item Item) error {
+	errs := item.fieldErrors()
+	if _, ok := item.ID.Int(); !ok && !s.stringIDs {
+		errs = slices.Insert(errs, 0, FieldError{Field: "id", Value: item.ID.String(), Message: "must be a positive number"})
+	}
+	if s.shapes != nil && item.Shape != "" && !s.shapes.Known(item.Shape) {
+		errs = append(errs, FieldError{
+			Field:   "shape",
+			Value:   item.Shape,
+			Message: "is not a known shape (known: " + strings.Join(s.shapes.Names(), ", ") + ")",
+		})
 	}
+	return errs.err()
 }
 
-// ItemStore handles storage and retrieval of items
-type ItemStore struct {
-	items []Item
+// normalize returns item in the form the store keeps it: normalized, with
+// its ID of the store's kind and its shape resolved in the store's registry
+// when it has one
+func (s *ItemStore) normalize(item Item) Item {
+	item = item.Normalize()
+	item.ID = s.normalizeID(item.ID)
+	if s.shapes != nil {
+		item.Shape, _ = s.shapes.Resolve(item.Shape)
+	}
+	return item
 }
 
-// New creates a new ItemStore with the given items
-func New(items []Item) (*ItemStore, error) {
-	// Validate all items
+// normalizeItems returns a normalized copy of items
+func (s *ItemStore) normalizeItems(items []Item) []Item {
+	normalized := make([]Item, len(items))
+	for i, item := range items {
+		normalized[i] = s.normalize(item)
+	}
+	return normalized
+}
+
+// validateItems checks every item with validate and rejects duplicate IDs. It
+// returns the ID following the highest one in use. Errors are *indexError.
+func validateItems(items []Item, validate func(Item) error) (int, error) {
+	seen := make(map[ID]struct{}, len(items))
+	nextID := 1
+	for i, item := range items {
+		if err := validate(item); err != nil {
+			return 0, &indexError{index: i, err: err}
+		}
+		if _, dup := seen[item.ID]; dup {
+			return 0, &indexError{index: i, err: fmt.Errorf("%w: %s", ErrDuplicateID, item.ID)}
+		}
+		seen[item.ID] = struct{}{}
+		nextID = followingID(nextID, item.ID)
+	}
+	return nextID, nil
+}
+
+// indexError is an error about the item at index in a list of items
+type indexError struct {
+	index int
+	err   error
+}
+
+func (e *indexError) Error() string {
+	return fmt.Sprintf("invalid item at index %d: %v", e.index, e.err)
+}
+
+func (e *indexError) Unwrap() error {
+	return e.err
+}
+
+// PositionError is an error LoadJSON found at a position in its input.
+// Line and Column count from 1, and Column counts bytes.
+type PositionError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// positionError returns err as a *PositionError at offset in data
+func positionError(data []byte, offset int64, err error) error {
+	offset = min(max(offset, 0), int64(len(data)))
+	before := data[:offset]
+	return &PositionError{
+		Line:   bytes.Count(before, []byte("\n")) + 1,
+		Column: len(before) - bytes.LastIndexByte(before, '\n'),
+		Err:    err,
+	}
+}
+
+// skipSeparators returns the offset of the first byte at or after offset in
+// data that is neither JSON whitespace nor a comma
+func skipSeparators(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) && bytes.IndexByte([]byte(" \t\r\n,"), data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// LoadJSON reads a JSON array of items and validates them with the same
+// rules as New. Errors are *PositionError, pointing at the malformed JSON or
+// at the start of the invalid item.
+func LoadJSON(r io.Reader) ([]Item, error) {
+	data, items, starts, err := decodeJSON(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := validateItems(items, Item.Validate); err != nil {
+		var ie *indexError
+		if errors.As(err, &ie) {
+			return nil, positionError(data, starts[ie.index], err)
+		}
+		return nil, err
+	}
+	return items, nil
+}
+
+// LoadFile reads and validates a JSON array of items from path like
+// LoadJSON. Errors in the content are reported as "path:line:column: problem".
+func LoadFile(path string) ([]Item, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	items, err := LoadJSON(f)
+	if posErr := (*PositionError)(nil); errors.As(err, &posErr) {
+		return nil, fmt.Errorf("%s:%d:%d: %w", path, posErr.Line, posErr.Column, posErr.Err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return items, nil
+}
+
+// ValidateItems checks items with the rules New applies under opts, but
+// carries on past an invalid item to report every one. The returned error
+// joins an error naming the index of each invalid item.
+func ValidateItems(items []Item, opts ...Option) error {
+	s := &ItemStore{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	var errs []error
+	seen := make(map[ID]bool, len(items))
+	for i, item := range s.normalizeItems(items) {
+		err := s.validate(item)
+		if err == nil && seen[item.ID] {
+			err = fmt.Errorf("%w: %s", ErrDuplicateID, item.ID)
+		}
+		if err != nil {
+			errs = append(errs, &indexError{index: i, err: err})
+		}
+		seen[item.ID] = true
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateJSON checks a JSON array of items like LoadJSON, but carries on
+// past an invalid item to report every one. The returned error joins a
+// *PositionError per invalid item, or is the single error that stopped the
+// JSON from being read.
+func ValidateJSON(r io.Reader) error {
+	data, items, starts, err := decodeJSON(r)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	seen := make(map[ID]bool, len(items))
+	for i, item := range items {
+		err := item.Validate()
+		if err == nil && seen[item.ID] {
+			err = fmt.Errorf("%w: %s", ErrDuplicateID, item.ID)
+		}
+		if err != nil {
+			errs = append(errs, positionError(data, starts[i], &indexError{index: i, err: err}))
+		}
+		seen[item.ID] = true
+	}
+	return errors.Join(errs...)
+}
+
+// decodeJSON reads a JSON array of items without validating them. It returns
+// the input and the offset each item starts at, for reporting positions.
+func decodeJSON(r io.Reader) (data []byte, items []Item, starts []int64, err error) {
+	data, err = io.ReadAll(r)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("read items: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	// decodeError places err at the character a syntax error reports, or
+	// else at at
+	decodeError := func(err error, at int64) error {
+		if syntaxErr := (*json.SyntaxError)(nil); errors.As(err, &syntaxErr) {
+			at = syntaxErr.Offset - 1
+		}
+		return positionError(data, at, fmt.Errorf("decode items: %w", err))
+	}
+
+	if tok, err := dec.Token(); errors.Is(err, io.EOF) || (err == nil && tok != json.Delim('[')) {
+		return nil, nil, nil, positionError(data, 0, errors.New("decode items: want a JSON array of items"))
+	} else if err != nil {
+		return nil, nil, nil, decodeError(err, 0)
+	}
+	for dec.More() {
+		// The item starts after the separator that follows the offset
+		start := skipSeparators(data, dec.InputOffset())
+		var item Item
+		if err := dec.Decode(&item); err != nil {
+			return nil, nil, nil, decodeError(err, start)
+		}
+		items = append(items, item)
+		starts = append(starts, start)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, nil, decodeError(err, dec.InputOffset())
+	}
+	if end := dec.InputOffset(); !errors.Is(dec.Decode(&struct{}{}), io.EOF) {
+		return nil, nil, nil, positionError(data, skipSeparators(data, end), errors.New("decode items: unexpected data after the array"))
+	}
+	return data, items, starts, nil
+}
+
+// SetItems atomically replaces the store's contents and returns what changed.
+// The items are validated first and nothing changes if any is invalid. Change
+// hooks are told about every item that was created, updated, or deleted by
+// the swap.
+func (s *ItemStore) SetItems(items []Item) (Diff, error) {
+	items = s.normalizeItems(items)
+	nextID, err := validateItems(items, s.validate)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	diff := ComputeDiff(s.items, items)
+	s.items = items
+	s.nextID = nextID
+	s.counts = newValueCounts(items)
+	s.rebuildIndexesLocked()
+	s.publishLocked()
+
+	for _, item := range diff.Added {
+		s.notifyLocked(Change{Type: ItemCreated, Item: item})
+	}
+	for _, c := range diff.Changed {
+		s.notifyLocked(Change{Type: ItemUpdated, Item: c.After, Previous: &c.Before})
+	}
+	for _, item := range diff.Removed {
+		s.notifyLocked(Change{Type: ItemDeleted, Item: item})
+	}
+	return diff, nil
+}
+
+// Diff describes how one set of items differs from another, matching items
+// by ID
+type Diff struct {
+	Added   []Item
+	Removed []Item
+	Changed []ItemChange
+}
+
+// ItemChange is an item whose fields differ between two sets
+type ItemChange struct {
+	Before Item
+	After  Item
+}
+
+// Empty reports whether the two sets were identical
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// ComputeDiff compares old and new by item ID. Added and Changed follow the
+// order of new; Removed follows the order of old.
+func ComputeDiff(old, new []Item) Diff {
+	oldByID := make(map[ID]Item, len(old))
+	for _, item := range old {
+		oldByID[item.ID] = item
+	}
+
+	var d Diff
+	for _, item := range new {
+		prev, ok := oldByID[item.ID]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, item)
+		case prev != item:
+			d.Changed = append(d.Changed, ItemChange{Before: prev, After: item})
+		}
+		delete(oldByID, item.ID)
+	}
+	for _, item := range old {
+		if _, removed := oldByID[item.ID]; removed {
+			d.Removed = append(d.Removed, item)
+		}
+	}
+	return d
+}
+
+// Comparison splits two sets of items by membership
+type Comparison struct {
+	OnlyLeft  []Item
+	OnlyRight []Item
+	Both      []Item
+}
+
+// Compare reports which items are only in left, only in right, and in both,
+// matching items by ID. OnlyLeft and Both follow the order of left;
+// OnlyRight follows the order of right.
+func Compare(left, right []Item) Comparison {
+	inRight := make(map[ID]bool, len(right))
+	for _, item := range right {
+		inRight[item.ID] = true
+	}
+	inLeft := make(map[ID]bool, len(left))
+	var c Comparison
+	for _, item := range left {
+		inLeft[item.ID] = true
+		if inRight[item.ID] {
+			c.Both = append(c.Both, item)
+		} else {
+			c.OnlyLeft = append(c.OnlyLeft, item)
+		}
+	}
+	for _, item := range right {
+		if !inLeft[item.ID] {
+			c.OnlyRight = append(c.OnlyRight, item)
+		}
+	}
+	return c
+}
+
+// OnChange registers fn to be called after every successful mutation. Hooks
+// run synchronously, in mutation order, while the store is locked, so they
+// must return quickly and must not call back into the store.
+func (s *ItemStore) OnChange(fn func(Change)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, fn)
+}
+
+// notifyLocked calls the change hooks. The caller must hold the write lock.
+func (s *ItemStore) notifyLocked(c Change) {
+	for _, fn := range s.hooks {
+		fn(c)
+	}
+}
+
+// Get returns the item with the given ID
+func (s *ItemStore) Get(id ID) (Item, error) {
+	id = s.normalizeID(id)
+	for _, item := range s.current() {
+		if item.ID == id {
+			return item, nil
+		}
+	}
+	return Item{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// Add validates and stores a new item. An item with a zero ID is assigned the
+// next free ID, or a random UUID in a store of string IDs. The stored item is
+// returned.
+func (s *ItemStore) Add(item Item) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added, err := s.addLocked(item)
+	if err != nil {
+		return Item{}, err
+	}
+	s.publishLocked()
+	s.notifyLocked(Change{Type: ItemCreated, Item: added})
+	return added, nil
+}
+
+// AddAll adds all items atomically: either every item is stored or, if any
+// item is invalid or conflicts, none are. The stored items are returned in
+// input order.
+func (s *ItemStore) AddAll(items []Item) ([]Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	origLen, origNextID := len(s.items), s.nextID
+	added := make([]Item, 0, len(items))
 	for i, item := range items {
-		if err := item.Validate(); err != nil {
-			return nil, fmt.Errorf("invalid item at index %d: %w", i, err)
+		stored, err := s.addLocked(item)
+		if err != nil {
+			s.items, s.nextID = s.items[:origLen], origNextID
+			for _, item := range added {
+				s.counts.remove(item)
+			}
+			s.rebuildIndexesLocked()
+			return nil, fmt.Errorf("item at index %d: %w", i, err)
 		}
+		added = append(added, stored)
+	}
+	s.publishLocked()
+	for _, item := range added {
+		s.notifyLocked(Change{Type: ItemCreated, Item: item})
+	}
+	return added, nil
+}
+
+// Check returns item normalized and validated as Add would store it, without
+// storing it. An item without an ID passes, since Add assigns one, and
+// whether an ID is free is only known once the item is added. Check takes no
+// lock, so many goroutines can check items while the store is being written.
+func (s *ItemStore) Check(item Item) (Item, error) {
+	item = s.normalize(item)
+	checked := item
+	if checked.ID.IsZero() {
+		checked.ID = s.newID(1)
+	}
+	if err := s.validate(checked); err != nil {
+		return Item{}, fmt.Errorf("%w: %w", ErrInvalidItem, err)
+	}
+	return item, nil
+}
+
+func (s *ItemStore) addLocked(item Item) (Item, error) {
+	if item.ID.IsZero() {
+		item.ID = s.newID(s.nextID)
+	}
+	item = s.normalize(item)
+	if err := s.validate(item); err != nil {
+		return Item{}, fmt.Errorf("%w: %w", ErrInvalidItem, err)
 	}
+	if s.indexOf(item.ID) >= 0 {
+		return Item{}, fmt.Errorf("%w: %s", ErrDuplicateID, item.ID)
+	}
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = s.now().UTC()
+	}
+
+	s.items = append(s.items, item)
+	s.counts.add(item)
+	s.indexAppendedLocked(len(s.items) - 1)
+	s.nextID = followingID(s.nextID, item.ID)
+	return item, nil
+}
 
-	return &ItemStore{
-		items: items,
-	}, nil
+// Update replaces the stored item that has the same ID as item, keeping its
+// CreatedAt
+func (s *ItemStore) Update(item Item) (Item, error) {
+	return s.UpdateIf(item, nil)
 }
 
-// Filter applies the given filters to the items and returns the result
+// UpdateIf is Update if match approves the stored item, and fails with
+// ErrConflict otherwise. match runs with the store locked, so no other write
+// lands between the check and the update. A nil match approves every item.
+func (s *ItemStore) UpdateIf(item Item, match func(stored Item) bool) (Item, error) {
+	item = s.normalize(item)
+	if err := s.validate(item); err != nil {
+		return Item{}, fmt.Errorf("%w: %w", ErrInvalidItem, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexOf(item.ID)
+	if i < 0 {
+		return Item{}, fmt.Errorf("%w: %s", ErrNotFound, item.ID)
+	}
+	previous := s.items[i]
+	if match != nil && !match(previous) {
+		return Item{}, fmt.Errorf("%w: %s", ErrConflict, item.ID)
+	}
+	item.CreatedAt = previous.CreatedAt
+	s.items = slices.Clone(s.items)
+	s.items[i] = item
+	s.counts.remove(previous)
+	s.counts.add(item)
+	s.indexReplacedLocked(i, previous)
+	s.publishLocked()
+	s.notifyLocked(Change{Type: ItemUpdated, Item: item, Previous: &previous})
+	return item, nil
+}
+
+// Delete removes the item with the given ID
+func (s *ItemStore) Delete(id ID) error {
+	id = s.normalizeID(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexOf(id)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	removed := s.items[i]
+	s.items = slices.Concat(s.items[:i], s.items[i+1:])
+	s.counts.remove(removed)
+	// Every later item moved down a position
+	s.rebuildIndexesLocked()
+	s.publishLocked()
+	s.notifyLocked(Change{Type: ItemDeleted, Item: removed})
+	return nil
+}
+
+// Len returns the number of stored items
+func (s *ItemStore) Len() int {
+	return len(s.current())
+}
+
+// indexOf returns the position of the item with the given ID, or -1. The
+// caller must hold the lock.
+func (s *ItemStore) indexOf(id ID) int {
+	for i, item := range s.items {
+		if item.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Filter applies the given filters to the items and returns the result.
+// Filters are evaluated over the bitmap index when the store has one, and
+// filters on properties indexed with WithIndexes are looked up; anything
+// else means a scan.
 func (s *ItemStore) Filter(filters map[string]string) []Item {
 	if len(filters) == 0 {
 		// Return a copy of all items
-		result := make([]Item, len(s.items))
-		copy(result, s.items)
-		return result
+		return slices.Clone(s.current())
 	}
 
-	var result []Item
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Collect(s.itemsLocked(context.Background(), filters))
+}
 
-	// For each item, check if it matches all filters
-ItemLoop:
-	for _, item := range s.items {
-		for key, value := range filters {
-			switch key {
-			case "color":
-				if item.Color != value {
-					continue ItemLoop
-				}
-			case "shape":
-				if item.Shape != value {
-					continue ItemLoop
+// Items returns the items Filter would, one at a time and without copying
+// the store. The iterator reads a snapshot taken when Items is called, so
+// it holds no lock and changes made while iterating do not show up.
+func (s *ItemStore) Items(filters map[string]string) iter.Seq[Item] {
+	if len(filters) == 0 {
+		return slices.Values(s.current())
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.itemsLocked(context.Background(), filters)
+}
+
+// cancelCheckInterval is how many items a scan visits between checks of its
+// context, so a canceled scan stops soon without paying for a check per item
+const cancelCheckInterval = 1024
+
+// canceled reports whether a scan at its ith item should stop because ctx is
+// done. Only every cancelCheckInterval-th item looks at ctx.
+func canceled(ctx context.Context, i int) bool {
+	return i%cancelCheckInterval == 0 && ctx.Err() != nil
+}
+
+// valuesContext yields items in order, stopping once ctx is done
+func valuesContext(ctx context.Context, items []Item) iter.Seq[Item] {
+	return func(yield func(Item) bool) {
+		for i, item := range items {
+			if canceled(ctx, i) || !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// itemsLocked implements Items and ItemsContext, stopping once ctx is done.
+// The caller must hold the lock.
+func (s *ItemStore) itemsLocked(ctx context.Context, filters map[string]string) iter.Seq[Item] {
+	items := s.items
+	normalized := s.normalizeFilters(filters)
+
+	if len(normalized) > 0 && s.bitmaps != nil {
+		set := bitmapFilter(normalized).bits(s.bitmaps, len(items))
+		return func(yield func(Item) bool) {
+			n := 0
+			for i := range set.positions() {
+				if canceled(ctx, n) || !yield(items[i]) {
+					return
 				}
-			case "category":
-				if item.Category != value {
-					continue ItemLoop
+				n++
+			}
+		}
+	}
+	if len(normalized) > 0 {
+		if positions, ok := s.indexedPositionsLocked(normalized); ok {
+			// The index changes in place, so iterate a copy
+			positions = slices.Clone(positions)
+			return func(yield func(Item) bool) {
+				for n, i := range positions {
+					if canceled(ctx, n) || !yield(items[i]) {
+						return
+					}
 				}
 			}
 		}
-		// If we get here, the item matches all filters
-		result = append(result, item)
 	}
 
-	return result
+	return func(yield func(Item) bool) {
+		for i, item := range items {
+			if canceled(ctx, i) {
+				return
+			}
+			if matchesFilters(item, normalized) && !yield(item) {
+				return
+			}
+		}
+	}
 }
 
-// GetUniqueValues returns all unique values for a given property
-func (s *ItemStore) GetUniqueValues(property string) []string {
-	values := make(map[string]struct{})
-	var result []string
+// Count returns the number of items Filter would return without copying
+// any: the bitmap index and property indexes are counted directly, and
+// anything else is counted during the scan
+func (s *ItemStore) Count(filters map[string]string) int {
+	if len(filters) == 0 {
+		return s.Len()
+	}
 
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	normalized := s.normalizeFilters(filters)
+	if s.bitmaps != nil {
+		return bitmapFilter(normalized).bits(s.bitmaps, len(s.items)).count()
+	}
+	if positions, ok := s.indexedPositionsLocked(normalized); ok {
+		return len(positions)
+	}
+	n := 0
 	for _, item := range s.items {
-		var value string
-		switch property {
-		case "color":
-			value = item.Color
-		case "shape":
-			value = item.Shape
-		case "category":
-			value = item.Category
-		default:
-			continue
+		if matchesFilters(item, normalized) {
+			n++
 		}
+	}
+	return n
+}
 
-		if _, exists := values[value]; !exists {
-			values[value] = struct{}{}
-			result = append(result, value)
+// normalizeFilters returns filters with the values in the form items are
+// stored in, so they can be compared directly
+func (s *ItemStore) normalizeFilters(filters map[string]string) map[string]string {
+	normalized := make(map[string]string, len(filters))
+	for key, value := range filters {
+		normalized[key] = s.filterValue(key, value)
+	}
+	return normalized
+}
+
+// bitmapFilter returns the expression matching normalized filters. Filters
+// on anything but a property are ignored, as in a scan.
+func bitmapFilter(normalized map[string]string) andExpr {
+	var exprs andExpr
+	for key, value := range normalized {
+		if IsProperty(key) {
+			exprs = append(exprs, eqExpr{property: key, value: value})
 		}
 	}
+	return exprs
+}
 
-	sort.Strings(result)
-	return result
+// matchesFilters reports whether item has the value of every normalized
+// filter on a property
+func matchesFilters(item Item, normalized map[string]string) bool {
+	for key, value := range normalized {
+		if IsProperty(key) && item.property(key) != value {
+			return false
+		}
+	}
+	return true
 }
 
-// formatTitle converts a string to title case
-func formatTitle(s string) string {
-	if s == "" {
-		return s
+// filterValue returns a filter value on property in the form the store
+// keeps that property in
+func (s *ItemStore) filterValue(property, value string) string {
+	switch property {
+	case "color":
+		return format.ColorKey(value)
+	case "shape":
+		return s.normalize(Item{Shape: value}).Shape
+	}
+	return value
+}
+
+// GetUniqueValues returns all unique values for a given property, sorted.
+// It takes time in the number of distinct values, not items.
+func (s *ItemStore) GetUniqueValues(property string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []string
+	for value := range s.counts[property] {
+		result = append(result, value)
 	}
-	return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+	sort.Strings(result)
+	return result
 }
 
-// formatColor formats a color string for display
-func formatColor(color string) string {
-	return strings.ToLower(color)
+// CountBy returns the number of items with each value of property. Unknown
+// properties have no values.
+func (s *ItemStore) CountBy(property string) map[string]int {
+	return s.CountsBy(property)[property]
 }
 
-// formatShape formats a shape string for display
-func formatShape(shape string) string {
-	shape = strings.ToLower(shape)
-	switch shape {
-	case "triangle":
-		return "triangle"
-	case "circle":
-		return "circle"
-	case "square":
-		return "square"
-	default:
-		return shape
+// CountsBy is CountBy for several properties at once, keyed by property.
+// It takes time in the number of distinct values, not items.
+func (s *ItemStore) CountsBy(properties ...string) map[string]map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[string]map[string]int, len(properties))
+	for _, property := range properties {
+		if _, dup := counts[property]; !dup {
+			counts[property] = maps.Clone(s.counts[property])
+			if counts[property] == nil {
+				counts[property] = make(map[string]int)
+			}
+		}
 	}
+	return counts
 }
@@ -1,150 +1,394 @@
 package itemstore
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/format"
 )
 
 var testItems = []Item{
-	{ID: 1, Color: "red", Shape: "circle", Category: "A"},
-	{ID: 2, Color: "blue", Shape: "square", Category: "A"},
-	{ID: 3, Color: "red", Shape: "square", Category: "B"},
-	{ID: 4, Color: "green", Shape: "circle", Category: "B"},
+	{ID: IntID(1), Color: "red", Shape: "circle", Category: "A"},
+	{ID: IntID(2), Color: "blue", Shape: "square", Category: "A"},
+	{ID: IntID(3), Color: "red", Shape: "square", Category: "B"},
+	{ID: IntID(4), Color: "green", Shape: "circle", Category: "B"},
 }
 
-func TestItemStore_Filter(t *testing.T) {
+func TestItem_Validate(t *testing.T) {
 	tests := []struct {
 		name    string
-		filters map[string]string
-		wantLen int
+		item    Item
+		wantErr bool
 	}{
 		{
-			name:    "no filters",
-			filters: map[string]string{},
-			wantLen: 4,
+			name:    "valid item",
+			item:    Item{ID: IntID(1), Color: "red", Shape: "circle", Category: "A"},
+			wantErr: false,
 		},
 		{
-			name:    "filter by color",
-			filters: map[string]string{"color": "red"},
-			wantLen: 2,
+			name:    "missing ID",
+			item:    Item{ID: IntID(0), Color: "red", Shape: "circle", Category: "A"},
+			wantErr: true,
 		},
 		{
-			name:    "filter by shape",
-			filters: map[string]string{"shape": "square"},
-			wantLen: 2,
+			name:    "missing color",
+			item:    Item{ID: IntID(1), Color: "", Shape: "circle", Category: "A"},
+			wantErr: true,
 		},
 		{
-			name:    "filter by multiple properties",
-			filters: map[string]string{"color": "red", "shape": "square"},
-			wantLen: 1,
+			name:    "missing shape",
+			item:    Item{ID: IntID(1), Color: "red", Shape: "", Category: "A"},
+			wantErr: true,
 		},
 		{
-			name:    "filter by non-existent value",
-			filters: map[string]string{"color": "purple"},
-			wantLen: 0,
+			name:    "missing category",
+			item:    Item{ID: IntID(1), Color: "red", Shape: "circle", Category: ""},
+			wantErr: true,
 		},
 	}
 
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.item.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Item.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func newTestStore(t *testing.T) *ItemStore {
+	t.Helper()
 	store, err := New(testItems)
 	if err != nil {
 		t.Fatalf("Failed to create store: %v", err)
 	}
+	return store
+}
+
+func TestNew_DuplicateID(t *testing.T) {
+	_, err := New([]Item{
+		{ID: IntID(1), Color: "red", Shape: "circle", Category: "A"},
+		{ID: IntID(1), Color: "blue", Shape: "square", Category: "B"},
+	})
+	if !errors.Is(err, ErrDuplicateID) {
+		t.Errorf("New() error = %v, want %v", err, ErrDuplicateID)
+	}
+}
 
+func TestLoadJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{name: "valid", input: `[{"id":1,"color":"red","shape":"circle","category":"A"},{"id":2,"color":"blue","shape":"square","category":"B"}]`, want: 2},
+		{name: "empty array", input: `[]`, want: 0},
+		{name: "invalid item", input: `[{"id":1,"color":"","shape":"circle","category":"A"}]`, wantErr: true},
+		{name: "duplicate IDs", input: `[{"id":1,"color":"red","shape":"circle","category":"A"},{"id":1,"color":"red","shape":"circle","category":"A"}]`, wantErr: true},
+		{name: "unknown field", input: `[{"id":1,"colour":"red","shape":"circle","category":"A"}]`, wantErr: true},
+		{name: "not JSON", input: `items`, wantErr: true},
+	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := store.Filter(tt.filters)
-			if len(got) != tt.wantLen {
-				t.Errorf("Filter() = %v items, want %v", len(got), tt.wantLen)
+			items, err := LoadJSON(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(items) != tt.want {
+				t.Errorf("LoadJSON() returned %d items, want %d", len(items), tt.want)
 			}
 		})
 	}
 }
 
-func TestItemStore_GetUniqueValues(t *testing.T) {
+func TestLoadJSON_Position(t *testing.T) {
 	tests := []struct {
-		name     string
-		property string
-		want     []string
+		input      string
+		line, col  int
+		wantErrMsg string
 	}{
-		{
-			name:     "get unique colors",
-			property: "color",
-			want:     []string{"blue", "green", "red"},
-		},
-		{
-			name:     "get unique shapes",
-			property: "shape",
-			want:     []string{"circle", "square"},
-		},
-		{
-			name:     "get unique categories",
-			property: "category",
-			want:     []string{"A", "B"},
-		},
-		{
-			name:     "invalid property",
-			property: "invalid",
-			want:     []string{},
-		},
+		{"items", 1, 1, "invalid character"},
+		{"{}", 1, 1, "want a JSON array"},
+		{"[\n  {\"id\":1,\"colour\":\"red\"}\n]", 2, 3, `unknown field "colour"`},
+		{"[\n  {\"id\":1,\"color\":\"red\" \"shape\"}]", 2, 25, "invalid character"},
+		{"[\n {\"id\":1,\"color\":\"red\",\"shape\":\"circle\",\"category\":\"A\"},\n\n   {\"id\":1,\"color\":\"red\",\"shape\":\"circle\",\"category\":\"A\"}]", 4, 4, "duplicate item ID"},
+		{"[] []", 1, 4, "after the array"},
+	}
+	for _, tt := range tests {
+		_, err := LoadJSON(strings.NewReader(tt.input))
+		var posErr *PositionError
+		if !errors.As(err, &posErr) || posErr.Line != tt.line || posErr.Column != tt.col || !strings.Contains(err.Error(), tt.wantErrMsg) {
+			t.Errorf("LoadJSON(%q) error = %v, want %q at line %d, column %d", tt.input, err, tt.wantErrMsg, tt.line, tt.col)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.json")
+	os.WriteFile(good, []byte(`[{"id":1,"color":"red","shape":"circle","category":"A"}]`), 0o600)
+
+	if items, err := LoadFile(good); err != nil || len(items) != 1 {
+		t.Errorf("LoadFile(good) = %v, %v; want 1 item", items, err)
+	}
+	if _, err := LoadFile(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadFile(missing) succeeded")
+	}
+
+	// Problems in the content point at the line and column
+	bad := filepath.Join(dir, "bad.json")
+	os.WriteFile(bad, []byte("[\n  {\"id\":1,\"color\":\"red\",\"shape\":\"circle\",\"category\":\"A\"},\n  {\"id\":2,\"color\":\"\",\"shape\":\"circle\",\"category\":\"A\"}\n]"), 0o600)
+	_, err := LoadFile(bad)
+	if want := bad + ":3:3: invalid item at index 1: color is required"; err == nil || err.Error() != want {
+		t.Errorf("LoadFile(bad) error = %v, want %q", err, want)
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	input := "[\n" +
+		`  {"id":1,"color":"red","shape":"circle","category":"A"},` + "\n" +
+		`  {"id":2,"color":"","shape":"","category":"A"},` + "\n" +
+		`  {"id":1,"color":"blue","shape":"square","category":"B"},` + "\n" +
+		`  {"id":0,"color":"red","shape":"circle","category":"A"}` + "\n]"
+	err := ValidateJSON(strings.NewReader(input))
+	if err == nil {
+		t.Fatal("ValidateJSON() = nil, want every invalid item reported")
+	}
+	var lines []int
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var posErr *PositionError
+		if !errors.As(e, &posErr) {
+			t.Fatalf("error %v is not a *PositionError", e)
+		}
+		lines = append(lines, posErr.Line)
+	}
+	if want := []int{3, 4, 5}; !slices.Equal(lines, want) {
+		t.Errorf("errors on lines %v, want %v:\n%v", lines, want, err)
+	}
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 2 {
+		t.Errorf("first error = %v, want the item's two field errors", err)
+	}
+
+	if err := ValidateJSON(strings.NewReader("[1,")); err == nil {
+		t.Error("ValidateJSON() accepted malformed JSON")
+	}
+	if err := ValidateJSON(strings.NewReader(`[{"id":1,"color":"red","shape":"circle","category":"A"}]`)); err != nil {
+		t.Errorf("ValidateJSON() of valid items = %v", err)
+	}
+}
+
+func TestValidateItems(t *testing.T) {
+	items := []Item{
+		{ID: IntID(1), Color: "red", Shape: "circle", Category: "A"},
+		{ID: IntID(2), Color: "", Shape: "square", Category: "A"},
+		{ID: IntID(1), Color: "blue", Shape: "square", Category: "B"},
+		{ID: IntID(3), Color: "red", Shape: "blob", Category: "A"},
+	}
+	err := ValidateItems(items, WithKnownShapes(format.NewShapeRegistry()))
+	if err == nil {
+		t.Fatal("ValidateItems() = nil, want every invalid item reported")
+	}
+	for _, want := range []string{"index 1: color is required", "index 2: " + ErrDuplicateID.Error(), "index 3: shape"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateItems() error lacks %q:\n%v", want, err)
+		}
+	}
+	if err := ValidateItems(items[:1]); err != nil {
+		t.Errorf("ValidateItems() of valid items = %v", err)
+	}
+	// Without WithKnownShapes any shape goes
+	if err := ValidateItems(items[3:]); err != nil {
+		t.Errorf("ValidateItems() without known shapes = %v", err)
+	}
+}
+
+func TestComputeDiff(t *testing.T) {
+	old := testItems
+	updated := Item{ID: IntID(2), Color: "blue", Shape: "triangle", Category: "A"}
+	added := Item{ID: IntID(5), Color: "red", Shape: "circle", Category: "C"}
+	next := []Item{testItems[0], updated, testItems[3], added}
+
+	got := ComputeDiff(old, next)
+	want := Diff{
+		Added:   []Item{added},
+		Removed: []Item{testItems[2]},
+		Changed: []ItemChange{{Before: testItems[1], After: updated}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ComputeDiff() = %+v, want %+v", got, want)
+	}
+	if got.Empty() {
+		t.Error("Empty() = true for differing sets")
+	}
+	if d := ComputeDiff(old, old); !d.Empty() {
+		t.Errorf("ComputeDiff(old, old) = %+v, want empty", d)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	categoryA := []Item{testItems[0], testItems[1]}
+	red := []Item{testItems[0], testItems[2]}
+	tests := []struct {
+		name        string
+		left, right []Item
+		want        Comparison
+	}{
+		{"overlapping", categoryA, red, Comparison{OnlyLeft: []Item{testItems[1]}, OnlyRight: []Item{testItems[2]}, Both: []Item{testItems[0]}}},
+		{"disjoint", categoryA, testItems[2:], Comparison{OnlyLeft: categoryA, OnlyRight: testItems[2:]}},
+		{"identical", red, red, Comparison{Both: red}},
+		{"empty", nil, red, Comparison{OnlyRight: red}},
+	}
+	for _, tt := range tests {
+		if got := Compare(tt.left, tt.right); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Compare() = %+v, want %+v", tt.name, got, tt.want)
+		}
 	}
+}
 
+func TestItemStore_CreatedAt(t *testing.T) {
 	store, err := New(testItems)
 	if err != nil {
 		t.Fatalf("Failed to create store: %v", err)
 	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := store.GetUniqueValues(tt.property)
-			if len(got) != len(tt.want) {
-				t.Fatalf("GetUniqueValues() = %v, want %v", got, tt.want)
-			}
-			for i := range got {
-				if got[i] != tt.want[i] {
-					t.Errorf("GetUniqueValues()[%d] = %v, want %v", i, got[i], tt.want[i])
-				}
-			}
-		})
+	added, err := store.Add(Item{Color: "red", Shape: "circle", Category: "A"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if !added.CreatedAt.Equal(now) {
+		t.Errorf("CreatedAt = %v, want %v", added.CreatedAt, now)
+	}
+
+	given := now.Add(-time.Hour)
+	kept, err := store.Add(Item{Color: "red", Shape: "circle", Category: "A", CreatedAt: given})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if !kept.CreatedAt.Equal(given) {
+		t.Errorf("CreatedAt = %v, want the given %v", kept.CreatedAt, given)
+	}
+
+	updated, err := store.Update(Item{ID: added.ID, Color: "blue", Shape: "circle", Category: "A", CreatedAt: given})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !updated.CreatedAt.Equal(now) {
+		t.Errorf("CreatedAt after Update = %v, want it unchanged at %v", updated.CreatedAt, now)
 	}
 }
 
-func TestItem_Validate(t *testing.T) {
-	tests := []struct {
-		name    string
-		item    Item
-		wantErr bool
-	}{
-		{
-			name:    "valid item",
-			item:    Item{ID: 1, Color: "red", Shape: "circle", Category: "A"},
-			wantErr: false,
-		},
-		{
-			name:    "missing ID",
-			item:    Item{ID: 0, Color: "red", Shape: "circle", Category: "A"},
-			wantErr: true,
-		},
-		{
-			name:    "missing color",
-			item:    Item{ID: 1, Color: "", Shape: "circle", Category: "A"},
-			wantErr: true,
-		},
-		{
-			name:    "missing shape",
-			item:    Item{ID: 1, Color: "red", Shape: "", Category: "A"},
-			wantErr: true,
-		},
-		{
-			name:    "missing category",
-			item:    Item{ID: 1, Color: "red", Shape: "circle", Category: ""},
-			wantErr: true,
-		},
+func TestItemStore_CanonicalColors(t *testing.T) {
+	store, err := New([]Item{
+		{ID: IntID(1), Color: "Grey", Shape: "circle", Category: "A"},
+		{ID: IntID(2), Color: "gray", Shape: "square", Category: "A"},
+		{ID: IntID(3), Color: "NAVY BLUE", Shape: "square", Category: "B"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := store.Add(Item{Color: "navy-blue", Shape: "circle", Category: "B"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := store.Update(Item{ID: IntID(2), Color: " grey ", Shape: "square", Category: "A"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := tt.item.Validate()
-			if (err != nil) != tt.wantErr {
-				t.Errorf("Item.Validate() error = %v, wantErr %v", err, tt.wantErr)
+	if got, want := store.GetUniqueValues("color"), []string{"gray", "navy-blue"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetUniqueValues(color) = %q, want %q", got, want)
+	}
+	if got, want := store.CountBy("color"), map[string]int{"gray": 2, "navy-blue": 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CountBy(color) = %v, want %v", got, want)
+	}
+	groups, err := GroupBy(t.Context(), store.Filter(nil), "color")
+	if err != nil || len(groups) != 2 || len(groups["gray"]) != 2 {
+		t.Errorf("GroupBy(color) = %v, want gray and navy-blue with two items each", groups)
+	}
+	// Filters match however the color is written
+	if got := store.Filter(map[string]string{"color": "Navy Blue"}); len(got) != 2 {
+		t.Errorf("Filter(color: Navy Blue) = %v, want both navy blue items", got)
+	}
+
+	// Display uses the pretty form
+	item, _ := store.Get(IntID(3))
+	if got := item.Format().Color; got != "Navy Blue" {
+		t.Errorf("Format().Color = %q, want Navy Blue", got)
+	}
+}
+
+func TestItemStore_WithKnownShapes(t *testing.T) {
+	shapes := format.NewShapeRegistry()
+	if err := shapes.RegisterShape("hexagon", "", "hex"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New([]Item{{ID: IntID(1), Color: "red", Shape: "octagon", Category: "A"}}, WithKnownShapes(shapes)); err == nil {
+		t.Error("New() accepted an unknown shape")
+	}
+	store, err := New([]Item{{ID: IntID(1), Color: "red", Shape: "Hex", Category: "A"}}, WithKnownShapes(shapes))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if item, _ := store.Get(IntID(1)); item.Shape != "hexagon" {
+		t.Errorf("stored shape = %q, want the alias resolved to hexagon", item.Shape)
+	}
+	if _, err := store.Add(Item{Color: "red", Shape: "octagon", Category: "A"}); !errors.Is(err, ErrInvalidItem) {
+		t.Errorf("Add() error = %v, want %v", err, ErrInvalidItem)
+	}
+	if _, err := store.Update(Item{ID: IntID(1), Color: "red", Shape: "octagon", Category: "A"}); !errors.Is(err, ErrInvalidItem) {
+		t.Errorf("Update() error = %v, want %v", err, ErrInvalidItem)
+	}
+	if _, err := store.SetItems([]Item{{ID: IntID(1), Color: "red", Shape: "octagon", Category: "A"}}); err == nil {
+		t.Error("SetItems() accepted an unknown shape")
+	}
+	if _, err := store.Add(Item{Color: "red", Shape: "triangle", Category: "A"}); err != nil {
+		t.Errorf("Add() error = %v", err)
+	}
+
+	// Without the option any shape goes
+	if _, err := New([]Item{{ID: IntID(1), Color: "red", Shape: "octagon", Category: "A"}}); err != nil {
+		t.Errorf("New() without WithKnownShapes error = %v", err)
+	}
+}
+
+func TestItemStore_ItemsSnapshot(t *testing.T) {
+	for name, opts := range storeVariants {
+		t.Run(name, func(t *testing.T) {
+			store, err := New(testItems, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var seen []Item
+			for item := range store.Items(map[string]string{"color": "red"}) {
+				// The iterator holds no lock, so the store can change
+				// underneath it without the change showing up
+				if _, err := store.Update(Item{ID: IntID(3), Color: "blue", Shape: "circle", Category: "A"}); err != nil {
+					t.Fatal(err)
+				}
+				if err := store.Delete(IntID(1)); err != nil && len(seen) == 0 {
+					t.Fatal(err)
+				}
+				seen = append(seen, item)
+			}
+			if got, want := idsOf(seen), []int{1, 3}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Items() yielded %v, want the snapshot's %v", got, want)
+			}
+			if seen[1].Color != "red" {
+				t.Errorf("item 3 = %+v, want the red one from before the update", seen[1])
+			}
+			if got := idsOf(store.Filter(map[string]string{"color": "red"})); len(got) != 0 {
+				t.Errorf("Filter() after the changes = %v, want none", got)
 			}
 		})
 	}
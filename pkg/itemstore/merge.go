@@ -0,0 +1,191 @@
+package itemstore
+
+import (
+	"fmt"
+	"slices"
+)
+
+// MergeStrategy decides what Merge does with an incoming item whose ID is
+// taken by a different stored item
+type MergeStrategy int
+
+const (
+	// MergeFail aborts the merge at the first conflict, leaving the store
+	// untouched
+	MergeFail MergeStrategy = iota
+	// MergeSkip keeps the stored item
+	MergeSkip
+	// MergeOverwrite replaces the stored item with the incoming one, keeping
+	// its CreatedAt like Update
+	MergeOverwrite
+	// MergeRenumber adds the incoming item under a new ID
+	MergeRenumber
+)
+
+var mergeStrategyNames = []string{
+	MergeFail:      "fail",
+	MergeSkip:      "skip",
+	MergeOverwrite: "overwrite",
+	MergeRenumber:  "renumber",
+}
+
+func (m MergeStrategy) String() string {
+	if m < 0 || int(m) >= len(mergeStrategyNames) {
+		return fmt.Sprintf("MergeStrategy(%d)", int(m))
+	}
+	return mergeStrategyNames[m]
+}
+
+// ParseMergeStrategy returns the strategy named by s: fail, skip, overwrite,
+// or renumber
+func ParseMergeStrategy(s string) (MergeStrategy, error) {
+	if i := slices.Index(mergeStrategyNames, s); i >= 0 {
+		return MergeStrategy(i), nil
+	}
+	return 0, fmt.Errorf("unknown merge strategy %q, want fail, skip, overwrite, or renumber", s)
+}
+
+// MergeOutcome is what Merge did with one incoming item
+type MergeOutcome string
+
+const (
+	// MergeAdded items had a free ID, or none and were assigned one
+	MergeAdded MergeOutcome = "added"
+	// MergeUnchanged items were already stored with the same values
+	MergeUnchanged MergeOutcome = "unchanged"
+	// MergeSkipped items conflicted and were dropped for the stored item
+	MergeSkipped MergeOutcome = "skipped"
+	// MergeOverwritten items conflicted and replaced the stored item
+	MergeOverwritten MergeOutcome = "overwritten"
+	// MergeRenumbered items conflicted and were added under a new ID
+	MergeRenumbered MergeOutcome = "renumbered"
+)
+
+// MergeResult reports what happened to one incoming item
+type MergeResult struct {
+	// Index is the item's position in the incoming items
+	Index   int
+	Outcome MergeOutcome
+	// Item is the incoming item as stored, or for an unchanged or skipped
+	// item the one that stays stored
+	Item Item
+	// Existing is the stored item a conflicting incoming item collided with
+	Existing *Item
+}
+
+// MergeReport lists the result of every incoming item in input order
+type MergeReport struct {
+	Results []MergeResult
+}
+
+// Count returns the number of incoming items with the given outcome
+func (r MergeReport) Count(outcome MergeOutcome) int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Outcome == outcome {
+			n++
+		}
+	}
+	return n
+}
+
+// Merge adds items to the store, resolving items whose ID is taken by a
+// different stored item with strategy. An incoming item whose ID is taken
+// by an item with the same values is left alone; one without a creation time
+// matches any. Items are merged in order, so an incoming item can also
+// conflict with an earlier one. Items without an ID and renumbered items get
+// IDs above every stored and incoming ID, or new UUIDs in a store of string
+// IDs, so the IDs of the other items are kept.
+//
+// The merge is atomic: if any item is invalid, or strategy is MergeFail and
+// an item conflicts, the store is left untouched.
+func (s *ItemStore) Merge(items []Item, strategy MergeStrategy) (MergeReport, error) {
+	normalized := s.normalizeItems(items)
+	for i, item := range normalized {
+		if item.ID.IsZero() {
+			item.ID = s.newID(1)
+		}
+		if err := s.validate(item); err != nil {
+			return MergeReport{}, fmt.Errorf("item at index %d: %w: %w", i, ErrInvalidItem, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Plan the merge on a copy, so nothing changes until it is known to
+	// succeed
+	merged := slices.Clone(s.items)
+	positions := make(map[ID]int, len(merged))
+	for i, item := range merged {
+		positions[item.ID] = i
+	}
+	nextID := s.nextID
+	for _, item := range normalized {
+		nextID = followingID(nextID, item.ID)
+	}
+	var changes []Change
+	report := MergeReport{Results: make([]MergeResult, 0, len(items))}
+	add := func(item Item) Item {
+		if item.CreatedAt.IsZero() {
+			item.CreatedAt = s.now().UTC()
+		}
+		positions[item.ID] = len(merged)
+		merged = append(merged, item)
+		changes = append(changes, Change{Type: ItemCreated, Item: item})
+		return item
+	}
+
+	for i, item := range normalized {
+		result := MergeResult{Index: i}
+		pos, taken := positions[item.ID]
+		switch {
+		case item.ID.IsZero():
+			item.ID = s.newID(nextID)
+			nextID++
+			result.Outcome, result.Item = MergeAdded, add(item)
+		case !taken:
+			result.Outcome, result.Item = MergeAdded, add(item)
+		case sameValues(merged[pos], item):
+			result.Outcome, result.Item = MergeUnchanged, merged[pos]
+		default:
+			existing := merged[pos]
+			result.Existing = &existing
+			switch strategy {
+			case MergeSkip:
+				result.Outcome, result.Item = MergeSkipped, existing
+			case MergeOverwrite:
+				item.CreatedAt = existing.CreatedAt
+				merged[pos] = item
+				changes = append(changes, Change{Type: ItemUpdated, Item: item, Previous: &existing})
+				result.Outcome, result.Item = MergeOverwritten, item
+			case MergeRenumber:
+				item.ID = s.newID(nextID)
+				nextID++
+				result.Outcome, result.Item = MergeRenumbered, add(item)
+			default:
+				return MergeReport{}, fmt.Errorf("%w: %s", ErrDuplicateID, item.ID)
+			}
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	if len(changes) > 0 {
+		s.items, s.nextID = merged, nextID
+		s.counts = newValueCounts(s.items)
+		s.rebuildIndexesLocked()
+		s.publishLocked()
+		for _, c := range changes {
+			s.notifyLocked(c)
+		}
+	}
+	return report, nil
+}
+
+// sameValues reports whether incoming holds the values of stored. An
+// incoming item without a creation time takes the stored one, so its absence
+// is no difference.
+func sameValues(stored, incoming Item) bool {
+	return stored.Color == incoming.Color && stored.Shape == incoming.Shape && stored.Category == incoming.Category &&
+		(incoming.CreatedAt.IsZero() || incoming.CreatedAt.Equal(stored.CreatedAt))
+}
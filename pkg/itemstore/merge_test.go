@@ -0,0 +1,131 @@
+package itemstore
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// mergeItems keeps item 1, changes item 2, and adds items 9, one without an
+// ID, and 5
+var mergeItems = []Item{
+	{ID: IntID(1), Color: "Red", Shape: "circle", Category: "A"},
+	{ID: IntID(2), Color: "teal", Shape: "square", Category: "A"},
+	{ID: IntID(9), Color: "pink", Shape: "circle", Category: "C"},
+	{Color: "gray", Shape: "square", Category: "C"},
+	{ID: IntID(5), Color: "black", Shape: "circle", Category: "C"},
+}
+
+// outcomes returns the outcome and stored ID of each result
+func outcomes(report MergeReport) []string {
+	var got []string
+	for _, r := range report.Results {
+		got = append(got, fmt.Sprintf("%s:%s", r.Outcome, r.Item.ID))
+	}
+	return got
+}
+
+func colorsByID(store *ItemStore) map[int]string {
+	colors := make(map[int]string)
+	for _, item := range store.Filter(nil) {
+		id, _ := item.ID.Int()
+		colors[id] = item.Color
+	}
+	return colors
+}
+
+func TestItemStore_Merge(t *testing.T) {
+	tests := []struct {
+		strategy MergeStrategy
+		want     []string
+		colors   map[int]string
+	}{
+		{
+			MergeSkip,
+			[]string{"unchanged:1", "skipped:2", "added:9", "added:10", "added:5"},
+			map[int]string{1: "red", 2: "blue", 3: "red", 4: "green", 5: "black", 9: "pink", 10: "gray"},
+		},
+		{
+			MergeOverwrite,
+			[]string{"unchanged:1", "overwritten:2", "added:9", "added:10", "added:5"},
+			map[int]string{1: "red", 2: "teal", 3: "red", 4: "green", 5: "black", 9: "pink", 10: "gray"},
+		},
+		{
+			// The renumbered item and the one without an ID go above every
+			// incoming ID, so items 9 and 5 keep theirs
+			MergeRenumber,
+			[]string{"unchanged:1", "renumbered:10", "added:9", "added:11", "added:5"},
+			map[int]string{1: "red", 2: "blue", 3: "red", 4: "green", 5: "black", 9: "pink", 10: "teal", 11: "gray"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy.String(), func(t *testing.T) {
+			store := newTestStore(t)
+			var changes []Change
+			store.OnChange(func(c Change) { changes = append(changes, c) })
+
+			report, err := store.Merge(mergeItems, tt.strategy)
+			if err != nil {
+				t.Fatalf("Merge() error = %v", err)
+			}
+			if got := outcomes(report); !slices.Equal(got, tt.want) {
+				t.Errorf("outcomes = %q, want %q", got, tt.want)
+			}
+			if got := colorsByID(store); !reflect.DeepEqual(got, tt.colors) {
+				t.Errorf("stored colors = %v, want %v", got, tt.colors)
+			}
+			if conflict := report.Results[1]; conflict.Existing == nil || conflict.Existing.Color != "blue" {
+				t.Errorf("conflict result = %+v, want the stored blue square as Existing", conflict)
+			}
+			if n := report.Count(MergeAdded) + report.Count(MergeRenumbered) + report.Count(MergeOverwritten); len(changes) != n {
+				t.Errorf("got %d change notifications, want %d", len(changes), n)
+			}
+			want := slices.Max(slices.Collect(maps.Keys(tt.colors))) + 1
+			if item, _ := store.Add(Item{Color: "red", Shape: "circle", Category: "A"}); item.ID != IntID(want) {
+				t.Errorf("next added ID = %s, want %d", item.ID, want)
+			}
+		})
+	}
+}
+
+func TestItemStore_MergeFail(t *testing.T) {
+	store := newTestStore(t)
+	revision := store.Revision()
+
+	_, err := store.Merge(mergeItems, MergeFail)
+	if !errors.Is(err, ErrDuplicateID) {
+		t.Fatalf("Merge() error = %v, want ErrDuplicateID", err)
+	}
+	if got := store.Filter(nil); !reflect.DeepEqual(got, testItems) || store.Revision() != revision {
+		t.Errorf("items = %+v after a failed merge, want them untouched", got)
+	}
+
+	// Without conflicts, fail merges like the others
+	report, err := store.Merge(mergeItems[2:], MergeFail)
+	if err != nil || report.Count(MergeAdded) != 3 {
+		t.Errorf("Merge() = %+v, %v; want three items added", report, err)
+	}
+
+	// An invalid item aborts every strategy
+	before := store.Filter(nil)
+	if _, err := store.Merge([]Item{{ID: IntID(20), Color: "red", Shape: "circle", Category: "A"}, {ID: IntID(21)}}, MergeSkip); !errors.Is(err, ErrInvalidItem) {
+		t.Errorf("Merge() of an invalid item error = %v, want ErrInvalidItem", err)
+	}
+	if got := store.Filter(nil); !reflect.DeepEqual(got, before) {
+		t.Error("a merge with an invalid item changed the store")
+	}
+}
+
+func TestParseMergeStrategy(t *testing.T) {
+	for _, m := range []MergeStrategy{MergeFail, MergeSkip, MergeOverwrite, MergeRenumber} {
+		if got, err := ParseMergeStrategy(m.String()); got != m || err != nil {
+			t.Errorf("ParseMergeStrategy(%q) = %v, %v", m, got, err)
+		}
+	}
+	if _, err := ParseMergeStrategy("replace"); err == nil {
+		t.Error("ParseMergeStrategy(replace) succeeded")
+	}
+}
@@ -0,0 +1,42 @@
+package itemstore
+
+// readOnlyStore serves the reads of the Store it embeds and refuses its
+// writes
+type readOnlyStore struct {
+	Store
+}
+
+// ReadOnly returns a Store that reads from store and rejects every write with
+// ErrReadOnly. Change hooks registered on it still see the changes made to
+// store directly, such as data file reloads.
+func ReadOnly(store Store) Store {
+	return readOnlyStore{store}
+}
+
+func (readOnlyStore) Add(Item) (Item, error) {
+	return Item{}, ErrReadOnly
+}
+
+func (readOnlyStore) AddAll([]Item) ([]Item, error) {
+	return nil, ErrReadOnly
+}
+
+func (readOnlyStore) Update(Item) (Item, error) {
+	return Item{}, ErrReadOnly
+}
+
+func (readOnlyStore) UpdateIf(Item, func(Item) bool) (Item, error) {
+	return Item{}, ErrReadOnly
+}
+
+func (readOnlyStore) Delete(ID) error {
+	return ErrReadOnly
+}
+
+func (readOnlyStore) SetItems([]Item) (Diff, error) {
+	return Diff{}, ErrReadOnly
+}
+
+func (readOnlyStore) Merge([]Item, MergeStrategy) (MergeReport, error) {
+	return MergeReport{}, ErrReadOnly
+}
@@ -0,0 +1,42 @@
+package itemstore
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	store := newTestStore(t)
+	ro := ReadOnly(store)
+
+	if _, err := ro.Add(Item{Color: "red", Shape: "circle", Category: "A"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Add() error = %v, want ErrReadOnly", err)
+	}
+	if _, err := ro.AddAll(testItems[:1]); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("AddAll() error = %v, want ErrReadOnly", err)
+	}
+	if _, err := ro.Update(Item{ID: IntID(1), Color: "blue", Shape: "circle", Category: "A"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Update() error = %v, want ErrReadOnly", err)
+	}
+	if err := ro.Delete(IntID(1)); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Delete() error = %v, want ErrReadOnly", err)
+	}
+	if _, err := ro.SetItems(nil); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("SetItems() error = %v, want ErrReadOnly", err)
+	}
+	if _, err := ro.Merge(testItems[:1], MergeOverwrite); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Merge() error = %v, want ErrReadOnly", err)
+	}
+	if got := store.Filter(nil); !reflect.DeepEqual(got, testItems) {
+		t.Errorf("items = %+v after refused writes, want them unchanged", got)
+	}
+
+	// Reads see changes made to the wrapped store
+	if _, err := store.Add(Item{ID: IntID(9), Color: "red", Shape: "circle", Category: "A"}); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := ro.Get(IntID(9)); err != nil || item.ID != IntID(9) || ro.Len() != 5 {
+		t.Errorf("Get(9) = %+v, %v, Len() = %d; want the added item", item, err, ro.Len())
+	}
+}
@@ -0,0 +1,165 @@
+package itemstore
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestItemStore_SaveJSON(t *testing.T) {
+	store := newTestStore(t)
+	var buf bytes.Buffer
+	if err := store.SaveJSON(&buf); err != nil {
+		t.Fatalf("SaveJSON() error = %v", err)
+	}
+	loaded, err := LoadJSON(&buf)
+	if err != nil {
+		t.Fatalf("LoadJSON() of saved items error = %v", err)
+	}
+	if want := store.Filter(nil); !reflect.DeepEqual(loaded, want) {
+		t.Errorf("LoadJSON() = %+v, want %+v", loaded, want)
+	}
+
+	empty, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := empty.SaveJSON(&buf); err != nil || buf.String() != "[]\n" {
+		t.Errorf("SaveJSON() of an empty store = %q, %v", buf.String(), err)
+	}
+}
+
+// blockingWriter blocks every Write until release is closed
+type blockingWriter struct {
+	writing chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.writing) })
+	<-w.release
+	return len(p), nil
+}
+
+func TestItemStore_SaveJSONDoesNotBlockWriters(t *testing.T) {
+	store := newTestStore(t)
+	w := &blockingWriter{writing: make(chan struct{}), release: make(chan struct{})}
+	saved := make(chan error)
+	go func() { saved <- store.SaveJSON(w) }()
+	<-w.writing
+
+	// The export is stuck writing, yet every kind of mutation goes through
+	if _, err := store.Add(Item{Color: "blue", Shape: "circle", Category: "C"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Update(Item{ID: IntID(1), Color: "blue", Shape: "circle", Category: "C"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(IntID(2)); err != nil {
+		t.Fatal(err)
+	}
+	close(w.release)
+	if err := <-saved; err != nil {
+		t.Errorf("SaveJSON() error = %v", err)
+	}
+}
+
+// TestItemStore_SnapshotsDuringMutations exports and iterates the store
+// while other goroutines mutate it. Run with -race: readers take no lock,
+// so any write to an element a snapshot can see is reported.
+func TestItemStore_SnapshotsDuringMutations(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 5))
+	items := make([]Item, 200)
+	for i := range items {
+		items[i] = randomItem(r, i+1)
+	}
+	for name, opts := range storeVariants {
+		t.Run(name, func(t *testing.T) {
+			store, err := New(items, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var writers, readers sync.WaitGroup
+			done := make(chan struct{})
+			for w := range 4 {
+				writers.Go(func() {
+					r := rand.New(rand.NewPCG(uint64(w), 0))
+					for range 100 {
+						mutate(store, r.IntN(5), r)
+					}
+				})
+			}
+			for range 4 {
+				readers.Go(func() {
+					for {
+						select {
+						case <-done:
+							return
+						default:
+						}
+						var buf bytes.Buffer
+						if err := store.SaveJSON(&buf); err != nil {
+							t.Error(err)
+							return
+						}
+						// A snapshot is one consistent state: LoadJSON rejects
+						// duplicate IDs
+						if _, err := LoadJSON(&buf); err != nil {
+							t.Errorf("LoadJSON() of a snapshot error = %v", err)
+							return
+						}
+						n := 0
+						for range store.Items(nil) {
+							n++
+						}
+						store.CountsBy(properties...)
+						store.Get(IntID(n))
+					}
+				})
+			}
+			writers.Wait()
+			close(done)
+			readers.Wait()
+		})
+	}
+}
+
+// benchmarkMixed runs parallel goroutines over a store of 1,000 items where
+// one operation in every writeEvery is an Update and the rest count the items
+// by color
+func benchmarkMixed(b *testing.B, writeEvery int) {
+	items := make([]Item, 1000)
+	for i := range items {
+		items[i] = Item{ID: IntID(i + 1), Color: fmt.Sprintf("color-%d", i%40), Shape: "circle", Category: "A"}
+	}
+	store, err := New(items)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewPCG(rand.Uint64(), 0))
+		for i := 0; pb.Next(); i++ {
+			if i%writeEvery == 0 {
+				item := items[r.IntN(len(items))]
+				item.Color = fmt.Sprintf("color-%d", r.IntN(40))
+				if _, err := store.Update(item); err != nil {
+					b.Error(err)
+				}
+				continue
+			}
+			store.CountBy("color")
+		}
+	})
+}
+
+func BenchmarkSnapshot(b *testing.B) {
+	b.Run("read-heavy", func(b *testing.B) { benchmarkMixed(b, 100) })
+	b.Run("write-heavy", func(b *testing.B) { benchmarkMixed(b, 2) })
+}
@@ -0,0 +1,796 @@
+// Package storetest holds the behavioral test suite every itemstore.Store
+// implementation must pass, so backends cannot drift from the semantics of
+// the in-memory ItemStore. It passes for stores of int and of string IDs
+// alike. A backend wires it up with one call:
+//
+//	func TestStore(t *testing.T) {
+//		storetest.TestStore(t, func() itemstore.Store { return newBackend(t) })
+//	}
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// created is the creation time of the first seed item; each later one was
+// created an hour after the one before
+var created = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// seed are the items every test starts from. Item 5's color is stored in
+// its canonical form, gray. Stores of string IDs keep the IDs as "1" to "6".
+var seed = []itemstore.Item{
+	{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"},
+	{ID: itemstore.IntID(2), Color: "blue", Shape: "square", Category: "A"},
+	{ID: itemstore.IntID(3), Color: "red", Shape: "square", Category: "B"},
+	{ID: itemstore.IntID(4), Color: "green", Shape: "circle", Category: "B"},
+	{ID: itemstore.IntID(5), Color: "Grey", Shape: "triangle", Category: "C"},
+	{ID: itemstore.IntID(6), Color: "red", Shape: "circle", Category: "A"},
+}
+
+func init() {
+	for i := range seed {
+		seed[i].CreatedAt = created.Add(time.Duration(i) * time.Hour)
+	}
+}
+
+// TestStore runs the suite against the stores newStore returns. Every call
+// must return a new, empty store that accepts any shape; each subtest seeds
+// its own store through SetItems.
+func TestStore(t *testing.T, newStore func() itemstore.Store) {
+	t.Run("Empty", func(t *testing.T) { testEmpty(t, newStore()) })
+
+	tests := []struct {
+		name string
+		fn   func(*testing.T, itemstore.Store)
+	}{
+		{"Get", testGet},
+		{"Add", testAdd},
+		{"AddAll", testAddAll},
+		{"Check", testCheck},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"SetItems", testSetItems},
+		{"Merge", testMerge},
+		{"Filter", testFilter},
+		{"Canceled", testCanceled},
+		{"UniqueValues", testUniqueValues},
+		{"Counts", testCounts},
+		{"Combinations", testCombinations},
+		{"GroupBy", testGroupBy},
+		{"Revision", testRevision},
+		{"OnChange", testOnChange},
+		{"Concurrency", testConcurrency},
+		{"IDs", testIDs},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore()
+			if _, err := store.SetItems(seed); err != nil {
+				t.Fatalf("SetItems(seed) error = %v", err)
+			}
+			tt.fn(t, store)
+		})
+	}
+}
+
+// stored returns the seed item with the given ID as the store keeps it
+func stored(id int) itemstore.Item {
+	return seed[id-1].Normalize()
+}
+
+// sameItem reports whether a and b are the same item, comparing IDs by their
+// string form, as seed IDs are ints that stores of string IDs convert, and
+// creation times as instants so backends may return them in any location
+func sameItem(a, b itemstore.Item) bool {
+	return a.ID.String() == b.ID.String() && a.Color == b.Color && a.Shape == b.Shape && a.Category == b.Category && a.CreatedAt.Equal(b.CreatedAt)
+}
+
+// ids returns the string forms of the IDs of items
+func ids(items []itemstore.Item) []string {
+	ids := []string{}
+	for _, item := range items {
+		ids = append(ids, item.ID.String())
+	}
+	return ids
+}
+
+// idList returns the string forms of ids, each an int or an itemstore.ID, to
+// compare with ids
+func idList(ids ...any) []string {
+	list := []string{}
+	for _, id := range ids {
+		switch id := id.(type) {
+		case int:
+			list = append(list, strconv.Itoa(id))
+		case itemstore.ID:
+			list = append(list, id.String())
+		}
+	}
+	return list
+}
+
+// uuid matches the IDs stores of string IDs assign
+var uuid = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// checkAssigned fails the test unless items have the IDs the store assigns:
+// want in a store of int IDs, or distinct UUIDs in a store of string IDs
+func checkAssigned(t *testing.T, store itemstore.Store, items []itemstore.Item, want ...int) {
+	t.Helper()
+	if len(items) != len(want) {
+		t.Errorf("%d items were assigned IDs, want %d", len(items), len(want))
+		return
+	}
+	seen := make(map[itemstore.ID]bool)
+	for i, item := range items {
+		switch {
+		case !store.StringIDs() && item.ID != itemstore.IntID(want[i]):
+			t.Errorf("assigned ID = %s, want %d", item.ID, want[i])
+		case store.StringIDs() && (!uuid.MatchString(item.ID.String()) || seen[item.ID]):
+			t.Errorf("assigned ID = %q, want a new UUID", item.ID)
+		}
+		seen[item.ID] = true
+	}
+}
+
+// checkIDs fails the test unless the store holds exactly the given IDs, in
+// that order. Each is an int or an itemstore.ID, as for idList.
+func checkIDs(t *testing.T, store itemstore.Store, wantIDs ...any) {
+	t.Helper()
+	want := idList(wantIDs...)
+	if got := ids(store.Filter(nil)); !slices.Equal(got, want) {
+		t.Errorf("stored IDs = %q, want %q", got, want)
+	}
+	if store.Len() != len(want) {
+		t.Errorf("Len() = %d, want %d", store.Len(), len(want))
+	}
+}
+
+func testEmpty(t *testing.T, store itemstore.Store) {
+	checkIDs(t, store)
+	if got := store.GetUniqueValues("color"); len(got) != 0 {
+		t.Errorf("GetUniqueValues(color) = %q, want none", got)
+	}
+	if got := store.CountBy("color"); len(got) != 0 {
+		t.Errorf("CountBy(color) = %v, want none", got)
+	}
+	if _, err := store.Get(itemstore.IntID(1)); !errors.Is(err, itemstore.ErrNotFound) {
+		t.Errorf("Get(1) error = %v, want ErrNotFound", err)
+	}
+	added, err := store.Add(itemstore.Item{Color: "red", Shape: "circle", Category: "A"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	checkAssigned(t, store, []itemstore.Item{added}, 1)
+}
+
+func testGet(t *testing.T, store itemstore.Store) {
+	for i, item := range seed {
+		got, err := store.Get(item.ID)
+		if err != nil || !sameItem(got, stored(i+1)) {
+			t.Errorf("Get(%s) = %+v, %v; want %+v", item.ID, got, err, stored(i+1))
+		}
+	}
+	for _, id := range []itemstore.ID{{}, itemstore.IntID(-1), itemstore.IntID(42), itemstore.StringID("missing")} {
+		if _, err := store.Get(id); !errors.Is(err, itemstore.ErrNotFound) {
+			t.Errorf("Get(%s) error = %v, want ErrNotFound", id, err)
+		}
+	}
+}
+
+func testAdd(t *testing.T, store itemstore.Store) {
+	added, err := store.Add(itemstore.Item{Color: " Blue ", Shape: "circle", Category: "C"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if added.Color != "blue" || added.CreatedAt.IsZero() {
+		t.Errorf("Add() = %+v, want the normalized color and a creation time", added)
+	}
+	if got, err := store.Get(added.ID); err != nil || !sameItem(got, added) {
+		t.Errorf("Get(%s) = %+v, %v; want the added %+v", added.ID, got, err, added)
+	}
+
+	given := created.Add(-time.Hour)
+	kept, err := store.Add(itemstore.Item{ID: itemstore.IntID(10), Color: "red", Shape: "square", Category: "C", CreatedAt: given})
+	if err != nil || kept.ID.String() != "10" || !kept.CreatedAt.Equal(given) {
+		t.Errorf("Add() with an ID and creation time = %+v, %v; want both kept", kept, err)
+	}
+	next, err := store.Add(itemstore.Item{Color: "red", Shape: "square", Category: "C"})
+	if err != nil {
+		t.Fatalf("Add() after ID 10 error = %v", err)
+	}
+	// Assigned IDs go above every stored one
+	checkAssigned(t, store, []itemstore.Item{added, next}, 7, 11)
+
+	for _, tt := range []struct {
+		item itemstore.Item
+		want error
+	}{
+		{itemstore.Item{ID: itemstore.IntID(1), Color: "blue", Shape: "circle", Category: "C"}, itemstore.ErrDuplicateID},
+		{itemstore.Item{Color: "blue", Category: "C"}, itemstore.ErrInvalidItem},
+		{itemstore.Item{ID: itemstore.IntID(-3), Color: "blue", Shape: "circle", Category: "C"}, itemstore.ErrInvalidItem},
+	} {
+		if _, err := store.Add(tt.item); !errors.Is(err, tt.want) {
+			t.Errorf("Add(%+v) error = %v, want %v", tt.item, err, tt.want)
+		}
+	}
+	checkIDs(t, store, 1, 2, 3, 4, 5, 6, added.ID, 10, next.ID)
+}
+
+func testAddAll(t *testing.T, store itemstore.Store) {
+	for _, tt := range []struct {
+		batch []itemstore.Item
+		want  error
+	}{
+		{[]itemstore.Item{{Color: "blue", Shape: "circle", Category: "C"}, {Color: "blue", Category: "C"}}, itemstore.ErrInvalidItem},
+		{[]itemstore.Item{{ID: itemstore.IntID(20), Color: "blue", Shape: "circle", Category: "C"}, {ID: itemstore.IntID(20), Color: "red", Shape: "circle", Category: "C"}}, itemstore.ErrDuplicateID},
+		{[]itemstore.Item{{Color: "blue", Shape: "circle", Category: "C"}, {ID: itemstore.IntID(3), Color: "red", Shape: "circle", Category: "C"}}, itemstore.ErrDuplicateID},
+	} {
+		if _, err := store.AddAll(tt.batch); !errors.Is(err, tt.want) {
+			t.Errorf("AddAll(%+v) error = %v, want %v", tt.batch, err, tt.want)
+		}
+	}
+	checkIDs(t, store, 1, 2, 3, 4, 5, 6)
+
+	added, err := store.AddAll([]itemstore.Item{
+		{Color: "blue", Shape: "circle", Category: "C"},
+		{Color: "red", Shape: "triangle", Category: "C"},
+	})
+	if err != nil {
+		t.Fatalf("AddAll() error = %v", err)
+	}
+	checkAssigned(t, store, added, 7, 8)
+	checkIDs(t, store, 1, 2, 3, 4, 5, 6, added[0].ID, added[1].ID)
+}
+
+func testCheck(t *testing.T, store itemstore.Store) {
+	got, err := store.Check(itemstore.Item{Color: " Blue ", Shape: "circle", Category: "C"})
+	if err != nil || !got.ID.IsZero() || got.Color != "blue" {
+		t.Errorf("Check() = %+v, %v; want the normalized item without an ID", got, err)
+	}
+	// Whether an ID is taken is only known once the item is added
+	if _, err := store.Check(itemstore.Item{ID: itemstore.IntID(1), Color: "blue", Shape: "circle", Category: "C"}); err != nil {
+		t.Errorf("Check() with a taken ID error = %v", err)
+	}
+	if _, err := store.Check(itemstore.Item{ID: itemstore.IntID(-1), Color: "blue", Category: "C"}); !errors.Is(err, itemstore.ErrInvalidItem) {
+		t.Errorf("Check() of an invalid item error = %v, want ErrInvalidItem", err)
+	}
+	checkIDs(t, store, 1, 2, 3, 4, 5, 6)
+}
+
+func testUpdate(t *testing.T, store itemstore.Store) {
+	updated, err := store.Update(itemstore.Item{ID: itemstore.IntID(2), Color: "Green", Shape: "triangle", Category: "C", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	// Every field changes but the creation time
+	want := itemstore.Item{ID: itemstore.IntID(2), Color: "green", Shape: "triangle", Category: "C", CreatedAt: seed[1].CreatedAt}
+	if !sameItem(updated, want) {
+		t.Errorf("Update() = %+v, want %+v", updated, want)
+	}
+	if got, _ := store.Get(itemstore.IntID(2)); !sameItem(got, want) {
+		t.Errorf("Get(2) = %+v, want %+v", got, want)
+	}
+
+	if _, err := store.Update(itemstore.Item{ID: itemstore.IntID(42), Color: "green", Shape: "triangle", Category: "C"}); !errors.Is(err, itemstore.ErrNotFound) {
+		t.Errorf("Update() of a missing item error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Update(itemstore.Item{ID: itemstore.IntID(3), Shape: "triangle", Category: "C"}); !errors.Is(err, itemstore.ErrInvalidItem) {
+		t.Errorf("Update() of an invalid item error = %v, want ErrInvalidItem", err)
+	}
+	if got, _ := store.Get(itemstore.IntID(3)); !sameItem(got, stored(3)) {
+		t.Errorf("Get(3) = %+v after a rejected update", got)
+	}
+	// Updated items keep their place
+	checkIDs(t, store, 1, 2, 3, 4, 5, 6)
+
+	// UpdateIf passes the stored item to match and updates only if it approves
+	var matched itemstore.Item
+	_, err = store.UpdateIf(itemstore.Item{ID: itemstore.IntID(4), Color: "pink", Shape: "square", Category: "B"}, func(stored itemstore.Item) bool {
+		matched = stored
+		return false
+	})
+	if !errors.Is(err, itemstore.ErrConflict) || !sameItem(matched, stored(4)) {
+		t.Errorf("UpdateIf() refused = %v after matching %+v; want ErrConflict after matching %+v", err, matched, stored(4))
+	}
+	if got, _ := store.Get(itemstore.IntID(4)); !sameItem(got, stored(4)) {
+		t.Errorf("Get(4) = %+v after a refused update", got)
+	}
+	approve := func(itemstore.Item) bool { return true }
+	if got, err := store.UpdateIf(itemstore.Item{ID: itemstore.IntID(4), Color: "pink", Shape: "square", Category: "B"}, approve); err != nil || got.Color != "pink" {
+		t.Errorf("UpdateIf() approved = %+v, %v; want the pink item", got, err)
+	}
+	if _, err := store.UpdateIf(itemstore.Item{ID: itemstore.IntID(42), Color: "green", Shape: "triangle", Category: "C"}, approve); !errors.Is(err, itemstore.ErrNotFound) {
+		t.Errorf("UpdateIf() of a missing item error = %v, want ErrNotFound", err)
+	}
+}
+
+func testDelete(t *testing.T, store itemstore.Store) {
+	if err := store.Delete(itemstore.IntID(3)); err != nil {
+		t.Fatalf("Delete(3) error = %v", err)
+	}
+	if _, err := store.Get(itemstore.IntID(3)); !errors.Is(err, itemstore.ErrNotFound) {
+		t.Errorf("Get(3) after Delete error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(itemstore.IntID(3)); !errors.Is(err, itemstore.ErrNotFound) {
+		t.Errorf("second Delete(3) error = %v, want ErrNotFound", err)
+	}
+	checkIDs(t, store, 1, 2, 4, 5, 6)
+	if got := store.CountBy("color")["red"]; got != 2 {
+		t.Errorf("CountBy(color)[red] = %d after deleting a red item, want 2", got)
+	}
+}
+
+func testSetItems(t *testing.T, store itemstore.Store) {
+	var changes []itemstore.Change
+	store.OnChange(func(c itemstore.Change) { changes = append(changes, c) })
+
+	replacement := []itemstore.Item{
+		seed[0],
+		{ID: itemstore.IntID(2), Color: "purple", Shape: "square", Category: "A", CreatedAt: seed[1].CreatedAt},
+		{ID: itemstore.IntID(9), Color: "green", Shape: "triangle", Category: "C", CreatedAt: created},
+	}
+	diff, err := store.SetItems(replacement)
+	if err != nil {
+		t.Fatalf("SetItems() error = %v", err)
+	}
+	if !slices.Equal(ids(diff.Added), idList(9)) || len(diff.Changed) != 1 || diff.Changed[0].After.ID.String() != "2" || !slices.Equal(ids(diff.Removed), idList(3, 4, 5, 6)) {
+		t.Errorf("SetItems() diff = %+v, want 9 added, 2 changed, and 3 to 6 removed", diff)
+	}
+	checkIDs(t, store, 1, 2, 9)
+
+	counts := make(map[itemstore.ChangeType]int)
+	for _, c := range changes {
+		counts[c.Type]++
+	}
+	if want := map[itemstore.ChangeType]int{itemstore.ItemCreated: 1, itemstore.ItemUpdated: 1, itemstore.ItemDeleted: 4}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("change counts = %v, want %v", counts, want)
+	}
+
+	added, err := store.Add(itemstore.Item{Color: "red", Shape: "square", Category: "B"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	checkAssigned(t, store, []itemstore.Item{added}, 10)
+
+	for _, items := range [][]itemstore.Item{
+		{{ID: itemstore.IntID(1)}},
+		{{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"}, {ID: itemstore.IntID(1), Color: "blue", Shape: "circle", Category: "A"}},
+	} {
+		if _, err := store.SetItems(items); err == nil {
+			t.Errorf("SetItems(%+v) succeeded", items)
+		}
+	}
+	checkIDs(t, store, 1, 2, 9, added.ID)
+}
+
+func testMerge(t *testing.T, store itemstore.Store) {
+	teal := itemstore.Item{ID: itemstore.IntID(2), Color: "teal", Shape: "square", Category: "A"}
+
+	if _, err := store.Merge([]itemstore.Item{teal}, itemstore.MergeFail); !errors.Is(err, itemstore.ErrDuplicateID) {
+		t.Errorf("Merge(fail) error = %v, want ErrDuplicateID", err)
+	}
+	if _, err := store.Merge([]itemstore.Item{{ID: itemstore.IntID(30)}}, itemstore.MergeSkip); !errors.Is(err, itemstore.ErrInvalidItem) {
+		t.Errorf("Merge() of an invalid item error = %v, want ErrInvalidItem", err)
+	}
+	checkIDs(t, store, 1, 2, 3, 4, 5, 6)
+
+	// Outcomes show the IDs the store assigns as "new"
+	tests := []struct {
+		strategy itemstore.MergeStrategy
+		items    []itemstore.Item
+		want     []string
+	}{
+		// Item 1 without a creation time matches the stored one
+		{itemstore.MergeSkip, []itemstore.Item{{ID: itemstore.IntID(1), Color: "Red", Shape: "circle", Category: "A"}, teal}, []string{"unchanged:1", "skipped:2"}},
+		{itemstore.MergeRenumber, []itemstore.Item{teal, {Color: "pink", Shape: "circle", Category: "D"}}, []string{"renumbered:new", "added:new"}},
+		{itemstore.MergeOverwrite, []itemstore.Item{teal, {ID: itemstore.IntID(20), Color: "pink", Shape: "circle", Category: "D"}}, []string{"overwritten:2", "added:20"}},
+	}
+	var assigned []itemstore.Item
+	for _, tt := range tests {
+		report, err := store.Merge(tt.items, tt.strategy)
+		if err != nil {
+			t.Fatalf("Merge(%s) error = %v", tt.strategy, err)
+		}
+		var got []string
+		for _, r := range report.Results {
+			id := r.Item.ID.String()
+			if r.Outcome == itemstore.MergeRenumbered || tt.items[r.Index].ID.IsZero() {
+				id = "new"
+				assigned = append(assigned, r.Item)
+			}
+			got = append(got, fmt.Sprintf("%s:%s", r.Outcome, id))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Merge(%s) outcomes = %q, want %q", tt.strategy, got, tt.want)
+		}
+	}
+	// Assigned IDs go above every stored and incoming one
+	checkAssigned(t, store, assigned, 7, 8)
+	if len(assigned) == 2 {
+		checkIDs(t, store, 1, 2, 3, 4, 5, 6, assigned[0].ID, assigned[1].ID, 20)
+	}
+	// An overwritten item keeps its creation time, like an update
+	want := teal
+	want.CreatedAt = seed[1].CreatedAt
+	if got, _ := store.Get(itemstore.IntID(2)); !sameItem(got, want) {
+		t.Errorf("Get(2) = %+v, want the overwritten %+v", got, want)
+	}
+}
+
+func testFilter(t *testing.T, store itemstore.Store) {
+	tests := []struct {
+		filters map[string]string
+		want    []string
+	}{
+		{nil, idList(1, 2, 3, 4, 5, 6)},
+		{map[string]string{}, idList(1, 2, 3, 4, 5, 6)},
+		{map[string]string{"color": "red"}, idList(1, 3, 6)},
+		// Color and shape filters are normalized like stored values
+		{map[string]string{"color": "Red", "shape": "square"}, idList(3)},
+		{map[string]string{"color": " grey "}, idList(5)},
+		{map[string]string{"shape": "circle", "category": "B"}, idList(4)},
+		{map[string]string{"category": "a"}, idList()},
+		{map[string]string{"color": "purple"}, idList()},
+		// Filters on anything but a property are ignored
+		{map[string]string{"weight": "5"}, idList(1, 2, 3, 4, 5, 6)},
+	}
+	for _, tt := range tests {
+		if got := ids(store.Filter(tt.filters)); !slices.Equal(got, tt.want) {
+			t.Errorf("Filter(%v) = %v, want %v", tt.filters, got, tt.want)
+		}
+		if got, err := store.FilterContext(t.Context(), tt.filters); err != nil || !slices.Equal(ids(got), tt.want) {
+			t.Errorf("FilterContext(%v) = %v, %v; want %v", tt.filters, ids(got), err, tt.want)
+		}
+		if got := ids(slices.Collect(store.Items(tt.filters))); !slices.Equal(got, tt.want) {
+			t.Errorf("Items(%v) = %v, want %v", tt.filters, got, tt.want)
+		}
+		if got := ids(slices.Collect(store.ItemsContext(t.Context(), tt.filters))); !slices.Equal(got, tt.want) {
+			t.Errorf("ItemsContext(%v) = %v, want %v", tt.filters, got, tt.want)
+		}
+		if got := store.Count(tt.filters); got != len(tt.want) {
+			t.Errorf("Count(%v) = %d, want %d", tt.filters, got, len(tt.want))
+		}
+	}
+
+	// Stopping early is allowed
+	for item := range store.Items(map[string]string{"color": "red"}) {
+		if item.ID.String() != "1" {
+			t.Errorf("first red item = %s, want 1", item.ID)
+		}
+		break
+	}
+
+	// Results are copies the caller may change
+	items := store.Filter(nil)
+	items[0].Color = "changed"
+	if got, _ := store.Get(items[0].ID); got.Color == "changed" {
+		t.Error("changing a Filter result changed the stored item")
+	}
+}
+
+func testUniqueValues(t *testing.T, store itemstore.Store) {
+	tests := map[string][]string{
+		"color":    {"blue", "gray", "green", "red"},
+		"shape":    {"circle", "square", "triangle"},
+		"category": {"A", "B", "C"},
+		"size":     {},
+	}
+	for property, want := range tests {
+		if got := store.GetUniqueValues(property); len(got) != len(want) || len(want) > 0 && !slices.Equal(got, want) {
+			t.Errorf("GetUniqueValues(%s) = %q, want %q", property, got, want)
+		}
+	}
+
+	// Values follow mutations
+	if err := store.Delete(itemstore.IntID(5)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Update(itemstore.Item{ID: itemstore.IntID(2), Color: "navy blue", Shape: "square", Category: "A"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := store.GetUniqueValues("color"), []string{"green", "navy-blue", "red"}; !slices.Equal(got, want) {
+		t.Errorf("GetUniqueValues(color) after changes = %q, want %q", got, want)
+	}
+}
+
+func testCounts(t *testing.T, store itemstore.Store) {
+	if got, want := store.CountBy("shape"), map[string]int{"circle": 3, "square": 2, "triangle": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CountBy(shape) = %v, want %v", got, want)
+	}
+	if got := store.CountBy("size"); len(got) != 0 {
+		t.Errorf("CountBy(size) = %v, want no values", got)
+	}
+	got := store.CountsBy("shape", "category", "size", "shape")
+	want := map[string]map[string]int{
+		"shape":    {"circle": 3, "square": 2, "triangle": 1},
+		"category": {"A": 3, "B": 2, "C": 1},
+		"size":     {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CountsBy() = %v, want %v", got, want)
+	}
+
+	// Counts are copies the caller may change
+	got["shape"]["circle"] = 100
+	if store.CountBy("shape")["circle"] != 3 {
+		t.Error("changing a CountsBy result changed the store's counts")
+	}
+}
+
+func testCombinations(t *testing.T, store itemstore.Store) {
+	tests := []struct {
+		props []string
+		want  []itemstore.Combination
+	}{
+		{[]string{"category"}, []itemstore.Combination{
+			{Values: []string{"A"}, Count: 3}, {Values: []string{"B"}, Count: 2}, {Values: []string{"C"}, Count: 1},
+		}},
+		// Most common first, ties in order of values
+		{[]string{"color", "shape"}, []itemstore.Combination{
+			{Values: []string{"red", "circle"}, Count: 2},
+			{Values: []string{"blue", "square"}, Count: 1},
+			{Values: []string{"gray", "triangle"}, Count: 1},
+			{Values: []string{"green", "circle"}, Count: 1},
+			{Values: []string{"red", "square"}, Count: 1},
+		}},
+		{[]string{"size"}, []itemstore.Combination{{Values: []string{""}, Count: 6}}},
+	}
+	for _, tt := range tests {
+		if got := store.Combinations(tt.props...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Combinations(%q) = %v, want %v", tt.props, got, tt.want)
+		}
+	}
+}
+
+// testCanceled checks that reads with a context that is already done fail
+// with its error rather than return items
+func testCanceled(t *testing.T, store itemstore.Store) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	for _, filters := range []map[string]string{nil, {"color": "red"}} {
+		if got, err := store.FilterContext(ctx, filters); !errors.Is(err, context.Canceled) {
+			t.Errorf("FilterContext(%v) with a canceled context = %v, %v; want context.Canceled", filters, ids(got), err)
+		}
+		if got := ids(slices.Collect(store.ItemsContext(ctx, filters))); len(got) != 0 {
+			t.Errorf("ItemsContext(%v) with a canceled context yielded %v, want nothing", filters, got)
+		}
+	}
+
+	expired, cancel := context.WithDeadline(t.Context(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := store.FilterContext(expired, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("FilterContext() past the deadline error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func testGroupBy(t *testing.T, store itemstore.Store) {
+	items, err := store.FilterContext(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("FilterContext() error = %v", err)
+	}
+	groups, err := itemstore.GroupBy(t.Context(), items, "category")
+	if err != nil {
+		t.Fatalf("GroupBy(category) error = %v", err)
+	}
+	want := map[string][]string{"A": idList(1, 2, 6), "B": idList(3, 4), "C": idList(5)}
+	if len(groups) != len(want) {
+		t.Errorf("GroupBy(category) has %d groups, want %d", len(groups), len(want))
+	}
+	for name, wantIDs := range want {
+		if got := ids(groups[name]); !slices.Equal(got, wantIDs) {
+			t.Errorf("group %s = %v, want %v", name, got, wantIDs)
+		}
+	}
+
+	groups, err = itemstore.GroupBy(t.Context(), store.Filter(map[string]string{"shape": "circle"}), "color")
+	if err != nil || len(groups) != 2 || len(groups["red"]) != 2 || len(groups["green"]) != 1 {
+		t.Errorf("GroupBy(color) of the circles = %v, want two red and one green", groups)
+	}
+}
+
+func testRevision(t *testing.T, store itemstore.Store) {
+	rev := store.Revision()
+	failed := []func() error{
+		func() error { _, err := store.Add(itemstore.Item{Color: "blue", Category: "C"}); return err },
+		func() error {
+			_, err := store.Add(itemstore.Item{ID: itemstore.IntID(1), Color: "blue", Shape: "circle", Category: "C"})
+			return err
+		},
+		func() error { _, err := store.AddAll([]itemstore.Item{{ID: itemstore.IntID(1)}}); return err },
+		func() error {
+			_, err := store.Update(itemstore.Item{ID: itemstore.IntID(42), Color: "blue", Shape: "circle", Category: "C"})
+			return err
+		},
+		func() error { return store.Delete(itemstore.IntID(42)) },
+		func() error { _, err := store.SetItems([]itemstore.Item{{ID: itemstore.IntID(1)}}); return err },
+	}
+	for i, op := range failed {
+		if err := op(); err == nil {
+			t.Fatalf("failing operation %d succeeded", i)
+		}
+	}
+	if store.Revision() != rev {
+		t.Errorf("Revision() changed after failed operations")
+	}
+
+	succeeded := []func() error{
+		func() error {
+			_, err := store.Add(itemstore.Item{Color: "blue", Shape: "circle", Category: "C"})
+			return err
+		},
+		func() error {
+			_, err := store.Update(itemstore.Item{ID: itemstore.IntID(1), Color: "blue", Shape: "circle", Category: "C"})
+			return err
+		},
+		func() error { return store.Delete(itemstore.IntID(1)) },
+		func() error { _, err := store.SetItems(seed); return err },
+	}
+	for i, op := range succeeded {
+		if err := op(); err != nil {
+			t.Fatalf("operation %d error = %v", i, err)
+		}
+		if next := store.Revision(); next == rev {
+			t.Errorf("Revision() unchanged after operation %d", i)
+		} else {
+			rev = next
+		}
+	}
+}
+
+func testOnChange(t *testing.T, store itemstore.Store) {
+	var changes []itemstore.Change
+	store.OnChange(func(c itemstore.Change) { changes = append(changes, c) })
+
+	created, err := store.Add(itemstore.Item{Color: "blue", Shape: "circle", Category: "C"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := store.Update(itemstore.Item{ID: itemstore.IntID(1), Color: "green", Shape: "circle", Category: "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(itemstore.IntID(2)); err != nil {
+		t.Fatal(err)
+	}
+	// Failed operations notify nothing
+	store.AddAll([]itemstore.Item{{Color: "red", Shape: "square", Category: "C"}, {ID: itemstore.IntID(1)}})
+	store.Delete(itemstore.IntID(99))
+
+	previous := stored(1)
+	want := []itemstore.Change{
+		{Type: itemstore.ItemCreated, Item: created},
+		{Type: itemstore.ItemUpdated, Item: updated, Previous: &previous},
+		{Type: itemstore.ItemDeleted, Item: stored(2)},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i, c := range changes {
+		w := want[i]
+		if c.Type != w.Type || !sameItem(c.Item, w.Item) || (c.Previous == nil) != (w.Previous == nil) || c.Previous != nil && !sameItem(*c.Previous, *w.Previous) {
+			t.Errorf("change %d = %+v, want %+v", i, c, w)
+		}
+	}
+}
+
+// testConcurrency is a smoke test for data races and lost writes: writers
+// add items while readers query the store
+func testConcurrency(t *testing.T, store itemstore.Store) {
+	const writers, perWriter = 8, 50
+	var notified sync.Map
+	store.OnChange(func(c itemstore.Change) { notified.Store(c.Item.ID, true) })
+
+	var writing, reading sync.WaitGroup
+	errs := make(chan error, writers*perWriter*2)
+	for w := range writers {
+		writing.Go(func() {
+			for i := range perWriter {
+				if _, err := store.Add(itemstore.Item{Color: "red", Shape: "circle", Category: fmt.Sprintf("W%d", w)}); err != nil {
+					errs <- err
+				}
+				if i%10 == 0 {
+					if _, err := store.Update(itemstore.Item{ID: itemstore.IntID(1 + w%6), Color: "blue", Shape: "square", Category: "A"}); err != nil {
+						errs <- err
+					}
+				}
+			}
+		})
+	}
+	done := make(chan struct{})
+	for range 4 {
+		reading.Go(func() {
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for range store.Items(map[string]string{"color": "red"}) {
+				}
+				store.Count(map[string]string{"shape": "circle"})
+				store.CountsBy("color", "category")
+				store.GetUniqueValues("category")
+				store.Get(itemstore.IntID(1))
+			}
+		})
+	}
+	writing.Wait()
+	close(done)
+	reading.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent write error = %v", err)
+	}
+
+	items := store.Filter(nil)
+	if len(items) != len(seed)+writers*perWriter {
+		t.Fatalf("Len = %d after concurrent adds, want %d", len(items), len(seed)+writers*perWriter)
+	}
+	seen := make(map[itemstore.ID]bool)
+	for i, item := range items {
+		if seen[item.ID] {
+			t.Errorf("ID %s was assigned twice", item.ID)
+		}
+		seen[item.ID] = true
+		if _, ok := notified.Load(item.ID); !ok && i >= len(seed) {
+			t.Errorf("no change notification for item %s", item.ID)
+		}
+	}
+	total := 0
+	for _, n := range store.CountBy("category") {
+		total += n
+	}
+	if total != len(items) {
+		t.Errorf("CountBy(category) totals %d, want %d", total, len(items))
+	}
+}
+
+func testIDs(t *testing.T, store itemstore.Store) {
+	store.SetItems(seed)
+	// Either kind of store finds seed item 3 by "3", and both reject a blank ID
+	if id, err := store.ParseID("3"); err != nil || id.String() != "3" {
+		t.Errorf(`ParseID("3") = %s, %v; want 3`, id, err)
+	}
+	if _, err := store.ParseID(""); err == nil {
+		t.Error(`ParseID("") succeeded, want an error`)
+	}
+
+	// A number written as a string is the same item in either kind of store
+	if got, err := store.Get(itemstore.StringID("3")); err != nil || !sameItem(got, stored(3)) {
+		t.Errorf(`Get("3") = %+v, %v; want %+v`, got, err, stored(3))
+	}
+	_, err := store.Add(itemstore.Item{ID: itemstore.StringID("4"), Color: "red", Shape: "circle", Category: "A"})
+	if !errors.Is(err, itemstore.ErrDuplicateID) {
+		t.Errorf(`Add() of ID "4" error = %v, want ErrDuplicateID`, err)
+	}
+
+	// Other strings are IDs only in a store of string IDs
+	named := itemstore.Item{ID: itemstore.StringID("abc"), Color: "red", Shape: "circle", Category: "A"}
+	_, parseErr := store.ParseID("abc")
+	added, err := store.Add(named)
+	if store.StringIDs() {
+		if parseErr != nil || err != nil {
+			t.Fatalf(`ParseID("abc"), Add() = %v, %v; want the ID "abc"`, parseErr, err)
+		}
+		if got, err := store.Get(itemstore.StringID("abc")); err != nil || !sameItem(got, added) {
+			t.Errorf(`Get("abc") = %+v, %v; want %+v`, got, err, added)
+		}
+		checkIDs(t, store, 1, 2, 3, 4, 5, 6, named.ID)
+		return
+	}
+	if parseErr == nil || !errors.Is(err, itemstore.ErrInvalidItem) {
+		t.Errorf(`ParseID("abc"), Add() = %v, %v; want errors for a store of int IDs`, parseErr, err)
+	}
+	checkIDs(t, store, 1, 2, 3, 4, 5, 6)
+}
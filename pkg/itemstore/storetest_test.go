@@ -0,0 +1,32 @@
+package itemstore_test
+
+import (
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore/storetest"
+)
+
+// TestItemStore_Conformance runs the storetest suite against each store
+// configuration, restated here because this external test package cannot
+// see storeVariants
+func TestItemStore_Conformance(t *testing.T) {
+	variants := map[string][]itemstore.Option{
+		"scan":       nil,
+		"indexed":    {itemstore.WithIndexes("color", "shape", "category")},
+		"partial":    {itemstore.WithIndexes("color")},
+		"bitmap":     {itemstore.WithBitmapIndex()},
+		"string-ids": {itemstore.WithStringIDs()},
+	}
+	for name, opts := range variants {
+		t.Run(name, func(t *testing.T) {
+			storetest.TestStore(t, func() itemstore.Store {
+				store, err := itemstore.New(nil, opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return store
+			})
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package itemstore
+
+import (
+	"cmp"
+	"context"
+	"iter"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans created by this package
+const tracerName = "github.com/ElodinLaarz/dashboard/pkg/itemstore"
+
+// startSpan starts a child span of the span in ctx, using the tracer provider
+// that created the parent. Without a recording parent it returns ctx and a
+// no-op span and allocates nothing, so untraced calls pay almost nothing.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	parent := trace.SpanFromContext(ctx)
+	if !parent.IsRecording() {
+		return ctx, parent
+	}
+	return parent.TracerProvider().Tracer(tracerName).Start(ctx, name)
+}
+
+// FilterContext is Filter that gives up once ctx is done, returning its
+// error, and records a child span of the span in ctx, if any, with how many
+// items were scanned and how many matched
+func (s *ItemStore) FilterContext(ctx context.Context, filters map[string]string) ([]Item, error) {
+	_, span := startSpan(ctx, "itemstore.Filter")
+	defer span.End()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var result []Item
+	if len(filters) == 0 {
+		result = slices.Clone(s.current())
+	} else {
+		s.mu.RLock()
+		result = slices.Collect(s.itemsLocked(ctx, filters))
+		s.mu.RUnlock()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.Int("itemstore.filters", len(filters)),
+			attribute.Int("itemstore.items.scanned", s.Len()),
+			attribute.Int("itemstore.items.matched", len(result)),
+		)
+	}
+	return result, nil
+}
+
+// ItemsContext is Items with a child span of the span in ctx, if any,
+// recorded as for FilterContext. The span covers the iteration and ends
+// when it does. Iteration stops early once ctx is done, so callers check
+// ctx.Err() when it ends.
+func (s *ItemStore) ItemsContext(ctx context.Context, filters map[string]string) iter.Seq[Item] {
+	var items iter.Seq[Item]
+	if len(filters) == 0 {
+		items = valuesContext(ctx, s.current())
+	} else {
+		s.mu.RLock()
+		items = s.itemsLocked(ctx, filters)
+		s.mu.RUnlock()
+	}
+	scanned := s.Len()
+	return func(yield func(Item) bool) {
+		_, span := startSpan(ctx, "itemstore.Filter")
+		defer span.End()
+
+		matched := 0
+		defer func() {
+			if span.IsRecording() {
+				span.SetAttributes(
+					attribute.Int("itemstore.filters", len(filters)),
+					attribute.Int("itemstore.items.scanned", scanned),
+					attribute.Int("itemstore.items.matched", matched),
+				)
+			}
+		}()
+		for item := range items {
+			matched++
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// GroupBy groups items by the value of property ("color", "shape", or
+// "category"). Any other property puts every item in a single group named
+// "All". It gives up once ctx is done, returning its error. A span is
+// recorded as for FilterContext.
+func GroupBy(ctx context.Context, items []Item, property string) (map[string][]Item, error) {
+	_, span := startSpan(ctx, "itemstore.GroupBy")
+	defer span.End()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var grouped map[string][]Item
+	if !slices.Contains(properties, property) {
+		grouped = map[string][]Item{"All": items}
+	} else {
+		// Size every group first so they can share one exactly sized
+		// backing array instead of each growing its own
+		sizes := make(map[string]int)
+		for i, item := range items {
+			if canceled(ctx, i) {
+				return nil, ctx.Err()
+			}
+			sizes[item.property(property)]++
+		}
+		backing := make([]Item, len(items))
+		grouped = make(map[string][]Item, len(sizes))
+		offset := 0
+		for v, n := range sizes {
+			grouped[v] = backing[offset : offset : offset+n]
+			offset += n
+		}
+		for i, item := range items {
+			if canceled(ctx, i) {
+				return nil, ctx.Err()
+			}
+			v := item.property(property)
+			grouped[v] = append(grouped[v], item)
+		}
+	}
+
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.String("itemstore.group_by", property),
+			attribute.Int("itemstore.items", len(items)),
+			attribute.Int("itemstore.groups", len(grouped)),
+		)
+	}
+	return grouped, nil
+}
+
+// Sort sorts items in place by field ("id" or a property), breaking ties by
+// ID. IDs sort as ID.Compare orders them, so string IDs sort lexically.
+// Descending order reverses the result. Once ctx is done the remaining
+// comparisons treat every item as equal, so the sort ends soon, leaving the
+// items in no particular order, and Sort returns the context's error. A span
+// is recorded as for FilterContext.
+func Sort(ctx context.Context, items []Item, field string, descending bool) error {
+	_, span := startSpan(ctx, "itemstore.Sort")
+	defer span.End()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	compared, done := 0, false
+	slices.SortFunc(items, func(a, b Item) int {
+		if compared++; done || canceled(ctx, compared) {
+			done = true
+			return 0
+		}
+		if field != "id" {
+			if c := cmp.Compare(a.property(field), b.property(field)); c != 0 {
+				return c
+			}
+		}
+		return a.ID.Compare(b.ID)
+	})
+	if descending {
+		slices.Reverse(items)
+	}
+
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.String("itemstore.sort_by", field),
+			attribute.Bool("itemstore.descending", descending),
+			attribute.Int("itemstore.items", len(items)),
+		)
+	}
+	if done {
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package itemstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestGroupBy(t *testing.T) {
+	grouped, _ := GroupBy(context.Background(), testItems, "color")
+	if len(grouped) != 3 || len(grouped["red"]) != 2 || len(grouped["blue"]) != 1 {
+		t.Errorf("GroupBy(color) = %v", grouped)
+	}
+
+	grouped, _ = GroupBy(context.Background(), testItems, "size")
+	if len(grouped) != 1 || len(grouped["All"]) != len(testItems) {
+		t.Errorf("GroupBy(size) = %v, want every item under All", grouped)
+	}
+}
+
+func TestSort(t *testing.T) {
+	items := append([]Item(nil), testItems...)
+	Sort(context.Background(), items, "shape", false)
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.ID.String())
+	}
+	if want := []string{"1", "4", "2", "3"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Sort(shape) IDs = %v, want %v", ids, want)
+	}
+
+	Sort(context.Background(), items, "id", true)
+	if items[0].ID != IntID(4) || items[3].ID != IntID(1) {
+		t.Errorf("Sort(id, descending) = %v", items)
+	}
+}
+
+func TestSpans(t *testing.T) {
+	store, err := New(testItems)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	// Without a span in the context nothing is recorded
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	store.FilterContext(context.Background(), nil)
+	if got := exporter.GetSpans(); len(got) != 0 {
+		t.Fatalf("recorded %d spans without a parent, want 0", len(got))
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	items, _ := store.FilterContext(ctx, map[string]string{"color": "red"})
+	GroupBy(ctx, items, "shape")
+	Sort(ctx, items, "id", false)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 4 {
+		t.Fatalf("recorded %d spans, want 4", len(spans))
+	}
+	wantAttrs := map[string]attribute.KeyValue{
+		"itemstore.Filter":  attribute.Int("itemstore.items.matched", 2),
+		"itemstore.GroupBy": attribute.Int("itemstore.groups", 2),
+		"itemstore.Sort":    attribute.Int("itemstore.items", 2),
+	}
+	for _, span := range spans[:3] {
+		want, ok := wantAttrs[span.Name]
+		if !ok {
+			t.Errorf("unexpected span %q", span.Name)
+			continue
+		}
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %q is not a child of the request span", span.Name)
+		}
+		found := false
+		for _, attr := range span.Attributes {
+			if attr == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("span %q attributes = %v, want %v among them", span.Name, span.Attributes, want)
+		}
+	}
+}
+
+func TestGroupBy_Groups(t *testing.T) {
+	items := []Item{
+		{ID: IntID(1), Color: "red"}, {ID: IntID(2), Color: "blue"}, {ID: IntID(3), Color: "red"}, {ID: IntID(4), Color: "green"},
+	}
+	grouped, _ := GroupBy(t.Context(), items, "color")
+	if got := idsOf(grouped["red"]); !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Errorf("red group = %v, want [1 3] in input order", got)
+	}
+	// Groups share a backing array, so appending to one must not
+	// overwrite another
+	grouped["blue"] = append(grouped["blue"], Item{ID: IntID(99)})
+	for name, want := range map[string][]int{"red": {1, 3}, "green": {4}} {
+		if got := idsOf(grouped[name]); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s group = %v after appending to blue, want %v", name, got, want)
+		}
+	}
+	if got, _ := GroupBy(t.Context(), items, "size"); len(got) != 1 || len(got["All"]) != 4 {
+		t.Errorf("GroupBy(size) = %v, want every item in All", got)
+	}
+}
+
+func TestContext_Canceled(t *testing.T) {
+	items := Generate(200000, 1)
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := GroupBy(ctx, items, "color"); !errors.Is(err, context.Canceled) {
+		t.Errorf("GroupBy() with a canceled context error = %v, want context.Canceled", err)
+	}
+	if err := Sort(ctx, items, "color", false); !errors.Is(err, context.Canceled) {
+		t.Errorf("Sort() with a canceled context error = %v, want context.Canceled", err)
+	}
+
+	for _, opts := range [][]Option{nil, {WithIndexes("color")}, {WithBitmapIndex()}} {
+		store, err := New(items, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.FilterContext(ctx, map[string]string{"shape": "circle"}); !errors.Is(err, context.Canceled) {
+			t.Errorf("FilterContext() with a canceled context error = %v, want context.Canceled", err)
+		}
+
+		// Canceling mid-scan stops the scan at the next check rather
+		// than at the end of the items
+		for _, filters := range []map[string]string{nil, {"color": "red"}} {
+			ctx, cancel := context.WithCancel(t.Context())
+			n := 0
+			for range store.ItemsContext(ctx, filters) {
+				n++
+				cancel()
+			}
+			cancel()
+			if n == 0 || n > cancelCheckInterval {
+				t.Errorf("ItemsContext(%v) yielded %d items after being canceled at the first, want at most %d", filters, n, cancelCheckInterval)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package itemstore
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FieldError is one problem with one field of an item
+type FieldError struct {
+	// Field is the JSON name of the field, e.g. "color"
+	Field string `json:"field"`
+	// Value is the rejected value
+	Value string `json:"value"`
+	// Message says what is wrong with the value, e.g. "is required"
+	Message string `json:"message"`
+}
+
+// ValidationErrors lists every problem found with an item, in field order.
+// Extract it from a returned error with errors.As.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	problems := make([]string, len(e))
+	for i, fe := range e {
+		problems[i] = fe.Field + " " + fe.Message
+	}
+	return strings.Join(problems, "; ")
+}
+
+// err returns e as an error, or nil when there are no problems
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validate checks every field of the item and returns ValidationErrors
+// describing all of the problems, or nil if there are none
+func (i Item) Validate() error {
+	return i.fieldErrors().err()
+}
+
+// fieldErrors returns the problems Validate reports
+func (i Item) fieldErrors() ValidationErrors {
+	var errs ValidationErrors
+	if n, ok := i.ID.Int(); ok && n <= 0 {
+		errs = append(errs, FieldError{Field: "id", Value: strconv.Itoa(n), Message: "must be a positive number"})
+	} else if !ok && strings.TrimSpace(i.ID.String()) == "" {
+		errs = append(errs, FieldError{Field: "id", Value: i.ID.String(), Message: "must not be blank"})
+	}
+	for _, f := range []struct{ name, value string }{
+		{"color", i.Color},
+		{"shape", i.Shape},
+		{"category", i.Category},
+	} {
+		if strings.TrimSpace(f.value) == "" {
+			errs = append(errs, FieldError{Field: f.name, Value: f.value, Message: "is required"})
+		}
+	}
+	return errs
+}
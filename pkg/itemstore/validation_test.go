@@ -0,0 +1,62 @@
+package itemstore
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+)
+
+func TestItemValidate(t *testing.T) {
+	err := Item{ID: IntID(-2), Color: " ", Shape: "circle", Category: ""}.Validate()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("Validate() error = %v, want ValidationErrors", err)
+	}
+	want := ValidationErrors{
+		{Field: "id", Value: "-2", Message: "must be a positive number"},
+		{Field: "color", Value: " ", Message: "is required"},
+		{Field: "category", Value: "", Message: "is required"},
+	}
+	if !reflect.DeepEqual(verrs, want) {
+		t.Errorf("Validate() = %+v, want %+v", verrs, want)
+	}
+	if got, want := err.Error(), "id must be a positive number; color is required; category is required"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	if err := (Item{ID: IntID(1), Color: "red", Shape: "circle", Category: "A"}).Validate(); err != nil {
+		t.Errorf("Validate() of a valid item = %v, want nil", err)
+	}
+}
+
+func TestItemStore_ValidationErrors(t *testing.T) {
+	shapes := format.NewShapeRegistry()
+	store, err := New(nil, WithKnownShapes(shapes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Add(Item{Color: "", Shape: "octagon", Category: ""})
+	if !errors.Is(err, ErrInvalidItem) {
+		t.Fatalf("Add() error = %v, want %v", err, ErrInvalidItem)
+	}
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("Add() error = %v, want ValidationErrors", err)
+	}
+	var fields []string
+	for _, fe := range verrs {
+		fields = append(fields, fe.Field)
+	}
+	if want := []string{"color", "category", "shape"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("problems with %q, want %q", fields, want)
+	}
+
+	// Loading reports the index of the item at fault and keeps the details
+	_, err = New([]Item{{ID: IntID(1), Color: "red", Shape: "circle", Category: "A"}, {ID: IntID(2)}})
+	if !errors.As(err, &verrs) || len(verrs) != 3 {
+		t.Errorf("New() error = %v, want three problems", err)
+	}
+}
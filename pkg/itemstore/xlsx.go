@@ -0,0 +1,141 @@
+package itemstore
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xlsxSheetName is the name of the one worksheet WriteXLSX writes
+const xlsxSheetName = "Items"
+
+// xlsxParts are the fixed parts of the workbook: everything but the
+// worksheet, whose rows and filter range depend on the items
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`},
+	// Style 0 is the default and style 1 the bold header
+	{"xl/styles.xml", xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+		`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+		`</styleSheet>`},
+}
+
+// WriteXLSX writes items to w as an Excel workbook with one worksheet: a
+// bold header row with an auto-filter, then one row per item with the same
+// columns as WriteCSV. IDs are number cells and everything else text, with
+// creation times in RFC 3339.
+func WriteXLSX(w io.Writer, items []Item) error {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		if err := writeZipPart(zw, part.name, part.content); err != nil {
+			return err
+		}
+	}
+
+	// The filter range covers the header and every item
+	lastCell := fmt.Sprintf("%s%d", xlsxColumn(len(csvHeader)-1), len(items)+1)
+	workbook := xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + xlsxSheetName + `" sheetId="1" r:id="rId1"/></sheets>` +
+		`<definedNames><definedName name="_xlnm._FilterDatabase" localSheetId="0" hidden="1">` +
+		xlsxSheetName + `!$A$1:$` + xlsxColumn(len(csvHeader)-1) + `$` + strconv.Itoa(len(items)+1) +
+		`</definedName></definedNames></workbook>`
+	if err := writeZipPart(zw, "xl/workbook.xml", workbook); err != nil {
+		return err
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return fmt.Errorf("write xl/worksheets/sheet1.xml: %w", err)
+	}
+	bw := bufio.NewWriter(sheet)
+	bw.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	// The header row stays in view while scrolling
+	bw.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	bw.WriteString(`<sheetData>`)
+	bw.WriteString(`<row r="1">`)
+	for i, name := range csvHeader {
+		writeXLSXString(bw, i, 1, name, 1)
+	}
+	bw.WriteString(`</row>`)
+	for i, item := range items {
+		row := i + 2
+		fmt.Fprintf(bw, `<row r="%d">`, row)
+		if id, ok := item.ID.Int(); ok {
+			fmt.Fprintf(bw, `<c r="A%d"><v>%d</v></c>`, row, id)
+		} else {
+			writeXLSXString(bw, 0, row, item.ID.String(), 0)
+		}
+		writeXLSXString(bw, 1, row, item.Color, 0)
+		writeXLSXString(bw, 2, row, item.Shape, 0)
+		writeXLSXString(bw, 3, row, item.Category, 0)
+		if !item.CreatedAt.IsZero() {
+			writeXLSXString(bw, 4, row, item.CreatedAt.Format(time.RFC3339), 0)
+		}
+		bw.WriteString(`</row>`)
+	}
+	bw.WriteString(`</sheetData>`)
+	fmt.Fprintf(bw, `<autoFilter ref="A1:%s"/>`, lastCell)
+	bw.WriteString(`</worksheet>`)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("write xl/worksheets/sheet1.xml: %w", err)
+	}
+	return zw.Close()
+}
+
+// writeZipPart adds a part with the given content to zw
+func writeZipPart(zw *zip.Writer, name, content string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	if _, err := io.WriteString(f, content); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// writeXLSXString writes an inline string cell in the given style
+func writeXLSXString(w *bufio.Writer, column, row int, value string, style int) {
+	fmt.Fprintf(w, `<c r="%s%d" t="inlineStr"`, xlsxColumn(column), row)
+	if style != 0 {
+		fmt.Fprintf(w, ` s="%d"`, style)
+	}
+	w.WriteString(`><is><t xml:space="preserve">`)
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(value))
+	w.WriteString(escaped.String())
+	w.WriteString(`</t></is></c>`)
+}
+
+// xlsxColumn returns the letters naming the zero-based column i, e.g. A for
+// 0 and AA for 26
+func xlsxColumn(i int) string {
+	var name []byte
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = append([]byte{byte('A' + (i-1)%26)}, name...)
+	}
+	return string(name)
+}
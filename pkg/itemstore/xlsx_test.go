@@ -0,0 +1,139 @@
+package itemstore
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// xlsxSheet is the part of a worksheet the tests read back
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Style  string `xml:"s,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+	AutoFilter struct {
+		Ref string `xml:"ref,attr"`
+	} `xml:"autoFilter"`
+}
+
+// readXLSXSheet unzips a workbook and parses its worksheet, checking every
+// part is well-formed XML
+func readXLSXSheet(t *testing.T, data []byte) xlsxSheet {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("not a zip file: %v", err)
+	}
+	var sheet xlsxSheet
+	var found bool
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dec := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed: %v", f.Name, err)
+			}
+		}
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			found = true
+			if err := xml.Unmarshal(content, &sheet); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("workbook has no xl/worksheets/sheet1.xml")
+	}
+	return sheet
+}
+
+func TestWriteXLSX(t *testing.T) {
+	items := []Item{
+		{ID: IntID(1), Color: "red", Shape: "circle", Category: "A & <B>"},
+		{ID: IntID(12), Color: "blue", Shape: "square", Category: "C", CreatedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, items); err != nil {
+		t.Fatal(err)
+	}
+	sheet := readXLSXSheet(t, buf.Bytes())
+
+	if len(sheet.Rows) != 3 {
+		t.Fatalf("got %d rows, want a header and 2 items", len(sheet.Rows))
+	}
+	var header []string
+	for _, c := range sheet.Rows[0].Cells {
+		header = append(header, c.Inline)
+		if c.Style != "1" {
+			t.Errorf("header cell %s has style %q, want the bold style 1", c.Ref, c.Style)
+		}
+	}
+	if !slices.Equal(header, csvHeader) {
+		t.Errorf("header = %q, want %q", header, csvHeader)
+	}
+	if sheet.AutoFilter.Ref != "A1:E3" {
+		t.Errorf("autoFilter ref = %q, want A1:E3", sheet.AutoFilter.Ref)
+	}
+
+	// Read the items back from the cells
+	for i, row := range sheet.Rows[1:] {
+		if row.R != i+2 {
+			t.Errorf("row %d is numbered %d", i+2, row.R)
+		}
+		id := row.Cells[0]
+		if id.Type != "" {
+			t.Errorf("ID cell %s has type %q, want a number", id.Ref, id.Type)
+		}
+		var got Item
+		n, _ := strconv.Atoi(id.Value)
+		got.ID = IntID(n)
+		got.Color, got.Shape, got.Category = row.Cells[1].Inline, row.Cells[2].Inline, row.Cells[3].Inline
+		if len(row.Cells) > 4 {
+			got.CreatedAt, _ = time.Parse(time.RFC3339, row.Cells[4].Inline)
+		}
+		if got != items[i] {
+			t.Errorf("row %d = %+v, want %+v", row.R, got, items[i])
+		}
+	}
+}
+
+func TestWriteXLSX_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	sheet := readXLSXSheet(t, buf.Bytes())
+	if len(sheet.Rows) != 1 || sheet.AutoFilter.Ref != "A1:E1" {
+		t.Errorf("got %d rows and filter %q, want only the header", len(sheet.Rows), sheet.AutoFilter.Ref)
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 4: "E", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(i); got != want {
+			t.Errorf("xlsxColumn(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Package logfile is a log file that rotates itself once it reaches a size
+// limit, keeping a bounded number of timestamped backups, and that can be
+// reopened after an external tool such as logrotate moves it away.
+package logfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat names backups so that they sort oldest first
+const backupTimeFormat = "20060102T150405.000000000"
+
+// File is an io.Writer appending to a log file. It is safe for concurrent
+// use; each Write goes to the file whole, never split across a rotation.
+type File struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	// now is the clock backups are named with
+	now func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open opens or creates the log file at path for appending. Once a write
+// would take it past maxSize bytes, the file is renamed to a backup named
+// after it and the time, e.g. dashboard-20260102T150405.000000000.log, and a
+// new file is started. Only the newest maxBackups backups are kept. A
+// maxSize of 0 never rotates, and a maxBackups of 0 keeps every backup.
+func Open(path string, maxSize int64, maxBackups int) (*File, error) {
+	if maxSize < 0 || maxBackups < 0 {
+		return nil, errors.New("logfile: negative size or backup count")
+	}
+	f := &File{path: path, maxSize: maxSize, maxBackups: maxBackups, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the file at f.path, which the caller holds f.mu for or has not
+// shared f yet
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("open log file: %w", err)
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// Write appends p to the file, rotating it first if p would take it past
+// the size limit. A p larger than the limit still goes to a file of its own.
+// A failed rotation is reported, but p is still written if a file is open.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	var rotateErr error
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		rotateErr = f.rotate()
+		if f.file == nil {
+			return 0, rotateErr
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return n, err
+}
+
+// Reopen closes the file and opens whatever is at its path now, creating it
+// if it was moved away. It is how the file cooperates with external rotation.
+func (f *File) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	return f.open()
+}
+
+// Close closes the file; later writes fail
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// rotate renames the file to a new backup, starts a new file, and prunes the
+// oldest backups. The caller holds f.mu.
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	// Backups made within the clock's resolution would share a name
+	now := f.now()
+	backup := f.backupName(now)
+	for fileExists(backup) {
+		now = now.Add(time.Nanosecond)
+		backup = f.backupName(now)
+	}
+	if err := os.Rename(f.path, backup); err != nil {
+		// Keep logging to the oversized file rather than losing lines
+		if openErr := f.open(); openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("rotate log file: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	return f.prune()
+}
+
+// backupName is the name of the backup made at t
+func (f *File) backupName(t time.Time) string {
+	ext := filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext) + "-" + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// Backups returns the paths of the file's backups, oldest first
+func (f *File) Backups() ([]string, error) {
+	ext := filepath.Ext(f.path)
+	prefix := filepath.Base(strings.TrimSuffix(f.path, ext)) + "-"
+	entries, err := os.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		stamp, ok := strings.CutPrefix(name, prefix)
+		if !ok || !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, strings.TrimSuffix(stamp, ext)); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(filepath.Dir(f.path), name))
+	}
+	slices.Sort(backups)
+	return backups, nil
+}
+
+// prune removes all but the newest maxBackups backups
+func (f *File) prune() error {
+	if f.maxBackups == 0 {
+		return nil
+	}
+	backups, err := f.Backups()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for len(backups) > f.maxBackups {
+		if err := os.Remove(backups[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+		backups = backups[1:]
+	}
+	return errors.Join(errs...)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -0,0 +1,150 @@
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFile_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dashboard.log")
+	f, err := Open(path, 20, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	clock := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	f.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	// Each line fills the file, so every line after the first rotates
+	for i := range 6 {
+		if _, err := fmt.Fprintf(f, "line %d: 0123456\n", i); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	backups, err := f.Backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "dashboard-20260102T150409.000000000.log"),
+		filepath.Join(dir, "dashboard-20260102T150410.000000000.log"),
+	}
+	if fmt.Sprint(backups) != fmt.Sprint(want) {
+		t.Fatalf("Backups() = %v, want the newest two %v", backups, want)
+	}
+	for i, path := range append(backups, path) {
+		data, _ := os.ReadFile(path)
+		if got, want := string(data), fmt.Sprintf("line %d: 0123456\n", i+3); got != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(path), got, want)
+		}
+	}
+}
+
+func TestFile_OversizedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dashboard.log")
+	f, err := Open(path, 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	long := strings.Repeat("x", 10)
+	for range 3 {
+		if n, err := f.Write([]byte(long)); n != len(long) || err != nil {
+			t.Fatalf("Write() = %d, %v; want the whole line written", n, err)
+		}
+	}
+	// No limit on the number of backups keeps them all
+	if backups, _ := f.Backups(); len(backups) != 2 {
+		t.Errorf("Backups() = %v, want 2", backups)
+	}
+	if data, _ := os.ReadFile(path); string(data) != long {
+		t.Errorf("log file = %q, want the last line alone", data)
+	}
+}
+
+func TestFile_ConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dashboard.log")
+	f, err := Open(path, 200, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	const writers, lines = 8, 50
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Go(func() {
+			for i := range lines {
+				fmt.Fprintf(f, "writer %d line %02d\n", w, i)
+			}
+		})
+	}
+	wg.Wait()
+
+	backups, err := f.Backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := 0
+	for _, path := range append(backups, path) {
+		data, _ := os.ReadFile(path)
+		if len(data) > 200 {
+			t.Errorf("%s holds %d bytes, over the limit", filepath.Base(path), len(data))
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			var w, i int
+			if _, err := fmt.Sscanf(line, "writer %d line %d", &w, &i); err != nil {
+				t.Errorf("%s has a garbled line %q", filepath.Base(path), line)
+			}
+			seen++
+		}
+	}
+	if seen != writers*lines {
+		t.Errorf("found %d lines, want %d", seen, writers*lines)
+	}
+}
+
+func TestFile_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dashboard.log")
+	f, err := Open(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("before\n"))
+	// An external tool moves the file away, then signals the server
+	moved := filepath.Join(dir, "dashboard.log.1")
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("still the old file\n"))
+	if err := f.Reopen(); err != nil {
+		t.Fatalf("Reopen() error = %v", err)
+	}
+	f.Write([]byte("after\n"))
+
+	if data, _ := os.ReadFile(moved); string(data) != "before\nstill the old file\n" {
+		t.Errorf("moved file = %q", data)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "after\n" {
+		t.Errorf("reopened file = %q, want only the later line", data)
+	}
+
+	f.Close()
+	if _, err := f.Write([]byte("closed\n")); err == nil {
+		t.Error("Write() after Close succeeded")
+	}
+}
@@ -0,0 +1,216 @@
+// Package openapi builds OpenAPI 3 documents, deriving JSON schemas from Go
+// types so that the document follows the structs the API actually encodes.
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Version is the OpenAPI specification version produced
+const Version = "3.0.3"
+
+// Document is the root of an OpenAPI document
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Servers    []Server                        `json:"servers,omitempty"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server is a base URL the API is served from
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Operation describes one method on one path
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Explode     *bool   `json:"explode,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes an operation's request payload
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes one possible response
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header describes a response header
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType holds the schema for one content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds reusable schemas and security schemes
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+
+	names map[reflect.Type]string
+}
+
+// SecurityScheme describes how requests authenticate
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+// Schema is the subset of JSON Schema used by OpenAPI 3.0
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Register adds a named schema derived from v's type. Later schemas that
+// contain that type refer to it by name instead of repeating it.
+func (c *Components) Register(name string, v any) {
+	t := reflect.TypeOf(v)
+	if c.Schemas == nil {
+		c.Schemas = make(map[string]*Schema)
+		c.names = make(map[reflect.Type]string)
+	}
+	// Build before recording the name so the component itself is inlined
+	c.Schemas[name] = c.schemaFor(t)
+	c.names[t] = name
+}
+
+// RegisterSchema adds s as the named schema of v's type, for types whose
+// JSON does not follow their fields, such as those with a MarshalJSON method.
+// Register it before the schemas that contain the type.
+func (c *Components) RegisterSchema(name string, v any, s *Schema) {
+	if c.Schemas == nil {
+		c.Schemas = make(map[string]*Schema)
+		c.names = make(map[reflect.Type]string)
+	}
+	c.Schemas[name] = s
+	c.names[reflect.TypeOf(v)] = name
+}
+
+// Schema returns the schema for v's type, using references to registered
+// components where possible
+func (c *Components) Schema(v any) *Schema {
+	return c.schemaFor(reflect.TypeOf(v))
+}
+
+// Ref returns a reference to the registered component name
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func (c *Components) schemaFor(t reflect.Type) *Schema {
+	if name, ok := c.names[t]; ok {
+		return Ref(name)
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := c.schemaFor(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: c.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: c.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		c.addFields(s, t)
+		return s
+	default:
+		// Interfaces and anything else accept any JSON value
+		return &Schema{}
+	}
+}
+
+// addFields adds t's exported fields to s following encoding/json's rules for
+// names, omitempty, and embedded structs. Pointer fields are optional.
+func (c *Components) addFields(s *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		// Embedded structs promote their fields even when the type itself is
+		// unexported
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			c.addFields(s, f.Type)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		s.Properties[name] = c.schemaFor(f.Type)
+		optional := f.Type.Kind() == reflect.Pointer || strings.Contains(opts, "omitempty") || strings.Contains(opts, "omitzero")
+		if !optional {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
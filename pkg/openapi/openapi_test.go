@@ -0,0 +1,82 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Name string `json:"name"`
+}
+
+type sample struct {
+	ID       int            `json:"id"`
+	Tags     []string       `json:"tags,omitempty"`
+	Counts   map[string]int `json:"counts"`
+	Optional *string        `json:"optional"`
+	When     time.Time      `json:"when"`
+	Child    inner          `json:"child"`
+	Skipped  string         `json:"-"`
+	hidden   string
+	Any      any               `json:"any,omitempty"`
+	Nested   map[string][]bool `json:"nested,omitempty"`
+	inner
+}
+
+func TestComponents_Schema(t *testing.T) {
+	var c Components
+	c.Register("Inner", inner{})
+	s := c.Schema(sample{})
+
+	if s.Type != "object" {
+		t.Fatalf("Type = %q, want object", s.Type)
+	}
+	want := map[string]*Schema{
+		"id":       {Type: "integer", Format: "int32"},
+		"tags":     {Type: "array", Items: &Schema{Type: "string"}},
+		"counts":   {Type: "object", AdditionalProperties: &Schema{Type: "integer", Format: "int32"}},
+		"optional": {Type: "string", Nullable: true},
+		"when":     {Type: "string", Format: "date-time"},
+		"child":    Ref("Inner"),
+		"any":      {},
+		"nested":   {Type: "object", AdditionalProperties: &Schema{Type: "array", Items: &Schema{Type: "boolean"}}},
+		"name":     {Type: "string"},
+	}
+	if !reflect.DeepEqual(s.Properties, want) {
+		for name, got := range s.Properties {
+			if !reflect.DeepEqual(got, want[name]) {
+				t.Errorf("property %q = %+v, want %+v", name, got, want[name])
+			}
+		}
+		if len(s.Properties) != len(want) {
+			t.Errorf("got %d properties, want %d", len(s.Properties), len(want))
+		}
+	}
+
+	wantRequired := []string{"id", "counts", "when", "child", "name"}
+	if !reflect.DeepEqual(s.Required, wantRequired) {
+		t.Errorf("Required = %v, want %v", s.Required, wantRequired)
+	}
+
+	if got := c.Schemas["Inner"]; got.Type != "object" || got.Properties["name"].Type != "string" {
+		t.Errorf("registered Inner schema = %+v", got)
+	}
+}
+
+// opaque has no fields to derive its JSON from, as if it marshaled itself
+type opaque struct{ n int }
+
+func TestComponents_RegisterSchema(t *testing.T) {
+	var c Components
+	c.RegisterSchema("Opaque", opaque{}, &Schema{Type: "string"})
+	s := c.Schema(struct {
+		Value opaque `json:"value"`
+	}{})
+	if got := s.Properties["value"]; !reflect.DeepEqual(got, Ref("Opaque")) {
+		t.Errorf("property value = %+v, want a reference to Opaque", got)
+	}
+	if got := c.Schemas["Opaque"]; got.Type != "string" {
+		t.Errorf("registered Opaque schema = %+v, want the given one", got)
+	}
+}
@@ -0,0 +1,150 @@
+// Package palette maps color names to hex values.
+package palette
+
+import (
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"math"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+)
+
+// defaults are the hex values of common CSS color names
+var defaults = map[string]string{
+	"black":   "#000000",
+	"blue":    "#0000ff",
+	"brown":   "#a52a2a",
+	"cyan":    "#00ffff",
+	"gold":    "#ffd700",
+	"gray":    "#808080",
+	"green":   "#008000",
+	"indigo":  "#4b0082",
+	"lime":    "#00ff00",
+	"magenta": "#ff00ff",
+	"maroon":  "#800000",
+	"navy":    "#000080",
+	"olive":   "#808000",
+	"orange":  "#ffa500",
+	"pink":    "#ffc0cb",
+	"purple":  "#800080",
+	"red":     "#ff0000",
+	"silver":  "#c0c0c0",
+	"teal":    "#008080",
+	"violet":  "#ee82ee",
+	"white":   "#ffffff",
+	"yellow":  "#ffff00",
+}
+
+// Palette maps color names to hex values. It is read-only once created and
+// safe for concurrent use.
+type Palette struct {
+	colors map[string]string
+}
+
+// New creates a palette of the common CSS color names, with overrides
+// replacing or adding to them. Override values may use any form ParseHex
+// accepts.
+func New(overrides map[string]string) (*Palette, error) {
+	colors := maps.Clone(defaults)
+	for name, value := range overrides {
+		key := normalize(name)
+		if key == "" {
+			return nil, fmt.Errorf("palette: empty color name")
+		}
+		hex, err := ParseHex(value)
+		if err != nil {
+			return nil, fmt.Errorf("palette: %s: %w", name, err)
+		}
+		colors[key] = hex
+	}
+	return &Palette{colors: colors}, nil
+}
+
+// ParseHex validates a "#rgb" or "#rrggbb" color and returns it in the
+// lower-case "#rrggbb" form
+func ParseHex(s string) (string, error) {
+	digits, ok := strings.CutPrefix(strings.ToLower(s), "#")
+	if !ok || (len(digits) != 3 && len(digits) != 6) || strings.Trim(digits, "0123456789abcdef") != "" {
+		return "", fmt.Errorf("%q is not a #rgb or #rrggbb color", s)
+	}
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	return "#" + digits, nil
+}
+
+// Colors returns a copy of every named color in the palette
+func (p *Palette) Colors() map[string]string {
+	return maps.Clone(p.colors)
+}
+
+// Hex returns the hex value of the named color. Names are matched without
+// regard to case or surrounding space; unknown names get Fallback.
+func (p *Palette) Hex(name string) string {
+	if hex, ok := p.colors[normalize(name)]; ok {
+		return hex
+	}
+	return Fallback(name)
+}
+
+// normalize is the form color names are looked up in, the canonical form
+// items store colors in
+func normalize(name string) string {
+	return format.ColorKey(strings.ToLower(name))
+}
+
+// Fallback derives a color for name by hashing it into the HSL space, so a
+// name gets the same color in every run. Saturation and lightness stay in a
+// range that reads on both light and dark backgrounds.
+func Fallback(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(normalize(name)))
+	sum := h.Sum32()
+	hue := float64(sum % 360)
+	saturation := 0.55 + float64(sum/360%20)/100
+	lightness := 0.45 + float64(sum/7200%15)/100
+	return hslToHex(hue, saturation, lightness)
+}
+
+// seriesColors are distinguishable colors for chart series that have no color
+// of their own
+var seriesColors = []string{
+	"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948",
+	"#b07aa1", "#ff9da7", "#9c755f", "#bab0ac", "#86bcb6", "#d37295",
+}
+
+// Series picks a color for a chart series from a fixed set, choosing by a
+// hash of key so that a key keeps its color from one chart to the next
+func Series(key string) string {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return seriesColors[h.Sum32()%uint32(len(seriesColors))]
+}
+
+// hslToHex converts a hue in degrees and saturation and lightness in [0, 1]
+// to a "#rrggbb" string
+func hslToHex(hue, saturation, lightness float64) string {
+	c := (1 - math.Abs(2*lightness-1)) * saturation
+	x := c * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+	m := lightness - c/2
+
+	var r, g, b float64
+	switch {
+	case hue < 60:
+		r, g, b = c, x, 0
+	case hue < 120:
+		r, g, b = x, c, 0
+	case hue < 180:
+		r, g, b = 0, c, x
+	case hue < 240:
+		r, g, b = 0, x, c
+	case hue < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	channel := func(v float64) int { return int(math.Round((v + m) * 255)) }
+	return fmt.Sprintf("#%02x%02x%02x", channel(r), channel(g), channel(b))
+}
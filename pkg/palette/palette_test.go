@@ -0,0 +1,126 @@
+package palette
+
+import (
+	"regexp"
+	"testing"
+)
+
+var hexPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+func mustNew(t *testing.T, overrides map[string]string) *Palette {
+	t.Helper()
+	p, err := New(overrides)
+	if err != nil {
+		t.Fatalf("New(%v) error = %v", overrides, err)
+	}
+	return p
+}
+
+func TestHex_KnownNames(t *testing.T) {
+	p := mustNew(t, nil)
+	tests := map[string]string{
+		"blue":    "#0000ff",
+		"red":     "#ff0000",
+		"green":   "#008000",
+		" Blue ":  "#0000ff",
+		"MAGENTA": "#ff00ff",
+	}
+	for name, want := range tests {
+		if got := p.Hex(name); got != want {
+			t.Errorf("Hex(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestHex_UnknownNamesUseFallback(t *testing.T) {
+	p := mustNew(t, nil)
+	for _, name := range []string{"chartreuse-ish", "sunset", ""} {
+		got := p.Hex(name)
+		if got != Fallback(name) {
+			t.Errorf("Hex(%q) = %q, want the fallback %q", name, got, Fallback(name))
+		}
+		if !hexPattern.MatchString(got) {
+			t.Errorf("Hex(%q) = %q, want a #rrggbb value", name, got)
+		}
+	}
+}
+
+func TestFallback_Deterministic(t *testing.T) {
+	if got, again := Fallback("sunset"), Fallback("sunset"); got != again {
+		t.Fatalf("Fallback(sunset) = %q, then %q", got, again)
+	}
+	if Fallback("Sunset") != Fallback("sunset") {
+		t.Error("Fallback depends on case")
+	}
+	if Fallback("sunset") == Fallback("seafoam") {
+		t.Error("different names got the same fallback")
+	}
+}
+
+func TestHSLToHex(t *testing.T) {
+	tests := []struct {
+		h, s, l float64
+		want    string
+	}{
+		{0, 1, 0.5, "#ff0000"},
+		{120, 1, 0.5, "#00ff00"},
+		{240, 1, 0.5, "#0000ff"},
+		{0, 0, 0, "#000000"},
+		{0, 0, 1, "#ffffff"},
+		{60, 1, 0.25, "#808000"},
+	}
+	for _, tt := range tests {
+		if got := hslToHex(tt.h, tt.s, tt.l); got != tt.want {
+			t.Errorf("hslToHex(%v, %v, %v) = %q, want %q", tt.h, tt.s, tt.l, got, tt.want)
+		}
+	}
+}
+
+func TestSeries(t *testing.T) {
+	if Series("shape:circle") != Series("shape:circle") {
+		t.Error("Series is not deterministic")
+	}
+	for _, key := range []string{"a", "b", "shape:square"} {
+		if !hexPattern.MatchString(Series(key)) {
+			t.Errorf("Series(%q) = %q, want a #rrggbb value", key, Series(key))
+		}
+	}
+}
+
+func TestNew_Overrides(t *testing.T) {
+	p := mustNew(t, map[string]string{"Red": "#E53935", "brand": "#0af"})
+
+	tests := map[string]string{
+		"red":   "#e53935",
+		"brand": "#00aaff",
+		"blue":  "#0000ff",
+	}
+	for name, want := range tests {
+		if got := p.Hex(name); got != want {
+			t.Errorf("Hex(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if colors := p.Colors(); colors["brand"] != "#00aaff" || colors["red"] != "#e53935" || colors["green"] != "#008000" {
+		t.Errorf("Colors() = %v, want the defaults with the overrides applied", colors)
+	}
+
+	// Overrides never leak into other palettes
+	if got := mustNew(t, nil).Hex("red"); got != "#ff0000" {
+		t.Errorf("Hex(red) in a new palette = %q, want #ff0000", got)
+	}
+}
+
+func TestNew_InvalidOverrides(t *testing.T) {
+	for _, overrides := range []map[string]string{
+		{"red": "ff0000"},
+		{"red": "#ff00"},
+		{"red": "#gggggg"},
+		{"red": "red"},
+		{"red": "#ff0000;background:url(x)"},
+		{" ": "#ff0000"},
+	} {
+		if _, err := New(overrides); err == nil {
+			t.Errorf("New(%v) succeeded, want an error", overrides)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package preset stores named filter presets: query strings of the items
+// page saved under a name so a team can re-apply them with one click.
+package preset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxNameLength is the longest name a preset may have
+const MaxNameLength = 40
+
+var (
+	// ErrInvalidName is returned for names that are empty, too long, or use
+	// characters other than letters, digits, "-", and "_"
+	ErrInvalidName = errors.New("invalid preset name")
+	// ErrExists is returned by Create when the name is taken
+	ErrExists = errors.New("preset already exists")
+	// ErrNotFound is returned for names no preset has
+	ErrNotFound = errors.New("preset not found")
+)
+
+// namePattern matches valid names, which start with a letter or digit so
+// they read well in URLs
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ValidateName returns an error wrapping ErrInvalidName explaining what is
+// wrong with name, or nil
+func ValidateName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("%w: the name is empty", ErrInvalidName)
+	case len(name) > MaxNameLength:
+		return fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidName, name, MaxNameLength)
+	case !namePattern.MatchString(name):
+		return fmt.Errorf("%w: %q must start with a letter or digit and contain only letters, digits, \"-\", and \"_\"", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// Preset is one saved query
+type Preset struct {
+	Name string `json:"name"`
+	// Title is shown above the items when the preset is opened as a view;
+	// empty means the name
+	Title string `json:"title,omitempty"`
+	// Query is the items page query string the preset applies, without a
+	// leading "?"
+	Query     string    `json:"query"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Store holds presets in memory and, when given a file, saves them there
+// after every change so they survive restarts. It is safe for concurrent use.
+type Store struct {
+	mu      sync.Mutex
+	presets map[string]Preset
+	path    string
+	now     func() time.Time
+}
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithClock replaces time.Now, which lets tests pin creation times
+func WithClock(now func() time.Time) Option {
+	return func(s *Store) { s.now = now }
+}
+
+// WithFile persists the presets as JSON at path. Presets already saved there
+// are loaded by New.
+func WithFile(path string) Option {
+	return func(s *Store) { s.path = path }
+}
+
+// New creates a Store, loading any presets from its file
+func New(opts ...Option) (*Store, error) {
+	s := &Store{presets: make(map[string]Preset), now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read presets: %w", err)
+	}
+	var presets []Preset
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, fmt.Errorf("parse presets in %s: %w", s.path, err)
+	}
+	for _, p := range presets {
+		if err := ValidateName(p.Name); err != nil {
+			return nil, fmt.Errorf("presets in %s: %w", s.path, err)
+		}
+		s.presets[p.Name] = p
+	}
+	return s, nil
+}
+
+// Create saves query and its title under name, which must be valid and not
+// taken
+func (s *Store) Create(name, title, query string) (Preset, error) {
+	if err := ValidateName(name); err != nil {
+		return Preset{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, taken := s.presets[name]; taken {
+		return Preset{}, fmt.Errorf("%w: %s", ErrExists, name)
+	}
+	p := Preset{Name: name, Title: title, Query: strings.TrimPrefix(query, "?"), CreatedAt: s.now().UTC()}
+	s.presets[name] = p
+	if err := s.saveLocked(); err != nil {
+		delete(s.presets, name)
+		return Preset{}, err
+	}
+	return p, nil
+}
+
+// Get returns the preset called name
+func (s *Store) Get(name string) (Preset, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.presets[name]
+	return p, ok
+}
+
+// List returns every preset sorted by name
+func (s *Store) List() []Preset {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listLocked()
+}
+
+// Delete removes the preset called name
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.presets[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	delete(s.presets, name)
+	if err := s.saveLocked(); err != nil {
+		s.presets[name] = p
+		return err
+	}
+	return nil
+}
+
+// listLocked returns the presets sorted by name. The caller must hold the
+// lock.
+func (s *Store) listLocked() []Preset {
+	presets := make([]Preset, 0, len(s.presets))
+	for _, p := range s.presets {
+		presets = append(presets, p)
+	}
+	slices.SortFunc(presets, func(a, b Preset) int { return strings.Compare(a.Name, b.Name) })
+	return presets
+}
+
+// saveLocked writes the presets to the store's file, if it has one,
+// replacing it atomically so a crash never leaves a truncated file behind
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.listLocked())
+	if err != nil {
+		return fmt.Errorf("encode presets: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("save presets: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("save presets: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("save presets: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("save presets: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package preset
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore_CRUD(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	s, err := New(WithClock(func() time.Time { return created }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := s.Create("red-things", "Red things", "?filter=color:red")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if want := (Preset{Name: "red-things", Title: "Red things", Query: "filter=color:red", CreatedAt: created}); p != want {
+		t.Errorf("Create() = %+v, want %+v", p, want)
+	}
+	if _, err := s.Create("red-things", "", "groupBy=color"); !errors.Is(err, ErrExists) {
+		t.Errorf("Create() of a taken name error = %v, want ErrExists", err)
+	}
+	if _, err := s.Create("a_by_shape", "", "groupBy=shape"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := names(s.List()); !reflect.DeepEqual(got, []string{"a_by_shape", "red-things"}) {
+		t.Errorf("List() = %q, want sorted by name", got)
+	}
+	if got, ok := s.Get("red-things"); !ok || got != p {
+		t.Errorf("Get() = %+v, %v", got, ok)
+	}
+
+	if err := s.Delete("red-things"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := s.Delete("red-things"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete() error = %v, want ErrNotFound", err)
+	}
+	if _, ok := s.Get("red-things"); ok {
+		t.Error("deleted preset still found")
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"a", "Team-1", "by_shape", strings.Repeat("x", MaxNameLength)} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "-lead", "_lead", "has space", "slash/ed", "ünï", strings.Repeat("x", MaxNameLength+1)} {
+		if err := ValidateName(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("ValidateName(%q) = %v, want ErrInvalidName", name, err)
+		}
+	}
+}
+
+func TestStore_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.presets.json")
+	s, err := New(WithFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Create("reds", "Reds", "filter=color:red")
+	s.Create("blues", "", "filter=color:blue")
+	s.Delete("blues")
+
+	reloaded, err := New(WithFile(path))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got, want := reloaded.List(), s.List(); !reflect.DeepEqual(got, want) {
+		t.Errorf("reloaded presets = %+v, want %+v", got, want)
+	}
+
+	os.WriteFile(path, []byte(`[{"name":"bad name","query":""}]`), 0o644)
+	if _, err := New(WithFile(path)); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("New() with an invalid saved name error = %v", err)
+	}
+}
+
+func names(presets []Preset) []string {
+	var names []string
+	for _, p := range presets {
+		names = append(names, p.Name)
+	}
+	return names
+}
@@ -0,0 +1,498 @@
+// Package qrcode encodes data as QR codes (ISO/IEC 18004). Data is encoded
+// in byte mode with error correction level M, which recovers about 15% of
+// the codewords, in the smallest version that holds it.
+package qrcode
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"slices"
+)
+
+// QuietZone is the width in modules of the light border a reader needs
+// around the symbol, which Image draws
+const QuietZone = 4
+
+// ErrTooLong is returned by Encode for data that does not fit in the
+// largest QR code, version 40
+var ErrTooLong = errors.New("qrcode: data too long")
+
+// Level M tables, indexed by version; index 0 is unused
+var (
+	// eccPerBlock is the number of error correction codewords of each block
+	eccPerBlock = [41]int{0,
+		10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26,
+		26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28}
+	// numBlocks is the number of blocks the codewords are split into
+	numBlocks = [41]int{0,
+		1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16,
+		17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49}
+)
+
+// formatLevelM is the two error correction level bits of the format
+// information for level M
+const formatLevelM = 0b00
+
+// Code is an encoded QR code: a square of dark and light modules
+type Code struct {
+	// Version is the QR version, 1 to 40, which sets the size
+	Version int
+	// Size is the number of modules along each side, without the quiet zone
+	Size int
+	dark []bool
+	// function marks the modules of the finder, timing, alignment, format,
+	// and version patterns, which carry no data and are never masked
+	function []bool
+}
+
+// Dark reports whether the module in column x and row y is dark
+func (c *Code) Dark(x, y int) bool {
+	return c.dark[y*c.Size+x]
+}
+
+// Encode returns the QR code of data
+func Encode(data []byte) (*Code, error) {
+	version := 1
+	for ; version <= 40; version++ {
+		if 4+countBits(version)+8*len(data) <= 8*dataCodewords(version) {
+			break
+		}
+	}
+	if version > 40 {
+		return nil, ErrTooLong
+	}
+
+	c := &Code{Version: version, Size: 4*version + 17}
+	c.dark = make([]bool, c.Size*c.Size)
+	c.function = make([]bool, c.Size*c.Size)
+	c.drawFunctionPatterns()
+	c.drawCodewords(addECC(encodeData(data, version), version))
+
+	// Use the mask whose symbol is easiest to read
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		// Masking twice undoes it
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+	return c, nil
+}
+
+// Image draws c with its quiet zone as a size×size image, each module a
+// square of whole pixels centered in it. A size too small for one pixel per
+// module is raised to that.
+func (c *Code) Image(size int) *image.Paletted {
+	modules := c.Size + 2*QuietZone
+	size = max(size, modules)
+	scale := size / modules
+	offset := (size-scale*modules)/2 + QuietZone*scale
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y := range c.Size {
+		for x := range c.Size {
+			if !c.Dark(x, y) {
+				continue
+			}
+			for py := range scale {
+				row := img.Pix[(offset+y*scale+py)*img.Stride:]
+				for px := range scale {
+					row[offset+x*scale+px] = 1
+				}
+			}
+		}
+	}
+	return img
+}
+
+// countBits is the width of the byte mode character count
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// rawModules is the number of modules of a version left for data and error
+// correction codewords once the function patterns are drawn, including the
+// remainder bits
+func rawModules(version int) int {
+	n := (16*version+128)*version + 64
+	if version >= 2 {
+		numAlign := version/7 + 2
+		n -= (25*numAlign-10)*numAlign - 55
+		if version >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+// dataCodewords is the number of data codewords of a version
+func dataCodewords(version int) int {
+	return rawModules(version)/8 - eccPerBlock[version]*numBlocks[version]
+}
+
+// encodeData returns the data codewords of a byte mode segment holding data,
+// padded to the capacity of version
+func encodeData(data []byte, version int) []byte {
+	var bb bitBuffer
+	bb.append(0b0100, 4)
+	bb.append(len(data), countBits(version))
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+	capacity := 8 * dataCodewords(version)
+	// The terminator, then zeros to a byte boundary
+	bb.append(0, min(4, capacity-bb.n))
+	bb.append(0, (8-bb.n%8)%8)
+	for pad := 0xEC; bb.n < capacity; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+	return bb.bytes
+}
+
+// bitBuffer accumulates bits most significant first
+type bitBuffer struct {
+	bytes []byte
+	n     int
+}
+
+// append adds the low width bits of v
+func (bb *bitBuffer) append(v, width int) {
+	for i := width - 1; i >= 0; i-- {
+		if bb.n%8 == 0 {
+			bb.bytes = append(bb.bytes, 0)
+		}
+		if v>>i&1 != 0 {
+			bb.bytes[bb.n/8] |= 0x80 >> (bb.n % 8)
+		}
+		bb.n++
+	}
+}
+
+// addECC splits data into the blocks of version, appends the error
+// correction codewords of each, and interleaves the blocks into the final
+// codeword sequence
+func addECC(data []byte, version int) []byte {
+	blocks, eccLen := numBlocks[version], eccPerBlock[version]
+	raw := rawModules(version) / 8
+	// The first blocks are one data codeword shorter than the rest
+	short := blocks - raw%blocks
+	shortLen := raw / blocks
+	divisor := rsDivisor(eccLen)
+
+	dataBlocks := make([][]byte, blocks)
+	eccBlocks := make([][]byte, blocks)
+	for i := range blocks {
+		n := shortLen - eccLen
+		if i >= short {
+			n++
+		}
+		dataBlocks[i], data = data[:n], data[n:]
+		eccBlocks[i] = rsRemainder(dataBlocks[i], divisor)
+	}
+
+	result := make([]byte, 0, raw)
+	for i := range shortLen - eccLen + 1 {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := range eccLen {
+		for _, block := range eccBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// rsDivisor returns the coefficients of the Reed-Solomon generator
+// polynomial of the given degree, highest power first without its leading 1
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		// Multiply by (x - root)
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords of data
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMultiply(d, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// set sets a module, marking it as part of a function pattern if function
+func (c *Code) set(x, y int, dark, function bool) {
+	c.dark[y*c.Size+x] = dark
+	c.function[y*c.Size+x] = function
+}
+
+// alignmentPositions returns the row and column coordinates of the centers
+// of the alignment patterns of version
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	numAlign := version/7 + 2
+	step := (version*8 + numAlign*3 + 5) / (numAlign*4 - 4) * 2
+	positions := make([]int, numAlign)
+	positions[0] = 6
+	for i, pos := numAlign-1, 4*version+10; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+// drawFunctionPatterns draws the finder, timing, alignment, and version
+// patterns, and reserves the modules of the format information
+func (c *Code) drawFunctionPatterns() {
+	for i := range c.Size {
+		c.set(6, i, i%2 == 0, true)
+		c.set(i, 6, i%2 == 0, true)
+	}
+
+	// Finder patterns with their separators
+	for _, center := range [][2]int{{3, 3}, {c.Size - 4, 3}, {3, c.Size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := center[0]+dx, center[1]+dy
+				if x < 0 || x >= c.Size || y < 0 || y >= c.Size {
+					continue
+				}
+				dist := max(abs(dx), abs(dy))
+				c.set(x, y, dist != 2 && dist != 4, true)
+			}
+		}
+	}
+
+	// Alignment patterns everywhere but over the finder patterns
+	positions := alignmentPositions(c.Version)
+	last := len(positions) - 1
+	for i, cy := range positions {
+		for j, cx := range positions {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1, true)
+				}
+			}
+		}
+	}
+
+	// Placeholder format information, drawn for real once the mask is known
+	c.drawFormat(0)
+
+	if c.Version >= 7 {
+		bits := c.Version<<12 | bchRemainder(c.Version, 0x1F25, 12)
+		for i := range 18 {
+			a, b := c.Size-11+i%3, i/3
+			c.set(a, b, bits>>i&1 != 0, true)
+			c.set(b, a, bits>>i&1 != 0, true)
+		}
+	}
+}
+
+// bchRemainder returns the remainder of data shifted left by degree bits
+// divided by the generator polynomial
+func bchRemainder(data, generator, degree int) int {
+	rem := data
+	for range degree {
+		rem = rem<<1 ^ (rem>>(degree-1))*generator
+	}
+	return rem
+}
+
+// drawFormat draws both copies of the format information for mask, and the
+// dark module beside the lower left finder
+func (c *Code) drawFormat(mask int) {
+	data := formatLevelM<<3 | mask
+	bits := (data<<10 | bchRemainder(data, 0x537, 10)) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 != 0 }
+
+	// Around the upper left finder
+	for i := range 6 {
+		c.set(8, i, bit(i), true)
+	}
+	c.set(8, 7, bit(6), true)
+	c.set(8, 8, bit(7), true)
+	c.set(7, 8, bit(8), true)
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i), true)
+	}
+
+	// Split between the other two finders
+	for i := range 8 {
+		c.set(c.Size-1-i, 8, bit(i), true)
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.Size-15+i, bit(i), true)
+	}
+	c.set(8, c.Size-8, true, true)
+}
+
+// drawCodewords places the codewords in the modules that are not part of a
+// function pattern, in two-module columns zigzagging up and down from the
+// right. Modules left over are the light remainder bits.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		// The vertical timing pattern takes a whole column
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range c.Size {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if c.function[y*c.Size+x] || i >= len(codewords)*8 {
+					continue
+				}
+				c.dark[y*c.Size+x] = codewords[i/8]>>(7-i%8)&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+// maskFuncs decide which modules each mask pattern inverts
+var maskFuncs = [8]func(x, y int) bool{
+	func(x, y int) bool { return (x+y)%2 == 0 },
+	func(x, y int) bool { return y%2 == 0 },
+	func(x, y int) bool { return x%3 == 0 },
+	func(x, y int) bool { return (x+y)%3 == 0 },
+	func(x, y int) bool { return (x/3+y/2)%2 == 0 },
+	func(x, y int) bool { return x*y%2+x*y%3 == 0 },
+	func(x, y int) bool { return (x*y%2+x*y%3)%2 == 0 },
+	func(x, y int) bool { return ((x+y)%2+x*y%3)%2 == 0 },
+}
+
+// applyMask inverts the data modules the mask pattern selects
+func (c *Code) applyMask(mask int) {
+	invert := maskFuncs[mask]
+	for y := range c.Size {
+		for x := range c.Size {
+			if !c.function[y*c.Size+x] && invert(x, y) {
+				c.dark[y*c.Size+x] = !c.dark[y*c.Size+x]
+			}
+		}
+	}
+}
+
+// finderLike is the 1:1:3:1:1 finder pattern ratio with four light modules
+// on one side, which readers could mistake for a finder
+var finderLike = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty scores how hard the symbol is to read: long runs of one color,
+// 2×2 blocks, finder-like patterns, and an unbalanced share of dark modules
+// all add to it
+func (c *Code) penalty() int {
+	p := 0
+	line := make([]bool, c.Size)
+	for _, vertical := range []bool{false, true} {
+		for i := range c.Size {
+			for j := range c.Size {
+				if vertical {
+					line[j] = c.Dark(i, j)
+				} else {
+					line[j] = c.Dark(j, i)
+				}
+			}
+			p += linePenalty(line)
+		}
+	}
+
+	dark := 0
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.Dark(x, y) {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				d := c.Dark(x, y)
+				if c.Dark(x+1, y) == d && c.Dark(x, y+1) == d && c.Dark(x+1, y+1) == d {
+					p += 3
+				}
+			}
+		}
+	}
+	total := c.Size * c.Size
+	p += abs(dark*20-total*10) / total * 10
+	return p
+}
+
+// linePenalty scores the runs and finder-like patterns of one row or column
+func linePenalty(line []bool) int {
+	p := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			p += run - 2
+		}
+		run = 1
+	}
+	for i := 0; i+11 <= len(line); i++ {
+		for _, pattern := range finderLike {
+			if slices.Equal(line[i:i+11], pattern) {
+				p += 40
+			}
+		}
+	}
+	return p
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
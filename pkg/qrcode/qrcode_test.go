@@ -0,0 +1,184 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// decode reads the data back out of c: it checks the format information,
+// unmasks the data modules, de-interleaves the codewords, and parses the
+// byte mode segment. Errors are not corrected, so the codes Encode makes
+// must come back exactly.
+func decode(t *testing.T, c *Code) []byte {
+	t.Helper()
+	bit := func(x, y int) int {
+		if c.Dark(x, y) {
+			return 1
+		}
+		return 0
+	}
+	var first, second int
+	for i := range 6 {
+		first |= bit(8, i) << i
+	}
+	first |= bit(8, 7)<<6 | bit(8, 8)<<7 | bit(7, 8)<<8
+	for i := 9; i < 15; i++ {
+		first |= bit(14-i, 8) << i
+	}
+	for i := range 8 {
+		second |= bit(c.Size-1-i, 8) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= bit(8, c.Size-15+i) << i
+	}
+	if first != second {
+		t.Fatalf("format copies differ: %015b and %015b", first, second)
+	}
+	format := first ^ 0x5412
+	if bchRemainder(format>>10, 0x537, 10) != format&0x3FF || format>>13 != formatLevelM {
+		t.Fatalf("format information %015b is not level M with a valid checksum", format)
+	}
+	mask := maskFuncs[format>>10&7]
+
+	// The codewords in placement order
+	var bb bitBuffer
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range c.Size {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = c.Size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if c.function[y*c.Size+x] {
+					continue
+				}
+				v := bit(x, y)
+				if mask(x, y) {
+					v ^= 1
+				}
+				bb.append(v, 1)
+			}
+		}
+	}
+	raw := rawModules(c.Version) / 8
+	codewords := bb.bytes[:raw]
+
+	// De-interleave the data codewords and check each block's error
+	// correction
+	blocks, eccLen := numBlocks[c.Version], eccPerBlock[c.Version]
+	short, shortLen := blocks-raw%blocks, raw/blocks
+	dataBlocks := make([][]byte, blocks)
+	i := 0
+	for pos := range shortLen - eccLen + 1 {
+		for b := range blocks {
+			if pos < shortLen-eccLen || b >= short {
+				dataBlocks[b] = append(dataBlocks[b], codewords[i])
+				i++
+			}
+		}
+	}
+	divisor := rsDivisor(eccLen)
+	for b, block := range dataBlocks {
+		ecc := make([]byte, eccLen)
+		for pos := range ecc {
+			ecc[pos] = codewords[i+pos*blocks+b]
+		}
+		if want := rsRemainder(block, divisor); !bytes.Equal(ecc, want) {
+			t.Fatalf("block %d error correction = %x, want %x", b, ecc, want)
+		}
+	}
+	data := bytes.Join(dataBlocks, nil)
+
+	// Parse the segment: mode, count, then the bytes
+	read := func(pos, width int) int {
+		v := 0
+		for i := range width {
+			v = v<<1 | int(data[(pos+i)/8]>>(7-(pos+i)%8)&1)
+		}
+		return v
+	}
+	if mode := read(0, 4); mode != 0b0100 {
+		t.Fatalf("mode = %04b, want byte mode", mode)
+	}
+	n := read(4, countBits(c.Version))
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = byte(read(4+countBits(c.Version)+8*i, 8))
+	}
+	return out
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		data    string
+		version int
+	}{
+		{"", 1},
+		{"https://dash.example/s/AbCdEfGh", 3},
+		// Version 7 adds the version information
+		{strings.Repeat("x", 120), 7},
+		// Version 10 widens the count to 16 bits
+		{strings.Repeat("y", 200), 10},
+		// Blocks of two lengths
+		{strings.Repeat("z", 1000), 26},
+		{strings.Repeat("\x00\xff", 1165), 40},
+	}
+	for _, tt := range tests {
+		c, err := Encode([]byte(tt.data))
+		if err != nil {
+			t.Fatalf("Encode(%d bytes) error = %v", len(tt.data), err)
+		}
+		if c.Version != tt.version || c.Size != 4*tt.version+17 {
+			t.Errorf("Encode(%d bytes) = version %d, size %d; want version %d", len(tt.data), c.Version, c.Size, tt.version)
+		}
+		if got := decode(t, c); string(got) != tt.data {
+			t.Errorf("decoded %q, want %q", got, tt.data)
+		}
+	}
+
+	if _, err := Encode(make([]byte, 2332)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Encode(2332 bytes) error = %v, want ErrTooLong", err)
+	}
+}
+
+func TestRSRemainder(t *testing.T) {
+	// The version 1-M example of ISO/IEC 18004 Annex I, "01234567"
+	data := []byte{0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	want := []byte{0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder() = %x, want %x", got, want)
+	}
+}
+
+func TestImage(t *testing.T) {
+	c, err := Encode([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 21 modules and the quiet zone are 29; 100 pixels give 3 per module,
+	// centered with 13 to spare
+	img := c.Image(100)
+	if b := img.Bounds(); b.Dx() != 100 || b.Dy() != 100 {
+		t.Fatalf("Image(100) bounds = %v", b)
+	}
+	for y := range c.Size {
+		for x := range c.Size {
+			if dark := img.ColorIndexAt(6+3*(QuietZone+x)+1, 6+3*(QuietZone+y)+1) == 1; dark != c.Dark(x, y) {
+				t.Fatalf("pixel of module (%d, %d) dark = %v, want %v", x, y, dark, c.Dark(x, y))
+			}
+		}
+	}
+	if img.ColorIndexAt(6+3*QuietZone-1, 6+3*QuietZone) != 0 {
+		t.Error("quiet zone is not light")
+	}
+
+	if b := c.Image(10).Bounds(); b.Dx() != 29 {
+		t.Errorf("Image(10) width = %d, want one pixel per module", b.Dx())
+	}
+}
@@ -0,0 +1,112 @@
+// Package ratelimit implements a keyed token-bucket rate limiter.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter hands out tokens from one bucket per key. Each bucket holds at most
+// burst tokens and refills at rate tokens per second. Buckets that have been
+// idle long enough to refill completely are evicted, so memory stays bounded
+// by the number of recently active keys.
+type Limiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	idleTTL   time.Duration
+	now       func() time.Time
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Option configures a Limiter
+type Option func(*Limiter)
+
+// WithClock replaces time.Now, which lets tests control the passage of time
+func WithClock(now func() time.Time) Option {
+	return func(l *Limiter) { l.now = now }
+}
+
+// WithIdleTTL sets how long a bucket may sit unused before it is evicted. It
+// is never shorter than the time a bucket takes to refill from empty, since
+// evicting earlier would hand the key a fresh burst.
+func WithIdleTTL(ttl time.Duration) Option {
+	return func(l *Limiter) { l.idleTTL = ttl }
+}
+
+// New creates a Limiter allowing rate requests per second per key with the
+// given burst size
+func New(rate float64, burst int, opts ...Option) *Limiter {
+	l := &Limiter{
+		rate:    rate,
+		burst:   float64(max(burst, 1)),
+		idleTTL: time.Minute,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	if l.idleTTL < refill {
+		l.idleTTL = refill
+	}
+	l.lastSweep = l.now()
+	return l
+}
+
+// Allow takes a token for key. When the bucket is empty it returns false and
+// how long the caller should wait before a token becomes available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	} else {
+		elapsed := now.Sub(b.last).Seconds()
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// Len returns the number of keys currently tracked
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// sweep evicts idle buckets at most once per idleTTL. The caller must hold
+// the lock.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.idleTTL {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestLimiter_BurstThenRefill(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	l := New(2, 3, WithClock(clock.Now))
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d within burst was rejected", i+1)
+		}
+	}
+
+	ok, wait := l.Allow("a")
+	if ok {
+		t.Fatal("request beyond burst was allowed")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("wait = %v, want %v", wait, 500*time.Millisecond)
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("request after refilling one token was rejected")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("second request after refilling one token was allowed")
+	}
+}
+
+func TestLimiter_KeysAreIndependent(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	l := New(1, 1, WithClock(clock.Now))
+
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("first request for a was rejected")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Fatal("second request for a was allowed")
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("first request for b was rejected")
+	}
+}
+
+func TestLimiter_RefillIsCappedAtBurst(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	l := New(10, 2, WithClock(clock.Now))
+
+	l.Allow("a")
+	clock.Advance(time.Hour)
+
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if ok, _ := l.Allow("a"); ok {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("allowed %d requests after a long idle period, want burst of 2", allowed)
+	}
+}
+
+func TestLimiter_EvictsIdleKeys(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	l := New(1, 1, WithClock(clock.Now), WithIdleTTL(time.Minute))
+
+	for _, key := range []string{"a", "b", "c"} {
+		l.Allow(key)
+	}
+	if got := l.Len(); got != 3 {
+		t.Fatalf("Len() = %d, want 3", got)
+	}
+
+	clock.Advance(30 * time.Second)
+	l.Allow("c")
+	clock.Advance(40 * time.Second)
+	l.Allow("d")
+
+	if got := l.Len(); got != 2 {
+		t.Errorf("Len() = %d after eviction, want 2 (c and d)", got)
+	}
+}
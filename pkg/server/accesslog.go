@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// logRequest writes one structured access log record per request once it has
+// been handled, including the response status, body size, and handler
+// duration. Health probes are logged at debug level so they don't drown out
+// real traffic.
+func (s *Server) logRequest(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &accessLogEntry{}
+		rw := &responseRecorder{ResponseWriter: w}
+
+		handler.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), accessLogKey{}, entry)))
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("query", r.URL.RawQuery),
+			slog.Int("status", rw.Status()),
+			slog.Int64("bytes", rw.bytes),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("client_ip", s.clientIP(r)),
+			slog.String("request_id", RequestIDFromContext(r.Context())),
+		}
+		if entry.user != "" {
+			attrs = append(attrs, slog.String("user", entry.user))
+		}
+		level := slog.LevelInfo
+		if slices.Contains(probePaths, r.URL.Path) {
+			level = slog.LevelDebug
+		}
+		s.logger.LogAttrs(r.Context(), level, "request", attrs...)
+	})
+}
+
+type accessLogKey struct{}
+
+// accessLogEntry collects details that handlers further down the chain want
+// to appear in the access log line
+type accessLogEntry struct {
+	user string
+}
+
+// setAccessLogUser records the authenticated username for the access log
+func setAccessLogUser(ctx context.Context, user string) {
+	if entry, ok := ctx.Value(accessLogKey{}).(*accessLogEntry); ok {
+		entry.user = user
+	}
+}
+
+// responseRecorder wraps a ResponseWriter to capture the status code and the
+// number of body bytes written. Flush and Hijack are passed through to the
+// underlying writer so streaming and connection upgrades keep working.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// Status returns the status code sent to the client, which is 200 if the
+// handler never called WriteHeader
+func (rw *responseRecorder) Status() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+	return rw.status
+}
+
+func (rw *responseRecorder) WriteHeader(status int) {
+	// Informational responses may precede the real status
+	if rw.status == 0 && status >= 200 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseRecorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Flush sends buffered data to the client if the underlying writer supports it
+func (rw *responseRecorder) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Hijack takes over the connection if the underlying writer supports it
+func (rw *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T does not support hijacking: %w", rw.ResponseWriter, http.ErrNotSupported)
+	}
+	return h.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
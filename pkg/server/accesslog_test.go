@@ -0,0 +1,128 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	t.Run("implicit 200 and byte count", func(t *testing.T) {
+		rw := &responseRecorder{ResponseWriter: httptest.NewRecorder()}
+		rw.Write([]byte("hello"))
+		rw.Write([]byte(" world"))
+		if rw.Status() != http.StatusOK || rw.bytes != 11 {
+			t.Errorf("status, bytes = %d, %d, want 200, 11", rw.Status(), rw.bytes)
+		}
+	})
+
+	t.Run("explicit status is kept", func(t *testing.T) {
+		rw := &responseRecorder{ResponseWriter: httptest.NewRecorder()}
+		rw.WriteHeader(http.StatusTeapot)
+		rw.WriteHeader(http.StatusOK)
+		if rw.Status() != http.StatusTeapot {
+			t.Errorf("Status() = %d, want %d", rw.Status(), http.StatusTeapot)
+		}
+	})
+
+	t.Run("flush passes through", func(t *testing.T) {
+		underlying := httptest.NewRecorder()
+		var w http.ResponseWriter = &responseRecorder{ResponseWriter: underlying}
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("responseRecorder does not implement http.Flusher")
+		}
+		f.Flush()
+		if !underlying.Flushed {
+			t.Error("Flush() did not reach the underlying writer")
+		}
+	})
+
+	t.Run("hijack unsupported", func(t *testing.T) {
+		rw := &responseRecorder{ResponseWriter: httptest.NewRecorder()}
+		if _, _, err := rw.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("Hijack() error = %v, want %v", err, http.ErrNotSupported)
+		}
+	})
+
+	t.Run("hijack passes through", func(t *testing.T) {
+		srv := httptest.NewServer(newTestServer(t, testConfig(t)).logRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, buf, err := http.NewResponseController(w).Hijack()
+			if err != nil {
+				t.Errorf("Hijack() error = %v", err)
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+			buf.Flush()
+		})))
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("GET error = %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != "hijacked" {
+			t.Errorf("body = %q, want hijacked", body)
+		}
+	})
+}
+
+func TestLogRequest_Status(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	buf := captureLogs(srv)
+	mux := srv.routes()
+	mux.HandleFunc("/boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	handler := srv.withMiddleware(mux)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{path: "/does-not-exist", wantStatus: http.StatusNotFound},
+		{path: "/boom", wantStatus: http.StatusInternalServerError},
+		{path: "/api/items", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("X-Request-ID", "log-test")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			var access map[string]any
+			for _, rec := range logRecords(t, buf) {
+				if rec["msg"] == "request" {
+					access = rec
+				}
+			}
+			if access == nil {
+				t.Fatalf("no access log record in %q", buf.String())
+			}
+
+			want := map[string]any{
+				"method":     "GET",
+				"path":       tt.path,
+				"status":     float64(tt.wantStatus),
+				"request_id": "log-test",
+			}
+			for k, v := range want {
+				if access[k] != v {
+					t.Errorf("access log %s = %v, want %v", k, access[k], v)
+				}
+			}
+			for _, k := range []string{"duration_ms", "bytes"} {
+				if _, ok := access[k].(float64); !ok {
+					t.Errorf("access log %s = %v, want a number", k, access[k])
+				}
+			}
+		})
+	}
+}
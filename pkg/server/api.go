@@ -0,0 +1,429 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// httpError is an error that carries the HTTP status it should be reported with
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+// respondError maps err to an HTTP status and reports it with renderError.
+// Errors that are not recognized are logged and reported as a generic 500 so
+// internal details never reach the client.
+func (s *Server) respondError(w http.ResponseWriter, r *http.Request, err error) {
+	var he *httpError
+	switch {
+	case errors.As(err, &he):
+		s.renderError(w, r, he.status, he.message)
+	case errors.Is(err, itemstore.ErrNotFound):
+		s.renderError(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, itemstore.ErrDuplicateID), errors.Is(err, itemstore.ErrConflict):
+		s.renderError(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, itemstore.ErrInvalidItem):
+		var details itemstore.ValidationErrors
+		if errors.As(err, &details) && !wantsHTML(r) {
+			s.writeErrorResponse(w, r, errorResponse{Error: err.Error(), Status: http.StatusBadRequest, Details: details})
+			return
+		}
+		s.renderError(w, r, http.StatusBadRequest, err.Error())
+	case errors.Is(err, itemstore.ErrReadOnly):
+		s.renderError(w, r, errReadOnly.status, errReadOnly.message)
+	case errors.Is(err, context.DeadlineExceeded):
+		s.renderError(w, r, http.StatusServiceUnavailable, timeoutMessage)
+	case errors.Is(err, context.Canceled):
+		// The client left before the store finished, which is no fault
+		// worth an error log
+		s.logger.DebugContext(r.Context(), "request canceled", "error", err, "request_id", RequestIDFromContext(r.Context()))
+		s.renderError(w, r, http.StatusServiceUnavailable, "the request was canceled before it completed")
+	default:
+		s.logger.ErrorContext(r.Context(), "internal error", "error", err, "request_id", RequestIDFromContext(r.Context()))
+		s.renderError(w, r, http.StatusInternalServerError, internalErrorMessage)
+	}
+}
+
+// writeJSON encodes v as the response body with the given status code
+func (s *Server) writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.WarnContext(r.Context(), "failed to write JSON response", "error", err, "request_id", RequestIDFromContext(r.Context()))
+	}
+}
+
+// decodeJSONBody decodes the request body into dst. The body must be declared
+// as application/json and may be at most limit bytes; unknown fields and
+// trailing data are rejected. The returned error is an *httpError with the
+// status to report (400, 413, or 415).
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, dst any, limit int64) error {
+	if err := requireJSON(r); err != nil {
+		return err
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return jsonBodyError(err)
+	}
+
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return &httpError{status: http.StatusBadRequest, message: "request body must contain a single JSON value"}
+	}
+	return nil
+}
+
+// decodeItemBody is decodeJSONBody for the item write endpoints, which also
+// take the body as application/yaml. A YAML body is converted to JSON and
+// decoded just like a JSON one.
+func decodeItemBody(w http.ResponseWriter, r *http.Request, dst any, limit int64) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil && isYAMLMediaType(mediaType) {
+		data, err := readYAML(http.MaxBytesReader(w, r.Body, limit))
+		if err != nil {
+			return err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(dst); err != nil {
+			return &httpError{status: http.StatusBadRequest, message: "invalid YAML body: " + err.Error()}
+		}
+		return nil
+	}
+	if err == nil && mediaType == "application/json" {
+		return decodeJSONBody(w, r, dst, limit)
+	}
+	return &httpError{status: http.StatusUnsupportedMediaType, message: "Content-Type must be application/json or " + yamlContentType}
+}
+
+// requireJSON rejects a request body that is not declared as application/json
+func requireJSON(r *http.Request) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return &httpError{
+			status:  http.StatusUnsupportedMediaType,
+			message: "Content-Type must be application/json",
+		}
+	}
+	return nil
+}
+
+// jsonBodyError maps an error from decoding a request body limited by
+// http.MaxBytesReader to the *httpError to report
+func jsonBodyError(err error) error {
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		return &httpError{
+			status:  http.StatusRequestEntityTooLarge,
+			message: fmt.Sprintf("request body must not exceed %d bytes", maxErr.Limit),
+		}
+	case errors.Is(err, io.EOF):
+		return &httpError{status: http.StatusBadRequest, message: "request body must not be empty"}
+	default:
+		return &httpError{status: http.StatusBadRequest, message: "invalid JSON body: " + err.Error()}
+	}
+}
+
+// pathItemID parses the {id} path segment as an ID of the store r addresses
+func (s *Server) pathItemID(r *http.Request) (itemstore.ID, error) {
+	id, err := s.storeFor(r).ParseID(r.PathValue("id"))
+	if err != nil {
+		return itemstore.ID{}, &httpError{status: http.StatusBadRequest, message: "invalid item ID: " + r.PathValue("id")}
+	}
+	return id, nil
+}
+
+// itemAPIPath returns the API path of the item id
+func itemAPIPath(id itemstore.ID) string {
+	return "/api/items/" + url.PathEscape(id.String())
+}
+
+// itemResponse wraps a single item in the API envelope
+type itemResponse struct {
+	Item itemstore.Item `json:"item"`
+}
+
+// itemListResponse wraps a list of items in the API envelope
+type itemListResponse struct {
+	Items []itemstore.Item `json:"items"`
+	Meta  listMeta         `json:"meta"`
+}
+
+// listMeta describes a list response
+type listMeta struct {
+	Count int `json:"count"`
+}
+
+func (s *Server) apiListItemsHandler(w http.ResponseWriter, r *http.Request) {
+	r, err := negotiateEncoding(w, r)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	query, err := parseItemsQuery(fillDefaults(s.cfg.Defaults, r.URL.Query(), apiListItemsParams), apiListItemsParams, true)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	items := s.storeFor(r).ItemsContext(r.Context(), query.Filters)
+	switch requestEncoding(r) {
+	case encodingMsgpack:
+		body := appendMsgpackItemList(nil, items)
+		if err := r.Context().Err(); err != nil {
+			s.respondError(w, r, err)
+			return
+		}
+		s.writeMsgpack(w, r, http.StatusOK, body)
+	case encodingYAML:
+		list := []itemstore.Item{}
+		for item := range items {
+			list = append(list, item)
+		}
+		if err := r.Context().Err(); err != nil {
+			s.respondError(w, r, err)
+			return
+		}
+		s.writeYAML(w, r, http.StatusOK, itemListResponse{Items: list, Meta: listMeta{Count: len(list)}})
+	default:
+		s.writeItemList(w, r, items)
+	}
+}
+
+// countResponse is the body of GET /api/items/count
+type countResponse struct {
+	Count int `json:"count"`
+}
+
+// apiCountItemsHandler counts the items GET /api/items would list without
+// copying them. The count is its own ETag, so a client polling for a badge
+// gets 304 until it changes.
+func (s *Server) apiCountItemsHandler(w http.ResponseWriter, r *http.Request) {
+	query, err := parseItemsQuery(fillDefaults(s.cfg.Defaults, r.URL.Query(), apiItemsParams), apiItemsParams, true)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	count := s.storeFor(r).Count(query.Filters)
+	etag := `"` + strconv.Itoa(count) + `"`
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, countResponse{Count: count})
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing
+// weakly as RFC 9110 requires
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// itemListFlushEvery is how many items writeItemList writes between flushes
+const itemListFlushEvery = 1000
+
+// writeItemList streams items as an itemListResponse, encoding one item at
+// a time so a large store is never copied or buffered whole. The status is
+// sent before the first item, so an error mid-stream cannot be reported:
+// it is logged and the body ends early, leaving the client with truncated
+// JSON that fails to parse. That includes the request's context ending,
+// which stops items early.
+func (s *Server) writeItemList(w http.ResponseWriter, r *http.Request, items iter.Seq[itemstore.Item]) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+
+	fail := func(err error) {
+		s.logger.WarnContext(r.Context(), "failed to stream item list", "error", err, "request_id", RequestIDFromContext(r.Context()))
+	}
+	if _, err := io.WriteString(w, `{"items":[`); err != nil {
+		fail(err)
+		return
+	}
+	// Each item is encoded into buf, which is reused, and written from there
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// current is encoded by pointer so each item is not boxed separately
+	var current itemstore.Item
+	count := 0
+	for item := range items {
+		buf.Reset()
+		if count > 0 {
+			buf.WriteByte(',')
+		}
+		current = item
+		err := enc.Encode(&current)
+		if err == nil {
+			// Drop the newline Encode ends each value with
+			buf.Truncate(buf.Len() - 1)
+			_, err = w.Write(buf.Bytes())
+		}
+		if err != nil {
+			fail(err)
+			return
+		}
+		count++
+		if count%itemListFlushEvery == 0 {
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				fail(err)
+				return
+			}
+		}
+	}
+	if err := r.Context().Err(); err != nil {
+		fail(err)
+		return
+	}
+	if _, err := fmt.Fprintf(w, `],"meta":{"count":%d}}`+"\n", count); err != nil {
+		fail(err)
+	}
+}
+
+func (s *Server) apiGetItemHandler(w http.ResponseWriter, r *http.Request) {
+	r, err := negotiateEncoding(w, r)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	id, err := s.pathItemID(r)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	item, err := s.storeFor(r).Get(id)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	switch requestEncoding(r) {
+	case encodingMsgpack:
+		s.writeMsgpack(w, r, http.StatusOK, appendMsgpackItemResponse(nil, itemResponse{Item: item}))
+	case encodingYAML:
+		s.writeYAML(w, r, http.StatusOK, itemResponse{Item: item})
+	default:
+		s.writeJSON(w, r, http.StatusOK, itemResponse{Item: item})
+	}
+}
+
+func (s *Server) apiCreateItemHandler(w http.ResponseWriter, r *http.Request) {
+	var item itemstore.Item
+	if err := decodeItemBody(w, r, &item, s.cfg.MaxBodyBytes); err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	created, err := s.writeStore(r).Add(item)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", s.scopedURL(r, itemAPIPath(created.ID)))
+	s.writeJSON(w, r, http.StatusCreated, itemResponse{Item: created})
+}
+
+func (s *Server) apiReplaceItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathItemID(r)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	var item itemstore.Item
+	if err := decodeItemBody(w, r, &item, s.cfg.MaxBodyBytes); err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	// Compared as strings, since a body may give a string ID as a number
+	if !item.ID.IsZero() && item.ID.String() != id.String() {
+		s.respondError(w, r, &httpError{status: http.StatusBadRequest, message: "item ID in body does not match the URL"})
+		return
+	}
+	item.ID = id
+
+	updated, err := s.writeStore(r).Update(item)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, itemResponse{Item: updated})
+}
+
+// itemPatch holds the fields of a PATCH request; nil fields are left unchanged
+type itemPatch struct {
+	Color    *string `json:"color"`
+	Shape    *string `json:"shape"`
+	Category *string `json:"category"`
+}
+
+func (s *Server) apiPatchItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathItemID(r)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	var patch itemPatch
+	if err := decodeItemBody(w, r, &patch, s.cfg.MaxBodyBytes); err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+
+	item, err := s.storeFor(r).Get(id)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	if patch.Color != nil {
+		item.Color = *patch.Color
+	}
+	if patch.Shape != nil {
+		item.Shape = *patch.Shape
+	}
+	if patch.Category != nil {
+		item.Category = *patch.Category
+	}
+
+	updated, err := s.writeStore(r).Update(item)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, itemResponse{Item: updated})
+}
+
+func (s *Server) apiDeleteItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathItemID(r)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	if err := s.writeStore(r).Delete(id); err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,473 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+func TestDecodeJSONBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		limit       int64
+		wantStatus  int
+	}{
+		{
+			name:        "valid body",
+			contentType: "application/json",
+			body:        `{"color":"red","shape":"circle","category":"A"}`,
+			limit:       1024,
+		},
+		{
+			name:        "content type with charset",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"color":"red"}`,
+			limit:       1024,
+		},
+		{
+			name:        "body too large",
+			contentType: "application/json",
+			body:        `{"color":"` + strings.Repeat("r", 100) + `"}`,
+			limit:       16,
+			wantStatus:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "wrong content type",
+			contentType: "text/plain",
+			body:        `{"color":"red"}`,
+			limit:       1024,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "missing content type",
+			body:       `{"color":"red"}`,
+			limit:      1024,
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "unknown field",
+			contentType: "application/json",
+			body:        `{"colour":"red"}`,
+			limit:       1024,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "trailing data",
+			contentType: "application/json",
+			body:        `{"color":"red"} {}`,
+			limit:       1024,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "empty body",
+			contentType: "application/json",
+			limit:       1024,
+			wantStatus:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/items", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			var item itemstore.Item
+			err := decodeJSONBody(httptest.NewRecorder(), req, &item, tt.limit)
+
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("decodeJSONBody() error = %v", err)
+				}
+				return
+			}
+			var he *httpError
+			if !errors.As(err, &he) {
+				t.Fatalf("decodeJSONBody() error = %v, want *httpError", err)
+			}
+			if he.status != tt.wantStatus {
+				t.Errorf("decodeJSONBody() status = %d, want %d", he.status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAPI_CreateItemTooLarge(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MaxBodyBytes = 32
+	router := newTestServer(t, cfg).routes()
+
+	body := `{"color":"red","shape":"circle","category":"` + strings.Repeat("A", 64) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	var resp errorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+	if resp.Status != http.StatusRequestEntityTooLarge {
+		t.Errorf("envelope status = %d, want %d", resp.Status, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestAPI_ValidationDetails(t *testing.T) {
+	router := newTestServer(t, testConfig(t)).routes()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/items", strings.NewReader(`{"color":"","shape":" ","category":"A"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+	}
+	want := `{"error":"invalid item: color is required; shape is required","status":400,` +
+		`"details":[{"field":"color","value":"","message":"is required"},{"field":"shape","value":"","message":"is required"}]}` + "\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("body =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestAPI_ListItemsStreamed(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	for _, tt := range []struct {
+		query   string
+		filters map[string]string
+	}{
+		{"", nil},
+		{"?filter=category:A", map[string]string{"category": "A"}},
+		{"?filter=color:purple", map[string]string{"color": "purple"}},
+	} {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/items"+tt.query, nil))
+
+		// The stream must match encoding the whole list at once
+		items := srv.store.Filter(tt.filters)
+		if items == nil {
+			items = []itemstore.Item{}
+		}
+		want, _ := json.Marshal(itemListResponse{Items: items, Meta: listMeta{Count: len(items)}})
+		if got := rec.Body.String(); got != string(want)+"\n" {
+			t.Errorf("GET /api/items%s =\n%s\nwant\n%s", tt.query, got, want)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+	}
+}
+
+// pausingStore holds its item lists back after the first flush's worth of
+// items until resume is closed
+type pausingStore struct {
+	*itemstore.ItemStore
+	resume chan struct{}
+}
+
+func (s pausingStore) ItemsContext(ctx context.Context, filters map[string]string) iter.Seq[itemstore.Item] {
+	items := s.ItemStore.ItemsContext(ctx, filters)
+	return func(yield func(itemstore.Item) bool) {
+		n := 0
+		for item := range items {
+			if n == itemListFlushEvery {
+				<-s.resume
+			}
+			if !yield(item) {
+				return
+			}
+			n++
+		}
+	}
+}
+
+func TestAPI_ListItemsStreamedWithTimeout(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Timeouts.Request = 10 * time.Second
+	items := make([]itemstore.Item, 2*itemListFlushEvery)
+	for i := range items {
+		items[i] = itemstore.Item{ID: itemstore.IntID(i + 1), Color: "red", Shape: "circle", Category: "A"}
+	}
+	inner, err := itemstore.New(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := pausingStore{ItemStore: inner, resume: make(chan struct{})}
+	srv, err := New(store, WithConfig(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	// The first items arrive while the handler is still waiting for the rest
+	var resp *http.Response
+	first := make(chan error, 1)
+	go func() {
+		var err error
+		if resp, err = http.Get(ts.URL + "/api/items"); err == nil {
+			_, err = io.ReadFull(resp.Body, make([]byte, len(`{"items":[`)+100))
+		}
+		first <- err
+	}()
+	select {
+	case err := <-first:
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+	case <-time.After(5 * time.Second):
+		close(store.resume)
+		t.Fatal("no part of the list arrived before the handler finished")
+	}
+
+	close(store.resume)
+	rest, err := io.ReadAll(resp.Body)
+	if err != nil || !strings.HasSuffix(string(rest), fmt.Sprintf(`],"meta":{"count":%d}}`+"\n", len(items))) {
+		t.Errorf("rest of the list = %d bytes, %v; want it to end with the count", len(rest), err)
+	}
+}
+
+func TestCanceledRequest(t *testing.T) {
+	cfg := testConfig(t)
+	// http.TimeoutHandler would answer for the handlers
+	cfg.Timeouts.Request = 0
+	srv := newTestServer(t, cfg)
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	// Handlers that read the store before writing anything report the
+	// canceled request rather than render what they scanned
+	for _, target := range []string{
+		"/items",
+		"/items?groupBy=color&sortBy=id",
+		"/items/export?format=csv",
+		"/api/charts/color",
+		"/api/compare?left=filter%3Dcolor%3Ared",
+		"/api/items?format=yaml",
+		"/feed.atom",
+	} {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil).WithContext(ctx))
+		if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "canceled") {
+			t.Errorf("GET %s with a canceled context = %d %s, want 503", target, rec.Code, rec.Body)
+		}
+	}
+
+	// The JSON list has sent its status by the time it notices, so it
+	// leaves the body unterminated instead
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/items", nil).WithContext(ctx))
+	if json.Valid(rec.Body.Bytes()) {
+		t.Errorf("GET /api/items with a canceled context = %s, want a truncated body", rec.Body)
+	}
+}
+
+func TestAPI_CountItems(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	for _, query := range []string{
+		"",
+		"?filter=category:A",
+		"?filter=category:A&filter=color:Blue",
+		"?filterBy=shape&filterValue=triangle",
+		"?filter=color:purple",
+	} {
+		var list itemListResponse
+		if err := json.NewDecoder(getItems(t, srv, "/api/items"+query).Body).Decode(&list); err != nil {
+			t.Fatal(err)
+		}
+		rec := getItems(t, srv, "/api/items/count"+query)
+		var got countResponse
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusOK || got.Count != len(list.Items) {
+			t.Errorf("GET /api/items/count%s = %d %+v, want a count of %d", query, rec.Code, got, len(list.Items))
+		}
+	}
+
+	if rec := getItems(t, srv, "/api/items/count?sortBy=id"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown parameter: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestAPI_CountItemsNotModified(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+	count := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/items/count?filter=category:A", nil)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	etag := count("").Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	for _, header := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		if rec := count(header); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("If-None-Match: %s gave %d %q, want an empty 304", header, rec.Code, rec.Body)
+		}
+	}
+
+	// Adding a matching item changes the count and so the ETag
+	req := httptest.NewRequest(http.MethodPost, "/api/items", strings.NewReader(`{"color":"red","shape":"square","category":"A"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /api/items status = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := count(etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("after an add: status %d, ETag %s; want 200 with a new ETag", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+// discardResponseWriter is a ResponseWriter that drops the body, so
+// benchmarks measure the handler rather than a recorder's buffer
+type discardResponseWriter struct{ header http.Header }
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+// BenchmarkAPIListItems compares streaming 50,000 items with copying them
+// and encoding the whole response at once, as the handler used to. Run with
+// -benchmem: streaming allocates a small, fixed amount per item instead of
+// a copy of the store plus the encoded body.
+func BenchmarkAPIListItems(b *testing.B) {
+	srv := newTestServer(b, testConfig(b))
+	items := make([]itemstore.Item, 50000)
+	for i := range items {
+		items[i] = itemstore.Item{ID: itemstore.IntID(i + 1), Color: "red", Shape: "circle", Category: fmt.Sprintf("c%d", i%25)}
+	}
+	store, err := itemstore.New(items)
+	if err != nil {
+		b.Fatal(err)
+	}
+	srv.store = store
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+
+	b.Run("streamed", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			srv.writeItemList(&discardResponseWriter{header: http.Header{}}, req, store.Items(nil))
+		}
+	})
+	b.Run("buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			all := store.Filter(nil)
+			srv.writeJSON(&discardResponseWriter{header: http.Header{}}, req, http.StatusOK, itemListResponse{Items: all, Meta: listMeta{Count: len(all)}})
+		}
+	})
+}
+
+func TestAPI_CRUD(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	router := srv.routes()
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/items", `{"color":"blue","shape":"circle","category":"C"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/api/items/4" {
+		t.Errorf("Location = %q, want /api/items/4", loc)
+	}
+
+	rec = do(http.MethodPatch, "/api/items/4", `{"shape":"square"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if got, _ := srv.store.Get(itemstore.IntID(4)); got.Shape != "square" || got.Color != "blue" {
+		t.Errorf("after PATCH item = %+v, want blue square", got)
+	}
+
+	rec = do(http.MethodPut, "/api/items/4", `{"color":"red","shape":"triangle","category":"A"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	rec = do(http.MethodGet, "/api/items/4", "")
+	var got itemResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode GET response: %v", err)
+	}
+	// PUT replaces every field but the creation time
+	if got.Item.CreatedAt.IsZero() {
+		t.Error("GET item lost its createdAt after PUT")
+	}
+	got.Item.CreatedAt = time.Time{}
+	want := itemstore.Item{ID: itemstore.IntID(4), Color: "red", Shape: "triangle", Category: "A"}
+	if got.Item != want {
+		t.Errorf("GET item = %+v, want %+v", got.Item, want)
+	}
+
+	if rec := do(http.MethodDelete, "/api/items/4", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if rec := do(http.MethodGet, "/api/items/4", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET after DELETE status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestAPI_BulkCreate(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		wantStatus int
+		wantLen    int
+	}{
+		{name: "atomic rejects everything", mode: "atomic", wantStatus: http.StatusBadRequest, wantLen: 3},
+		{name: "best-effort keeps valid items", mode: "best-effort", wantStatus: http.StatusMultiStatus, wantLen: 4},
+	}
+
+	body := `[{"color":"blue","shape":"circle","category":"C"},{"color":"","shape":"circle","category":"C"}]`
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, testConfig(t))
+			req := httptest.NewRequest(http.MethodPost, "/api/items/bulk?mode="+tt.mode, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			srv.routes().ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if srv.store.Len() != tt.wantLen {
+				t.Errorf("store Len() = %d, want %d", srv.store.Len(), tt.wantLen)
+			}
+		})
+	}
+}
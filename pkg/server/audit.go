@@ -0,0 +1,210 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/audit"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+const (
+	// defaultAuditLimit is the number of entries /api/audit returns without
+	// a limit parameter
+	defaultAuditLimit = 100
+	// maxAuditLimit bounds the limit parameter of /api/audit
+	maxAuditLimit = 1000
+	// itemHistoryLimit is the number of changes shown on an item's page
+	itemHistoryLimit = 20
+)
+
+// auditParams are the query parameters accepted by /api/audit
+var auditParams = []string{"limit", "itemId", "since"}
+
+// auditResponse is the body of /api/audit
+type auditResponse struct {
+	Entries []audit.Entry `json:"entries"`
+}
+
+// undoResponse is the body of POST /api/undo
+type undoResponse struct {
+	// Undone is the change that was reverted
+	Undone audit.Entry `json:"undone"`
+	// Entry is the reverting change, as recorded in the audit log
+	Entry audit.Entry `json:"entry"`
+}
+
+// AuditLog returns the log of item changes, or nil when --audit-size is 0.
+// Writers outside the server, such as the gRPC service, record their changes
+// in it by writing through audit.Store.
+func (s *Server) AuditLog() *audit.Log {
+	return s.audit
+}
+
+// writeStore returns the store r addresses for making changes, which records
+// them in the audit log attributed to the sender of r
+func (s *Server) writeStore(r *http.Request) itemstore.Store {
+	store := s.storeFor(r)
+	if s.audit == nil {
+		return store
+	}
+	return audit.Store(store, s.audit, s.auditSource(r))
+}
+
+// auditSource attributes the changes r makes
+func (s *Server) auditSource(r *http.Request) audit.Source {
+	source := audit.Source{Name: s.writeSource(r), RequestID: RequestIDFromContext(r.Context())}
+	if c := requestCollection(r); c != nil {
+		source.Collection = c.name
+	}
+	return source
+}
+
+// writeSource names who is behind a write: the HTML forms are "form", and
+// the API and GraphQL are the API key's name, or "api" when writes need no
+// key
+func (s *Server) writeSource(r *http.Request) string {
+	if !strings.HasPrefix(r.URL.Path, "/api/") && r.URL.Path != graphqlPath {
+		return "form"
+	}
+	if len(s.apiKeys.digests) == 0 {
+		return "api"
+	}
+	return apiKeyName(requestAPIKey(r))
+}
+
+// apiKeyName names key in the audit log without revealing it: "api-key:"
+// followed by the first 8 hex digits of its SHA-256
+func apiKeyName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "api-key:" + hex.EncodeToString(sum[:4])
+}
+
+// apiAuditHandler lists the audit entries of the store r addresses, newest
+// first, optionally only those of one item or since a time
+func (s *Server) apiAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if s.audit == nil {
+		s.writeError(w, r, http.StatusNotFound, "the audit log is disabled")
+		return
+	}
+	query := r.URL.Query()
+	if err := checkQueryParams(query, auditParams); err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+
+	q := audit.Query{Limit: defaultAuditLimit}
+	if c := requestCollection(r); c != nil {
+		q.Collection = c.name
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			s.respondError(w, r, &httpError{status: http.StatusBadRequest,
+				message: fmt.Sprintf("limit must be between 1 and %d", maxAuditLimit)})
+			return
+		}
+		q.Limit = limit
+	}
+	if v := query.Get("itemId"); v != "" {
+		id, err := s.storeFor(r).ParseID(v)
+		if err != nil {
+			s.respondError(w, r, &httpError{status: http.StatusBadRequest, message: "itemId is not a valid item ID"})
+			return
+		}
+		q.ItemID = id
+	}
+	if v := query.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.respondError(w, r, &httpError{status: http.StatusBadRequest, message: "since must be an RFC 3339 time, e.g. 2026-01-02T15:04:05Z"})
+			return
+		}
+		q.Since = since
+	}
+
+	entries := s.audit.Entries(q)
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	s.writeJSON(w, r, http.StatusOK, auditResponse{Entries: entries})
+}
+
+// apiUndoHandler reverts the latest change to the store r addresses that has
+// not been undone yet, within the last --undo-depth changes
+func (s *Server) apiUndoHandler(w http.ResponseWriter, r *http.Request) {
+	if s.audit == nil || s.cfg.UndoDepth == 0 {
+		s.writeError(w, r, http.StatusNotFound, "undo is disabled")
+		return
+	}
+	undone, entry, err := s.audit.Undo(s.storeFor(r), s.auditSource(r), s.cfg.UndoDepth)
+	if errors.Is(err, audit.ErrNothingToUndo) || errors.Is(err, audit.ErrUndoConflict) {
+		err = &httpError{status: http.StatusConflict, message: err.Error()}
+	}
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, undoResponse{Undone: undone, Entry: entry})
+}
+
+// historyEntry is one change shown on an item's page
+type historyEntry struct {
+	Time      time.Time
+	Action    string
+	Source    string
+	RequestID string
+	// Changes lists the fields an update changed, e.g. "color: red → blue"
+	Changes []string
+}
+
+// itemHistory returns the latest changes to item id in the store r
+// addresses, newest first; nil when the audit log is disabled
+func (s *Server) itemHistory(r *http.Request, id itemstore.ID) []historyEntry {
+	if s.audit == nil {
+		return nil
+	}
+	q := audit.Query{ItemID: id, Limit: itemHistoryLimit}
+	if c := requestCollection(r); c != nil {
+		q.Collection = c.name
+	}
+	var history []historyEntry
+	for _, e := range s.audit.Entries(q) {
+		h := historyEntry{Time: e.Time, Source: e.Source, RequestID: e.RequestID}
+		switch e.Action {
+		case itemstore.ItemCreated:
+			h.Action = "Created"
+		case itemstore.ItemUpdated:
+			h.Action = "Updated"
+			h.Changes = itemChanges(*e.Before, *e.After)
+		case itemstore.ItemDeleted:
+			h.Action = "Deleted"
+		}
+		if e.Undoes != 0 {
+			h.Action += " by undo"
+		}
+		history = append(history, h)
+	}
+	return history
+}
+
+// itemChanges describes the fields that differ between before and after
+func itemChanges(before, after itemstore.Item) []string {
+	var changes []string
+	for _, field := range []struct{ name, before, after string }{
+		{"color", before.Color, after.Color},
+		{"shape", before.Shape, after.Shape},
+		{"category", before.Category, after.Category},
+	} {
+		if field.before != field.after {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", field.name, field.before, field.after))
+		}
+	}
+	return changes
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/audit"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// getAudit fetches target from srv and decodes the audit entries
+func getAudit(t *testing.T, srv *Server, target string) []audit.Entry {
+	t.Helper()
+	rec := getItems(t, srv, target)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s status = %d: %s", target, rec.Code, rec.Body)
+	}
+	var resp auditResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode %s: %v", target, err)
+	}
+	return resp.Entries
+}
+
+func TestAudit_APIUpdate(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.APIKeys = []string{"secret"}
+	srv := newTestServer(t, cfg)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/items/2", strings.NewReader(`{"color":"teal","shape":"square","category":"A"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Request-ID", "req-42")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", rec.Code, rec.Body)
+	}
+
+	entries := getAudit(t, srv, "/api/audit?itemId=2")
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1: %+v", len(entries), entries)
+	}
+	e := entries[0]
+	if e.Action != "item.updated" || e.ItemID != itemstore.IntID(2) || e.RequestID != "req-42" || e.Source != apiKeyName("secret") {
+		t.Errorf("entry = %+v, want an update of item 2 by the key in request req-42", e)
+	}
+	if e.Before == nil || e.Before.Color != "blue" || e.After == nil || e.After.Color != "teal" {
+		t.Errorf("entry before = %+v, after = %+v, want blue then teal", e.Before, e.After)
+	}
+	if !strings.HasPrefix(e.Source, "api-key:") || strings.Contains(e.Source, "secret") {
+		t.Errorf("source = %q, want the key's fingerprint", e.Source)
+	}
+}
+
+func TestAudit_Query(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AuditSize = 3
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+	for _, body := range []string{`{"color":"a","shape":"s","category":"C"}`, `{"color":"b","shape":"s","category":"C"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/api/items", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		srv.Handler().ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for _, id := range []string{"1", "4"} {
+		srv.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/api/items/"+id, nil))
+	}
+
+	// The first creation was evicted
+	entries := getAudit(t, srv, "/api/audit")
+	if len(entries) != 3 || entries[0].ItemID != itemstore.IntID(4) || entries[1].ItemID != itemstore.IntID(1) || entries[2].ItemID != itemstore.IntID(5) {
+		t.Fatalf("entries = %+v, want deletions of 4 and 1, then the creation of 5", entries)
+	}
+	for _, e := range entries {
+		if e.Source != "api" {
+			t.Errorf("entry %d source = %q, want api", e.Seq, e.Source)
+		}
+	}
+	if entries := getAudit(t, srv, "/api/audit?limit=1"); len(entries) != 1 || entries[0].Seq != 4 {
+		t.Errorf("limit=1 entries = %+v, want the newest", entries)
+	}
+	if entries := getAudit(t, srv, "/api/audit?itemId=4"); len(entries) != 1 || entries[0].Before == nil {
+		t.Errorf("itemId=4 entries = %+v, want its deletion", entries)
+	}
+	if entries := getAudit(t, srv, "/api/audit?since=2999-01-01T00:00:00Z"); entries == nil || len(entries) != 0 {
+		t.Errorf("future since entries = %+v, want none", entries)
+	}
+
+	for _, target := range []string{"/api/audit?limit=0", "/api/audit?limit=1001", "/api/audit?itemId=x", "/api/audit?since=yesterday", "/api/audit?sort=id"} {
+		if rec := getItems(t, srv, target); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s status = %d, want 400", target, rec.Code)
+		}
+	}
+
+	cfg.AuditSize = 0
+	if rec := getItems(t, newTestServer(t, cfg), "/api/audit"); rec.Code != http.StatusNotFound {
+		t.Errorf("disabled audit log status = %d, want 404", rec.Code)
+	}
+}
+
+func TestAudit_Collections(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newCollectionServer(t, cfg)
+	srv.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/api/c/a/items/1", nil))
+
+	if entries := getAudit(t, srv, "/api/audit"); len(entries) != 0 {
+		t.Errorf("default store entries = %+v, want none", entries)
+	}
+	entries := getAudit(t, srv, "/api/c/a/audit")
+	if len(entries) != 1 || entries[0].Collection != "a" || entries[0].Before.Color != "yellow" {
+		t.Errorf("collection a entries = %+v, want the deletion of its star", entries)
+	}
+	if entries := getAudit(t, srv, "/api/c/b/audit"); len(entries) != 0 {
+		t.Errorf("collection b entries = %+v, want none", entries)
+	}
+}
+
+func TestAudit_FormHistory(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+
+	body := getItems(t, srv, "/items/2/edit").Body.String()
+	if strings.Contains(body, "History") {
+		t.Error("edit page of an unchanged item shows a history")
+	}
+	rec := postForm(srv, "/items/2/edit", url.Values{"version": {formVersion(t, body)}, "color": {"teal"}, "shape": {"square"}, "category": {"A"}})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("POST edit status = %d", rec.Code)
+	}
+
+	entries := getAudit(t, srv, "/api/audit")
+	if len(entries) != 1 || entries[0].Source != "form" || entries[0].RequestID == "" {
+		t.Errorf("entries = %+v, want one form edit with a request ID", entries)
+	}
+	body = getItems(t, srv, "/items/2/edit").Body.String()
+	for _, want := range []string{"History", "Updated", "by form", "color: blue → teal"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("edit page does not show %q in the history", want)
+		}
+	}
+}
+
+func TestAudit_GraphQL(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"mutation { deleteItem(id: 3) }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "errors") {
+		t.Fatalf("mutation status = %d: %s", rec.Code, rec.Body)
+	}
+	entries := getAudit(t, srv, "/api/audit")
+	if len(entries) != 1 || entries[0].Action != "item.deleted" || entries[0].ItemID != itemstore.IntID(3) || entries[0].Source != "api" {
+		t.Errorf("entries = %+v, want the deletion of item 3 by the API", entries)
+	}
+}
+
+func TestAudit_Undo(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+	do := func(method, target string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/api/undo"); rec.Code != http.StatusConflict {
+		t.Errorf("undo with no changes status = %d, want 409", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/items/3"); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d", rec.Code)
+	}
+	rec := do(http.MethodPost, "/api/undo")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("undo status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp undoResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Undone.Action != "item.deleted" || resp.Entry.Action != "item.created" || resp.Entry.Undoes != resp.Undone.Seq {
+		t.Errorf("undo response = %+v, want the deletion reverted by a creation", resp)
+	}
+	if item, err := srv.store.Get(itemstore.IntID(3)); err != nil || item.Color != "green" {
+		t.Errorf("item 3 after undo = %+v, %v; want the green triangle back", item, err)
+	}
+	if entries := getAudit(t, srv, "/api/audit"); len(entries) != 2 || entries[0].Undoes != entries[1].Seq {
+		t.Errorf("audit entries = %+v, want the deletion and its undo", entries)
+	}
+	if rec := do(http.MethodPost, "/api/undo"); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "nothing to undo") {
+		t.Errorf("second undo = %d %s, want 409 nothing to undo", rec.Code, rec.Body)
+	}
+
+	cfg.UndoDepth = 0
+	srv = newTestServer(t, cfg)
+	if rec := do(http.MethodPost, "/api/undo"); rec.Code != http.StatusNotFound {
+		t.Errorf("disabled undo status = %d, want 404", rec.Code)
+	}
+}
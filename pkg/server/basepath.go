@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// url returns the public URL for an application path such as "/items" by
+// prepending the configured base path. Every link, redirect, and Location
+// header the server generates goes through here.
+func (s *Server) url(path string) string {
+	return s.cfg.BasePath + path
+}
+
+// stripBasePath removes the configured base path from incoming requests so
+// the router only ever sees application paths. Requests outside the base path
+// get a 404.
+func (s *Server) stripBasePath(next http.Handler) http.Handler {
+	base := s.cfg.BasePath
+	if base == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, base)
+		if !ok || (rest != "" && rest[0] != '/') {
+			s.notFoundHandler(w, r)
+			return
+		}
+		if rest == "" {
+			rest = "/"
+		}
+
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = rest
+		r2.URL.RawPath = ""
+		if raw, ok := strings.CutPrefix(r.URL.RawPath, base); ok && raw != "" {
+			r2.URL.RawPath = raw
+		}
+		next.ServeHTTP(w, r2)
+	})
+}
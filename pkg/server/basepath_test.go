@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBasePath(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.BasePath = "/dashboard"
+	cfg.AllowUnauthenticatedWrites = true
+	handler := newTestServer(t, cfg).Handler()
+
+	serve := func(method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("routes under the prefix", func(t *testing.T) {
+		for _, path := range []string{"/dashboard/items", "/dashboard/api/items", "/dashboard/healthz"} {
+			if rec := serve(http.MethodGet, path, "", nil); rec.Code != http.StatusOK {
+				t.Errorf("GET %s status = %d, want %d", path, rec.Code, http.StatusOK)
+			}
+		}
+	})
+
+	t.Run("paths outside the prefix", func(t *testing.T) {
+		for _, path := range []string{"/items", "/api/items", "/dashboardx/items"} {
+			if rec := serve(http.MethodGet, path, "", nil); rec.Code != http.StatusNotFound {
+				t.Errorf("GET %s status = %d, want %d", path, rec.Code, http.StatusNotFound)
+			}
+		}
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		for _, path := range []string{"/dashboard", "/dashboard/"} {
+			rec := serve(http.MethodGet, path, "", nil)
+			if got := rec.Header().Get("Location"); got != "/dashboard/items" {
+				t.Errorf("GET %s Location = %q, want /dashboard/items", path, got)
+			}
+		}
+	})
+
+	t.Run("location header", func(t *testing.T) {
+		rec := serve(http.MethodPost, "/dashboard/api/items", `{"color":"red","shape":"square","category":"C"}`,
+			map[string]string{"Content-Type": "application/json"})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+		}
+		if got := rec.Header().Get("Location"); got != "/dashboard/api/items/4" {
+			t.Errorf("Location = %q, want /dashboard/api/items/4", got)
+		}
+	})
+
+	t.Run("rendered links", func(t *testing.T) {
+		html := map[string]string{"Accept": "text/html"}
+		pages := map[string]string{
+			"/dashboard/items":   `href="/dashboard/version"`,
+			"/dashboard/missing": `href="/dashboard/items"`,
+		}
+		for path, want := range pages {
+			if body := serve(http.MethodGet, path, "", html).Body.String(); !strings.Contains(body, want) {
+				t.Errorf("GET %s body does not contain %s", path, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,364 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"runtime"
+	"sync"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// maxBulkItems bounds the number of items accepted by one bulk import
+const maxBulkItems = 10000
+
+// bulkResult reports the outcome for one item of a bulk import
+type bulkResult struct {
+	Index int `json:"index"`
+	// Line is the item's line in an NDJSON body
+	Line   int             `json:"line,omitempty"`
+	Status int             `json:"status"`
+	Item   *itemstore.Item `json:"item,omitempty"`
+	// Outcome is what a merge (onConflict) did with the item: added,
+	// unchanged, skipped, overwritten, or renumbered
+	Outcome itemstore.MergeOutcome `json:"outcome,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+	// Details lists each invalid field when the item failed validation
+	Details itemstore.ValidationErrors `json:"details,omitempty"`
+}
+
+// bulkResponse is the body returned by the bulk import endpoint
+type bulkResponse struct {
+	Mode string `json:"mode"`
+	// OnConflict is the merge strategy, if the import was a merge
+	OnConflict string       `json:"onConflict,omitempty"`
+	Created    int          `json:"created"`
+	Failed     int          `json:"failed"`
+	Results    []bulkResult `json:"results"`
+}
+
+// apiBulkCreateHandler imports a JSON array of items, or newline-delimited
+// JSON with one item per line. In the default "atomic" mode either every
+// item is stored or none are; "best-effort" stores the valid items and
+// reports the failures individually. A malformed NDJSON line fails its item
+// only, like an invalid item.
+//
+// Items are validated concurrently as they are decoded, then stored one by
+// one in input order, so assigned IDs and the report never depend on
+// scheduling. If the client goes away, nothing is stored in atomic mode; in
+// best-effort mode the items stored before the cancellation was noticed stay
+// stored, and they are always a prefix of the valid items in input order.
+//
+// With onConflict set, the valid items are merged into the store in one step
+// instead, with the strategy deciding what happens to an item whose ID is
+// taken; see mergeBulkItems.
+func (s *Server) apiBulkCreateHandler(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "atomic"
+	}
+	if mode != "atomic" && mode != "best-effort" {
+		s.respondError(w, r, &httpError{status: http.StatusBadRequest, message: "mode must be atomic or best-effort"})
+		return
+	}
+	var strategy *itemstore.MergeStrategy
+	if v := r.URL.Query().Get("onConflict"); v != "" {
+		m, err := itemstore.ParseMergeStrategy(v)
+		if err != nil {
+			s.respondError(w, r, &httpError{status: http.StatusBadRequest, message: "onConflict must be fail, skip, overwrite, or renumber"})
+			return
+		}
+		strategy = &m
+	}
+
+	store := s.writeStore(r)
+	checks, err := checkBulkItems(w, r, store, s.cfg.MaxBulkBodyBytes)
+	if err != nil {
+		s.respondBulkError(w, r, err)
+		return
+	}
+	if strategy != nil {
+		s.mergeBulkItems(w, r, store, checks, mode, *strategy)
+		return
+	}
+
+	resp := bulkResponse{Mode: mode, Results: make([]bulkResult, 0, len(checks))}
+
+	if mode == "atomic" {
+		items := make([]itemstore.Item, len(checks))
+		for i, c := range checks {
+			if c.err != nil {
+				s.respondError(w, r, bulkItemError(i, c))
+				return
+			}
+			items[i] = c.item
+		}
+		if err := r.Context().Err(); err != nil {
+			s.respondBulkError(w, r, err)
+			return
+		}
+		added, err := store.AddAll(items)
+		if err != nil {
+			s.respondError(w, r, err)
+			return
+		}
+		for i := range added {
+			resp.Results = append(resp.Results, bulkResult{Index: i, Line: checks[i].line, Status: http.StatusCreated, Item: &added[i]})
+		}
+		resp.Created = len(added)
+		s.writeJSON(w, r, http.StatusCreated, resp)
+		return
+	}
+
+	for i, c := range checks {
+		created, err := c.item, c.err
+		if err == nil {
+			if err := r.Context().Err(); err != nil {
+				s.respondBulkError(w, r, err)
+				return
+			}
+			created, err = store.Add(c.item)
+		}
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, itemstore.ErrDuplicateID) {
+				status = http.StatusConflict
+			}
+			result := bulkResult{Index: i, Line: c.line, Status: status, Error: err.Error()}
+			errors.As(err, &result.Details)
+			resp.Results = append(resp.Results, result)
+			resp.Failed++
+			continue
+		}
+		resp.Results = append(resp.Results, bulkResult{Index: i, Line: c.line, Status: http.StatusCreated, Item: &created})
+		resp.Created++
+	}
+
+	status := http.StatusCreated
+	if resp.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	s.writeJSON(w, r, status, resp)
+}
+
+// mergeBulkItems merges the checked items of a bulk import into store with
+// strategy. Invalid items fail the whole import in atomic mode and are
+// reported individually in best-effort mode; either way the valid items are
+// merged atomically, and with MergeFail a conflict leaves the store untouched
+// and is reported with 409. Each merged item is reported with its outcome:
+// 201 for items added under their own or a new ID, 200 for the others.
+func (s *Server) mergeBulkItems(w http.ResponseWriter, r *http.Request, store itemstore.Store, checks []*bulkCheck, mode string, strategy itemstore.MergeStrategy) {
+	resp := bulkResponse{Mode: mode, OnConflict: strategy.String(), Results: make([]bulkResult, len(checks))}
+	var items []itemstore.Item
+	// indexes maps the position of each merged item to its input index
+	var indexes []int
+	for i, c := range checks {
+		if c.err == nil {
+			items = append(items, c.item)
+			indexes = append(indexes, i)
+			continue
+		}
+		if mode == "atomic" {
+			s.respondError(w, r, bulkItemError(i, c))
+			return
+		}
+		result := bulkResult{Index: i, Line: c.line, Status: http.StatusBadRequest, Error: c.err.Error()}
+		errors.As(c.err, &result.Details)
+		resp.Results[i] = result
+		resp.Failed++
+	}
+	if err := r.Context().Err(); err != nil {
+		s.respondBulkError(w, r, err)
+		return
+	}
+
+	report, err := store.Merge(items, strategy)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	for _, result := range report.Results {
+		status := http.StatusOK
+		if result.Outcome == itemstore.MergeAdded || result.Outcome == itemstore.MergeRenumbered {
+			status = http.StatusCreated
+			resp.Created++
+		}
+		i := indexes[result.Index]
+		resp.Results[i] = bulkResult{Index: i, Line: checks[i].line, Status: status, Item: &result.Item, Outcome: result.Outcome}
+	}
+
+	status := http.StatusOK
+	if resp.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	s.writeJSON(w, r, status, resp)
+}
+
+// respondBulkError reports err from a bulk import. Once the request is
+// canceled there is no one left to answer, so the error is only logged.
+func (s *Server) respondBulkError(w http.ResponseWriter, r *http.Request, err error) {
+	if r.Context().Err() != nil {
+		s.logger.InfoContext(r.Context(), "bulk import canceled", "error", err, "request_id", RequestIDFromContext(r.Context()))
+		return
+	}
+	s.respondError(w, r, err)
+}
+
+// bulkCheck is one item of a bulk import, normalized, and the error from
+// decoding it or checking it against the store, if any
+type bulkCheck struct {
+	item itemstore.Item
+	err  error
+	// line is the item's line in an NDJSON body, or 0
+	line int
+}
+
+// bulkItemError reports the failed check c of the item at index i, naming
+// its line for NDJSON bodies
+func bulkItemError(i int, c *bulkCheck) error {
+	prefix := fmt.Sprintf("item at index %d: ", i)
+	if c.line > 0 {
+		prefix = fmt.Sprintf("item at index %d (line %d): ", i, c.line)
+	}
+	if he := (*httpError)(nil); errors.As(c.err, &he) {
+		return &httpError{status: he.status, message: prefix + he.message}
+	}
+	return fmt.Errorf("%s%w", prefix, c.err)
+}
+
+// checkBulkItems decodes the items in the request body, a JSON array,
+// NDJSON, or a YAML sequence, one item at a time, handing each to a pool of
+// workers that check it against store while decoding goes on. The checks
+// come back in input order however the workers were scheduled. If the
+// request's context is canceled, decoding stops, the workers drain, and the
+// context's error is returned.
+func checkBulkItems(w http.ResponseWriter, r *http.Request, store itemstore.Store, limit int64) ([]*bulkCheck, error) {
+	decode := decodeBulkItems
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case err == nil && mediaType == ndjsonContentType:
+		decode = decodeBulkLines
+	case err == nil && isYAMLMediaType(mediaType):
+		decode = decodeBulkYAML
+	case err != nil || mediaType != "application/json":
+		return nil, &httpError{status: http.StatusUnsupportedMediaType, message: "Content-Type must be application/json, " + ndjsonContentType + ", or " + yamlContentType}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+	jobs := make(chan *bulkCheck)
+	var wg sync.WaitGroup
+	for range runtime.GOMAXPROCS(0) {
+		wg.Go(func() {
+			for c := range jobs {
+				c.item, c.err = store.Check(c.item)
+			}
+		})
+	}
+	checks, err := decode(r.Context(), r.Body, jobs)
+	close(jobs)
+	wg.Wait()
+	return checks, err
+}
+
+// decodeBulkItems decodes a JSON array of at most maxBulkItems items from
+// body, sending each to jobs as soon as it is decoded
+func decodeBulkItems(ctx context.Context, body io.Reader, jobs chan<- *bulkCheck) ([]*bulkCheck, error) {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, jsonBodyError(err)
+	}
+	if tok != json.Delim('[') {
+		return nil, &httpError{status: http.StatusBadRequest, message: "request body must be a JSON array of items"}
+	}
+
+	var checks []*bulkCheck
+	for dec.More() {
+		if len(checks) == maxBulkItems {
+			return nil, &httpError{
+				status:  http.StatusRequestEntityTooLarge,
+				message: fmt.Sprintf("bulk import is limited to %d items", maxBulkItems),
+			}
+		}
+		c := new(bulkCheck)
+		if err := dec.Decode(&c.item); err != nil {
+			return nil, jsonBodyError(err)
+		}
+		checks = append(checks, c)
+		if err := sendBulkCheck(ctx, jobs, c); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, jsonBodyError(err)
+	}
+
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return nil, &httpError{status: http.StatusBadRequest, message: "request body must contain a single JSON value"}
+	}
+	return checks, nil
+}
+
+// decodeBulkLines decodes at most maxBulkItems items from body, one JSON
+// object per line, sending each to jobs as soon as it is decoded. Blank
+// lines are skipped. A malformed line fails only its own item, so it is
+// reported with its line number like an invalid item.
+func decodeBulkLines(ctx context.Context, body io.Reader, jobs chan<- *bulkCheck) ([]*bulkCheck, error) {
+	br := bufio.NewReader(body)
+	var checks []*bulkCheck
+	for line := 1; ; line++ {
+		data, readErr := br.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return nil, jsonBodyError(readErr)
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			if len(checks) == maxBulkItems {
+				return nil, &httpError{
+					status:  http.StatusRequestEntityTooLarge,
+					message: fmt.Sprintf("bulk import is limited to %d items", maxBulkItems),
+				}
+			}
+			c := &bulkCheck{line: line}
+			checks = append(checks, c)
+			if err := decodeBulkLine(data, &c.item); err != nil {
+				c.err = err
+			} else if err := sendBulkCheck(ctx, jobs, c); err != nil {
+				return nil, err
+			}
+		}
+		if readErr != nil {
+			return checks, nil
+		}
+	}
+}
+
+// decodeBulkLine decodes the one item on an NDJSON line into item
+func decodeBulkLine(data []byte, item *itemstore.Item) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(item); err != nil {
+		return &httpError{status: http.StatusBadRequest, message: "invalid JSON: " + err.Error()}
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return &httpError{status: http.StatusBadRequest, message: "line must contain a single JSON value"}
+	}
+	return nil
+}
+
+// sendBulkCheck hands c to the workers, unless ctx is done first
+func sendBulkCheck(ctx context.Context, jobs chan<- *bulkCheck, c *bulkCheck) error {
+	select {
+	case jobs <- c:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,413 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// bulkBody returns a bulk import body of n items: most without an ID, some
+// with explicit and clashing IDs, and every seventh invalid
+func bulkBody(n int) string {
+	items := make([]map[string]any, n)
+	for i := range items {
+		item := map[string]any{"color": fmt.Sprintf("c%d", i%13), "shape": "circle", "category": "A"}
+		switch {
+		case i%7 == 3:
+			item["color"] = ""
+		case i%11 == 5:
+			item["id"] = 100 + i%50
+		}
+		items[i] = item
+	}
+	b, _ := json.Marshal(items)
+	return string(b)
+}
+
+// postBulk sends body to the bulk import endpoint of srv with ctx
+func postBulk(ctx context.Context, srv *Server, mode, body string) *httptest.ResponseRecorder {
+	return postBulkAs(ctx, srv, mode, "application/json", body)
+}
+
+// postBulkAs is postBulk for a body of the given content type
+func postBulkAs(ctx context.Context, srv *Server, mode, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/items/bulk?mode="+mode, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	srv.routes().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPI_BulkCreateDeterministic(t *testing.T) {
+	body := bulkBody(3000)
+	var first bulkResponse
+	for run := range 10 {
+		rec := postBulk(t.Context(), newTestServer(t, testConfig(t)), "best-effort", body)
+		if rec.Code != http.StatusMultiStatus {
+			t.Fatalf("run %d: status = %d, want %d", run, rec.Code, http.StatusMultiStatus)
+		}
+		var resp bulkResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		// Only the creation times may differ between runs
+		for _, result := range resp.Results {
+			if result.Item != nil {
+				result.Item.CreatedAt = time.Time{}
+			}
+		}
+		if run == 0 {
+			first = resp
+			continue
+		}
+		if !reflect.DeepEqual(resp, first) {
+			t.Fatalf("run %d reported differently from the first run", run)
+		}
+	}
+
+	for i, result := range first.Results {
+		if result.Index != i {
+			t.Fatalf("Results[%d].Index = %d", i, result.Index)
+		}
+	}
+	if first.Failed == 0 || first.Created == 0 || first.Created+first.Failed != 3000 {
+		t.Errorf("created %d and failed %d, want a mix of 3000", first.Created, first.Failed)
+	}
+	if r := first.Results[3]; r.Status != http.StatusBadRequest || len(r.Details) != 1 || r.Details[0].Field != "color" {
+		t.Errorf("Results[3] = %+v, want a 400 about the color", r)
+	}
+}
+
+func TestAPI_BulkCreateAtomicReportsFirstInvalidItem(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	rec := postBulk(t.Context(), srv, "atomic", bulkBody(100))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(rec.Body.String(), "item at index 3:") {
+		t.Errorf("body = %s, want the error for index 3", rec.Body)
+	}
+	if srv.store.Len() != 3 {
+		t.Errorf("store Len() = %d, want 3", srv.store.Len())
+	}
+}
+
+func TestAPI_BulkCreateTooManyItems(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	body := "[" + strings.Repeat(`{"color":"red","shape":"circle","category":"A"},`, maxBulkItems) + `{"color":"red","shape":"circle","category":"A"}]`
+	if rec := postBulk(t.Context(), srv, "best-effort", body); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	if rec := postBulk(t.Context(), srv, "best-effort", `{"color":"red"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("object body status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAPI_BulkCreateCanceled(t *testing.T) {
+	body := bulkBody(500)
+
+	t.Run("while applying", func(t *testing.T) {
+		srv := newTestServer(t, testConfig(t))
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+		var stored int
+		srv.store.OnChange(func(itemstore.Change) {
+			if stored++; stored == 100 {
+				cancel()
+			}
+		})
+
+		rec := postBulk(ctx, srv, "best-effort", body)
+		if rec.Body.Len() != 0 {
+			t.Errorf("canceled import wrote %q", rec.Body)
+		}
+
+		// The stored items are exactly the first valid items of the request
+		want := newTestServer(t, testConfig(t))
+		full := postBulk(t.Context(), want, "best-effort", body)
+		var resp bulkResponse
+		if err := json.Unmarshal(full.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		var wantIDs []int
+		for _, result := range resp.Results {
+			if result.Item != nil && len(wantIDs) < 100 {
+				id, _ := result.Item.ID.Int()
+				wantIDs = append(wantIDs, id)
+			}
+		}
+		var gotIDs []int
+		for _, item := range srv.store.Filter(nil)[3:] {
+			id, _ := item.ID.Int()
+			gotIDs = append(gotIDs, id)
+		}
+		if !reflect.DeepEqual(gotIDs, wantIDs) {
+			t.Errorf("stored IDs = %v, want %v", gotIDs, wantIDs)
+		}
+	})
+
+	t.Run("while decoding", func(t *testing.T) {
+		for _, mode := range []string{"atomic", "best-effort"} {
+			srv := newTestServer(t, testConfig(t))
+			ctx, cancel := context.WithCancel(t.Context())
+			req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/items/bulk?mode="+mode,
+				&cancelingReader{r: strings.NewReader(body), after: len(body) / 2, cancel: cancel})
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			srv.routes().ServeHTTP(rec, req)
+
+			if srv.store.Len() != 3 {
+				t.Errorf("%s: store Len() = %d after canceling, want 3", mode, srv.store.Len())
+			}
+			if rec.Body.Len() != 0 {
+				t.Errorf("%s: canceled import wrote %q", mode, rec.Body)
+			}
+		}
+	})
+}
+
+// cancelingReader reads a request body in small chunks and calls cancel once
+// after bytes have been read
+type cancelingReader struct {
+	r      *strings.Reader
+	after  int
+	read   int
+	cancel context.CancelFunc
+}
+
+func (c *cancelingReader) Read(p []byte) (int, error) {
+	if len(p) > 64 {
+		p = p[:64]
+	}
+	n, err := c.r.Read(p)
+	if c.read += n; c.read >= c.after {
+		c.cancel()
+	}
+	return n, err
+}
+
+// BenchmarkAPIBulkCreate imports maxBulkItems items into an empty store
+func BenchmarkAPIBulkCreate(b *testing.B) {
+	body := bulkBody(maxBulkItems)
+	b.ReportAllocs()
+	for b.Loop() {
+		b.StopTimer()
+		store, err := itemstore.New(nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		srv, err := New(store, WithConfig(testConfig(b)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if rec := postBulk(b.Context(), srv, "best-effort", body); rec.Code != http.StatusMultiStatus {
+			b.Fatalf("status = %d", rec.Code)
+		}
+	}
+}
+
+func TestAPI_BulkMerge(t *testing.T) {
+	// Item 1 is unchanged, item 2 conflicts, and item 7 is new
+	body := `[{"id":1,"color":"red","shape":"circle","category":"A"},{"id":2,"color":"teal","shape":"square","category":"A"},{"id":7,"color":"pink","shape":"star","category":"C"}]`
+	tests := []struct {
+		onConflict string
+		wantStatus int
+		want       []itemstore.MergeOutcome
+		wantColor  string
+	}{
+		{"skip", http.StatusOK, []itemstore.MergeOutcome{"unchanged", "skipped", "added"}, "blue"},
+		{"overwrite", http.StatusOK, []itemstore.MergeOutcome{"unchanged", "overwritten", "added"}, "teal"},
+		{"renumber", http.StatusOK, []itemstore.MergeOutcome{"unchanged", "renumbered", "added"}, "blue"},
+		{"fail", http.StatusConflict, nil, "blue"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.onConflict, func(t *testing.T) {
+			srv := newTestServer(t, testConfig(t))
+			rec := postBulk(t.Context(), srv, "atomic&onConflict="+tt.onConflict, body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if item, _ := srv.store.Get(itemstore.IntID(2)); item.Color != tt.wantColor {
+				t.Errorf("item 2 = %+v, want %s", item, tt.wantColor)
+			}
+			if tt.want == nil {
+				if srv.store.Len() != 3 {
+					t.Errorf("store Len() = %d after a failed merge, want 3", srv.store.Len())
+				}
+				return
+			}
+			var resp bulkResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			var got []itemstore.MergeOutcome
+			for _, r := range resp.Results {
+				got = append(got, r.Outcome)
+			}
+			if !reflect.DeepEqual(got, tt.want) || resp.OnConflict != tt.onConflict {
+				t.Errorf("outcomes = %v (%s), want %v", got, resp.OnConflict, tt.want)
+			}
+			if _, err := srv.store.Get(itemstore.IntID(7)); err != nil {
+				t.Errorf("new item 7 was not added: %v", err)
+			}
+		})
+	}
+
+	// Best-effort merges the valid items and reports the others
+	srv := newTestServer(t, testConfig(t))
+	rec := postBulk(t.Context(), srv, "best-effort&onConflict=renumber", `[{"color":"","shape":"circle","category":"A"},{"id":3,"color":"gold","shape":"circle","category":"A"}]`)
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("best-effort status = %d, want 207: %s", rec.Code, rec.Body)
+	}
+	var resp bulkResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if r := resp.Results; len(r) != 2 || r[0].Status != http.StatusBadRequest || r[1].Index != 1 || r[1].Outcome != "renumbered" || r[1].Item.ID != itemstore.IntID(4) {
+		t.Errorf("results = %+v, want the invalid item and item 3 renumbered to 4", resp.Results)
+	}
+
+	if rec := postBulk(t.Context(), srv, "atomic&onConflict=replace", body); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown onConflict status = %d, want 400", rec.Code)
+	}
+}
+
+// ndjsonBody returns the items of a JSON array body one per line
+func ndjsonBody(t *testing.T, arrayBody string) string {
+	t.Helper()
+	var items []json.RawMessage
+	if err := json.Unmarshal([]byte(arrayBody), &items); err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	for _, item := range items {
+		b.Write(item)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// comparableBulk decodes a bulk import response without the fields that
+// differ between formats and runs: lines and creation times
+func comparableBulk(t *testing.T, rec *httptest.ResponseRecorder) bulkResponse {
+	t.Helper()
+	var resp bulkResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	for i := range resp.Results {
+		resp.Results[i].Line = 0
+		if item := resp.Results[i].Item; item != nil {
+			item.CreatedAt = time.Time{}
+		}
+	}
+	return resp
+}
+
+func TestAPI_BulkNDJSONMatchesArray(t *testing.T) {
+	mixed := bulkBody(300)
+	var valid []json.RawMessage
+	json.Unmarshal([]byte(mixed), &valid)
+	valid = slices.DeleteFunc(valid, func(item json.RawMessage) bool { return strings.Contains(string(item), `"color":""`) })
+	validBody, _ := json.Marshal(valid)
+
+	tests := []struct {
+		mode, body string
+	}{
+		{"best-effort", mixed},
+		{"atomic", mixed},
+		{"atomic", string(validBody)},
+		{"best-effort&onConflict=renumber", mixed},
+		{"atomic&onConflict=skip", string(validBody)},
+		{"atomic&onConflict=fail", string(validBody)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			array := postBulk(t.Context(), newTestServer(t, testConfig(t)), tt.mode, tt.body)
+			ndjson := postBulkAs(t.Context(), newTestServer(t, testConfig(t)), tt.mode, "application/x-ndjson", ndjsonBody(t, tt.body))
+			if array.Code != ndjson.Code {
+				t.Fatalf("array status %d, NDJSON status %d: %s", array.Code, ndjson.Code, ndjson.Body)
+			}
+			if array.Code >= 300 && array.Code != http.StatusMultiStatus {
+				// Errors name the same item, the NDJSON one with its line
+				var a, n errorResponse
+				json.Unmarshal(array.Body.Bytes(), &a)
+				json.Unmarshal(ndjson.Body.Bytes(), &n)
+				if got := regexp.MustCompile(` \(line \d+\)`).ReplaceAllString(n.Error, ""); got != a.Error {
+					t.Errorf("NDJSON error %q, array error %q", n.Error, a.Error)
+				}
+				return
+			}
+			if a, n := comparableBulk(t, array), comparableBulk(t, ndjson); !reflect.DeepEqual(a, n) {
+				t.Errorf("NDJSON import reported differently from the array import")
+			}
+			var resp bulkResponse
+			json.Unmarshal(ndjson.Body.Bytes(), &resp)
+			for _, result := range resp.Results {
+				if result.Line != result.Index+1 {
+					t.Fatalf("result %d has line %d, want %d", result.Index, result.Line, result.Index+1)
+				}
+			}
+		})
+	}
+}
+
+func TestAPI_BulkNDJSONMalformedLines(t *testing.T) {
+	body := `{"color":"red","shape":"circle","category":"A"}
+
+{"color":"blue","shape":
+{"color":"green","shape":"square","category":"B","size":"L"}
+{"color":"red","shape":"circle","category":"A"} {"color":"red"}
+{"color":"gray","shape":"circle","category":"C"}`
+	srv := newTestServer(t, testConfig(t))
+	rec := postBulkAs(t.Context(), srv, "best-effort", "application/x-ndjson; charset=utf-8", body)
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp bulkResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	type outcome struct {
+		line, status int
+		error        string
+	}
+	want := []outcome{
+		{1, http.StatusCreated, ""},
+		{3, http.StatusBadRequest, "invalid JSON"},
+		{4, http.StatusBadRequest, "unknown field"},
+		{5, http.StatusBadRequest, "single JSON value"},
+		{6, http.StatusCreated, ""},
+	}
+	if len(resp.Results) != len(want) || resp.Created != 2 || resp.Failed != 3 {
+		t.Fatalf("results = %+v, want %d", resp.Results, len(want))
+	}
+	for i, w := range want {
+		got := resp.Results[i]
+		if got.Index != i || got.Line != w.line || got.Status != w.status || !strings.Contains(got.Error, w.error) {
+			t.Errorf("result %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	rec = postBulkAs(t.Context(), newTestServer(t, testConfig(t)), "atomic", "application/x-ndjson", body)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "item at index 1 (line 3): invalid JSON") {
+		t.Errorf("atomic import = %d %s, want the error for line 3", rec.Code, rec.Body)
+	}
+
+	many := strings.Repeat(`{"color":"red","shape":"circle","category":"A"}`+"\n", maxBulkItems+1)
+	if rec := postBulkAs(t.Context(), srv, "best-effort", "application/x-ndjson", many); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("%d lines status = %d, want 413", maxBulkItems+1, rec.Code)
+	}
+	if rec := postBulkAs(t.Context(), srv, "best-effort", "text/csv", body); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("text/csv status = %d, want 415", rec.Code)
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/palette"
+)
+
+// apiChartParams are the query parameters understood by GET /api/charts/{property}
+var apiChartParams = []string{"filter", "filterBy", "filterValue", "strict", "type"}
+
+// chartTypes are the values accepted by the type parameter. Pie charts get
+// percentages and bar charts get raw counts.
+var chartTypes = []string{"bar", "pie"}
+
+// chartResponse is one series in the shape chart libraries take directly:
+// Data[i] and Colors[i] belong to Labels[i]
+type chartResponse struct {
+	Labels []string  `json:"labels"`
+	Data   []float64 `json:"data"`
+	Colors []string  `json:"colors"`
+}
+
+func (s *Server) apiChartHandler(w http.ResponseWriter, r *http.Request) {
+	property := r.PathValue("property")
+	if !itemstore.IsProperty(property) {
+		s.respondError(w, r, &httpError{status: http.StatusBadRequest,
+			message: "invalid chart property: " + property + " (supported: color, shape, category)"})
+		return
+	}
+	chartType := r.URL.Query().Get("type")
+	if chartType == "" {
+		chartType = "bar"
+	}
+	if !slices.Contains(chartTypes, chartType) {
+		s.respondError(w, r, &httpError{status: http.StatusBadRequest,
+			message: fmt.Sprintf("invalid chart type: %s (supported: %s)", chartType, strings.Join(chartTypes, ", "))})
+		return
+	}
+	query, err := parseItemsQuery(fillDefaults(s.cfg.Defaults, r.URL.Query(), apiChartParams), apiChartParams, true)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+
+	items, err := s.storeFor(r).FilterContext(r.Context(), query.Filters)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	groups, err := itemstore.GroupBy(r.Context(), items, property)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, s.chartData(groups, property, chartType))
+}
+
+// chartData builds a chart of the number of items in each group, with labels
+// sorted. The "color" property is drawn in the colors it names; other values
+// get a series color that depends only on the value.
+func (s *Server) chartData(groups map[string][]itemstore.Item, property, chartType string) chartResponse {
+	chart := chartResponse{
+		Labels: slices.AppendSeq(make([]string, 0, len(groups)), maps.Keys(groups)),
+		Data:   make([]float64, 0, len(groups)),
+		Colors: make([]string, 0, len(groups)),
+	}
+	slices.Sort(chart.Labels)
+	total := 0
+	for _, items := range groups {
+		total += len(items)
+	}
+	for _, label := range chart.Labels {
+		value := float64(len(groups[label]))
+		if chartType == "pie" {
+			// Percentages are rounded to two decimals
+			value = math.Round(value/float64(total)*10000) / 100
+		}
+		chart.Data = append(chart.Data, value)
+		if property == "color" {
+			chart.Colors = append(chart.Colors, s.palette.Hex(label))
+		} else {
+			chart.Colors = append(chart.Colors, palette.Series(property+":"+label))
+		}
+	}
+	return chart
+}
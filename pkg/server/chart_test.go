@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// getChart requests target from srv and decodes a successful chart response
+func getChart(t *testing.T, srv *Server, target string) chartResponse {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	srv.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s status = %d, want %d: %s", target, rec.Code, http.StatusOK, rec.Body)
+	}
+	var chart chartResponse
+	if err := json.NewDecoder(rec.Body).Decode(&chart); err != nil {
+		t.Fatalf("Failed to decode chart: %v", err)
+	}
+	if len(chart.Data) != len(chart.Labels) || len(chart.Colors) != len(chart.Labels) {
+		t.Fatalf("GET %s: %d labels, %d data, %d colors; want them aligned",
+			target, len(chart.Labels), len(chart.Data), len(chart.Colors))
+	}
+	return chart
+}
+
+func TestChart(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	tests := []struct {
+		target     string
+		wantLabels []string
+		wantData   []float64
+	}{
+		{"/api/charts/category", []string{"A", "B"}, []float64{2, 1}},
+		{"/api/charts/category?type=bar", []string{"A", "B"}, []float64{2, 1}},
+		{"/api/charts/category?type=pie", []string{"A", "B"}, []float64{66.67, 33.33}},
+		{"/api/charts/shape?filter=category:A", []string{"circle", "square"}, []float64{1, 1}},
+		{"/api/charts/color?type=pie&filter=color:red", []string{"red"}, []float64{100}},
+		{"/api/charts/color?filter=color:purple", []string{}, []float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			chart := getChart(t, srv, tt.target)
+			if !reflect.DeepEqual(chart.Labels, tt.wantLabels) {
+				t.Errorf("labels = %q, want %q", chart.Labels, tt.wantLabels)
+			}
+			if !reflect.DeepEqual(chart.Data, tt.wantData) {
+				t.Errorf("data = %v, want %v", chart.Data, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestChart_Colors(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	colors := getChart(t, srv, "/api/charts/color")
+	want := map[string]string{"blue": "#0000ff", "green": "#008000", "red": "#ff0000"}
+	for i, label := range colors.Labels {
+		if colors.Colors[i] != want[label] {
+			t.Errorf("color of %s = %q, want %q", label, colors.Colors[i], want[label])
+		}
+	}
+
+	// A value keeps its color across calls and regardless of what else the
+	// chart shows
+	all := getChart(t, srv, "/api/charts/shape")
+	if again := getChart(t, srv, "/api/charts/shape"); !reflect.DeepEqual(again.Colors, all.Colors) {
+		t.Errorf("colors changed between calls: %q, then %q", all.Colors, again.Colors)
+	}
+	filtered := getChart(t, srv, "/api/charts/shape?filter=shape:square")
+	for i, label := range all.Labels {
+		if label == "square" && filtered.Colors[0] != all.Colors[i] {
+			t.Errorf("square is %q when filtered, %q otherwise", filtered.Colors[0], all.Colors[i])
+		}
+	}
+}
+
+func TestChart_Invalid(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	for _, target := range []string{
+		"/api/charts/size",
+		"/api/charts/id",
+		"/api/charts/color?type=line",
+		"/api/charts/color?groupBy=shape",
+	} {
+		rec := httptest.NewRecorder()
+		srv.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s status = %d, want %d", target, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/events"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+const (
+	// collectionPagePrefix and collectionAPIPrefix are followed by the
+	// collection name and then the path of a page or API route
+	collectionPagePrefix = "/c/"
+	collectionAPIPrefix  = "/api/c/"
+)
+
+// collection is a named item store served alongside the default one. Its
+// pages live under /c/{name}/ and its JSON API under /api/c/{name}/.
+type collection struct {
+	name  string
+	store itemstore.Store
+	// hub broadcasts the collection's changes to its /api/events
+	// subscribers
+	hub *events.Hub
+}
+
+// collectionKey is the context key of the collection a request addresses
+type collectionKey struct{}
+
+// addCollection registers store under name. It must be called before the
+// server starts handling requests.
+func (s *Server) addCollection(name string, store itemstore.Store) error {
+	if !config.ValidCollectionName(name) {
+		return fmt.Errorf("collection name %q must be lower-case letters, digits, dashes, and underscores", name)
+	}
+	if _, dup := s.collections[name]; dup {
+		return fmt.Errorf("collection %q is defined more than once", name)
+	}
+	if s.collections == nil {
+		s.collections = make(map[string]*collection)
+	}
+	c := &collection{name: name, store: store, hub: events.NewHub(sseClientBuffer)}
+	publishChanges(store, c.hub)
+	s.collections[name] = c
+	return nil
+}
+
+// requestCollection returns the collection r addresses, or nil for the
+// default store
+func requestCollection(r *http.Request) *collection {
+	c, _ := r.Context().Value(collectionKey{}).(*collection)
+	return c
+}
+
+// storeFor returns the store r addresses
+func (s *Server) storeFor(r *http.Request) itemstore.Store {
+	if c := requestCollection(r); c != nil {
+		return c.store
+	}
+	return s.store
+}
+
+// hubFor returns the event hub of the store r addresses
+func (s *Server) hubFor(r *http.Request) *events.Hub {
+	if c := requestCollection(r); c != nil {
+		return c.hub
+	}
+	return s.hub
+}
+
+// scopedPath maps the path of a route to the same route in the collection r
+// addresses, e.g. "/items" to "/c/inventory/items" and "/api/items" to
+// "/api/c/inventory/items". Paths are returned unchanged for the default
+// store. The base path is not included.
+func scopedPath(r *http.Request, path string) string {
+	c := requestCollection(r)
+	if c == nil {
+		return path
+	}
+	if rest, ok := strings.CutPrefix(path, "/api/"); ok {
+		return collectionAPIPrefix + c.name + "/" + rest
+	}
+	return collectionPagePrefix + c.name + path
+}
+
+// scopedURL is scopedPath with the base path prepended
+func (s *Server) scopedURL(r *http.Request, path string) string {
+	return s.url(scopedPath(r, path))
+}
+
+// unscopedPath maps a collection route back to the default store's route, so
+// path-based policies apply to both alike
+func unscopedPath(path string) string {
+	if rest, ok := strings.CutPrefix(path, collectionAPIPrefix); ok {
+		if _, route, ok := strings.Cut(rest, "/"); ok {
+			return "/api/" + route
+		}
+	}
+	return path
+}
+
+// serveCollection returns the handler of a collection's routes under prefix.
+// The collection is added to the request context and the prefix and name are
+// stripped, so routes serves the request as if it were for the default
+// store. API routes are only reachable under collectionAPIPrefix, where the
+// API key and rate limit middleware see them.
+func (s *Server) serveCollection(prefix string, routes http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, ok := s.collections[r.PathValue("collection")]
+		if !ok {
+			s.notFoundHandler(w, r)
+			return
+		}
+		rest := strings.TrimPrefix(r.URL.Path, prefix+c.name)
+		if prefix == collectionAPIPrefix {
+			rest = "/api" + rest
+		} else if isAPIPath(rest) {
+			s.notFoundHandler(w, r)
+			return
+		}
+
+		r2 := r.Clone(context.WithValue(r.Context(), collectionKey{}, c))
+		r2.URL.Path = rest
+		r2.URL.RawPath = ""
+		routes.ServeHTTP(w, r2)
+	})
+}
+
+// collectionRoutes registers the routes each collection offers: the item
+// pages and forms, the feed, and the JSON API
+func (s *Server) collectionRoutes() *http.ServeMux {
+	mux := s.newRouteMux()
+	mux.HandleFunc("/{$}", s.indexHandler)
+	s.registerItemRoutes(mux)
+	mux.handleAPIRoutes(s.apiRoutes())
+	mux.HandleFunc("/", s.notFoundHandler)
+	return mux.ServeMux
+}
+
+// collectionSummary is one entry of the collection index
+type collectionSummary struct {
+	Name  string
+	Link  string
+	Count int
+}
+
+// collectionsIndexHandler lists the default store, every collection, and the
+// saved views
+func (s *Server) collectionsIndexHandler(w http.ResponseWriter, r *http.Request) {
+	summaries := []collectionSummary{{Name: "default", Link: s.url("/items"), Count: s.store.Len()}}
+	for _, name := range slices.Sorted(maps.Keys(s.collections)) {
+		summaries = append(summaries, collectionSummary{
+			Name:  name,
+			Link:  s.url(collectionPagePrefix + name + "/items"),
+			Count: s.collections[name].store.Len(),
+		})
+	}
+	s.render(w, r, http.StatusOK, "collections.html", struct {
+		pageData
+		Collections []collectionSummary
+		Views       []savedView
+	}{pageData: s.pageData(r), Collections: summaries, Views: s.savedViews()})
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// newCollectionServer returns a test server with collections "a", holding a
+// single yellow star, and "b", holding a single purple hexagon
+func newCollectionServer(t *testing.T, cfg config.Config) *Server {
+	t.Helper()
+	srv := newTestServer(t, cfg)
+	for name, item := range map[string]itemstore.Item{
+		"a": {ID: itemstore.IntID(1), Color: "yellow", Shape: "star", Category: "X"},
+		"b": {ID: itemstore.IntID(1), Color: "purple", Shape: "hexagon", Category: "Y"},
+	} {
+		store, err := itemstore.New([]itemstore.Item{item})
+		if err != nil {
+			t.Fatalf("itemstore.New() error = %v", err)
+		}
+		if err := srv.addCollection(name, store); err != nil {
+			t.Fatalf("addCollection(%q) error = %v", name, err)
+		}
+	}
+	return srv
+}
+
+func TestCollections_Pages(t *testing.T) {
+	srv := newCollectionServer(t, testConfig(t))
+
+	body := getItems(t, srv, "/c/a/items").Body.String()
+	if !strings.Contains(body, "yellow") || strings.Contains(body, "purple") || strings.Contains(body, "item-2 ") {
+		t.Error("/c/a/items does not list just collection a's item")
+	}
+	// Links stay within the collection
+	if !strings.Contains(body, `href="/c/a/items?`) || strings.Contains(body, `href="/items?`) {
+		t.Error("/c/a/items links out of the collection")
+	}
+
+	body = getItems(t, srv, "/c/b/items").Body.String()
+	if !strings.Contains(body, "purple") || strings.Contains(body, "yellow") {
+		t.Error("/c/b/items does not list just collection b's item")
+	}
+
+	body = getItems(t, srv, "/items").Body.String()
+	if !strings.Contains(body, "item-3 ") || strings.Contains(body, "yellow") {
+		t.Error("/items does not list the default store")
+	}
+}
+
+func TestCollections_Index(t *testing.T) {
+	srv := newCollectionServer(t, testConfig(t))
+	rec := getItems(t, srv, "/")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET / status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{`href="/items"`, `href="/c/a/items"`, `href="/c/b/items"`, "3 items", "1 item<"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("collection index does not contain %s", want)
+		}
+	}
+
+	rec = getItems(t, srv, "/c/a/")
+	if loc := rec.Header().Get("Location"); rec.Code != http.StatusFound || loc != "/c/a/items" {
+		t.Errorf("GET /c/a/ = %d to %q, want a redirect to /c/a/items", rec.Code, loc)
+	}
+}
+
+func TestCollections_API(t *testing.T) {
+	srv := newCollectionServer(t, testConfig(t))
+	router := srv.routes()
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	list := func(target string) []itemstore.Item {
+		t.Helper()
+		rec := do(http.MethodGet, target, "")
+		var resp itemListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode GET %s: %v", target, err)
+		}
+		return resp.Items
+	}
+
+	if items := list("/api/c/a/items"); len(items) != 1 || items[0].Shape != "star" {
+		t.Errorf("GET /api/c/a/items = %+v, want the star", items)
+	}
+	if items := list("/api/c/b/items"); len(items) != 1 || items[0].Shape != "hexagon" {
+		t.Errorf("GET /api/c/b/items = %+v, want the hexagon", items)
+	}
+
+	rec := do(http.MethodPost, "/api/c/a/items", `{"color":"blue","shape":"circle","category":"C"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/api/c/a/items/2" {
+		t.Errorf("Location = %q, want /api/c/a/items/2", loc)
+	}
+	if n := len(list("/api/c/a/items")); n != 2 {
+		t.Errorf("collection a has %d items after POST, want 2", n)
+	}
+	if n := len(list("/api/c/b/items")); n != 1 {
+		t.Errorf("collection b has %d items after POST to a, want 1", n)
+	}
+	if n := srv.store.Len(); n != 3 {
+		t.Errorf("default store has %d items after POST to a, want 3", n)
+	}
+
+	for _, target := range []string{"/c/missing/items", "/api/c/missing/items", "/c/a/api/items", "/api/c/a/nope"} {
+		if rec := do(http.MethodGet, target, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s status = %d, want %d", target, rec.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestCollections_APIKey(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.APIKeys = []string{"secret"}
+	srv := newCollectionServer(t, cfg)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/c/a/items", strings.NewReader(`{"color":"blue","shape":"circle","category":"C"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("POST without a key status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAddCollection_Invalid(t *testing.T) {
+	srv := newCollectionServer(t, testConfig(t))
+	store, _ := itemstore.New(nil)
+	for _, name := range []string{"a", "", "A", "x/y", "-x"} {
+		if err := srv.addCollection(name, store); err == nil {
+			t.Errorf("addCollection(%q) succeeded, want an error", name)
+		}
+	}
+}
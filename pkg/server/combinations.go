@@ -0,0 +1,74 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// apiCombinationsParams are the query parameters understood by GET
+// /api/combinations
+var apiCombinationsParams = []string{"by"}
+
+// maxCombinationProperties caps the by parameter. Each property can be named
+// only once, so this is every property.
+const maxCombinationProperties = 3
+
+// combination is one distinct tuple of values and its number of items
+type combination struct {
+	// Values line up with the by list of the response
+	Values []string `json:"values"`
+	Count  int      `json:"count"`
+}
+
+// combinationsResponse is the body of GET /api/combinations
+type combinationsResponse struct {
+	By           []string      `json:"by"`
+	Combinations []combination `json:"combinations"`
+}
+
+// parseCombinationProperties parses the comma-separated by parameter
+func parseCombinationProperties(raw string) ([]string, error) {
+	fail := func(message string) error {
+		return &httpError{status: http.StatusBadRequest, message: message}
+	}
+	if raw == "" {
+		return nil, fail("by is required: a comma-separated list of color, shape, and category")
+	}
+	props := strings.Split(raw, ",")
+	if len(props) > maxCombinationProperties {
+		return nil, fail(fmt.Sprintf("by names %d properties, at most %d are allowed", len(props), maxCombinationProperties))
+	}
+	for i, property := range props {
+		if !itemstore.IsProperty(property) {
+			return nil, fail(fmt.Sprintf("invalid by property %q (supported: color, shape, category)", property))
+		}
+		if slices.Contains(props[:i], property) {
+			return nil, fail(fmt.Sprintf("by names %q more than once", property))
+		}
+	}
+	return props, nil
+}
+
+// apiCombinationsHandler counts the items with each distinct combination of
+// values of the properties in by, most common first
+func (s *Server) apiCombinationsHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	if err := checkQueryParams(params, apiCombinationsParams); err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	props, err := parseCombinationProperties(params.Get("by"))
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	resp := combinationsResponse{By: props, Combinations: []combination{}}
+	for _, c := range s.storeFor(r).Combinations(props...) {
+		resp.Combinations = append(resp.Combinations, combination{Values: c.Values, Count: c.Count})
+	}
+	s.writeJSON(w, r, http.StatusOK, resp)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+func TestAPI_Combinations(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	// A second red circle makes one combination more common than the rest
+	if _, err := srv.store.Add(itemstore.Item{Color: "red", Shape: "circle", Category: "B"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		by   string
+		want []combination
+	}{
+		{"color,shape", []combination{
+			{[]string{"red", "circle"}, 2},
+			{[]string{"blue", "square"}, 1},
+			{[]string{"green", "triangle"}, 1},
+		}},
+		// Ties are in order of values
+		{"category", []combination{{[]string{"A"}, 2}, {[]string{"B"}, 2}}},
+		{"shape,category,color", []combination{
+			{[]string{"circle", "A", "red"}, 1},
+			{[]string{"circle", "B", "red"}, 1},
+			{[]string{"square", "A", "blue"}, 1},
+			{[]string{"triangle", "B", "green"}, 1},
+		}},
+	}
+	for _, tt := range tests {
+		rec := getItems(t, srv, "/api/combinations?by="+tt.by)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("by=%s: status = %d: %s", tt.by, rec.Code, rec.Body)
+		}
+		var got combinationsResponse
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		want := combinationsResponse{By: strings.Split(tt.by, ","), Combinations: tt.want}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("by=%s: got %+v, want %+v", tt.by, got, want)
+		}
+	}
+}
+
+func TestAPI_CombinationsEmptyStore(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	store, err := itemstore.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.store = store
+
+	rec := getItems(t, srv, "/api/combinations?by=color,shape")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"combinations":[]`) {
+		t.Errorf("empty store: %d %s, want an empty list", rec.Code, rec.Body)
+	}
+}
+
+func TestAPI_CombinationsInvalid(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	for target, want := range map[string]string{
+		"/api/combinations":                               "by is required",
+		"/api/combinations?by=":                           "by is required",
+		"/api/combinations?by=color,weight":               `invalid by property \"weight\"`,
+		"/api/combinations?by=color,":                     `invalid by property \"\"`,
+		"/api/combinations?by=color,shape,color":          `by names \"color\" more than once`,
+		"/api/combinations?by=color,shape,category,color": "at most 3",
+		"/api/combinations?by=color&filter=category:A":    "unknown query parameters: filter",
+		"/api/combinations?by=Color":                      `invalid by property \"Color\"`,
+	} {
+		rec := getItems(t, srv, target)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("GET %s = %d %s, want 400 mentioning %q", target, rec.Code, rec.Body, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+var (
+	// compareParams are the query parameters understood by /compare and
+	// GET /api/compare
+	compareParams = []string{"left", "right"}
+	// compareSideParams are the parameters the query of each side may use
+	compareSideParams = []string{"filter", "filterBy", "filterValue", "q"}
+)
+
+// compareCounts are the sizes of the sets of a comparison
+type compareCounts struct {
+	OnlyLeft  int `json:"onlyLeft"`
+	OnlyRight int `json:"onlyRight"`
+	Both      int `json:"both"`
+}
+
+// compareResponse is the body of GET /api/compare
+type compareResponse struct {
+	OnlyLeft  []itemstore.Item `json:"onlyLeft"`
+	OnlyRight []itemstore.Item `json:"onlyRight"`
+	Both      []itemstore.Item `json:"both"`
+	Counts    compareCounts    `json:"counts"`
+}
+
+// parseCompareSide parses the query of one side of a comparison, which uses
+// the filter parameters of /items. Errors name the side.
+func parseCompareSide(side, raw string) (itemsQuery, error) {
+	fail := func(message string) error {
+		return &httpError{status: http.StatusBadRequest, message: side + ": " + message}
+	}
+	values, err := url.ParseQuery(strings.TrimPrefix(raw, "?"))
+	if err != nil {
+		return itemsQuery{}, fail("not a valid query string")
+	}
+	query, err := parseItemsQuery(values, compareSideParams, true)
+	if he := (*httpError)(nil); errors.As(err, &he) {
+		return itemsQuery{}, fail(he.message)
+	}
+	if err != nil {
+		return itemsQuery{}, err
+	}
+	if he := (*httpError)(nil); errors.As(checkFilterProperties(values), &he) {
+		return itemsQuery{}, fail(he.message)
+	}
+	return query, nil
+}
+
+// compare splits the items of the store r addresses by whether they match
+// the left query, the right query, or both. An empty query matches every
+// item. When both queries are invalid, both are reported.
+func (s *Server) compare(r *http.Request) (compareResponse, error) {
+	params := r.URL.Query()
+	if err := checkQueryParams(params, compareParams); err != nil {
+		return compareResponse{}, err
+	}
+	left, leftErr := parseCompareSide("left", params.Get("left"))
+	right, rightErr := parseCompareSide("right", params.Get("right"))
+	var messages []string
+	for _, err := range []error{leftErr, rightErr} {
+		if he := (*httpError)(nil); errors.As(err, &he) {
+			messages = append(messages, he.message)
+		} else if err != nil {
+			return compareResponse{}, err
+		}
+	}
+	if len(messages) > 0 {
+		return compareResponse{}, &httpError{status: http.StatusBadRequest, message: strings.Join(messages, "; ")}
+	}
+
+	store := s.storeFor(r)
+	leftItems, err := store.FilterContext(r.Context(), left.Filters)
+	if err != nil {
+		return compareResponse{}, err
+	}
+	rightItems, err := store.FilterContext(r.Context(), right.Filters)
+	if err != nil {
+		return compareResponse{}, err
+	}
+	c := itemstore.Compare(searchItems(leftItems, left.Search), searchItems(rightItems, right.Search))
+	resp := compareResponse{
+		OnlyLeft:  c.OnlyLeft,
+		OnlyRight: c.OnlyRight,
+		Both:      c.Both,
+		Counts:    compareCounts{OnlyLeft: len(c.OnlyLeft), OnlyRight: len(c.OnlyRight), Both: len(c.Both)},
+	}
+	// Empty sets are [] rather than null
+	for _, set := range []*[]itemstore.Item{&resp.OnlyLeft, &resp.OnlyRight, &resp.Both} {
+		if *set == nil {
+			*set = []itemstore.Item{}
+		}
+	}
+	return resp, nil
+}
+
+// apiCompareHandler compares the items matching two queries
+func (s *Server) apiCompareHandler(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.compare(r)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	s.writeJSON(w, r, http.StatusOK, resp)
+}
+
+// comparePage is the data of the /compare page
+type comparePage struct {
+	pageData
+	Left, Right string
+	// Error explains why the queries could not be compared
+	Error  string
+	Result compareResponse
+}
+
+// compareHandler shows the items matching two queries side by side: those
+// only the left one matches, those both match, and those only the right one
+// matches. Invalid queries are shown with the error above the form.
+func (s *Server) compareHandler(w http.ResponseWriter, r *http.Request) {
+	page := comparePage{pageData: s.pageData(r), Left: r.URL.Query().Get("left"), Right: r.URL.Query().Get("right")}
+	resp, err := s.compare(r)
+	if he := (*httpError)(nil); errors.As(err, &he) {
+		page.Error = he.message
+		s.render(w, r, he.status, "compare.html", page)
+		return
+	}
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	page.Result = resp
+	s.render(w, r, http.StatusOK, "compare.html", page)
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// compareTarget returns the path comparing the left and right queries
+func compareTarget(path, left, right string) string {
+	return path + "?" + url.Values{"left": {left}, "right": {right}}.Encode()
+}
+
+func itemIDs(items []itemstore.Item) []int {
+	ids := []int{}
+	for _, item := range items {
+		id, _ := item.ID.Int()
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestAPI_Compare(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	tests := []struct {
+		name                      string
+		left, right               string
+		onlyLeft, both, onlyRight []int
+	}{
+		// Category A is items 1 and 2; red is item 1
+		{"overlapping", "filter=category:A", "filter=color:red", []int{2}, []int{1}, []int{}},
+		{"disjoint", "filter=category:A", "filter=category:B", []int{1, 2}, []int{}, []int{3}},
+		{"identical", "filter=shape:square", "?filterBy=shape&filterValue=square", []int{}, []int{2}, []int{}},
+		{"search and everything", "q=gre", "", []int{}, []int{3}, []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := getItems(t, srv, compareTarget("/api/compare", tt.left, tt.right))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			var resp compareResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(itemIDs(resp.OnlyLeft), tt.onlyLeft) || !slices.Equal(itemIDs(resp.Both), tt.both) || !slices.Equal(itemIDs(resp.OnlyRight), tt.onlyRight) {
+				t.Errorf("onlyLeft %v, both %v, onlyRight %v; want %v, %v, %v",
+					itemIDs(resp.OnlyLeft), itemIDs(resp.Both), itemIDs(resp.OnlyRight), tt.onlyLeft, tt.both, tt.onlyRight)
+			}
+			want := compareCounts{OnlyLeft: len(tt.onlyLeft), Both: len(tt.both), OnlyRight: len(tt.onlyRight)}
+			if resp.Counts != want {
+				t.Errorf("counts = %+v, want %+v", resp.Counts, want)
+			}
+		})
+	}
+}
+
+func TestAPI_CompareInvalid(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{compareTarget("/api/compare", "filter=weight:5", "filter=color:red"), []string{"left: invalid filter"}},
+		{compareTarget("/api/compare", "filter=color:red", "sortBy=id"), []string{"right: unknown query parameters: sortBy"}},
+		{compareTarget("/api/compare", "filter=size:L", "filterBy=size&filterValue=L"), []string{"left: invalid filter", "right: invalid filterBy"}},
+		{compareTarget("/api/compare", "%zz", ""), []string{"left: not a valid query string"}},
+		{"/api/compare?left=&middle=", []string{"unknown query parameters: middle"}},
+	}
+	for _, tt := range tests {
+		rec := getItems(t, srv, tt.target)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s status = %d, want 400", tt.target, rec.Code)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("GET %s body = %s, want it to mention %q", tt.target, rec.Body, want)
+			}
+		}
+	}
+}
+
+func TestComparePage(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	rec := getItems(t, srv, compareTarget("/compare", "filter=category:A", "filter=color:red"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"Only left (1)", "#2 Blue square", "Both (1)", "#1 Red circle", "Only right (0)", `value="filter=category:A"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("compare page does not contain %q", want)
+		}
+	}
+
+	rec = getItems(t, srv, compareTarget("/compare", "filter=weight:5", ""))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "left: invalid filter") || !strings.Contains(rec.Body.String(), `value="filter=weight:5"`) {
+		t.Errorf("invalid compare page = %d, want the form again with the error:\n%s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// responseEncoding is how a response body is encoded
+type responseEncoding int
+
+const (
+	encodingJSON responseEncoding = iota
+	encodingMsgpack
+	encodingYAML
+)
+
+// encodingNames are the format parameter values naming each encoding
+var encodingNames = map[string]responseEncoding{
+	"json":    encodingJSON,
+	"msgpack": encodingMsgpack,
+	"yaml":    encodingYAML,
+}
+
+// encodingMediaTypes maps the media types an Accept header may list to
+// their encodings
+var encodingMediaTypes = map[string]responseEncoding{
+	"application/json":      encodingJSON,
+	msgpackContentType:      encodingMsgpack,
+	"application/x-msgpack": encodingMsgpack,
+	yamlContentType:         encodingYAML,
+	"application/x-yaml":    encodingYAML,
+	"text/yaml":             encodingYAML,
+}
+
+// encodingKey holds the responseEncoding negotiated for a request
+type encodingKey struct{}
+
+// negotiateEncoding returns r marked with the encoding its responses,
+// errors included, should use: the one named by ?format=json|msgpack|yaml,
+// or else the first supported one its Accept header lists, or else JSON
+func negotiateEncoding(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	w.Header().Add("Vary", "Accept")
+	encoding := encodingJSON
+	if format := r.URL.Query().Get("format"); format != "" {
+		var ok bool
+		if encoding, ok = encodingNames[format]; !ok {
+			return r, &httpError{status: http.StatusBadRequest, message: fmt.Sprintf("invalid format %q, want json, msgpack, or yaml", format)}
+		}
+	} else {
+		encoding = acceptedEncoding(r.Header.Get("Accept"))
+	}
+	if encoding == encodingJSON {
+		return r, nil
+	}
+	return r.WithContext(context.WithValue(r.Context(), encodingKey{}, encoding)), nil
+}
+
+// acceptedEncoding returns the encoding of the first supported media type an
+// Accept header lists, or JSON
+func acceptedEncoding(accept string) responseEncoding {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		if encoding, ok := encodingMediaTypes[mediaType]; ok {
+			return encoding
+		}
+	}
+	return encodingJSON
+}
+
+// requestEncoding returns the encoding negotiateEncoding chose for r
+func requestEncoding(r *http.Request) responseEncoding {
+	encoding, _ := r.Context().Value(encodingKey{}).(responseEncoding)
+	return encoding
+}
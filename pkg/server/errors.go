@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// errorTemplate is the HTML error page rendered by renderError
+const errorTemplate = "error.html"
+
+// errorResponse is the JSON envelope returned for every API error
+type errorResponse struct {
+	Error     string `json:"error"`
+	Status    int    `json:"status"`
+	RequestID string `json:"requestId,omitempty"`
+	// Details lists each invalid field of a rejected item
+	Details itemstore.ValidationErrors `json:"details,omitempty"`
+}
+
+// writeError writes a JSON error envelope with the given status code
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	s.writeErrorResponse(w, r, errorResponse{Error: message, Status: status})
+}
+
+// writeErrorResponse writes resp as the JSON error envelope, or in
+// MessagePack or YAML for a request that asked for it, filling in the
+// request ID
+func (s *Server) writeErrorResponse(w http.ResponseWriter, r *http.Request, resp errorResponse) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	resp.RequestID = RequestIDFromContext(r.Context())
+	switch requestEncoding(r) {
+	case encodingMsgpack:
+		s.writeMsgpack(w, r, resp.Status, appendMsgpackError(nil, resp))
+		return
+	case encodingYAML:
+		s.writeYAML(w, r, resp.Status, resp)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.Status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.logger.WarnContext(r.Context(), "failed to write error response", "error", err, "request_id", resp.RequestID)
+	}
+}
+
+// wantsHTML reports whether the client asked for an HTML page rather than JSON.
+// Anything under /api/ is always treated as an API request.
+func wantsHTML(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// notFoundHandler renders the 404 page for browsers and the JSON error
+// envelope for everything else
+func (s *Server) notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	if !wantsHTML(r) {
+		s.writeError(w, r, http.StatusNotFound, "not found: "+r.URL.Path)
+		return
+	}
+
+	s.render(w, r, http.StatusNotFound, "404.html", struct {
+		pageData
+		Path string
+	}{pageData: s.pageData(r), Path: r.URL.Path})
+}
+
+// internalErrorMessage is the only description of an internal error a client
+// ever sees; the real error is logged with the request ID
+const internalErrorMessage = "Something went wrong on our end."
+
+// renderError reports an error to the client: browsers get the HTML error
+// page and everything else gets the JSON envelope. publicMessage is shown
+// verbatim, so callers log the underlying error themselves and never pass
+// err.Error() from a template or store failure.
+func (s *Server) renderError(w http.ResponseWriter, r *http.Request, status int, publicMessage string) {
+	if !wantsHTML(r) {
+		s.writeError(w, r, status, publicMessage)
+		return
+	}
+	s.renderErrorPage(w, r, status, publicMessage)
+}
+
+// renderErrorPage renders the HTML error page with the status, the public
+// message, and the request ID
+func (s *Server) renderErrorPage(w http.ResponseWriter, r *http.Request, status int, publicMessage string) {
+	data := struct {
+		pageData
+		Status     int
+		StatusText string
+		Message    string
+		RequestID  string
+	}{
+		pageData:   s.pageData(r),
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Message:    publicMessage,
+		RequestID:  RequestIDFromContext(r.Context()),
+	}
+	s.render(w, r, status, errorTemplate, data)
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// failingStore fails every write with an error that must never reach a client
+type failingStore struct {
+	itemstore.Store
+}
+
+func (failingStore) Add(itemstore.Item) (itemstore.Item, error) {
+	return itemstore.Item{}, errors.New("write /var/lib/dashboard/items.db: disk full")
+}
+
+func TestRespondError_InternalDetailsHidden(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+	logs := captureLogs(srv)
+	srv.store = failingStore{Store: srv.store}
+
+	t.Run("API", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/items", strings.NewReader(`{"color":"red","shape":"circle","category":"A"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "text/html")
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want 500", rec.Code)
+		}
+		var envelope errorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+			t.Fatalf("API errors must stay JSON, got %q: %v", rec.Body, err)
+		}
+		if envelope.Error != internalErrorMessage || strings.Contains(rec.Body.String(), "disk full") {
+			t.Errorf("body = %s, want only the public message", rec.Body)
+		}
+	})
+
+	t.Run("browser", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set("Accept", "text/html")
+		req = req.WithContext(context.WithValue(req.Context(), requestIDKey{}, "req-123"))
+		srv.respondError(rec, req, errors.New("template: items.html:12: secret detail"))
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want 500", rec.Code)
+		}
+		body := rec.Body.String()
+		if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+			t.Errorf("Content-Type = %q, want the HTML error page", rec.Header().Get("Content-Type"))
+		}
+		for _, want := range []string{internalErrorMessage, "req-123"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("error page does not contain %q", want)
+			}
+		}
+		if strings.Contains(body, "secret detail") || strings.Contains(body, "items.html") {
+			t.Errorf("error page leaks the internal error:\n%s", body)
+		}
+	})
+
+	if !strings.Contains(logs.String(), "disk full") || !strings.Contains(logs.String(), "secret detail") {
+		t.Errorf("internal errors were not logged: %s", logs)
+	}
+}
+
+func TestRenderError_ClientErrors(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/items?strict=1&secret=1", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "<title>400 Bad Request</title>") {
+		t.Errorf("bad query = %d, want the 400 error page:\n%s", rec.Code, rec.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/items/99", nil)
+	req.Header.Set("Accept", "text/html")
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	var envelope errorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil || envelope.Status != http.StatusNotFound {
+		t.Errorf("API 404 = %d %s, want the JSON envelope", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/events"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+const (
+	// sseHeartbeatInterval is how often an idle event stream sends a comment
+	// so proxies don't close the connection
+	sseHeartbeatInterval = 15 * time.Second
+	// sseClientBuffer is the number of events queued per client before the
+	// oldest are dropped
+	sseClientBuffer = 64
+)
+
+// publishChanges forwards every store mutation to the event hub
+func publishChanges(store itemstore.Store, hub *events.Hub) {
+	store.OnChange(func(c itemstore.Change) {
+		hub.Publish(events.Event{Type: string(c.Type), Data: itemResponse{Item: c.Item}})
+	})
+}
+
+// apiEventsHandler streams item changes as Server-Sent Events. A "snapshot"
+// event with every item is sent first, followed by one event per mutation.
+func (s *Server) apiEventsHandler(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// The stream outlives the server's write timeout by design
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.respondError(w, r, err)
+		return
+	}
+
+	// Subscribe before taking the snapshot so no mutation falls in between
+	sub := s.hubFor(r).Subscribe()
+	defer s.hubFor(r).Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	items := s.storeFor(r).Filter(nil)
+	snapshot := itemListResponse{Items: items, Meta: listMeta{Count: len(items)}}
+	if err := writeSSE(w, "snapshot", snapshot); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		s.logger.WarnContext(r.Context(), "event stream cannot be flushed", "error", err, "request_id", RequestIDFromContext(r.Context()))
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			err = writeSSE(w, e.Type, e.Data)
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// writeSSE writes one event with a JSON-encoded data line
+func writeSSE(w http.ResponseWriter, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
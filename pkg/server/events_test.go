@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// sseEvent is one parsed Server-Sent Event
+type sseEvent struct {
+	name, data string
+}
+
+// readSSE parses events from r onto the returned channel until r fails
+func readSSE(r *bufio.Reader) <-chan sseEvent {
+	ch := make(chan sseEvent)
+	go func() {
+		defer close(ch)
+		var e sseEvent
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\n")
+			switch {
+			case line == "":
+				if e.name != "" {
+					ch <- e
+				}
+				e = sseEvent{}
+			case strings.HasPrefix(line, "event: "):
+				e.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				e.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return ch
+}
+
+func TestAPIEvents(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/events: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+	stream := readSSE(bufio.NewReader(resp.Body))
+
+	next := func() sseEvent {
+		t.Helper()
+		select {
+		case e := <-stream:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for an event")
+			return sseEvent{}
+		}
+	}
+
+	snapshot := next()
+	var list itemListResponse
+	if snapshot.name != "snapshot" || json.Unmarshal([]byte(snapshot.data), &list) != nil || list.Meta.Count != 3 {
+		t.Fatalf("first event = %+v, want a snapshot of 3 items", snapshot)
+	}
+
+	post, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/items", strings.NewReader(`{"color":"red","shape":"square","category":"C"}`))
+	post.Header.Set("Content-Type", "application/json")
+	postResp, err := http.DefaultClient.Do(post)
+	if err != nil {
+		t.Fatalf("POST /api/items: %v", err)
+	}
+	postResp.Body.Close()
+
+	created := next()
+	var item itemResponse
+	if created.name != "item.created" || json.Unmarshal([]byte(created.data), &item) != nil || item.Item.ID != itemstore.IntID(4) {
+		t.Errorf("event = %+v, want item.created for item 4", created)
+	}
+
+	// Disconnecting must unsubscribe the client and end the handler
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.hub.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("hub still has %d subscribers after the client disconnected", srv.hub.Len())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// exportParams are the query parameters understood by /items/export: those
+// of /items, so a page's query exports what it shows, plus the format
+var exportParams = slices.Concat(itemsPageParams, []string{"format"})
+
+// exportFormat is one file format /items/export writes
+type exportFormat struct {
+	contentType string
+	write       func(io.Writer, []itemstore.Item) error
+}
+
+// exportFormats are the formats /items/export writes, by format parameter
+var exportFormats = map[string]exportFormat{
+	"csv":  {"text/csv; charset=utf-8", itemstore.WriteCSV},
+	"json": {"application/json", itemstore.WriteJSON},
+	"xlsx": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", itemstore.WriteXLSX},
+}
+
+// exportHandler downloads the items /items would show for the same query,
+// filtered, searched, and sorted but not grouped, as a csv, json, or xlsx
+// file named after the time of the export
+func (s *Server) exportHandler(w http.ResponseWriter, r *http.Request) {
+	params := fillDefaults(s.cfg.Defaults, r.URL.Query(), exportParams)
+	query, err := parseItemsQuery(params, exportParams, true)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	name := params.Get("format")
+	format, ok := exportFormats[name]
+	if !ok {
+		s.respondError(w, r, &httpError{status: http.StatusBadRequest, message: fmt.Sprintf("invalid format %q, want csv, json, or xlsx", name)})
+		return
+	}
+
+	items, err := s.storeFor(r).FilterContext(r.Context(), query.Filters)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	items = searchItems(items, query.Search)
+	if query.SortBy != "" {
+		if err := itemstore.Sort(r.Context(), items, query.SortBy, query.Descending); err != nil {
+			s.respondError(w, r, err)
+			return
+		}
+	}
+
+	filename := fmt.Sprintf("items-%s.%s", time.Now().UTC().Format("20060102-150405"), name)
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if err := format.write(w, items); err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to export items", "format", name, "error", err, "request_id", RequestIDFromContext(r.Context()))
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestExport_XLSX(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	rec := getItems(t, srv, "/items/export?format=xlsx&filter=category:A&sortBy=id&order=desc")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Type"); got != exportFormats["xlsx"].contentType {
+		t.Errorf("Content-Type = %q", got)
+	}
+	disposition := rec.Header().Get("Content-Disposition")
+	if !regexp.MustCompile(`^attachment; filename="items-\d{8}-\d{6}\.xlsx"$`).MatchString(disposition) {
+		t.Errorf("Content-Disposition = %q, want a timestamped xlsx attachment", disposition)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("not a zip file: %v", err)
+	}
+	f, err := zr.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet := string(data)
+
+	// Category A sorted by descending ID is item 2, then item 1
+	second := strings.Index(sheet, `<c r="A2"><v>2</v></c>`)
+	first := strings.Index(sheet, `<c r="A3"><v>1</v></c>`)
+	if second < 0 || first < second {
+		t.Errorf("sheet does not list item 2 then item 1:\n%s", sheet)
+	}
+	for _, want := range []string{`<t xml:space="preserve">blue</t>`, `<t xml:space="preserve">red</t>`, `<autoFilter ref="A1:E3"/>`} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet does not contain %s", want)
+		}
+	}
+	if strings.Contains(sheet, "green") {
+		t.Error("sheet contains the filtered out green item")
+	}
+}
+
+func TestExport_Formats(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	rec := getItems(t, srv, "/items/export?format=csv&q=gre")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Disposition"), `attachment; filename="items-`) {
+		t.Fatalf("csv export = %d %q", rec.Code, rec.Header().Get("Content-Disposition"))
+	}
+	if lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[1], "3,green,triangle,B,") {
+		t.Errorf("csv export = %q, want the header and item 3", rec.Body)
+	}
+
+	rec = getItems(t, srv, "/items/export?format=json")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" || strings.Count(rec.Body.String(), `"id"`) != 3 {
+		t.Errorf("json export = %d %q:\n%s", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+
+	for _, target := range []string{"/items/export", "/items/export?format=pdf", "/items/export?format=csv&size=L", "/items/export?format=csv&sortBy=weight"} {
+		if rec := getItems(t, srv, target); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s status = %d, want 400", target, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+const (
+	// feedPath serves the Atom feed of recently created items
+	feedPath = "/feed.atom"
+	// feedSize is the number of entries in the feed
+	feedSize = 20
+)
+
+// feedParams are the query parameters understood by /feed.atom: the usual
+// filters plus one shorthand per property, e.g. ?category=A
+var feedParams = []string{"filter", "filterBy", "filterValue", "strict", "color", "shape", "category"}
+
+// atomFeed is an Atom feed document (RFC 4287)
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Link      atomLink    `xml:"link"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// feedHandler serves the feedSize most recently created items, newest first,
+// as an Atom feed. Items without a creation time are left out.
+func (s *Server) feedHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q, err := parseItemsQuery(query, feedParams, true)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	for _, prop := range []string{"color", "shape", "category"} {
+		if v := query.Get(prop); v != "" {
+			q.Filters[prop] = v
+		}
+	}
+
+	items, err := s.storeFor(r).FilterContext(r.Context(), q.Filters)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	items = slices.DeleteFunc(items, func(item itemstore.Item) bool {
+		return item.CreatedAt.IsZero()
+	})
+	slices.SortFunc(items, func(a, b itemstore.Item) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return b.ID.Compare(a.ID)
+	})
+	items = items[:min(len(items), feedSize)]
+
+	self := s.externalURL(r, scopedPath(r, feedPath))
+	if r.URL.RawQuery != "" {
+		self += "?" + r.URL.RawQuery
+	}
+	updated := s.startedAt
+	if len(items) > 0 {
+		updated = items[0].CreatedAt
+	}
+	feed := atomFeed{
+		ID:      self,
+		Title:   "Dashboard: recently added items",
+		Updated: atomTime(updated),
+		Author:  atomPerson{Name: "Dashboard"},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: self},
+			{Rel: "alternate", Type: "text/html", Href: s.externalURL(r, scopedPath(r, "/items"))},
+		},
+	}
+	for _, item := range items {
+		f := item.Format()
+		title := fmt.Sprintf("%s %s (category %s)", format.Title(f.Color), f.Shape, f.Category)
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        feedEntryID(r, item.ID),
+			Title:     title,
+			Updated:   atomTime(item.CreatedAt),
+			Published: atomTime(item.CreatedAt),
+			Link: atomLink{Rel: "alternate", Type: "application/json",
+				Href: s.externalURL(r, scopedPath(r, itemAPIPath(item.ID)))},
+			Content: atomContent{Type: "text",
+				Body: fmt.Sprintf("Item %s: %s", item.ID, title)},
+		})
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(body)
+}
+
+// feedEntryID identifies an item across feeds. IDs are only unique within a
+// store, so items of a collection include its name.
+func feedEntryID(r *http.Request, id itemstore.ID) string {
+	if c := requestCollection(r); c != nil {
+		return fmt.Sprintf("urn:dashboard:%s:item:%s", c.name, url.PathEscape(id.String()))
+	}
+	return "urn:dashboard:item:" + url.PathEscape(id.String())
+}
+
+// externalURL returns the absolute URL of path as the client sees it,
+// including the base path
+func (s *Server) externalURL(r *http.Request, path string) string {
+	return s.requestScheme(r) + "://" + r.Host + s.url(path)
+}
+
+// atomTime formats t as an RFC 3339 date-time in UTC
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
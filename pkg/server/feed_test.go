@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// addItemsCreatedAt adds one item per category to srv's store, each created
+// an hour after the previous one, starting at base
+func addItemsCreatedAt(t *testing.T, srv *Server, base time.Time, categories ...string) {
+	t.Helper()
+	for i, category := range categories {
+		_, err := srv.store.Add(itemstore.Item{Color: "red", Shape: "circle", Category: category,
+			CreatedAt: base.Add(time.Duration(i) * time.Hour)})
+		if err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+}
+
+func getFeed(t *testing.T, srv *Server, target string) (*httptest.ResponseRecorder, atomFeed) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	var feed atomFeed
+	if rec.Code == http.StatusOK {
+		if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+			t.Fatalf("Failed to parse feed: %v\n%s", err, rec.Body)
+		}
+	}
+	return rec, feed
+}
+
+func TestFeedHandler(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	addItemsCreatedAt(t, srv, base, "A", "B", "A")
+
+	rec, feed := getFeed(t, srv, "/feed.atom")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+		t.Errorf("Content-Type = %q, want application/atom+xml", ct)
+	}
+	if feed.XMLName.Space != "http://www.w3.org/2005/Atom" {
+		t.Errorf("feed namespace = %q, want the Atom namespace", feed.XMLName.Space)
+	}
+	if feed.ID == "" || feed.Title == "" || feed.Author.Name == "" {
+		t.Errorf("feed is missing required elements: %+v", feed)
+	}
+	if want := "2024-05-01T14:00:00Z"; feed.Updated != want {
+		t.Errorf("feed updated = %q, want the newest item's %q", feed.Updated, want)
+	}
+
+	// The store's three seed items have no creation time and are left out
+	var ids []string
+	for _, e := range feed.Entries {
+		if e.Title == "" || e.Updated == "" || e.Content.Body == "" {
+			t.Errorf("entry is missing required elements: %+v", e)
+		}
+		ids = append(ids, e.ID)
+	}
+	want := []string{"urn:dashboard:item:6", "urn:dashboard:item:5", "urn:dashboard:item:4"}
+	if strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Errorf("entry IDs = %v, want %v", ids, want)
+	}
+	if got := feed.Entries[1].Title; got != "Red circle (category B)" {
+		t.Errorf("entry title = %q, want Red circle (category B)", got)
+	}
+}
+
+func TestFeedHandler_Filtered(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	addItemsCreatedAt(t, srv, base, "A", "B", "A")
+
+	for _, target := range []string{"/feed.atom?category=B", "/feed.atom?filter=category:B"} {
+		rec, feed := getFeed(t, srv, target)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200: %s", target, rec.Code, rec.Body)
+		}
+		if len(feed.Entries) != 1 || feed.Entries[0].ID != "urn:dashboard:item:5" {
+			t.Errorf("%s: entries = %+v, want only item 5", target, feed.Entries)
+		}
+		if feed.Updated != "2024-05-01T13:00:00Z" {
+			t.Errorf("%s: feed updated = %q, want item 5's", target, feed.Updated)
+		}
+	}
+
+	if rec, _ := getFeed(t, srv, "/feed.atom?size=5"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown parameter status = %d, want 400", rec.Code)
+	}
+}
+
+func TestFeedHandler_Limit(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	categories := make([]string, feedSize+5)
+	for i := range categories {
+		categories[i] = "A"
+	}
+	addItemsCreatedAt(t, srv, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), categories...)
+
+	_, feed := getFeed(t, srv, "/feed.atom")
+	if len(feed.Entries) != feedSize {
+		t.Fatalf("feed has %d entries, want %d", len(feed.Entries), feedSize)
+	}
+	if got := feed.Entries[0].ID; got != "urn:dashboard:item:28" {
+		t.Errorf("first entry = %s, want the newest item", got)
+	}
+}
@@ -0,0 +1,372 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// itemFormFields are the item properties edited through the HTML forms, in
+// display order
+var itemFormFields = []string{"color", "shape", "category"}
+
+// maxFormFieldLength bounds each submitted property value
+const maxFormFieldLength = 64
+
+// itemFormPage is the data rendered by item_form.html
+type itemFormPage struct {
+	pageData
+	Title  string
+	Action string
+	Submit string
+	Fields []itemFormField
+	// FormError describes a problem that is not tied to one field
+	FormError string
+	// Version is the edited item's itemVersion when the form was rendered;
+	// empty on the add form
+	Version string
+	// DeleteAction links to the delete confirmation; empty on the add form
+	DeleteAction string
+	// History is the edited item's latest changes; empty on the add form
+	History []historyEntry
+}
+
+// itemFormField is one input of the item form. Options are the values
+// already in use, offered as suggestions next to free-text entry.
+type itemFormField struct {
+	Name    string
+	Value   string
+	Error   string
+	Options []string
+}
+
+// newItemFormPage builds the add form with the given values and per-field
+// errors
+func (s *Server) newItemFormPage(r *http.Request, values, fieldErrors map[string]string) itemFormPage {
+	page := itemFormPage{pageData: s.pageData(r), Title: "Add item", Action: "/items", Submit: "Add item"}
+	s.addFormFields(r, &page, values, fieldErrors)
+	return page
+}
+
+// editItemFormPage builds the edit form for item id with the given values,
+// version, and per-field errors
+func (s *Server) editItemFormPage(r *http.Request, id itemstore.ID, version string, values, fieldErrors map[string]string) itemFormPage {
+	page := itemFormPage{
+		pageData:     s.pageData(r),
+		Title:        fmt.Sprintf("Edit item #%s", id),
+		Action:       itemPath(id, "edit"),
+		Submit:       "Save changes",
+		Version:      version,
+		DeleteAction: itemPath(id, "delete"),
+		History:      s.itemHistory(r, id),
+	}
+	s.addFormFields(r, &page, values, fieldErrors)
+	return page
+}
+
+// addFormFields fills in the page's inputs, suggesting the values in use in
+// the store r addresses
+func (s *Server) addFormFields(r *http.Request, page *itemFormPage, values, fieldErrors map[string]string) {
+	for _, name := range itemFormFields {
+		page.Fields = append(page.Fields, itemFormField{
+			Name:    name,
+			Value:   values[name],
+			Error:   fieldErrors[name],
+			Options: s.storeFor(r).GetUniqueValues(name),
+		})
+	}
+}
+
+// newItemFormHandler renders the empty add form
+func (s *Server) newItemFormHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.formWritesAllowed(w, r) {
+		return
+	}
+	s.render(w, r, http.StatusOK, "item_form.html", s.newItemFormPage(r, nil, nil))
+}
+
+// createItemFormHandler adds the item submitted by the add form and
+// redirects to the dashboard, which confirms it with the "added" parameter.
+// Invalid submissions re-render the form with the values kept and an error
+// next to each field at fault.
+func (s *Server) createItemFormHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.formWritesAllowed(w, r) {
+		return
+	}
+	values, err := parseItemForm(w, r, s.cfg.MaxBodyBytes)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+
+	if fieldErrors := validateItemForm(values); len(fieldErrors) > 0 {
+		s.render(w, r, http.StatusUnprocessableEntity, "item_form.html", s.newItemFormPage(r, values, fieldErrors))
+		return
+	}
+
+	created, err := s.writeStore(r).Add(itemstore.Item{
+		Color:    values["color"],
+		Shape:    values["shape"],
+		Category: values["category"],
+	})
+	if errors.Is(err, itemstore.ErrInvalidItem) {
+		fieldErrors := storeFieldErrors(err)
+		page := s.newItemFormPage(r, values, fieldErrors)
+		if len(fieldErrors) == 0 {
+			page.FormError = err.Error()
+		}
+		s.render(w, r, http.StatusUnprocessableEntity, "item_form.html", page)
+		return
+	}
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, s.scopedURL(r, "/items?added="+url.QueryEscape(created.ID.String())), http.StatusSeeOther)
+}
+
+// editItemFormHandler renders the edit form pre-filled with the item's
+// current values
+func (s *Server) editItemFormHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.formWritesAllowed(w, r) {
+		return
+	}
+	item, err := s.formItem(r)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	s.render(w, r, http.StatusOK, "item_form.html", s.editItemFormPage(r, item.ID, itemVersion(item), itemFormValues(item), nil))
+}
+
+// updateItemFormHandler applies the submitted edit form. The form carries
+// the version of the item it was rendered from; if the item has changed
+// since, the form is shown again with the current values and a conflict
+// message rather than overwriting someone else's edit. The store checks the
+// version again as it updates, which catches an edit saved in between.
+func (s *Server) updateItemFormHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.formWritesAllowed(w, r) {
+		return
+	}
+	current, err := s.formItem(r)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	values, err := parseItemForm(w, r, s.cfg.MaxBodyBytes)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+
+	version := r.PostForm.Get("version")
+	if version != itemVersion(current) {
+		s.renderEditConflict(w, r, current)
+		return
+	}
+	if fieldErrors := validateItemForm(values); len(fieldErrors) > 0 {
+		page := s.editItemFormPage(r, current.ID, itemVersion(current), values, fieldErrors)
+		s.render(w, r, http.StatusUnprocessableEntity, "item_form.html", page)
+		return
+	}
+
+	_, err = s.writeStore(r).UpdateIf(itemstore.Item{
+		ID:       current.ID,
+		Color:    values["color"],
+		Shape:    values["shape"],
+		Category: values["category"],
+	}, func(stored itemstore.Item) bool {
+		return itemVersion(stored) == version
+	})
+	if errors.Is(err, itemstore.ErrConflict) {
+		if current, err = s.storeFor(r).Get(current.ID); err != nil {
+			s.respondError(w, r, err)
+			return
+		}
+		s.renderEditConflict(w, r, current)
+		return
+	}
+	if errors.Is(err, itemstore.ErrInvalidItem) {
+		fieldErrors := storeFieldErrors(err)
+		page := s.editItemFormPage(r, current.ID, itemVersion(current), values, fieldErrors)
+		if len(fieldErrors) == 0 {
+			page.FormError = err.Error()
+		}
+		s.render(w, r, http.StatusUnprocessableEntity, "item_form.html", page)
+		return
+	}
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, s.scopedURL(r, "/items?updated="+url.QueryEscape(current.ID.String())), http.StatusSeeOther)
+}
+
+// renderEditConflict shows the edit form again with current, the item as
+// someone else saved it, and asks for the changes to be made again
+func (s *Server) renderEditConflict(w http.ResponseWriter, r *http.Request, current itemstore.Item) {
+	page := s.editItemFormPage(r, current.ID, itemVersion(current), itemFormValues(current), nil)
+	page.FormError = "Someone else edited this item while you were working on it. " +
+		"Their version is shown below; make your changes again and save."
+	s.render(w, r, http.StatusConflict, "item_form.html", page)
+}
+
+// deleteItemConfirmHandler asks for confirmation before deleting an item
+func (s *Server) deleteItemConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.formWritesAllowed(w, r) {
+		return
+	}
+	item, err := s.formItem(r)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	s.render(w, r, http.StatusOK, "item_delete.html", struct {
+		pageData
+		Item itemstore.Item
+	}{pageData: s.pageData(r), Item: item})
+}
+
+// deleteItemFormHandler deletes the item once confirmed and redirects to the
+// dashboard
+func (s *Server) deleteItemFormHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.formWritesAllowed(w, r) {
+		return
+	}
+	id, err := s.pathItemID(r)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	if err := s.writeStore(r).Delete(id); err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, s.scopedURL(r, "/items?deleted="+url.QueryEscape(id.String())), http.StatusSeeOther)
+}
+
+// formItem returns the item named by the {id} path segment
+func (s *Server) formItem(r *http.Request) (itemstore.Item, error) {
+	id, err := s.pathItemID(r)
+	if err != nil {
+		return itemstore.Item{}, err
+	}
+	return s.storeFor(r).Get(id)
+}
+
+// itemPath returns the path of one of the form pages of item id, e.g.
+// /items/7/edit: {{itemPath .ID "edit"}}
+func itemPath(id itemstore.ID, page string) string {
+	return "/items/" + url.PathEscape(id.String()) + "/" + page
+}
+
+// itemFormValues returns the form values of item
+func itemFormValues(item itemstore.Item) map[string]string {
+	return map[string]string{"color": item.Color, "shape": item.Shape, "category": item.Category}
+}
+
+// itemVersion identifies the current contents of item, so an edit form can
+// tell whether the item changed after it was rendered
+func itemVersion(item itemstore.Item) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%s\x00%s\x00%s", item.ID, item.Color, item.Shape, item.Category))
+	return hex.EncodeToString(sum[:8])
+}
+
+// parseItemForm reads the submitted item properties, trimmed of surrounding
+// whitespace
+func parseItemForm(w http.ResponseWriter, r *http.Request, limit int64) (map[string]string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	if err := r.ParseForm(); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, &httpError{status: http.StatusRequestEntityTooLarge, message: "form is too large"}
+		}
+		return nil, &httpError{status: http.StatusBadRequest, message: "invalid form submission"}
+	}
+	values := make(map[string]string, len(itemFormFields))
+	for _, name := range itemFormFields {
+		values[name] = strings.TrimSpace(r.PostForm.Get(name))
+	}
+	return values, nil
+}
+
+// validateItemForm returns an error message for each field that is missing
+// or too long
+func validateItemForm(values map[string]string) map[string]string {
+	fieldErrors := make(map[string]string)
+	for _, name := range itemFormFields {
+		switch v := values[name]; {
+		case v == "":
+			fieldErrors[name] = fmt.Sprintf("Enter a %s.", name)
+		case len(v) > maxFormFieldLength:
+			fieldErrors[name] = fmt.Sprintf("Keep the %s under %d characters.", name, maxFormFieldLength)
+		}
+	}
+	return fieldErrors
+}
+
+// storeFieldErrors turns the ValidationErrors in an error from the store
+// into messages next to the form fields at fault. It returns nil when none
+// of the problems belongs to a form field.
+func storeFieldErrors(err error) map[string]string {
+	var verrs itemstore.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil
+	}
+	var fieldErrors map[string]string
+	for _, fe := range verrs {
+		if !slices.Contains(itemFormFields, fe.Field) || fieldErrors[fe.Field] != "" {
+			continue
+		}
+		if fieldErrors == nil {
+			fieldErrors = make(map[string]string)
+		}
+		fieldErrors[fe.Field] = format.Title(fe.Field) + " " + fe.Message + "."
+	}
+	return fieldErrors
+}
+
+// flashMessage returns the confirmation shown on the dashboard after a form
+// redirects there, or "" if there is none. Added and updated items are only
+// confirmed while store holds them, and deleted ones if store could have
+// held them, so a crafted link cannot confirm any string it likes.
+func flashMessage(query url.Values, store itemstore.Store) string {
+	for _, change := range []string{"added", "updated", "deleted"} {
+		id, err := store.ParseID(query.Get(change))
+		if err != nil {
+			continue
+		}
+		if change != "deleted" {
+			if _, err := store.Get(id); err != nil {
+				continue
+			}
+		}
+		return fmt.Sprintf("Item #%s %s.", id, change)
+	}
+	if n, err := strconv.Atoi(query.Get("imported")); err == nil && n > 0 {
+		return fmt.Sprintf("%d items imported.", n)
+	}
+	return ""
+}
+
+// formWritesAllowed applies the write policy to the HTML forms, reporting a
+// 403 page when they are disabled. Browsers cannot send API keys, so the
+// forms are only enabled behind Basic Auth or when unauthenticated writes
+// are explicitly allowed.
+func (s *Server) formWritesAllowed(w http.ResponseWriter, r *http.Request) bool {
+	if s.cfg.AuthUser != "" || s.cfg.AllowUnauthenticatedWrites {
+		return true
+	}
+	s.renderError(w, r, http.StatusForbidden,
+		"Editing from the dashboard is disabled. Enable Basic Auth or unauthenticated writes to use it.")
+	return false
+}
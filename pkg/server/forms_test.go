@@ -0,0 +1,267 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// postForm submits form to target through the full middleware chain
+func postForm(srv *Server, target string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestItemForm_Add(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/new", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /items/new status = %d, want 200", rec.Code)
+	}
+	for _, want := range []string{`action="/items"`, `name="color"`, `<option value="triangle">`} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("form does not contain %s", want)
+		}
+	}
+
+	rec = postForm(srv, "/items", url.Values{"color": {" purple "}, "shape": {"hexagon"}, "category": {"C"}})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("POST status = %d, want 303: %s", rec.Code, rec.Body)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/items?added=4" {
+		t.Errorf("Location = %q, want /items?added=4", loc)
+	}
+	item, err := srv.store.Get(itemstore.IntID(4))
+	if err != nil || item.Color != "purple" || item.Shape != "hexagon" || item.Category != "C" {
+		t.Errorf("stored item = %+v, %v; want the submitted purple hexagon", item, err)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?added=4", nil))
+	if !strings.Contains(rec.Body.String(), "Item #4 added.") {
+		t.Error("dashboard does not confirm the added item")
+	}
+	// Items the store does not hold are not confirmed
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?updated=99", nil))
+	if strings.Contains(rec.Body.String(), "Item #99") {
+		t.Error("dashboard confirms an update of a missing item")
+	}
+}
+
+func TestItemForm_Invalid(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+
+	rec := postForm(srv, "/items", url.Values{"color": {"  "}, "shape": {strings.Repeat("x", 100)}, "category": {"Kept"}})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"Enter a color.", "Keep the shape under 64 characters.", `value="Kept"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("re-rendered form does not contain %q", want)
+		}
+	}
+	if strings.Contains(body, `id="category-error"`) {
+		t.Error("valid category is reported as an error")
+	}
+	if srv.store.Len() != 3 {
+		t.Errorf("store has %d items after an invalid submission, want 3", srv.store.Len())
+	}
+}
+
+func TestItemForm_StoreValidation(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+	store, err := itemstore.New(nil, itemstore.WithKnownShapes(format.NewShapeRegistry()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.store = store
+
+	rec := postForm(srv, "/items", url.Values{"color": {"red"}, "shape": {"octagon"}, "category": {"A"}})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `id="shape-error"`) || !strings.Contains(body, "Shape is not a known shape") {
+		t.Errorf("re-rendered form does not report the shape next to its field:\n%s", body)
+	}
+	if strings.Contains(body, `id="color-error"`) {
+		t.Error("valid color is reported as an error")
+	}
+}
+
+func TestItemForm_WritePolicy(t *testing.T) {
+	// Without Basic Auth or --allow-unauthenticated-writes the forms are off
+	srv := newTestServer(t, testConfig(t))
+	rec := postForm(srv, "/items", url.Values{"color": {"red"}, "shape": {"circle"}, "category": {"A"}})
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("POST status = %d, want 403", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/new", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("GET /items/new status = %d, want 403", rec.Code)
+	}
+
+	for _, target := range []string{"/items/1/edit", "/items/1/delete"} {
+		if rec := postForm(srv, target, url.Values{}); rec.Code != http.StatusForbidden {
+			t.Errorf("POST %s status = %d, want 403", target, rec.Code)
+		}
+	}
+
+	// Cross-site submissions are rejected even when writes are allowed
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv = newTestServer(t, cfg)
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader("color=red&shape=circle&category=A"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("cross-site POST status = %d, want 403", rec.Code)
+	}
+	if srv.store.Len() != 3 {
+		t.Errorf("store has %d items, want 3", srv.store.Len())
+	}
+}
+
+// formVersion extracts the hidden version input from a rendered edit form
+func formVersion(t *testing.T, body string) string {
+	t.Helper()
+	_, rest, ok := strings.Cut(body, `name="version" value="`)
+	if !ok {
+		t.Fatalf("form has no version field:\n%s", body)
+	}
+	version, _, _ := strings.Cut(rest, `"`)
+	return version
+}
+
+// interleavedStore saves another edit of an item just before each UpdateIf
+// of it, as if it came in between the handler's read and its write
+type interleavedStore struct {
+	*itemstore.ItemStore
+}
+
+func (s interleavedStore) UpdateIf(item itemstore.Item, match func(itemstore.Item) bool) (itemstore.Item, error) {
+	other := item
+	other.Color = "olive"
+	if _, err := s.ItemStore.Update(other); err != nil {
+		return itemstore.Item{}, err
+	}
+	return s.ItemStore.UpdateIf(item, match)
+}
+
+func TestItemForm_EditRace(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+	srv.store = interleavedStore{srv.store.(*itemstore.ItemStore)}
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/2/edit", nil))
+	rec = postForm(srv, "/items/2/edit", url.Values{"version": {formVersion(t, rec.Body.String())}, "color": {"teal"}, "shape": {"square"}, "category": {"A"}})
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `value="olive"`) {
+		t.Fatalf("POST edit = %d, want 409 showing the edit saved in between:\n%s", rec.Code, rec.Body)
+	}
+	if item, _ := srv.store.Get(itemstore.IntID(2)); item.Color != "olive" {
+		t.Errorf("stored item = %+v, want the olive edit kept", item)
+	}
+}
+
+func TestItemForm_Edit(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/2/edit", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET edit status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{`action="/items/2/edit"`, `value="blue"`, `value="square"`, `href="/items/2/delete"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("edit form does not contain %s", want)
+		}
+	}
+	version := formVersion(t, body)
+
+	rec = postForm(srv, "/items/2/edit", url.Values{"version": {version}, "color": {"teal"}, "shape": {"square"}, "category": {"A"}})
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/items?updated=2" {
+		t.Fatalf("POST edit = %d %q, want 303 to /items?updated=2", rec.Code, rec.Header().Get("Location"))
+	}
+	if item, _ := srv.store.Get(itemstore.IntID(2)); item.Color != "teal" {
+		t.Errorf("stored item = %+v, want teal", item)
+	}
+
+	// The same form submitted again is now stale
+	rec = postForm(srv, "/items/2/edit", url.Values{"version": {version}, "color": {"pink"}, "shape": {"square"}, "category": {"A"}})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("stale POST status = %d, want 409", rec.Code)
+	}
+	body = rec.Body.String()
+	if !strings.Contains(body, "Someone else edited this item") || !strings.Contains(body, `value="teal"`) {
+		t.Errorf("conflict page does not explain the conflict with the current values:\n%s", body)
+	}
+	if item, _ := srv.store.Get(itemstore.IntID(2)); item.Color != "teal" {
+		t.Errorf("stale edit overwrote the item: %+v", item)
+	}
+
+	// Resubmitting from the conflict page succeeds
+	rec = postForm(srv, "/items/2/edit", url.Values{"version": {formVersion(t, body)}, "color": {"pink"}, "shape": {"square"}, "category": {"A"}})
+	if rec.Code != http.StatusSeeOther {
+		t.Errorf("POST after conflict status = %d, want 303", rec.Code)
+	}
+
+	if rec := postForm(srv, "/items/99/edit", url.Values{}); rec.Code != http.StatusNotFound {
+		t.Errorf("edit of a missing item status = %d, want 404", rec.Code)
+	}
+}
+
+func TestItemForm_Delete(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/3/delete", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Delete item #3?") {
+		t.Fatalf("confirmation = %d, want the delete prompt:\n%s", rec.Code, rec.Body)
+	}
+	if srv.store.Len() != 3 {
+		t.Fatal("showing the confirmation deleted the item")
+	}
+
+	rec = postForm(srv, "/items/3/delete", nil)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/items?deleted=3" {
+		t.Fatalf("POST delete = %d %q, want 303 to /items?deleted=3", rec.Code, rec.Header().Get("Location"))
+	}
+	if _, err := srv.store.Get(itemstore.IntID(3)); err == nil {
+		t.Error("item 3 still exists")
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?deleted=3", nil))
+	if !strings.Contains(rec.Body.String(), "Item #3 deleted.") {
+		t.Error("dashboard does not confirm the deletion")
+	}
+}
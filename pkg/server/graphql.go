@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ElodinLaarz/dashboard/pkg/graphqlapi"
+	"github.com/graphql-go/graphql"
+)
+
+// graphqlPath serves the GraphQL endpoint
+const graphqlPath = "/graphql"
+
+// graphqlHandler runs a GraphQL request sent as a JSON POST body or, for
+// queries only, as GET query parameters. Requests that fail to parse,
+// validate, or stay within the query limits get 400 with a GraphQL "errors"
+// body; mutations need the same API key as other writes, and are refused
+// while the server is read-only.
+func (s *Server) graphqlHandler(w http.ResponseWriter, r *http.Request) {
+	var req graphqlapi.Request
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				s.writeError(w, r, http.StatusBadRequest, "variables must be a JSON object")
+				return
+			}
+		}
+	} else if err := decodeJSONBody(w, r, &req, s.cfg.MaxBodyBytes); err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+
+	op, errs := s.graphql.Prepare(req)
+	if errs != nil {
+		s.writeJSON(w, r, http.StatusBadRequest, graphql.Result{Errors: errs})
+		return
+	}
+	if op.Mutation() {
+		if s.cfg.ReadOnly {
+			s.respondError(w, r, errReadOnly)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			s.writeError(w, r, http.StatusMethodNotAllowed, "mutations must be sent with POST")
+			return
+		}
+		if !s.authorizeWrite(w, r, s.apiKeys) {
+			return
+		}
+	}
+
+	ctx := r.Context()
+	if op.Mutation() {
+		ctx = graphqlapi.WithStore(ctx, s.writeStore(r))
+	}
+	s.writeJSON(w, r, http.StatusOK, s.graphql.Execute(ctx, op))
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGraphQL_EndToEnd(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.APIKeys = []string{"secret"}
+	srv := newTestServer(t, cfg)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	post := func(body, key string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/graphql", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var out json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return resp.StatusCode, string(out)
+	}
+
+	status, body := post(`{"query":"query($f: ItemFilter) { items(filter: $f, sort: {field: ID, order: DESC}) { id color } stats(property: CATEGORY) { total values { value count } } }","variables":{"f":{"category":"A"}}}`, "")
+	if status != http.StatusOK {
+		t.Fatalf("query status = %d: %s", status, body)
+	}
+	want := `{"data":{"items":[{"color":"blue","id":2},{"color":"red","id":1}],"stats":{"total":3,"values":[{"count":2,"value":"A"},{"count":1,"value":"B"}]}}}`
+	if body != want {
+		t.Errorf("query body\n got  %s\n want %s", body, want)
+	}
+
+	mutation := `{"query":"mutation { createItem(input: {color: \"purple\", shape: \"star\", category: \"C\"}) { id } }"}`
+	if status, body := post(mutation, ""); status != http.StatusUnauthorized {
+		t.Errorf("mutation without key: status = %d, want 401: %s", status, body)
+	}
+	if srv.store.Len() != 3 {
+		t.Fatalf("unauthorized mutation changed the store")
+	}
+	status, body = post(mutation, "secret")
+	if status != http.StatusOK || body != `{"data":{"createItem":{"id":4}}}` {
+		t.Errorf("mutation with key: status = %d, body = %s", status, body)
+	}
+
+	status, body = post(`{"query":"{ items { nope } }"}`, "")
+	if status != http.StatusBadRequest || !strings.Contains(body, `"errors"`) {
+		t.Errorf("invalid query: status = %d, body = %s", status, body)
+	}
+}
+
+func TestGraphQL_GET(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+	router := srv.routes()
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(query), nil))
+		return rec
+	}
+
+	rec := get(`{ item(id: 3) { shape } }`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"shape":"triangle"`) {
+		t.Errorf("GET query: status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	rec = get(`mutation { deleteItem(id: 1) }`)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET mutation: status = %d, want 405", rec.Code)
+	}
+	if srv.store.Len() != 3 {
+		t.Errorf("GET mutation changed the store")
+	}
+}
+
+func TestGraphQL_Limits(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.GraphQLMaxDepth = 2
+	srv := newTestServer(t, cfg)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/graphql",
+		strings.NewReader(`{"query":"{ groups(by: COLOR) { items { id } } }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	srv.routes().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "QUERY_TOO_DEEP") {
+		t.Errorf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// probePaths are the health endpoints polled by load balancers and
+// orchestrators. They bypass authentication and are logged at debug level.
+var probePaths = []string{"/healthz", "/readyz"}
+
+// readinessCheck is one dependency verified by /readyz
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// healthResponse is the body returned by /healthz and /readyz
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// defaultReadinessChecks verifies that the store is loaded and the templates
+// parse
+func (s *Server) defaultReadinessChecks() []readinessCheck {
+	return []readinessCheck{
+		{name: "store", check: func(context.Context) error {
+			if s.store == nil {
+				return errors.New("item store not loaded")
+			}
+			return nil
+		}},
+		{name: "templates", check: func(context.Context) error {
+			_, err := s.templates.Templates(defaultLanguage)
+			return err
+		}},
+	}
+}
+
+// healthzHandler reports that the process is up and serving requests
+func (s *Server) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, r, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// readyzHandler runs every readiness check and reports 503 naming the checks
+// that failed
+func (s *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	resp := healthResponse{Status: "ok", Checks: make(map[string]string, len(s.readinessChecks))}
+	status := http.StatusOK
+
+	for _, c := range s.readinessChecks {
+		if err := c.check(r.Context()); err != nil {
+			s.logger.WarnContext(r.Context(), "readiness check failed", "check", c.name, "error", err)
+			resp.Checks[c.name] = "error: " + err.Error()
+			resp.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Checks[c.name] = "ok"
+	}
+	s.writeJSON(w, r, status, resp)
+}
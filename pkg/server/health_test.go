@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthz(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var body healthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.Status != "ok" {
+		t.Errorf("status = %q, want ok", body.Status)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name       string
+		extra      []readinessCheck
+		wantStatus int
+		wantChecks map[string]string
+	}{
+		{
+			name:       "ready",
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{"store": "ok", "templates": "ok"},
+		},
+		{
+			name: "degraded",
+			extra: []readinessCheck{{name: "database", check: func(context.Context) error {
+				return errors.New("connection refused")
+			}}},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"store": "ok", "templates": "ok", "database": "error: connection refused"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, testConfig(t))
+			srv.readinessChecks = append(srv.readinessChecks, tt.extra...)
+
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var body healthResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			for name, want := range tt.wantChecks {
+				if got := body.Checks[name]; got != want {
+					t.Errorf("checks[%q] = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProbesLoggedAtDebug(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	buf := captureLogs(srv)
+	handler := srv.Handler()
+
+	for _, path := range []string{"/healthz", "/readyz", "/api/items"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	levels := make(map[string]string)
+	for _, rec := range logRecords(t, buf) {
+		if rec["msg"] == "request" {
+			levels[rec["path"].(string)] = rec["level"].(string)
+		}
+	}
+	want := map[string]string{"/healthz": "DEBUG", "/readyz": "DEBUG", "/api/items": "INFO"}
+	for path, level := range want {
+		if levels[path] != level {
+			t.Errorf("access log level for %s = %q, want %q", path, levels[path], level)
+		}
+	}
+}
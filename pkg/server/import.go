@@ -0,0 +1,266 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+const (
+	// importTTL is how long an uploaded import waits to be confirmed
+	importTTL = 15 * time.Minute
+	// maxPendingImports bounds the imports held for confirmation; the one
+	// closest to expiring makes way for a new one
+	maxPendingImports = 16
+	// importPreviewRows bounds the rows to be added listed on the preview
+	importPreviewRows = 50
+)
+
+// pendingImport is an uploaded import waiting to be confirmed
+type pendingImport struct {
+	// scope is the items page of the store the import was checked against,
+	// so it can only be confirmed into that store
+	scope   string
+	items   []itemstore.Item
+	expires time.Time
+}
+
+// importStore holds the imports between their preview and confirmation
+type importStore struct {
+	mu      sync.Mutex
+	pending map[string]pendingImport
+	now     func() time.Time
+}
+
+func newImportStore() *importStore {
+	return &importStore{pending: make(map[string]pendingImport), now: time.Now}
+}
+
+// add stores p under a new token, dropping expired imports first
+func (s *importStore) add(p pendingImport) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate import token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var oldest string
+	for t, pending := range s.pending {
+		if !now.Before(pending.expires) {
+			delete(s.pending, t)
+		} else if oldest == "" || pending.expires.Before(s.pending[oldest].expires) {
+			oldest = t
+		}
+	}
+	if len(s.pending) >= maxPendingImports {
+		delete(s.pending, oldest)
+	}
+	p.expires = now.Add(importTTL)
+	s.pending[token] = p
+	return token, nil
+}
+
+// take removes and returns the import stored under token for scope. It
+// reports false for unknown and expired tokens and those of another scope.
+func (s *importStore) take(token, scope string) (pendingImport, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pending[token]
+	if !ok || p.scope != scope {
+		return pendingImport{}, false
+	}
+	delete(s.pending, token)
+	return p, s.now().Before(p.expires)
+}
+
+// importRow is one row of an uploaded file as shown on the preview
+type importRow struct {
+	// Row counts the rows after the header from 1
+	Row    int
+	Item   itemstore.Item
+	Errors []string
+}
+
+// importPage is the data of import.html: the upload form, or with Token
+// set the preview of an uploaded file
+type importPage struct {
+	pageData
+	// Error explains why the upload or confirmation failed
+	Error    string
+	Filename string
+	Token    string
+	// Valid lists the first importPreviewRows rows to be added, of
+	// ValidCount; More counts the rest
+	Valid      []importRow
+	ValidCount int
+	More       int
+	// Invalid lists every row that will be skipped, with its problems
+	Invalid []importRow
+}
+
+// importFormHandler renders the CSV upload form
+func (s *Server) importFormHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.formWritesAllowed(w, r) {
+		return
+	}
+	s.render(w, r, http.StatusOK, "import.html", importPage{pageData: s.pageData(r)})
+}
+
+// importUploadHandler reads an uploaded CSV file, checks every row as the
+// store would add it, and previews the result. The rows that can be added
+// are held under a token until the preview is confirmed; nothing is stored
+// yet. Files that are not CSV text re-render the form with the problem.
+func (s *Server) importUploadHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.formWritesAllowed(w, r) {
+		return
+	}
+	page := importPage{pageData: s.pageData(r)}
+	fail := func(status int, message string) {
+		page.Error = message
+		s.render(w, r, status, "import.html", page)
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxBulkBodyBytes)
+	if err := r.ParseMultipartForm(s.cfg.MaxBulkBodyBytes); err != nil {
+		if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+			fail(http.StatusRequestEntityTooLarge, fmt.Sprintf("The file is too large; upload at most %d bytes.", maxErr.Limit))
+			return
+		}
+		fail(http.StatusBadRequest, "Choose a CSV file to upload.")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		fail(http.StatusBadRequest, "Choose a CSV file to upload.")
+		return
+	}
+	defer file.Close()
+	page.Filename = header.Filename
+	data, err := io.ReadAll(file)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	// The declared type depends on the browser and platform, so the
+	// content decides: CSV sniffs as plain text
+	if sniffed := http.DetectContentType(data); len(data) == 0 || !strings.HasPrefix(sniffed, "text/plain") {
+		fail(http.StatusUnsupportedMediaType, "The file is not a CSV file.")
+		return
+	}
+
+	items, err := itemstore.ReadCSV(bytes.NewReader(data))
+	switch {
+	case err != nil:
+		fail(http.StatusUnprocessableEntity, "The file could not be read: "+err.Error()+".")
+		return
+	case len(items) == 0:
+		fail(http.StatusUnprocessableEntity, "The file has no rows after the header.")
+		return
+	case len(items) > maxBulkItems:
+		fail(http.StatusRequestEntityTooLarge, fmt.Sprintf("The file has %d rows; import at most %d at a time.", len(items), maxBulkItems))
+		return
+	}
+
+	valid, invalid := s.checkImportRows(r, items)
+	page.ValidCount, page.Invalid = len(valid), invalid
+	page.Valid = valid[:min(len(valid), importPreviewRows)]
+	page.More = len(valid) - len(page.Valid)
+	if len(valid) > 0 {
+		validItems := make([]itemstore.Item, len(valid))
+		for i, row := range valid {
+			validItems[i] = row.Item
+		}
+		page.Token, err = s.imports.add(pendingImport{scope: s.scopedURL(r, "/items"), items: validItems})
+		if err != nil {
+			s.respondError(w, r, err)
+			return
+		}
+	}
+	s.render(w, r, http.StatusOK, "import.html", page)
+}
+
+// checkImportRows checks items as the store r writes to would add them,
+// also failing IDs that are taken or repeated in the file. It returns the
+// rows that can be added, normalized, and those that cannot.
+func (s *Server) checkImportRows(r *http.Request, items []itemstore.Item) (valid, invalid []importRow) {
+	store := s.writeStore(r)
+	// seen maps each ID in the file to the first row with it, by its string
+	// form, as an ID may be given as a number or as a string
+	seen := make(map[string]int)
+	for i, item := range items {
+		row := importRow{Row: i + 1, Item: item}
+		checked, err := store.Check(item)
+		var verrs itemstore.ValidationErrors
+		switch {
+		case errors.As(err, &verrs):
+			for _, fe := range verrs {
+				row.Errors = append(row.Errors, fe.Field+" "+fe.Message)
+			}
+		case err != nil:
+			row.Errors = append(row.Errors, err.Error())
+		default:
+			row.Item = checked
+		}
+		if id := item.ID; !id.IsZero() {
+			if first, ok := seen[id.String()]; ok {
+				row.Errors = append(row.Errors, fmt.Sprintf("ID %s is also used by row %d", id, first))
+			} else if _, err := store.Get(id); err == nil {
+				row.Errors = append(row.Errors, fmt.Sprintf("ID %s is taken by an existing item", id))
+			} else {
+				seen[id.String()] = row.Row
+			}
+		}
+		if len(row.Errors) > 0 {
+			invalid = append(invalid, row)
+		} else {
+			valid = append(valid, row)
+		}
+	}
+	return valid, invalid
+}
+
+// importConfirmHandler adds the rows of a previewed import, all or none,
+// and redirects to the dashboard, which confirms how many were imported.
+// Tokens work once; an expired one, or a store that changed so the rows no
+// longer fit, sends the user back to the upload form.
+func (s *Server) importConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.formWritesAllowed(w, r) {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes)
+	if err := r.ParseForm(); err != nil {
+		s.respondError(w, r, &httpError{status: http.StatusBadRequest, message: "invalid form submission"})
+		return
+	}
+	page := importPage{pageData: s.pageData(r)}
+	pending, ok := s.imports.take(r.PostForm.Get("token"), s.scopedURL(r, "/items"))
+	if !ok {
+		page.Error = "This import has expired or was already done. Upload the file again."
+		s.render(w, r, http.StatusGone, "import.html", page)
+		return
+	}
+	added, err := s.writeStore(r).AddAll(pending.items)
+	if errors.Is(err, itemstore.ErrDuplicateID) || errors.Is(err, itemstore.ErrInvalidItem) {
+		page.Error = "The items changed since the preview, so nothing was imported (" + err.Error() + "). Upload the file again."
+		s.render(w, r, http.StatusConflict, "import.html", page)
+		return
+	}
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, s.scopedURL(r, fmt.Sprintf("/items?imported=%d", len(added))), http.StatusSeeOther)
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// uploadImport posts content as the file of the import form
+func uploadImport(t *testing.T, srv *Server, filename string, content []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/items/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+// confirmImport posts the confirmation of the import stored under token
+func confirmImport(t *testing.T, srv *Server, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/items/import/confirm", strings.NewReader(url.Values{"token": {token}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+var importTokenPattern = regexp.MustCompile(`name="token" value="([0-9a-f]+)"`)
+
+func TestImport(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+
+	if rec := getItems(t, srv, "/items/import"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `enctype="multipart/form-data"`) {
+		t.Fatalf("GET /items/import = %d, want the upload form", rec.Code)
+	}
+
+	fixture, err := os.ReadFile("testdata/import.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := uploadImport(t, srv, "import.csv", fixture)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upload status = %d: %s", rec.Code, rec.Body)
+	}
+	preview := rec.Body.String()
+	for _, want := range []string{
+		"import.csv: 3 rows will be added, 3 with errors will be skipped.",
+		"ID 1 is taken by an existing item",
+		"shape is required",
+		"ID 10 is also used by row 2",
+		"<td>gray</td>",
+	} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("preview does not contain %q", want)
+		}
+	}
+	// Nothing is stored before the confirmation
+	if n := srv.store.Len(); n != 3 {
+		t.Fatalf("store has %d items after the preview, want 3", n)
+	}
+
+	match := importTokenPattern.FindStringSubmatch(preview)
+	if match == nil {
+		t.Fatal("preview has no confirmation token")
+	}
+	rec = confirmImport(t, srv, match[1])
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/items?imported=3" {
+		t.Fatalf("confirm = %d to %q, want a redirect to /items?imported=3", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := getItems(t, srv, "/items?imported=3"); !strings.Contains(rec.Body.String(), "3 items imported.") {
+		t.Error("items page does not confirm the import")
+	}
+
+	var list itemListResponse
+	if err := json.NewDecoder(getItems(t, srv, "/api/items?filter=category:C").Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[int]string)
+	for _, item := range list.Items {
+		id, _ := item.ID.Int()
+		got[id] = item.Color + " " + item.Shape
+	}
+	want := map[int]string{4: "purple circle", 10: "orange square"}
+	if len(got) != len(want) || got[4] != want[4] || got[10] != want[10] {
+		t.Errorf("category C = %v, want %v", got, want)
+	}
+	if item, err := srv.store.Get(itemstore.IntID(11)); err != nil || item.Color != "gray" || item.Category != "D" {
+		t.Errorf("item 11 = %+v, %v; want the gray circle in D", item, err)
+	}
+
+	// A token works once
+	if rec := confirmImport(t, srv, match[1]); rec.Code != http.StatusGone {
+		t.Errorf("second confirm = %d, want 410", rec.Code)
+	}
+}
+
+func TestImport_Rejected(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	cfg.MaxBulkBodyBytes = 1024
+	srv := newTestServer(t, cfg)
+
+	tests := []struct {
+		name    string
+		content []byte
+		status  int
+		want    string
+	}{
+		{"image", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), http.StatusUnsupportedMediaType, "not a CSV file"},
+		{"empty", nil, http.StatusUnsupportedMediaType, "not a CSV file"},
+		{"too large", bytes.Repeat([]byte("red,circle,A\n"), 100), http.StatusRequestEntityTooLarge, "too large"},
+		{"malformed", []byte("color,shape,size\nred,circle,L\n"), http.StatusUnprocessableEntity, "unknown column"},
+		{"header only", []byte("color,shape,category\n"), http.StatusUnprocessableEntity, "no rows"},
+	}
+	for _, tt := range tests {
+		rec := uploadImport(t, srv, "items.csv", tt.content)
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: upload = %d, want %d mentioning %q:\n%s", tt.name, rec.Code, tt.status, tt.want, rec.Body)
+		}
+	}
+
+	// A file where every row fails has nothing to confirm
+	rec := uploadImport(t, srv, "items.csv", []byte("color,shape,category\nred,,A\n"))
+	if rec.Code != http.StatusOK || importTokenPattern.MatchString(rec.Body.String()) {
+		t.Errorf("upload of only invalid rows = %d, want a preview without a confirmation", rec.Code)
+	}
+	if rec := confirmImport(t, srv, "0123"); rec.Code != http.StatusGone {
+		t.Errorf("confirm of an unknown token = %d, want 410", rec.Code)
+	}
+
+	// Cross-site uploads are refused
+	req := httptest.NewRequest(http.MethodPost, "/items/import", strings.NewReader(""))
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("cross-site upload = %d, want 403", rec.Code)
+	}
+
+	// So is importing with the forms disabled
+	if rec := getItems(t, newTestServer(t, testConfig(t)), "/items/import"); rec.Code != http.StatusForbidden {
+		t.Errorf("GET /items/import with writes disabled = %d, want 403", rec.Code)
+	}
+}
+
+func TestImportStore_Expiry(t *testing.T) {
+	imports := newImportStore()
+	now := imports.now()
+	imports.now = func() time.Time { return now }
+	token, err := imports.add(pendingImport{scope: "/items"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := imports.take(token, "/c/other/items"); ok {
+		t.Error("an import was taken into another collection")
+	}
+	now = now.Add(importTTL)
+	if _, ok := imports.take(token, "/items"); ok {
+		t.Error("an expired import was taken")
+	}
+
+	// The imports closest to expiring make way for new ones
+	for range maxPendingImports + 1 {
+		if _, err := imports.add(pendingImport{}); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Second)
+	}
+	if len(imports.pending) != maxPendingImports {
+		t.Errorf("%d pending imports, want at most %d", len(imports.pending), maxPendingImports)
+	}
+}
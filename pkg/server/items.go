@@ -0,0 +1,216 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// indexHandler lists the collections and saved views when there are any,
+// and otherwise redirects to the items page
+func (s *Server) indexHandler(w http.ResponseWriter, r *http.Request) {
+	if (len(s.collections) > 0 || len(s.savedViews()) > 0) && requestCollection(r) == nil {
+		s.collectionsIndexHandler(w, r)
+		return
+	}
+	http.Redirect(w, r, s.scopedURL(r, "/items"), http.StatusFound)
+}
+
+func (s *Server) itemsHandler(w http.ResponseWriter, r *http.Request) {
+	// Links are built from the query with the saved view and the defaults
+	// written in, so they keep the view the page shows
+	raw, restored := s.restoreViewState(w, r)
+	params := fillDefaults(s.cfg.Defaults, raw, itemsPageParams)
+	query, err := parseItemsQuery(params, itemsPageParams, false)
+	if err != nil && restored {
+		// A saved view that no longer parses is dropped rather than
+		// breaking every visit
+		s.clearViewState(w, r)
+		params = fillDefaults(s.cfg.Defaults, r.URL.Query(), itemsPageParams)
+		query, err = parseItemsQuery(params, itemsPageParams, false)
+	}
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	if !restored {
+		s.saveViewState(w, r, raw)
+	}
+	s.renderItemsPage(w, r, params, query, nil)
+}
+
+// renderItemsPage renders the items page for params, which parse to query.
+// A saved view's title is shown above the items.
+func (s *Server) renderItemsPage(w http.ResponseWriter, r *http.Request, params url.Values, query itemsQuery, saved *savedView) {
+	groupBy := query.GroupBy
+
+	// The groups and sidebar only change with the store, so they are
+	// cached under its revision
+	key := s.itemsViewKey(r, params)
+	view, ok := s.views.get(key)
+	if !ok {
+		var err error
+		if view, err = s.itemsView(r, params, query); err != nil {
+			s.respondError(w, r, err)
+			return
+		}
+		s.views.add(key, view)
+	}
+
+	// Prepare template data
+	data := struct {
+		pageData
+		Title string
+		// View is the saved view shown, if any
+		View            *savedView
+		GroupedItems    map[string]itemGroup
+		GroupBy         string
+		SidebarSections []sidebarSection
+		GroupOptions    []groupOption
+		SortColumns     []sortColumn
+		ActiveFilters   []activeFilter
+		ClearAllLink    string
+		// Presets apply a saved query with one click
+		Presets []presetLink
+		// Search is the q parameter, and SearchTerms the terms highlighted
+		// in each item
+		Search         string
+		SearchTerms    []string
+		ItemsAtStartup int
+		// Flash confirms a change just made through the item forms
+		Flash string
+	}{
+		pageData:        s.pageData(r),
+		Title:           "Dashboard",
+		View:            saved,
+		GroupedItems:    view.groups,
+		GroupBy:         groupBy,
+		SidebarSections: view.sidebar,
+		GroupOptions:    s.groupOptions(r, params, groupBy),
+		SortColumns:     s.sortColumns(r, params, query),
+		ActiveFilters:   s.activeFilters(r, params),
+		ClearAllLink:    s.itemsLink(r, params, withoutFilters),
+		Presets:         s.presetLinks(r),
+		Search:          params.Get("q"),
+		SearchTerms:     query.Search,
+		ItemsAtStartup:  s.itemsAtStartup,
+		Flash:           flashMessage(params, s.storeFor(r)),
+	}
+
+	if saved != nil {
+		data.Title = saved.Title
+	}
+
+	_, span := s.startSpan(r.Context(), "render items.html")
+	defer span.End()
+	s.render(w, r, http.StatusOK, "items.html", data)
+}
+
+// itemsView filters, sorts, and groups the items of the store r addresses
+// for the items page and builds its sidebar. It fails only when the
+// request's context ends first.
+func (s *Server) itemsView(r *http.Request, params url.Values, query itemsQuery) (itemsView, error) {
+	filteredItems, err := s.storeFor(r).FilterContext(r.Context(), query.Filters)
+	if err != nil {
+		return itemsView{}, err
+	}
+	filteredItems = searchItems(filteredItems, query.Search)
+	s.logger.DebugContext(r.Context(), "filtered items",
+		"filters", query.Filters,
+		"count", len(filteredItems),
+		"request_id", RequestIDFromContext(r.Context()))
+
+	if query.SortBy != "" {
+		if err := itemstore.Sort(r.Context(), filteredItems, query.SortBy, query.Descending); err != nil {
+			return itemsView{}, err
+		}
+	}
+
+	// Group items by the specified property, or list them as one flat
+	// group so a sort applies across every item. No items make no groups,
+	// which the page shows as an empty list.
+	var groups map[string]itemGroup
+	switch {
+	case query.GroupBy != groupByNone:
+		if groups, err = s.groupItems(r.Context(), filteredItems, query.GroupBy); err != nil {
+			return itemsView{}, err
+		}
+	case len(filteredItems) > 0:
+		groups = map[string]itemGroup{"": s.newItemGroup(filteredItems, true)}
+	}
+	return itemsView{groups: groups, sidebar: s.sidebarSections(r, params, query.Filters)}, nil
+}
+
+// itemGroup is one group of the items page
+type itemGroup struct {
+	Items []displayItem
+	// Count is the number of items in the group after filtering
+	Count int
+	// Flat marks the single, unheaded group of an ungrouped list
+	Flat bool
+	// Hex is the color the group is named after when grouping by color
+	Hex string
+}
+
+// displayItem is an item as shown on the items page
+type displayItem struct {
+	itemstore.Item
+	// Hex is the item's color as a "#rrggbb" value
+	Hex string
+}
+
+// newItemGroup returns a group of items with its counts filled in
+func (s *Server) newItemGroup(items []itemstore.Item, flat bool) itemGroup {
+	return itemGroup{
+		Items: s.displayItems(make([]displayItem, 0, len(items)), items, s.hexLookup()),
+		Count: len(items),
+		Flat:  flat,
+	}
+}
+
+// groupItems groups the filtered items by property and counts each group.
+// The groups' display items share one backing array and each color's hex
+// value is looked up once, however many items have it.
+func (s *Server) groupItems(ctx context.Context, items []itemstore.Item, property string) (map[string]itemGroup, error) {
+	grouped, err := itemstore.GroupBy(ctx, items, property)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string]itemGroup, len(grouped))
+	hex := s.hexLookup()
+	display := make([]displayItem, 0, len(items))
+	for name, members := range grouped {
+		start := len(display)
+		display = s.displayItems(display, members, hex)
+		group := itemGroup{Items: display[start:len(display):len(display)], Count: len(members)}
+		if property == "color" {
+			group.Hex = hex(name)
+		}
+		groups[name] = group
+	}
+	return groups, nil
+}
+
+// displayItems appends items to display with their colors' hex values
+func (s *Server) displayItems(display []displayItem, items []itemstore.Item, hex func(string) string) []displayItem {
+	for _, item := range items {
+		display = append(display, displayItem{Item: item, Hex: hex(item.Color)})
+	}
+	return display
+}
+
+// hexLookup returns palette.Hex remembering each color it has looked up, for
+// one request's worth of items
+func (s *Server) hexLookup() func(color string) string {
+	hexes := make(map[string]string)
+	return func(color string) string {
+		hex, ok := hexes[color]
+		if !ok {
+			hex = s.palette.Hex(color)
+			hexes[color] = hex
+		}
+		return hex
+	}
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+)
+
+// flashParams are one-shot parameters, the form and import confirmations,
+// the view reset, and the language switch, that links never carry forward
+var flashParams = []string{"added", "updated", "deleted", "imported", "reset", langParam}
+
+// itemsLink returns the URL of the items page r addresses for the current
+// query after edit has changed it. Every link the items page builds from its
+// own query string, in the sidebar or the sort bar, goes through here so that
+// escaping and the dropping of flash confirmations happen in one place.
+func (s *Server) itemsLink(r *http.Request, query url.Values, edit func(url.Values)) string {
+	next := make(url.Values, len(query)+1)
+	for key, values := range query {
+		if slices.Contains(flashParams, key) {
+			continue
+		}
+		next[key] = slices.Clone(values)
+	}
+	edit(next)
+	// Without any filter parameter the default filters would come back
+	if len(s.cfg.Defaults.Filters) > 0 && !next.Has("filter") && !next.Has("filterBy") {
+		next.Set("filter", "")
+	}
+
+	if len(next) == 0 {
+		return s.scopedURL(r, "/items")
+	}
+	return s.scopedURL(r, "/items") + "?" + next.Encode()
+}
+
+// withFilter sets the filter on property to value, replacing any other
+// filter on the same property in either the filter or the legacy
+// filterBy/filterValue form
+func withFilter(property, value string) func(url.Values) {
+	return func(q url.Values) {
+		q["filter"] = slices.DeleteFunc(q["filter"], func(f string) bool {
+			return strings.HasPrefix(f, property+":")
+		})
+		if q.Get("filterBy") == property {
+			q.Del("filterBy")
+			q.Del("filterValue")
+		}
+		q.Add("filter", property+":"+value)
+	}
+}
+
+// withSort sorts by column in the given direction
+func withSort(column string, descending bool) func(url.Values) {
+	return func(q url.Values) {
+		q.Set("sortBy", column)
+		if descending {
+			q.Set("order", "desc")
+		} else {
+			q.Del("order")
+		}
+	}
+}
+
+// sortColumn is one entry of the items page's sort bar. Link toggles the
+// sort: ascending for a column not yet sorted on, otherwise the reverse of
+// the current direction.
+type sortColumn struct {
+	Field string
+	Label string
+	Link  string
+	// Active marks the column currently sorted on, and Descending its
+	// direction
+	Active     bool
+	Descending bool
+}
+
+// sortColumns builds the sort bar for a request to /items with the given
+// query and its parsed form
+func (s *Server) sortColumns(r *http.Request, query url.Values, parsed itemsQuery) []sortColumn {
+	columns := make([]sortColumn, 0, len(sortFields))
+	for _, field := range sortFields {
+		active := parsed.SortBy == field
+		columns = append(columns, sortColumn{
+			Field:      field,
+			Label:      format.Title(field),
+			Link:       s.itemsLink(r, query, withSort(field, active && !parsed.Descending)),
+			Active:     active,
+			Descending: active && parsed.Descending,
+		})
+	}
+	return columns
+}
+
+// activeFilter is one applied filter, shown as a chip that links to the
+// current page without it
+type activeFilter struct {
+	Property string
+	// Value is formatted for display
+	Value      string
+	RemoveLink string
+}
+
+// activeFilters lists the filters in query in the order given, followed by
+// a legacy filterBy/filterValue pair. Removing one filter keeps every other
+// parameter, including other filters on the same property.
+func (s *Server) activeFilters(r *http.Request, query url.Values) []activeFilter {
+	var active []activeFilter
+	seen := make(map[string]bool)
+	for _, raw := range query["filter"] {
+		property, value, ok := strings.Cut(raw, ":")
+		if !ok || seen[raw] {
+			continue
+		}
+		seen[raw] = true
+		active = append(active, activeFilter{
+			Property:   property,
+			Value:      filterDisplayValue(property, value),
+			RemoveLink: s.itemsLink(r, query, withoutFilter(raw)),
+		})
+	}
+	if property, value := query.Get("filterBy"), query.Get("filterValue"); property != "" && value != "" {
+		active = append(active, activeFilter{
+			Property:   property,
+			Value:      filterDisplayValue(property, value),
+			RemoveLink: s.itemsLink(r, query, withoutLegacyFilter),
+		})
+	}
+	return active
+}
+
+// filterDisplayValue formats a filter value for its chip
+func filterDisplayValue(property, value string) string {
+	switch property {
+	case "color":
+		return format.Color(value)
+	case "shape":
+		return format.ShapeName(value)
+	}
+	return format.Title(value)
+}
+
+// withoutFilter removes every filter parameter equal to raw
+func withoutFilter(raw string) func(url.Values) {
+	return func(q url.Values) {
+		q["filter"] = slices.DeleteFunc(q["filter"], func(f string) bool { return f == raw })
+		if len(q["filter"]) == 0 {
+			q.Del("filter")
+		}
+	}
+}
+
+// withoutLegacyFilter removes the filterBy/filterValue pair
+func withoutLegacyFilter(q url.Values) {
+	q.Del("filterBy")
+	q.Del("filterValue")
+}
+
+// withoutFilters removes every filter in either form
+func withoutFilters(q url.Values) {
+	q.Del("filter")
+	withoutLegacyFilter(q)
+}
+
+// groupOption is one choice of the items page's grouping selector
+type groupOption struct {
+	Value  string
+	Label  string
+	Link   string
+	Active bool
+}
+
+// groupOptions builds the grouping selector for a request to /items with the
+// given query, grouped by groupBy
+func (s *Server) groupOptions(r *http.Request, query url.Values, groupBy string) []groupOption {
+	options := make([]groupOption, 0, len(groupFields))
+	for _, field := range groupFields {
+		options = append(options, groupOption{
+			Value: field,
+			Label: format.Title(field),
+			Link: s.itemsLink(r, query, func(q url.Values) {
+				q.Set("groupBy", field)
+			}),
+			Active: groupBy == field,
+		})
+	}
+	return options
+}
@@ -0,0 +1,365 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// itemsRequest is a request for the default store's items page, for the link
+// builders that scope their links to the request
+var itemsRequest = httptest.NewRequest(http.MethodGet, "/items", nil)
+
+// checkEncodedLink fails unless link is path followed by an encoded query
+// string, with every value escaped, and returns the query
+func checkEncodedLink(t *testing.T, link, path string) url.Values {
+	t.Helper()
+	rest, ok := strings.CutPrefix(link, path)
+	if !ok {
+		t.Fatalf("link %q does not start with %s", link, path)
+	}
+	if i := strings.IndexFunc(rest, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-._~%?=&+", r))
+	}); i >= 0 {
+		t.Fatalf("link %q has the unescaped character %q", link, rest[i])
+	}
+	query, err := url.ParseQuery(strings.TrimPrefix(rest, "?"))
+	if err != nil {
+		t.Fatalf("link %q: %v", link, err)
+	}
+	return query
+}
+
+func TestItemsLink_Filter(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	tests := []struct {
+		name     string
+		rawQuery string
+		property string
+		value    string
+		want     string
+	}{
+		{
+			name:     "no parameters",
+			property: "color",
+			value:    "red",
+			want:     "/items?filter=color%3Ared",
+		},
+		{
+			name:     "keeps grouping and other filters",
+			rawQuery: "groupBy=color&filter=shape:circle",
+			property: "category",
+			value:    "A",
+			want:     "/items?filter=shape%3Acircle&filter=category%3AA&groupBy=color",
+		},
+		{
+			name:     "replaces a filter on the same property",
+			rawQuery: "filter=color:blue&filter=shape:circle",
+			property: "color",
+			value:    "red",
+			want:     "/items?filter=shape%3Acircle&filter=color%3Ared",
+		},
+		{
+			name:     "replaces a legacy filter on the same property",
+			rawQuery: "filterBy=color&filterValue=blue&groupBy=shape",
+			property: "color",
+			value:    "red",
+			want:     "/items?filter=color%3Ared&groupBy=shape",
+		},
+		{
+			name:     "keeps a legacy filter on another property",
+			rawQuery: "filterBy=shape&filterValue=square",
+			property: "color",
+			value:    "red",
+			want:     "/items?filter=color%3Ared&filterBy=shape&filterValue=square",
+		},
+		{
+			name:     "keeps the sort",
+			rawQuery: "sortBy=color&order=desc",
+			property: "shape",
+			value:    "square",
+			want:     "/items?filter=shape%3Asquare&order=desc&sortBy=color",
+		},
+		{
+			name:     "drops flash confirmations",
+			rawQuery: "added=4&groupBy=category",
+			property: "shape",
+			value:    "square",
+			want:     "/items?filter=shape%3Asquare&groupBy=category",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.rawQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := srv.itemsLink(itemsRequest, query, withFilter(tt.property, tt.value)); got != tt.want {
+				t.Errorf("itemsLink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// FuzzItemsLink checks that the links the items page builds from its query
+// escape every value, whatever filters and search the query holds
+func FuzzItemsLink(f *testing.F) {
+	f.Add("color:red", "circle")
+	f.Add(`category:"><script>alert(1)</script>`, `"><fz`)
+	f.Add("shape:a&groupBy=<fz>", "x y+z")
+	f.Add("color:\x00\r\n<", "%zz")
+	srv := newTestServer(f, testConfig(f))
+	f.Fuzz(func(t *testing.T, filter, search string) {
+		query := url.Values{"filter": {filter}, "q": {search}}
+		property, value, _ := strings.Cut(filter, ":")
+		links := []string{
+			srv.itemsLink(itemsRequest, query, withFilter(property, value)),
+			srv.itemsLink(itemsRequest, query, withSort("color", true)),
+		}
+		for _, active := range srv.activeFilters(itemsRequest, query) {
+			links = append(links, active.RemoveLink)
+		}
+		for _, link := range links {
+			if got := checkEncodedLink(t, link, "/items"); got.Get("q") != search {
+				t.Errorf("link %q carries q = %q, want %q", link, got.Get("q"), search)
+			}
+		}
+	})
+}
+
+func TestSortColumns(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.BasePath = "/dash"
+	srv := newTestServer(t, cfg)
+
+	tests := []struct {
+		name     string
+		rawQuery string
+		// want maps each column to its expected link
+		want map[string]string
+		// active is the sorted column and descending its direction
+		active     string
+		descending bool
+	}{
+		{
+			name: "unsorted",
+			want: map[string]string{
+				"id":    "/dash/items?sortBy=id",
+				"color": "/dash/items?sortBy=color",
+			},
+		},
+		{
+			name:     "ascending flips to descending",
+			rawQuery: "sortBy=color&groupBy=category&filter=shape:square",
+			want: map[string]string{
+				"color": "/dash/items?filter=shape%3Asquare&groupBy=category&order=desc&sortBy=color",
+				"shape": "/dash/items?filter=shape%3Asquare&groupBy=category&sortBy=shape",
+			},
+			active: "color",
+		},
+		{
+			name:     "descending flips back to ascending",
+			rawQuery: "sortBy=color&order=desc&updated=2",
+			want: map[string]string{
+				"color":    "/dash/items?sortBy=color",
+				"category": "/dash/items?sortBy=category",
+			},
+			active:     "color",
+			descending: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.rawQuery)
+			parsed, err := parseItemsQuery(query, itemsPageParams, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range srv.sortColumns(itemsRequest, query, parsed) {
+				if want, ok := tt.want[c.Field]; ok && c.Link != want {
+					t.Errorf("%s link = %q, want %q", c.Field, c.Link, want)
+				}
+				if c.Active != (c.Field == tt.active) {
+					t.Errorf("%s active = %v", c.Field, c.Active)
+				}
+				if c.Descending != (c.Active && tt.descending) {
+					t.Errorf("%s descending = %v", c.Field, c.Descending)
+				}
+			}
+		})
+	}
+}
+
+func TestItemsPage_Sort(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	tests := []struct {
+		rawQuery   string
+		wantStatus int
+		wantOrder  []string
+	}{
+		{"sortBy=color&groupBy=none", http.StatusOK, []string{"item-2 ", "item-3 ", "item-1 "}},
+		{"sortBy=color&order=desc&groupBy=none", http.StatusOK, []string{"item-1 ", "item-3 ", "item-2 "}},
+		{"sortBy=weight", http.StatusBadRequest, nil},
+		{"sortBy=id&order=sideways", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?"+tt.rawQuery, nil))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.rawQuery, rec.Code, tt.wantStatus)
+			continue
+		}
+
+		body, last := rec.Body.String(), -1
+		for _, item := range tt.wantOrder {
+			i := strings.Index(body, `class="item `+item)
+			if i < 0 || i < last {
+				t.Errorf("%s: %s is missing or out of order", tt.rawQuery, item)
+			}
+			last = i
+		}
+	}
+}
+
+func TestActiveFilters(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	tests := []struct {
+		name         string
+		rawQuery     string
+		want         []activeFilter
+		wantClearAll string
+	}{
+		{
+			name:         "no filters",
+			rawQuery:     "groupBy=color",
+			wantClearAll: "/items?groupBy=color",
+		},
+		{
+			name:     "one filter",
+			rawQuery: "filter=color:red",
+			want: []activeFilter{
+				{Property: "color", Value: "Red", RemoveLink: "/items"},
+			},
+			wantClearAll: "/items",
+		},
+		{
+			name:     "keeps sort and grouping",
+			rawQuery: "filter=color:red&filter=shape:circle&groupBy=category&sortBy=id&order=desc",
+			want: []activeFilter{
+				{Property: "color", Value: "Red", RemoveLink: "/items?filter=shape%3Acircle&groupBy=category&order=desc&sortBy=id"},
+				{Property: "shape", Value: "Circle", RemoveLink: "/items?filter=color%3Ared&groupBy=category&order=desc&sortBy=id"},
+			},
+			wantClearAll: "/items?groupBy=category&order=desc&sortBy=id",
+		},
+		{
+			name:     "several values of one property",
+			rawQuery: "filter=color:red&filter=color:blue&filter=shape:square",
+			want: []activeFilter{
+				{Property: "color", Value: "Red", RemoveLink: "/items?filter=color%3Ablue&filter=shape%3Asquare"},
+				{Property: "color", Value: "Blue", RemoveLink: "/items?filter=color%3Ared&filter=shape%3Asquare"},
+				{Property: "shape", Value: "Square", RemoveLink: "/items?filter=color%3Ared&filter=color%3Ablue"},
+			},
+			wantClearAll: "/items",
+		},
+		{
+			name:     "repeated filter is one chip",
+			rawQuery: "filter=color:red&filter=shape:circle&filter=color:red",
+			want: []activeFilter{
+				{Property: "color", Value: "Red", RemoveLink: "/items?filter=shape%3Acircle"},
+				{Property: "shape", Value: "Circle", RemoveLink: "/items?filter=color%3Ared&filter=color%3Ared"},
+			},
+			wantClearAll: "/items",
+		},
+		{
+			name:     "value containing a colon",
+			rawQuery: "filter=category:A%3AB&groupBy=none",
+			want: []activeFilter{
+				{Property: "category", Value: "A:B", RemoveLink: "/items?groupBy=none"},
+			},
+			wantClearAll: "/items?groupBy=none",
+		},
+		{
+			name:     "legacy filter",
+			rawQuery: "filterBy=shape&filterValue=square&filter=color:red",
+			want: []activeFilter{
+				{Property: "color", Value: "Red", RemoveLink: "/items?filterBy=shape&filterValue=square"},
+				{Property: "shape", Value: "Square", RemoveLink: "/items?filter=color%3Ared"},
+			},
+			wantClearAll: "/items",
+		},
+		{
+			name:     "malformed filter and flash are dropped",
+			rawQuery: "filter=red&filter=shape:circle&deleted=3",
+			want: []activeFilter{
+				{Property: "shape", Value: "Circle", RemoveLink: "/items?filter=red"},
+			},
+			wantClearAll: "/items",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.rawQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := srv.activeFilters(itemsRequest, query)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("activeFilters() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			if got := srv.itemsLink(itemsRequest, query, withoutFilters); got != tt.wantClearAll {
+				t.Errorf("clear all link = %q, want %q", got, tt.wantClearAll)
+			}
+		})
+	}
+}
+
+func TestItemsPage_ActiveFilters(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?filter=color:red&filter=shape:circle", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`<span class="filter-value">Red</span>`,
+		`<a class="remove-filter" href="/items?filter=shape%3Acircle&amp;groupBy=shape"`,
+		`<a class="clear-filters" href="/items?groupBy=shape">Clear all</a>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("items page does not contain %s", want)
+		}
+	}
+}
+
+func TestGroupOptions(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	query, _ := url.ParseQuery("groupBy=color&filter=shape:circle&sortBy=id&added=5")
+	options := srv.groupOptions(itemsRequest, query, "color")
+
+	want := map[string]string{
+		"color":    "/items?filter=shape%3Acircle&groupBy=color&sortBy=id",
+		"shape":    "/items?filter=shape%3Acircle&groupBy=shape&sortBy=id",
+		"category": "/items?filter=shape%3Acircle&groupBy=category&sortBy=id",
+		"none":     "/items?filter=shape%3Acircle&groupBy=none&sortBy=id",
+	}
+	if len(options) != len(want) {
+		t.Fatalf("got %d options, want %d", len(options), len(want))
+	}
+	for _, o := range options {
+		if o.Link != want[o.Value] {
+			t.Errorf("%s link = %q, want %q", o.Value, o.Link, want[o.Value])
+		}
+		if o.Active != (o.Value == "color") {
+			t.Errorf("%s active = %v", o.Value, o.Active)
+		}
+	}
+}
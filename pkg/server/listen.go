@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// unixAddrPrefix marks an --addr value as a Unix domain socket path
+const unixAddrPrefix = "unix:"
+
+// shutdownTimeout bounds how long in-flight requests may take to finish once
+// the server is asked to stop
+const shutdownTimeout = 10 * time.Second
+
+// Listen opens the listener for addr. "unix:/path" listens on a Unix domain
+// socket created with the given permissions, replacing a stale socket file
+// left behind by a previous run; anything else is a TCP address.
+func Listen(addr string, socketMode os.FileMode) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixAddrPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("listen on %q: missing socket path", addr)
+	}
+
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, socketMode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("set socket permissions: %w", err)
+	}
+	return ln, nil
+}
+
+// removeStaleSocket deletes the socket file at path unless another process is
+// still accepting connections on it. Files that are not sockets are never
+// removed.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("listen on %s: file exists and is not a socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("listen on %s: socket is in use by another process", path)
+	}
+	return os.Remove(path)
+}
+
+// ListenAndServe listens on the configured address, with TLS when it is
+// configured, and serves until ctx is canceled
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	tlsConfig, err := NewTLSConfig(s.cfg)
+	if err != nil {
+		return err
+	}
+	ln, err := Listen(s.cfg.Addr, s.cfg.SocketMode)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln, tlsConfig)
+}
+
+// Serve handles connections from ln until ctx is canceled, then shuts down
+// gracefully and flushes queued webhook deliveries. Closing a Unix listener
+// removes its socket file. With a non-nil tlsConfig the connections are served
+// over TLS.
+func (s *Server) Serve(ctx context.Context, ln net.Listener, tlsConfig *tls.Config) error {
+	timeouts := s.cfg.Timeouts
+	httpServer := &http.Server{
+		Handler:           s.Handler(),
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: timeouts.ReadHeader,
+		ReadTimeout:       timeouts.Read,
+		WriteTimeout:      timeouts.Write,
+		IdleTimeout:       timeouts.Idle,
+		ErrorLog:          slog.NewLogLogger(s.logger.Handler(), slog.LevelWarn),
+	}
+	// Event streams never finish on their own and item streams can run long,
+	// so end both rather than have Shutdown wait for them
+	httpServer.RegisterOnShutdown(s.beginShutdown)
+
+	s.logger.Info("server starting",
+		"addr", ln.Addr().String(),
+		"network", ln.Addr().Network(),
+		"tls", tlsConfig != nil,
+		"self_signed", s.cfg.TLSSelfSigned)
+	errCh := make(chan error, 1)
+	go func() {
+		if tlsConfig != nil {
+			errCh <- httpServer.ServeTLS(ln, "", "")
+		} else {
+			errCh <- httpServer.Serve(ln)
+		}
+	}()
+
+	var serveErr error
+	select {
+	case serveErr = <-errCh:
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if serveErr == nil {
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			serveErr = fmt.Errorf("shutdown: %w", err)
+		}
+	}
+	if s.webhooks != nil {
+		if err := s.webhooks.Close(shutdownCtx); err != nil {
+			s.logger.Warn("pending webhook deliveries abandoned", "error", err)
+		}
+	}
+	if s.audit != nil {
+		if err := s.audit.Close(); err != nil {
+			s.logger.Warn("failed to close the audit log", "error", err)
+		}
+	}
+	return serveErr
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServeUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dashboard.sock")
+	ln, err := Listen(unixAddrPrefix+path, 0o600)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat socket: %v", err)
+	}
+	if info.Mode().Type() != fs.ModeSocket || info.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode = %v, want a socket with 0600 permissions", info.Mode())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- newTestServer(t, testConfig(t)).Serve(ctx, ln, nil) }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://dashboard/healthz")
+	if err != nil {
+		t.Fatalf("GET over unix socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve() did not return after the context was canceled")
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("socket file still present after shutdown: %v", err)
+	}
+}
+
+func TestServe_ShutdownEndsEventStreams(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- newTestServer(t, testConfig(t)).Serve(ctx, ln, nil) }()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/api/events")
+	if err != nil {
+		t.Fatalf("GET /api/events: %v", err)
+	}
+	defer resp.Body.Close()
+	select {
+	case e := <-readSSE(bufio.NewReader(resp.Body)):
+		if e.name != "snapshot" {
+			t.Fatalf("first event = %+v, want a snapshot", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the snapshot")
+	}
+
+	// The subscriber stays connected while the server shuts down
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve() did not return with an event stream open")
+	}
+}
+
+func TestListenUnix_StaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dashboard.sock")
+
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := Listen(unixAddrPrefix+path, 0o660)
+	if err != nil {
+		t.Fatalf("Listen() over a stale socket: %v", err)
+	}
+	ln.Close()
+}
+
+func TestListenUnix_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	inUse := filepath.Join(dir, "in-use.sock")
+	ln, err := net.Listen("unix", inUse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	regular := filepath.Join(dir, "regular.sock")
+	if err := os.WriteFile(regular, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, addr := range []string{"unix:", unixAddrPrefix + inUse, unixAddrPrefix + regular} {
+		if ln, err := Listen(addr, 0o660); err == nil {
+			ln.Close()
+			t.Errorf("Listen(%q) succeeded, want an error", addr)
+		}
+	}
+	if _, err := os.Stat(regular); err != nil {
+		t.Errorf("listen removed a regular file: %v", err)
+	}
+}
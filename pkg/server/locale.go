@@ -0,0 +1,131 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+	"github.com/ElodinLaarz/dashboard/pkg/i18n"
+)
+
+const (
+	// defaultLanguage is the language of the user interface when the
+	// visitor asks for none with a catalog, and the locale of numbers and
+	// plurals when they ask for none the format package knows
+	defaultLanguage = "en"
+	// langParam switches the user interface language on any page, and
+	// langCookie remembers the choice
+	langParam        = "lang"
+	langCookie       = "lang"
+	langCookieMaxAge = 365 * 24 * 60 * 60
+)
+
+// loadMessages loads the embedded message catalogs, one per language
+func loadMessages() (*i18n.Bundle, error) {
+	fsys, err := fs.Sub(embedFS, "locales")
+	if err != nil {
+		return nil, fmt.Errorf("locales directory in embedded filesystem: %w", err)
+	}
+	return i18n.Load(fsys, defaultLanguage)
+}
+
+// parseAcceptLanguage returns the language tags of an Accept-Language header
+// from most to least preferred. Tags with q=0, malformed q-values, and the
+// "*" wildcard are left out; equal q-values keep the header's order.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for part := range strings.SplitSeq(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(value, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		if q == 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag, q})
+	}
+	slices.SortStableFunc(tags, func(a, b weighted) int { return cmp.Compare(b.q, a.q) })
+
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = t.tag
+	}
+	return out
+}
+
+// chosenLanguage returns the language the visitor picked with ?lang=, or
+// earlier as remembered by the cookie, if it has a catalog
+func (s *Server) chosenLanguage(r *http.Request) (string, bool) {
+	if lang, ok := s.messages.Match(r.URL.Query().Get(langParam)); ok {
+		return lang, true
+	}
+	if c, err := r.Cookie(langCookie); err == nil {
+		return s.messages.Match(c.Value)
+	}
+	return "", false
+}
+
+// requestLanguage returns the language of the user interface for r: the one
+// the visitor picked, else the most preferred of Accept-Language with a
+// catalog, else defaultLanguage
+func (s *Server) requestLanguage(r *http.Request) string {
+	if lang, ok := s.chosenLanguage(r); ok {
+		return lang
+	}
+	if lang, ok := s.messages.Match(parseAcceptLanguage(r.Header.Get("Accept-Language"))...); ok {
+		return lang
+	}
+	return defaultLanguage
+}
+
+// requestLocale returns the locale numbers and plurals are formatted for: the
+// language the visitor picked, else the most preferred locale of
+// Accept-Language that the format package knows
+func (s *Server) requestLocale(r *http.Request) string {
+	if lang, ok := s.chosenLanguage(r); ok {
+		return lang
+	}
+	for _, tag := range parseAcceptLanguage(r.Header.Get("Accept-Language")) {
+		if format.SupportsLocale(tag) {
+			return tag
+		}
+	}
+	return defaultLanguage
+}
+
+// languageMiddleware remembers a language picked with ?lang= in a cookie.
+// Unknown languages are ignored. The parameter is accepted on every page.
+func (s *Server) languageMiddleware(next http.Handler) http.Handler {
+	exemptQueryParam(langParam)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if lang, ok := s.messages.Match(r.URL.Query().Get(langParam)); ok {
+			http.SetCookie(w, &http.Cookie{
+				Name:     langCookie,
+				Value:    lang,
+				Path:     s.url("/"),
+				MaxAge:   langCookieMaxAge,
+				HttpOnly: true,
+				Secure:   s.requestScheme(r) == "https",
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		next.ServeHTTP(w, r)
+	})
+}
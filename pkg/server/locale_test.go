@@ -0,0 +1,191 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := map[string][]string{
+		"":                                   {},
+		"de":                                 {"de"},
+		"fr-CH, fr;q=0.9, en;q=0.8, *;q=0.5": {"fr-CH", "fr", "en"},
+		"en;q=0.5, de":                       {"de", "en"},
+		"da, en-GB;q=0.8, en;q=0.8":          {"da", "en-GB", "en"},
+		"de;q=0, en":                         {"en"},
+		"de;q=abc, en;q=2, fr":               {"fr"},
+		" , es ;q=0.3,":                      {"es"},
+	}
+	for header, want := range tests {
+		if got := parseAcceptLanguage(header); !reflect.DeepEqual(got, want) {
+			t.Errorf("parseAcceptLanguage(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestRequestLocale(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	tests := []struct {
+		target, acceptLanguage, want string
+	}{
+		{"/items", "", "en"},
+		{"/items", "de-DE,de;q=0.9", "de-DE"},
+		{"/items", "tlh, fr;q=0.5", "fr"},
+		{"/items", "tlh", "en"},
+		{"/items", "en;q=0.1, hi;q=0.9", "hi"},
+		// A language picked for the messages formats numbers too
+		{"/items?lang=de", "hi", "de"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		req.Header.Set("Accept-Language", tt.acceptLanguage)
+		if got := srv.requestLocale(req); got != tt.want {
+			t.Errorf("requestLocale(%s, Accept-Language: %q) = %q, want %q", tt.target, tt.acceptLanguage, got, tt.want)
+		}
+	}
+}
+
+func TestRequestLanguage(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	tests := []struct {
+		name           string
+		target         string
+		cookie         string
+		acceptLanguage string
+		want           string
+	}{
+		{"default", "/items", "", "", "en"},
+		{"header", "/items", "", "de-AT, en;q=0.8", "de"},
+		{"header order by q-value", "/items", "", "en;q=0.5, de;q=0.9", "de"},
+		{"first header language with a catalog", "/items", "", "fr, it;q=0.9, en;q=0.8, de;q=0.7", "en"},
+		{"cookie wins over the header", "/items", "de", "en", "de"},
+		{"unknown cookie is ignored", "/items", "fr", "de", "de"},
+		{"query wins over the cookie", "/items?lang=en", "de", "de", "en"},
+		{"unknown query is ignored", "/items?lang=xx", "de", "en", "de"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: langCookie, Value: tt.cookie})
+			}
+			if got := srv.requestLanguage(req); got != tt.want {
+				t.Errorf("requestLanguage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestItemsPage_Language(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	rec := getItems(t, srv, "/items?lang=de&strict=1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /items?lang=de&strict=1 status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Content-Language"); got != "de" {
+		t.Errorf("Content-Language = %q, want de", got)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "Aktive Filter") || strings.Contains(body, "Active Filters") {
+		t.Error("page is not in German")
+	}
+	if strings.Contains(body, "lang=de") {
+		t.Error("links carry the language switch forward")
+	}
+
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == langCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Value != "de" || cookie.Path != "/" {
+		t.Fatalf("language cookie = %+v, want de for every page", cookie)
+	}
+
+	// The cookie keeps the language on later visits
+	rec = getItems(t, srv, "/items", cookie)
+	if !strings.Contains(rec.Body.String(), "Aktive Filter") {
+		t.Error("cookie did not keep the page in German")
+	}
+
+	rec = getItems(t, srv, "/items")
+	if got := rec.Header().Get("Content-Language"); got != "en" {
+		t.Errorf("Content-Language without a choice = %q, want en", got)
+	}
+}
+
+func TestMessageCatalogs(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	english := srv.messages.Keys(defaultLanguage)
+
+	// Every key used by a template has an English message
+	used := regexp.MustCompile(`\{\{t "([^"]+)"\}\}`)
+	pages, err := filepath.Glob(filepath.Join("templates", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range pages {
+		data, err := os.ReadFile(page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range used.FindAllStringSubmatch(string(data), -1) {
+			if !slices.Contains(english, m[1]) {
+				t.Errorf("%s uses %q, which has no English message", page, m[1])
+			}
+		}
+	}
+
+	// Other catalogs only translate English messages
+	for _, lang := range srv.messages.Languages() {
+		for _, key := range srv.messages.Keys(lang) {
+			if !slices.Contains(english, key) {
+				t.Errorf("%s catalog has %q, which has no English message", lang, key)
+			}
+		}
+	}
+}
+
+func TestItemsPage_Locale(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	items := make([]itemstore.Item, 1200)
+	for i := range items {
+		items[i] = itemstore.Item{Color: "red", Shape: "circle", Category: "A"}
+	}
+	if _, err := srv.store.AddAll(items); err != nil {
+		t.Fatalf("AddAll() error = %v", err)
+	}
+
+	get := func(acceptLanguage string) string {
+		req := httptest.NewRequest(http.MethodGet, "/items?groupBy=category", nil)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+
+	body := get("")
+	for _, want := range []string{"A categories", "1,202 items", `<span class="item-count">1,201</span>`, "1 item<"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("English page does not contain %q", want)
+		}
+	}
+	body = get("de-DE, en;q=0.5")
+	for _, want := range []string{"1.202 items", `<span class="item-count">1.201</span>`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("German page does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,551 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/ratelimit"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// corsMiddleware adds CORS headers to API responses for allowed origins and
+// answers preflight requests. Requests from other origins are passed through
+// without CORS headers, so the browser blocks them.
+func corsMiddleware(cfg config.CORS, next http.Handler) http.Handler {
+	if len(cfg.AllowedOrigins) == 0 {
+		return next
+	}
+
+	allowAny := slices.Contains(cfg.AllowedOrigins, "*")
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	originAllowed := func(origin string) bool {
+		return allowAny || slices.Contains(cfg.AllowedOrigins, origin)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if !isAPIPath(r.URL.Path) || origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if !originAllowed(origin) {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// A literal "*" is not valid together with credentials, so echo the
+		// origin whenever credentials are allowed
+		if allowAny && !cfg.AllowCredentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			h.Set("Access-Control-Expose-Headers", "Location, X-Request-ID")
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		if !containsFold(cfg.AllowedMethods, r.Header.Get("Access-Control-Request-Method")) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		for _, name := range splitList(r.Header.Get("Access-Control-Request-Headers")) {
+			if !containsFold(cfg.AllowedHeaders, name) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+
+		h.Set("Access-Control-Allow-Methods", methods)
+		h.Set("Access-Control-Allow-Headers", headers)
+		h.Set("Access-Control-Max-Age", maxAge)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// isAPIPath reports whether path belongs to a machine-facing API, which is
+// subject to CORS and rate limiting
+func isAPIPath(path string) bool {
+	return strings.HasPrefix(path, "/api/") || path == graphqlPath
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool { return strings.EqualFold(v, s) })
+}
+
+// splitList splits a comma-separated header value, dropping empty entries
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// rateLimitMiddleware rejects API requests from clients that have used up
+// their token bucket with 429 and a Retry-After header
+func (s *Server) rateLimitMiddleware(limiter *ratelimit.Limiter, next http.Handler) http.Handler {
+	if limiter == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAPIPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if ok, wait := limiter.Allow(s.clientIP(r)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			s.writeError(w, r, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestIDHeader carries the request ID in both directions
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds client-supplied request IDs
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// requestIDMiddleware tags every request with an ID, taken from a sane
+// incoming X-Request-ID header or freshly generated. The ID is stored on the
+// request context and echoed in the response header.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// RequestIDFromContext returns the request ID stored by requestIDMiddleware,
+// or "" if there is none
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID accepts short IDs made of characters that are safe to echo
+// into headers and log lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 128-bit hex ID
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// recoverMiddleware turns a panicking handler into a 500 response instead of
+// a dropped connection. The panic value and stack are logged with the request
+// ID but never sent to the client. http.ErrAbortHandler is re-panicked so
+// net/http can abort the response as intended.
+func (s *Server) recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			s.logger.ErrorContext(r.Context(), "panic serving request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"panic", fmt.Sprint(rec),
+				"stack", string(debug.Stack()),
+				"request_id", RequestIDFromContext(r.Context()))
+
+			s.renderError(w, r, http.StatusInternalServerError, internalErrorMessage)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// errReadOnly answers writes while the server is read-only
+var errReadOnly = &httpError{status: http.StatusForbidden, message: "server is read-only"}
+
+// itemFormPath matches the pages holding the add, edit, and delete forms of
+// the default store or a collection
+var itemFormPath = regexp.MustCompile(`^(` + collectionPagePrefix + `[^/]+)?/items/(new|[^/]+/edit|[^/]+/delete)$`)
+
+// readOnlyMiddleware refuses every write request, and the pages of the forms
+// that would send one, with 403 when readOnly is set. It runs before the API
+// key check, so the refusal does not depend on the key. Creating a short link
+// changes no items, and graphqlHandler refuses only mutations, so both pass.
+func (s *Server) readOnlyMiddleware(readOnly bool, next http.Handler) http.Handler {
+	if !readOnly {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := unscopedPath(r.URL.Path)
+		write := isWriteMethod(r.Method) && path != sharePath && path != graphqlPath
+		if write || itemFormPath.MatchString(r.URL.Path) {
+			s.respondError(w, r, errReadOnly)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiKeyMiddleware requires a valid API key, sent as "Authorization: Bearer
+// <key>" or "X-API-Key: <key>", on write requests under /api/. Reads are
+// always open. With no keys configured, writes are refused unless
+// allowUnauthenticated is set.
+func (s *Server) apiKeyMiddleware(keys []string, allowUnauthenticated bool, next http.Handler) http.Handler {
+	auth := newAPIKeyAuth(keys, allowUnauthenticated)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Creating a short link changes no items, so it needs no key
+		if !strings.HasPrefix(r.URL.Path, "/api/") || !isWriteMethod(r.Method) || unscopedPath(r.URL.Path) == sharePath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if s.authorizeWrite(w, r, auth) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// apiKeyAuth is the API key policy for writes
+type apiKeyAuth struct {
+	// digests are compared instead of the keys so neither the key contents
+	// nor their lengths influence the comparison time
+	digests              [][sha256.Size]byte
+	allowUnauthenticated bool
+}
+
+func newAPIKeyAuth(keys []string, allowUnauthenticated bool) apiKeyAuth {
+	digests := make([][sha256.Size]byte, len(keys))
+	for i, key := range keys {
+		digests[i] = sha256.Sum256([]byte(key))
+	}
+	return apiKeyAuth{digests: digests, allowUnauthenticated: allowUnauthenticated}
+}
+
+// authorizeWrite reports whether r carries credentials that satisfy auth.
+// When it does not, the 401 or 403 response has already been written.
+func (s *Server) authorizeWrite(w http.ResponseWriter, r *http.Request, auth apiKeyAuth) bool {
+	if len(auth.digests) == 0 {
+		if auth.allowUnauthenticated {
+			return true
+		}
+		s.writeError(w, r, http.StatusForbidden, "writes are disabled: no API keys are configured")
+		return false
+	}
+
+	key := requestAPIKey(r)
+	given := sha256.Sum256([]byte(key))
+	match := 0
+	for _, d := range auth.digests {
+		match |= subtle.ConstantTimeCompare(given[:], d[:])
+	}
+	if key == "" || match != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="dashboard"`)
+		s.writeError(w, r, http.StatusUnauthorized, "a valid API key is required")
+		return false
+	}
+	return true
+}
+
+// requestAPIKey returns the key from the Authorization bearer token or, failing
+// that, the X-API-Key header
+func requestAPIKey(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// isWriteMethod reports whether method can modify server state
+func isWriteMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// basicAuthMiddleware protects every route except the probePaths with
+// HTTP Basic Auth when user is non-empty. The password is checked against a
+// bcrypt hash even when the username is wrong, so response timing does not
+// reveal whether the username exists.
+func (s *Server) basicAuthMiddleware(user, passwordHash string, next http.Handler) http.Handler {
+	if user == "" {
+		return next
+	}
+	userDigest := sha256.Sum256([]byte(user))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(probePaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		givenUser, givenPassword, ok := r.BasicAuth()
+		givenDigest := sha256.Sum256([]byte(givenUser))
+		userOK := subtle.ConstantTimeCompare(givenDigest[:], userDigest[:]) == 1
+		passwordOK := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(givenPassword)) == nil
+
+		if !ok || !userOK || !passwordOK {
+			w.Header().Set("WWW-Authenticate", `Basic realm="dashboard", charset="UTF-8"`)
+			if wantsHTML(r) {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			s.writeError(w, r, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		setAccessLogUser(r.Context(), givenUser)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// endOnShutdownMiddleware cancels next's request context once the server
+// starts shutting down, so that a stream still open does not hold up Shutdown
+func (s *Server) endOnShutdownMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		stop := context.AfterFunc(s.shutdown, cancel)
+		defer stop()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// timeoutMessage is the error reported when a request exceeds its deadline
+const timeoutMessage = "request timed out"
+
+// timeoutMiddleware gives next's request a deadline d from now and, if next
+// has not finished by then, answers 503 with the error envelope, or the
+// error page for a browser. The response is held back until next finishes so
+// that it can be dropped for the 503, unless next flushes it: from then on it
+// streams, and the deadline only ends it early. Long-lived routes are
+// registered without the timeout. A zero d disables it.
+func (s *Server) timeoutMiddleware(d time.Duration, next http.Handler) http.Handler {
+	if d <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+
+		tw := &timeoutWriter{w: w, ctx: ctx, header: make(http.Header)}
+		done := make(chan struct{})
+		panicked := make(chan any, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					if p != http.ErrAbortHandler {
+						p = handlerPanic{value: p, stack: debug.Stack()}
+					}
+					panicked <- p
+				}
+			}()
+			next.ServeHTTP(tw, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			// Carried over to this goroutine for recoverMiddleware
+			panic(p)
+		case <-done:
+			if tw.copyTo() {
+				return
+			}
+		case <-ctx.Done():
+			if tw.isCommitted() {
+				// The response is under way, so next, whose writes now fail,
+				// finishes it however far it got
+				select {
+				case p := <-panicked:
+					panic(p)
+				case <-done:
+				}
+				return
+			}
+		}
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// The client left, so there is no one to answer
+			return
+		}
+		s.logger.WarnContext(r.Context(), "request timed out",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"timeout", d,
+			"request_id", RequestIDFromContext(r.Context()))
+		s.renderError(w, r, http.StatusServiceUnavailable, timeoutMessage)
+	})
+}
+
+// handlerPanic is a panic of a timed handler, carried out of the goroutine
+// it ran on with the stack it happened on
+type handlerPanic struct {
+	value any
+	stack []byte
+}
+
+func (p handlerPanic) String() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
+}
+
+// timeoutWriter holds a timed handler's response until the handler
+// finishes, or discards it once the deadline has passed. A flush commits
+// the response to w, and later writes go straight through. Writes fail once
+// ctx is done, whichever goroutine notices it first.
+type timeoutWriter struct {
+	w   http.ResponseWriter
+	ctx context.Context
+	// header is only touched by the handler until it finishes
+	header http.Header
+
+	mu        sync.Mutex
+	status    int
+	body      bytes.Buffer
+	committed bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.status == 0 {
+		tw.status = status
+	}
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.ctx.Err() != nil {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.committed {
+		return tw.w.Write(p)
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.body.Write(p)
+}
+
+// FlushError commits the response, sending what is held back, and flushes
+// it, so http.ResponseController can stream through the writer
+func (tw *timeoutWriter) FlushError() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.ctx.Err() != nil {
+		return http.ErrHandlerTimeout
+	}
+	if !tw.committed {
+		tw.committed = true
+		if _, err := tw.send(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(tw.w).Flush()
+}
+
+// Unwrap returns the ResponseWriter the response is sent to, for
+// http.ResponseController
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
+
+// isCommitted reports whether a flush has sent the response on its way.
+// Once ctx is done, no later flush can change the answer.
+func (tw *timeoutWriter) isCommitted() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.committed
+}
+
+// copyTo sends the finished response, or what is left of it, to w. It
+// reports false, sending nothing, if ctx ended before the response was
+// committed, so the handler's response is dropped for the timeout's.
+func (tw *timeoutWriter) copyTo() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.committed {
+		return true
+	}
+	if tw.ctx.Err() != nil {
+		return false
+	}
+	tw.send()
+	return true
+}
+
+// send writes the header and the body held back to w. tw.mu must be held.
+func (tw *timeoutWriter) send() (int, error) {
+	maps.Copy(tw.w.Header(), tw.header)
+	tw.w.WriteHeader(cmp.Or(tw.status, http.StatusOK))
+	n, err := tw.w.Write(tw.body.Bytes())
+	tw.body.Reset()
+	return n, err
+}
@@ -0,0 +1,676 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/ratelimit"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	cfg := config.CORS{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "PUT"},
+		AllowedHeaders: []string{"Content-Type", "X-Custom-Header"},
+		MaxAge:         5 * time.Minute,
+	}
+	handler := corsMiddleware(cfg, okHandler())
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		headers     map[string]string
+		wantStatus  int
+		wantHeaders map[string]string
+	}{
+		{
+			name:       "matching origin",
+			method:     http.MethodGet,
+			path:       "/api/items",
+			headers:    map[string]string{"Origin": "https://app.example.com"},
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		{
+			name:       "rejected origin",
+			method:     http.MethodGet,
+			path:       "/api/items",
+			headers:    map[string]string{"Origin": "https://evil.example.com"},
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name:   "preflight for PUT with a custom header",
+			method: http.MethodOptions,
+			path:   "/api/items/1",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "PUT",
+				"Access-Control-Request-Headers": "content-type, x-custom-header",
+			},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example.com",
+				"Access-Control-Allow-Methods": "GET, PUT",
+				"Access-Control-Allow-Headers": "Content-Type, X-Custom-Header",
+				"Access-Control-Max-Age":       "300",
+			},
+		},
+		{
+			name:   "preflight with a disallowed header",
+			method: http.MethodOptions,
+			path:   "/api/items/1",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "PUT",
+				"Access-Control-Request-Headers": "X-Other",
+			},
+			wantStatus: http.StatusForbidden,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+		{
+			name:       "non-API path",
+			method:     http.MethodGet,
+			path:       "/items",
+			headers:    map[string]string{"Origin": "https://app.example.com"},
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			for k, want := range tt.wantHeaders {
+				if got := rec.Header().Get(k); got != want {
+					t.Errorf("%s = %q, want %q", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSMiddleware_DisabledByDefault(t *testing.T) {
+	cfg := testConfig(t)
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+	corsMiddleware(cfg.CORS, okHandler()).ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+	}
+}
+
+func TestCORSMiddleware_WildcardWithCredentials(t *testing.T) {
+	cfg := config.CORS{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+	corsMiddleware(cfg, okHandler()).ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the echoed origin", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+	}
+}
+
+func TestRateLimitMiddleware_Burst(t *testing.T) {
+	s := newTestServer(t, testConfig(t))
+	limiter := ratelimit.New(1, 5)
+	srv := httptest.NewServer(s.rateLimitMiddleware(limiter, s.routes()))
+	defer srv.Close()
+
+	var ok, limited int
+	for i := 0; i < 12; i++ {
+		resp, err := http.Get(srv.URL + "/api/items")
+		if err != nil {
+			t.Fatalf("GET error = %v", err)
+		}
+		resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusOK:
+			ok++
+		case http.StatusTooManyRequests:
+			limited++
+			if resp.Header.Get("Retry-After") == "" {
+				t.Error("429 response has no Retry-After header")
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("429 Content-Type = %q, want application/json", ct)
+			}
+		default:
+			t.Fatalf("unexpected status %d", resp.StatusCode)
+		}
+	}
+
+	if ok != 5 || limited != 7 {
+		t.Errorf("got %d OK and %d limited responses, want 5 and 7", ok, limited)
+	}
+
+	// Pages outside /api/ are not limited
+	resp, err := http.Get(srv.URL + "/items")
+	if err != nil {
+		t.Fatalf("GET /items error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /items status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	// GraphQL shares the API budget, which is used up by now
+	resp, err = http.Get(srv.URL + "/graphql?query=" + url.QueryEscape("{ items { id } }"))
+	if err != nil {
+		t.Fatalf("GET /graphql error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("GET /graphql status = %d, want %d", resp.StatusCode, http.StatusTooManyRequests)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	t.Run("supplied ID is preserved", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-ID", "abc-123")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if seen != "abc-123" {
+			t.Errorf("context ID = %q, want abc-123", seen)
+		}
+		if got := rec.Header().Get("X-Request-ID"); got != "abc-123" {
+			t.Errorf("X-Request-ID = %q, want abc-123", got)
+		}
+	})
+
+	t.Run("insane ID is replaced", func(t *testing.T) {
+		for _, id := range []string{"has space", "new\nline", strings.Repeat("x", 200)} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Request-ID", id)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if seen == id || seen == "" {
+				t.Errorf("ID %q was not replaced (got %q)", id, seen)
+			}
+		}
+	})
+
+	t.Run("generated IDs are unique", func(t *testing.T) {
+		ids := make(map[string]bool)
+		for i := 0; i < 100; i++ {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			id := rec.Header().Get("X-Request-ID")
+			if id == "" || ids[id] {
+				t.Fatalf("generated ID %q is empty or repeated", id)
+			}
+			ids[id] = true
+		}
+	})
+}
+
+func TestRequestID_InLogAndErrorBody(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	buf := captureLogs(srv)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/items/999", nil)
+	req.Header.Set("X-Request-ID", "trace-me-42")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	var logged bool
+	for _, r := range logRecords(t, buf) {
+		if r["msg"] == "request" && r["request_id"] == "trace-me-42" {
+			logged = true
+		}
+	}
+	if !logged {
+		t.Errorf("log output %q has no access record with the request ID", buf.String())
+	}
+	var resp errorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+	if resp.RequestID != "trace-me-42" {
+		t.Errorf("error envelope requestId = %q, want trace-me-42", resp.RequestID)
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	s := newTestServer(t, testConfig(t))
+	logs := captureLogs(s)
+	mux := s.routes()
+	mux.HandleFunc("/boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("secret internal detail")
+	})
+	srv := httptest.NewServer(s.withMiddleware(mux))
+	defer srv.Close()
+
+	t.Run("JSON client", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/boom")
+		if err != nil {
+			t.Fatalf("GET /boom error = %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusInternalServerError)
+		}
+		var envelope errorResponse
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			t.Fatalf("Failed to decode error envelope %q: %v", body, err)
+		}
+		if envelope.Status != http.StatusInternalServerError || envelope.RequestID == "" {
+			t.Errorf("error envelope = %+v, want status 500 with a request ID", envelope)
+		}
+		if strings.Contains(string(body), "secret") {
+			t.Errorf("response leaks the panic message: %s", body)
+		}
+
+		var logged bool
+		for _, rec := range logRecords(t, logs) {
+			if rec["msg"] == "panic serving request" && rec["request_id"] == envelope.RequestID {
+				logged = rec["panic"] == "secret internal detail" && rec["stack"] != ""
+			}
+		}
+		if !logged {
+			t.Errorf("panic and stack were not logged with the request ID: %s", logs)
+		}
+	})
+
+	t.Run("browser client", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/boom", nil)
+		req.Header.Set("Accept", "text/html")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /boom error = %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusInternalServerError)
+		}
+		if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+			t.Errorf("Content-Type = %q, want text/html", resp.Header.Get("Content-Type"))
+		}
+		if strings.Contains(string(body), "secret") {
+			t.Errorf("response leaks the panic message: %s", body)
+		}
+	})
+
+	t.Run("server keeps serving", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/api/items")
+		if err != nil {
+			t.Fatalf("GET /api/items error = %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+	})
+}
+
+func TestRecoverMiddleware_RepanicsAbortHandler(t *testing.T) {
+	handler := newTestServer(t, testConfig(t)).recoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", rec)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		keys        []string
+		allowUnauth bool
+		method      string
+		path        string
+		headers     map[string]string
+		wantStatus  int
+	}{
+		{
+			name:       "valid bearer key",
+			keys:       []string{"k1", "k2"},
+			method:     http.MethodPost,
+			path:       "/api/items",
+			headers:    map[string]string{"Authorization": "Bearer k2"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "valid X-API-Key",
+			keys:       []string{"k1"},
+			method:     http.MethodDelete,
+			path:       "/api/items/1",
+			headers:    map[string]string{"X-API-Key": "k1"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing key",
+			keys:       []string{"k1"},
+			method:     http.MethodPut,
+			path:       "/api/items/1",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong key",
+			keys:       []string{"k1"},
+			method:     http.MethodPatch,
+			path:       "/api/items/1",
+			headers:    map[string]string{"Authorization": "Bearer k1x"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "no keys configured fails closed",
+			method:     http.MethodPost,
+			path:       "/api/items",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:        "no keys configured with unauthenticated writes allowed",
+			allowUnauth: true,
+			method:      http.MethodPost,
+			path:        "/api/items",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:       "reads stay open",
+			keys:       []string{"k1"},
+			method:     http.MethodGet,
+			path:       "/api/items",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	srv := newTestServer(t, testConfig(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			srv.apiKeyMiddleware(tt.keys, tt.allowUnauth, okHandler()).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 response has no WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestBasicAuthMiddleware(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	buf := captureLogs(srv)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	handler := srv.logRequest(srv.basicAuthMiddleware("alice", string(hash), okHandler()))
+
+	tests := []struct {
+		name       string
+		path       string
+		user, pass string
+		noAuth     bool
+		wantStatus int
+	}{
+		{name: "correct credentials", path: "/items", user: "alice", pass: "s3cret", wantStatus: http.StatusOK},
+		{name: "static assets covered", path: "/static/htmx.min.js", noAuth: true, wantStatus: http.StatusUnauthorized},
+		{name: "wrong password", path: "/items", user: "alice", pass: "guess", wantStatus: http.StatusUnauthorized},
+		{name: "wrong username", path: "/items", user: "mallory", pass: "s3cret", wantStatus: http.StatusUnauthorized},
+		{name: "missing credentials", path: "/items", noAuth: true, wantStatus: http.StatusUnauthorized},
+		{name: "health check exempt", path: "/healthz", noAuth: true, wantStatus: http.StatusOK},
+		{name: "readiness check exempt", path: "/readyz", noAuth: true, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if !tt.noAuth {
+				req.SetBasicAuth(tt.user, tt.pass)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnauthorized {
+				if got := rec.Header().Get("WWW-Authenticate"); !strings.Contains(got, `realm="dashboard"`) {
+					t.Errorf("WWW-Authenticate = %q, want a realm", got)
+				}
+			}
+			if loggedUser := strings.Contains(buf.String(), `"user":"alice"`); loggedUser != (tt.name == "correct credentials") {
+				t.Errorf("access log %q: user logged = %v", buf.String(), loggedUser)
+			}
+			if strings.Contains(buf.String(), "s3cret") {
+				t.Errorf("access log leaks the password: %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestParseConfig_AuthValidation(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	if _, err := config.Parse([]string{"--auth-user=alice"}, noEnv); err == nil {
+		t.Error("config.Parse() accepted --auth-user without --auth-password-hash")
+	}
+	if _, err := config.Parse([]string{"--auth-user=alice", "--auth-password-hash=plaintext"}, noEnv); err == nil {
+		t.Error("config.Parse() accepted a password hash that is not bcrypt")
+	}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	srv := newTestServer(t, testConfig(t))
+	logs := captureLogs(srv)
+	serve := func(h http.HandlerFunc, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+		req.Header.Set(requestIDHeader, "req-timeout")
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		requestIDMiddleware(srv.recoverMiddleware(srv.timeoutMiddleware(timeout, h))).ServeHTTP(rec, req)
+		return rec
+	}
+	// slow ignores its context, so only the middleware can cut it off
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(4 * timeout)
+		fmt.Fprint(w, "too late")
+	}
+
+	t.Run("slow handler", func(t *testing.T) {
+		start := time.Now()
+		rec := serve(slow, "")
+		if elapsed := time.Since(start); elapsed >= 4*timeout {
+			t.Errorf("response took %v, want it cut off after %v", elapsed, timeout)
+		}
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Content-Type") != "application/json" {
+			t.Fatalf("response = %d %s, want a 503 JSON envelope", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		var resp errorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if want := (errorResponse{Error: timeoutMessage, Status: http.StatusServiceUnavailable, RequestID: "req-timeout"}); !reflect.DeepEqual(resp, want) {
+			t.Errorf("body = %+v, want %+v", resp, want)
+		}
+		if !strings.Contains(logs.String(), `"msg":"request timed out"`) {
+			t.Errorf("timeout was not logged:\n%s", logs)
+		}
+	})
+
+	t.Run("browser", func(t *testing.T) {
+		rec := serve(slow, "text/html")
+		if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "<html") || !strings.Contains(rec.Body.String(), timeoutMessage) {
+			t.Errorf("response = %d %s, want the 503 error page", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("handler in time", func(t *testing.T) {
+		rec := serve(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Deadline(); !ok {
+				t.Error("request context has no deadline")
+			}
+			w.Header().Set("Location", "/items/4")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, "created")
+		}, "")
+		if rec.Code != http.StatusCreated || rec.Header().Get("Location") != "/items/4" || rec.Body.String() != "created" {
+			t.Errorf("response = %d %v %q, want the handler's own", rec.Code, rec.Header(), rec.Body)
+		}
+	})
+
+	t.Run("flushing handler", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h := srv.timeoutMiddleware(timeout, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprint(w, "first")
+			if err := http.NewResponseController(w).Flush(); err != nil {
+				t.Errorf("Flush() error = %v", err)
+			}
+			// Flushed writes reach the client before the handler finishes
+			if !rec.Flushed || rec.Body.String() != "first" {
+				t.Errorf("after Flush() the client has %q, want the first write", rec.Body)
+			}
+			fmt.Fprint(w, " second")
+			<-r.Context().Done()
+			if _, err := fmt.Fprint(w, " too late"); !errors.Is(err, http.ErrHandlerTimeout) {
+				t.Errorf("write after the deadline error = %v, want ErrHandlerTimeout", err)
+			}
+		}))
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		// The committed response is cut short rather than replaced by a 503
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/plain" || rec.Body.String() != "first second" {
+			t.Errorf("response = %d %v %q, want the streamed part", rec.Code, rec.Header(), rec.Body)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		logs.Reset()
+		rec := serve(func(w http.ResponseWriter, r *http.Request) {
+			panic("timed handler failed")
+		}, "")
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d, want 500", rec.Code)
+		}
+		// The stack logged is the handler's, not the middleware's
+		if !strings.Contains(logs.String(), "timed handler failed") || !strings.Contains(logs.String(), "TestTimeoutMiddleware") {
+			t.Errorf("panic was not logged with the handler's stack:\n%s", logs)
+		}
+	})
+}
+
+func TestRouteTimeouts(t *testing.T) {
+	cfg := testConfig(t)
+	// Every timed route is past its deadline before it starts
+	cfg.Timeouts.Request = time.Nanosecond
+	srv := newTestServer(t, cfg)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	for _, target := range []string{"/api/items?format=yaml", "/api/compare", "/items/export?format=csv"} {
+		resp, err := http.Get(ts.URL + target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable || !strings.Contains(string(body), timeoutMessage) {
+			t.Errorf("GET %s = %d %s, want 503 %s", target, resp.StatusCode, body, timeoutMessage)
+		}
+	}
+
+	// Long-lived routes are registered without the timeout
+	resp, err := http.Get(ts.URL + "/api/items/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasSuffix(string(body), `{"_meta":{"count":3}}`+"\n") {
+		t.Errorf("GET /api/items/stream = %d %s, want every item", resp.StatusCode, body)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/events", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	stream := readSSE(bufio.NewReader(resp.Body))
+	if e := <-stream; e.name != "snapshot" {
+		t.Fatalf("first event = %+v, want the snapshot", e)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := srv.store.Add(itemstore.Item{Color: "red", Shape: "square", Category: "C"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-stream:
+		if e.name != "item.created" {
+			t.Errorf("event = %+v, want item.created", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("event stream ended at the request timeout")
+	}
+}
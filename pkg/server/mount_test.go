@@ -0,0 +1,79 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/server"
+)
+
+// TestMount embeds the dashboard the way another program would: under a
+// prefix of its own mux, next to its own routes
+func TestMount(t *testing.T) {
+	store, err := itemstore.New([]itemstore.Item{
+		{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"},
+		{ID: itemstore.IntID(2), Color: "blue", Shape: "square", Category: "B"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := server.New(store,
+		server.WithBasePath("/dashboard"),
+		server.WithDefaults(config.ViewDefaults{GroupBy: "color"}),
+		server.WithReadinessCheck("host", func(context.Context) error { return nil }))
+	if err != nil {
+		t.Fatalf("server.New() error = %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /hello", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) })
+	mux.Handle("/dashboard/", srv.Handler())
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	if rec := get("/hello"); rec.Body.String() != "hello" {
+		t.Errorf("GET /hello = %q, want the host's own route", rec.Body)
+	}
+	rec := get("/dashboard/items")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /dashboard/items = %d, want 200", rec.Code)
+	}
+	for _, want := range []string{`data-group="red"`, `href="/dashboard/items/new"`} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("GET /dashboard/items lacks %s", want)
+		}
+	}
+	if rec := get("/dashboard/readyz"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"host":"ok"`) {
+		t.Errorf("GET /dashboard/readyz = %d %s, want the host's check to pass", rec.Code, rec.Body)
+	}
+}
+
+func TestNew_Options(t *testing.T) {
+	store, err := itemstore.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.New(store, server.WithCollection("Not Valid", store)); err == nil {
+		t.Error("server.New() accepted an invalid collection name")
+	}
+
+	templates := fstest.MapFS{"items.html": {Data: []byte(`custom {{len .GroupedItems}}`)}}
+	srv, err := server.New(store, server.WithTemplates(templates))
+	if err != nil {
+		t.Fatalf("server.New(WithTemplates) error = %v", err)
+	}
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items", nil))
+	if rec.Body.String() != "custom 0" {
+		t.Errorf("GET /items = %q, want the custom template", rec.Body)
+	}
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"encoding/binary"
+	"iter"
+	"net/http"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// msgpackContentType is the media type of MessagePack responses
+const msgpackContentType = "application/msgpack"
+
+// writeMsgpack writes body, an encoded MessagePack value, with the given
+// status code
+func (s *Server) writeMsgpack(w http.ResponseWriter, r *http.Request, status int, body []byte) {
+	w.Header().Set("Content-Type", msgpackContentType)
+	w.WriteHeader(status)
+	if _, err := w.Write(body); err != nil {
+		s.logger.WarnContext(r.Context(), "failed to write MessagePack response", "error", err, "request_id", RequestIDFromContext(r.Context()))
+	}
+}
+
+// The append functions below encode the API envelopes with the same field
+// names as their JSON forms. Times are RFC 3339 strings, as in JSON, and
+// fields JSON leaves out are left out here too.
+
+// appendMsgpackItemList encodes an itemListResponse. The items are
+// collected first, since a MessagePack array starts with its length.
+func appendMsgpackItemList(b []byte, items iter.Seq[itemstore.Item]) []byte {
+	var list []itemstore.Item
+	for item := range items {
+		list = append(list, item)
+	}
+	b = appendMsgpackMapHeader(b, 2)
+	b = appendMsgpackString(b, "items")
+	b = appendMsgpackArrayHeader(b, len(list))
+	for _, item := range list {
+		b = appendMsgpackItem(b, item)
+	}
+	b = appendMsgpackString(b, "meta")
+	b = appendMsgpackMapHeader(b, 1)
+	b = appendMsgpackString(b, "count")
+	return appendMsgpackInt(b, int64(len(list)))
+}
+
+// appendMsgpackItemResponse encodes an itemResponse
+func appendMsgpackItemResponse(b []byte, resp itemResponse) []byte {
+	b = appendMsgpackMapHeader(b, 1)
+	b = appendMsgpackString(b, "item")
+	return appendMsgpackItem(b, resp.Item)
+}
+
+// appendMsgpackItem encodes an item
+func appendMsgpackItem(b []byte, item itemstore.Item) []byte {
+	fields := 4
+	if !item.CreatedAt.IsZero() {
+		fields++
+	}
+	b = appendMsgpackMapHeader(b, fields)
+	b = appendMsgpackString(b, "id")
+	if id, ok := item.ID.Int(); ok {
+		b = appendMsgpackInt(b, int64(id))
+	} else {
+		b = appendMsgpackString(b, item.ID.String())
+	}
+	b = appendMsgpackString(b, "color")
+	b = appendMsgpackString(b, item.Color)
+	b = appendMsgpackString(b, "shape")
+	b = appendMsgpackString(b, item.Shape)
+	b = appendMsgpackString(b, "category")
+	b = appendMsgpackString(b, item.Category)
+	if !item.CreatedAt.IsZero() {
+		b = appendMsgpackString(b, "createdAt")
+		b = appendMsgpackString(b, item.CreatedAt.Format(time.RFC3339Nano))
+	}
+	return b
+}
+
+// appendMsgpackError encodes an errorResponse
+func appendMsgpackError(b []byte, resp errorResponse) []byte {
+	fields := 2
+	if resp.RequestID != "" {
+		fields++
+	}
+	if len(resp.Details) > 0 {
+		fields++
+	}
+	b = appendMsgpackMapHeader(b, fields)
+	b = appendMsgpackString(b, "error")
+	b = appendMsgpackString(b, resp.Error)
+	b = appendMsgpackString(b, "status")
+	b = appendMsgpackInt(b, int64(resp.Status))
+	if resp.RequestID != "" {
+		b = appendMsgpackString(b, "requestId")
+		b = appendMsgpackString(b, resp.RequestID)
+	}
+	if len(resp.Details) > 0 {
+		b = appendMsgpackString(b, "details")
+		b = appendMsgpackArrayHeader(b, len(resp.Details))
+		for _, fe := range resp.Details {
+			b = appendMsgpackMapHeader(b, 3)
+			b = appendMsgpackString(b, "field")
+			b = appendMsgpackString(b, fe.Field)
+			b = appendMsgpackString(b, "value")
+			b = appendMsgpackString(b, fe.Value)
+			b = appendMsgpackString(b, "message")
+			b = appendMsgpackString(b, fe.Message)
+		}
+	}
+	return b
+}
+
+// appendMsgpackMapHeader starts a map of n key-value pairs
+func appendMsgpackMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+	}
+}
+
+// appendMsgpackArrayHeader starts an array of n values
+func appendMsgpackArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+	}
+}
+
+// appendMsgpackString encodes s as a str value
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= 0xff:
+		b = append(b, 0xd9, byte(n))
+	case n <= 0xffff:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+// appendMsgpackInt encodes n in the smallest int or uint format that holds it
+func appendMsgpackInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n < 128:
+		return append(b, byte(n))
+	case n >= -32 && n < 0:
+		return append(b, byte(n))
+	case n >= 0 && n <= 0xff:
+		return append(b, 0xcc, byte(n))
+	case n >= 0 && n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(n))
+	case n >= 0 && n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(n))
+	case n >= 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(n))
+	case n >= -128:
+		return append(b, 0xd0, byte(n))
+	case n >= -32768:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(n))
+	case n >= -2147483648:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+	}
+}
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// decodeMsgpack decodes the one MessagePack value in b into maps, slices,
+// strings, and int64s: the types the API's encoder writes
+func decodeMsgpack(b []byte) (any, error) {
+	v, rest, err := decodeMsgpackValue(b)
+	if err == nil && len(rest) > 0 {
+		err = fmt.Errorf("%d trailing bytes", len(rest))
+	}
+	return v, err
+}
+
+func decodeMsgpackValue(b []byte) (any, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, fmt.Errorf("unexpected end of input")
+	}
+	tag, b := b[0], b[1:]
+	// size reads an n-byte big-endian length or number
+	size := func(n int) (uint64, error) {
+		if len(b) < n {
+			return 0, fmt.Errorf("unexpected end of input")
+		}
+		var v uint64
+		for _, c := range b[:n] {
+			v = v<<8 | uint64(c)
+		}
+		b = b[n:]
+		return v, nil
+	}
+	var length uint64
+	var err error
+	switch {
+	case tag < 0x80:
+		return int64(tag), b, nil
+	case tag >= 0xe0:
+		return int64(int8(tag)), b, nil
+	case tag >= 0xa0 && tag < 0xc0:
+		return decodeMsgpackString(b, uint64(tag&0x1f))
+	case tag >= 0x90 && tag < 0xa0:
+		return decodeMsgpackArray(b, uint64(tag&0x0f))
+	case tag >= 0x80 && tag < 0x90:
+		return decodeMsgpackMap(b, uint64(tag&0x0f))
+	}
+	switch tag {
+	case 0xc0:
+		return nil, b, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := size(1 << (tag - 0xcc))
+		if n > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("uint64 %d out of range", n)
+		}
+		return int64(n), b, err
+	case 0xd0:
+		n, err := size(1)
+		return int64(int8(n)), b, err
+	case 0xd1:
+		n, err := size(2)
+		return int64(int16(n)), b, err
+	case 0xd2:
+		n, err := size(4)
+		return int64(int32(n)), b, err
+	case 0xd3:
+		n, err := size(8)
+		return int64(n), b, err
+	case 0xd9, 0xda, 0xdb:
+		if length, err = size(1 << (tag - 0xd9)); err != nil {
+			return nil, nil, err
+		}
+		return decodeMsgpackString(b, length)
+	case 0xdc, 0xdd:
+		if length, err = size(2 << (tag - 0xdc)); err != nil {
+			return nil, nil, err
+		}
+		return decodeMsgpackArray(b, length)
+	case 0xde, 0xdf:
+		if length, err = size(2 << (tag - 0xde)); err != nil {
+			return nil, nil, err
+		}
+		return decodeMsgpackMap(b, length)
+	}
+	return nil, nil, fmt.Errorf("unsupported tag %#x", tag)
+}
+
+func decodeMsgpackString(b []byte, n uint64) (any, []byte, error) {
+	if uint64(len(b)) < n {
+		return nil, nil, fmt.Errorf("string of %d bytes runs past the input", n)
+	}
+	return string(b[:n]), b[n:], nil
+}
+
+func decodeMsgpackArray(b []byte, n uint64) (any, []byte, error) {
+	values := []any{}
+	for range n {
+		v, rest, err := decodeMsgpackValue(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		values, b = append(values, v), rest
+	}
+	return values, b, nil
+}
+
+func decodeMsgpackMap(b []byte, n uint64) (any, []byte, error) {
+	m := make(map[string]any, n)
+	for range n {
+		k, rest, err := decodeMsgpackValue(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("map key %v is not a string", k)
+		}
+		v, rest, err := decodeMsgpackValue(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		m[key], b = v, rest
+	}
+	return m, b, nil
+}
+
+// jsonNumbers converts the int64s of a decoded MessagePack value to the
+// float64s encoding/json decodes numbers into, so the two compare equal
+func jsonNumbers(v any) any {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case []any:
+		for i := range v {
+			v[i] = jsonNumbers(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = jsonNumbers(v[k])
+		}
+	}
+	return v
+}
+
+// apiRequest gets target with the given Accept header and a fixed request
+// ID, so the error envelopes of two requests match
+func apiRequest(t *testing.T, srv *Server, target, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set("Accept", accept)
+	req.Header.Set(requestIDHeader, "req-msgpack")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPI_Msgpack(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	tests := []struct {
+		name           string
+		target, accept string
+		status         int
+	}{
+		{"list", "/api/items", "application/msgpack", http.StatusOK},
+		{"filtered list", "/api/items?filter=category:A", "application/x-msgpack, application/json;q=0.5", http.StatusOK},
+		{"empty list", "/api/items?format=msgpack&filter=color:purple", "", http.StatusOK},
+		{"item", "/api/items/2?format=msgpack", "", http.StatusOK},
+		{"missing item", "/api/items/99", "application/msgpack", http.StatusNotFound},
+		{"invalid id", "/api/items/x?format=msgpack", "", http.StatusBadRequest},
+		{"invalid query", "/api/items?format=msgpack&size=L", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := apiRequest(t, srv, tt.target, tt.accept)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %q", rec.Code, tt.status, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); got != msgpackContentType {
+				t.Fatalf("Content-Type = %q, want %q", got, msgpackContentType)
+			}
+			got, err := decodeMsgpack(rec.Body.Bytes())
+			if err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+
+			jsonTarget := strings.Replace(tt.target, "format=msgpack", "format=json", 1)
+			jsonRec := apiRequest(t, srv, jsonTarget, "application/json")
+			if jsonRec.Code != tt.status || !strings.HasPrefix(jsonRec.Header().Get("Content-Type"), "application/json") {
+				t.Fatalf("JSON response = %d %q", jsonRec.Code, jsonRec.Header().Get("Content-Type"))
+			}
+			var want any
+			if err := json.Unmarshal(jsonRec.Body.Bytes(), &want); err != nil {
+				t.Fatal(err)
+			}
+			if got := jsonNumbers(got); !reflect.DeepEqual(got, want) {
+				t.Errorf("MessagePack response = %v\nJSON response = %v", got, want)
+			}
+		})
+	}
+}
+
+func TestAPI_MsgpackNegotiation(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	tests := []struct {
+		target, accept string
+		want           string
+	}{
+		{"/api/items", "", "application/json"},
+		{"/api/items", "application/msgpack;q=0", "application/json"},
+		{"/api/items?format=json", "application/msgpack", "application/json"},
+		{"/api/items/1", "text/plain, application/msgpack", msgpackContentType},
+		{"/api/items", "text/yaml, application/msgpack", yamlContentType},
+	}
+	for _, tt := range tests {
+		rec := apiRequest(t, srv, tt.target, tt.accept)
+		if got := rec.Header().Get("Content-Type"); rec.Code != http.StatusOK || got != tt.want {
+			t.Errorf("GET %s with Accept %q = %d %q, want 200 %q", tt.target, tt.accept, rec.Code, got, tt.want)
+		}
+		if rec.Header().Get("Vary") != "Accept" {
+			t.Errorf("GET %s Vary = %q, want Accept", tt.target, rec.Header().Get("Vary"))
+		}
+	}
+
+	rec := apiRequest(t, srv, "/api/items?format=xml", "")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `invalid format \"xml\"`) {
+		t.Errorf("format=xml = %d %s, want 400", rec.Code, rec.Body)
+	}
+}
+
+func TestMsgpackEncoding(t *testing.T) {
+	long := strings.Repeat("x", 70000)
+	tests := []struct {
+		name string
+		b    []byte
+		want any
+	}{
+		{"fixint", appendMsgpackInt(nil, 5), int64(5)},
+		{"negative fixint", appendMsgpackInt(nil, -3), int64(-3)},
+		{"uint8", appendMsgpackInt(nil, 200), int64(200)},
+		{"uint16", appendMsgpackInt(nil, 40000), int64(40000)},
+		{"uint32", appendMsgpackInt(nil, 1<<31), int64(1 << 31)},
+		{"uint64", appendMsgpackInt(nil, 1<<40), int64(1 << 40)},
+		{"int8", appendMsgpackInt(nil, -100), int64(-100)},
+		{"int16", appendMsgpackInt(nil, -1000), int64(-1000)},
+		{"int32", appendMsgpackInt(nil, -1<<20), int64(-1 << 20)},
+		{"int64", appendMsgpackInt(nil, math.MinInt64), int64(math.MinInt64)},
+		{"str8", appendMsgpackString(nil, long[:40]), long[:40]},
+		{"str16", appendMsgpackString(nil, long[:300]), long[:300]},
+		{"str32", appendMsgpackString(nil, long), long},
+	}
+	for _, tt := range tests {
+		got, err := decodeMsgpack(tt.b)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: decoded %.20v, %v; want %.20v", tt.name, got, err, tt.want)
+		}
+	}
+
+	// A list long enough for the 16-bit array header
+	items := make([]itemstore.Item, 20)
+	for i := range items {
+		items[i] = itemstore.Item{ID: itemstore.IntID(i + 1), Color: "red", Shape: "circle", Category: "A"}
+	}
+	got, err := decodeMsgpack(appendMsgpackItemList(nil, slices.Values(items)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := got.(map[string]any)
+	if n := len(list["items"].([]any)); n != 20 || list["meta"].(map[string]any)["count"] != int64(20) {
+		t.Errorf("decoded %d items and meta %v, want 20", n, list["meta"])
+	}
+	if header := appendMsgpackMapHeader(nil, 1<<16); header[0] != 0xdf || binary.BigEndian.Uint32(header[1:]) != 1<<16 {
+		t.Errorf("map header for 65536 pairs = % x", header)
+	}
+}
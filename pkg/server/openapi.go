@@ -0,0 +1,391 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/buildinfo"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/openapi"
+)
+
+// apiOperations describes every route in apiRoutes, keyed by "METHOD /path"
+var apiOperations = map[string]openapi.Operation{
+	"GET /api/openapi.json": {
+		OperationID: "getOpenAPI",
+		Summary:     "This OpenAPI document",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The OpenAPI 3 document", Content: jsonContent(&openapi.Schema{Type: "object"})},
+		},
+	},
+	"GET /api/events": {
+		OperationID: "streamEvents",
+		Summary:     "Stream item changes as Server-Sent Events: a snapshot event, then item.created, item.updated, and item.deleted",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "An event stream", Content: map[string]openapi.MediaType{
+				"text/event-stream": {Schema: &openapi.Schema{Type: "string"}},
+			}},
+		},
+	},
+	"GET /api/items": {
+		OperationID: "listItems",
+		Summary:     "List items, optionally filtered",
+		Parameters:  append(slices.Clone(filterParams), formatParam),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The matching items", Content: encodedContent(openapi.Ref("ItemList"))},
+			"400": errorDoc("Unknown or malformed query parameters"),
+		},
+	},
+	"GET /api/items/count": {
+		OperationID: "countItems",
+		Summary:     "Count the items GET /api/items would list",
+		Parameters:  filterParams,
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "The number of matching items",
+				Headers:     map[string]openapi.Header{"ETag": {Description: "Changes when the count does", Schema: &openapi.Schema{Type: "string"}}},
+				Content:     jsonContent(openapi.Ref("Count")),
+			},
+			"304": {Description: "The count still matches If-None-Match"},
+			"400": errorDoc("Unknown or malformed query parameters"),
+		},
+	},
+	"GET /api/items/stream": {
+		OperationID: "streamItems",
+		Summary:     "Stream the items GET /api/items would list as newline-delimited JSON, ending with a {\"_meta\":{\"count\":N}} line",
+		Parameters: append(slices.Clone(filterParams),
+			openapi.Parameter{Name: "sortBy", In: "query", Schema: &openapi.Schema{Type: "string", Enum: sortFields}},
+			openapi.Parameter{Name: "order", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []string{"asc", "desc"}}},
+		),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "One item per line, then the count; a stream without the count line was cut short",
+				Content: map[string]openapi.MediaType{ndjsonContentType: {Schema: openapi.Ref("Item")}}},
+			"400": errorDoc("Unknown or malformed query parameters"),
+		},
+	},
+	"POST /api/items": {
+		OperationID: "createItem",
+		Summary:     "Create an item; a zero or missing id is assigned automatically",
+		RequestBody: itemBody(openapi.Ref("Item")),
+		Security:    writeSecurity,
+		Responses: withWriteErrors(map[string]openapi.Response{
+			"201": {
+				Description: "The created item",
+				Headers:     map[string]openapi.Header{"Location": {Description: "URL of the new item", Schema: &openapi.Schema{Type: "string"}}},
+				Content:     jsonContent(openapi.Ref("ItemEnvelope")),
+			},
+			"409": errorDoc("An item with this id already exists"),
+		}),
+	},
+	"POST /api/items/bulk": {
+		OperationID: "bulkCreateItems",
+		Summary:     "Import many items at once",
+		Parameters: []openapi.Parameter{{
+			Name:        "mode",
+			In:          "query",
+			Description: "atomic stores all items or none; best-effort stores the valid ones and reports each failure",
+			Schema:      &openapi.Schema{Type: "string", Enum: []string{"atomic", "best-effort"}},
+		}, {
+			Name:        "onConflict",
+			In:          "query",
+			Description: "Merge the items instead, resolving items whose id is taken by a different item: fail aborts, skip keeps the stored item, overwrite replaces it, and renumber adds the item under a new id",
+			Schema:      &openapi.Schema{Type: "string", Enum: []string{"fail", "skip", "overwrite", "renumber"}},
+		}},
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"application/json": {Schema: &openapi.Schema{Type: "array", Items: openapi.Ref("Item")}},
+			ndjsonContentType:  {Schema: openapi.Ref("Item")},
+			yamlContentType:    {Schema: &openapi.Schema{Type: "array", Items: openapi.Ref("Item")}},
+		}},
+		Security: writeSecurity,
+		Responses: withWriteErrors(map[string]openapi.Response{
+			"200": {Description: "Every item was merged (onConflict)", Content: jsonContent(openapi.Ref("BulkResult"))},
+			"201": {Description: "Every item was created", Content: jsonContent(openapi.Ref("BulkResult"))},
+			"207": {Description: "Some items failed (best-effort mode)", Content: jsonContent(openapi.Ref("BulkResult"))},
+			"409": errorDoc("An item id is already taken (atomic mode without onConflict, or onConflict=fail)"),
+		}),
+	},
+	"GET /api/items/{id}": {
+		OperationID: "getItem",
+		Summary:     "Get one item",
+		Parameters:  []openapi.Parameter{idParam, formatParam},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The item", Content: encodedContent(openapi.Ref("ItemEnvelope"))},
+			"400": errorDoc("Invalid item id"),
+			"404": errorDoc("No item has this id"),
+		},
+	},
+	"PUT /api/items/{id}": {
+		OperationID: "replaceItem",
+		Summary:     "Replace an item",
+		Parameters:  []openapi.Parameter{idParam},
+		RequestBody: itemBody(openapi.Ref("Item")),
+		Security:    writeSecurity,
+		Responses: withWriteErrors(map[string]openapi.Response{
+			"200": {Description: "The updated item", Content: jsonContent(openapi.Ref("ItemEnvelope"))},
+			"404": errorDoc("No item has this id"),
+		}),
+	},
+	"PATCH /api/items/{id}": {
+		OperationID: "patchItem",
+		Summary:     "Update only the fields present in the body",
+		Parameters:  []openapi.Parameter{idParam},
+		RequestBody: itemBody(openapi.Ref("ItemPatch")),
+		Security:    writeSecurity,
+		Responses: withWriteErrors(map[string]openapi.Response{
+			"200": {Description: "The updated item", Content: jsonContent(openapi.Ref("ItemEnvelope"))},
+			"404": errorDoc("No item has this id"),
+		}),
+	},
+	"GET /api/charts/{property}": {
+		OperationID: "getChart",
+		Summary:     "Count the matching items by property value, shaped for chart libraries: labels, data, and colors line up by index",
+		Parameters: append([]openapi.Parameter{
+			{Name: "property", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Enum: []string{"color", "shape", "category"}}},
+			{Name: "type", In: "query", Description: "pie returns percentages, bar (the default) returns counts",
+				Schema: &openapi.Schema{Type: "string", Enum: chartTypes}},
+		}, filterParams...),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The chart series", Content: jsonContent(openapi.Ref("Chart"))},
+			"400": errorDoc("Unknown property, chart type, or query parameters"),
+		},
+	},
+	"GET /api/palette": {
+		OperationID: "getPalette",
+		Summary:     "Hex values of the named colors, including --palette overrides, and of every color in use by an item",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The color mapping", Content: jsonContent(openapi.Ref("Palette"))},
+		},
+	},
+	"GET /api/combinations": {
+		OperationID: "getCombinations",
+		Summary:     "Count the items with each distinct combination of property values, most common first",
+		Parameters: []openapi.Parameter{
+			{Name: "by", In: "query", Required: true, Description: "Comma-separated properties, each of color, shape, and category at most once",
+				Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The combinations and their counts", Content: jsonContent(openapi.Ref("Combinations"))},
+			"400": errorDoc("Missing, unknown, or repeated properties, or unknown query parameters"),
+		},
+	},
+	"GET /api/compare": {
+		OperationID: "compareItems",
+		Summary:     "Split the items by whether they match the left query, the right query, or both",
+		Parameters: []openapi.Parameter{
+			{Name: "left", In: "query", Description: "Query string using the filter, filterBy, filterValue, and q parameters of /items, e.g. filter=category:A; empty matches every item",
+				Schema: &openapi.Schema{Type: "string"}},
+			{Name: "right", In: "query", Description: "Query string for the other side, like left",
+				Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The items only the left query matches, only the right one matches, and both match, with their counts", Content: jsonContent(openapi.Ref("Comparison"))},
+			"400": errorDoc("A query is invalid; the error names the side"),
+		},
+	},
+	"GET /api/audit": {
+		OperationID: "listAuditEntries",
+		Summary:     "List the recorded item changes, newest first: who made each one, in which request, and the item before and after",
+		Parameters: []openapi.Parameter{
+			{Name: "limit", In: "query", Description: "Maximum number of entries, 1 to 1000; 100 by default",
+				Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "itemId", In: "query", Description: "Only the changes to this item",
+				Schema: openapi.Ref("ItemID")},
+			{Name: "since", In: "query", Description: "Only the changes made at or after this RFC 3339 time",
+				Schema: &openapi.Schema{Type: "string", Format: "date-time"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The matching entries", Content: jsonContent(openapi.Ref("AuditLog"))},
+			"400": errorDoc("Invalid or unknown query parameters"),
+			"404": errorDoc("The audit log is disabled"),
+		},
+	},
+	"POST /api/undo": {
+		OperationID: "undo",
+		Summary:     "Revert the latest change that has not been undone yet; each call goes one change further back, up to --undo-depth changes",
+		Security:    writeSecurity,
+		Responses: withWriteErrors(map[string]openapi.Response{
+			"200": {Description: "The reverted change and the audit entry of its reversal", Content: jsonContent(openapi.Ref("Undo"))},
+			"404": errorDoc("Undo or the audit log is disabled"),
+			"409": errorDoc("Nothing is left to undo, or the item changed since"),
+		}),
+	},
+	"POST /api/share": {
+		OperationID: "createShareLink",
+		Summary:     "Create a short link to a view of the dashboard; GET /s/{token} redirects to it until it expires, and GET /s/{token}/qr.png is a QR code of its URL. Needs no API key, but is rate limited per client",
+		RequestBody: jsonBody(openapi.Ref("ShareRequest")),
+		Responses: map[string]openapi.Response{
+			"201": {
+				Description: "The short link",
+				Headers:     map[string]openapi.Header{"Location": {Description: "Path of the short link", Schema: &openapi.Schema{Type: "string"}}},
+				Content:     jsonContent(openapi.Ref("ShareLink")),
+			},
+			"400": errorDoc("Invalid body or unknown /items query parameters"),
+			"429": errorDoc("Too many short link requests"),
+			"503": errorDoc("Too many short links exist"),
+		},
+	},
+	"GET /api/presets": {
+		OperationID: "listPresets",
+		Summary:     "List the saved filter presets, sorted by name",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The presets", Content: jsonContent(openapi.Ref("PresetList"))},
+		},
+	},
+	"POST /api/presets": {
+		OperationID: "createPreset",
+		Summary:     "Save an /items query under a name; the query may only use the view parameters and q, and filters must name item properties",
+		RequestBody: jsonBody(openapi.Ref("PresetRequest")),
+		Security:    writeSecurity,
+		Responses: withWriteErrors(map[string]openapi.Response{
+			"201": {Description: "The saved preset", Content: jsonContent(openapi.Ref("Preset"))},
+			"400": errorDoc("Invalid name, body, or query"),
+			"409": errorDoc("A preset with this name already exists"),
+		}),
+	},
+	"DELETE /api/presets/{name}": {
+		OperationID: "deletePreset",
+		Summary:     "Delete a preset",
+		Parameters:  []openapi.Parameter{{Name: "name", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}}},
+		Security:    writeSecurity,
+		Responses: withWriteErrors(map[string]openapi.Response{
+			"204": {Description: "The preset was deleted"},
+			"404": errorDoc("No preset has this name"),
+		}),
+	},
+	"DELETE /api/items/{id}": {
+		OperationID: "deleteItem",
+		Summary:     "Delete an item",
+		Parameters:  []openapi.Parameter{idParam},
+		Security:    writeSecurity,
+		Responses: withWriteErrors(map[string]openapi.Response{
+			"204": {Description: "The item was deleted"},
+			"404": errorDoc("No item has this id"),
+		}),
+	},
+}
+
+var (
+	explode = true
+
+	filterParams = []openapi.Parameter{
+		{Name: "filter", In: "query", Explode: &explode, Description: "Repeatable filter in the form property:value, e.g. color:red",
+			Schema: &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string"}}},
+		{Name: "filterBy", In: "query", Description: "Legacy single filter property; use with filterValue",
+			Schema: &openapi.Schema{Type: "string", Enum: []string{"color", "shape", "category"}}},
+		{Name: "filterValue", In: "query", Description: "Legacy single filter value",
+			Schema: &openapi.Schema{Type: "string"}},
+		{Name: "strict", In: "query", Description: "Reject unknown query parameters; on by default for the API",
+			Schema: &openapi.Schema{Type: "boolean"}},
+	}
+
+	formatParam = openapi.Parameter{Name: "format", In: "query", Description: "Response encoding; overrides an Accept header asking for application/msgpack or application/yaml",
+		Schema: &openapi.Schema{Type: "string", Enum: []string{"json", "msgpack", "yaml"}}}
+
+	idParam = openapi.Parameter{Name: "id", In: "path", Required: true, Schema: openapi.Ref("ItemID")}
+
+	writeSecurity = []map[string][]string{{"bearerAuth": {}}, {"apiKeyHeader": {}}}
+)
+
+func jsonContent(s *openapi.Schema) map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{"application/json": {Schema: s}}
+}
+
+// encodedContent describes a response also available as MessagePack and
+// YAML, with the same structure as the JSON
+func encodedContent(s *openapi.Schema) map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{"application/json": {Schema: s}, msgpackContentType: {Schema: s}, yamlContentType: {Schema: s}}
+}
+
+func jsonBody(s *openapi.Schema) *openapi.RequestBody {
+	return &openapi.RequestBody{Required: true, Content: jsonContent(s)}
+}
+
+// itemBody describes a request body taken as JSON or YAML
+func itemBody(s *openapi.Schema) *openapi.RequestBody {
+	return &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{"application/json": {Schema: s}, yamlContentType: {Schema: s}}}
+}
+
+func errorDoc(description string) openapi.Response {
+	return openapi.Response{Description: description, Content: jsonContent(openapi.Ref("Error"))}
+}
+
+// withWriteErrors adds the error responses shared by every write endpoint
+func withWriteErrors(responses map[string]openapi.Response) map[string]openapi.Response {
+	shared := map[string]openapi.Response{
+		"400": errorDoc("Invalid body or item"),
+		"401": errorDoc("Missing or invalid API key"),
+		"403": errorDoc("Writes are disabled because no API keys are configured"),
+		"413": errorDoc("Request body too large"),
+		"415": errorDoc("Content-Type is not one the endpoint takes"),
+	}
+	for code, r := range shared {
+		if _, ok := responses[code]; !ok {
+			responses[code] = r
+		}
+	}
+	return responses
+}
+
+// openAPIDocument builds the OpenAPI description of the JSON API. Schemas are
+// derived from the response structs, so they follow changes to them.
+func (s *Server) openAPIDocument() openapi.Document {
+	var components openapi.Components
+	idSchema := &openapi.Schema{Type: "integer", Format: "int32"}
+	if s.store.StringIDs() {
+		idSchema = &openapi.Schema{Type: "string"}
+	}
+	components.RegisterSchema("ItemID", itemstore.ID{}, idSchema)
+	components.Register("Item", itemstore.Item{})
+	components.Register("ItemPatch", itemPatch{})
+	components.Register("Error", errorResponse{})
+	components.Register("ItemEnvelope", itemResponse{})
+	components.Register("ItemList", itemListResponse{})
+	components.Register("Count", countResponse{})
+	components.Register("BulkResult", bulkResponse{})
+	components.Register("Chart", chartResponse{})
+	components.Register("Palette", paletteResponse{})
+	components.Register("ShareRequest", shareRequest{})
+	components.Register("ShareLink", shareResponse{})
+	components.Register("PresetRequest", presetRequest{})
+	components.Register("Preset", presetResponse{})
+	components.Register("PresetList", presetListResponse{})
+	components.Register("Combinations", combinationsResponse{})
+	components.Register("Comparison", compareResponse{})
+	components.Register("AuditLog", auditResponse{})
+	components.Register("Undo", undoResponse{})
+	components.SecuritySchemes = map[string]openapi.SecurityScheme{
+		"bearerAuth":   {Type: "http", Scheme: "bearer"},
+		"apiKeyHeader": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+	}
+
+	doc := openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "Dashboard API",
+			Version:     buildinfo.Get().Version,
+			Description: "Every error uses the Error envelope; responses carry an X-Request-ID header.",
+		},
+		Paths:      make(map[string]map[string]openapi.Operation),
+		Components: components,
+	}
+	if s.cfg.BasePath != "" {
+		doc.Servers = []openapi.Server{{URL: s.cfg.BasePath}}
+	}
+
+	for _, route := range s.apiRoutes() {
+		op, ok := apiOperations[route.method+" "+route.path]
+		if !ok {
+			continue
+		}
+		if doc.Paths[route.path] == nil {
+			doc.Paths[route.path] = make(map[string]openapi.Operation)
+		}
+		doc.Paths[route.path][strings.ToLower(route.method)] = op
+	}
+	return doc
+}
+
+func (s *Server) apiOpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, r, http.StatusOK, s.openAPIDocument())
+}
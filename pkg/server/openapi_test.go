@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want a 3.x version", doc.OpenAPI)
+	}
+
+	// Every registered API route must be documented
+	for _, route := range srv.apiRoutes() {
+		if _, ok := doc.Paths[route.path][strings.ToLower(route.method)]; !ok {
+			t.Errorf("%s %s is missing from the OpenAPI document; describe it in apiOperations", route.method, route.path)
+		}
+	}
+
+	// The Item schema follows the struct's JSON fields
+	itemProps := doc.Components.Schemas["Item"].Properties
+	itemType := reflect.TypeFor[itemstore.Item]()
+	for i := range itemType.NumField() {
+		name, _, _ := strings.Cut(itemType.Field(i).Tag.Get("json"), ",")
+		if _, ok := itemProps[name]; !ok {
+			t.Errorf("Item schema has no %q property", name)
+		}
+	}
+}
+
+func TestOpenAPIDocument_NoStaleOperations(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	registered := make(map[string]bool)
+	for _, route := range srv.apiRoutes() {
+		registered[route.method+" "+route.path] = true
+	}
+	for key := range apiOperations {
+		if !registered[key] {
+			t.Errorf("apiOperations describes %s, which is not a registered route", key)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"net/http"
+)
+
+// paletteResponse is the body of GET /api/palette
+type paletteResponse struct {
+	// Colors maps every named color, and every color in use by an item, to
+	// its "#rrggbb" value
+	Colors map[string]string `json:"colors"`
+}
+
+func (s *Server) apiPaletteHandler(w http.ResponseWriter, r *http.Request) {
+	colors := s.palette.Colors()
+	for color := range s.storeFor(r).CountBy("color") {
+		if _, ok := colors[color]; !ok {
+			colors[color] = s.palette.Hex(color)
+		}
+	}
+	s.writeJSON(w, r, http.StatusOK, paletteResponse{Colors: colors})
+}
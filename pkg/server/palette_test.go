@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/palette"
+)
+
+func TestPaletteAPI(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Palette = map[string]string{"red": "#e53935", "brand": "#00aaff"}
+	srv := newTestServer(t, cfg)
+	if _, err := srv.store.Add(itemstore.Item{Color: "sunset", Shape: "circle", Category: "A"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/palette", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var got paletteResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode palette: %v", err)
+	}
+	want := map[string]string{
+		"red":    "#e53935",
+		"brand":  "#00aaff",
+		"blue":   "#0000ff",
+		"sunset": palette.Fallback("sunset"),
+	}
+	for name, hex := range want {
+		if got.Colors[name] != hex {
+			t.Errorf("colors[%s] = %q, want %q", name, got.Colors[name], hex)
+		}
+	}
+}
+
+func TestItemsPage_ColorHex(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Palette = map[string]string{"red": "#e53935"}
+	srv := newTestServer(t, cfg)
+
+	body := getItems(t, srv, "/items?groupBy=color").Body.String()
+	for _, want := range []string{
+		// Sidebar swatch, rendered with colorHex
+		`data-color="blue" style="background-color: #0000ff;"`,
+		// Group heading swatch
+		`<h3 class="group-title"><span class="item-color" style="background-color: #e53935;"></span> Red`,
+		// Icon of the red circle
+		`<circle cx="12" cy="12" r="9" fill="#e53935"/>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page is missing %q", want)
+		}
+	}
+
+	// Only color groups get a swatch
+	body = getItems(t, srv, "/items?groupBy=shape").Body.String()
+	if strings.Contains(body, `<h3 class="group-title"><span class="item-color"`) {
+		t.Error("shape group headings have color swatches")
+	}
+}
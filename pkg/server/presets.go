@@ -0,0 +1,169 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/preset"
+)
+
+// savedQueryParams are the /items parameters a preset or a view defined
+// with --views may set: those of the view state and the search
+var savedQueryParams = slices.Concat(viewStateParams, []string{"q"})
+
+// presetRequest is the body of POST /api/presets
+type presetRequest struct {
+	Name string `json:"name"`
+	// Title is shown when the preset is opened as a view, by default the
+	// name
+	Title string `json:"title,omitempty"`
+	// Query is the /items query string the preset applies, with or without
+	// the leading "?"
+	Query string `json:"query"`
+}
+
+// presetResponse describes a saved preset
+type presetResponse struct {
+	Name  string `json:"name"`
+	Title string `json:"title,omitempty"`
+	Query string `json:"query"`
+	// URL is the items page with the preset applied
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// presetListResponse is the body of GET /api/presets
+type presetListResponse struct {
+	Presets []presetResponse `json:"presets"`
+}
+
+// presetLink is a preset offered on the items page
+type presetLink struct {
+	Name string
+	Link string
+}
+
+// newPresetStore returns the preset store. Presets are saved alongside the
+// --data file when there is one and kept in memory otherwise.
+func newPresetStore(cfg config.Config) (*preset.Store, error) {
+	var opts []preset.Option
+	if cfg.DataFile != "" {
+		opts = append(opts, preset.WithFile(presetsFile(cfg.DataFile)))
+	}
+	return preset.New(opts...)
+}
+
+// presetsFile returns where presets are saved for dataFile, e.g.
+// items.presets.json for items.json
+func presetsFile(dataFile string) string {
+	return strings.TrimSuffix(dataFile, filepath.Ext(dataFile)) + ".presets.json"
+}
+
+// parseSavedQuery validates the query of a preset or view against the
+// parameters and properties the items page has now, and returns it in a
+// canonical form
+func parseSavedQuery(raw string) (string, error) {
+	query, err := url.ParseQuery(strings.TrimPrefix(raw, "?"))
+	if err != nil {
+		return "", &httpError{status: http.StatusBadRequest, message: "query is not a valid query string"}
+	}
+	if _, err := parseItemsQuery(query, savedQueryParams, true); err != nil {
+		return "", err
+	}
+	if err := checkFilterProperties(query); err != nil {
+		return "", err
+	}
+	if groupBy := query.Get("groupBy"); groupBy != "" && !slices.Contains(groupFields, groupBy) {
+		return "", &httpError{status: http.StatusBadRequest,
+			message: fmt.Sprintf("invalid groupBy %q (supported: %s)", groupBy, strings.Join(groupFields, ", "))}
+	}
+	return query.Encode(), nil
+}
+
+// presetURL returns the items page of the store r addresses with p applied.
+// The query is encoded again, so a preset edited by hand in the presets file
+// cannot put anything but a query string into the link.
+func (s *Server) presetURL(r *http.Request, p preset.Preset) string {
+	link := s.scopedURL(r, "/items")
+	// ParseQuery keeps the parameters it could parse, which are all the
+	// link needs
+	query, _ := url.ParseQuery(p.Query)
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
+
+func (s *Server) presetResponse(r *http.Request, p preset.Preset) presetResponse {
+	return presetResponse{Name: p.Name, Title: p.Title, Query: p.Query, URL: s.presetURL(r, p), CreatedAt: p.CreatedAt}
+}
+
+// presetLinks returns the presets for the items page, sorted by name
+func (s *Server) presetLinks(r *http.Request) []presetLink {
+	var links []presetLink
+	for _, p := range s.presets.List() {
+		links = append(links, presetLink{Name: p.Name, Link: s.presetURL(r, p)})
+	}
+	return links
+}
+
+func (s *Server) apiListPresetsHandler(w http.ResponseWriter, r *http.Request) {
+	resp := presetListResponse{Presets: []presetResponse{}}
+	for _, p := range s.presets.List() {
+		resp.Presets = append(resp.Presets, s.presetResponse(r, p))
+	}
+	s.writeJSON(w, r, http.StatusOK, resp)
+}
+
+// apiCreatePresetHandler saves a query under a new name. The query must
+// only use parameters and properties the items page understands, so a
+// preset cannot silently stop filtering.
+func (s *Server) apiCreatePresetHandler(w http.ResponseWriter, r *http.Request) {
+	var req presetRequest
+	if err := decodeJSONBody(w, r, &req, s.cfg.MaxBodyBytes); err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	query, err := parseSavedQuery(req.Query)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	if _, taken := s.configuredView(req.Name); taken {
+		s.respondError(w, r, &httpError{status: http.StatusConflict, message: fmt.Sprintf("%q is the name of a view defined with --views", req.Name)})
+		return
+	}
+	p, err := s.presets.Create(req.Name, req.Title, query)
+	switch {
+	case errors.Is(err, preset.ErrInvalidName):
+		s.respondError(w, r, &httpError{status: http.StatusBadRequest, message: err.Error()})
+		return
+	case errors.Is(err, preset.ErrExists):
+		s.respondError(w, r, &httpError{status: http.StatusConflict, message: fmt.Sprintf("a preset named %q already exists", req.Name)})
+		return
+	case err != nil:
+		s.respondError(w, r, fmt.Errorf("create preset: %w", err))
+		return
+	}
+	s.writeJSON(w, r, http.StatusCreated, s.presetResponse(r, p))
+}
+
+func (s *Server) apiDeletePresetHandler(w http.ResponseWriter, r *http.Request) {
+	err := s.presets.Delete(r.PathValue("name"))
+	if errors.Is(err, preset.ErrNotFound) {
+		s.respondError(w, r, &httpError{status: http.StatusNotFound, message: "preset not found: " + r.PathValue("name")})
+		return
+	}
+	if err != nil {
+		s.respondError(w, r, fmt.Errorf("delete preset: %w", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
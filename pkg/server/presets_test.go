@@ -0,0 +1,135 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/preset"
+)
+
+// presetRequestTo sends a JSON API request for presets through srv
+func presetRequestTo(t *testing.T, srv *Server, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+// listPresets returns the names of the presets GET /api/presets lists
+func listPresets(t *testing.T, srv *Server) []presetResponse {
+	t.Helper()
+	var resp presetListResponse
+	if err := json.NewDecoder(getItems(t, srv, "/api/presets").Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Presets
+}
+
+func TestPresets(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+
+	rec := presetRequestTo(t, srv, http.MethodPost, "/api/presets", `{"name":"blue-a","query":"?filter=category:A&filter=color:blue&groupBy=color"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d: %s", rec.Code, rec.Body)
+	}
+	var created presetResponse
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.Name != "blue-a" || created.URL != "/items?"+created.Query || created.CreatedAt.IsZero() {
+		t.Errorf("created preset = %+v", created)
+	}
+	if rec := presetRequestTo(t, srv, http.MethodPost, "/api/presets", `{"name":"blue-a","query":"groupBy=shape"}`); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate name status = %d, want 409", rec.Code)
+	}
+	presetRequestTo(t, srv, http.MethodPost, "/api/presets", `{"name":"all","query":""}`)
+
+	presets := listPresets(t, srv)
+	if len(presets) != 2 || presets[0].Name != "all" || presets[0].URL != "/items" || presets[1] != created {
+		t.Errorf("GET /api/presets = %+v", presets)
+	}
+
+	// The items page offers a link applying each preset, which shows only
+	// the matching item
+	body := getItems(t, srv, "/items").Body.String()
+	if !strings.Contains(body, `id="presets"`) || !strings.Contains(body, `href="`+strings.ReplaceAll(created.URL, "&", "&amp;")+`"`) {
+		t.Errorf("items page does not link the preset %s", created.URL)
+	}
+	if page := getItems(t, srv, created.URL).Body.String(); !strings.Contains(page, "Item #2 ") || strings.Contains(page, "Item #1 ") {
+		t.Errorf("following the preset did not apply it")
+	}
+
+	if rec := presetRequestTo(t, srv, http.MethodDelete, "/api/presets/blue-a", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE status = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := presetRequestTo(t, srv, http.MethodDelete, "/api/presets/blue-a", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("second DELETE status = %d, want 404", rec.Code)
+	}
+	if presets := listPresets(t, srv); len(presets) != 1 {
+		t.Errorf("presets after delete = %+v", presets)
+	}
+}
+
+func TestPresets_Invalid(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+
+	for body, want := range map[string]string{
+		`{"name":"","query":""}`:                                "the name is empty",
+		`{"name":"has space","query":""}`:                       "must start with a letter or digit",
+		`{"name":"` + strings.Repeat("x", 41) + `","query":""}`: "longer than 40 characters",
+		`{"name":"p","query":"filter=weight:5"}`:                "invalid filter",
+		`{"name":"p","query":"filterBy=size&filterValue=L"}`:    "invalid filterBy",
+		`{"name":"p","query":"groupBy=size"}`:                   "groupBy",
+		`{"name":"p","query":"added=3"}`:                        "unknown query parameters: added",
+		`{"name":"p","query":"%zz"}`:                            "not a valid query string",
+	} {
+		rec := presetRequestTo(t, srv, http.MethodPost, "/api/presets", body)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("POST %s = %d %s, want 400 mentioning %q", body, rec.Code, rec.Body, want)
+		}
+	}
+	if presets := listPresets(t, srv); len(presets) != 0 {
+		t.Errorf("invalid presets were saved: %+v", presets)
+	}
+
+	// Presets are writes, so they need a key like the rest of the API
+	srv = newTestServer(t, testConfig(t))
+	if rec := presetRequestTo(t, srv, http.MethodPost, "/api/presets", `{"name":"p","query":""}`); rec.Code != http.StatusForbidden {
+		t.Errorf("POST without write access status = %d, want 403", rec.Code)
+	}
+}
+
+func TestPresets_Persisted(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	cfg.DataFile = filepath.Join(t.TempDir(), "items.json")
+	srv := newTestServer(t, cfg)
+	presetRequestTo(t, srv, http.MethodPost, "/api/presets", `{"name":"reds","query":"filter=color:red"}`)
+	presetRequestTo(t, srv, http.MethodPost, "/api/presets", `{"name":"gone","query":"groupBy=color"}`)
+	presetRequestTo(t, srv, http.MethodDelete, "/api/presets/gone", "")
+
+	// A new server over the same data file loads the saved presets
+	reloaded := listPresets(t, newTestServer(t, cfg))
+	if want := listPresets(t, srv); len(reloaded) != 1 || reloaded[0] != want[0] {
+		t.Errorf("presets after reload = %+v, want %+v", reloaded, want)
+	}
+}
+
+func TestPresetURL_Reencodes(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	// A query edited by hand in the presets file, rather than through the
+	// API, which stores it encoded
+	p := preset.Preset{Name: "hand", Query: `q="><script>alert(1)</script>&groupBy=color`}
+	want := "/items?groupBy=color&q=%22%3E%3Cscript%3Ealert%281%29%3C%2Fscript%3E"
+	if got := srv.presetURL(itemsRequest, p); got != want {
+		t.Errorf("presetURL() = %q, want %q", got, want)
+	}
+}
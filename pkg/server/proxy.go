@@ -0,0 +1,103 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// clientIP returns the address of the client that sent r. When the peer is
+// a trusted proxy, X-Forwarded-For is read from the right, past every entry
+// that is itself a trusted proxy: the first one that is not is the client.
+// Entries to its left are supplied by the client and can be spoofed, and
+// peers that are not trusted have their headers ignored altogether.
+func (s *Server) clientIP(r *http.Request) string {
+	client := remoteHost(r)
+	if !s.trustsPeer(r) {
+		return client
+	}
+	entries := forwardedFor(r)
+	for i := len(entries) - 1; i >= 0; i-- {
+		addr, ok := parseForwardedAddr(entries[i])
+		if !ok {
+			// The hops before an entry that is not an address cannot be
+			// vouched for
+			break
+		}
+		client = addr.String()
+		if !s.trustedProxy(addr) {
+			break
+		}
+	}
+	return client
+}
+
+// requestScheme returns "https" or "http", the scheme the client used. A
+// trusted proxy that terminates TLS reports it in X-Forwarded-Proto.
+func (s *Server) requestScheme(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if s.trustsPeer(r) {
+		// The last value is the one set by the proxy talking to us
+		if values := splitList(strings.Join(r.Header.Values("X-Forwarded-Proto"), ",")); len(values) > 0 {
+			if proto := strings.ToLower(values[len(values)-1]); proto == "http" || proto == "https" {
+				scheme = proto
+			}
+		}
+	}
+	return scheme
+}
+
+// trustsPeer reports whether the forwarding headers of r's immediate peer are
+// believed: every peer's with --trust-proxy-headers, otherwise only those of
+// --trusted-proxies
+func (s *Server) trustsPeer(r *http.Request) bool {
+	if s.cfg.TrustProxyHeaders {
+		return true
+	}
+	addr, err := netip.ParseAddr(remoteHost(r))
+	return err == nil && s.trustedProxy(addr)
+}
+
+// trustedProxy reports whether addr is in one of the --trusted-proxies
+// networks
+func (s *Server) trustedProxy(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range s.cfg.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteHost returns the host of r's peer without the port, or RemoteAddr as
+// it is when it has none, as for a Unix socket
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor returns the X-Forwarded-For entries of every such header, in
+// order, as one list
+func forwardedFor(r *http.Request) []string {
+	return splitList(strings.Join(r.Header.Values("X-Forwarded-For"), ","))
+}
+
+// parseForwardedAddr parses an X-Forwarded-For entry, which some proxies
+// write with a port
+func parseForwardedAddr(entry string) (netip.Addr, bool) {
+	if addr, err := netip.ParseAddr(entry); err == nil {
+		return addr.Unmap(), true
+	}
+	if addrPort, err := netip.ParseAddrPort(entry); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	return netip.Addr{}, false
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+// proxyServer returns a server trusting the proxies in the networks given,
+// or every peer when trustAll is set
+func proxyServer(t *testing.T, trustAll bool, networks ...string) *Server {
+	t.Helper()
+	cfg := testConfig(t)
+	cfg.TrustProxyHeaders = trustAll
+	for _, network := range networks {
+		cfg.TrustedProxies = append(cfg.TrustedProxies, netip.MustParsePrefix(network))
+	}
+	return newTestServer(t, cfg)
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustAll   bool
+		trusted    []string
+		remoteAddr string
+		xff        []string
+		want       string
+	}{
+		{name: "remote address", remoteAddr: "192.0.2.1:1234", want: "192.0.2.1"},
+		{name: "untrusted header ignored", remoteAddr: "192.0.2.1:1234", xff: []string{"203.0.113.9"}, want: "192.0.2.1"},
+		{name: "untrusted peer outside the networks", trusted: []string{"10.0.0.0/8"}, remoteAddr: "192.0.2.1:1234", xff: []string{"203.0.113.9"}, want: "192.0.2.1"},
+		{name: "trusted peer", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", xff: []string{"203.0.113.9"}, want: "203.0.113.9"},
+		{name: "spoofed prefix ignored", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", xff: []string{"1.1.1.1, 203.0.113.9"}, want: "203.0.113.9"},
+		{name: "chain of trusted proxies", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", xff: []string{"1.1.1.1, 203.0.113.9, 10.0.0.2, 10.0.0.3"}, want: "203.0.113.9"},
+		{name: "headers form one list", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", xff: []string{"1.1.1.1, 203.0.113.9", "10.0.0.2"}, want: "203.0.113.9"},
+		{name: "only trusted entries", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", xff: []string{"10.0.0.2, 10.0.0.3"}, want: "10.0.0.2"},
+		{name: "trusted but absent", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", want: "10.0.0.1"},
+		{name: "entry with a port", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", xff: []string{"203.0.113.9:5678"}, want: "203.0.113.9"},
+		{name: "IPv6 entry", trusted: []string{"fd00::/8"}, remoteAddr: "[fd00::1]:1234", xff: []string{"2001:db8::7"}, want: "2001:db8::7"},
+		{name: "IPv4-mapped peer", trusted: []string{"10.0.0.0/8"}, remoteAddr: "[::ffff:10.0.0.1]:1234", xff: []string{"203.0.113.9"}, want: "203.0.113.9"},
+		{name: "garbage entry stops the walk", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", xff: []string{"203.0.113.9, unknown"}, want: "10.0.0.1"},
+		{name: "trust every peer", trustAll: true, remoteAddr: "192.0.2.1:1234", xff: []string{"1.1.1.1, 203.0.113.9"}, want: "203.0.113.9"},
+		{name: "unix socket peer", trustAll: true, remoteAddr: "@", xff: []string{"203.0.113.9"}, want: "203.0.113.9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := proxyServer(t, tt.trustAll, tt.trusted...)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if got := srv.clientIP(req); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestScheme(t *testing.T) {
+	tests := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		tls        bool
+		proto      []string
+		want       string
+	}{
+		{name: "plain", remoteAddr: "192.0.2.1:1234", want: "http"},
+		{name: "TLS", remoteAddr: "192.0.2.1:1234", tls: true, want: "https"},
+		{name: "untrusted header ignored", trusted: []string{"10.0.0.0/8"}, remoteAddr: "192.0.2.1:1234", proto: []string{"https"}, want: "http"},
+		{name: "trusted proxy terminates TLS", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", proto: []string{"https"}, want: "https"},
+		{name: "trusted proxy downgrades", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", tls: true, proto: []string{"http"}, want: "http"},
+		{name: "last value wins", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", proto: []string{"http, HTTPS"}, want: "https"},
+		{name: "unknown scheme ignored", trusted: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", proto: []string{"javascript"}, want: "http"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := proxyServer(t, false, tt.trusted...)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			for _, v := range tt.proto {
+				req.Header.Add("X-Forwarded-Proto", v)
+			}
+			if got := srv.requestScheme(req); got != tt.want {
+				t.Errorf("requestScheme() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrustedProxies_Server(t *testing.T) {
+	srv := proxyServer(t, false, "10.0.0.0/8")
+	logs := captureLogs(srv)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		wantClient string
+		wantScheme string
+	}{
+		{"through the proxy", "10.0.0.1:1234", "203.0.113.9", "https"},
+		{"spoofed by a client", "192.0.2.1:1234", "192.0.2.1", "http"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest(http.MethodGet, feedPath, nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Host = "dash.example"
+			req.Header.Set("X-Forwarded-For", "203.0.113.9")
+			req.Header.Set("X-Forwarded-Proto", "https")
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, req)
+
+			if want := `href="` + tt.wantScheme + `://dash.example/feed.atom"`; !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("GET %s = %d, want the self link %s:\n%s", feedPath, rec.Code, want, rec.Body)
+			}
+			records := logRecords(t, logs)
+			if last := records[len(records)-1]; last["msg"] != "request" || last["client_ip"] != tt.wantClient {
+				t.Errorf("request logged as %v, want client_ip %s", last, tt.wantClient)
+			}
+		})
+	}
+}
+
+func TestTrustedProxies_SecureCookies(t *testing.T) {
+	srv := proxyServer(t, false, "10.0.0.0/8")
+	for _, tt := range []struct {
+		target, cookie string
+	}{
+		{"/items?groupBy=color", viewStateCookie},
+		{"/theme?set=dark", themeCookie},
+		{"/items?lang=de", langCookie},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-Proto", "https")
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+
+		var found bool
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == tt.cookie {
+				found = true
+				if !c.Secure {
+					t.Errorf("GET %s behind a TLS proxy set cookie %s without Secure", tt.target, c.Name)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("GET %s set no %s cookie", tt.target, tt.cookie)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+var (
+	// itemsPageParams are the query parameters understood by /items
+	itemsPageParams = []string{"groupBy", "filter", "filterBy", "filterValue", "sortBy", "order", "q", "strict", "reset", "added", "updated", "deleted", "imported"}
+	// apiItemsParams are the query parameters understood by GET
+	// /api/items/count
+	apiItemsParams = []string{"filter", "filterBy", "filterValue", "strict"}
+	// apiListItemsParams are the query parameters understood by GET
+	// /api/items: those of the count plus the response format
+	apiListItemsParams = slices.Concat(apiItemsParams, []string{"format"})
+)
+
+var (
+	exemptParamsMu sync.RWMutex
+	// exemptParams are query parameters consumed by middleware rather than
+	// by the endpoint, so strict validation never reports them
+	exemptParams = map[string]struct{}{}
+)
+
+// exemptQueryParam excludes a query parameter from strict validation on every
+// endpoint. Middleware that consumes its own parameters registers them here.
+func exemptQueryParam(name string) {
+	exemptParamsMu.Lock()
+	defer exemptParamsMu.Unlock()
+	exemptParams[name] = struct{}{}
+}
+
+// checkFilterProperties returns a 400 *httpError when a filter or filterBy
+// in query names something other than an item property. The items page
+// ignores such filters, but a query saved for later should not hide a typo.
+func checkFilterProperties(query url.Values) error {
+	for _, filter := range query["filter"] {
+		if property, _, ok := strings.Cut(filter, ":"); !ok || !itemstore.IsProperty(property) {
+			return &httpError{status: http.StatusBadRequest,
+				message: fmt.Sprintf("invalid filter %q (want property:value with property color, shape, or category)", filter)}
+		}
+	}
+	if property := query.Get("filterBy"); property != "" && !itemstore.IsProperty(property) {
+		return &httpError{status: http.StatusBadRequest,
+			message: fmt.Sprintf("invalid filterBy %q (supported: color, shape, category)", property)}
+	}
+	return nil
+}
+
+// checkQueryParams returns a 400 *httpError naming every parameter in query
+// that is neither in allowed nor exempt
+func checkQueryParams(query url.Values, allowed []string) error {
+	exemptParamsMu.RLock()
+	defer exemptParamsMu.RUnlock()
+
+	var unknown []string
+	for name := range query {
+		if slices.Contains(allowed, name) {
+			continue
+		}
+		if _, ok := exemptParams[name]; ok {
+			continue
+		}
+		unknown = append(unknown, name)
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	slices.Sort(unknown)
+	supported := slices.Sorted(slices.Values(allowed))
+	return &httpError{
+		status: http.StatusBadRequest,
+		message: fmt.Sprintf("unknown query parameters: %s (supported: %s)",
+			strings.Join(unknown, ", "), strings.Join(supported, ", ")),
+	}
+}
+
+// itemsQuery is the parsed form of the query string shared by the item
+// listing endpoints
+type itemsQuery struct {
+	GroupBy string
+	Filters map[string]string
+	// SortBy is the column items are sorted by, or empty for store order
+	SortBy     string
+	Descending bool
+	// Search holds the terms of the q parameter, which every listed item
+	// must contain
+	Search []string
+}
+
+// groupByNone is the groupBy value that lists items without grouping them
+const groupByNone = config.GroupByNone
+
+// groupFields are the groupBy values offered by the items page
+var groupFields = config.GroupFields
+
+// sortFields are the values accepted by the sortBy parameter
+var sortFields = config.SortFields
+
+// fillDefaults returns a copy of query with the defaults d written in for each
+// setting it leaves out. Only settings whose parameter is in allowed are
+// filled, so strict endpoints never see parameters they reject. Filters count
+// as given when any filter parameter is present, even an empty "filter=",
+// which is how a page with no filters links back to itself.
+func fillDefaults(d config.ViewDefaults, query url.Values, allowed []string) url.Values {
+	filled := make(url.Values, len(query)+3)
+	for key, values := range query {
+		filled[key] = slices.Clone(values)
+	}
+	if d.GroupBy != "" && slices.Contains(allowed, "groupBy") && !query.Has("groupBy") {
+		filled.Set("groupBy", d.GroupBy)
+	}
+	if d.SortBy != "" && slices.Contains(allowed, "sortBy") && !query.Has("sortBy") {
+		filled.Set("sortBy", d.SortBy)
+		if d.Descending {
+			filled.Set("order", "desc")
+		} else {
+			filled.Del("order")
+		}
+	}
+	if len(d.Filters) > 0 && slices.Contains(allowed, "filter") && !query.Has("filter") && !query.Has("filterBy") {
+		for _, property := range slices.Sorted(maps.Keys(d.Filters)) {
+			filled.Add("filter", property+":"+d.Filters[property])
+		}
+	}
+	return filled
+}
+
+// parseItemsQuery parses the item listing parameters. Unknown parameters are
+// rejected when strict mode is on, which is either the endpoint's default or
+// requested explicitly with ?strict=1.
+func parseItemsQuery(query url.Values, allowed []string, strictByDefault bool) (itemsQuery, error) {
+	strict := strictByDefault
+	if v := query.Get("strict"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return itemsQuery{}, &httpError{status: http.StatusBadRequest, message: "invalid strict value: " + v}
+		}
+		strict = b
+	}
+	if strict {
+		if err := checkQueryParams(query, allowed); err != nil {
+			return itemsQuery{}, err
+		}
+	}
+
+	groupBy := query.Get("groupBy")
+	if groupBy == "" {
+		groupBy = "shape" // Default grouping
+	}
+
+	// Only endpoints that sort validate the sort parameters
+	var sortBy, order string
+	if slices.Contains(allowed, "sortBy") {
+		sortBy, order = query.Get("sortBy"), query.Get("order")
+		if sortBy != "" && !slices.Contains(sortFields, sortBy) {
+			return itemsQuery{}, &httpError{status: http.StatusBadRequest,
+				message: fmt.Sprintf("invalid sortBy value: %s (supported: %s)", sortBy, strings.Join(sortFields, ", "))}
+		}
+		if order != "" && order != "asc" && order != "desc" {
+			return itemsQuery{}, &httpError{status: http.StatusBadRequest, message: "invalid order value: " + order + " (supported: asc, desc)"}
+		}
+	}
+
+	var search []string
+	if slices.Contains(allowed, "q") {
+		search = searchTerms(query.Get("q"))
+	}
+
+	return itemsQuery{
+		GroupBy:    groupBy,
+		Filters:    parseFilters(query),
+		SortBy:     sortBy,
+		Descending: order == "desc",
+		Search:     search,
+	}, nil
+}
+
+// parseFilters extracts property filters from the query string. Filters use
+// the repeated "filter=type:value" form; the older filterBy/filterValue pair is
+// still honored.
+func parseFilters(query url.Values) map[string]string {
+	filters := make(map[string]string)
+	for _, filter := range query["filter"] {
+		parts := strings.SplitN(filter, ":", 2)
+		if len(parts) == 2 {
+			filters[parts[0]] = parts[1]
+		}
+	}
+
+	// For backward compatibility with old format
+	if filterBy := query.Get("filterBy"); filterBy != "" {
+		if filterValue := query.Get("filterValue"); filterValue != "" {
+			filters[filterBy] = filterValue
+		}
+	}
+
+	return filters
+}
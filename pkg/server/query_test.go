@@ -0,0 +1,231 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+)
+
+func TestParseItemsQuery_Strict(t *testing.T) {
+	tests := []struct {
+		name            string
+		rawQuery        string
+		allowed         []string
+		strictByDefault bool
+		wantErr         bool
+		wantUnknown     []string
+	}{
+		{
+			name:     "lenient ignores typos",
+			rawQuery: "groupby=color&filter=color:red",
+			allowed:  itemsPageParams,
+		},
+		{
+			name:        "strict opt-in reports typos",
+			rawQuery:    "groupby=color&filter=color:red&strict=1",
+			allowed:     itemsPageParams,
+			wantErr:     true,
+			wantUnknown: []string{"groupby"},
+		},
+		{
+			name:            "strict by default",
+			rawQuery:        "filter=color:red&colour=red&sort=id",
+			allowed:         apiItemsParams,
+			strictByDefault: true,
+			wantErr:         true,
+			wantUnknown:     []string{"colour", "sort"},
+		},
+		{
+			name:            "strict by default with known params only",
+			rawQuery:        "filter=color:red&filterBy=shape&filterValue=circle",
+			allowed:         apiItemsParams,
+			strictByDefault: true,
+		},
+		{
+			name:            "strict explicitly disabled",
+			rawQuery:        "colour=red&strict=0",
+			allowed:         apiItemsParams,
+			strictByDefault: true,
+		},
+		{
+			name:     "invalid strict value",
+			rawQuery: "strict=maybe",
+			allowed:  itemsPageParams,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.rawQuery)
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+			_, err = parseItemsQuery(query, tt.allowed, tt.strictByDefault)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseItemsQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			var he *httpError
+			if !errors.As(err, &he) || he.status != http.StatusBadRequest {
+				t.Fatalf("parseItemsQuery() error = %v, want a 400 *httpError", err)
+			}
+			for _, name := range tt.wantUnknown {
+				if !strings.Contains(he.message, name) {
+					t.Errorf("error %q does not mention unknown parameter %q", he.message, name)
+				}
+			}
+			if len(tt.wantUnknown) > 0 && !strings.Contains(he.message, "supported: ") {
+				t.Errorf("error %q does not list the supported parameters", he.message)
+			}
+		})
+	}
+}
+
+// FuzzItemsQuery renders the items page for any query string, over a store
+// holding hostileItems, and checks that neither the query nor the items reach
+// the page unescaped. The seeds mark their values with <fz, fz"fz, and fz'fz,
+// which the page itself can only contain if a value escaped its context.
+func FuzzItemsQuery(f *testing.F) {
+	for _, seed := range []string{
+		"q=%3Cfz%3E",
+		"filter=color:%3Cfz%22&filter=category:fz%22fz%3E",
+		"groupBy=%3Cfz&sortBy=fz%27fz",
+		"filterBy=%3Cfz&filterValue=fz%22fz%3E",
+		"filter=shape:%3Cimg+src%3Dx+onerror%3Dalert(2)%3E&q=alert",
+		"groupBy=category&order=%3Cfz&strict=fz%27fz",
+		"q=%00%1B%3Cfz&added=%3Cfz",
+		"%zz=%3Cfz&lang=fz%22fz",
+	} {
+		f.Add(seed)
+	}
+	srv := newTestServer(f, testConfig(f))
+	addHostileItems(f, srv)
+	f.Fuzz(func(t *testing.T, rawQuery string) {
+		if _, err := parseItemsQuery(parseQueryLoosely(rawQuery), itemsPageParams, false); err != nil && !errors.As(err, new(*httpError)) {
+			t.Fatalf("parseItemsQuery(%q) error = %v, want an httpError", rawQuery, err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.URL.RawQuery = rawQuery
+		// Errors come back as the HTML error page rather than JSON
+		req.Header.Set("Accept", "text/html")
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		body := rec.Body.String()
+		for _, marker := range append([]string{"<fz", `fz"fz`, "fz'fz"}, hostileMarkers...) {
+			if strings.Contains(body, marker) {
+				t.Fatalf("GET /items?%s (status %d) rendered %q unescaped", rawQuery, rec.Code, marker)
+			}
+		}
+	})
+}
+
+// parseQueryLoosely parses what it can of a query string, as the handlers do
+func parseQueryLoosely(rawQuery string) url.Values {
+	values, _ := url.ParseQuery(rawQuery)
+	return values
+}
+
+func TestCheckQueryParams_Exempt(t *testing.T) {
+	query := url.Values{"filter": {"color:red"}, "testTrace": {"1"}}
+	if err := checkQueryParams(query, apiItemsParams); err == nil {
+		t.Fatal("checkQueryParams() accepted an unregistered parameter")
+	}
+
+	exemptQueryParam("testTrace")
+	t.Cleanup(func() {
+		exemptParamsMu.Lock()
+		delete(exemptParams, "testTrace")
+		exemptParamsMu.Unlock()
+	})
+	if err := checkQueryParams(query, apiItemsParams); err != nil {
+		t.Errorf("checkQueryParams() error = %v for an exempt parameter", err)
+	}
+}
+
+func TestStrictMode_Endpoints(t *testing.T) {
+	tests := []struct {
+		target     string
+		wantStatus int
+	}{
+		{target: "/api/items?groupby=color", wantStatus: http.StatusBadRequest},
+		{target: "/api/items?filter=color:red", wantStatus: http.StatusOK},
+		{target: "/items?groupby=color", wantStatus: http.StatusOK},
+		{target: "/items?groupby=color&strict=1", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newTestRouter(t).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestFillDefaults(t *testing.T) {
+	d := config.ViewDefaults{
+		GroupBy:    "color",
+		SortBy:     "id",
+		Descending: true,
+		Filters:    map[string]string{"shape": "circle", "category": "A"},
+	}
+
+	tests := []struct {
+		name     string
+		rawQuery string
+		allowed  []string
+		want     string
+	}{
+		{
+			name:    "everything filled",
+			allowed: itemsPageParams,
+			want:    "filter=category%3AA&filter=shape%3Acircle&groupBy=color&order=desc&sortBy=id",
+		},
+		{
+			name:     "given settings are kept",
+			rawQuery: "groupBy=shape&sortBy=color&filter=color:red",
+			allowed:  itemsPageParams,
+			want:     "filter=color%3Ared&groupBy=shape&sortBy=color",
+		},
+		{
+			name:     "a legacy filter counts as given",
+			rawQuery: "filterBy=color&filterValue=red",
+			allowed:  itemsPageParams,
+			want:     "filterBy=color&filterValue=red&groupBy=color&order=desc&sortBy=id",
+		},
+		{
+			name:     "empty filter opts out of the default filters",
+			rawQuery: "filter=",
+			allowed:  itemsPageParams,
+			want:     "filter=&groupBy=color&order=desc&sortBy=id",
+		},
+		{
+			name:    "only allowed parameters",
+			allowed: apiItemsParams,
+			want:    "filter=category%3AA&filter=shape%3Acircle",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.rawQuery)
+			before := query.Encode()
+			if got := fillDefaults(d, query, tt.allowed).Encode(); got != tt.want {
+				t.Errorf("fillDefaults() = %q, want %q", got, tt.want)
+			}
+			if query.Encode() != before {
+				t.Error("fillDefaults() modified its argument")
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+func TestReadOnly_RefusesEveryWrite(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.ReadOnly = true
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newCollectionServer(t, cfg)
+	handler := srv.Handler()
+
+	type request struct{ method, path, body string }
+	var requests []request
+	for _, route := range srv.apiRoutes() {
+		if !isWriteMethod(route.method) || route.path == sharePath {
+			continue
+		}
+		path := strings.ReplaceAll(route.path, "{id}", "1")
+		requests = append(requests,
+			request{route.method, path, `{"color":"red","shape":"circle","category":"A"}`},
+			request{route.method, "/api/c/a/" + strings.TrimPrefix(path, "/api/"), `{"color":"red","shape":"circle","category":"A"}`},
+		)
+	}
+	for _, prefix := range []string{"", "/c/a"} {
+		requests = append(requests,
+			request{http.MethodGet, prefix + "/items/new", ""},
+			request{http.MethodPost, prefix + "/items", "color=red&shape=circle&category=A"},
+			request{http.MethodGet, prefix + "/items/1/edit", ""},
+			request{http.MethodPost, prefix + "/items/1/edit", "color=blue&shape=circle&category=A"},
+			request{http.MethodGet, prefix + "/items/1/delete", ""},
+			request{http.MethodPost, prefix + "/items/1/delete", ""},
+		)
+	}
+	requests = append(requests, request{http.MethodPost, graphqlPath,
+		`{"query":"mutation { deleteItem(id: 1) }"}`})
+
+	for _, req := range requests {
+		r := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+		if strings.HasPrefix(req.body, "{") {
+			r.Header.Set("Content-Type", "application/json")
+		} else {
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "server is read-only") {
+			t.Errorf("%s %s: status = %d, want 403 read-only: %s", req.method, req.path, rec.Code, rec.Body)
+		}
+	}
+	if srv.store.Len() != 3 || srv.collections["a"].store.Len() != 1 {
+		t.Errorf("stores hold %d and %d items, want them unchanged", srv.store.Len(), srv.collections["a"].store.Len())
+	}
+
+	// Reads, GraphQL queries, and short links keep working
+	for _, req := range []request{
+		{http.MethodGet, "/items", ""},
+		{http.MethodGet, "/api/items/1", ""},
+		{http.MethodGet, "/healthz", ""},
+		{http.MethodPost, graphqlPath, `{"query":"{ item(id: 1) { color } }"}`},
+		{http.MethodPost, sharePath, `{"query":"groupBy=color"}`},
+	} {
+		r := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+		r.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if rec.Code >= 400 {
+			t.Errorf("%s %s: status = %d, want it served: %s", req.method, req.path, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestReadOnly_HidesFormLinks(t *testing.T) {
+	cfg := testConfig(t)
+	for _, readOnly := range []bool{false, true} {
+		cfg.ReadOnly = readOnly
+		body := getItems(t, newTestServer(t, cfg), "/items").Body.String()
+		for _, link := range []string{`class="add-item"`, `class="item-edit"`} {
+			if strings.Contains(body, link) == readOnly {
+				t.Errorf("read-only %v: page contains %s = %v", readOnly, link, !readOnly)
+			}
+		}
+	}
+}
+
+func TestReadOnly_StoreRefusalIsForbidden(t *testing.T) {
+	// A handler that reaches a read-only store answers 403 rather than 500
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+	srv.store = itemstore.ReadOnly(srv.store)
+
+	r := httptest.NewRequest(http.MethodDelete, "/api/items/1", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, r)
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "server is read-only") {
+		t.Errorf("DELETE through a read-only store: status = %d, want 403: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"html/template"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// searchTerms splits the q parameter into the distinct terms an item must
+// all contain
+func searchTerms(q string) []string {
+	var terms []string
+	for _, term := range strings.Fields(q) {
+		if !slices.ContainsFunc(terms, func(t string) bool { return strings.EqualFold(t, term) }) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// searchItems keeps the items whose color, shape, or category contains each
+// term, ignoring case
+func searchItems(items []itemstore.Item, terms []string) []itemstore.Item {
+	if len(terms) == 0 {
+		return items
+	}
+	return slices.DeleteFunc(items, func(item itemstore.Item) bool {
+		for _, term := range terms {
+			if !containsTerm(item.Color, term) && !containsTerm(item.Shape, term) && !containsTerm(item.Category, term) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// containsTerm reports whether term appears in text, ignoring case
+func containsTerm(text, term string) bool {
+	for i := range text {
+		if foldPrefix(text[i:], term) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// foldPrefix returns the length in bytes of the prefix of text that equals
+// term ignoring case, or 0 when there is none. Case folding can change a
+// character's encoded length, so the prefix is measured in runes.
+func foldPrefix(text, term string) int {
+	n := utf8.RuneCountInString(term)
+	if n == 0 {
+		return 0
+	}
+	end := 0
+	for i := 0; i < n; i++ {
+		if end >= len(text) {
+			return 0
+		}
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+	if !strings.EqualFold(text[:end], term) {
+		return 0
+	}
+	return end
+}
+
+// highlight escapes text for HTML and wraps each case-insensitive match of
+// the terms in <mark>. Matches that overlap or touch share one mark. Only the
+// escaped text and the mark tags reach the output, so the result is safe to
+// insert as template.HTML.
+func highlight(text string, terms []string) template.HTML {
+	var b strings.Builder
+	plain := 0 // start of the text not yet written
+	for i := 0; i < len(text); {
+		end := i
+		// Extend the match over every match that starts inside or right
+		// after it
+		for j := i; j <= end && j < len(text); {
+			for _, term := range terms {
+				end = max(end, j+foldPrefix(text[j:], term))
+			}
+			_, size := utf8.DecodeRuneInString(text[j:])
+			j += size
+		}
+		if end == i {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+		b.WriteString(template.HTMLEscapeString(text[plain:i]))
+		b.WriteString("<mark>" + template.HTMLEscapeString(text[i:end]) + "</mark>")
+		plain, i = end, end
+	}
+	b.WriteString(template.HTMLEscapeString(text[plain:]))
+	return template.HTML(b.String())
+}
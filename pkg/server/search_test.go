@@ -0,0 +1,126 @@
+package server
+
+import (
+	"html"
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := map[string][]string{
+		"":                nil,
+		"   ":             nil,
+		"red":             {"red"},
+		"  red   circle ": {"red", "circle"},
+		"Red red RED a":   {"Red", "a"},
+	}
+	for q, want := range tests {
+		if got := searchTerms(q); !reflect.DeepEqual(got, want) {
+			t.Errorf("searchTerms(%q) = %q, want %q", q, got, want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  template.HTML
+	}{
+		{"no terms", "circle", nil, "circle"},
+		{"no match", "circle", []string{"sq"}, "circle"},
+		{"prefix", "circle", []string{"cir"}, "<mark>cir</mark>cle"},
+		{"ignores case", "Circle", []string{"cIRC"}, "<mark>Circ</mark>le"},
+		{"every occurrence", "banana", []string{"an"}, "b<mark>anan</mark>a"},
+		{"separate matches", "abcabc", []string{"a"}, "<mark>a</mark>bc<mark>a</mark>bc"},
+		{"overlapping terms", "triangle", []string{"tria", "angle"}, "<mark>triangle</mark>"},
+		{"several terms", "red square", []string{"red", "squ"}, "<mark>red</mark> <mark>squ</mark>are"},
+		{"multibyte case folding", "KELVIN", []string{"Kelvin"}, "<mark>KELVIN</mark>"},
+		{"escapes plain text", `a<b>&"c'`, nil, "a&lt;b&gt;&amp;&#34;c&#39;"},
+		{"escapes around a match", "<i>red</i>", []string{"red"}, "&lt;i&gt;<mark>red</mark>&lt;/i&gt;"},
+		{"escapes inside a match", "x<y", []string{"x<y"}, "<mark>x&lt;y</mark>"},
+		{"term matching markup", "<script>", []string{"script"}, "&lt;<mark>script</mark>&gt;"},
+		{"term matching an entity", "a&amp;b", []string{"amp"}, "a&amp;<mark>amp</mark>;b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlight(tt.text, tt.terms); got != tt.want {
+				t.Errorf("highlight(%q, %q) = %q, want %q", tt.text, tt.terms, got, tt.want)
+			}
+		})
+	}
+}
+
+// FuzzHighlight checks that highlight never lets markup of its own input
+// through: apart from the mark tags, the result has no tag or quote
+// characters, and unescaped it is the text again
+func FuzzHighlight(f *testing.F) {
+	f.Add("circle", "cir")
+	f.Add(`<script>alert("x")</script>`, "script")
+	f.Add(`"><img src=x onerror='y'>`, `"><`)
+	f.Add("<mark>red</mark>", "mark")
+	f.Add("a&amp;b", "amp;")
+	f.Add("KELVIN \u212a", "k")
+	f.Add("\x00\x1b<\x7f", "\x1b")
+	f.Fuzz(func(t *testing.T, text, term string) {
+		got := string(highlight(text, searchTerms(term)))
+		plain := strings.NewReplacer("<mark>", "", "</mark>", "").Replace(got)
+		if strings.ContainsAny(plain, `<>"'`) {
+			t.Fatalf("highlight(%q, %q) = %q, which has unescaped markup", text, term, got)
+		}
+		// HTMLEscapeString replaces NUL, so only text without one comes
+		// back exactly
+		if utf8.ValidString(text) && !strings.Contains(text, "\x00") && html.UnescapeString(plain) != text {
+			t.Errorf("highlight(%q, %q) = %q, which does not unescape to the text", text, term, got)
+		}
+	})
+}
+
+func TestItemsPage_Search(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	body := getItems(t, srv, "/items?q=CIRC").Body.String()
+
+	if !strings.Contains(body, "item-1 ") || strings.Contains(body, "item-2 ") || strings.Contains(body, "item-3 ") {
+		t.Error("search for circ did not list just the red circle")
+	}
+	if !strings.Contains(body, "<mark>circ</mark>le") {
+		t.Error("matched shape is not highlighted")
+	}
+	if !strings.Contains(body, `name="q" value="CIRC"`) {
+		t.Error("search box does not show the query")
+	}
+
+	// Every term must match, in any property
+	body = getItems(t, srv, "/items?q=a+blue").Body.String()
+	if !strings.Contains(body, "item-2 ") || strings.Contains(body, "item-1 ") {
+		t.Error("search for a and blue did not list just the blue square")
+	}
+}
+
+func TestItemsPage_SearchEscapesValues(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	if _, err := srv.store.Add(itemstore.Item{Color: "red", Shape: "circle", Category: "<script>alert(1)</script>"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	for _, target := range []string{
+		"/items?q=red",    // the category does not match
+		"/items?q=script", // the category matches
+		"/items?q=%3Cscript%3E",
+		"/items?q=alert(1)",
+	} {
+		body := getItems(t, srv, target).Body.String()
+		if strings.Contains(body, "<script>alert") || strings.Contains(body, "<mark><script") {
+			t.Errorf("GET %s rendered the category as markup", target)
+		}
+		if !strings.Contains(body, "item-4 ") {
+			t.Errorf("GET %s did not list the item", target)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// staticAssets are the files under static/ that the templates reference.
+// SelfCheck fails when one is missing or empty.
+var staticAssets = []string{"htmx.min.js"}
+
+// selfCheckItems are the items SelfCheck renders the pages with
+var selfCheckItems = []itemstore.Item{
+	{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"},
+	{ID: itemstore.IntID(2), Color: "blue", Shape: "square", Category: "A"},
+	{ID: itemstore.IntID(3), Color: "green", Shape: "triangle", Category: "B"},
+}
+
+// selfCheckCollection is the collection SelfCheck adds so that the
+// collection index is rendered too
+const selfCheckCollection = "selfcheck"
+
+// selfCheckPage is a request SelfCheck makes and the status it expects
+type selfCheckPage struct {
+	path   string
+	status int
+}
+
+// selfCheckPages render every page template, including the error pages
+var selfCheckPages = []selfCheckPage{
+	{"/", http.StatusOK},
+	{"/items", http.StatusOK},
+	{"/items?groupBy=none&sortBy=color", http.StatusOK},
+	{"/items/new", http.StatusOK},
+	{"/items/1/edit", http.StatusOK},
+	{"/items/1/delete", http.StatusOK},
+	{"/compare?left=filter%3Dcategory%3AA&right=filter%3Dcolor%3Ared", http.StatusOK},
+	{"/compare?left=filter%3Dweight%3A1", http.StatusBadRequest},
+	{"/items?sortBy=weight", http.StatusBadRequest},
+	{"/selfcheck-missing", http.StatusNotFound},
+}
+
+// SelfCheck builds a server with opts over a few sample items and renders
+// every page in every language into io.Discard, then checks that the static
+// files the pages load are embedded. It reports every problem found rather
+// than stopping at the first. Authentication, limits, read-only mode,
+// webhooks, and persisted short links and audit logs are left out of the
+// check server, and the item forms are enabled.
+func SelfCheck(opts ...Option) error {
+	store, err := itemstore.New(selfCheckItems)
+	if err != nil {
+		return err
+	}
+	collection, err := itemstore.New(selfCheckItems)
+	if err != nil {
+		return err
+	}
+	logs := &bytes.Buffer{}
+	opts = append(opts, WithCollection(selfCheckCollection, collection), func(s *Server) {
+		// Only GETs are made, so the forms can be enabled safely
+		s.cfg.AuthUser, s.cfg.AuthPasswordHash = "", ""
+		s.cfg.AllowUnauthenticatedWrites = true
+		s.cfg.RateLimit.RPS = 0
+		s.cfg.ReadOnly = false
+		s.cfg.Webhooks = nil
+		s.cfg.DataFile = ""
+		s.cfg.AuditFile = ""
+		s.cfg.ViewCacheSize = 0
+		// Render failures are logged; keep the messages for the report
+		s.logger = slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{
+			Level: slog.LevelError,
+			ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == "request_id" {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+	})
+	s, err := New(store, opts...)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	handler := s.Handler()
+	for _, lang := range s.messages.Languages() {
+		for _, page := range selfCheckPages {
+			logs.Reset()
+			r := httptest.NewRequest(http.MethodGet, s.url(page.path), nil)
+			r.Header.Set("Accept", "text/html")
+			r.Header.Set("Accept-Language", lang)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			io.Copy(io.Discard, rec.Body)
+			if rec.Code != page.status {
+				errs = append(errs, fmt.Errorf("GET %s in %s: status %d, want %d: %s",
+					page.path, lang, rec.Code, page.status, strings.TrimSpace(logs.String())))
+			}
+		}
+	}
+	errs = append(errs, checkStaticAssets(s.staticFS, staticAssets))
+	return errors.Join(errs...)
+}
+
+// checkStaticAssets reports each of names that fsys lacks or holds empty
+func checkStaticAssets(fsys fs.FS, names []string) error {
+	var errs []error
+	for _, name := range names {
+		info, err := fs.Stat(fsys, name)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("static asset %s: %w", name, err))
+		case info.Size() == 0:
+			errs = append(errs, fmt.Errorf("static asset %s is empty", name))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSelfCheck(t *testing.T) {
+	if err := SelfCheck(WithConfig(testConfig(t))); err != nil {
+		t.Errorf("SelfCheck() of the built-in templates = %v", err)
+	}
+
+	// The check server leaves out what would refuse its requests
+	cfg := testConfig(t)
+	cfg.ReadOnly = true
+	cfg.AuthUser, cfg.AuthPasswordHash = "alice", "$2a$10$invalid"
+	cfg.BasePath = "/dash"
+	if err := SelfCheck(WithConfig(cfg)); err != nil {
+		t.Errorf("SelfCheck() with auth, read-only mode, and a base path = %v", err)
+	}
+}
+
+func TestSelfCheck_BrokenTemplates(t *testing.T) {
+	templates := fstest.MapFS{}
+	for _, name := range []string{"404.html", "collections.html", "error.html", "item_delete.html", "item_form.html", "items.html", "theme.html"} {
+		data, err := os.ReadFile("templates/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		templates[name] = &fstest.MapFile{Data: data}
+	}
+	// Both parse, but fail as they are executed
+	templates["items.html"] = &fstest.MapFile{Data: []byte(`{{.NoSuchField}}`)}
+	templates["item_delete.html"] = &fstest.MapFile{Data: []byte(`{{template "no-such-partial"}}`)}
+
+	err := SelfCheck(WithConfig(testConfig(t)), WithTemplates(templates))
+	if err == nil {
+		t.Fatal("SelfCheck() = nil, want the broken pages reported")
+	}
+	for _, want := range []string{
+		"GET /items in en: status 500",
+		"GET /items?groupBy=none&sortBy=color in de: status 500",
+		"NoSuchField",
+		"GET /items/1/delete in en: status 500",
+		"no-such-partial",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("SelfCheck() error lacks %q:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "/items/new") {
+		t.Errorf("SelfCheck() reported the intact form page:\n%v", err)
+	}
+
+	templates["theme.html"] = &fstest.MapFile{Data: []byte(`{{define "theme-style"}}{{end`)}
+	if err := SelfCheck(WithConfig(testConfig(t)), WithTemplates(templates)); err == nil || !strings.Contains(err.Error(), "theme.html") {
+		t.Errorf("SelfCheck() with a template that does not parse = %v", err)
+	}
+}
+
+func TestCheckStaticAssets(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":   {Data: []byte("ok")},
+		"empty.js": {Data: nil},
+	}
+	err := checkStaticAssets(fsys, []string{"app.js", "empty.js", "missing.js"})
+	if err == nil || !strings.Contains(err.Error(), "empty.js is empty") || !strings.Contains(err.Error(), "missing.js") {
+		t.Errorf("checkStaticAssets() = %v, want the empty and the missing file", err)
+	}
+	if err := checkStaticAssets(fsys, []string{"app.js"}); err != nil {
+		t.Errorf("checkStaticAssets() = %v, want nil", err)
+	}
+}
+
+// TestStaticAssets_Manifest keeps staticAssets in step with the static files
+// the templates load
+func TestStaticAssets_Manifest(t *testing.T) {
+	pages, err := os.ReadDir("templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := regexp.MustCompile(`(?:/static/|asset ")([^"'?#\s]+)`)
+	for _, page := range pages {
+		data, err := os.ReadFile("templates/" + page.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range ref.FindAllStringSubmatch(string(data), -1) {
+			if !slices.Contains(staticAssets, m[1]) {
+				t.Errorf("%s loads /static/%s, which staticAssets does not list", page.Name(), m[1])
+			}
+		}
+	}
+}
@@ -0,0 +1,421 @@
+// Package server is the dashboard's HTTP layer: the item pages and forms, the
+// JSON API, GraphQL, and the middleware in front of them. New serves a store
+// with the built-in templates and assets; mount Handler on a mux of your own
+// or run ListenAndServe.
+package server
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/audit"
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/events"
+	"github.com/ElodinLaarz/dashboard/pkg/graphqlapi"
+	"github.com/ElodinLaarz/dashboard/pkg/i18n"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/palette"
+	"github.com/ElodinLaarz/dashboard/pkg/preset"
+	"github.com/ElodinLaarz/dashboard/pkg/ratelimit"
+	"github.com/ElodinLaarz/dashboard/pkg/shortlink"
+	"github.com/ElodinLaarz/dashboard/pkg/webhook"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//go:embed templates/* static/* locales/*
+var embedFS embed.FS
+
+// Server holds the dependencies shared by the HTTP handlers and middleware
+type Server struct {
+	cfg      config.Config
+	store    itemstore.Store
+	logger   *slog.Logger
+	staticFS fs.FS
+	// static serves staticFS: fingerprinted and compressed at startup, or
+	// read from disk on every request in dev mode
+	static staticSource
+	// templates provides the page templates: parsed once at startup, or
+	// re-read from disk in dev mode
+	templates templateSource
+	// readinessChecks are run by /readyz
+	readinessChecks []readinessCheck
+	// hub broadcasts store changes to /api/events subscribers
+	hub *events.Hub
+	// shutdown is canceled by beginShutdown once Serve starts shutting
+	// down, ending the long-lived routes
+	shutdown      context.Context
+	beginShutdown context.CancelFunc
+	// collections are the named stores served next to store, keyed by name
+	collections map[string]*collection
+	// webhooks delivers change notifications; nil when none are configured
+	webhooks *webhook.Dispatcher
+	// audit records the changes made through the server; nil when disabled
+	audit *audit.Log
+	// graphql executes /graphql requests against store
+	graphql *graphqlapi.Executor
+	// apiKeys is the write policy shared by the JSON API and GraphQL
+	// mutations
+	apiKeys apiKeyAuth
+	// csrf rejects cross-origin form submissions
+	csrf *http.CrossOriginProtection
+	// palette maps color names to hex values
+	palette *palette.Palette
+	// messages are the user interface texts in every supported language
+	messages *i18n.Bundle
+	// shares holds the short links created by /api/share
+	shares *shortlink.Store
+	// presets holds the filter presets created by /api/presets
+	presets *preset.Store
+	// imports holds the CSV imports previewed on /items/import until they
+	// are confirmed
+	imports *importStore
+	// shareLimiter rate limits each client's short link requests
+	shareLimiter *ratelimit.Limiter
+	// stateKey signs the view state cookie
+	stateKey []byte
+	// tracer creates request spans; nil when tracing is disabled
+	tracer trace.Tracer
+	// startedAt is when the server was created
+	startedAt time.Time
+	// itemsAtStartup is the number of items the store held when the server
+	// was created
+	itemsAtStartup int
+	// views caches the groups and sidebar of recent /items pages; nil when
+	// disabled
+	views *viewCache
+	// templatesFS replaces the embedded templates when set
+	templatesFS fs.FS
+	// errs are the problems the options ran into, reported by New
+	errs []error
+}
+
+// Option configures a Server. Options apply in order, so WithConfig goes
+// before the options that change part of the configuration.
+type Option func(*Server)
+
+// WithConfig sets the whole configuration, which defaults to config.Default.
+// The settings for the store and the process, such as the data file, the
+// collections' files, and the log level, are left to the caller.
+func WithConfig(cfg config.Config) Option {
+	return func(s *Server) { s.cfg = cfg }
+}
+
+// WithLogger sets the logger, which defaults to discarding every record
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) { s.logger = logger }
+}
+
+// WithBasePath serves the dashboard under a URL prefix such as "/dashboard"
+// and includes it in every link and redirect
+func WithBasePath(path string) Option {
+	return func(s *Server) { s.cfg.BasePath = strings.TrimSuffix(path, "/") }
+}
+
+// WithDefaults sets the view of /items when a request asks for none
+func WithDefaults(defaults config.ViewDefaults) Option {
+	return func(s *Server) { s.cfg.Defaults = defaults }
+}
+
+// WithTemplates renders the pages with the *.html templates in fsys instead
+// of the built-in ones; a page fsys lacks fails when requested. In dev mode
+// they are re-read on every request.
+func WithTemplates(fsys fs.FS) Option {
+	return func(s *Server) { s.templatesFS = fsys }
+}
+
+// WithStatic serves the files in fsys below /static/ instead of the
+// built-in ones. In dev mode they are re-read on every request.
+func WithStatic(fsys fs.FS) Option {
+	return func(s *Server) { s.staticFS = fsys }
+}
+
+// WithCollection serves store as the collection name, under /c/{name}/ and
+// /api/c/{name}/, next to the default store
+func WithCollection(name string, store itemstore.Store) Option {
+	return func(s *Server) {
+		if err := s.addCollection(name, store); err != nil {
+			s.errs = append(s.errs, err)
+		}
+	}
+}
+
+// WithReadinessCheck adds a dependency verified by /readyz, reported under
+// name
+func WithReadinessCheck(name string, check func(ctx context.Context) error) Option {
+	return func(s *Server) {
+		s.readinessChecks = append(s.readinessChecks, readinessCheck{name: name, check: check})
+	}
+}
+
+// WithTracerProvider traces every request with a tracer from tp
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(s *Server) { s.tracer = tp.Tracer(tracerName) }
+}
+
+// New creates a server that serves store
+func New(store itemstore.Store, opts ...Option) (*Server, error) {
+	staticFS, err := fs.Sub(embedFS, "static")
+	if err != nil {
+		return nil, fmt.Errorf("static directory in embedded filesystem: %w", err)
+	}
+	s := &Server{
+		cfg:       config.Default(),
+		store:     store,
+		logger:    slog.New(slog.DiscardHandler),
+		staticFS:  staticFS,
+		hub:       events.NewHub(sseClientBuffer),
+		startedAt: time.Now(),
+		csrf:      http.NewCrossOriginProtection(),
+		imports:   newImportStore(),
+	}
+	s.shutdown, s.beginShutdown = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(s)
+	}
+	if len(s.errs) > 0 {
+		return nil, errors.Join(s.errs...)
+	}
+	cfg, logger := s.cfg, s.logger
+	s.apiKeys = newAPIKeyAuth(cfg.APIKeys, cfg.AllowUnauthenticatedWrites)
+	s.views = newViewCache(cfg.ViewCacheSize)
+	if s.palette, err = palette.New(cfg.Palette); err != nil {
+		return nil, err
+	}
+	if s.shares, err = newShareStore(cfg); err != nil {
+		return nil, err
+	}
+	s.shareLimiter = ratelimit.New(shareRate, shareBurst)
+	if s.presets, err = newPresetStore(cfg); err != nil {
+		return nil, err
+	}
+	if err := checkConfiguredViews(cfg.Views); err != nil {
+		return nil, err
+	}
+	if s.stateKey, err = newStateKey(cfg.StateSecret); err != nil {
+		return nil, fmt.Errorf("view state key: %w", err)
+	}
+	if s.messages, err = loadMessages(); err != nil {
+		return nil, err
+	}
+	// Dev mode swaps both the templates and the static files for ones
+	// read on every request
+	if s.templates, err = s.newTemplateSource(cfg.Dev); err != nil {
+		return nil, err
+	}
+	if s.static, err = s.newStaticSource(cfg.Dev); err != nil {
+		return nil, err
+	}
+	s.graphql, err = graphqlapi.New(store, graphqlapi.WithLimits(graphqlapi.Limits{
+		MaxDepth:      cfg.GraphQLMaxDepth,
+		MaxComplexity: cfg.GraphQLMaxComplexity,
+		ListSize:      graphqlapi.DefaultLimits.ListSize,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	if store != nil {
+		s.itemsAtStartup = store.Len()
+		publishChanges(store, s.hub)
+		if len(cfg.Webhooks) > 0 {
+			s.webhooks = webhook.New(cfg.Webhooks,
+				webhook.WithLogger(logger),
+				webhook.WithConcurrency(cfg.WebhookConcurrency))
+			forwardWebhooks(store, s.webhooks)
+		}
+	}
+	if cfg.AuditSize > 0 {
+		s.audit, err = audit.New(cfg.AuditSize, audit.WithFile(cfg.AuditFile), audit.WithLogger(logger))
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.readinessChecks = append(s.defaultReadinessChecks(), s.readinessChecks...)
+	return s, nil
+}
+
+// Handler returns the dashboard's routes wrapped in the middleware chain
+// shared by every request. The request ID is assigned first so that logging,
+// panic recovery, and error responses can all refer to it.
+func (s *Server) Handler() http.Handler {
+	return s.withMiddleware(s.routes())
+}
+
+// withMiddleware wraps router in the middleware chain
+func (s *Server) withMiddleware(router http.Handler) http.Handler {
+	cfg := s.cfg
+
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.RPS > 0 {
+		limiter = ratelimit.New(cfg.RateLimit.RPS, cfg.RateLimit.Burst)
+	}
+
+	if s.tracer != nil {
+		router = recordRoute(router)
+	}
+
+	var handler http.Handler = requestIDMiddleware(
+		s.traceMiddleware(
+			s.logRequest(
+				s.recoverMiddleware(
+					s.stripBasePath(
+						corsMiddleware(cfg.CORS,
+							s.basicAuthMiddleware(cfg.AuthUser, cfg.AuthPasswordHash,
+								s.rateLimitMiddleware(limiter,
+									s.readOnlyMiddleware(cfg.ReadOnly,
+										s.apiKeyMiddleware(cfg.APIKeys, cfg.AllowUnauthenticatedWrites,
+											s.languageMiddleware(router)))))))))))
+
+	if cfg.TLSEnabled() {
+		handler = hstsMiddleware(handler)
+	}
+	return handler
+}
+
+// routeMux is a ServeMux that puts every route registered through it behind
+// the per-request timeout, except API routes that are long-lived, which end
+// on shutdown instead
+type routeMux struct {
+	*http.ServeMux
+	timeout   func(http.Handler) http.Handler
+	longLived func(http.Handler) http.Handler
+}
+
+// newRouteMux returns an empty routeMux timing routes out after
+// --request-timeout
+func (s *Server) newRouteMux() routeMux {
+	return routeMux{
+		ServeMux: http.NewServeMux(),
+		timeout: func(h http.Handler) http.Handler {
+			return s.timeoutMiddleware(s.cfg.Timeouts.Request, h)
+		},
+		longLived: s.endOnShutdownMiddleware,
+	}
+}
+
+func (m routeMux) Handle(pattern string, h http.Handler) {
+	m.ServeMux.Handle(pattern, m.timeout(h))
+}
+
+func (m routeMux) HandleFunc(pattern string, h func(http.ResponseWriter, *http.Request)) {
+	m.Handle(pattern, http.HandlerFunc(h))
+}
+
+// handleAPIRoutes registers every API route, each timed unless it is
+// long-lived
+func (m routeMux) handleAPIRoutes(routes []apiRoute) {
+	for _, route := range routes {
+		pattern := route.method + " " + route.path
+		if route.kind == longLivedRoute {
+			m.ServeMux.Handle(pattern, m.longLived(route.handler))
+			continue
+		}
+		m.Handle(pattern, route.handler)
+	}
+}
+
+// routes registers all HTTP handlers on a fresh mux. The "/" pattern is the
+// mux's fallback, so every path that matches nothing else ends up in
+// notFoundHandler.
+func (s *Server) routes() *http.ServeMux {
+	mux := s.newRouteMux()
+
+	mux.Handle("/static/", http.StripPrefix("/static/", s.static))
+
+	mux.HandleFunc("/{$}", s.indexHandler)
+	mux.HandleFunc("GET /healthz", s.healthzHandler)
+	mux.HandleFunc("GET /readyz", s.readyzHandler)
+	mux.HandleFunc("GET /version", s.versionHandler)
+	s.registerItemRoutes(mux)
+	mux.HandleFunc("GET /theme", s.themeHandler)
+	mux.HandleFunc("GET /shapes/{file}", s.shapeHandler)
+	mux.HandleFunc("GET /s/{token}", s.shortLinkHandler)
+	mux.HandleFunc("GET /s/{token}/qr.png", s.shortLinkQRHandler)
+	mux.HandleFunc("GET "+graphqlPath, s.graphqlHandler)
+	mux.HandleFunc("POST "+graphqlPath, s.graphqlHandler)
+
+	mux.handleAPIRoutes(s.apiRoutes())
+	if len(s.collections) > 0 {
+		// The collection routes are timed by their own mux
+		collectionRoutes := s.collectionRoutes()
+		mux.ServeMux.Handle(collectionPagePrefix+"{collection}/", s.serveCollection(collectionPagePrefix, collectionRoutes))
+		mux.ServeMux.Handle(collectionAPIPrefix+"{collection}/", s.serveCollection(collectionAPIPrefix, collectionRoutes))
+	}
+	mux.HandleFunc("/", s.notFoundHandler)
+
+	return mux.ServeMux
+}
+
+// registerItemRoutes registers the item pages, forms, import, export, feed,
+// comparison, and saved views, which are served for the default store and
+// for every collection
+func (s *Server) registerItemRoutes(mux routeMux) {
+	mux.HandleFunc("/items", s.itemsHandler)
+	mux.HandleFunc("GET /items/new", s.newItemFormHandler)
+	mux.HandleFunc("GET /items/export", s.exportHandler)
+	mux.HandleFunc("GET /items/import", s.importFormHandler)
+	mux.Handle("POST /items/import", s.csrf.Handler(http.HandlerFunc(s.importUploadHandler)))
+	mux.Handle("POST /items/import/confirm", s.csrf.Handler(http.HandlerFunc(s.importConfirmHandler)))
+	mux.Handle("POST /items", s.csrf.Handler(http.HandlerFunc(s.createItemFormHandler)))
+	mux.HandleFunc("GET /items/{id}/edit", s.editItemFormHandler)
+	mux.Handle("POST /items/{id}/edit", s.csrf.Handler(http.HandlerFunc(s.updateItemFormHandler)))
+	mux.HandleFunc("GET /items/{id}/delete", s.deleteItemConfirmHandler)
+	mux.Handle("POST /items/{id}/delete", s.csrf.Handler(http.HandlerFunc(s.deleteItemFormHandler)))
+	mux.HandleFunc("GET "+feedPath, s.feedHandler)
+	mux.HandleFunc("GET /compare", s.compareHandler)
+	mux.HandleFunc("GET /views/{name}", s.viewHandler)
+}
+
+// routeKind says whether a route is held to the per-request timeout
+type routeKind int
+
+const (
+	// timedRoute is cut off after --request-timeout
+	timedRoute routeKind = iota
+	// longLivedRoute streams for as long as the client stays, so it has no
+	// timeout. Its handler lifts the connection's write deadline with
+	// http.ResponseController.
+	longLivedRoute
+)
+
+// apiRoute is one endpoint of the JSON API
+type apiRoute struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+	kind    routeKind
+}
+
+// apiRoutes lists the JSON API endpoints. Each one must also be described in
+// apiOperations so it appears in the OpenAPI document.
+func (s *Server) apiRoutes() []apiRoute {
+	return []apiRoute{
+		{http.MethodGet, "/api/openapi.json", s.apiOpenAPIHandler, timedRoute},
+		{http.MethodGet, "/api/events", s.apiEventsHandler, longLivedRoute},
+		{http.MethodGet, "/api/items", s.apiListItemsHandler, timedRoute},
+		{http.MethodPost, "/api/items", s.apiCreateItemHandler, timedRoute},
+		{http.MethodPost, "/api/items/bulk", s.apiBulkCreateHandler, timedRoute},
+		{http.MethodGet, "/api/items/count", s.apiCountItemsHandler, timedRoute},
+		{http.MethodGet, "/api/items/stream", s.apiStreamItemsHandler, longLivedRoute},
+		{http.MethodGet, "/api/items/{id}", s.apiGetItemHandler, timedRoute},
+		{http.MethodPut, "/api/items/{id}", s.apiReplaceItemHandler, timedRoute},
+		{http.MethodPatch, "/api/items/{id}", s.apiPatchItemHandler, timedRoute},
+		{http.MethodDelete, "/api/items/{id}", s.apiDeleteItemHandler, timedRoute},
+		{http.MethodGet, "/api/charts/{property}", s.apiChartHandler, timedRoute},
+		{http.MethodGet, "/api/palette", s.apiPaletteHandler, timedRoute},
+		{http.MethodGet, "/api/combinations", s.apiCombinationsHandler, timedRoute},
+		{http.MethodGet, "/api/compare", s.apiCompareHandler, timedRoute},
+		{http.MethodGet, "/api/audit", s.apiAuditHandler, timedRoute},
+		{http.MethodPost, "/api/undo", s.apiUndoHandler, timedRoute},
+		{http.MethodPost, sharePath, s.apiShareHandler, timedRoute},
+		{http.MethodGet, "/api/presets", s.apiListPresetsHandler, timedRoute},
+		{http.MethodPost, "/api/presets", s.apiCreatePresetHandler, timedRoute},
+		{http.MethodDelete, "/api/presets/{name}", s.apiDeletePresetHandler, timedRoute},
+	}
+}
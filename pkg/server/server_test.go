@@ -0,0 +1,441 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/buildinfo"
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// update rewrites golden files with the current output instead of comparing
+// against them
+var update = flag.Bool("update", false, "update golden files")
+
+// testConfig returns the default configuration, ignoring the environment
+func testConfig(t testing.TB) config.Config {
+	t.Helper()
+	cfg, err := config.Parse(nil, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("config.Parse() error = %v", err)
+	}
+	return cfg
+}
+
+// newTestServer returns a server over a fresh three-item store that discards
+// its logs
+func newTestServer(t testing.TB, cfg config.Config) *Server {
+	t.Helper()
+	store, err := itemstore.New([]itemstore.Item{
+		{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"},
+		{ID: itemstore.IntID(2), Color: "blue", Shape: "square", Category: "A"},
+		{ID: itemstore.IntID(3), Color: "green", Shape: "triangle", Category: "B"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	srv, err := New(store, WithConfig(cfg))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return srv
+}
+
+// captureLogs redirects srv's logger to a buffer of JSON records at debug
+// level
+func captureLogs(srv *Server) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	srv.logger = slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return buf
+}
+
+// logRecords decodes the JSON records written to a captureLogs buffer
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for dec.More() {
+		var rec map[string]any
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatalf("Failed to decode log record: %v", err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func newTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+	return newTestServer(t, testConfig(t)).routes()
+}
+
+func TestNotFoundHandler_HTML(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/itmes", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	rec := httptest.NewRecorder()
+
+	newTestRouter(t).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, want text/html", ct)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `href="/items"`) {
+		t.Errorf("404 page has no link back to the dashboard: %s", body)
+	}
+}
+
+func TestNotFoundHandler_JSON(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/nope", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	newTestRouter(t).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var resp errorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+	if resp.Status != http.StatusNotFound || resp.Error == "" {
+		t.Errorf("error envelope = %+v, want status 404 with a message", resp)
+	}
+}
+
+func TestIndexHandler_ExactRoot(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+
+	newTestRouter(t).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusFound)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/items" {
+		t.Errorf("Location = %q, want /items", loc)
+	}
+}
+
+// sampleItems match the dashboard's demo items
+var sampleItems = []itemstore.Item{
+	{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"},
+	{ID: itemstore.IntID(2), Color: "blue", Shape: "square", Category: "A"},
+	{ID: itemstore.IntID(3), Color: "green", Shape: "triangle", Category: "B"},
+	{ID: itemstore.IntID(4), Color: "red", Shape: "square", Category: "B"},
+	{ID: itemstore.IntID(5), Color: "blue", Shape: "circle", Category: "C"},
+	{ID: itemstore.IntID(6), Color: "green", Shape: "square", Category: "C"},
+}
+
+// TestItemsPage_Golden pins the rendered /items page for the sample items.
+// Run with -update after an intended change to the page.
+func TestItemsPage_Golden(t *testing.T) {
+	store, err := itemstore.New(sampleItems)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	srv, err := New(store, WithConfig(testConfig(t)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	rec := getItems(t, srv, "/items")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /items status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	// The build details differ between toolchains and builds
+	info := buildinfo.Get()
+	got := strings.NewReplacer(
+		">"+info.Version+"</a>", ">VERSION</a>",
+		"commit "+info.Commit, "commit COMMIT",
+		"built "+info.BuildDate, "built DATE",
+		info.GoVersion, "GOVERSION",
+	).Replace(rec.Body.String())
+
+	golden := filepath.Join("testdata", "items.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatalf("Failed to update %s: %v", golden, err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", golden, err)
+	}
+	if got != string(want) {
+		t.Errorf("GET /items differs from %s; rerun with -update if the change is intended\n%s", golden, firstDiff(string(want), got))
+	}
+}
+
+// firstDiff describes the first line where got differs from want
+func firstDiff(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := range max(len(wantLines), len(gotLines)) {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want %q\n  got  %q", i+1, w, g)
+		}
+	}
+	return ""
+}
+
+func TestItemsPage_GroupCounts(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	tests := []struct {
+		name     string
+		rawQuery string
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "one and many",
+			rawQuery: "groupBy=category",
+			want:     []string{"&mdash; 2 items</span>", "&mdash; 1 item</span>", `data-count="2"`},
+		},
+		{
+			name:     "counts follow the filter",
+			rawQuery: "groupBy=category&filter=color:red",
+			want:     []string{`data-group="A" data-count="1"`, "&mdash; 1 item</span>"},
+			notWant:  []string{"2 items", `data-group="B"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?"+tt.rawQuery, nil))
+			body := rec.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("items page does not contain %s", want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(body, notWant) {
+					t.Errorf("items page contains %s", notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestItemsPage_GroupByNone(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	tests := []struct {
+		name      string
+		rawQuery  string
+		wantOrder []int
+		wantFlat  bool
+	}{
+		{"flat sorts globally", "groupBy=none&sortBy=color", []int{2, 3, 1}, true},
+		{"flat keeps store order", "groupBy=none", []int{1, 2, 3}, true},
+		{"grouped sorts within groups", "groupBy=category&sortBy=color", []int{2, 1, 3}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?"+tt.rawQuery, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			body := rec.Body.String()
+
+			if hasHeaders := strings.Contains(body, `class="group-title"`); hasHeaders == tt.wantFlat {
+				t.Errorf("group headers shown = %v, want %v", hasHeaders, !tt.wantFlat)
+			}
+			last := -1
+			for _, id := range tt.wantOrder {
+				i := strings.Index(body, fmt.Sprintf(`class="item item-%d `, id))
+				if i < 0 || i < last {
+					t.Fatalf("item %d is missing or out of order, want order %v", id, tt.wantOrder)
+				}
+				last = i
+			}
+		})
+	}
+}
+
+// hostileItems have values that are markup, break out of attributes, or hold
+// control characters, as a careless import might store them
+var hostileItems = []itemstore.Item{
+	{Color: `"><script>alert(1)</script>`, Shape: "circle", Category: "A"},
+	{Color: "red", Shape: `<img src=x onerror=alert(2)>`, Category: "B"},
+	{Color: "blue", Shape: "square", Category: `'><svg onload=alert(3)>`},
+	{Color: "green", Shape: "triangle", Category: "\x00\x1b</h3><b>x"},
+}
+
+// hostileMarkers are the parts of hostileItems that must never reach a page
+// unescaped
+var hostileMarkers = []string{"<script>alert", "<img src=x", "<svg onload", "</h3><b>", "\x00"}
+
+// addHostileItems adds hostileItems to srv's store
+func addHostileItems(t testing.TB, srv *Server) {
+	t.Helper()
+	for _, item := range hostileItems {
+		if _, err := srv.store.Add(item); err != nil {
+			t.Fatalf("Add(%q) error = %v", item, err)
+		}
+	}
+}
+
+func TestItemsPage_HostileValues(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	addHostileItems(t, srv)
+
+	targets := []string{"/items", "/items?groupBy=none", "/items?q=alert", "/items?q=%3C"}
+	for _, groupBy := range groupFields {
+		targets = append(targets, "/items?groupBy="+groupBy)
+	}
+	for _, item := range hostileItems {
+		for property, value := range map[string]string{"color": item.Color, "shape": item.Shape, "category": item.Category} {
+			targets = append(targets, "/items?"+url.Values{"filter": {property + ":" + value}, "q": {value}}.Encode())
+		}
+	}
+	for _, target := range targets {
+		rec := getItems(t, srv, target)
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s status = %d, want 200", target, rec.Code)
+			continue
+		}
+		for _, marker := range hostileMarkers {
+			if strings.Contains(rec.Body.String(), marker) {
+				t.Errorf("GET %s rendered %q unescaped", target, marker)
+			}
+		}
+	}
+}
+
+func TestConfiguredDefaults(t *testing.T) {
+	cfg, err := config.Parse([]string{
+		"--default-group-by=category",
+		"--default-sort=id:desc",
+		"--default-filters=shape:square",
+	}, func(string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer(t, cfg)
+	if _, err := srv.store.Add(itemstore.Item{ID: itemstore.IntID(4), Color: "red", Shape: "square", Category: "A"}); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(target string) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d", target, rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	body := get("/items")
+	for _, want := range []string{
+		`data-group="A" data-count="2"`,
+		`<span class="filter-value">Square</span>`,
+		`<a class="clear-filters" href="/items?filter=&amp;groupBy=category&amp;order=desc&amp;sortBy=id">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("items page does not contain %s", want)
+		}
+	}
+	if i, j := strings.Index(body, `class="item item-4 `), strings.Index(body, `class="item item-2 `); i < 0 || j < 0 || i > j {
+		t.Error("items are not sorted by descending ID")
+	}
+	if strings.Contains(body, `class="item item-1 `) {
+		t.Error("default filter not applied")
+	}
+
+	// Explicit parameters win, and an empty filter lists every item
+	body = get("/items?groupBy=color&filter=")
+	if !strings.Contains(body, `data-group="green"`) || strings.Contains(body, `data-group="A"`) {
+		t.Error("explicit groupBy and empty filter were not honored")
+	}
+
+	var resp itemListResponse
+	if err := json.Unmarshal([]byte(get("/api/items")), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Meta.Count != 2 {
+		t.Errorf("GET /api/items returned %d items, want the 2 squares", resp.Meta.Count)
+	}
+	if err := json.Unmarshal([]byte(get("/api/items?filter=color:green")), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Meta.Count != 1 {
+		t.Errorf("GET /api/items?filter=color:green returned %d items, want 1", resp.Meta.Count)
+	}
+}
+
+// newBenchServer returns a test server over n items spread across 40
+// colors, the three shapes, and 25 categories
+func newBenchServer(b *testing.B, n int) *Server {
+	srv := newTestServer(b, testConfig(b))
+	items := make([]itemstore.Item, n)
+	for i := range items {
+		items[i] = itemstore.Item{
+			ID:       itemstore.IntID(i + 1),
+			Color:    fmt.Sprintf("color-%d", i%40),
+			Shape:    []string{"circle", "square", "triangle"}[i%3],
+			Category: fmt.Sprintf("c%d", i%25),
+		}
+	}
+	store, err := itemstore.New(items)
+	if err != nil {
+		b.Fatal(err)
+	}
+	srv.store = store
+	return srv
+}
+
+func BenchmarkGroupItems(b *testing.B) {
+	srv := newBenchServer(b, 20000)
+	items := srv.store.Filter(nil)
+	for _, property := range []string{"color", "category"} {
+		b.Run(property, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				srv.groupItems(b.Context(), items, property)
+			}
+		})
+	}
+}
+
+func BenchmarkItemsHandler(b *testing.B) {
+	srv := newBenchServer(b, 20000)
+	handler := srv.Handler()
+	for _, query := range []string{"groupBy=color", "groupBy=category&filter=shape:circle"} {
+		b.Run(query, func(b *testing.B) {
+			b.ReportAllocs()
+			req := httptest.NewRequest(http.MethodGet, "/items?"+query, nil)
+			for b.Loop() {
+				handler.ServeHTTP(&discardResponseWriter{header: http.Header{}}, req)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/palette"
+)
+
+const (
+	// defaultShapeColor fills icons requested without a color
+	defaultShapeColor = "#808080"
+	// defaultShapeSize, minShapeSize, and maxShapeSize bound the size
+	// parameter of /shapes/, in pixels
+	defaultShapeSize = 24
+	minShapeSize     = 8
+	maxShapeSize     = 512
+	// itemIconSize is the size of the icons inlined on the items page
+	itemIconSize = 40
+)
+
+// shapeMarkup is the drawing of each known shape on a 24×24 canvas. %[1]s is
+// the fill color.
+var shapeMarkup = map[string]string{
+	"square":   `<rect x="3" y="3" width="18" height="18" rx="2" fill="%[1]s"/>`,
+	"circle":   `<circle cx="12" cy="12" r="9" fill="%[1]s"/>`,
+	"triangle": `<polygon points="12,3 21,20 3,20" fill="%[1]s"/>`,
+}
+
+// placeholderMarkup is drawn for shapes without their own drawing
+const placeholderMarkup = `<rect x="4" y="4" width="16" height="16" rx="3" fill="none" stroke="%[1]s" stroke-width="2" stroke-dasharray="3 2"/>`
+
+// shapeSVG draws shape, or the shape it is an alias of, filled with fill.
+// A fill that is not a hex color is replaced by defaultShapeColor rather
+// than written into the markup, and the shape's display name only appears
+// escaped, in the title.
+func shapeSVG(shape, fill string, size int) string {
+	markup, ok := shapeMarkup[format.Shape(shape)]
+	if !ok {
+		markup = placeholderMarkup
+	}
+	fill, err := palette.ParseHex(fill)
+	if err != nil {
+		fill = defaultShapeColor
+	}
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 24 24" role="img"><title>%s</title>%s</svg>`,
+		size, size, template.HTMLEscapeString(format.ShapeName(shape)), fmt.Sprintf(markup, fill))
+}
+
+// shapeIcon inlines the icon of an item in its own color
+func (s *Server) shapeIcon(item itemstore.Item) template.HTML {
+	return template.HTML(shapeSVG(item.Shape, s.palette.Hex(item.Color), itemIconSize))
+}
+
+// shapeColor resolves the color parameter of /shapes/: a "#rgb" or "#rrggbb"
+// value, or a color name looked up in the palette. Anything else is refused,
+// so nothing but a hex value ever reaches the markup.
+func (s *Server) shapeColor(color string) (string, error) {
+	switch {
+	case color == "":
+		return defaultShapeColor, nil
+	case strings.HasPrefix(color, "#"):
+		hex, err := palette.ParseHex(color)
+		if err != nil {
+			return "", &httpError{status: http.StatusBadRequest, message: "invalid color: " + err.Error()}
+		}
+		return hex, nil
+	case strings.Trim(strings.ToLower(color), "abcdefghijklmnopqrstuvwxyz-") == "":
+		return s.palette.Hex(color), nil
+	}
+	return "", &httpError{status: http.StatusBadRequest, message: fmt.Sprintf("invalid color %q: use a color name or a #rrggbb value", color)}
+}
+
+// shapeHandler serves /shapes/{shape}.svg. The response depends only on the
+// URL, so it may be cached for a long time.
+func (s *Server) shapeHandler(w http.ResponseWriter, r *http.Request) {
+	shape, ok := strings.CutSuffix(r.PathValue("file"), ".svg")
+	if !ok || shape == "" {
+		s.notFoundHandler(w, r)
+		return
+	}
+	fill, err := s.shapeColor(r.URL.Query().Get("color"))
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	size := defaultShapeSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		size, err = strconv.Atoi(v)
+		if err != nil || size < minShapeSize || size > maxShapeSize {
+			s.respondError(w, r, &httpError{status: http.StatusBadRequest,
+				message: fmt.Sprintf("invalid size %q: must be between %d and %d", v, minShapeSize, maxShapeSize)})
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	fmt.Fprint(w, shapeSVG(shape, fill, size))
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/palette"
+)
+
+// svgDoc is the structure of a shape icon
+type svgDoc struct {
+	XMLName xml.Name `xml:"svg"`
+	Width   string   `xml:"width,attr"`
+	Title   string   `xml:"title"`
+	Shapes  []struct {
+		XMLName xml.Name
+		Fill    string `xml:"fill,attr"`
+		Stroke  string `xml:"stroke,attr"`
+	} `xml:",any"`
+}
+
+// getShape requests target from srv
+func getShape(srv *Server, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	srv.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestShapeHandler(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	tests := []struct {
+		target      string
+		wantElement string
+		wantFill    string
+		wantStroke  string
+		wantWidth   string
+	}{
+		{"/shapes/square.svg", "rect", defaultShapeColor, "", "24"},
+		{"/shapes/circle.svg?color=blue&size=64", "circle", "#0000ff", "", "64"},
+		{"/shapes/triangle.svg?color=%23F0a", "polygon", "#ff00aa", "", "24"},
+		{"/shapes/hexagon.svg?color=%23123456", "rect", "none", "#123456", "24"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			rec := getShape(srv, tt.target)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "image/svg+xml" {
+				t.Errorf("Content-Type = %q, want image/svg+xml", ct)
+			}
+			if cc := rec.Header().Get("Cache-Control"); !strings.Contains(cc, "max-age=31536000") {
+				t.Errorf("Cache-Control = %q, want a long max-age", cc)
+			}
+
+			var doc svgDoc
+			if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("body is not valid SVG: %v\n%s", err, rec.Body)
+			}
+			if doc.Width != tt.wantWidth {
+				t.Errorf("width = %q, want %q", doc.Width, tt.wantWidth)
+			}
+			if len(doc.Shapes) != 1 {
+				t.Fatalf("got %d shape elements, want 1", len(doc.Shapes))
+			}
+			shape := doc.Shapes[0]
+			if shape.XMLName.Local != tt.wantElement || shape.Fill != tt.wantFill || shape.Stroke != tt.wantStroke {
+				t.Errorf("shape = <%s fill=%q stroke=%q>, want <%s fill=%q stroke=%q>",
+					shape.XMLName.Local, shape.Fill, shape.Stroke, tt.wantElement, tt.wantFill, tt.wantStroke)
+			}
+		})
+	}
+}
+
+func TestShapeHandler_Invalid(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{`/shapes/square.svg?color=red"/><script>alert(1)</script>`, http.StatusBadRequest},
+		{"/shapes/square.svg?color=red%3Bstroke:url(x)", http.StatusBadRequest},
+		{"/shapes/square.svg?color=%23ff00", http.StatusBadRequest},
+		{"/shapes/square.svg?color=rgb(1,2,3)", http.StatusBadRequest},
+		{"/shapes/square.svg?size=4", http.StatusBadRequest},
+		{"/shapes/square.svg?size=big", http.StatusBadRequest},
+		{"/shapes/square.png", http.StatusNotFound},
+		{"/shapes/.svg", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rec := getShape(srv, tt.target); rec.Code != tt.want {
+			t.Errorf("GET %s status = %d, want %d", tt.target, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestShapeSVG_Alias(t *testing.T) {
+	if err := format.Shapes.RegisterShape("square", "", "box"); err != nil {
+		t.Fatal(err)
+	}
+	var doc svgDoc
+	if err := xml.Unmarshal([]byte(shapeSVG("Box", "#000000", 24)), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Shapes) != 1 || doc.Shapes[0].XMLName.Local != "rect" || doc.Title != "Square" {
+		t.Errorf("box icon = %+v, want the square titled Square", doc)
+	}
+}
+
+func TestShapeSVG_EscapesShape(t *testing.T) {
+	svg := shapeSVG(`<script>alert("x")</script>`, "#000000", 24)
+	if strings.Contains(svg, "<script>") {
+		t.Errorf("shape name was not escaped: %s", svg)
+	}
+	if err := xml.Unmarshal([]byte(svg), &svgDoc{}); err != nil {
+		t.Errorf("SVG with an escaped name is invalid: %v", err)
+	}
+}
+
+// FuzzShapeSVG checks that neither the shape nor the fill can change the
+// markup of an icon: the shape only reaches the title, escaped, and a fill
+// that is not a hex color is replaced
+func FuzzShapeSVG(f *testing.F) {
+	f.Add("circle", "#ff0000")
+	f.Add(`<script>alert("x")</script>`, "#000")
+	f.Add("square", `red"/><script>alert(1)</script>`)
+	f.Add("triangle", "url(javascript:alert(1))")
+	f.Add("\x00</title>", "#GGGGGG")
+	f.Fuzz(func(t *testing.T, shape, fill string) {
+		svg := shapeSVG(shape, fill, 24)
+		wantFill, err := palette.ParseHex(fill)
+		if err != nil {
+			wantFill = defaultShapeColor
+		}
+		markup, ok := shapeMarkup[format.Shape(shape)]
+		if !ok {
+			markup = placeholderMarkup
+		}
+		title, rest, ok := strings.Cut(strings.TrimPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="24" height="24" viewBox="0 0 24 24" role="img"><title>`), "</title>")
+		if !ok || strings.ContainsAny(title, `<>"'`) {
+			t.Fatalf("shapeSVG(%q, %q) = %q, want the name escaped in the title", shape, fill, svg)
+		}
+		if want := fmt.Sprintf(markup, wantFill) + "</svg>"; rest != want {
+			t.Fatalf("shapeSVG(%q, %q) drawing = %q, want %q", shape, fill, rest, want)
+		}
+	})
+}
+
+func TestShapeSVG_InvalidFill(t *testing.T) {
+	svg := shapeSVG("circle", `red"/><script>alert(1)</script>`, 24)
+	if !strings.Contains(svg, `fill="`+defaultShapeColor+`"`) || strings.Contains(svg, "<script>") {
+		t.Errorf("shapeSVG() with an invalid fill = %s, want the default color", svg)
+	}
+}
+
+func TestShapeIcon(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	icon := string(srv.shapeIcon(itemstore.Item{Color: "green", Shape: "triangle"}))
+	if !strings.Contains(icon, `<polygon points="12,3 21,20 3,20" fill="#008000"/>`) {
+		t.Errorf("shapeIcon() = %s, want a green triangle", icon)
+	}
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/png"
+	"math"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/qrcode"
+	"github.com/ElodinLaarz/dashboard/pkg/shortlink"
+)
+
+const (
+	// sharePath creates short links
+	sharePath = "/api/share"
+	// defaultQRSize and maxQRSize bound the width in pixels of short link
+	// QR codes; the upper bound keeps one request from encoding a huge image
+	defaultQRSize = 256
+	maxQRSize     = 2048
+	// shareRate and shareBurst limit how fast one client may create or
+	// follow short links, which keeps tokens from being enumerated
+	shareRate  = 1
+	shareBurst = 30
+)
+
+// shareRequest is the body of POST /api/share
+type shareRequest struct {
+	// Query is the /items query string to share, with or without the
+	// leading "?"
+	Query string `json:"query"`
+}
+
+// shareResponse describes a created short link
+type shareResponse struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// newShareStore returns the short link store. Links are saved alongside the
+// --data file when there is one and kept in memory otherwise.
+func newShareStore(cfg config.Config) (*shortlink.Store, error) {
+	opts := []shortlink.Option{shortlink.WithTTL(cfg.ShareTTL)}
+	if cfg.DataFile != "" {
+		opts = append(opts, shortlink.WithFile(sharesFile(cfg.DataFile)))
+	}
+	return shortlink.New(opts...)
+}
+
+// sharesFile returns where short links are saved for dataFile, e.g.
+// items.shares.json for items.json
+func sharesFile(dataFile string) string {
+	return strings.TrimSuffix(dataFile, filepath.Ext(dataFile)) + ".shares.json"
+}
+
+// apiShareHandler stores the view parameters of an /items query under a new
+// short link. Only the grouping, filters, and sort are kept.
+func (s *Server) apiShareHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.allowShare(w, r) {
+		return
+	}
+	var req shareRequest
+	if err := decodeJSONBody(w, r, &req, s.cfg.MaxBodyBytes); err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	query, err := url.ParseQuery(strings.TrimPrefix(req.Query, "?"))
+	if err != nil {
+		s.respondError(w, r, &httpError{status: http.StatusBadRequest, message: "query is not a valid query string"})
+		return
+	}
+	if _, err := parseItemsQuery(query, itemsPageParams, true); err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+
+	// Links to the default store keep only the query, which is how they have
+	// always been saved; links into a collection keep its items path too
+	target := viewState(query).Encode()
+	if requestCollection(r) != nil {
+		target = scopedPath(r, "/items") + "?" + target
+	}
+	link, err := s.shares.Create(target)
+	if errors.Is(err, shortlink.ErrFull) {
+		s.respondError(w, r, &httpError{status: http.StatusServiceUnavailable, message: "too many short links; try again later"})
+		return
+	}
+	if err != nil {
+		s.respondError(w, r, fmt.Errorf("create short link: %w", err))
+		return
+	}
+
+	path := "/s/" + link.Token
+	w.Header().Set("Location", s.url(path))
+	s.writeJSON(w, r, http.StatusCreated, shareResponse{
+		Token:     link.Token,
+		URL:       s.externalURL(r, path),
+		ExpiresAt: link.ExpiresAt,
+	})
+}
+
+// shortLinkHandler redirects a short link to the view it stands for.
+// Unknown and expired tokens get the 404 page.
+func (s *Server) shortLinkHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.allowShare(w, r) {
+		return
+	}
+	target, ok := s.shares.Resolve(r.PathValue("token"))
+	if !ok {
+		s.notFoundHandler(w, r)
+		return
+	}
+	var location string
+	switch {
+	case strings.HasPrefix(target, "/"):
+		location = s.url(target)
+	case target != "":
+		location = s.url("/items") + "?" + target
+	default:
+		location = s.url("/items")
+	}
+	http.Redirect(w, r, location, http.StatusFound)
+}
+
+// shortLinkQRHandler serves a PNG QR code of a short link's absolute URL,
+// for showing a view on a screen so people can open it on their phones.
+// ?size= sets the width in pixels. The code of a token never changes, so it
+// may be cached.
+func (s *Server) shortLinkQRHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.allowShare(w, r) {
+		return
+	}
+	if err := checkQueryParams(r.URL.Query(), []string{"size"}); err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	size := defaultQRSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		var err error
+		size, err = strconv.Atoi(v)
+		if err != nil || size < 1 || size > maxQRSize {
+			s.respondError(w, r, &httpError{status: http.StatusBadRequest,
+				message: fmt.Sprintf("invalid size %q: must be between 1 and %d", v, maxQRSize)})
+			return
+		}
+	}
+	token := r.PathValue("token")
+	if _, ok := s.shares.Resolve(token); !ok {
+		s.notFoundHandler(w, r)
+		return
+	}
+
+	code, err := qrcode.Encode([]byte(s.externalURL(r, "/s/"+token)))
+	if err != nil {
+		s.respondError(w, r, fmt.Errorf("encode QR code: %w", err))
+		return
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, code.Image(size)); err != nil {
+		s.respondError(w, r, fmt.Errorf("encode QR code PNG: %w", err))
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		s.logger.WarnContext(r.Context(), "failed to write QR code", "error", err, "request_id", RequestIDFromContext(r.Context()))
+	}
+}
+
+// allowShare applies the short link rate limit. When the client is over it,
+// the 429 response has already been written.
+func (s *Server) allowShare(w http.ResponseWriter, r *http.Request) bool {
+	ok, wait := s.shareLimiter.Allow(s.clientIP(r))
+	if !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		s.renderError(w, r, http.StatusTooManyRequests, "too many short link requests; try again later")
+	}
+	return ok
+}
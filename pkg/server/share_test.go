@@ -0,0 +1,242 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/qrcode"
+	"github.com/ElodinLaarz/dashboard/pkg/ratelimit"
+	"github.com/ElodinLaarz/dashboard/pkg/shortlink"
+)
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// share posts query to /api/share
+func share(t *testing.T, srv *Server, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(shareRequest{Query: query})
+	req := httptest.NewRequest(http.MethodPost, srv.url(sharePath), strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShare(t *testing.T) {
+	tests := []struct {
+		name         string
+		basePath     string
+		query        string
+		wantLocation string
+	}{
+		{
+			name:         "view parameters",
+			query:        "?groupBy=color&sortBy=id&order=desc",
+			wantLocation: "/items?groupBy=color&order=desc&sortBy=id",
+		},
+		{
+			name:         "escaped values",
+			query:        "filter=category%3AA+%26+B&filter=shape:circle%2Fsquare",
+			wantLocation: "/items?filter=category%3AA+%26+B&filter=shape%3Acircle%2Fsquare",
+		},
+		{
+			name:         "confirmations are dropped",
+			query:        "groupBy=none&added=4&reset=1",
+			wantLocation: "/items?groupBy=none",
+		},
+		{
+			name:         "no parameters",
+			wantLocation: "/items",
+		},
+		{
+			name:         "base path",
+			basePath:     "/dash",
+			query:        "groupBy=shape",
+			wantLocation: "/dash/items?groupBy=shape",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.BasePath = tt.basePath
+			// Creating a short link needs no key even when keys are set
+			cfg.APIKeys = []string{"secret"}
+			srv := newTestServer(t, cfg)
+
+			rec := share(t, srv, tt.query)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body)
+			}
+			var resp shareResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			path := tt.basePath + "/s/" + resp.Token
+			if resp.URL != "http://example.com"+path || rec.Header().Get("Location") != path {
+				t.Errorf("url = %q, Location = %q, want %s", resp.URL, rec.Header().Get("Location"), path)
+			}
+			if resp.ExpiresAt.IsZero() {
+				t.Error("expiresAt is missing")
+			}
+
+			rec = httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			if rec.Code != http.StatusFound {
+				t.Fatalf("GET %s status = %d, want 302", path, rec.Code)
+			}
+			if loc := rec.Header().Get("Location"); loc != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", loc, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestShortLinkQR(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.BasePath = "/dash"
+	cfg.TrustProxyHeaders = true
+	srv := newTestServer(t, cfg)
+	link, err := srv.shares.Create("groupBy=color")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Host = "dash.example"
+		req.Header.Set("X-Forwarded-Proto", "https")
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+	path := "/dash/s/" + link.Token + "/qr.png"
+	for _, tt := range []struct {
+		query string
+		size  int
+	}{{"", defaultQRSize}, {"?size=100", 100}} {
+		rec := get(path + tt.query)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" || !strings.Contains(rec.Header().Get("Cache-Control"), "max-age=") {
+			t.Fatalf("GET %s = %d %v", path+tt.query, rec.Code, rec.Header())
+		}
+		img, err := png.Decode(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b.Dx() != tt.size || b.Dy() != tt.size {
+			t.Errorf("GET %s image is %v, want %d pixels square", path+tt.query, b, tt.size)
+		}
+
+		// Read the modules back out of the image and compare them with the
+		// code of the link's absolute URL, which pkg/qrcode tests decode
+		want, err := qrcode.Encode([]byte("https://dash.example/dash/s/" + link.Token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		modules := want.Size + 2*qrcode.QuietZone
+		scale := tt.size / modules
+		offset := (tt.size-scale*modules)/2 + qrcode.QuietZone*scale
+		for y := range want.Size {
+			for x := range want.Size {
+				r, _, _, _ := img.At(offset+x*scale+scale/2, offset+y*scale+scale/2).RGBA()
+				if dark := r == 0; dark != want.Dark(x, y) {
+					t.Fatalf("module (%d, %d) dark = %v, want %v", x, y, dark, want.Dark(x, y))
+				}
+			}
+		}
+	}
+
+	for target, status := range map[string]int{
+		"/dash/s/unknown/qr.png":                    http.StatusNotFound,
+		path + "?size=0":                            http.StatusBadRequest,
+		path + fmt.Sprintf("?size=%d", maxQRSize+1): http.StatusBadRequest,
+		path + "?scale=2":                           http.StatusBadRequest,
+	} {
+		if rec := get(target); rec.Code != status {
+			t.Errorf("GET %s status = %d, want %d", target, rec.Code, status)
+		}
+	}
+}
+
+func TestShare_InvalidQuery(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	for _, query := range []string{"sortBy=weight", "colour=red", "filter=%zz"} {
+		if rec := share(t, srv, query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+	if srv.shares.Len() != 0 {
+		t.Errorf("%d links were created for invalid queries", srv.shares.Len())
+	}
+}
+
+func TestShortLink_Expiry(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	shares, err := shortlink.New(shortlink.WithClock(clock.Now), shortlink.WithTTL(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.shares = shares
+	link, _ := shares.Create("groupBy=color")
+
+	follow := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", "text/html")
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	clock.Advance(59 * time.Minute)
+	if rec := follow("/s/" + link.Token); rec.Code != http.StatusFound {
+		t.Errorf("status before expiry = %d, want 302", rec.Code)
+	}
+	clock.Advance(time.Minute)
+	for _, path := range []string{"/s/" + link.Token, "/s/unknown"} {
+		rec := follow(path)
+		if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "Page Not Found") {
+			t.Errorf("GET %s = %d, want the 404 page", path, rec.Code)
+		}
+	}
+}
+
+func TestShortLink_RateLimit(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	srv.shareLimiter = ratelimit.New(1, 2)
+
+	for i := range 2 {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/s/guess"+string(rune('a'+i)), nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("request %d status = %d, want 404", i+1, rec.Code)
+		}
+	}
+	if rec := share(t, srv, "groupBy=color"); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("status = %d, want 429 with Retry-After", rec.Code)
+	}
+}
+
+func TestSharesFile(t *testing.T) {
+	for dataFile, want := range map[string]string{
+		"items.json":         "items.shares.json",
+		"/srv/data/all.json": "/srv/data/all.shares.json",
+		"items":              "items.shares.json",
+	} {
+		if got := sharesFile(dataFile); got != want {
+			t.Errorf("sharesFile(%q) = %q, want %q", dataFile, got, want)
+		}
+	}
+}
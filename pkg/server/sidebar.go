@@ -0,0 +1,49 @@
+package server
+
+import (
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// sidebarProperties are the item properties listed in the sidebar, in
+// display order
+var sidebarProperties = []string{"color", "shape", "category"}
+
+// sidebarSection lists the values of one property
+type sidebarSection struct {
+	Property string
+	Entries  []sidebarEntry
+}
+
+// sidebarEntry is one filterable value. Link is the current page with the
+// value's filter applied, and Active marks the value being filtered on.
+type sidebarEntry struct {
+	Value  string
+	Count  int
+	Link   string
+	Active bool
+}
+
+// sidebarSections builds the sidebar for a request to /items with the given
+// query and parsed filters. Values are sorted and counted across the whole
+// store the request addresses, in one pass over its items.
+func (s *Server) sidebarSections(r *http.Request, query url.Values, filters map[string]string) []sidebarSection {
+	sections := make([]sidebarSection, 0, len(sidebarProperties))
+	allCounts := s.storeFor(r).CountsBy(sidebarProperties...)
+	for _, prop := range sidebarProperties {
+		counts := allCounts[prop]
+		section := sidebarSection{Property: prop, Entries: make([]sidebarEntry, 0, len(counts))}
+		for _, value := range slices.Sorted(maps.Keys(counts)) {
+			section.Entries = append(section.Entries, sidebarEntry{
+				Value:  value,
+				Count:  counts[value],
+				Link:   s.itemsLink(r, query, withFilter(prop, value)),
+				Active: filters[prop] == value,
+			})
+		}
+		sections = append(sections, section)
+	}
+	return sections
+}
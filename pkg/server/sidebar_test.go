@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSidebarSections(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	if _, err := srv.store.Add(sampleItems[3]); err != nil { // a second red item
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		rawQuery   string
+		wantActive map[string]string
+	}{
+		{rawQuery: "", wantActive: map[string]string{}},
+		{rawQuery: "filter=color:red", wantActive: map[string]string{"color": "red"}},
+		{rawQuery: "filter=color:red&filter=category:B", wantActive: map[string]string{"color": "red", "category": "B"}},
+		{rawQuery: "filterBy=shape&filterValue=square", wantActive: map[string]string{"shape": "square"}},
+	}
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.rawQuery)
+		sections := srv.sidebarSections(itemsRequest, query, parseFilters(query))
+
+		if len(sections) != len(sidebarProperties) {
+			t.Fatalf("%q: %d sections, want %d", tt.rawQuery, len(sections), len(sidebarProperties))
+		}
+		for _, section := range sections {
+			for _, e := range section.Entries {
+				if wantActive := tt.wantActive[section.Property] == e.Value; e.Active != wantActive {
+					t.Errorf("%q: %s=%s active = %v, want %v", tt.rawQuery, section.Property, e.Value, e.Active, wantActive)
+				}
+				if !strings.HasPrefix(e.Link, "/items?") {
+					t.Errorf("%q: link %q is not an /items link", tt.rawQuery, e.Link)
+				}
+			}
+		}
+	}
+
+	colors := srv.sidebarSections(itemsRequest, url.Values{}, nil)[0]
+	if colors.Property != "color" || len(colors.Entries) != 3 {
+		t.Fatalf("color section = %+v, want three colors", colors)
+	}
+	if red := colors.Entries[2]; red.Value != "red" || red.Count != 2 {
+		t.Errorf("red entry = %+v, want a count of 2", red)
+	}
+}
+
+func TestItemsPage_SidebarCounts(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.BasePath = "/dash"
+	srv := newTestServer(t, cfg)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dash/items?filter=shape:circle", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`href="/dash/items?filter=shape%3Acircle&amp;filter=color%3Agreen&amp;groupBy=shape"`,
+		`class="category-item active" href="/dash/items?filter=shape%3Acircle&amp;groupBy=shape"`,
+		`<span class="item-count">1</span>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("items page does not contain %s", want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// viewStateCookie remembers the grouping, filters, and sort last chosen
+	// on /items
+	viewStateCookie = "view_state"
+	// viewStateVersion prefixes every cookie value. A cookie with any other
+	// version is ignored, so the format can change without breaking old
+	// cookies.
+	viewStateVersion = "v1"
+	// maxViewStateBytes bounds the cookie value; larger states are not saved
+	maxViewStateBytes = 2048
+	// viewStateMaxAge is how long a saved state is kept
+	viewStateMaxAge = 30 * 24 * time.Hour
+)
+
+// viewStateParams are the /items parameters saved in the view state cookie
+var viewStateParams = []string{"groupBy", "filter", "filterBy", "filterValue", "sortBy", "order"}
+
+// newStateKey returns the key that signs view state cookies: the configured
+// secret, or a random key when none is set, which invalidates saved states on
+// restart
+func newStateKey(secret string) ([]byte, error) {
+	if secret != "" {
+		return []byte(secret), nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// restoreViewState returns the query /items renders: the request's own, or
+// for a request without view parameters, the request's merged with the
+// state saved in the view state cookie, in which case restored is true.
+// ?reset=1 clears the saved state instead.
+func (s *Server) restoreViewState(w http.ResponseWriter, r *http.Request) (query url.Values, restored bool) {
+	query = r.URL.Query()
+	if query.Has("reset") {
+		s.clearViewState(w, r)
+		return query, false
+	}
+	if len(viewState(query)) > 0 {
+		return query, false
+	}
+
+	c, err := r.Cookie(viewStateCookie)
+	if err != nil {
+		return query, false
+	}
+	saved, ok := s.decodeViewState(c.Value)
+	if !ok {
+		return query, false
+	}
+	for key, values := range saved {
+		query[key] = values
+	}
+	return query, true
+}
+
+// saveViewState stores the view parameters of query in the view state
+// cookie. Nothing is saved for a query without any, and a state too large
+// for the cookie clears it instead.
+func (s *Server) saveViewState(w http.ResponseWriter, r *http.Request, query url.Values) {
+	state := viewState(query)
+	if len(state) == 0 {
+		return
+	}
+	value := s.encodeViewState(state)
+	if len(value) > maxViewStateBytes {
+		s.clearViewState(w, r)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     viewStateCookie,
+		Value:    value,
+		Path:     s.scopedURL(r, "/items"),
+		MaxAge:   int(viewStateMaxAge / time.Second),
+		HttpOnly: true,
+		Secure:   s.requestScheme(r) == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clearViewState deletes the view state cookie
+func (s *Server) clearViewState(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:   viewStateCookie,
+		Path:   s.scopedURL(r, "/items"),
+		MaxAge: -1,
+	})
+}
+
+// viewState returns the view parameters of query
+func viewState(query url.Values) url.Values {
+	state := make(url.Values)
+	for key, values := range query {
+		if slices.Contains(viewStateParams, key) {
+			state[key] = values
+		}
+	}
+	return state
+}
+
+// encodeViewState returns the cookie value for state: the version, the
+// encoded parameters, and an HMAC of both
+func (s *Server) encodeViewState(state url.Values) string {
+	payload := viewStateVersion + "." + base64.RawURLEncoding.EncodeToString([]byte(state.Encode()))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.signViewState(payload))
+}
+
+// decodeViewState returns the state in a cookie value, or false when the
+// value is malformed, of another version, or not signed by this server
+func (s *Server) decodeViewState(value string) (url.Values, bool) {
+	if len(value) > maxViewStateBytes {
+		return nil, false
+	}
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return nil, false
+	}
+	payload, sig := value[:i], value[i+1:]
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.signViewState(payload)) {
+		return nil, false
+	}
+
+	version, encoded, ok := strings.Cut(payload, ".")
+	if !ok || version != viewStateVersion {
+		return nil, false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false
+	}
+	query, err := url.ParseQuery(string(raw))
+	if err != nil {
+		return nil, false
+	}
+	return viewState(query), true
+}
+
+// signViewState returns the HMAC-SHA256 of payload under the server's state
+// key
+func (s *Server) signViewState(payload string) []byte {
+	mac := hmac.New(sha256.New, s.stateKey)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
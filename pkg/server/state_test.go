@@ -0,0 +1,159 @@
+package server
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// getItems requests target from srv with the given cookies
+func getItems(t *testing.T, srv *Server, target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+// viewStateFrom returns the view state cookie set by rec, or nil
+func viewStateFrom(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == viewStateCookie {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestViewState_RoundTrip(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.StateSecret = "test secret"
+	srv := newTestServer(t, cfg)
+
+	rec := getItems(t, srv, "/items?groupBy=color&filter=category:A&sortBy=id&order=desc&strict=0")
+	saved := viewStateFrom(rec)
+	if saved == nil || saved.MaxAge <= 0 || !saved.HttpOnly || saved.Path != "/items" {
+		t.Fatalf("view state cookie = %+v, want a saved state", saved)
+	}
+	if state, ok := srv.decodeViewState(saved.Value); !ok ||
+		state.Encode() != "filter=category%3AA&groupBy=color&order=desc&sortBy=id" {
+		t.Errorf("saved state = %v, %v", state, ok)
+	}
+
+	// A bare visit shows the saved view without saving it again
+	rec = getItems(t, srv, "/items", saved)
+	body := rec.Body.String()
+	if !strings.Contains(body, `data-group="blue"`) || strings.Contains(body, `data-group="green"`) {
+		t.Error("bare visit did not restore the grouping and filter")
+	}
+	if viewStateFrom(rec) != nil {
+		t.Error("restored state was saved again")
+	}
+
+	// So does a visit carrying only a form confirmation
+	rec = getItems(t, srv, "/items?added=2", saved)
+	if body := rec.Body.String(); !strings.Contains(body, `data-group="blue"`) || !strings.Contains(body, "Item #2 added.") {
+		t.Error("confirmation visit did not restore the view")
+	}
+
+	// A new server with the same secret accepts the cookie
+	other := newTestServer(t, cfg)
+	if body := getItems(t, other, "/items", saved).Body.String(); !strings.Contains(body, `data-group="blue"`) {
+		t.Error("server with the same secret did not restore the view")
+	}
+
+	// Explicit parameters win and replace the saved state
+	rec = getItems(t, srv, "/items?groupBy=shape", saved)
+	if next := viewStateFrom(rec); next == nil || next.Value == saved.Value {
+		t.Error("explicit parameters did not replace the saved state")
+	}
+}
+
+func TestViewState_Tampered(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.StateSecret = "test secret"
+	srv := newTestServer(t, cfg)
+	value := viewStateFrom(getItems(t, srv, "/items?groupBy=color")).Value
+	version, rest, _ := strings.Cut(value, ".")
+	_, sig, _ := strings.Cut(rest, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte("groupBy=category"))
+	otherCfg := cfg
+	otherCfg.StateSecret = "another secret"
+	otherKey := viewStateFrom(getItems(t, newTestServer(t, otherCfg), "/items?groupBy=category")).Value
+
+	tests := map[string]string{
+		"payload swapped":       version + "." + forged + "." + sig,
+		"signature dropped":     version + "." + forged,
+		"signature corrupted":   value[:len(value)-2] + "AA",
+		"signed by another key": otherKey,
+		"unknown version":       "v2" + strings.TrimPrefix(value, version),
+		"not a state":           "groupBy=category",
+		"oversized":             strings.Repeat("a", maxViewStateBytes+1),
+	}
+	for name, cookie := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, ok := srv.decodeViewState(cookie); ok {
+				t.Fatal("decodeViewState() accepted the cookie")
+			}
+			rec := getItems(t, srv, "/items", &http.Cookie{Name: viewStateCookie, Value: cookie})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			if body := rec.Body.String(); strings.Contains(body, `data-group="A"`) || !strings.Contains(body, `data-group="circle"`) {
+				t.Error("tampered state was applied")
+			}
+		})
+	}
+}
+
+func TestViewState_Reset(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	saved := viewStateFrom(getItems(t, srv, "/items?groupBy=color"))
+
+	rec := getItems(t, srv, "/items?reset=1", saved)
+	cleared := viewStateFrom(rec)
+	if cleared == nil || cleared.MaxAge >= 0 {
+		t.Fatalf("view state cookie = %+v, want it deleted", cleared)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `data-group="circle"`) {
+		t.Error("reset did not show the default grouping")
+	}
+	if strings.Contains(body, "reset=1") {
+		t.Error("links carry the reset parameter forward")
+	}
+}
+
+func TestViewState_NotSaved(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	// Invalid views are rejected, not remembered
+	rec := getItems(t, srv, "/items?sortBy=bogus")
+	if rec.Code != http.StatusBadRequest || viewStateFrom(rec) != nil {
+		t.Errorf("status = %d, cookie = %v; want 400 and no cookie", rec.Code, viewStateFrom(rec))
+	}
+
+	// A view too large for the cookie is not saved
+	query := url.Values{"filter": {"category:" + strings.Repeat("x", maxViewStateBytes)}}
+	rec = getItems(t, srv, "/items?"+query.Encode())
+	if c := viewStateFrom(rec); c == nil || c.MaxAge >= 0 {
+		t.Errorf("view state cookie = %+v, want it deleted", c)
+	}
+
+	// A saved view that no longer parses is dropped
+	bad := &http.Cookie{Name: viewStateCookie, Value: srv.encodeViewState(url.Values{"sortBy": {"bogus"}})}
+	rec = getItems(t, srv, "/items", bad)
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", rec.Code)
+	}
+	if c := viewStateFrom(rec); c == nil || c.MaxAge >= 0 {
+		t.Errorf("view state cookie = %+v, want it deleted", c)
+	}
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// fingerprintLen is the number of hex digits of the content hash put in
+	// fingerprinted names
+	fingerprintLen = 8
+	// immutableCacheControl is sent with fingerprinted files, whose content
+	// never changes under the same name
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	// revalidateCacheControl is sent with files requested by their plain
+	// name, which change with every release
+	revalidateCacheControl = "no-cache"
+)
+
+// staticSource serves the static files below /static/, with the prefix
+// stripped, and names the paths pages link to them by
+type staticSource interface {
+	http.Handler
+	// assetPath returns the path below /static/ that the file name is
+	// linked by, or false when there is no such file
+	assetPath(name string) (string, bool)
+}
+
+// newStaticSource returns the static files, the embedded ones unless
+// WithStatic replaced them, fingerprinted and compressed once or, in dev
+// mode, read on every request under their plain names
+func (s *Server) newStaticSource(dev bool) (staticSource, error) {
+	if dev {
+		return diskStatic{fsys: s.staticFS}, nil
+	}
+	return newAssetManifest(s.staticFS)
+}
+
+// diskStatic is the --dev source: files read from fsys on every request and
+// never cached, so edits show up on the next reload. Pages link to the plain
+// names.
+type diskStatic struct {
+	fsys fs.FS
+}
+
+func (d diskStatic) assetPath(name string) (string, bool) {
+	info, err := fs.Stat(d.fsys, name)
+	return name, err == nil && !info.IsDir()
+}
+
+func (d diskStatic) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", revalidateCacheControl)
+	http.ServeFileFS(w, r, d.fsys, r.URL.Path)
+}
+
+// assetManifest is the production source. It maps the static files to
+// fingerprinted names, which carry a hash of the content: "app.css" is also
+// served as "app.1a2b3c4d.css". Pages link to the fingerprinted names, so
+// browsers can cache them for good and still fetch a file again once it
+// changes.
+type assetManifest struct {
+	fsys fs.FS
+	// hashes are the content hashes of the files, by name
+	hashes map[string]string
+	// files maps each fingerprinted name back to the file's name
+	files map[string]string
+	// gzipped holds the files compressed once at startup, for the files
+	// that compression makes smaller
+	gzipped map[string][]byte
+}
+
+// newAssetManifest hashes every file in fsys and compresses it. The hashes
+// are of the uncompressed content, so every encoding of a file shares its
+// fingerprinted name.
+func newAssetManifest(fsys fs.FS) (*assetManifest, error) {
+	m := &assetManifest{
+		fsys:    fsys,
+		hashes:  make(map[string]string),
+		files:   make(map[string]string),
+		gzipped: make(map[string][]byte),
+	}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		m.hashes[name] = hex.EncodeToString(sum[:])[:fingerprintLen]
+		m.files[fingerprintName(name, m.hashes[name])] = name
+		gz, err := gzipBytes(data)
+		if err != nil {
+			return err
+		}
+		if len(gz) < len(data) {
+			m.gzipped[name] = gz
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint static files: %w", err)
+	}
+	return m, nil
+}
+
+// gzipBytes compresses data as tightly as gzip can
+func gzipBytes(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&b, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// fingerprintName puts hash in front of the extension of name, e.g.
+// "js/htmx.min.js" becomes "js/htmx.min.1a2b3c4d.js"
+func fingerprintName(name, hash string) string {
+	ext := path.Ext(path.Base(name))
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// assetPath returns the fingerprinted name of the static file name
+func (m *assetManifest) assetPath(name string) (string, bool) {
+	hash, ok := m.hashes[name]
+	if !ok {
+		return "", false
+	}
+	return fingerprintName(name, hash), true
+}
+
+// assetURL returns the URL pages link the static file name by, its
+// fingerprinted name outside dev mode: {{asset "htmx.min.js"}}. An unknown
+// name fails the template, so SelfCheck reports it before the server starts.
+func (s *Server) assetURL(name string) (string, error) {
+	p, ok := s.static.assetPath(name)
+	if !ok {
+		return "", fmt.Errorf("asset: no static file %q", name)
+	}
+	return s.url("/static/" + p), nil
+}
+
+// ServeHTTP serves the static files. Fingerprinted names are cached for a
+// year; plain names must be revalidated, against an ETag made from the
+// content hash. Clients that accept gzip get the compressed copy, and the
+// others the file as it is.
+func (m *assetManifest) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, cacheControl, etag := r.URL.Path, revalidateCacheControl, ""
+	if file, ok := m.files[name]; ok {
+		name, cacheControl = file, immutableCacheControl
+	} else if hash, ok := m.hashes[name]; ok {
+		etag = hash
+	}
+	h := w.Header()
+	h.Set("Cache-Control", cacheControl)
+
+	gz, compressed := m.gzipped[name]
+	if compressed {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	// Byte ranges are served from the file as it is, so a range always
+	// means the same bytes
+	if compressed && r.Header.Get("Range") == "" && acceptsEncoding(r.Header.Get("Accept-Encoding"), "gzip") {
+		h.Set("Content-Encoding", "gzip")
+		// ServeContent leaves the length out of encoded responses
+		h.Set("Content-Length", strconv.Itoa(len(gz)))
+		if etag != "" {
+			// Each encoding is a different representation
+			h.Set("ETag", `"`+etag+`-gzip"`)
+		}
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(gz))
+		return
+	}
+	if etag != "" {
+		h.Set("ETag", `"`+etag+`"`)
+	}
+	http.ServeFileFS(w, r, m.fsys, name)
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows coding,
+// named or through "*", with a q-value above zero
+func acceptsEncoding(header, coding string) bool {
+	accepted := false
+	for part := range strings.SplitSeq(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != coding && name != "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if name == coding {
+			// The coding's own entry overrides the wildcard
+			return q > 0
+		}
+		accepted = q > 0
+	}
+	return accepted
+}
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// contentHash returns the fingerprint of data
+func contentHash(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])[:fingerprintLen]
+}
+
+func TestAssetManifest(t *testing.T) {
+	m, err := newAssetManifest(fstest.MapFS{
+		"app.css":        {Data: []byte("body { margin: 0 }")},
+		"js/htmx.min.js": {Data: []byte("htmx()")},
+		"LICENSE":        {Data: []byte("MIT")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ name, want string }{
+		{"app.css", "app." + contentHash("body { margin: 0 }") + ".css"},
+		{"js/htmx.min.js", "js/htmx.min." + contentHash("htmx()") + ".js"},
+		{"LICENSE", "LICENSE." + contentHash("MIT")},
+	}
+	for _, tt := range tests {
+		got, ok := m.assetPath(tt.name)
+		if !ok || got != tt.want {
+			t.Errorf("assetPath(%q) = %q, %v; want %q", tt.name, got, ok, tt.want)
+		}
+		if m.files[tt.want] != tt.name {
+			t.Errorf("files[%q] = %q, want %q", tt.want, m.files[tt.want], tt.name)
+		}
+	}
+	if got, ok := m.assetPath("missing.css"); ok {
+		t.Errorf("assetPath(missing.css) = %q, want no file", got)
+	}
+	// Compression would only make the short files longer
+	if len(m.gzipped) != 0 {
+		t.Errorf("gzipped = %v, want no copies of files gzip does not shrink", slices.Collect(maps.Keys(m.gzipped)))
+	}
+}
+
+func TestStaticHandler(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	data, err := os.ReadFile("static/htmx.min.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := contentHash(string(data))
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		maps.Copy(req.Header, header)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/static/htmx.min."+hash+".js", nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != string(data) {
+		t.Fatalf("fingerprinted GET = %d with %d bytes, want the file", rec.Code, rec.Body.Len())
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != immutableCacheControl {
+		t.Errorf("fingerprinted Cache-Control = %q, want %q", cc, immutableCacheControl)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") {
+		t.Errorf("fingerprinted Content-Type = %q, want JavaScript", ct)
+	}
+
+	rec = get("/static/htmx.min.js", nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != string(data) {
+		t.Fatalf("plain GET = %d with %d bytes, want the file", rec.Code, rec.Body.Len())
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != revalidateCacheControl {
+		t.Errorf("plain Cache-Control = %q, want %q", cc, revalidateCacheControl)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag != `"`+hash+`"` {
+		t.Errorf("plain ETag = %q, want the content hash", etag)
+	}
+	if rec := get("/static/htmx.min.js", http.Header{"If-None-Match": {etag}}); rec.Code != http.StatusNotModified {
+		t.Errorf("revalidating GET status = %d, want 304", rec.Code)
+	}
+
+	// A stale fingerprint is not served as the current file
+	if rec := get("/static/htmx.min.00000000.js", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("stale fingerprint status = %d, want 404", rec.Code)
+	}
+}
+
+func TestStaticHandler_Gzip(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	data, err := os.ReadFile("static/tests/filtering.test.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := contentHash(string(data))
+
+	tests := []struct {
+		acceptEncoding string
+		gzip           bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"gzip, deflate, br", true},
+		{"br, GZIP;q=0.5", true},
+		{"gzip;q=0", false},
+		{"*", true},
+		{"*, gzip;q=0", false},
+		{"deflate, identity", false},
+	}
+	for _, path := range []string{"/static/tests/filtering.test.js", "/static/tests/filtering.test." + hash + ".js"} {
+		for _, tt := range tests {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, req)
+
+			name := fmt.Sprintf("GET %s with Accept-Encoding %q", path, tt.acceptEncoding)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s status = %d", name, rec.Code)
+			}
+			if vary := rec.Header().Values("Vary"); !slices.Contains(vary, "Accept-Encoding") {
+				t.Errorf("%s Vary = %v, want Accept-Encoding", name, vary)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") {
+				t.Errorf("%s Content-Type = %q, want that of the file", name, ct)
+			}
+			if cl := rec.Header().Get("Content-Length"); cl != strconv.Itoa(rec.Body.Len()) {
+				t.Errorf("%s Content-Length = %q, want %d", name, cl, rec.Body.Len())
+			}
+			body := rec.Body.Bytes()
+			if !tt.gzip {
+				if ce := rec.Header().Get("Content-Encoding"); ce != "" || !bytes.Equal(body, data) {
+					t.Errorf("%s = %q encoding, %d bytes; want the file as it is", name, ce, len(body))
+				}
+				continue
+			}
+			if ce := rec.Header().Get("Content-Encoding"); ce != "gzip" || len(body) >= len(data) {
+				t.Errorf("%s = %q encoding, %d bytes; want gzip shorter than %d", name, ce, len(body), len(data))
+				continue
+			}
+			zr, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := io.ReadAll(zr); err != nil || !bytes.Equal(got, data) {
+				t.Errorf("%s decompresses to %d bytes, %v; want the file", name, len(got), err)
+			}
+		}
+	}
+
+	// The plain name's representations have their own ETags
+	etags := map[string]bool{}
+	for _, acceptEncoding := range []string{"", "gzip"} {
+		req := httptest.NewRequest(http.MethodGet, "/static/tests/filtering.test.js", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		etags[rec.Header().Get("ETag")] = true
+
+		req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+		rec = httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("revalidating with Accept-Encoding %q status = %d, want 304", acceptEncoding, rec.Code)
+		}
+	}
+	if len(etags) != 2 {
+		t.Errorf("ETags = %v, want one per encoding", etags)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/static/tests/filtering.test.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-9")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent || rec.Header().Get("Content-Encoding") != "" || !bytes.Equal(rec.Body.Bytes(), data[:10]) {
+		t.Errorf("range request = %d %q, want the first bytes of the file as it is", rec.Code, rec.Body)
+	}
+}
+
+func TestAssetURL(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.BasePath = "/dash"
+	srv := newTestServer(t, cfg)
+	data, err := os.ReadFile("static/htmx.min.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := template.Must(template.New("").Funcs(srv.templateFuncs(defaultLanguage)).Parse(`<script src="{{asset .}}"></script>`))
+	var b strings.Builder
+	if err := tmpl.Execute(&b, "htmx.min.js"); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := `<script src="/dash/static/htmx.min.` + contentHash(string(data)) + `.js"></script>`; b.String() != want {
+		t.Errorf("asset = %s, want %s", b.String(), want)
+	}
+	if err := tmpl.Execute(&b, "missing.css"); err == nil || !strings.Contains(err.Error(), `no static file "missing.css"`) {
+		t.Errorf("asset of an unknown file error = %v", err)
+	}
+}
+
+func TestSelfCheck_UnknownAsset(t *testing.T) {
+	templates := fstest.MapFS{}
+	entries, err := os.ReadDir("templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		data, err := os.ReadFile("templates/" + entry.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		templates[entry.Name()] = &fstest.MapFile{Data: data}
+	}
+	templates["items.html"] = &fstest.MapFile{Data: []byte(`<link rel="stylesheet" href="{{asset "missing.css"}}">`)}
+
+	err = SelfCheck(WithConfig(testConfig(t)), WithTemplates(templates))
+	if err == nil || !strings.Contains(err.Error(), "GET /items in en: status 500") || !strings.Contains(err.Error(), "missing.css") {
+		t.Errorf("SelfCheck() with an unknown asset = %v, want the page reported", err)
+	}
+}
+
+func TestNewStaticSource_Dev(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.css")
+	os.WriteFile(file, []byte("body { color: red }"), 0o600)
+	store, err := itemstore.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t)
+	cfg.Dev = true
+	srv, err := New(store, WithConfig(cfg), WithStatic(os.DirFS(dir)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, ok := srv.static.(diskStatic); !ok {
+		t.Fatalf("static = %T, want the files read on every request", srv.static)
+	}
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/app.css", nil))
+		return rec
+	}
+
+	rec := get()
+	if rec.Code != http.StatusOK || rec.Body.String() != "body { color: red }" {
+		t.Fatalf("GET /static/app.css = %d %q, want the file", rec.Code, rec.Body)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != revalidateCacheControl {
+		t.Errorf("Cache-Control = %q, want %q", cc, revalidateCacheControl)
+	}
+
+	// Edits are picked up without restarting
+	os.WriteFile(file, []byte("body { color: blue }"), 0o600)
+	if rec := get(); rec.Body.String() != "body { color: blue }" {
+		t.Errorf("GET /static/app.css = %q after an edit, want the edited file", rec.Body)
+	}
+
+	// Pages link to the plain names, which need no manifest
+	if got, err := srv.assetURL("app.css"); err != nil || got != "/static/app.css" {
+		t.Errorf("assetURL(app.css) = %q, %v; want the plain path", got, err)
+	}
+	if _, err := srv.assetURL("missing.css"); err == nil {
+		t.Error("assetURL(missing.css) succeeded in dev mode")
+	}
+}
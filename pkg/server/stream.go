@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// apiStreamParams are the query parameters understood by GET
+// /api/items/stream: the filters of GET /api/items plus a sort
+var apiStreamParams = slices.Concat(apiItemsParams, []string{"sortBy", "order"})
+
+const (
+	// ndjsonContentType is the media type of newline-delimited JSON
+	ndjsonContentType = "application/x-ndjson"
+	// streamFlushEvery is how many lines the stream writes between flushes
+	streamFlushEvery = 100
+	// streamFlushInterval is the longest a written line waits to be flushed
+	// while more follow
+	streamFlushInterval = 250 * time.Millisecond
+)
+
+// streamMeta is the last line of the stream
+type streamMeta struct {
+	Meta listMeta `json:"_meta"`
+}
+
+// apiStreamItemsHandler streams the items GET /api/items would list as
+// newline-delimited JSON, one item per line, optionally sorted. A final
+// {"_meta":{"count":N}} line tells clients the stream is complete: a failed
+// or abandoned stream ends without it. A client that disconnects stops the
+// iteration at the next item.
+func (s *Server) apiStreamItemsHandler(w http.ResponseWriter, r *http.Request) {
+	query, err := parseItemsQuery(fillDefaults(s.cfg.Defaults, r.URL.Query(), apiStreamParams), apiStreamParams, true)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	rc := http.NewResponseController(w)
+	// A large store takes longer to stream than the server's write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.respondError(w, r, err)
+		return
+	}
+
+	store := s.storeFor(r)
+	items := store.ItemsContext(r.Context(), query.Filters)
+	if query.SortBy != "" {
+		// Sorting needs every item first
+		sorted, err := store.FilterContext(r.Context(), query.Filters)
+		if err == nil {
+			err = itemstore.Sort(r.Context(), sorted, query.SortBy, query.Descending)
+		}
+		if err != nil {
+			s.respondError(w, r, err)
+			return
+		}
+		items = slices.Values(sorted)
+	}
+
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	switch err := writeItemLines(r.Context(), w, rc, items); {
+	case errors.Is(err, context.Canceled):
+		s.logger.DebugContext(r.Context(), "client left the item stream", "request_id", RequestIDFromContext(r.Context()))
+	case err != nil:
+		s.logger.WarnContext(r.Context(), "item stream ended early", "error", err, "request_id", RequestIDFromContext(r.Context()))
+	}
+}
+
+// writeItemLines writes items as JSON lines followed by the meta line,
+// flushing every streamFlushEvery lines or streamFlushInterval, whichever
+// comes first. It stops with ctx's error once ctx is done, which for a
+// request is when the client is gone.
+func writeItemLines(ctx context.Context, w http.ResponseWriter, rc *http.ResponseController, items iter.Seq[itemstore.Item]) error {
+	flush := func() error {
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	var current itemstore.Item
+	count, pending := 0, 0
+	lastFlush := time.Now()
+	for item := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		buf.Reset()
+		current = item
+		if err := enc.Encode(&current); err != nil {
+			return fmt.Errorf("encode item %s: %w", item.ID, err)
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		count++
+		pending++
+		if pending >= streamFlushEvery || time.Since(lastFlush) >= streamFlushInterval {
+			if err := flush(); err != nil {
+				return err
+			}
+			pending, lastFlush = 0, time.Now()
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	buf.Reset()
+	if err := enc.Encode(streamMeta{Meta: listMeta{Count: count}}); err != nil {
+		return err
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return flush()
+}
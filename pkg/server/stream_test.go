@@ -0,0 +1,119 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+func TestAPI_StreamItems(t *testing.T) {
+	ts := httptest.NewServer(newTestServer(t, testConfig(t)).Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/items/stream?filter=category:A&sortBy=id&order=desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != ndjsonContentType {
+		t.Fatalf("response = %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	var ids []int
+	var meta *streamMeta
+	for scanner.Scan() {
+		if meta != nil {
+			t.Fatalf("line after the meta line: %s", scanner.Text())
+		}
+		var line struct {
+			itemstore.Item
+			Meta *listMeta `json:"_meta"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		if line.Meta != nil {
+			meta = &streamMeta{Meta: *line.Meta}
+			continue
+		}
+		id, _ := line.ID.Int()
+		ids = append(ids, id)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 2 || ids[1] != 1 {
+		t.Errorf("streamed IDs %v, want [2 1]", ids)
+	}
+	if meta == nil || meta.Meta.Count != 2 {
+		t.Errorf("meta line = %+v, want a count of 2", meta)
+	}
+
+	for _, target := range []string{"/api/items/stream?sortBy=weight", "/api/items/stream?groupBy=color"} {
+		if resp, err := http.Get(ts.URL + target); err != nil || resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET %s = %v, %v; want 400", target, resp.StatusCode, err)
+		} else {
+			resp.Body.Close()
+		}
+	}
+}
+
+func TestAPI_StreamItemsDisconnect(t *testing.T) {
+	store, err := itemstore.New(itemstore.Generate(50000, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := New(store, WithConfig(testConfig(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := runtime.NumGoroutine()
+	ts := httptest.NewServer(srv.Handler())
+	client := &http.Client{Transport: &http.Transport{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/items/stream", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first lines arrive before the stream is done
+	scanner := bufio.NewScanner(resp.Body)
+	for range 3 {
+		if !scanner.Scan() {
+			t.Fatalf("stream ended after fewer than 3 lines: %v", scanner.Err())
+		}
+	}
+	cancel()
+	resp.Body.Close()
+
+	// Close waits for the handler, so it returns only once the stream stops
+	closed := make(chan struct{})
+	go func() {
+		ts.Close()
+		client.CloseIdleConnections()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stream handler kept running after the client disconnected")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines after the disconnect, %d before:\n%s", runtime.NumGoroutine(), before, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
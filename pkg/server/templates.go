@@ -0,0 +1,223 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+)
+
+// templateSource provides the page templates, each named after its file, with
+// the t function translating into lang
+type templateSource interface {
+	Templates(lang string) (*template.Template, error)
+}
+
+// parsedTemplates is the production source: templates parsed once at
+// startup for each language, keyed by language. Languages without their own
+// set get defaultLanguage's.
+type parsedTemplates map[string]*template.Template
+
+func (p parsedTemplates) Templates(lang string) (*template.Template, error) {
+	if tmpl, ok := p[lang]; ok {
+		return tmpl, nil
+	}
+	return p[defaultLanguage], nil
+}
+
+// diskTemplates is the --dev source: templates re-read from fsys on every
+// call, so edits show up on the next request without a rebuild
+type diskTemplates struct {
+	fsys  fs.FS
+	funcs func(lang string) template.FuncMap
+}
+
+func (d diskTemplates) Templates(lang string) (*template.Template, error) {
+	return parseTemplates(d.fsys, d.funcs(lang))
+}
+
+// newTemplateSource returns the templates, the embedded ones unless
+// WithTemplates replaced them, parsed once or, in dev mode, re-read on every
+// request
+func (s *Server) newTemplateSource(dev bool) (templateSource, error) {
+	fsys := s.templatesFS
+	if fsys == nil {
+		sub, err := fs.Sub(embedFS, "templates")
+		if err != nil {
+			return nil, fmt.Errorf("templates directory in embedded filesystem: %w", err)
+		}
+		fsys = sub
+	}
+	if dev {
+		return diskTemplates{fsys: fsys, funcs: s.templateFuncs}, nil
+	}
+	parsed := make(parsedTemplates)
+	for _, lang := range s.messages.Languages() {
+		var err error
+		if parsed[lang], err = parseTemplates(fsys, s.templateFuncs(lang)); err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+// templateFuncs returns the functions available to every template, with t
+// translating message keys into lang. All templates are parsed into one set
+// with these, so partials can rely on them whichever page includes them.
+func (s *Server) templateFuncs(lang string) template.FuncMap {
+	return template.FuncMap{
+		"asset":     s.assetURL,
+		"colorHex":  s.palette.Hex,
+		"colorName": format.Color,
+		"dict":      templateDict,
+		"highlight": highlight,
+		"itemPath":  itemPath,
+		"lower":     strings.ToLower,
+		"number":    templateNumber,
+		"plural":    templatePlural,
+		"pluralize": templatePluralize,
+		"safeURL":   s.linkURL,
+		"shapeIcon": s.shapeIcon,
+		"shapeName": format.ShapeName,
+		"t":         func(key string) string { return s.messages.Message(lang, key) },
+		"themes":    func() []string { return themes },
+		"title":     format.Title,
+		"upper":     strings.ToUpper,
+		"url":       s.url,
+	}
+}
+
+// templateNumber formats n with the digit grouping of the locale, if one is
+// given, or of English: {{number .Count $.Locale}}
+func templateNumber(n int, locale ...string) string {
+	return format.Number(n, optionalLocale(locale))
+}
+
+// templatePluralize formats a count with its noun in the locale, if one is
+// given, or in English: {{pluralize .Count "item" $.Locale}}
+func templatePluralize(n int, noun string, locale ...string) string {
+	return format.Count(n, noun, optionalLocale(locale))
+}
+
+// templatePlural returns the plural of noun, without a count:
+// {{plural "category" $.Locale}}
+func templatePlural(noun string, locale ...string) string {
+	return format.Noun(2, noun, optionalLocale(locale))
+}
+
+// optionalLocale returns the locale passed to a template function, or
+// defaultLanguage when none was
+func optionalLocale(locale []string) string {
+	if len(locale) == 0 || locale[0] == "" {
+		return defaultLanguage
+	}
+	return locale[0]
+}
+
+// templateDict builds a map from alternating keys and values, so a template
+// can pass several values to a partial:
+// {{template "badge" dict "Label" .Color "Hex" .Hex}}
+func templateDict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict: odd number of arguments")
+	}
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// linkURL returns the public URL of path with a query built from
+// alternating parameter names and values, each escaped, e.g.
+// {{safeURL "/items" "filter" "color:red"}}. It is an ordinary string, so
+// the template still escapes it for the attribute it lands in.
+func (s *Server) linkURL(path string, pairs ...string) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", errors.New("safeURL: odd number of query arguments")
+	}
+	link := s.url(path)
+	if len(pairs) == 0 {
+		return link, nil
+	}
+	query := make(url.Values, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		query.Add(pairs[i], pairs[i+1])
+	}
+	return link + "?" + query.Encode(), nil
+}
+
+// parseTemplates parses every page template in fsys with the shared
+// functions into one set
+func parseTemplates(fsys fs.FS, funcs template.FuncMap) (*template.Template, error) {
+	tmpl, err := template.New("").Funcs(funcs).ParseFS(fsys, "*.html")
+	if err != nil {
+		return nil, fmt.Errorf("parse templates: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderBuffers recycles the buffers pages are rendered into
+var renderBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// maxPooledRenderBuffer is the largest buffer put back in renderBuffers, so
+// that one huge page does not keep its memory pinned in the pool
+const maxPooledRenderBuffer = 1 << 20
+
+// render executes the named template into a pooled buffer and sends it with
+// status and its Content-Length. Nothing reaches the client until execution
+// has succeeded, so a failing template produces a clean 500 error page
+// instead of a truncated page.
+func (s *Server) render(w http.ResponseWriter, r *http.Request, status int, name string, data any) {
+	lang := s.requestLanguage(r)
+	tmpl, err := s.templates.Templates(lang)
+	if err != nil {
+		// Only dev mode parses per request, so show the developer what broke
+		s.logger.ErrorContext(r.Context(), "failed to parse templates",
+			"error", err,
+			"request_id", RequestIDFromContext(r.Context()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	buf := renderBuffers.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledRenderBuffer {
+			buf.Reset()
+			renderBuffers.Put(buf)
+		}
+	}()
+	if err := tmpl.ExecuteTemplate(buf, name, data); err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to execute template",
+			"template", name,
+			"error", err,
+			"stack", string(debug.Stack()),
+			"request_id", RequestIDFromContext(r.Context()))
+		if name == errorTemplate {
+			// The error page itself is broken, so fall back to plain text
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		s.renderErrorPage(w, r, http.StatusInternalServerError, internalErrorMessage)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Language", lang)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
@@ -0,0 +1,347 @@
+package server
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+func TestRender(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	rec := httptest.NewRecorder()
+	data := struct {
+		pageData
+		Path string
+	}{pageData: pageData{Theme: "dark"}, Path: "/missing"}
+	srv.render(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusNotFound, "404.html", data)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/html", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "/missing") {
+		t.Errorf("body does not mention the path:\n%s", rec.Body)
+	}
+	if cl := rec.Header().Get("Content-Length"); cl != strconv.Itoa(rec.Body.Len()) {
+		t.Errorf("Content-Length = %q, want %d", cl, rec.Body.Len())
+	}
+}
+
+func TestRender_BufferedError(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	logs := captureLogs(srv)
+
+	// A template that writes output before failing must not leak the
+	// partial page
+	parsed, err := srv.templates.Templates(defaultLanguage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := template.Must(parsed.Clone())
+	template.Must(tmpl.New("broken.html").Parse(`<p>partial output</p>{{call .Fail}}`))
+	srv.templates = parsedTemplates{defaultLanguage: tmpl}
+
+	data := struct{ Fail func() (string, error) }{
+		Fail: func() (string, error) { return "", errors.New("secret internal failure") },
+	}
+	rec := httptest.NewRecorder()
+	srv.render(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, "broken.html", data)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "partial output") || strings.Contains(body, "secret internal failure") {
+		t.Errorf("body leaks the failed render:\n%s", body)
+	}
+	if !strings.Contains(body, "<title>500 Internal Server Error</title>") {
+		t.Errorf("body is not the error page:\n%s", body)
+	}
+	if !strings.Contains(logs.String(), "secret internal failure") {
+		t.Errorf("template error was not logged: %s", logs)
+	}
+
+	// The failed render's buffer goes back to the pool empty
+	for range 10 {
+		rec := httptest.NewRecorder()
+		srv.render(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, "404.html", struct{ pageData }{})
+		if strings.Contains(rec.Body.String(), "partial output") {
+			t.Fatalf("a later render includes the failed one:\n%s", rec.Body)
+		}
+	}
+}
+
+// statusRecorder records every status written, so a test can tell a clean
+// response from one whose status was written twice
+type statusRecorder struct {
+	*httptest.ResponseRecorder
+	statuses []int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.statuses = append(w.statuses, status)
+	w.ResponseRecorder.WriteHeader(status)
+}
+
+func TestItemsPage_TemplateFailsMidBody(t *testing.T) {
+	// The real templates, with items.html replaced by a fixture that fails
+	// after writing most of the page
+	templates := fstest.MapFS{}
+	entries, err := os.ReadDir("templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join("templates", entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		templates[entry.Name()] = &fstest.MapFile{Data: data}
+	}
+	fixture, err := os.ReadFile(filepath.Join("testdata", "broken_items.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates["items.html"] = &fstest.MapFile{Data: fixture}
+
+	store, err := itemstore.New([]itemstore.Item{{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := New(store, WithConfig(testConfig(t)), WithTemplates(templates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := captureLogs(srv)
+
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := &statusRecorder{ResponseRecorder: httptest.NewRecorder()}
+	srv.Handler().ServeHTTP(rec, req)
+
+	if !slices.Equal(rec.statuses, []int{http.StatusInternalServerError}) {
+		t.Errorf("statuses written = %v, want one 500", rec.statuses)
+	}
+	body := rec.Body.String()
+	for _, partial := range []string{"Broken items page", "red circle", "no value follows"} {
+		if strings.Contains(body, partial) {
+			t.Errorf("body contains %q from the failed render:\n%s", partial, body)
+		}
+	}
+	if !strings.Contains(body, "<title>500 Internal Server Error</title>") || !strings.HasSuffix(strings.TrimSpace(body), "</html>") {
+		t.Errorf("body is not one whole error page:\n%s", body)
+	}
+	if cl := rec.Header().Get("Content-Length"); cl != strconv.Itoa(rec.Body.Len()) {
+		t.Errorf("Content-Length = %q, want %d", cl, rec.Body.Len())
+	}
+
+	var failures []map[string]any
+	for _, record := range logRecords(t, logs) {
+		if record["msg"] == "failed to execute template" {
+			failures = append(failures, record)
+		}
+	}
+	if len(failures) != 1 {
+		t.Fatalf("logged %d template failures, want 1:\n%s", len(failures), logs)
+	}
+	if stack, _ := failures[0]["stack"].(string); !strings.Contains(stack, "itemsHandler") {
+		t.Errorf("logged stack does not lead to the handler:\n%s", stack)
+	}
+}
+
+func TestNewServer_ParsesTemplates(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	if _, ok := srv.templates.(parsedTemplates); !ok {
+		t.Fatalf("templates = %T, want them parsed once", srv.templates)
+	}
+	tmpl, _ := srv.templates.Templates(defaultLanguage)
+	for _, name := range []string{"items.html", "404.html", errorTemplate} {
+		if tmpl.Lookup(name) == nil {
+			t.Errorf("template %s was not parsed at startup", name)
+		}
+	}
+}
+
+// BenchmarkItemsPage compares rendering the items page from the templates
+// parsed at startup with parsing them again for every request, as the
+// handler used to
+func BenchmarkItemsPage(b *testing.B) {
+	srv := newTestServer(b, testConfig(b))
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+
+	b.Run("parsed-once", func(b *testing.B) {
+		for b.Loop() {
+			srv.itemsHandler(httptest.NewRecorder(), req)
+		}
+	})
+	b.Run("parsed-per-request", func(b *testing.B) {
+		for b.Loop() {
+			tmpl, err := parseTemplates(os.DirFS("templates"), srv.templateFuncs(defaultLanguage))
+			if err != nil {
+				b.Fatal(err)
+			}
+			srv.templates = parsedTemplates{defaultLanguage: tmpl}
+			srv.itemsHandler(httptest.NewRecorder(), req)
+		}
+	})
+}
+
+// BenchmarkRender renders the items page of a 500-item store into a writer
+// that discards it, so only the rendering itself is measured
+func BenchmarkRender(b *testing.B) {
+	srv := newBenchServer(b, 500)
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	w := &discardResponseWriter{header: http.Header{}}
+	b.ReportAllocs()
+	for b.Loop() {
+		clear(w.header)
+		srv.itemsHandler(w, req)
+	}
+}
+
+func TestDevTemplates(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	dir := t.TempDir()
+	srv.templates = diskTemplates{fsys: os.DirFS(dir), funcs: srv.templateFuncs}
+
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "page.html"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	render := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.render(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, "page.html", nil)
+		return rec
+	}
+
+	write(`<p>{{title "first"}}</p>`)
+	if rec := render(); rec.Code != http.StatusOK || rec.Body.String() != "<p>First</p>" {
+		t.Errorf("render = %d %q, want the first version", rec.Code, rec.Body)
+	}
+
+	// Edits are picked up without restarting
+	write(`<p>{{title "second"}}</p>`)
+	if rec := render(); rec.Body.String() != "<p>Second</p>" {
+		t.Errorf("render = %q, want the edited version", rec.Body)
+	}
+
+	// A broken edit shows the parse error as plain text
+	write(`<p>{{if}}</p>`)
+	rec := render()
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "page.html") {
+		t.Errorf("body = %q, want the parse error", rec.Body)
+	}
+}
+
+func TestNewTemplateSource_Dev(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "items.html")
+	os.WriteFile(page, []byte("before"), 0o600)
+	store, err := itemstore.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t)
+	cfg.Dev = true
+	srv, err := New(store, WithConfig(cfg), WithTemplates(os.DirFS(dir)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, ok := srv.templates.(diskTemplates); !ok {
+		t.Fatalf("templates = %T, want them re-read on every request", srv.templates)
+	}
+
+	os.WriteFile(page, []byte("after"), 0o600)
+	if body := getItems(t, srv, "/items").Body.String(); body != "after" {
+		t.Errorf("GET /items = %q after an edit, want the edited template", body)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.BasePath = "/dash"
+	srv := newTestServer(t, cfg)
+
+	tests := []struct {
+		name string
+		tmpl string
+		data any
+		want string
+	}{
+		{"title", `{{title "hello world"}}`, nil, "Hello World"},
+		{"title non-ASCII", `{{title "élan ÇA"}}`, nil, "Élan Ça"},
+		{"pluralize", `{{pluralize 1 "item"}}, {{pluralize 3 "category"}}`, nil, "1 item, 3 categories"},
+		{"pluralize in a locale", `{{pluralize 1500 "item" .}}`, "de", "1.500 items"},
+		{"plural", `{{plural "category"}}`, nil, "categories"},
+		{"number", `{{number 1234567}} {{number 1234567 .}}`, "hi", "1,234,567 12,34,567"},
+		{"colorHex", `{{colorHex "Red"}}`, nil, "#ff0000"},
+		{"lower and upper", `{{lower "MiXed"}} {{upper "MiXed"}}`, nil, "mixed MIXED"},
+		{"dict", `{{template "pair" dict "A" 1 "B" "two"}}{{define "pair"}}{{.A}}-{{.B}}{{end}}`, nil, "1-two"},
+		{"url", `{{url "/items"}}`, nil, "/dash/items"},
+		{"safeURL", `<a href="{{safeURL "/items" "filter" "color:red" "q" .}}">`, `"><script>`,
+			`<a href="/dash/items?filter=color%3Ared&amp;q=%22%3E%3Cscript%3E">`},
+		{"safeURL without a query", `{{safeURL "/items"}}`, nil, "/dash/items"},
+		{"t", `{{t "filters.active"}}`, nil, "Active Filters"},
+		{"t with an unknown key", `{{t "no.such.key"}}`, nil, "no.such.key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := template.Must(template.New("").Funcs(srv.templateFuncs(defaultLanguage)).Parse(tt.tmpl))
+			var b strings.Builder
+			if err := tmpl.Execute(&b, tt.data); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{`{{dict "A"}}`, `{{dict 1 2}}`, `{{safeURL "/items" "q"}}`, `{{shapeIcon nil}}`, `{{highlight "x" 1}}`, `{{number .}}`} {
+		tmpl := template.Must(template.New("").Funcs(srv.templateFuncs(defaultLanguage)).Parse(bad))
+		if err := tmpl.Execute(io.Discard, nil); err == nil {
+			t.Errorf("%s executed without an error", bad)
+		}
+	}
+}
+
+// FuzzLinkURL checks that safeURL escapes any parameter name and value
+func FuzzLinkURL(f *testing.F) {
+	f.Add("filter", "color:red")
+	f.Add("q", `"><script>alert(1)</script>`)
+	f.Add("a&b=c", "d#e f")
+	f.Add("\x00", "\r\n\t%")
+	cfg := testConfig(f)
+	cfg.BasePath = "/dash"
+	srv := newTestServer(f, cfg)
+	f.Fuzz(func(t *testing.T, key, value string) {
+		link, err := srv.linkURL("/items", key, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := checkEncodedLink(t, link, "/dash/items?"); len(got) != 1 || len(got[key]) != 1 || got[key][0] != value {
+			t.Errorf("linkURL(%q, %q) = %q, which parses to %v", key, value, link, got)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/buildinfo"
+)
+
+const (
+	// themeCookie remembers the visitor's color theme
+	themeCookie = "theme"
+	// themeCookieMaxAge keeps the choice for a year
+	themeCookieMaxAge = 365 * 24 * 60 * 60
+	// defaultTheme follows the browser's color scheme preference
+	defaultTheme = "auto"
+)
+
+// themes are the values accepted by /theme?set= and the theme cookie
+var themes = []string{"auto", "dark", "light"}
+
+// pageData carries what every full HTML page needs besides its own content.
+// Page data structs embed it so templates can use .Theme and .URL directly.
+type pageData struct {
+	// Theme is one of themes; templates add it to <body> as a theme-* class
+	Theme string
+	// Lang is the language of the page's messages, for <html lang>
+	Lang string
+	// Locale is the visitor's locale, which templates pass to the number
+	// and pluralize functions
+	Locale string
+	// ReadOnly hides the links to the add, edit, and delete forms
+	ReadOnly bool
+	// Build describes the build serving the page, for footers
+	Build buildinfo.Info
+	// scope maps a route to its URL in the collection the page belongs to
+	scope func(path string) string
+}
+
+// URL returns the URL of a route in the collection the page belongs to.
+// Templates use it for links to items and forms, and the url function for
+// routes shared by every collection.
+func (p pageData) URL(path string) string {
+	if p.scope == nil {
+		return path
+	}
+	return p.scope(path)
+}
+
+// pageData returns the shared page data for r
+func (s *Server) pageData(r *http.Request) pageData {
+	return pageData{
+		Theme:    requestTheme(r),
+		Lang:     s.requestLanguage(r),
+		Locale:   s.requestLocale(r),
+		ReadOnly: s.cfg.ReadOnly,
+		Build:    buildinfo.Get(),
+		scope:    func(path string) string { return s.scopedURL(r, path) },
+	}
+}
+
+// requestTheme returns the theme chosen by the visitor, or defaultTheme when
+// the cookie is missing or holds anything but a known theme
+func requestTheme(r *http.Request) string {
+	c, err := r.Cookie(themeCookie)
+	if err != nil || !slices.Contains(themes, c.Value) {
+		return defaultTheme
+	}
+	return c.Value
+}
+
+// themeHandler stores the theme given by ?set= in a cookie and sends the
+// visitor back to the page they came from
+func (s *Server) themeHandler(w http.ResponseWriter, r *http.Request) {
+	theme := r.URL.Query().Get("set")
+	if !slices.Contains(themes, theme) {
+		s.respondError(w, r, &httpError{status: http.StatusBadRequest,
+			message: "invalid theme: " + theme + " (supported: " + strings.Join(themes, ", ") + ")"})
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     themeCookie,
+		Value:    theme,
+		Path:     s.url("/"),
+		MaxAge:   themeCookieMaxAge,
+		HttpOnly: true,
+		Secure:   s.requestScheme(r) == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, s.refererPath(r), http.StatusSeeOther)
+}
+
+// refererPath returns the path and query of the Referer when it is a page of
+// this dashboard, and the items page otherwise. Only a path under the base
+// path on the request's own host is ever returned, so the result is safe to
+// redirect to.
+func (s *Server) refererPath(r *http.Request) string {
+	fallback := s.url("/items")
+
+	u, err := url.Parse(r.Referer())
+	if err != nil || u.Host != r.Host || u.User != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fallback
+	}
+	p := u.EscapedPath()
+	// A path such as //evil.example or /\evil.example is read by browsers
+	// as another host
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.HasPrefix(p, `/\`) || strings.Contains(u.Path, `\`) {
+		return fallback
+	}
+	if rest, ok := strings.CutPrefix(p, s.cfg.BasePath); !ok || !strings.HasPrefix(rest, "/") || rest == "/theme" {
+		return fallback
+	}
+
+	if u.RawQuery != "" {
+		p += "?" + u.RawQuery
+	}
+	return p
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestThemeHandler(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/theme?set=dark", nil)
+	req.Header.Set("Referer", "http://example.com/items?groupBy=color&filter=shape:circle")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("status = %d, want 303", rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/items?groupBy=color&filter=shape:circle" {
+		t.Errorf("Location = %q, want the referring page", loc)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	c := cookies[0]
+	if c.Name != themeCookie || c.Value != "dark" || c.MaxAge != themeCookieMaxAge || c.Path != "/" || !c.HttpOnly {
+		t.Errorf("cookie = %+v, want a long-lived theme=dark cookie", c)
+	}
+
+	for _, set := range []string{"", "blue", "DARK"} {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/theme?set="+set, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("set=%q: status = %d, want 400", set, rec.Code)
+		}
+		if rec.Header().Get("Set-Cookie") != "" {
+			t.Errorf("set=%q: cookie was set", set)
+		}
+	}
+}
+
+func TestRefererPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		basePath string
+		referer  string
+		want     string
+	}{
+		{"no referer", "", "", "/items"},
+		{"same origin", "", "http://example.com/items?groupBy=color", "/items?groupBy=color"},
+		{"same origin over https", "", "https://example.com/items/3/edit", "/items/3/edit"},
+		{"other host", "", "https://evil.example/items", "/items"},
+		{"host with our name as prefix", "", "http://example.com.evil.example/items", "/items"},
+		{"our host with another port", "", "http://example.com:8443/items", "/items"},
+		{"credentials", "", "http://evil.example@example.com/items", "/items"},
+		{"scheme-relative", "", "//evil.example/items", "/items"},
+		{"double slash path", "", "http://example.com//evil.example/items", "/items"},
+		{"backslash path", "", `http://example.com/\evil.example`, "/items"},
+		{"escaped backslash path", "", "http://example.com/%5Cevil.example", "/items"},
+		{"escaped slashes stay a path", "", "http://example.com/%2F%2Fevil.example", "/%2F%2Fevil.example"},
+		{"javascript", "", "javascript:alert(1)", "/items"},
+		{"data", "", "data:text/html,<script>alert(1)</script>", "/items"},
+		{"relative", "", "items", "/items"},
+		{"header injection", "", "http://example.com/items\r\nSet-Cookie: x=1", "/items"},
+		{"theme page", "", "http://example.com/theme?set=light", "/items"},
+		{"under base path", "/dash", "http://example.com/dash/items?sortBy=id", "/dash/items?sortBy=id"},
+		{"outside base path", "/dash", "http://example.com/items", "/dash/items"},
+		{"base path as prefix of a name", "/dash", "http://example.com/dashboard", "/dash/items"},
+		{"theme page under base path", "/dash", "http://example.com/dash/theme?set=dark", "/dash/items"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.BasePath = tt.basePath
+			srv := newTestServer(t, cfg)
+
+			req := httptest.NewRequest(http.MethodGet, "/theme?set=light", nil)
+			req.Header.Set("Referer", tt.referer)
+			if got := srv.refererPath(req); got != tt.want {
+				t.Errorf("refererPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestTheme(t *testing.T) {
+	tests := []struct {
+		cookie string
+		want   string
+	}{
+		{"", "auto"},
+		{"dark", "dark"},
+		{"light", "light"},
+		{"auto", "auto"},
+		{"neon", "auto"},
+		{`dark" onload="alert(1)`, "auto"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		if tt.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: themeCookie, Value: tt.cookie})
+		}
+		if got := requestTheme(req); got != tt.want {
+			t.Errorf("cookie %q: theme = %q, want %q", tt.cookie, got, tt.want)
+		}
+	}
+}
+
+func TestPages_Theme(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/items", `<div class="main-container theme-light"`},
+		{"/items/new", `<body class="theme-light">`},
+		{"/items/1/delete", `<body class="theme-light">`},
+		{"/missing", `<body class="theme-light">`},
+		{"/items?sortBy=bogus", `<body class="theme-light">`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Accept", "text/html")
+		req.AddCookie(&http.Cookie{Name: themeCookie, Value: "light"})
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		if !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("GET %s does not contain %s", tt.path, tt.want)
+		}
+	}
+}
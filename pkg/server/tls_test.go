@@ -0,0 +1,87 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+)
+
+func TestSelfSignedTLS(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.TLSSelfSigned = true
+	tlsConfig, err := NewTLSConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewTLSConfig() error = %v", err)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("MinVersion = %#x, want TLS 1.2", tlsConfig.MinVersion)
+	}
+
+	ts := httptest.NewUnstartedServer(newTestServer(t, cfg).Handler())
+	ts.TLS = tlsConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsConfig.Certificates[0].Leaf)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	resp, err := client.Get(ts.URL + "/api/items")
+	if err != nil {
+		t.Fatalf("GET over TLS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := resp.Header.Get("Strict-Transport-Security"); !strings.HasPrefix(got, "max-age=") {
+		t.Errorf("Strict-Transport-Security = %q, want a max-age directive", got)
+	}
+}
+
+func TestNoHSTSWithoutTLS(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+
+	if got := rec.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("Strict-Transport-Security = %q over plain HTTP, want none", got)
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"cert without key", []string{"--tls-cert=" + missing}},
+		{"key without cert", []string{"--tls-key=" + missing}},
+		{"self-signed with cert", []string{"--tls-self-signed", "--tls-cert=" + missing, "--tls-key=" + missing}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := config.Parse(tt.args, func(string) string { return "" }); err == nil {
+				t.Errorf("config.Parse(%v) succeeded, want an error", tt.args)
+			}
+		})
+	}
+
+	t.Run("unreadable files", func(t *testing.T) {
+		cfg, err := config.Parse([]string{"--tls-cert=" + missing, "--tls-key=" + missing}, func(string) string { return "" })
+		if err != nil {
+			t.Fatalf("config.Parse() error = %v", err)
+		}
+		if _, err := NewTLSConfig(cfg); err == nil {
+			t.Error("NewTLSConfig() succeeded with missing certificate files")
+		}
+	})
+}
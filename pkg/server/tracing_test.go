@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// hasAttr reports whether attrs contains want
+func hasAttr(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTracing_APIItems(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	srv := newTestServer(t, testConfig(t))
+	srv.tracer = tp.Tracer(tracerName)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/items?filter=color:red", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2: %v", len(spans), spans.Snapshots())
+	}
+	filter, server := spans[0], spans[1]
+
+	if server.Name != "GET /api/items" || server.SpanKind != trace.SpanKindServer {
+		t.Errorf("server span = %q (%v), want GET /api/items (server)", server.Name, server.SpanKind)
+	}
+	if got := server.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s, want the propagated one", got)
+	}
+	for _, want := range []attribute.KeyValue{
+		attribute.String("http.route", "/api/items"),
+		attribute.Int("http.response.status_code", http.StatusOK),
+		attribute.String("request_id", rec.Header().Get(requestIDHeader)),
+	} {
+		if !hasAttr(server.Attributes, want) {
+			t.Errorf("server span attributes = %v, want %v among them", server.Attributes, want)
+		}
+	}
+
+	if filter.Name != "itemstore.Filter" {
+		t.Errorf("child span = %q, want itemstore.Filter", filter.Name)
+	}
+	if filter.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("itemstore.Filter is not a child of the server span")
+	}
+	if !hasAttr(filter.Attributes, attribute.Int("itemstore.items.matched", 1)) {
+		t.Errorf("filter span attributes = %v, want 1 matched item", filter.Attributes)
+	}
+}
+
+func TestTracing_ServerError(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	srv := newTestServer(t, testConfig(t))
+	srv.tracer = tp.Tracer(tracerName)
+
+	handler := srv.withMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	if spans[0].Status.Code != codes.Error {
+		t.Errorf("span status = %v, want Error", spans[0].Status)
+	}
+}
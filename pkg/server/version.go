@@ -0,0 +1,21 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/ElodinLaarz/dashboard/pkg/buildinfo"
+)
+
+// versionResponse is the body returned by /version
+type versionResponse struct {
+	buildinfo.Info
+	ItemsAtStartup int `json:"itemsAtStartup"`
+}
+
+// versionHandler reports which build is running and how many items it loaded
+func (s *Server) versionHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, r, http.StatusOK, versionResponse{
+		Info:           buildinfo.Get(),
+		ItemsAtStartup: s.itemsAtStartup,
+	})
+}
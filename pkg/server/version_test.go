@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/buildinfo"
+)
+
+func TestVersionHandler(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	for _, key := range []string{"version", "commit", "buildDate", "goVersion"} {
+		if s, ok := body[key].(string); !ok || s == "" {
+			t.Errorf("%s = %v, want a non-empty string", key, body[key])
+		}
+	}
+	if body["goVersion"] != runtime.Version() {
+		t.Errorf("goVersion = %v, want %s", body["goVersion"], runtime.Version())
+	}
+	if body["itemsAtStartup"] != float64(3) {
+		t.Errorf("itemsAtStartup = %v, want 3", body["itemsAtStartup"])
+	}
+}
+
+func TestItemsPageShowsBuildInfo(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items", nil))
+
+	if !strings.Contains(rec.Body.String(), `class="build-info"`) {
+		t.Error("items page has no build information footer")
+	}
+	if !strings.Contains(rec.Body.String(), runtime.Version()) {
+		t.Errorf("items page footer does not mention %s", runtime.Version())
+	}
+}
+
+func TestBuildInfoOverride(t *testing.T) {
+	version, commit, date := buildinfo.Version, buildinfo.Commit, buildinfo.Date
+	t.Cleanup(func() { buildinfo.Version, buildinfo.Commit, buildinfo.Date = version, commit, date })
+	buildinfo.Version, buildinfo.Commit, buildinfo.Date = "v9.8.7", "cafef00d", "2024-02-03T04:05:06Z"
+
+	srv := newCollectionServer(t, testConfig(t))
+	for _, path := range []string{"/", "/items", "/c/a/items"} {
+		rec := getItems(t, srv, path)
+		for _, want := range []string{"v9.8.7", "cafef00d", "2024-02-03T04:05:06Z"} {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("GET %s does not show %s", path, want)
+			}
+		}
+	}
+
+	rec := getItems(t, srv, "/version")
+	var body versionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode /version: %v", err)
+	}
+	want := buildinfo.Info{Version: "v9.8.7", Commit: "cafef00d", BuildDate: "2024-02-03T04:05:06Z", GoVersion: runtime.Version()}
+	if body.Info != want {
+		t.Errorf("/version = %+v, want %+v", body.Info, want)
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"container/list"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// itemsView is the part of the items page computed from the store: the
+// grouped items and the sidebar. Cached views are shared between requests
+// and must not be modified.
+type itemsView struct {
+	groups  map[string]itemGroup
+	sidebar []sidebarSection
+}
+
+// viewCache holds the most recently used items views, at most size of them.
+// Keys include the store's revision, so a mutation leaves every earlier
+// entry unreachable until it is evicted.
+type viewCache struct {
+	mu   sync.Mutex
+	size int
+	// order lists the entries from most to least recently used
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type viewCacheEntry struct {
+	key  string
+	view itemsView
+}
+
+// newViewCache returns a cache of size views, or nil when size is not
+// positive
+func newViewCache(size int) *viewCache {
+	if size <= 0 {
+		return nil
+	}
+	return &viewCache{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns the view cached under key. A nil cache has nothing.
+func (c *viewCache) get(key string) (itemsView, bool) {
+	if c == nil {
+		return itemsView{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return itemsView{}, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*viewCacheEntry).view, true
+}
+
+// add caches view under key, evicting the least recently used view when the
+// cache is full. A nil cache drops it.
+func (c *viewCache) add(key string, view itemsView) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*viewCacheEntry).view = view
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&viewCacheEntry{key: key, view: view})
+	if c.order.Len() > c.size {
+		oldest := c.order.Remove(c.order.Back()).(*viewCacheEntry)
+		delete(c.entries, oldest.key)
+	}
+}
+
+// len returns the number of cached views
+func (c *viewCache) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// itemsViewKey identifies the view of /items for r with the given
+// parameters: the store r addresses, that store's revision, and the
+// parameters other than the one-off flash messages, which links drop
+func (s *Server) itemsViewKey(r *http.Request, params url.Values) string {
+	key := make(url.Values, len(params))
+	for name, values := range params {
+		if !slices.Contains(flashParams, name) {
+			key[name] = values
+		}
+	}
+	return scopedPath(r, "/items") + " " + strconv.FormatUint(s.storeFor(r).Revision(), 10) + " " + key.Encode()
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+func TestViewCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newViewCache(2)
+	c.add("a", itemsView{sidebar: []sidebarSection{{Property: "a"}}})
+	c.add("b", itemsView{sidebar: []sidebarSection{{Property: "b"}}})
+	c.get("a")
+	c.add("c", itemsView{sidebar: []sidebarSection{{Property: "c"}}})
+
+	if _, ok := c.get("b"); ok {
+		t.Error("get(b) hit, want b evicted as the least recently used")
+	}
+	for _, key := range []string{"a", "c"} {
+		if view, ok := c.get(key); !ok || view.sidebar[0].Property != key {
+			t.Errorf("get(%s) = %+v, %v, want its view", key, view, ok)
+		}
+	}
+	if c.len() != 2 {
+		t.Errorf("len() = %d, want 2", c.len())
+	}
+
+	var disabled *viewCache
+	disabled.add("a", itemsView{})
+	if _, ok := disabled.get("a"); ok || newViewCache(0) != nil {
+		t.Error("a cache of size 0 should hold nothing")
+	}
+}
+
+func TestItemsHandler_ViewCache(t *testing.T) {
+	srv := newTestServer(t, testConfig(t))
+	get := func(query string) string {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /items?%s status = %d", query, rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	first := get("groupBy=color")
+	key := srv.views.order.Front().Value.(*viewCacheEntry).key
+	cached, _ := srv.views.get(key)
+	if second := get("groupBy=color&added=1"); second == first || srv.views.len() != 1 {
+		t.Fatalf("a flash message should reuse the cached view, cache holds %d", srv.views.len())
+	}
+	if again, _ := srv.views.get(key); !reflect.DeepEqual(again, cached) {
+		t.Errorf("cached view changed on a hit")
+	}
+	if third := get("groupBy=color"); third != first {
+		t.Errorf("a cache hit rendered a different page")
+	}
+
+	if _, err := srv.store.Add(itemstore.Item{Color: "purple", Shape: "circle", Category: "Z"}); err != nil {
+		t.Fatal(err)
+	}
+	if body := get("groupBy=color"); !strings.Contains(body, "purple") {
+		t.Errorf("page after adding an item does not show it")
+	}
+	if srv.views.len() != 2 {
+		t.Errorf("cache holds %d views after a mutation, want a second one for the new revision", srv.views.len())
+	}
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+)
+
+// savedView is a named view of the items page served at /views/{name}:
+// one defined with --views, or a preset
+type savedView struct {
+	Name  string
+	Title string
+	// Query is the items page query string the view applies
+	Query string
+}
+
+// Link returns the path of the view's page
+func (v savedView) Link() string {
+	return "/views/" + url.PathEscape(v.Name)
+}
+
+// configuredView returns the view defined with --views called name
+func (s *Server) configuredView(name string) (savedView, bool) {
+	for _, v := range s.cfg.Views {
+		if v.Name == name {
+			return savedView{Name: v.Name, Title: v.Title, Query: v.Query}, true
+		}
+	}
+	return savedView{}, false
+}
+
+// savedView returns the view called name. Views defined with --views come
+// before presets, which cannot take their names.
+func (s *Server) savedView(name string) (savedView, bool) {
+	if v, ok := s.configuredView(name); ok {
+		return v, true
+	}
+	p, ok := s.presets.Get(name)
+	if !ok {
+		return savedView{}, false
+	}
+	title := p.Title
+	if title == "" {
+		title = p.Name
+	}
+	return savedView{Name: p.Name, Title: title, Query: p.Query}, true
+}
+
+// savedViews returns the views defined with --views in their order, then
+// the presets by name
+func (s *Server) savedViews() []savedView {
+	var views []savedView
+	for _, v := range s.cfg.Views {
+		views = append(views, savedView{Name: v.Name, Title: v.Title, Query: v.Query})
+	}
+	for _, p := range s.presets.List() {
+		if v, ok := s.savedView(p.Name); ok {
+			views = append(views, v)
+		}
+	}
+	return views
+}
+
+// checkConfiguredViews validates the queries of the views defined with
+// --views like those of presets
+func checkConfiguredViews(views []config.View) error {
+	for _, v := range views {
+		if _, err := parseSavedQuery(v.Query); err != nil {
+			return fmt.Errorf("--views: %s: %w", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// layerViewQuery returns the query of a view narrowed by the parameters of
+// a request for it. Filters are added to the view's, so they can only
+// narrow it; a filter on a property the view already filters to another
+// value is refused rather than replacing the view's. Search terms are added
+// too, and the request's grouping and sort replace the view's.
+func layerViewQuery(view string, request url.Values) (url.Values, error) {
+	layered, err := url.ParseQuery(view)
+	if err != nil {
+		return nil, fmt.Errorf("view query %q: %w", view, err)
+	}
+	viewFilters, requestFilters := parseFilters(layered), parseFilters(request)
+	for _, property := range slices.Sorted(maps.Keys(requestFilters)) {
+		value := requestFilters[property]
+		if current, ok := viewFilters[property]; ok {
+			if !strings.EqualFold(current, value) {
+				return nil, &httpError{status: http.StatusBadRequest,
+					message: fmt.Sprintf("this view only shows items with %s %s, so %s %s would show none", property, current, property, value)}
+			}
+			continue
+		}
+		layered.Add("filter", property+":"+value)
+	}
+	if q := strings.TrimSpace(request.Get("q")); q != "" {
+		layered.Set("q", strings.TrimSpace(layered.Get("q")+" "+q))
+	}
+	for _, key := range []string{"groupBy", "sortBy", "order"} {
+		if value := request.Get(key); value != "" {
+			layered.Set(key, value)
+		}
+	}
+	return layered, nil
+}
+
+// viewHandler renders a saved view: the items page with the view's query
+// applied, narrowed by the request's parameters, and its title above the
+// items. Unknown views get the 404 page.
+func (s *Server) viewHandler(w http.ResponseWriter, r *http.Request) {
+	view, ok := s.savedView(r.PathValue("name"))
+	if !ok {
+		s.notFoundHandler(w, r)
+		return
+	}
+	params, err := layerViewQuery(view.Query, r.URL.Query())
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	query, err := parseItemsQuery(params, savedQueryParams, false)
+	if err != nil {
+		s.respondError(w, r, err)
+		return
+	}
+	s.renderItemsPage(w, r, params, query, &view)
+}
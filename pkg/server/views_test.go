@@ -0,0 +1,117 @@
+package server
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// groupNames returns the data-group of each group on an items page, in
+// order
+func groupNames(body string) []string {
+	var names []string
+	for _, m := range regexp.MustCompile(`data-group="([^"]*)"`).FindAllStringSubmatch(body, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// newViewServer returns a test server with a view of category A grouped by
+// color
+func newViewServer(t *testing.T) *Server {
+	t.Helper()
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	cfg.Views = []config.View{{Name: "cat-a", Title: "Category A", Query: "filter=category%3AA&groupBy=color"}}
+	return newTestServer(t, cfg)
+}
+
+func TestViews(t *testing.T) {
+	srv := newViewServer(t)
+	tests := []struct {
+		target string
+		groups []string
+	}{
+		// Items 1 and 2 are in category A, grouped by color
+		{"/views/cat-a", []string{"blue", "red"}},
+		{"/views/cat-a?filter=color:blue", []string{"blue"}},
+		{"/views/cat-a?filterBy=shape&filterValue=circle", []string{"red"}},
+		{"/views/cat-a?filter=category:a", []string{"blue", "red"}},
+		{"/views/cat-a?q=squ", []string{"blue"}},
+		{"/views/cat-a?groupBy=shape", []string{"circle", "square"}},
+		{"/views/cat-a?filter=color:green", nil},
+	}
+	for _, tt := range tests {
+		rec := getItems(t, srv, tt.target)
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s status = %d", tt.target, rec.Code)
+			continue
+		}
+		body := rec.Body.String()
+		if got := groupNames(body); !slices.Equal(got, tt.groups) {
+			t.Errorf("GET %s groups = %q, want %q", tt.target, got, tt.groups)
+		}
+		if !strings.Contains(body, `<h2 class="view-title">Category A</h2>`) {
+			t.Errorf("GET %s does not show the view's title", tt.target)
+		}
+	}
+
+	// A request filter cannot replace the view's own
+	if rec := getItems(t, srv, "/views/cat-a?filter=category:B"); rec.Code != http.StatusBadRequest {
+		t.Errorf("conflicting filter status = %d, want 400", rec.Code)
+	}
+	if rec := getItems(t, srv, "/views/missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown view status = %d, want 404", rec.Code)
+	}
+	if body := getItems(t, srv, "/items").Body.String(); strings.Contains(body, `class="view-title"`) {
+		t.Error("the items page shows a view title")
+	}
+}
+
+func TestViews_Presets(t *testing.T) {
+	srv := newViewServer(t)
+	presetRequestTo(t, srv, http.MethodPost, "/api/presets", `{"name":"triangles","title":"All triangles","query":"filter=shape:triangle"}`)
+	presetRequestTo(t, srv, http.MethodPost, "/api/presets", `{"name":"untitled","query":"groupBy=category"}`)
+
+	body := getItems(t, srv, "/views/triangles").Body.String()
+	if !strings.Contains(body, "All triangles") || !slices.Equal(groupNames(body), []string{"triangle"}) {
+		t.Errorf("preset view = groups %q, want the triangles under the preset's title", groupNames(body))
+	}
+	if body := getItems(t, srv, "/views/untitled").Body.String(); !strings.Contains(body, `<h2 class="view-title">untitled</h2>`) {
+		t.Error("a preset without a title is not shown under its name")
+	}
+
+	if rec := presetRequestTo(t, srv, http.MethodPost, "/api/presets", `{"name":"cat-a","query":""}`); rec.Code != http.StatusConflict {
+		t.Errorf("preset named like a configured view: status = %d, want 409", rec.Code)
+	}
+
+	// The index lists every view, configured ones first
+	rec := getItems(t, srv, "/")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET / status = %d, want the index", rec.Code)
+	}
+	body = rec.Body.String()
+	links := []string{`href="/views/cat-a">Category A<`, `href="/views/triangles">All triangles<`, `href="/views/untitled">untitled<`}
+	last := -1
+	for _, link := range links {
+		i := strings.Index(body, link)
+		if i < 0 || i < last {
+			t.Errorf("index does not list %s after the views before it", link)
+		}
+		last = i
+	}
+}
+
+func TestViews_InvalidConfig(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Views = []config.View{{Name: "heavy", Title: "heavy", Query: "filter=weight%3A5"}}
+	store, _ := itemstore.New(nil)
+	if _, err := New(store, WithConfig(cfg)); err == nil || !strings.Contains(err.Error(), "--views: heavy: invalid filter") {
+		t.Errorf("New() with a view filtering on an unknown property error = %v", err)
+	}
+}
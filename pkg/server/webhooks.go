@@ -0,0 +1,23 @@
+package server
+
+import (
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/webhook"
+)
+
+// forwardWebhooks sends every store mutation to the webhook dispatcher with
+// the item's state before and after the change
+func forwardWebhooks(store itemstore.Store, d *webhook.Dispatcher) {
+	store.OnChange(func(c itemstore.Change) {
+		e := webhook.Event{Type: string(c.Type)}
+		switch c.Type {
+		case itemstore.ItemCreated:
+			e.After = c.Item
+		case itemstore.ItemUpdated:
+			e.Before, e.After = c.Previous, c.Item
+		case itemstore.ItemDeleted:
+			e.Before = c.Item
+		}
+		d.Send(e)
+	})
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/webhook"
+)
+
+func TestWebhooksOnMutation(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		payloads []map[string]json.RawMessage
+	)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(webhook.SignatureHeader) != webhook.Sign("s3cret", body) {
+			t.Errorf("bad signature on %s", body)
+		}
+		var p map[string]json.RawMessage
+		json.Unmarshal(body, &p)
+		mu.Lock()
+		payloads = append(payloads, p)
+		mu.Unlock()
+	}))
+	defer target.Close()
+
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	cfg.Webhooks = []webhook.Target{{URL: target.URL, Secret: "s3cret"}}
+	cfg.WebhookConcurrency = 1
+	srv := newTestServer(t, cfg)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/items/1", strings.NewReader(`{"color":"blue"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d: %s", rec.Code, rec.Body)
+	}
+	if err := srv.webhooks.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if len(payloads) != 1 {
+		t.Fatalf("received %d deliveries, want 1", len(payloads))
+	}
+	var before, after itemstore.Item
+	json.Unmarshal(payloads[0]["before"], &before)
+	json.Unmarshal(payloads[0]["after"], &after)
+	if string(payloads[0]["event"]) != `"item.updated"` || before.Color != "red" || after.Color != "blue" {
+		t.Errorf("payload = event %s, before %+v, after %+v", payloads[0]["event"], before, after)
+	}
+}
+
+func TestParseConfig_Webhooks(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	cfg, err := config.Parse([]string{"--webhook-urls=https://a.example.com/hook,http://b.example.com", "--webhook-secrets=one,two"}, noEnv)
+	if err != nil {
+		t.Fatalf("config.Parse() error = %v", err)
+	}
+	if len(cfg.Webhooks) != 2 || cfg.Webhooks[1].URL != "http://b.example.com" || cfg.Webhooks[1].Secret != "two" {
+		t.Errorf("Webhooks = %+v", cfg.Webhooks)
+	}
+
+	for _, args := range [][]string{
+		{"--webhook-urls=https://a.example.com"},
+		{"--webhook-urls=ftp://a.example.com", "--webhook-secrets=x"},
+	} {
+		if _, err := config.Parse(args, noEnv); err == nil {
+			t.Errorf("config.Parse(%v) succeeded, want an error", args)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlContentType is the media type of YAML request and response bodies
+const yamlContentType = "application/yaml"
+
+// YAML bodies are converted to JSON and back rather than decoded and encoded
+// with their own struct tags, so they go through exactly the decoding,
+// strict field checks, and validation of JSON bodies, and come out with the
+// same field names and values.
+
+// isYAMLMediaType reports whether mediaType names YAML
+func isYAMLMediaType(mediaType string) bool {
+	return encodingMediaTypes[mediaType] == encodingYAML
+}
+
+// yamlToJSON converts the single YAML document in data to JSON. Mapping
+// keys must be strings, as JSON object keys are.
+func yamlToJSON(data []byte) ([]byte, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("empty document")
+		}
+		return nil, err
+	}
+	if err := dec.Decode(new(any)); !errors.Is(err, io.EOF) {
+		return nil, errors.New("body must contain a single YAML document")
+	}
+	v, err := jsonCompatible(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// jsonCompatible checks that every mapping in a decoded YAML value has
+// string keys, returning the value with them as map[string]any
+func jsonCompatible(v any) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, elem := range v {
+			var err error
+			if v[k], err = jsonCompatible(elem); err != nil {
+				return nil, err
+			}
+		}
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, elem := range v {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("mapping key %v is not a string", k)
+			}
+			var err error
+			if m[key], err = jsonCompatible(elem); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case []any:
+		for i, elem := range v {
+			var err error
+			if v[i], err = jsonCompatible(elem); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+// readYAML reads a YAML body and returns it converted to JSON. The returned
+// error is an *httpError.
+func readYAML(body io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, jsonBodyError(err)
+	}
+	converted, err := yamlToJSON(data)
+	if err != nil {
+		return nil, &httpError{status: http.StatusBadRequest, message: "invalid YAML body: " + err.Error()}
+	}
+	return converted, nil
+}
+
+// decodeBulkYAML decodes a YAML sequence of items from body like
+// decodeBulkItems does a JSON array. The whole body is read and converted
+// before the first item is decoded.
+func decodeBulkYAML(ctx context.Context, body io.Reader, jobs chan<- *bulkCheck) ([]*bulkCheck, error) {
+	data, err := readYAML(body)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBulkItems(ctx, bytes.NewReader(data), jobs)
+}
+
+// jsonToYAML converts a JSON value to YAML, keeping the order of object
+// fields and writing numbers as they are
+func jsonToYAML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := yamlNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlNode reads the next JSON value from dec as a YAML node
+func yamlNode(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if tok == '{' {
+			node.Kind, node.Tag = yaml.MappingNode, "!!map"
+		}
+		for dec.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			elem, err := yamlNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, elem)
+		}
+		// The closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: tok}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(tok.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: tok.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(tok)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
+
+// writeYAML encodes v as a YAML response body with the given status code,
+// by way of its JSON encoding
+func (s *Server) writeYAML(w http.ResponseWriter, r *http.Request, status int, v any) {
+	data, err := json.Marshal(v)
+	if err == nil {
+		data, err = jsonToYAML(data)
+	}
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to encode YAML response", "error", err, "request_id", RequestIDFromContext(r.Context()))
+		status, data = http.StatusInternalServerError, []byte("error: "+internalErrorMessage+"\n")
+	}
+	w.Header().Set("Content-Type", yamlContentType)
+	w.WriteHeader(status)
+	if _, err := w.Write(data); err != nil {
+		s.logger.WarnContext(r.Context(), "failed to write YAML response", "error", err, "request_id", RequestIDFromContext(r.Context()))
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// sendYAML sends body to target as application/yaml
+func sendYAML(t *testing.T, srv *Server, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", yamlContentType)
+	rec := httptest.NewRecorder()
+	srv.routes().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPI_YAMLRoundTrip(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+
+	rec := sendYAML(t, srv, http.MethodPost, "/api/items", "# a comment\ncolor: teal\nshape: square\ncategory: C\n")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d: %s", rec.Code, rec.Body)
+	}
+	rec = sendYAML(t, srv, http.MethodPatch, "/api/items/4", "shape: circle\n")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = apiRequest(t, srv, "/api/items/4", "application/json")
+	var fromJSON itemResponse
+	if err := json.NewDecoder(rec.Body).Decode(&fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	want := itemstore.Item{ID: itemstore.IntID(4), Color: "teal", Shape: "circle", Category: "C", CreatedAt: fromJSON.Item.CreatedAt}
+	if fromJSON.Item != want || want.CreatedAt.IsZero() {
+		t.Errorf("JSON item = %+v, want %+v", fromJSON.Item, want)
+	}
+
+	rec = apiRequest(t, srv, "/api/items/4", "application/yaml")
+	if got := rec.Header().Get("Content-Type"); rec.Code != http.StatusOK || got != yamlContentType {
+		t.Fatalf("YAML GET = %d %q: %s", rec.Code, got, rec.Body)
+	}
+	// Both bodies decode to the same generic value
+	var yamlBody, jsonBody any
+	if err := yaml.Unmarshal(rec.Body.Bytes(), &yamlBody); err != nil {
+		t.Fatal(err)
+	}
+	jsonBytes, _ := json.Marshal(yamlBody)
+	yamlJSON := string(jsonBytes)
+	rec = apiRequest(t, srv, "/api/items/4", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &jsonBody); err != nil {
+		t.Fatal(err)
+	}
+	jsonBytes, _ = json.Marshal(jsonBody)
+	if yamlJSON != string(jsonBytes) {
+		t.Errorf("YAML body %s differs from the JSON body %s", yamlJSON, jsonBytes)
+	}
+
+	rec = apiRequest(t, srv, "/api/items?filter=category:C&format=yaml", "")
+	if !strings.HasPrefix(rec.Body.String(), "items:\n  - id: 4\n") || !strings.Contains(rec.Body.String(), "meta:\n  count: 1\n") {
+		t.Errorf("YAML list =\n%s", rec.Body)
+	}
+}
+
+func TestAPI_YAMLInvalid(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+	tests := []struct {
+		name, body string
+		want       string
+	}{
+		{"unknown field", "color: red\nshape: circle\ncategory: A\nweight: 5\n", `unknown field \"weight\"`},
+		{"wrong type", "id: [4]\ncolor: red\nshape: circle\ncategory: A\n", "invalid YAML body"},
+		{"two documents", "color: red\n---\ncolor: blue\n", "single YAML document"},
+		{"non-string key", "1: red\n", "not a string"},
+		{"malformed", "color: [red\n", "invalid YAML body"},
+		{"empty", "", "empty document"},
+	}
+	for _, tt := range tests {
+		rec := sendYAML(t, srv, http.MethodPost, "/api/items", tt.body)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: status = %d, body %s; want 400 mentioning %q", tt.name, rec.Code, rec.Body, tt.want)
+		}
+	}
+	if srv.store.Len() != 3 {
+		t.Errorf("store has %d items after rejected YAML, want 3", srv.store.Len())
+	}
+
+	rec := apiRequest(t, srv, "/api/items/99", "application/yaml")
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "status: 404\n") || !strings.Contains(rec.Body.String(), "requestId: req-msgpack\n") {
+		t.Errorf("YAML 404 = %d:\n%s", rec.Code, rec.Body)
+	}
+}
+
+func TestAPI_BulkYAML(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowUnauthenticatedWrites = true
+	srv := newTestServer(t, cfg)
+	body := "- {color: teal, shape: square, category: C}\n- color: pink\n  shape: circle\n  category: D\n"
+	rec := postBulkAs(t.Context(), srv, "atomic", yamlContentType, body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if got, _ := srv.store.Get(itemstore.IntID(5)); got.Color != "pink" {
+		t.Errorf("item 5 = %+v, want the pink circle", got)
+	}
+}
+
+func TestJSONToYAML(t *testing.T) {
+	got, err := jsonToYAML([]byte(`{"b":"123","a":[1,2.5,true,null],"c":{}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Field order is kept and the string stays a string
+	want := "b: \"123\"\na:\n  - 1\n  - 2.5\n  - true\n  - null\nc: {}\n"
+	if string(got) != want {
+		t.Errorf("jsonToYAML() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,195 @@
+// Package shortlink stores short random tokens that stand for longer
+// targets, such as the query string of a dashboard view, until they expire.
+package shortlink
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// tokenBytes is the amount of randomness in a token; 64 bits keeps tokens
+// short while making them impractical to guess
+const tokenBytes = 8
+
+// ErrFull is returned by Create when the store already holds its limit of
+// unexpired links
+var ErrFull = errors.New("too many short links")
+
+// Link is one stored short link
+type Link struct {
+	Token     string    `json:"token"`
+	Target    string    `json:"target"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Store holds short links in memory and, when given a file, saves them there
+// after every change so they survive restarts. It is safe for concurrent use.
+type Store struct {
+	mu    sync.Mutex
+	links map[string]Link
+	ttl   time.Duration
+	limit int
+	path  string
+	now   func() time.Time
+}
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithClock replaces time.Now, which lets tests control expiry
+func WithClock(now func() time.Time) Option {
+	return func(s *Store) { s.now = now }
+}
+
+// WithTTL sets how long a link lives after it is created
+func WithTTL(ttl time.Duration) Option {
+	return func(s *Store) { s.ttl = ttl }
+}
+
+// WithLimit caps the number of unexpired links held at once
+func WithLimit(n int) Option {
+	return func(s *Store) { s.limit = n }
+}
+
+// WithFile persists the links as JSON at path. Links already saved there are
+// loaded by New.
+func WithFile(path string) Option {
+	return func(s *Store) { s.path = path }
+}
+
+// New creates a Store, loading any unexpired links from its file
+func New(opts ...Option) (*Store, error) {
+	s := &Store{
+		links: make(map[string]Link),
+		ttl:   30 * 24 * time.Hour,
+		limit: 10000,
+		now:   time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read short links: %w", err)
+	}
+	var links []Link
+	if err := json.Unmarshal(data, &links); err != nil {
+		return nil, fmt.Errorf("parse short links in %s: %w", s.path, err)
+	}
+	now := s.now()
+	for _, l := range links {
+		if now.Before(l.ExpiresAt) {
+			s.links[l.Token] = l
+		}
+	}
+	return s, nil
+}
+
+// Create stores target under a new random token
+func (s *Store) Create(target string) (Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.pruneLocked(now)
+	if len(s.links) >= s.limit {
+		return Link{}, ErrFull
+	}
+
+	var token string
+	for {
+		b := make([]byte, tokenBytes)
+		if _, err := rand.Read(b); err != nil {
+			return Link{}, fmt.Errorf("generate token: %w", err)
+		}
+		token = base64.RawURLEncoding.EncodeToString(b)
+		if _, taken := s.links[token]; !taken {
+			break
+		}
+	}
+
+	link := Link{Token: token, Target: target, ExpiresAt: now.Add(s.ttl)}
+	s.links[token] = link
+	if err := s.saveLocked(); err != nil {
+		delete(s.links, token)
+		return Link{}, err
+	}
+	return link, nil
+}
+
+// Resolve returns the target of token, or false when the token is unknown or
+// has expired
+func (s *Store) Resolve(token string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[token]
+	if !ok || !s.now().Before(link.ExpiresAt) {
+		return "", false
+	}
+	return link.Target, true
+}
+
+// Len returns the number of links held, including expired ones not yet
+// pruned
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.links)
+}
+
+// pruneLocked drops expired links
+func (s *Store) pruneLocked(now time.Time) {
+	for token, l := range s.links {
+		if !now.Before(l.ExpiresAt) {
+			delete(s.links, token)
+		}
+	}
+}
+
+// saveLocked writes the links to the store's file, if it has one, replacing
+// it atomically so a crash never leaves a truncated file behind
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	links := make([]Link, 0, len(s.links))
+	for _, l := range s.links {
+		links = append(links, l)
+	}
+	data, err := json.Marshal(links)
+	if err != nil {
+		return fmt.Errorf("encode short links: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("save short links: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("save short links: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("save short links: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("save short links: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package shortlink
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestStore_CreateResolve(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	s, err := New(WithClock(clock.Now), WithTTL(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link, err := s.Create("groupBy=color")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(link.Token) != 11 {
+		t.Errorf("token %q is not 11 characters", link.Token)
+	}
+	if !link.ExpiresAt.Equal(clock.t.Add(time.Hour)) {
+		t.Errorf("ExpiresAt = %v, want an hour from now", link.ExpiresAt)
+	}
+	other, _ := s.Create("groupBy=color")
+	if other.Token == link.Token {
+		t.Error("two links share a token")
+	}
+
+	if target, ok := s.Resolve(link.Token); !ok || target != "groupBy=color" {
+		t.Errorf("Resolve() = %q, %v", target, ok)
+	}
+	if _, ok := s.Resolve("unknown"); ok {
+		t.Error("Resolve() found an unknown token")
+	}
+
+	clock.Advance(time.Hour - time.Nanosecond)
+	if _, ok := s.Resolve(link.Token); !ok {
+		t.Error("link expired early")
+	}
+	clock.Advance(time.Nanosecond)
+	if _, ok := s.Resolve(link.Token); ok {
+		t.Error("expired link still resolves")
+	}
+}
+
+func TestStore_Limit(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	s, _ := New(WithClock(clock.Now), WithTTL(time.Minute), WithLimit(2))
+
+	for range 2 {
+		if _, err := s.Create("x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Create("x"); !errors.Is(err, ErrFull) {
+		t.Fatalf("Create() error = %v, want ErrFull", err)
+	}
+
+	// Expired links make room
+	clock.Advance(time.Minute)
+	if _, err := s.Create("x"); err != nil {
+		t.Errorf("Create() after expiry error = %v", err)
+	}
+	if s.Len() != 1 {
+		t.Errorf("Len() = %d, want the expired links pruned", s.Len())
+	}
+}
+
+func TestStore_File(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	path := filepath.Join(t.TempDir(), "items.shares.json")
+
+	s, err := New(WithFile(path), WithClock(clock.Now), WithTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("New() with a missing file error = %v", err)
+	}
+	short, _ := s.Create("sortBy=id")
+	clock.Advance(30 * time.Minute)
+	long, _ := s.Create("groupBy=none")
+
+	clock.Advance(45 * time.Minute)
+	reloaded, err := New(WithFile(path), WithClock(clock.Now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reloaded.Resolve(short.Token); ok {
+		t.Error("expired link was loaded")
+	}
+	if target, ok := reloaded.Resolve(long.Token); !ok || target != "groupBy=none" {
+		t.Errorf("Resolve() after reload = %q, %v", target, ok)
+	}
+	if reloaded.Len() != 1 {
+		t.Errorf("Len() = %d, want only the unexpired link", reloaded.Len())
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(WithFile(path)); err == nil {
+		t.Error("New() accepted a corrupt file")
+	}
+}
@@ -0,0 +1,173 @@
+// Package source keeps an item store in sync with a remote JSON document.
+package source
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// maxBodyBytes bounds the size of a fetched document
+const maxBodyBytes = 32 << 20
+
+// Poller periodically fetches a JSON array of items and replaces the store's
+// contents with it. Failed fetches leave the last good data in place.
+type Poller struct {
+	url      string
+	interval time.Duration
+	store    itemstore.Store
+	client   *http.Client
+	logger   *slog.Logger
+
+	mu     sync.Mutex
+	etag   string
+	status Status
+}
+
+// Status summarizes the poller's recent history
+type Status struct {
+	// Failures counts every failed fetch since startup
+	Failures int
+	// ConsecutiveFailures resets to zero on the next success
+	ConsecutiveFailures int
+	LastError           error
+	LastSuccess         time.Time
+}
+
+// Option configures a Poller
+type Option func(*Poller)
+
+// WithHTTPClient replaces the client used to fetch the source
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Poller) { p.client = c }
+}
+
+// WithLogger sets where sync results are reported
+func WithLogger(l *slog.Logger) Option {
+	return func(p *Poller) { p.logger = l }
+}
+
+// New creates a Poller that syncs store from url every interval
+func New(url string, interval time.Duration, store itemstore.Store, opts ...Option) *Poller {
+	p := &Poller{
+		url:      url,
+		interval: interval,
+		store:    store,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		logger:   slog.New(slog.DiscardHandler),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Run fetches the source immediately and then on every tick until ctx is
+// canceled
+func (p *Poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.Sync(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync performs one fetch and, if the document changed, replaces the store's
+// items. It reports whether the store was updated.
+func (p *Poller) Sync(ctx context.Context) (bool, error) {
+	updated, etag, err := p.fetch(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, err
+		}
+		p.status.Failures++
+		p.status.ConsecutiveFailures++
+		p.status.LastError = err
+		p.logger.Error("source sync failed",
+			"url", p.url,
+			"error", err,
+			"consecutive_failures", p.status.ConsecutiveFailures)
+		return false, err
+	}
+
+	p.status.ConsecutiveFailures = 0
+	p.status.LastError = nil
+	p.status.LastSuccess = time.Now()
+	if updated {
+		p.etag = etag
+		p.logger.Info("source synced", "url", p.url, "items", p.store.Len())
+	} else {
+		p.logger.Debug("source unchanged", "url", p.url)
+	}
+	return updated, nil
+}
+
+// fetch downloads and applies the document. A 304 is not an error and leaves
+// the store untouched.
+func (p *Poller) fetch(ctx context.Context) (updated bool, etag string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return false, "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	p.mu.Lock()
+	if p.etag != "" {
+		req.Header.Set("If-None-Match", p.etag)
+	}
+	p.mu.Unlock()
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false, "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return false, "", nil
+	default:
+		return false, "", fmt.Errorf("source responded %s", resp.Status)
+	}
+
+	items, err := itemstore.LoadJSON(http.MaxBytesReader(nil, resp.Body, maxBodyBytes))
+	if err != nil {
+		return false, "", err
+	}
+	if _, err := p.store.SetItems(items); err != nil {
+		return false, "", err
+	}
+	return true, resp.Header.Get("ETag"), nil
+}
+
+// Status returns a snapshot of the poller's history
+func (p *Poller) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+// Check reports an error while the most recent fetch failed, suitable for a
+// readiness probe
+func (p *Poller) Check(context.Context) error {
+	st := p.Status()
+	if st.ConsecutiveFailures == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d consecutive failures (%d total): %w", st.ConsecutiveFailures, st.Failures, st.LastError)
+}
@@ -0,0 +1,166 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// fakeSource serves a JSON document with an ETag derived from its version and
+// answers 304 when the client already has it
+type fakeSource struct {
+	mu       sync.Mutex
+	body     string
+	version  int
+	status   int
+	requests int
+	notMod   int
+}
+
+func (f *fakeSource) set(body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.body = body
+	f.version++
+}
+
+func (f *fakeSource) fail(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = status
+}
+
+func (f *fakeSource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		return
+	}
+	etag := fmt.Sprintf(`"v%d"`, f.version)
+	if r.Header.Get("If-None-Match") == etag {
+		f.notMod++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	fmt.Fprint(w, f.body)
+}
+
+func newTestStore(t *testing.T) *itemstore.ItemStore {
+	t.Helper()
+	store, err := itemstore.New([]itemstore.Item{{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestPoller_Sync(t *testing.T) {
+	src := &fakeSource{}
+	src.set(`[{"id":1,"color":"blue","shape":"square","category":"A"},{"id":2,"color":"green","shape":"circle","category":"B"}]`)
+	ts := httptest.NewServer(src)
+	defer ts.Close()
+
+	store := newTestStore(t)
+	p := New(ts.URL, time.Minute, store)
+	ctx := context.Background()
+
+	if updated, err := p.Sync(ctx); err != nil || !updated {
+		t.Fatalf("first Sync() = %v, %v; want an update", updated, err)
+	}
+	if store.Len() != 2 {
+		t.Fatalf("Len() = %d after sync, want 2", store.Len())
+	}
+
+	// Unchanged document: the ETag gets a 304 and the store is left alone
+	if updated, err := p.Sync(ctx); err != nil || updated {
+		t.Errorf("second Sync() = %v, %v; want no update", updated, err)
+	}
+	if src.notMod != 1 {
+		t.Errorf("source answered %d requests with 304, want 1", src.notMod)
+	}
+
+	src.set(`[{"id":3,"color":"red","shape":"triangle","category":"C"}]`)
+	if updated, err := p.Sync(ctx); err != nil || !updated {
+		t.Fatalf("Sync() after change = %v, %v; want an update", updated, err)
+	}
+	if item, err := store.Get(itemstore.IntID(3)); err != nil || item.Shape != "triangle" {
+		t.Errorf("Get(3) = %+v, %v; want the new item", item, err)
+	}
+}
+
+func TestPoller_FailureKeepsLastGoodData(t *testing.T) {
+	src := &fakeSource{}
+	src.set(`[{"id":1,"color":"blue","shape":"square","category":"A"}]`)
+	ts := httptest.NewServer(src)
+	defer ts.Close()
+
+	store := newTestStore(t)
+	p := New(ts.URL, time.Minute, store)
+	ctx := context.Background()
+	p.Sync(ctx)
+
+	src.fail(http.StatusBadGateway)
+	p.Sync(ctx)
+	src.fail(0)
+	src.set(`[{"id":1,"color":"","shape":"square","category":"A"}]`)
+	p.Sync(ctx)
+
+	if item, _ := store.Get(itemstore.IntID(1)); item.Color != "blue" {
+		t.Errorf("item 1 color = %q, want the last good value blue", item.Color)
+	}
+	st := p.Status()
+	if st.Failures != 2 || st.ConsecutiveFailures != 2 || st.LastError == nil {
+		t.Errorf("Status() = %+v, want 2 consecutive failures", st)
+	}
+	if err := p.Check(ctx); err == nil {
+		t.Error("Check() = nil while the source is failing")
+	}
+
+	src.set(`[{"id":1,"color":"green","shape":"square","category":"A"}]`)
+	p.Sync(ctx)
+	if err := p.Check(ctx); err != nil {
+		t.Errorf("Check() = %v after recovery, want nil", err)
+	}
+	if st := p.Status(); st.Failures != 2 || st.ConsecutiveFailures != 0 {
+		t.Errorf("Status() = %+v, want the total kept and the streak reset", st)
+	}
+}
+
+func TestPoller_RunStopsOnCancel(t *testing.T) {
+	src := &fakeSource{}
+	src.set(`[]`)
+	ts := httptest.NewServer(src)
+	defer ts.Close()
+
+	p := New(ts.URL, time.Millisecond, newTestStore(t))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after cancel")
+	}
+
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if src.requests < 2 {
+		t.Errorf("source polled %d times, want repeated polling", src.requests)
+	}
+}
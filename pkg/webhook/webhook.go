@@ -0,0 +1,275 @@
+// Package webhook delivers signed JSON event notifications to HTTP endpoints.
+//
+// Deliveries are queued and sent by a fixed pool of workers, so Send never
+// blocks. Network errors and 5xx responses are retried with exponential
+// backoff; once the attempts are exhausted the delivery is logged and dropped.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Request headers set on every delivery
+const (
+	SignatureHeader = "X-Dashboard-Signature"
+	EventHeader     = "X-Dashboard-Event"
+	DeliveryHeader  = "X-Dashboard-Delivery"
+)
+
+// Target is an endpoint that receives deliveries. Secret signs the payloads
+// sent to it.
+type Target struct {
+	URL    string
+	Secret string
+}
+
+// Event is a notification to deliver. Before and After are the affected
+// object's state around the change; either may be nil.
+type Event struct {
+	Type   string
+	Before any
+	After  any
+}
+
+// Payload is the JSON body POSTed to each target
+type Payload struct {
+	DeliveryID string    `json:"deliveryId"`
+	Event      string    `json:"event"`
+	Timestamp  time.Time `json:"timestamp"`
+	Before     any       `json:"before"`
+	After      any       `json:"after"`
+}
+
+// Dispatcher sends events to a fixed set of targets
+type Dispatcher struct {
+	targets     []Target
+	client      *http.Client
+	logger      *slog.Logger
+	now         func() time.Time
+	workers     int
+	queueSize   int
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+
+	queue  chan delivery
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+}
+
+type delivery struct {
+	target  Target
+	id      string
+	event   string
+	payload []byte
+}
+
+// Option configures a Dispatcher
+type Option func(*Dispatcher)
+
+// WithHTTPClient replaces the client used for deliveries
+func WithHTTPClient(c *http.Client) Option {
+	return func(d *Dispatcher) { d.client = c }
+}
+
+// WithLogger sets where failed deliveries are reported
+func WithLogger(l *slog.Logger) Option {
+	return func(d *Dispatcher) { d.logger = l }
+}
+
+// WithClock replaces time.Now for payload timestamps
+func WithClock(now func() time.Time) Option {
+	return func(d *Dispatcher) { d.now = now }
+}
+
+// WithConcurrency sets how many deliveries may be in flight at once
+func WithConcurrency(n int) Option {
+	return func(d *Dispatcher) { d.workers = max(n, 1) }
+}
+
+// WithQueueSize sets how many deliveries may wait for a worker before new
+// ones are dropped
+func WithQueueSize(n int) Option {
+	return func(d *Dispatcher) { d.queueSize = max(n, 1) }
+}
+
+// WithRetry sets the number of attempts per delivery and the backoff between
+// them, which doubles after every failure up to maxDelay
+func WithRetry(attempts int, baseDelay, maxDelay time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = max(attempts, 1)
+		d.baseDelay = baseDelay
+		d.maxDelay = maxDelay
+	}
+}
+
+// New creates a Dispatcher for targets and starts its workers
+func New(targets []Target, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		targets:     targets,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		logger:      slog.New(slog.DiscardHandler),
+		now:         time.Now,
+		workers:     4,
+		queueSize:   1000,
+		maxAttempts: 5,
+		baseDelay:   time.Second,
+		maxDelay:    time.Minute,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	d.queue = make(chan delivery, d.queueSize)
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	for range d.workers {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+// Send queues e for delivery to every target. It never blocks; if the queue
+// is full the delivery is logged and dropped.
+func (d *Dispatcher) Send(e Event) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return
+	}
+
+	for _, t := range d.targets {
+		id := newDeliveryID()
+		body, err := json.Marshal(Payload{
+			DeliveryID: id,
+			Event:      e.Type,
+			Timestamp:  d.now().UTC(),
+			Before:     e.Before,
+			After:      e.After,
+		})
+		if err != nil {
+			d.logger.Error("webhook payload encoding failed", "event", e.Type, "error", err)
+			return
+		}
+
+		select {
+		case d.queue <- delivery{target: t, id: id, event: e.Type, payload: body}:
+		default:
+			d.logger.Warn("webhook queue full, dropping delivery", "url", t.URL, "event", e.Type, "delivery_id", id)
+		}
+	}
+}
+
+// Close stops accepting events and waits for queued deliveries to finish.
+// When ctx ends first, pending retries are abandoned.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for del := range d.queue {
+		d.deliver(del)
+	}
+}
+
+// deliver sends one delivery, retrying retryable failures with backoff
+func (d *Dispatcher) deliver(del delivery) {
+	delay := d.baseDelay
+	var err error
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		var retry bool
+		if retry, err = d.post(del); err == nil {
+			return
+		}
+		if !retry || attempt == d.maxAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-d.ctx.Done():
+			d.logger.Warn("webhook delivery abandoned at shutdown", "url", del.target.URL, "event", del.event, "delivery_id", del.id)
+			return
+		}
+		delay = min(delay*2, d.maxDelay)
+	}
+	d.logger.Error("webhook delivery failed", "url", del.target.URL, "event", del.event, "delivery_id", del.id, "error", err)
+}
+
+// post makes a single delivery attempt and reports whether a failure is worth
+// retrying
+func (d *Dispatcher) post(del delivery) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, del.target.URL, bytes.NewReader(del.payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, del.event)
+	req.Header.Set(DeliveryHeader, del.id)
+	req.Header.Set(SignatureHeader, Sign(del.target.Secret, del.payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return true, fmt.Errorf("target responded %s", resp.Status)
+	case resp.StatusCode >= 300:
+		return false, fmt.Errorf("target responded %s", resp.Status)
+	}
+	return false, nil
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by the
+// hex HMAC-SHA256 of body keyed with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newDeliveryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type recordedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newTarget starts a server that records requests and answers with the
+// statuses in order, repeating the last one
+func newTarget(t *testing.T, statuses ...int) (*httptest.Server, func() []recordedRequest) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		received []recordedRequest
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, recordedRequest{header: r.Header.Clone(), body: body})
+		status := statuses[min(len(received), len(statuses))-1]
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recordedRequest(nil), received...)
+	}
+}
+
+func fastRetry(attempts int) Option {
+	return WithRetry(attempts, time.Millisecond, 4*time.Millisecond)
+}
+
+func TestDispatcher_PayloadAndSignature(t *testing.T) {
+	ts, received := newTarget(t, http.StatusOK)
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	d := New([]Target{{URL: ts.URL, Secret: "s3cret"}}, WithClock(func() time.Time { return now }), WithHTTPClient(ts.Client()))
+
+	d.Send(Event{Type: "item.updated", Before: map[string]int{"id": 1}, After: map[string]int{"id": 2}})
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	reqs := received()
+	if len(reqs) != 1 {
+		t.Fatalf("received %d requests, want 1", len(reqs))
+	}
+	req := reqs[0]
+
+	var p struct {
+		DeliveryID string         `json:"deliveryId"`
+		Event      string         `json:"event"`
+		Timestamp  time.Time      `json:"timestamp"`
+		Before     map[string]int `json:"before"`
+		After      map[string]int `json:"after"`
+	}
+	if err := json.Unmarshal(req.body, &p); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if p.Event != "item.updated" || !p.Timestamp.Equal(now) || p.Before["id"] != 1 || p.After["id"] != 2 || p.DeliveryID == "" {
+		t.Errorf("payload = %+v", p)
+	}
+	if got := req.header.Get(SignatureHeader); got != Sign("s3cret", req.body) {
+		t.Errorf("%s = %q, want %q", SignatureHeader, got, Sign("s3cret", req.body))
+	}
+	if got := req.header.Get(DeliveryHeader); got != p.DeliveryID {
+		t.Errorf("%s = %q, want %q", DeliveryHeader, got, p.DeliveryID)
+	}
+	if got := req.header.Get(EventHeader); got != "item.updated" {
+		t.Errorf("%s = %q, want item.updated", EventHeader, got)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// The widely published HMAC-SHA256 example for key "key"
+	got := Sign("key", []byte("The quick brown fox jumps over the lazy dog"))
+	want := "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+}
+
+func TestDispatcher_Retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int
+	}{
+		{"server error then success", []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK}, 3},
+		{"client error is not retried", []int{http.StatusBadRequest}, 1},
+		{"gives up after the cap", []int{http.StatusServiceUnavailable}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, received := newTarget(t, tt.statuses...)
+			d := New([]Target{{URL: ts.URL, Secret: "x"}}, fastRetry(4))
+
+			d.Send(Event{Type: "item.created"})
+			d.Close(context.Background())
+
+			reqs := received()
+			if len(reqs) != tt.wantAttempts {
+				t.Fatalf("attempts = %d, want %d", len(reqs), tt.wantAttempts)
+			}
+			id := reqs[0].header.Get(DeliveryHeader)
+			for _, r := range reqs {
+				if r.header.Get(DeliveryHeader) != id {
+					t.Errorf("retry changed the delivery ID")
+				}
+			}
+		})
+	}
+}
+
+func TestDispatcher_NetworkErrorRetried(t *testing.T) {
+	var attempts atomic.Int32
+	client := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		attempts.Add(1)
+		return nil, io.ErrUnexpectedEOF
+	})}
+	d := New([]Target{{URL: "http://hooks.invalid", Secret: "x"}}, WithHTTPClient(client), fastRetry(3))
+
+	d.Send(Event{Type: "item.deleted"})
+	d.Close(context.Background())
+
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("attempts = %d, want 3", got)
+	}
+}
+
+func TestDispatcher_CloseAbandonsRetries(t *testing.T) {
+	ts, _ := newTarget(t, http.StatusInternalServerError)
+	d := New([]Target{{URL: ts.URL}}, WithRetry(5, time.Hour, time.Hour))
+	d.Send(Event{Type: "item.created"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.Close(ctx); err == nil {
+		t.Error("Close() = nil, want the context error while a retry is pending")
+	}
+
+	d.Send(Event{Type: "ignored"})
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
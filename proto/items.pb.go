@@ -21,13 +21,67 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// Item represents an item in the dashboard
+type ItemEvent_Type int32
+
+const (
+	ItemEvent_TYPE_UNSPECIFIED ItemEvent_Type = 0
+	ItemEvent_TYPE_CREATED     ItemEvent_Type = 1
+	ItemEvent_TYPE_UPDATED     ItemEvent_Type = 2
+	ItemEvent_TYPE_DELETED     ItemEvent_Type = 3
+)
+
+// Enum value maps for ItemEvent_Type.
+var (
+	ItemEvent_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "TYPE_CREATED",
+		2: "TYPE_UPDATED",
+		3: "TYPE_DELETED",
+	}
+	ItemEvent_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"TYPE_CREATED":     1,
+		"TYPE_UPDATED":     2,
+		"TYPE_DELETED":     3,
+	}
+)
+
+func (x ItemEvent_Type) Enum() *ItemEvent_Type {
+	p := new(ItemEvent_Type)
+	*p = x
+	return p
+}
+
+func (x ItemEvent_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ItemEvent_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_items_proto_enumTypes[0].Descriptor()
+}
+
+func (ItemEvent_Type) Type() protoreflect.EnumType {
+	return &file_proto_items_proto_enumTypes[0]
+}
+
+func (x ItemEvent_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ItemEvent_Type.Descriptor instead.
+func (ItemEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return file_proto_items_proto_rawDescGZIP(), []int{13, 0}
+}
+
+// Item represents an item in the dashboard. Items of a store with string IDs
+// carry string_id instead of id.
 type Item struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
 	Color         string                 `protobuf:"bytes,2,opt,name=color,proto3" json:"color,omitempty"`
 	Shape         string                 `protobuf:"bytes,3,opt,name=shape,proto3" json:"shape,omitempty"`
 	Category      string                 `protobuf:"bytes,4,opt,name=category,proto3" json:"category,omitempty"`
+	StringId      string                 `protobuf:"bytes,5,opt,name=string_id,json=stringId,proto3" json:"string_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -90,6 +144,13 @@ func (x *Item) GetCategory() string {
 	return ""
 }
 
+func (x *Item) GetStringId() string {
+	if x != nil {
+		return x.StringId
+	}
+	return ""
+}
+
 // GroupedItems represents a collection of items grouped by a property
 type GroupedItems struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -326,16 +387,444 @@ func (x *ErrorResponse) GetError() string {
 	return ""
 }
 
+// ListItemsRequest selects items whose properties equal every filter value,
+// keyed by "color", "shape", or "category"
+type ListItemsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filters       map[string]string      `protobuf:"bytes,1,rep,name=filters,proto3" json:"filters,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListItemsRequest) Reset() {
+	*x = ListItemsRequest{}
+	mi := &file_proto_items_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListItemsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListItemsRequest) ProtoMessage() {}
+
+func (x *ListItemsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_items_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListItemsRequest.ProtoReflect.Descriptor instead.
+func (*ListItemsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_items_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListItemsRequest) GetFilters() map[string]string {
+	if x != nil {
+		return x.Filters
+	}
+	return nil
+}
+
+// ListItemsResponse holds the matching items in store order
+type ListItemsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*Item                `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListItemsResponse) Reset() {
+	*x = ListItemsResponse{}
+	mi := &file_proto_items_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListItemsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListItemsResponse) ProtoMessage() {}
+
+func (x *ListItemsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_items_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListItemsResponse.ProtoReflect.Descriptor instead.
+func (*ListItemsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_items_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListItemsResponse) GetItems() []*Item {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// GetItemRequest names the item to fetch by id, or by string_id in a store
+// with string IDs
+type GetItemRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	StringId      string                 `protobuf:"bytes,2,opt,name=string_id,json=stringId,proto3" json:"string_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetItemRequest) Reset() {
+	*x = GetItemRequest{}
+	mi := &file_proto_items_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetItemRequest) ProtoMessage() {}
+
+func (x *GetItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_items_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetItemRequest.ProtoReflect.Descriptor instead.
+func (*GetItemRequest) Descriptor() ([]byte, []int) {
+	return file_proto_items_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetItemRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *GetItemRequest) GetStringId() string {
+	if x != nil {
+		return x.StringId
+	}
+	return ""
+}
+
+// CreateItemRequest adds an item; an item without an id or string_id is
+// assigned one automatically
+type CreateItemRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Item          *Item                  `protobuf:"bytes,1,opt,name=item,proto3" json:"item,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateItemRequest) Reset() {
+	*x = CreateItemRequest{}
+	mi := &file_proto_items_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateItemRequest) ProtoMessage() {}
+
+func (x *CreateItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_items_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateItemRequest.ProtoReflect.Descriptor instead.
+func (*CreateItemRequest) Descriptor() ([]byte, []int) {
+	return file_proto_items_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CreateItemRequest) GetItem() *Item {
+	if x != nil {
+		return x.Item
+	}
+	return nil
+}
+
+// UpdateItemRequest replaces the stored item with the same id
+type UpdateItemRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Item          *Item                  `protobuf:"bytes,1,opt,name=item,proto3" json:"item,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateItemRequest) Reset() {
+	*x = UpdateItemRequest{}
+	mi := &file_proto_items_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateItemRequest) ProtoMessage() {}
+
+func (x *UpdateItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_items_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateItemRequest.ProtoReflect.Descriptor instead.
+func (*UpdateItemRequest) Descriptor() ([]byte, []int) {
+	return file_proto_items_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *UpdateItemRequest) GetItem() *Item {
+	if x != nil {
+		return x.Item
+	}
+	return nil
+}
+
+// DeleteItemRequest names the item to remove by id, or by string_id in a
+// store with string IDs
+type DeleteItemRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	StringId      string                 `protobuf:"bytes,2,opt,name=string_id,json=stringId,proto3" json:"string_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteItemRequest) Reset() {
+	*x = DeleteItemRequest{}
+	mi := &file_proto_items_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteItemRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteItemRequest) ProtoMessage() {}
+
+func (x *DeleteItemRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_items_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteItemRequest.ProtoReflect.Descriptor instead.
+func (*DeleteItemRequest) Descriptor() ([]byte, []int) {
+	return file_proto_items_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DeleteItemRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *DeleteItemRequest) GetStringId() string {
+	if x != nil {
+		return x.StringId
+	}
+	return ""
+}
+
+// DeleteItemResponse is empty; success is signalled by the status
+type DeleteItemResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteItemResponse) Reset() {
+	*x = DeleteItemResponse{}
+	mi := &file_proto_items_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteItemResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteItemResponse) ProtoMessage() {}
+
+func (x *DeleteItemResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_items_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteItemResponse.ProtoReflect.Descriptor instead.
+func (*DeleteItemResponse) Descriptor() ([]byte, []int) {
+	return file_proto_items_proto_rawDescGZIP(), []int{11}
+}
+
+// StreamEventsRequest opens an event stream
+type StreamEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_proto_items_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_items_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_items_proto_rawDescGZIP(), []int{12}
+}
+
+// ItemEvent describes one mutation of the store
+type ItemEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  ItemEvent_Type         `protobuf:"varint,1,opt,name=type,proto3,enum=dashboard.ItemEvent_Type" json:"type,omitempty"`
+	// item is the item after the change, or the removed item for deletions
+	Item *Item `protobuf:"bytes,2,opt,name=item,proto3" json:"item,omitempty"`
+	// previous is the item before an update
+	Previous      *Item `protobuf:"bytes,3,opt,name=previous,proto3" json:"previous,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ItemEvent) Reset() {
+	*x = ItemEvent{}
+	mi := &file_proto_items_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ItemEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ItemEvent) ProtoMessage() {}
+
+func (x *ItemEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_items_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ItemEvent.ProtoReflect.Descriptor instead.
+func (*ItemEvent) Descriptor() ([]byte, []int) {
+	return file_proto_items_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ItemEvent) GetType() ItemEvent_Type {
+	if x != nil {
+		return x.Type
+	}
+	return ItemEvent_TYPE_UNSPECIFIED
+}
+
+func (x *ItemEvent) GetItem() *Item {
+	if x != nil {
+		return x.Item
+	}
+	return nil
+}
+
+func (x *ItemEvent) GetPrevious() *Item {
+	if x != nil {
+		return x.Previous
+	}
+	return nil
+}
+
 var File_proto_items_proto protoreflect.FileDescriptor
 
 const file_proto_items_proto_rawDesc = "" +
 	"\n" +
-	"\x11proto/items.proto\x12\tdashboard\"^\n" +
+	"\x11proto/items.proto\x12\tdashboard\"{\n" +
 	"\x04Item\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x14\n" +
 	"\x05color\x18\x02 \x01(\tR\x05color\x12\x14\n" +
 	"\x05shape\x18\x03 \x01(\tR\x05shape\x12\x1a\n" +
-	"\bcategory\x18\x04 \x01(\tR\bcategory\"p\n" +
+	"\bcategory\x18\x04 \x01(\tR\bcategory\x12\x1b\n" +
+	"\tstring_id\x18\x05 \x01(\tR\bstringId\"p\n" +
 	"\fGroupedItems\x12\x1d\n" +
 	"\n" +
 	"group_name\x18\x01 \x01(\tR\tgroupName\x12\x1a\n" +
@@ -353,7 +842,45 @@ const file_proto_items_proto_rawDesc = "" +
 	"all_shapes\x18\x03 \x03(\tR\tallShapes\x12%\n" +
 	"\x0eall_categories\x18\x04 \x03(\tR\rallCategories\"%\n" +
 	"\rErrorResponse\x12\x14\n" +
-	"\x05error\x18\x01 \x01(\tR\x05errorB#Z!github.com/elodin/dashboard/protob\x06proto3"
+	"\x05error\x18\x01 \x01(\tR\x05error\"\x92\x01\n" +
+	"\x10ListItemsRequest\x12B\n" +
+	"\afilters\x18\x01 \x03(\v2(.dashboard.ListItemsRequest.FiltersEntryR\afilters\x1a:\n" +
+	"\fFiltersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\":\n" +
+	"\x11ListItemsResponse\x12%\n" +
+	"\x05items\x18\x01 \x03(\v2\x0f.dashboard.ItemR\x05items\"=\n" +
+	"\x0eGetItemRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x1b\n" +
+	"\tstring_id\x18\x02 \x01(\tR\bstringId\"8\n" +
+	"\x11CreateItemRequest\x12#\n" +
+	"\x04item\x18\x01 \x01(\v2\x0f.dashboard.ItemR\x04item\"8\n" +
+	"\x11UpdateItemRequest\x12#\n" +
+	"\x04item\x18\x01 \x01(\v2\x0f.dashboard.ItemR\x04item\"@\n" +
+	"\x11DeleteItemRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x1b\n" +
+	"\tstring_id\x18\x02 \x01(\tR\bstringId\"\x14\n" +
+	"\x12DeleteItemResponse\"\x15\n" +
+	"\x13StreamEventsRequest\"\xe0\x01\n" +
+	"\tItemEvent\x12-\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x19.dashboard.ItemEvent.TypeR\x04type\x12#\n" +
+	"\x04item\x18\x02 \x01(\v2\x0f.dashboard.ItemR\x04item\x12+\n" +
+	"\bprevious\x18\x03 \x01(\v2\x0f.dashboard.ItemR\bprevious\"R\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fTYPE_CREATED\x10\x01\x12\x10\n" +
+	"\fTYPE_UPDATED\x10\x02\x12\x10\n" +
+	"\fTYPE_DELETED\x10\x032\x99\x03\n" +
+	"\vItemService\x12F\n" +
+	"\tListItems\x12\x1b.dashboard.ListItemsRequest\x1a\x1c.dashboard.ListItemsResponse\x125\n" +
+	"\aGetItem\x12\x19.dashboard.GetItemRequest\x1a\x0f.dashboard.Item\x12;\n" +
+	"\n" +
+	"CreateItem\x12\x1c.dashboard.CreateItemRequest\x1a\x0f.dashboard.Item\x12;\n" +
+	"\n" +
+	"UpdateItem\x12\x1c.dashboard.UpdateItemRequest\x1a\x0f.dashboard.Item\x12I\n" +
+	"\n" +
+	"DeleteItem\x12\x1c.dashboard.DeleteItemRequest\x1a\x1d.dashboard.DeleteItemResponse\x12F\n" +
+	"\fStreamEvents\x12\x1e.dashboard.StreamEventsRequest\x1a\x14.dashboard.ItemEvent0\x01B(Z&github.com/ElodinLaarz/dashboard/protob\x06proto3"
 
 var (
 	file_proto_items_proto_rawDescOnce sync.Once
@@ -367,22 +894,53 @@ func file_proto_items_proto_rawDescGZIP() []byte {
 	return file_proto_items_proto_rawDescData
 }
 
-var file_proto_items_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_proto_items_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_items_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_proto_items_proto_goTypes = []any{
-	(*Item)(nil),             // 0: dashboard.Item
-	(*GroupedItems)(nil),     // 1: dashboard.GroupedItems
-	(*GetItemsRequest)(nil),  // 2: dashboard.GetItemsRequest
-	(*GetItemsResponse)(nil), // 3: dashboard.GetItemsResponse
-	(*ErrorResponse)(nil),    // 4: dashboard.ErrorResponse
+	(ItemEvent_Type)(0),         // 0: dashboard.ItemEvent.Type
+	(*Item)(nil),                // 1: dashboard.Item
+	(*GroupedItems)(nil),        // 2: dashboard.GroupedItems
+	(*GetItemsRequest)(nil),     // 3: dashboard.GetItemsRequest
+	(*GetItemsResponse)(nil),    // 4: dashboard.GetItemsResponse
+	(*ErrorResponse)(nil),       // 5: dashboard.ErrorResponse
+	(*ListItemsRequest)(nil),    // 6: dashboard.ListItemsRequest
+	(*ListItemsResponse)(nil),   // 7: dashboard.ListItemsResponse
+	(*GetItemRequest)(nil),      // 8: dashboard.GetItemRequest
+	(*CreateItemRequest)(nil),   // 9: dashboard.CreateItemRequest
+	(*UpdateItemRequest)(nil),   // 10: dashboard.UpdateItemRequest
+	(*DeleteItemRequest)(nil),   // 11: dashboard.DeleteItemRequest
+	(*DeleteItemResponse)(nil),  // 12: dashboard.DeleteItemResponse
+	(*StreamEventsRequest)(nil), // 13: dashboard.StreamEventsRequest
+	(*ItemEvent)(nil),           // 14: dashboard.ItemEvent
+	nil,                         // 15: dashboard.ListItemsRequest.FiltersEntry
 }
 var file_proto_items_proto_depIdxs = []int32{
-	0, // 0: dashboard.GroupedItems.items:type_name -> dashboard.Item
-	1, // 1: dashboard.GetItemsResponse.groups:type_name -> dashboard.GroupedItems
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	1,  // 0: dashboard.GroupedItems.items:type_name -> dashboard.Item
+	2,  // 1: dashboard.GetItemsResponse.groups:type_name -> dashboard.GroupedItems
+	15, // 2: dashboard.ListItemsRequest.filters:type_name -> dashboard.ListItemsRequest.FiltersEntry
+	1,  // 3: dashboard.ListItemsResponse.items:type_name -> dashboard.Item
+	1,  // 4: dashboard.CreateItemRequest.item:type_name -> dashboard.Item
+	1,  // 5: dashboard.UpdateItemRequest.item:type_name -> dashboard.Item
+	0,  // 6: dashboard.ItemEvent.type:type_name -> dashboard.ItemEvent.Type
+	1,  // 7: dashboard.ItemEvent.item:type_name -> dashboard.Item
+	1,  // 8: dashboard.ItemEvent.previous:type_name -> dashboard.Item
+	6,  // 9: dashboard.ItemService.ListItems:input_type -> dashboard.ListItemsRequest
+	8,  // 10: dashboard.ItemService.GetItem:input_type -> dashboard.GetItemRequest
+	9,  // 11: dashboard.ItemService.CreateItem:input_type -> dashboard.CreateItemRequest
+	10, // 12: dashboard.ItemService.UpdateItem:input_type -> dashboard.UpdateItemRequest
+	11, // 13: dashboard.ItemService.DeleteItem:input_type -> dashboard.DeleteItemRequest
+	13, // 14: dashboard.ItemService.StreamEvents:input_type -> dashboard.StreamEventsRequest
+	7,  // 15: dashboard.ItemService.ListItems:output_type -> dashboard.ListItemsResponse
+	1,  // 16: dashboard.ItemService.GetItem:output_type -> dashboard.Item
+	1,  // 17: dashboard.ItemService.CreateItem:output_type -> dashboard.Item
+	1,  // 18: dashboard.ItemService.UpdateItem:output_type -> dashboard.Item
+	12, // 19: dashboard.ItemService.DeleteItem:output_type -> dashboard.DeleteItemResponse
+	14, // 20: dashboard.ItemService.StreamEvents:output_type -> dashboard.ItemEvent
+	15, // [15:21] is the sub-list for method output_type
+	9,  // [9:15] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
 }
 
 func init() { file_proto_items_proto_init() }
@@ -395,13 +953,14 @@ func file_proto_items_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_items_proto_rawDesc), len(file_proto_items_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   5,
+			NumEnums:      1,
+			NumMessages:   15,
 			NumExtensions: 0,
-			NumServices:   0,
+			NumServices:   1,
 		},
 		GoTypes:           file_proto_items_proto_goTypes,
 		DependencyIndexes: file_proto_items_proto_depIdxs,
+		EnumInfos:         file_proto_items_proto_enumTypes,
 		MessageInfos:      file_proto_items_proto_msgTypes,
 	}.Build()
 	File_proto_items_proto = out.File
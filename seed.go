@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+)
+
+// sampleItems seed the store with the demo profile, the default
+var sampleItems = []itemstore.Item{
+	{ID: itemstore.IntID(1), Color: "red", Shape: "circle", Category: "A"},
+	{ID: itemstore.IntID(2), Color: "blue", Shape: "square", Category: "A"},
+	{ID: itemstore.IntID(3), Color: "green", Shape: "triangle", Category: "B"},
+	{ID: itemstore.IntID(4), Color: "red", Shape: "square", Category: "B"},
+	{ID: itemstore.IntID(5), Color: "blue", Shape: "circle", Category: "C"},
+	{ID: itemstore.IntID(6), Color: "green", Shape: "square", Category: "C"},
+}
+
+// The large profile always generates the same items
+const (
+	largeSeedCount = 10000
+	largeSeedSeed  = 1
+)
+
+// seedFilePrefix introduces the path of a file:<path> seed profile
+const seedFilePrefix = "file:"
+
+// seedItems resolves a --seed profile to the items the store starts with.
+// Unlike --data, a file profile is read once and never watched or saved.
+func seedItems(profile string) ([]itemstore.Item, error) {
+	switch profile {
+	case "demo":
+		return sampleItems, nil
+	case "empty":
+		return nil, nil
+	case "large":
+		return itemstore.Generate(largeSeedCount, largeSeedSeed), nil
+	}
+	if path, ok := strings.CutPrefix(profile, seedFilePrefix); ok {
+		if path == "" {
+			return nil, fmt.Errorf("--seed: %q names no file", profile)
+		}
+		return itemstore.LoadFile(path)
+	}
+	return nil, fmt.Errorf("--seed: unknown profile %q; want demo, empty, large, or file:<path>", profile)
+}
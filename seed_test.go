@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/server"
+)
+
+func TestSeedItems(t *testing.T) {
+	t.Run("demo", func(t *testing.T) {
+		items, err := seedItems("demo")
+		if err != nil || !slices.Equal(items, sampleItems) {
+			t.Errorf("seedItems(demo) = %d items, %v, want the sample items", len(items), err)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		if items, err := seedItems("empty"); err != nil || len(items) != 0 {
+			t.Errorf("seedItems(empty) = %d items, %v, want none", len(items), err)
+		}
+	})
+
+	t.Run("large is deterministic", func(t *testing.T) {
+		first, err := seedItems("large")
+		if err != nil || len(first) != largeSeedCount {
+			t.Fatalf("seedItems(large) = %d items, %v, want %d", len(first), err, largeSeedCount)
+		}
+		second, _ := seedItems("large")
+		if !reflect.DeepEqual(first, second) {
+			t.Error("seedItems(large) differs between calls")
+		}
+		if _, err := itemstore.New(first); err != nil {
+			t.Errorf("itemstore.New(large) error = %v", err)
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "seed.json")
+		os.WriteFile(path, []byte(`[{"id":9,"color":"teal","shape":"circle","category":"Z"}]`), 0o600)
+		items, err := seedItems("file:" + path)
+		if err != nil || len(items) != 1 || items[0].Color != "teal" {
+			t.Errorf("seedItems(file:) = %v, %v, want the teal item", items, err)
+		}
+		if _, err := seedItems("file:" + filepath.Join(t.TempDir(), "missing.json")); err == nil {
+			t.Error("seedItems(file:missing) succeeded, want an error")
+		}
+	})
+
+	for _, profile := range []string{"", "Demo", "huge", "file:"} {
+		if _, err := seedItems(profile); err == nil || !strings.Contains(err.Error(), "--seed") {
+			t.Errorf("seedItems(%q) error = %v, want one about --seed", profile, err)
+		}
+	}
+}
+
+func TestEmptyDashboard(t *testing.T) {
+	items, _ := seedItems("empty")
+	store, err := itemstore.New(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := server.New(store)
+	if err != nil {
+		t.Fatalf("server.New() error = %v", err)
+	}
+	handler := srv.Handler()
+	for _, path := range []string{
+		"/items", "/items?groupBy=none", "/items?groupBy=color&sortBy=color", "/api/items", "/api/charts/color",
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d with no items, want 200: %s", path, rec.Code, rec.Body)
+		}
+		if strings.HasPrefix(path, "/items") && !strings.Contains(rec.Body.String(), `class="no-items"`) {
+			t.Errorf("GET %s does not say there are no items", path)
+		}
+	}
+}
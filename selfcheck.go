@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/ElodinLaarz/dashboard/pkg/cli"
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/itemstore"
+	"github.com/ElodinLaarz/dashboard/pkg/server"
+)
+
+// selfCheck renders every page with the templates cfg serves, checks the
+// embedded static files, and validates the items the server would start
+// with and each collection's, reporting every problem it finds
+func selfCheck(cfg config.Config, storeOpts []itemstore.Option) error {
+	opts := []server.Option{server.WithConfig(cfg)}
+	if cfg.Dev {
+		opts = append(opts, server.WithTemplates(os.DirFS(devTemplatesDir)), server.WithStatic(os.DirFS(devStaticDir)))
+	}
+	errs := []error{server.SelfCheck(opts...)}
+
+	seedFile, isSeedFile := strings.CutPrefix(cfg.Seed, seedFilePrefix)
+	switch {
+	case cfg.DataFile != "":
+		errs = append(errs, checkDataFile(cfg.DataFile, storeOpts))
+	case cfg.Generate == 0 && isSeedFile && seedFile != "":
+		errs = append(errs, checkDataFile(seedFile, storeOpts))
+	default:
+		items, err := loadItems(cfg, slog.New(slog.DiscardHandler))
+		if err == nil {
+			err = itemstore.ValidateItems(items, storeOpts...)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("items: %w", err))
+		}
+	}
+	for _, c := range cfg.Collections {
+		if err := checkDataFile(c.DataFile, storeOpts); err != nil {
+			errs = append(errs, fmt.Errorf("collection %s: %w", c.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkDataFile validates every item in the data file at path, as
+// "dashboard validate" does, and then with the rules of the stores it would
+// fill
+func checkDataFile(path string, storeOpts []itemstore.Option) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := itemstore.ValidateJSON(f); err != nil {
+		var errs []error
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			errs = joined.Unwrap()
+		} else {
+			errs = []error{err}
+		}
+		for i, err := range errs {
+			if posErr := (*itemstore.PositionError)(nil); errors.As(err, &posErr) {
+				errs[i] = fmt.Errorf("%s:%d:%d: %w", path, posErr.Line, posErr.Column, posErr.Err)
+			} else {
+				errs[i] = fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	items, err := itemstore.LoadFile(path)
+	if err == nil {
+		err = itemstore.ValidateItems(items, storeOpts...)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// runSelfCheck implements "dashboard selfcheck [flags]". It takes the serve
+// flags and runs the self-check the server starts with, without listening,
+// so CI can catch a broken template or data file. It returns ExitFailure
+// when a check fails.
+func runSelfCheck(args []string, stdout, stderr io.Writer) int {
+	cfg, err := config.Load(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "Invalid configuration:\n%v\n", err)
+		return cli.ExitUsage
+	}
+	err = registerFormats(cfg)
+	if err == nil {
+		err = selfCheck(cfg, newStoreOptions(cfg))
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "self-check failed:\n%v\n", err)
+		return cli.ExitFailure
+	}
+	fmt.Fprintln(stdout, "self-check: ok")
+	return cli.ExitOK
+}
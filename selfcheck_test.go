@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ElodinLaarz/dashboard/pkg/cli"
+	"github.com/ElodinLaarz/dashboard/pkg/config"
+	"github.com/ElodinLaarz/dashboard/pkg/format"
+)
+
+func TestRunSelfCheck(t *testing.T) {
+	defer func(shapes *format.ShapeRegistry) { format.Shapes = shapes }(format.Shapes)
+	format.Shapes = format.NewShapeRegistry()
+
+	var stdout, stderr bytes.Buffer
+	if code := runSelfCheck(nil, &stdout, &stderr); code != cli.ExitOK {
+		t.Fatalf("runSelfCheck() = %d, want ExitOK; stderr:\n%s", code, &stderr)
+	}
+	if got := stdout.String(); got != "self-check: ok\n" {
+		t.Errorf("stdout = %q", got)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := runSelfCheck([]string{"--addr"}, &stdout, &stderr); code != cli.ExitUsage {
+		t.Errorf("runSelfCheck() with a bad flag = %d, want ExitUsage", code)
+	}
+
+	// Every invalid item is reported, in the items and the collections
+	dir := t.TempDir()
+	t.Chdir(dir)
+	os.WriteFile("items.json", []byte(`[{"id":1,"color":"","shape":"circle","category":"A"},{"id":2,"color":"red","shape":"circle","category":"A"},{"id":2,"color":"red","shape":"circle","category":"A"}]`), 0o600)
+	os.WriteFile("more.json", []byte(`[{"id":0,"color":"red","shape":"circle","category":"A"}]`), 0o600)
+	stderr.Reset()
+	if code := runSelfCheck([]string{"--data", "items.json", "--collections", "more=more.json"}, &stdout, &stderr); code != cli.ExitFailure {
+		t.Fatalf("runSelfCheck() with invalid items = %d, want ExitFailure", code)
+	}
+	for _, want := range []string{"items.json:1:2: invalid item at index 0", "index 2: duplicate", "collection more: more.json:1:2: invalid item at index 0"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr lacks %q:\n%s", want, &stderr)
+		}
+	}
+}
+
+func TestRun_SelfCheckFails(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	os.WriteFile("items.json", []byte(`[{"id":1,"color":"","shape":"circle","category":"A"}]`), 0o600)
+	defer func(shapes *format.ShapeRegistry) { format.Shapes = shapes }(format.Shapes)
+	format.Shapes = format.NewShapeRegistry()
+
+	cfg := config.Default()
+	cfg.DataFile = "items.json"
+	cfg.Addr = "127.0.0.1:0"
+	err := run(t.Context(), cfg, slog.New(slog.DiscardHandler))
+	if err == nil || !strings.Contains(err.Error(), "self-check failed") || !strings.Contains(err.Error(), "index 0") {
+		t.Errorf("run() = %v, want the self-check to stop startup", err)
+	}
+}